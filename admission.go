@@ -0,0 +1,55 @@
+package octobe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrSessionLimit is returned by Begin when no session could be admitted within the timeout of
+// WithMaxConcurrentSessions.
+var ErrSessionLimit = errors.New("too many concurrent sessions")
+
+// WithMaxConcurrentSessions bounds the number of sessions in a transaction that are open at the same time to n, which
+// protects a small connection pool from a thundering herd of requests. Begin and StartTransaction wait for a session to
+// end once the limit is reached, and fail with ErrSessionLimit when none ends within timeout or the context is done
+// first. A timeout of zero or less waits until the context is done. Sessions without a transaction wait for admission
+// as well, but hold their slot only while they are begun. The time waited is reported to session hooks as
+// SessionEvent.Wait.
+func WithMaxConcurrentSessions(n int, timeout time.Duration) InstanceOption {
+	return func(cfg *instanceConfig) {
+		cfg.maxSessions = n
+		cfg.admissionTimeout = timeout
+	}
+}
+
+// admit waits for a slot of the session limit, it returns the time waited and a function releasing the slot again,
+// which may be called more than once.
+func (ob *Octobe[DRIVER, CONFIG, BUILDER]) admit(ctx context.Context) (time.Duration, func(), error) {
+	if ob.slots == nil {
+		return 0, func() {}, nil
+	}
+
+	release := sync.OnceFunc(func() { <-ob.slots })
+	select {
+	case ob.slots <- struct{}{}:
+		return 0, release, nil
+	default:
+	}
+
+	start := ob.cfg.clock.Now()
+	var timeout <-chan time.Time
+	if ob.cfg.admissionTimeout > 0 {
+		timeout = ob.cfg.clock.After(ob.cfg.admissionTimeout)
+	}
+	select {
+	case ob.slots <- struct{}{}:
+		return ob.cfg.clock.Now().Sub(start), release, nil
+	case <-timeout:
+		return ob.cfg.clock.Now().Sub(start), nil, ErrSessionLimit
+	case <-ctx.Done():
+		return ob.cfg.clock.Now().Sub(start), nil, fmt.Errorf("%w: %w", ErrSessionLimit, ctx.Err())
+	}
+}
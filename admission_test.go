@@ -0,0 +1,49 @@
+package octobe_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ponrove/octobe"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithMaxConcurrentSessions(t *testing.T) {
+	d := &fakeDriver{}
+	recorder := &sessionRecorder{}
+	ob, err := octobe.New(d.open(), octobe.WithMaxConcurrentSessions(1, 50*time.Millisecond), octobe.WithQueryHook(recorder))
+	require.NoError(t, err)
+
+	// Sessions without a transaction do not hold on to their slot.
+	_, err = ob.Begin(context.Background(), withoutTx())
+	require.NoError(t, err)
+
+	first, err := ob.Begin(context.Background(), withTx())
+	require.NoError(t, err)
+
+	_, err = ob.Begin(context.Background(), withTx())
+	require.ErrorIs(t, err, octobe.ErrSessionLimit)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = ob.Begin(ctx, withTx())
+	require.ErrorIs(t, err, octobe.ErrSessionLimit)
+	require.ErrorIs(t, err, context.Canceled)
+
+	go func() {
+		time.Sleep(time.Millisecond)
+		_ = first.Commit()
+	}()
+	waiting, err := ob.Begin(context.Background(), withTx())
+	require.NoError(t, err)
+	require.NoError(t, waiting.Rollback())
+
+	require.Len(t, recorder.events, 5)
+	require.Zero(t, recorder.events[0].Wait)
+	require.ErrorIs(t, recorder.events[1].Err, octobe.ErrSessionLimit)
+	require.GreaterOrEqual(t, recorder.events[1].Wait, 50*time.Millisecond)
+	require.ErrorIs(t, recorder.events[2].Err, octobe.ErrSessionLimit)
+	require.True(t, recorder.events[3].Committed)
+	require.Positive(t, recorder.events[4].Wait)
+}
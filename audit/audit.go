@@ -0,0 +1,260 @@
+// Package audit records the write statements of Octobe instances, with the time, the actor taken from the context, the
+// SQL and the number of affected rows, to a pluggable sink like a logger or a file. An Auditor is added to an instance
+// as query hook. For strict audit guarantees, the records of a transaction can instead be written in the transaction
+// itself with InTransaction, so the audit trail commits or rolls back together with the changes.
+//
+//	auditor := audit.New(audit.LoggerSink(logger))
+//	ob, err := octobe.New(postgres.OpenPGXPool(dsn), octobe.WithQueryHook(auditor))
+//	...
+//	err = ob.StartTransaction(audit.ContextWithActor(ctx, user.ID), audit.InTransaction(auditor,
+//		audit.PostgresTable("audit_log"), handler))
+package audit
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ponrove/octobe"
+)
+
+// Record is an audited write statement.
+type Record struct {
+	Time      time.Time        `json:"time"`
+	Actor     string           `json:"actor,omitempty"`
+	Driver    string           `json:"driver"`
+	Operation octobe.Operation `json:"operation"`
+	Query     string           `json:"query"`
+	// Rows is the number of rows affected by the statement, or -1 if the driver does not know.
+	Rows  int64  `json:"rows"`
+	Error string `json:"error,omitempty"`
+}
+
+// Sink receives audit records, it must be safe for concurrent use.
+type Sink interface {
+	WriteAudit(ctx context.Context, record Record) error
+}
+
+// SinkFunc is a function that implements Sink.
+type SinkFunc func(ctx context.Context, record Record) error
+
+// WriteAudit calls f.
+func (f SinkFunc) WriteAudit(ctx context.Context, record Record) error {
+	return f(ctx, record)
+}
+
+// actorKey is the context key of the actor set with ContextWithActor.
+type actorKey struct{}
+
+// ContextWithActor returns a copy of ctx carrying actor, like the id of the authenticated user of a request, which is
+// recorded with the write statements of sessions begun with it.
+func ContextWithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor set on ctx with ContextWithActor.
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// Option configures an Auditor.
+type Option func(cfg *config)
+
+// config holds the configuration of an Auditor.
+type config struct {
+	actor   func(ctx context.Context) string
+	onError func(ctx context.Context, err error)
+	clock   octobe.Clock
+}
+
+// WithActor sets the function extracting the actor from the context of a statement, ActorFromContext by default.
+func WithActor(actor func(ctx context.Context) string) Option {
+	return func(cfg *config) {
+		cfg.actor = actor
+	}
+}
+
+// WithErrorHandler sets the function called when the sink fails to write a record, which is logged with slog.Default
+// by default. The statement itself is not failed by a failing sink, use InTransaction for that.
+func WithErrorHandler(onError func(ctx context.Context, err error)) Option {
+	return func(cfg *config) {
+		cfg.onError = onError
+	}
+}
+
+// WithClock sets the clock the time of records is taken from, octobe.SystemClock by default.
+func WithClock(clock octobe.Clock) Option {
+	return func(cfg *config) {
+		cfg.clock = clock
+	}
+}
+
+// Auditor is a query and session hook recording write statements: Exec, AsyncInsert and Batch operations, and
+// statements of other operations starting with INSERT, UPDATE, DELETE, MERGE or TRUNCATE, like an INSERT with a
+// RETURNING clause performed as query. Failed statements are recorded along with their error. Arguments are not
+// recorded, they may contain sensitive values.
+type Auditor struct {
+	sink Sink
+	cfg  config
+}
+
+// Ensure Auditor is invoked around queries and sessions.
+var (
+	_ octobe.QueryHook   = &Auditor{}
+	_ octobe.SessionHook = &Auditor{}
+)
+
+// New creates an auditor writing records to sink.
+func New(sink Sink, opts ...Option) *Auditor {
+	cfg := config{actor: ActorFromContext, clock: octobe.SystemClock, onError: logError}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return &Auditor{sink: sink, cfg: cfg}
+}
+
+// logError logs a record that could not be written.
+func logError(ctx context.Context, err error) {
+	slog.Default().ErrorContext(ctx, "failed to write audit record", slog.Any("error", err))
+}
+
+// buffer holds the records of a session written with InTransaction.
+type buffer struct {
+	mu      sync.Mutex
+	strict  bool
+	writing bool
+	records []Record
+}
+
+// bufferKey is the context key of the buffer of a session.
+type bufferKey struct{}
+
+// BeforeSession attaches the buffer of the session to its context.
+func (a *Auditor) BeforeSession(ctx context.Context, _ *octobe.SessionEvent) context.Context {
+	return context.WithValue(ctx, bufferKey{}, &buffer{})
+}
+
+// AfterSession does nothing, records are written as statements finish.
+func (a *Auditor) AfterSession(context.Context, *octobe.SessionEvent) {}
+
+// BeforeQuery does nothing, statements are recorded once they have finished.
+func (a *Auditor) BeforeQuery(ctx context.Context, _ *octobe.QueryEvent) context.Context {
+	return ctx
+}
+
+// AfterQuery records a finished write statement, in the buffer of the session if it is written with InTransaction and
+// with the sink otherwise.
+func (a *Auditor) AfterQuery(ctx context.Context, event *octobe.QueryEvent) {
+	if !isWrite(event) {
+		return
+	}
+	b, _ := ctx.Value(bufferKey{}).(*buffer)
+	if b != nil {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if b.writing {
+			return
+		}
+	}
+
+	record := Record{
+		Time:      a.cfg.clock.Now(),
+		Actor:     a.cfg.actor(ctx),
+		Driver:    event.Driver.Name,
+		Operation: event.Operation,
+		Query:     event.Query,
+		Rows:      event.Rows,
+	}
+	if event.Err != nil {
+		record.Error = event.Err.Error()
+	}
+	if b != nil && b.strict {
+		b.records = append(b.records, record)
+		return
+	}
+	if err := a.sink.WriteAudit(ctx, record); err != nil {
+		a.cfg.onError(ctx, err)
+	}
+}
+
+// isWrite reports whether event is a write statement.
+func isWrite(event *octobe.QueryEvent) bool {
+	switch event.Operation {
+	case octobe.OperationExec, octobe.OperationAsyncInsert, octobe.OperationBatch, octobe.OperationCopy:
+		return true
+	}
+	keyword, _, _ := strings.Cut(strings.TrimSpace(stripComments(event.Query)), " ")
+	switch strings.ToUpper(keyword) {
+	case "INSERT", "UPDATE", "DELETE", "MERGE", "TRUNCATE":
+		return true
+	}
+	return false
+}
+
+// stripComments removes the leading comments of query.
+func stripComments(query string) string {
+	for {
+		query = strings.TrimSpace(query)
+		switch {
+		case strings.HasPrefix(query, "--"):
+			_, rest, ok := strings.Cut(query, "\n")
+			if !ok {
+				return ""
+			}
+			query = rest
+		case strings.HasPrefix(query, "/*"):
+			_, rest, ok := strings.Cut(query, "*/")
+			if !ok {
+				return ""
+			}
+			query = rest
+		default:
+			return query
+		}
+	}
+}
+
+// InTransaction wraps fn to write the records of its write statements with write in the same transaction once fn
+// succeeded, instead of writing them to the sink of auditor, so the records are committed if and only if the changes
+// are. The statements write performs are not audited themselves.
+func InTransaction[BUILDER any](auditor *Auditor, write func(session octobe.BuilderSession[BUILDER], records []Record) error, fn func(session octobe.BuilderSession[BUILDER]) error) func(session octobe.BuilderSession[BUILDER]) error {
+	return func(session octobe.BuilderSession[BUILDER]) error {
+		b, _ := octobe.SessionContext(session).Value(bufferKey{}).(*buffer)
+		if b == nil {
+			// The auditor is not a hook of the instance of the session, there is nothing to record.
+			return fn(session)
+		}
+
+		b.mu.Lock()
+		strict, mark := b.strict, len(b.records)
+		b.strict = true
+		b.mu.Unlock()
+		defer func() {
+			b.mu.Lock()
+			b.strict = strict
+			b.mu.Unlock()
+		}()
+
+		if err := fn(session); err != nil {
+			return err
+		}
+
+		b.mu.Lock()
+		records := append([]Record(nil), b.records[mark:]...)
+		b.records = b.records[:mark]
+		b.writing = true
+		b.mu.Unlock()
+		defer func() {
+			b.mu.Lock()
+			b.writing = false
+			b.mu.Unlock()
+		}()
+		if len(records) == 0 {
+			return nil
+		}
+		return write(session, records)
+	}
+}
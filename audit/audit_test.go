@@ -0,0 +1,126 @@
+package audit_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/audit"
+	"github.com/ponrove/octobe/driver/postgres"
+	"github.com/stretchr/testify/require"
+)
+
+// clock is a clock standing still.
+type clock struct {
+	now time.Time
+}
+
+func (c clock) Now() time.Time                         { return c.now }
+func (c clock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+var now = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func handler(session octobe.BuilderSession[postgres.Builder]) error {
+	if _, err := session.Builder()("UPDATE products SET price = 0").Exec(); err != nil {
+		return err
+	}
+	var id int
+	if err := session.Builder()("-- name: CreateProduct\nINSERT INTO products (name) VALUES ('a') RETURNING id").QueryRow(&id); err != nil {
+		return err
+	}
+	return session.Builder()("SELECT id FROM products").Query(func(rows postgres.Rows) error { return rows.Err() })
+}
+
+func expectHandler(mock pgxmock.PgxConnIface) {
+	mock.ExpectExec("UPDATE products").WillReturnResult(pgxmock.NewResult("UPDATE", 3))
+	mock.ExpectQuery("INSERT INTO products").WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery("SELECT id FROM products").WillReturnRows(pgxmock.NewRows([]string{"id"}))
+}
+
+func TestAuditor(t *testing.T) {
+	ctx := audit.ContextWithActor(context.Background(), "user-1")
+	mock, err := pgxmock.NewConn()
+	require.NoError(t, err)
+	defer mock.Close(ctx)
+
+	mock.ExpectBegin()
+	expectHandler(mock)
+	mock.ExpectCommit()
+
+	var buf bytes.Buffer
+	auditor := audit.New(audit.WriterSink(&buf), audit.WithClock(clock{now}))
+	ob, err := octobe.New(postgres.OpenPGXWithConn(mock), octobe.WithQueryHook(auditor))
+	require.NoError(t, err)
+
+	require.NoError(t, ob.StartTransaction(ctx, handler, postgres.WithPGXTxOptions(postgres.PGXTxOptions{})))
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	var records []audit.Record
+	decoder := json.NewDecoder(&buf)
+	for decoder.More() {
+		var record audit.Record
+		require.NoError(t, decoder.Decode(&record))
+		records = append(records, record)
+	}
+	require.Equal(t, []audit.Record{
+		{Time: now, Actor: "user-1", Driver: "pgx", Operation: octobe.OperationExec, Query: "UPDATE products SET price = 0", Rows: 3},
+		{Time: now, Actor: "user-1", Driver: "pgx", Operation: octobe.OperationQueryRow, Query: "-- name: CreateProduct\nINSERT INTO products (name) VALUES ('a') RETURNING id", Rows: 1},
+	}, records)
+}
+
+func TestAuditorSinkError(t *testing.T) {
+	ctx := context.Background()
+	mock, err := pgxmock.NewConn()
+	require.NoError(t, err)
+	defer mock.Close(ctx)
+
+	mock.ExpectExec("DELETE FROM products").WillReturnResult(pgxmock.NewResult("DELETE", 1))
+
+	failed := errors.New("disk full")
+	var reported []error
+	auditor := audit.New(audit.SinkFunc(func(context.Context, audit.Record) error { return failed }),
+		audit.WithErrorHandler(func(_ context.Context, err error) { reported = append(reported, err) }))
+	ob, err := octobe.New(postgres.OpenPGXWithConn(mock), octobe.WithQueryHook(auditor))
+	require.NoError(t, err)
+
+	session, err := ob.Begin(ctx)
+	require.NoError(t, err)
+	_, err = session.Builder()("DELETE FROM products").Exec()
+	require.NoError(t, err, "a failing sink does not fail the statement")
+	require.Equal(t, []error{failed}, reported)
+}
+
+func TestInTransaction(t *testing.T) {
+	ctx := audit.ContextWithActor(context.Background(), "user-1")
+	mock, err := pgxmock.NewConn()
+	require.NoError(t, err)
+	defer mock.Close(ctx)
+
+	mock.ExpectBegin()
+	expectHandler(mock)
+	mock.ExpectExec(`INSERT INTO "audit"."log"`).
+		WithArgs(now, "user-1", "pgx", "exec", "UPDATE products SET price = 0", int64(3), "").
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec(`INSERT INTO "audit"."log"`).
+		WithArgs(now, "user-1", "pgx", "query_row", pgxmock.AnyArg(), int64(1), "").
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectCommit()
+
+	sink := audit.SinkFunc(func(context.Context, audit.Record) error {
+		t.Error("records written in the transaction must not be written to the sink")
+		return nil
+	})
+	auditor := audit.New(sink, audit.WithClock(clock{now}))
+	ob, err := octobe.New(postgres.OpenPGXWithConn(mock), octobe.WithQueryHook(auditor))
+	require.NoError(t, err)
+
+	err = ob.StartTransaction(ctx, audit.InTransaction(auditor, audit.PostgresTable("audit.log"), handler),
+		postgres.WithPGXTxOptions(postgres.PGXTxOptions{}))
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,61 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/driver/postgres"
+)
+
+// LoggerSink writes records to logger at the info level.
+func LoggerSink(logger *slog.Logger) Sink {
+	return SinkFunc(func(ctx context.Context, record Record) error {
+		attrs := []slog.Attr{
+			slog.String("actor", record.Actor),
+			slog.String("driver", record.Driver),
+			slog.String("operation", string(record.Operation)),
+			slog.String("query", record.Query),
+			slog.Int64("rows", record.Rows),
+		}
+		if record.Error != "" {
+			attrs = append(attrs, slog.String("error", record.Error))
+		}
+		logger.LogAttrs(ctx, slog.LevelInfo, "audit", attrs...)
+		return nil
+	})
+}
+
+// WriterSink writes records to w as JSON, one record per line, like to an append-only file.
+func WriterSink(w io.Writer) Sink {
+	var mu sync.Mutex
+	encoder := json.NewEncoder(w)
+	return SinkFunc(func(_ context.Context, record Record) error {
+		mu.Lock()
+		defer mu.Unlock()
+		return encoder.Encode(record)
+	})
+}
+
+// PostgresTable returns a function for InTransaction inserting records into table, optionally qualified with its
+// schema like audit.log, which must have the columns occurred_at timestamptz, actor text, driver text, operation text,
+// query text, rows bigint and error text.
+func PostgresTable(table string) func(session octobe.BuilderSession[postgres.Builder], records []Record) error {
+	query := "INSERT INTO " + pgx.Identifier(strings.Split(table, ".")).Sanitize() + " (occurred_at, actor, driver, operation, query, rows, error) " +
+		"VALUES ($1, $2, $3, $4, $5, $6, $7)"
+	return func(session octobe.BuilderSession[postgres.Builder], records []Record) error {
+		for _, record := range records {
+			_, err := session.Builder()(query).Arguments(record.Time, record.Actor, record.Driver,
+				string(record.Operation), record.Query, record.Rows, record.Error).Exec()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,314 @@
+// Package cache caches the results of read-only queries. The result of a query is cached as the value its load
+// function returns, like the structs it was scanned into, so caching works with every driver and every way of reading
+// rows. Entries expire after a TTL and can be invalidated by the tags they were cached with, like the tables a query
+// reads, once those are written to.
+//
+// The cache does not wrap sessions or builders. Queries are built, executed and scanned by the builders of the drivers,
+// whose segments and rows have no common interface, so there is no driver independent point at which QueryRow and
+// Query results could be captured and replayed. Fetch caches what the query was read into instead, around any builder.
+//
+//	c := cache.New(cache.WithTTL(time.Minute))
+//	product, err := cache.Fetch(c, cache.Key("SELECT id, name FROM products WHERE id = $1", id),
+//		func() (Product, error) { return loadProduct(builder, id) },
+//		cache.Tags("products"))
+//	...
+//	c.Invalidate("products")
+package cache
+
+import (
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ponrove/octobe"
+)
+
+// Defaults of the options of a Cache.
+const (
+	DefaultTTL  = time.Minute
+	DefaultSize = 1000
+)
+
+// Store holds the entries of a Cache. Values are stored and returned as they are, expiry and invalidation are handled
+// by the Cache. A Store must be safe for concurrent use.
+type Store interface {
+	// Get returns the value stored under key.
+	Get(key string) (any, bool)
+	// Set stores value under key, replacing the value stored before.
+	Set(key string, value any)
+	// Delete removes the value stored under key, if any.
+	Delete(key string)
+	// Clear removes all values.
+	Clear()
+}
+
+// Option configures a Cache.
+type Option func(cfg *config)
+
+// config holds the configuration of a Cache.
+type config struct {
+	store Store
+	ttl   time.Duration
+	clock octobe.Clock
+}
+
+// WithStore sets the store of the entries, an LRU store of DefaultSize entries by default.
+func WithStore(store Store) Option {
+	return func(cfg *config) {
+		cfg.store = store
+	}
+}
+
+// WithTTL sets the time entries are cached for unless an entry is given a TTL of its own, DefaultTTL by default.
+func WithTTL(ttl time.Duration) Option {
+	return func(cfg *config) {
+		cfg.ttl = ttl
+	}
+}
+
+// WithClock sets the clock entries expire by, octobe.SystemClock by default.
+func WithClock(clock octobe.Clock) Option {
+	return func(cfg *config) {
+		cfg.clock = clock
+	}
+}
+
+// Cache caches the results of queries, it is safe for concurrent use. Cached values are shared between all callers
+// fetching them and must not be modified.
+type Cache struct {
+	cfg config
+
+	mu   sync.Mutex
+	tags map[string]uint64
+}
+
+// New creates a cache.
+func New(opts ...Option) *Cache {
+	cfg := config{ttl: DefaultTTL}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.store == nil {
+		cfg.store = NewLRU(DefaultSize)
+	}
+	if cfg.clock == nil {
+		cfg.clock = octobe.SystemClock
+	}
+	return &Cache{cfg: cfg, tags: make(map[string]uint64)}
+}
+
+// entry is a value held by the store, along with its expiry and the versions of its tags when it was cached.
+type entry struct {
+	value   any
+	expires time.Time
+	tags    map[string]uint64
+}
+
+// EntryOption configures a single entry of a cache.
+type EntryOption func(cfg *entryConfig)
+
+// entryConfig holds the configuration of an entry.
+type entryConfig struct {
+	ttl  time.Duration
+	tags []string
+}
+
+// TTL caches the entry for ttl instead of the TTL of the cache.
+func TTL(ttl time.Duration) EntryOption {
+	return func(cfg *entryConfig) {
+		cfg.ttl = ttl
+	}
+}
+
+// Tags tags the entry, invalidating any of the tags invalidates the entry.
+func Tags(tags ...string) EntryOption {
+	return func(cfg *entryConfig) {
+		cfg.tags = append(cfg.tags, tags...)
+	}
+}
+
+// Key returns a cache key for query with args, for queries that are cached by their SQL and arguments. Arguments are
+// keyed by the values they point to, not by their addresses, and by the value of a driver.Valuer, so a pointer to a new
+// variable holding the same value yields the same key.
+func Key(query string, args ...any) string {
+	hash := sha256.New()
+	_, _ = fmt.Fprintf(hash, "%q", query)
+	for _, arg := range args {
+		_, _ = fmt.Fprintf(hash, "\x00%T:", arg)
+		writeArg(hash, reflect.ValueOf(arg), nil)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// writeArg writes v to w for Key, following pointers and interfaces instead of writing addresses. Visited holds the
+// pointers followed to reach v, to stop at cycles.
+func writeArg(w io.Writer, v reflect.Value, visited []uintptr) {
+	if !v.IsValid() {
+		_, _ = io.WriteString(w, "nil")
+		return
+	}
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface, reflect.Map, reflect.Slice:
+		if v.IsNil() {
+			_, _ = io.WriteString(w, "nil")
+			return
+		}
+	}
+	if v.CanInterface() {
+		switch value := v.Interface().(type) {
+		case time.Time:
+			// Without the monotonic clock reading, which differs between equal times.
+			_, _ = fmt.Fprint(w, value.Round(0))
+			return
+		case driver.Valuer:
+			if value, err := value.Value(); err == nil {
+				writeArg(w, reflect.ValueOf(value), visited)
+				return
+			}
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if slices.Contains(visited, v.Pointer()) {
+			_, _ = io.WriteString(w, "cycle")
+			return
+		}
+		writeArg(w, v.Elem(), append(visited, v.Pointer()))
+	case reflect.Interface:
+		_, _ = fmt.Fprintf(w, "%s:", v.Elem().Type())
+		writeArg(w, v.Elem(), visited)
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			_, _ = fmt.Fprintf(w, "%x", v)
+			return
+		}
+		_, _ = io.WriteString(w, "[")
+		for i := range v.Len() {
+			if i > 0 {
+				_, _ = io.WriteString(w, " ")
+			}
+			writeArg(w, v.Index(i), visited)
+		}
+		_, _ = io.WriteString(w, "]")
+	case reflect.Map:
+		entries := make([]string, 0, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			var entry strings.Builder
+			writeArg(&entry, iter.Key(), visited)
+			entry.WriteString(":")
+			writeArg(&entry, iter.Value(), visited)
+			entries = append(entries, entry.String())
+		}
+		sort.Strings(entries)
+		_, _ = fmt.Fprintf(w, "map%v", entries)
+	case reflect.Struct:
+		_, _ = io.WriteString(w, "{")
+		for i := range v.NumField() {
+			if i > 0 {
+				_, _ = io.WriteString(w, " ")
+			}
+			writeArg(w, v.Field(i), visited)
+		}
+		_, _ = io.WriteString(w, "}")
+	default:
+		_, _ = fmt.Fprintf(w, "%#v", v)
+	}
+}
+
+// Fetch returns the value cached under key, or the value load returns, which is cached under key unless load fails.
+// Concurrent fetches of a key that is not cached each call load.
+func Fetch[T any](c *Cache, key string, load func() (T, error), opts ...EntryOption) (T, error) {
+	if value, ok := c.get(key); ok {
+		if typed, ok := value.(T); ok {
+			return typed, nil
+		}
+	}
+
+	var cfg entryConfig
+	cfg.ttl = c.cfg.ttl
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	versions := c.versions(cfg.tags)
+	value, err := load()
+	if err != nil {
+		return value, err
+	}
+	c.cfg.store.Set(key, entry{value: value, expires: c.cfg.clock.Now().Add(cfg.ttl), tags: versions})
+	return value, nil
+}
+
+// get returns the value cached under key, if it has neither expired nor been invalidated.
+func (c *Cache) get(key string) (any, bool) {
+	stored, ok := c.cfg.store.Get(key)
+	if !ok {
+		return nil, false
+	}
+	e, ok := stored.(entry)
+	if !ok || !c.cfg.clock.Now().Before(e.expires) || !c.current(e.tags) {
+		c.cfg.store.Delete(key)
+		return nil, false
+	}
+	return e.value, true
+}
+
+// versions returns the current versions of tags. They are taken before loading a value, so an invalidation while it
+// is loaded invalidates the value as well.
+func (c *Cache) versions(tags []string) map[string]uint64 {
+	if len(tags) == 0 {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	versions := make(map[string]uint64, len(tags))
+	for _, tag := range tags {
+		versions[tag] = c.tags[tag]
+	}
+	return versions
+}
+
+// current reports whether none of the tags have been invalidated since their versions were taken.
+func (c *Cache) current(versions map[string]uint64) bool {
+	if len(versions) == 0 {
+		return true
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for tag, version := range versions {
+		if c.tags[tag] != version {
+			return false
+		}
+	}
+	return true
+}
+
+// Invalidate invalidates all entries cached with any of tags.
+func (c *Cache) Invalidate(tags ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, tag := range tags {
+		c.tags[tag]++
+	}
+}
+
+// Delete removes the entries cached under keys.
+func (c *Cache) Delete(keys ...string) {
+	for _, key := range keys {
+		c.cfg.store.Delete(key)
+	}
+}
+
+// Clear removes all entries.
+func (c *Cache) Clear() {
+	c.cfg.store.Clear()
+}
@@ -0,0 +1,152 @@
+package cache_test
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ponrove/octobe/cache"
+	"github.com/stretchr/testify/require"
+)
+
+// clock is a clock that only moves when advanced.
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time                         { return c.now }
+func (c *clock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// loader counts the loads of a value.
+type loader struct {
+	loads int
+}
+
+func (l *loader) load() (string, error) {
+	l.loads++
+	return "gopher", nil
+}
+
+func TestFetch(t *testing.T) {
+	clk := &clock{now: time.Now()}
+	c := cache.New(cache.WithTTL(time.Minute), cache.WithClock(clk))
+	l := &loader{}
+	key := cache.Key("SELECT name FROM users WHERE id = $1", 1)
+
+	for range 3 {
+		value, err := cache.Fetch(c, key, l.load)
+		require.NoError(t, err)
+		require.Equal(t, "gopher", value)
+	}
+	require.Equal(t, 1, l.loads)
+
+	clk.now = clk.now.Add(time.Minute)
+	_, err := cache.Fetch(c, key, l.load)
+	require.NoError(t, err)
+	require.Equal(t, 2, l.loads, "expired entries are loaded again")
+
+	_, err = cache.Fetch(c, cache.Key("SELECT name FROM users WHERE id = $1", 2), l.load, cache.TTL(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 3, l.loads, "keys differ by their arguments")
+
+	c.Delete(key)
+	_, err = cache.Fetch(c, key, l.load)
+	require.NoError(t, err)
+	require.Equal(t, 4, l.loads)
+}
+
+func TestKey(t *testing.T) {
+	query := "SELECT name FROM users WHERE id = $1"
+	id, same, other := 1, 1, 2
+	require.Equal(t, cache.Key(query, &id), cache.Key(query, &same), "pointers are keyed by their values")
+	require.NotEqual(t, cache.Key(query, &id), cache.Key(query, &other))
+	require.NotEqual(t, cache.Key(query, &id), cache.Key(query, id), "the type is part of the key")
+	require.NotEqual(t, cache.Key(query, (*int)(nil)), cache.Key(query, &id))
+
+	type filter struct {
+		Name *string
+		IDs  []*int
+	}
+	name, sameName := "gopher", "gopher"
+	require.Equal(t,
+		cache.Key(query, filter{Name: &name, IDs: []*int{&id}}),
+		cache.Key(query, filter{Name: &sameName, IDs: []*int{&same}}))
+	require.NotEqual(t, cache.Key(query, []string{"a b"}), cache.Key(query, []string{"a", "b"}))
+	require.Equal(t, cache.Key(query, map[string]*int{"a": &id, "b": &other}), cache.Key(query, map[string]*int{"b": &other, "a": &same}))
+
+	now := time.Now()
+	require.Equal(t, cache.Key(query, now), cache.Key(query, now.Round(0)), "the monotonic clock reading is ignored")
+	require.Equal(t, cache.Key(query, sql.NullString{String: "stale"}), cache.Key(query, sql.NullString{}),
+		"valuers are keyed by their values")
+}
+
+func TestFetchError(t *testing.T) {
+	c := cache.New()
+	failed := errors.New("failed")
+	_, err := cache.Fetch(c, "key", func() (int, error) { return 0, failed })
+	require.ErrorIs(t, err, failed)
+
+	value, err := cache.Fetch(c, "key", func() (int, error) { return 42, nil })
+	require.NoError(t, err)
+	require.Equal(t, 42, value, "failed loads are not cached")
+}
+
+func TestInvalidate(t *testing.T) {
+	c := cache.New()
+	users, products := &loader{}, &loader{}
+
+	fetch := func() {
+		_, err := cache.Fetch(c, "users", users.load, cache.Tags("users"))
+		require.NoError(t, err)
+		_, err = cache.Fetch(c, "products", products.load, cache.Tags("products", "prices"))
+		require.NoError(t, err)
+	}
+	fetch()
+	fetch()
+	require.Equal(t, 1, users.loads)
+	require.Equal(t, 1, products.loads)
+
+	c.Invalidate("prices")
+	fetch()
+	require.Equal(t, 1, users.loads)
+	require.Equal(t, 2, products.loads)
+
+	c.Clear()
+	fetch()
+	require.Equal(t, 2, users.loads)
+	require.Equal(t, 3, products.loads)
+}
+
+func TestInvalidateWhileLoading(t *testing.T) {
+	c := cache.New()
+	l := &loader{}
+	_, err := cache.Fetch(c, "key", func() (string, error) {
+		c.Invalidate("users")
+		return l.load()
+	}, cache.Tags("users"))
+	require.NoError(t, err)
+
+	_, err = cache.Fetch(c, "key", l.load, cache.Tags("users"))
+	require.NoError(t, err)
+	require.Equal(t, 2, l.loads, "a value invalidated while it was loaded is not served")
+}
+
+func TestLRU(t *testing.T) {
+	store := cache.NewLRU(2)
+	store.Set("a", 1)
+	store.Set("b", 2)
+	_, ok := store.Get("a")
+	require.True(t, ok)
+	store.Set("c", 3)
+
+	_, ok = store.Get("b")
+	require.False(t, ok, "the least recently used value is evicted")
+	value, ok := store.Get("a")
+	require.True(t, ok)
+	require.Equal(t, 1, value)
+
+	store.Delete("a")
+	_, ok = store.Get("a")
+	require.False(t, ok)
+}
@@ -0,0 +1,79 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+)
+
+// lru is a Store holding a limited number of values in memory, evicting the least recently used value.
+type lru struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+// lruEntry is an element of the order of an lru.
+type lruEntry struct {
+	key   string
+	value any
+}
+
+// Ensure lru implements the Store interface.
+var _ Store = &lru{}
+
+// NewLRU returns a Store holding at most size values in memory, evicting the least recently used value once it is
+// full. A size of zero or less holds DefaultSize values.
+func NewLRU(size int) Store {
+	if size <= 0 {
+		size = DefaultSize
+	}
+	return &lru{size: size, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+// Get returns the value stored under key and marks it as recently used.
+func (s *lru) Get(key string) (any, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	element, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+	s.order.MoveToFront(element)
+	return element.Value.(*lruEntry).value, true
+}
+
+// Set stores value under key, evicting the least recently used value if the store is full.
+func (s *lru) Set(key string, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if element, ok := s.entries[key]; ok {
+		element.Value.(*lruEntry).value = value
+		s.order.MoveToFront(element)
+		return
+	}
+	s.entries[key] = s.order.PushFront(&lruEntry{key: key, value: value})
+	if s.order.Len() > s.size {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.entries, oldest.Value.(*lruEntry).key)
+	}
+}
+
+// Delete removes the value stored under key.
+func (s *lru) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if element, ok := s.entries[key]; ok {
+		s.order.Remove(element)
+		delete(s.entries, key)
+	}
+}
+
+// Clear removes all values.
+func (s *lru) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.order.Init()
+	clear(s.entries)
+}
@@ -0,0 +1,32 @@
+package octobe
+
+// Capabilities lists the features a driver supports, so generic code like migrations and helpers can adapt to the
+// driver instead of type asserting on the driver packages.
+type Capabilities struct {
+	// Transactions reports whether sessions can run in a transaction that is committed or rolled back as a whole.
+	Transactions bool
+	// Savepoints reports whether transactions can be nested through savepoints, see Savepoints.
+	Savepoints bool
+	// Batch reports whether several statements or rows can be sent to the database in a single round trip.
+	Batch bool
+	// Copy reports whether rows can be bulk loaded with a copy protocol, like COPY FROM of PostgreSQL.
+	Copy bool
+	// AsyncInsert reports whether inserts can be buffered by the database and written asynchronously.
+	AsyncInsert bool
+	// Returning reports whether statements can return the rows they modified, like with a RETURNING clause.
+	Returning bool
+}
+
+// CapabilityReporter is implemented by drivers that report the features they support.
+type CapabilityReporter interface {
+	Capabilities() Capabilities
+}
+
+// Capabilities returns the features the driver of the instance supports, none if it does not implement
+// CapabilityReporter.
+func (ob *Octobe[DRIVER, CONFIG, BUILDER]) Capabilities() Capabilities {
+	if reporter, ok := ob.driver.(CapabilityReporter); ok {
+		return reporter.Capabilities()
+	}
+	return Capabilities{}
+}
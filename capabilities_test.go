@@ -0,0 +1,29 @@
+package octobe_test
+
+import (
+	"testing"
+
+	"github.com/ponrove/octobe"
+	"github.com/stretchr/testify/require"
+)
+
+// capableDriver is a fake driver that reports capabilities.
+type capableDriver struct {
+	*fakeDriver
+}
+
+func (d *capableDriver) Capabilities() octobe.Capabilities {
+	return octobe.Capabilities{Transactions: true, Savepoints: true}
+}
+
+func TestCapabilities(t *testing.T) {
+	ob, err := octobe.New(func() (octobe.Driver[fakeDriver, fakeConfig, string], error) {
+		return &capableDriver{fakeDriver: &fakeDriver{}}, nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, octobe.Capabilities{Transactions: true, Savepoints: true}, ob.Capabilities())
+
+	ob, err = octobe.New((&fakeDriver{}).open())
+	require.NoError(t, err)
+	require.Equal(t, octobe.Capabilities{}, ob.Capabilities())
+}
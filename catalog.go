@@ -0,0 +1,152 @@
+package octobe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrUnknownQuery is returned when looking up a name no query is registered under.
+	ErrUnknownQuery = errors.New("no query registered under name")
+	// ErrValidationUnsupported is returned by ValidateQueries when the driver cannot validate queries.
+	ErrValidationUnsupported = errors.New("driver does not support query validation")
+)
+
+// catalog holds the queries registered by name.
+var catalog = struct {
+	sync.RWMutex
+	queries map[string]string
+	names   map[string]string
+}{queries: make(map[string]string), names: make(map[string]string)}
+
+// RegisterQuery registers query under name, making it available to the Named method of the builders of the drivers. It
+// is meant to be called during initialization, like from an init function, and panics if name is empty or already
+// registered, or query is empty.
+func RegisterQuery(name, query string) {
+	if err := registerQuery(name, query); err != nil {
+		panic(err)
+	}
+}
+
+// RegisterQueries registers every .sql file in dir of fsys, typically embedded with go:embed, as a query named after the
+// file without its extension. A name that is already registered fails the registration of the remaining files.
+func RegisterQueries(fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return err
+		}
+		if err = registerQuery(strings.TrimSuffix(entry.Name(), ".sql"), string(content)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// registerQuery registers query under name.
+func registerQuery(name, query string) error {
+	if name == "" {
+		return errors.New("octobe: query name is empty")
+	}
+	if strings.TrimSpace(query) == "" {
+		return fmt.Errorf("octobe: query %q is empty", name)
+	}
+	catalog.Lock()
+	defer catalog.Unlock()
+	if _, ok := catalog.queries[name]; ok {
+		return fmt.Errorf("octobe: query %q is already registered", name)
+	}
+	catalog.queries[name] = query
+	if _, ok := catalog.names[query]; !ok {
+		catalog.names[query] = name
+	}
+	return nil
+}
+
+// Query returns the query registered under name.
+func Query(name string) (string, error) {
+	catalog.RLock()
+	defer catalog.RUnlock()
+	query, ok := catalog.queries[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnknownQuery, name)
+	}
+	return query, nil
+}
+
+// MustQuery returns the query registered under name, it panics if there is none. It backs the Named method of the
+// builders, an unknown name is a programming error like a typo in a query.
+func MustQuery(name string) string {
+	query, err := Query(name)
+	if err != nil {
+		panic(err)
+	}
+	return query
+}
+
+// LookupQueryName returns the name query is registered under, the name registered first if it is registered under
+// several names.
+func LookupQueryName(query string) (string, bool) {
+	catalog.RLock()
+	defer catalog.RUnlock()
+	name, ok := catalog.names[query]
+	return name, ok
+}
+
+// QueryNames returns the names of all registered queries in order.
+func QueryNames() []string {
+	catalog.RLock()
+	defer catalog.RUnlock()
+	names := make([]string, 0, len(catalog.queries))
+	for name := range catalog.queries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// QueryValidator is implemented by drivers that can check a query against the database without executing it, like by
+// preparing it.
+type QueryValidator interface {
+	ValidateQuery(ctx context.Context, query string) error
+}
+
+// ValidateQueries checks the registered queries with the given names against the database, or all registered queries
+// if no names are given, so that typos and references to missing tables or columns fail at startup rather than at the
+// first execution. Applications using several databases should pass the names of the queries meant for this instance.
+// The returned error joins the errors of all invalid queries, it is ErrValidationUnsupported if the driver does not
+// implement QueryValidator.
+func (ob *Octobe[DRIVER, CONFIG, BUILDER]) ValidateQueries(ctx context.Context, names ...string) error {
+	validator, ok := ob.driver.(QueryValidator)
+	if !ok {
+		return ErrValidationUnsupported
+	}
+	if len(names) == 0 {
+		names = QueryNames()
+	}
+
+	var errs []error
+	for _, name := range names {
+		query, err := Query(name)
+		if err == nil {
+			err = validator.ValidateQuery(ctx, query)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("query %q: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
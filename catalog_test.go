@@ -0,0 +1,79 @@
+package octobe_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ponrove/octobe"
+	"github.com/stretchr/testify/require"
+)
+
+// validatingDriver is a fake driver that rejects queries containing a typo.
+type validatingDriver struct {
+	*fakeDriver
+	validated []string
+}
+
+func (d *validatingDriver) ValidateQuery(_ context.Context, query string) error {
+	d.validated = append(d.validated, query)
+	if strings.Contains(query, "SELCT") {
+		return errors.New("syntax error")
+	}
+	return nil
+}
+
+func TestRegisterQuery(t *testing.T) {
+	octobe.RegisterQuery("catalog_test_product", "SELECT name FROM products WHERE id = $1")
+
+	query, err := octobe.Query("catalog_test_product")
+	require.NoError(t, err)
+	name, ok := octobe.LookupQueryName(query)
+	require.True(t, ok)
+	require.Equal(t, "catalog_test_product", name)
+	require.Equal(t, "SELECT name FROM products WHERE id = $1", query)
+	require.Contains(t, octobe.QueryNames(), "catalog_test_product")
+
+	_, err = octobe.Query("catalog_test_missing")
+	require.ErrorIs(t, err, octobe.ErrUnknownQuery)
+	require.Panics(t, func() { octobe.MustQuery("catalog_test_missing") })
+	require.Panics(t, func() { octobe.RegisterQuery("catalog_test_product", "SELECT 1") })
+	require.Panics(t, func() { octobe.RegisterQuery("catalog_test_empty", " ") })
+}
+
+func TestRegisterQueries(t *testing.T) {
+	fsys := fstest.MapFS{
+		"queries/catalog_test_fs_a.sql": {Data: []byte("SELECT 1")},
+		"queries/catalog_test_fs_b.sql": {Data: []byte("SELECT 2")},
+		"queries/README.md":             {Data: []byte("not a query")},
+	}
+	require.NoError(t, octobe.RegisterQueries(fsys, "queries"))
+
+	query, err := octobe.Query("catalog_test_fs_b")
+	require.NoError(t, err)
+	require.Equal(t, "SELECT 2", query)
+	require.Error(t, octobe.RegisterQueries(fsys, "queries"))
+	require.Error(t, octobe.RegisterQueries(fsys, "missing"))
+}
+
+func TestValidateQueries(t *testing.T) {
+	ctx := context.Background()
+	octobe.RegisterQuery("catalog_test_valid", "SELECT id FROM products")
+	octobe.RegisterQuery("catalog_test_typo", "SELCT id FROM products")
+
+	d := &validatingDriver{fakeDriver: &fakeDriver{}}
+	ob, err := octobe.New(func() (octobe.Driver[fakeDriver, fakeConfig, string], error) { return d, nil })
+	require.NoError(t, err)
+
+	err = ob.ValidateQueries(ctx, "catalog_test_valid", "catalog_test_typo", "catalog_test_missing")
+	require.ErrorContains(t, err, `query "catalog_test_typo": syntax error`)
+	require.ErrorIs(t, err, octobe.ErrUnknownQuery)
+	require.NotContains(t, err.Error(), "catalog_test_valid")
+	require.Equal(t, []string{"SELECT id FROM products", "SELCT id FROM products"}, d.validated)
+
+	ob, err = octobe.New((&fakeDriver{}).open())
+	require.NoError(t, err)
+	require.ErrorIs(t, ob.ValidateQueries(ctx), octobe.ErrValidationUnsupported)
+}
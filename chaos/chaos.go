@@ -0,0 +1,190 @@
+// Package chaos injects faults into an Octobe instance, so the retry and rollback behavior of handlers can be tested
+// without touching the real database. An Injector wraps the driver of an instance to inject latency and errors when
+// sessions begin, commit or roll back, and is a query hook injecting latency and errors into queries.
+//
+//	injector := chaos.New(
+//		chaos.Fail(chaos.Commit, chaos.Probability(0.1), chaos.ErrConnectionLost),
+//		chaos.Delay(chaos.Query, chaos.Every(5), 200*time.Millisecond),
+//	)
+//	ob, err := octobe.New(chaos.Open(injector, postgres.OpenPGXPool(dsn)), octobe.WithQueryHook(injector))
+package chaos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ponrove/octobe"
+)
+
+var (
+	// ErrInjected is the error injected by default, every fault injected with an error matching it is retryable for
+	// octobe.WithTxRetry, except at CommitResult.
+	ErrInjected = errors.New("chaos: injected fault")
+	// ErrConnectionLost is an injected error imitating a connection to the database that was lost.
+	ErrConnectionLost = fmt.Errorf("%w: connection lost", ErrInjected)
+)
+
+// Point is where a fault is injected.
+type Point string
+
+// Points faults can be injected at.
+const (
+	// Begin fails or delays beginning a session.
+	Begin Point = "begin"
+	// Commit fails or delays committing a transaction, a failed commit rolls the transaction back.
+	Commit Point = "commit"
+	// CommitResult fails the commit of a transaction after it has been committed, like a connection that was lost
+	// before the commit was acknowledged, which handlers that are not idempotent could apply twice if retried. It is
+	// not classified as retryable.
+	CommitResult Point = "commit_result"
+	// Rollback fails or delays rolling back a transaction, a failed rollback still rolls the transaction back.
+	Rollback Point = "rollback"
+	// Ping fails or delays pinging the database.
+	Ping Point = "ping"
+	// Query fails or delays queries, the Injector must be added with octobe.WithQueryHook. A failed query is cancelled
+	// before it is sent, with the injected error as the cause of its context, so the driver reports it like a query
+	// whose context was cancelled.
+	Query Point = "query"
+)
+
+// Trigger decides whether a fault is injected into the nth call at a point, n starts at 1.
+type Trigger func(n int64) bool
+
+// Always injects a fault into every call.
+func Always() Trigger {
+	return func(int64) bool { return true }
+}
+
+// Probability injects a fault into a call with probability p, between 0 and 1.
+func Probability(p float64) Trigger {
+	return func(int64) bool { return rand.Float64() < p }
+}
+
+// Every injects a fault into every nth call.
+func Every(n int64) Trigger {
+	return func(call int64) bool { return n > 0 && call%n == 0 }
+}
+
+// Calls injects a fault into the calls with the given numbers, like Calls(1, 2) for the first two calls.
+func Calls(calls ...int64) Trigger {
+	return func(call int64) bool { return slices.Contains(calls, call) }
+}
+
+// rule is a fault injected at a point.
+type rule struct {
+	point   Point
+	trigger Trigger
+	err     error
+	latency time.Duration
+}
+
+// Option configures an Injector.
+type Option func(i *Injector)
+
+// Fail fails the calls at point selected by trigger with err, ErrInjected if err is nil.
+func Fail(point Point, trigger Trigger, err error) Option {
+	if err == nil {
+		err = ErrInjected
+	}
+	return func(i *Injector) {
+		i.rules = append(i.rules, rule{point: point, trigger: trigger, err: err})
+	}
+}
+
+// Delay delays the calls at point selected by trigger by latency, or until their context is done.
+func Delay(point Point, trigger Trigger, latency time.Duration) Option {
+	return func(i *Injector) {
+		i.rules = append(i.rules, rule{point: point, trigger: trigger, latency: latency})
+	}
+}
+
+// WithClock sets the clock latency is measured with, octobe.SystemClock by default.
+func WithClock(clock octobe.Clock) Option {
+	return func(i *Injector) {
+		i.clock = clock
+	}
+}
+
+// Injector injects faults according to its rules, it is safe for concurrent use.
+type Injector struct {
+	rules    []rule
+	clock    octobe.Clock
+	disabled atomic.Bool
+	injected atomic.Int64
+
+	mu    sync.Mutex
+	calls map[Point]int64
+}
+
+// Ensure Injector can inject faults into queries.
+var _ octobe.QueryHook = &Injector{}
+
+// New creates an injector with the given rules.
+func New(opts ...Option) *Injector {
+	i := &Injector{calls: make(map[Point]int64)}
+	for _, opt := range opts {
+		opt(i)
+	}
+	if i.clock == nil {
+		i.clock = octobe.SystemClock
+	}
+	return i
+}
+
+// SetEnabled turns the injection of faults on or off, it is on when the injector is created. Calls are not counted
+// while it is off.
+func (i *Injector) SetEnabled(enabled bool) {
+	i.disabled.Store(!enabled)
+}
+
+// Injected returns the number of faults injected so far, failures and delays alike.
+func (i *Injector) Injected() int64 {
+	return i.injected.Load()
+}
+
+// inject applies the rules of point to a call, it waits for the delays selected and returns the error of the first
+// failure selected.
+func (i *Injector) inject(ctx context.Context, point Point) error {
+	if i.disabled.Load() {
+		return nil
+	}
+	i.mu.Lock()
+	i.calls[point]++
+	n := i.calls[point]
+	i.mu.Unlock()
+
+	for _, r := range i.rules {
+		if r.point != point || !r.trigger(n) {
+			continue
+		}
+		i.injected.Add(1)
+		if r.err != nil {
+			return r.err
+		}
+		select {
+		case <-i.clock.After(r.latency):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// BeforeQuery injects the faults of the Query point, a failure cancels the context the query is performed with.
+func (i *Injector) BeforeQuery(ctx context.Context, _ *octobe.QueryEvent) context.Context {
+	if err := i.inject(ctx, Query); err != nil {
+		ctx, cancel := context.WithCancelCause(ctx)
+		cancel(err)
+		return ctx
+	}
+	return ctx
+}
+
+// AfterQuery does nothing, faults are injected before queries are performed.
+func (i *Injector) AfterQuery(context.Context, *octobe.QueryEvent) {}
@@ -0,0 +1,143 @@
+package chaos_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/chaos"
+	"github.com/ponrove/octobe/driver/postgres"
+	"github.com/stretchr/testify/require"
+)
+
+// clock records the delays waited for and returns right away.
+type clock struct {
+	waits []time.Duration
+}
+
+func (c *clock) Now() time.Time { return time.Time{} }
+
+func (c *clock) After(d time.Duration) <-chan time.Time {
+	c.waits = append(c.waits, d)
+	ch := make(chan time.Time, 1)
+	ch <- time.Time{}
+	return ch
+}
+
+func update(session octobe.BuilderSession[postgres.Builder]) error {
+	_, err := session.Builder()("UPDATE products SET price = 0").Exec()
+	return err
+}
+
+func TestCommitFault(t *testing.T) {
+	ctx := context.Background()
+	mock, err := pgxmock.NewConn()
+	require.NoError(t, err)
+	defer mock.Close(ctx)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE products").WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE products").WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectCommit()
+
+	injector := chaos.New(chaos.Fail(chaos.Commit, chaos.Calls(1), chaos.ErrConnectionLost))
+	ob, err := octobe.New(chaos.Open(injector, postgres.OpenPGXWithConn(mock)), octobe.WithTxRetry(2, nil))
+	require.NoError(t, err)
+
+	require.NoError(t, ob.StartTransaction(ctx, update, postgres.WithPGXTxOptions(postgres.PGXTxOptions{})))
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.Equal(t, int64(1), injector.Injected())
+}
+
+func TestCommitResultFault(t *testing.T) {
+	ctx := context.Background()
+	mock, err := pgxmock.NewConn()
+	require.NoError(t, err)
+	defer mock.Close(ctx)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE products").WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectCommit()
+
+	// The transaction was committed, so it is not retried.
+	injector := chaos.New(chaos.Fail(chaos.CommitResult, chaos.Always(), nil))
+	ob, err := octobe.New(chaos.Open(injector, postgres.OpenPGXWithConn(mock)), octobe.WithTxRetry(2, nil))
+	require.NoError(t, err)
+
+	err = ob.StartTransaction(ctx, update, postgres.WithPGXTxOptions(postgres.PGXTxOptions{}))
+	require.ErrorIs(t, err, chaos.ErrInjected)
+	require.NoError(t, mock.ExpectationsWereMet(), "the transaction was committed")
+}
+
+func TestQueryFault(t *testing.T) {
+	ctx := context.Background()
+	mock, err := pgxmock.NewConn()
+	require.NoError(t, err)
+	defer mock.Close(ctx)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE products").WillReturnResult(pgxmock.NewResult("UPDATE", 1)).WillDelayFor(time.Second)
+	mock.ExpectRollback()
+
+	clk := &clock{}
+	injector := chaos.New(
+		chaos.Delay(chaos.Query, chaos.Always(), time.Second),
+		chaos.Fail(chaos.Query, chaos.Every(1), nil),
+		chaos.WithClock(clk),
+	)
+	ob, err := octobe.New(chaos.Open(injector, postgres.OpenPGXWithConn(mock)), octobe.WithQueryHook(injector))
+	require.NoError(t, err)
+
+	err = ob.StartTransaction(ctx, update, postgres.WithPGXTxOptions(postgres.PGXTxOptions{}))
+	require.ErrorIs(t, err, context.Canceled)
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.Equal(t, []time.Duration{time.Second}, clk.waits)
+	require.Equal(t, int64(2), injector.Injected())
+}
+
+func TestBeginAndPingFaults(t *testing.T) {
+	ctx := context.Background()
+	mock, err := pgxmock.NewConn()
+	require.NoError(t, err)
+	defer mock.Close(ctx)
+
+	custom := errors.New("too many clients")
+	injector := chaos.New(chaos.Fail(chaos.Begin, chaos.Always(), custom), chaos.Fail(chaos.Ping, chaos.Calls(2), nil))
+	ob, err := octobe.New(chaos.Open(injector, postgres.OpenPGXWithConn(mock)))
+	require.NoError(t, err)
+
+	_, err = ob.Begin(ctx)
+	require.ErrorIs(t, err, custom)
+
+	mock.ExpectPing()
+	require.NoError(t, ob.Ping(ctx))
+	require.ErrorIs(t, ob.Ping(ctx), chaos.ErrInjected)
+
+	injector.SetEnabled(false)
+	mock.ExpectBegin()
+	_, err = ob.Begin(ctx, postgres.WithPGXTxOptions(postgres.PGXTxOptions{}))
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.True(t, ob.Capabilities().Savepoints)
+}
+
+func TestTriggers(t *testing.T) {
+	var every, calls []int64
+	for n := int64(1); n <= 6; n++ {
+		if chaos.Every(3)(n) {
+			every = append(every, n)
+		}
+		if chaos.Calls(1, 4)(n) {
+			calls = append(calls, n)
+		}
+	}
+	require.Equal(t, []int64{3, 6}, every)
+	require.Equal(t, []int64{1, 4}, calls)
+	require.False(t, chaos.Probability(0)(1))
+	require.True(t, chaos.Probability(1)(1))
+}
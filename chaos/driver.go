@@ -0,0 +1,191 @@
+package chaos
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ponrove/octobe"
+)
+
+// Open wraps the driver opened by open to inject the faults of injector when sessions begin, commit or roll back and
+// when the database is pinged. The optional interfaces of the driver are forwarded, errors matching ErrInjected are
+// classified as retryable in addition to the errors the driver classifies as retryable, except for faults injected at
+// CommitResult, as the transaction was committed.
+func Open[DRIVER any, CONFIG any, BUILDER any](injector *Injector, open octobe.Open[DRIVER, CONFIG, BUILDER]) octobe.Open[DRIVER, CONFIG, BUILDER] {
+	return func() (octobe.Driver[DRIVER, CONFIG, BUILDER], error) {
+		d, err := open()
+		if err != nil {
+			return nil, err
+		}
+		return &chaosDriver[DRIVER, CONFIG, BUILDER]{Driver: d, injector: injector}, nil
+	}
+}
+
+// chaosDriver injects faults into a driver.
+type chaosDriver[DRIVER any, CONFIG any, BUILDER any] struct {
+	octobe.Driver[DRIVER, CONFIG, BUILDER]
+	injector *Injector
+}
+
+// Ensure chaosDriver forwards the optional interfaces of its driver.
+var (
+	_ octobe.Describer             = &chaosDriver[any, any, any]{}
+	_ octobe.CapabilityReporter    = &chaosDriver[any, any, any]{}
+	_ octobe.RetryClassifier       = &chaosDriver[any, any, any]{}
+	_ octobe.CommitRetryClassifier = &chaosDriver[any, any, any]{}
+	_ octobe.StatsReporter         = &chaosDriver[any, any, any]{}
+)
+
+// Begin begins a session of the driver, unless a fault is injected.
+func (d *chaosDriver[DRIVER, CONFIG, BUILDER]) Begin(ctx context.Context, opts ...octobe.Option[CONFIG]) (octobe.Session[BUILDER], error) {
+	if err := d.injector.inject(ctx, Begin); err != nil {
+		return nil, err
+	}
+	session, err := d.Driver.Begin(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &chaosSession[BUILDER]{Session: session, ctx: ctx, injector: d.injector}, nil
+}
+
+// BeginsTransaction reports whether opts begin a transaction like the driver if it implements
+// octobe.TransactionReporter, and assumes they do otherwise.
+func (d *chaosDriver[DRIVER, CONFIG, BUILDER]) BeginsTransaction(opts ...octobe.Option[CONFIG]) bool {
+	reporter, ok := d.Driver.(octobe.TransactionReporter[CONFIG])
+	return !ok || reporter.BeginsTransaction(opts...)
+}
+
+// Ping pings the database, unless a fault is injected.
+func (d *chaosDriver[DRIVER, CONFIG, BUILDER]) Ping(ctx context.Context) error {
+	if err := d.injector.inject(ctx, Ping); err != nil {
+		return err
+	}
+	return d.Driver.Ping(ctx)
+}
+
+// Describe describes the driver if it implements octobe.Describer.
+func (d *chaosDriver[DRIVER, CONFIG, BUILDER]) Describe() octobe.DriverInfo {
+	if describer, ok := d.Driver.(octobe.Describer); ok {
+		return describer.Describe()
+	}
+	return octobe.DriverInfo{}
+}
+
+// Capabilities reports the capabilities of the driver if it implements octobe.CapabilityReporter.
+func (d *chaosDriver[DRIVER, CONFIG, BUILDER]) Capabilities() octobe.Capabilities {
+	if reporter, ok := d.Driver.(octobe.CapabilityReporter); ok {
+		return reporter.Capabilities()
+	}
+	return octobe.Capabilities{}
+}
+
+// Retryable reports injected faults as retryable, and other errors like the driver if it implements
+// octobe.RetryClassifier.
+func (d *chaosDriver[DRIVER, CONFIG, BUILDER]) Retryable(err error) bool {
+	if errors.Is(err, ErrInjected) {
+		return true
+	}
+	classifier, ok := d.Driver.(octobe.RetryClassifier)
+	return ok && classifier.Retryable(err)
+}
+
+// RetryableCommit reports faults injected at Commit as retryable, as they roll the transaction back, and other errors
+// like the driver if it implements octobe.CommitRetryClassifier.
+func (d *chaosDriver[DRIVER, CONFIG, BUILDER]) RetryableCommit(err error) bool {
+	var rolledBack rolledBackError
+	if errors.Is(err, ErrInjected) && errors.As(err, &rolledBack) {
+		return true
+	}
+	classifier, ok := d.Driver.(octobe.CommitRetryClassifier)
+	return ok && classifier.RetryableCommit(err)
+}
+
+// Stats returns the statistics of the driver if it implements octobe.StatsReporter.
+func (d *chaosDriver[DRIVER, CONFIG, BUILDER]) Stats() any {
+	if reporter, ok := d.Driver.(octobe.StatsReporter); ok {
+		return reporter.Stats()
+	}
+	return nil
+}
+
+// chaosSession injects faults into a session of a driver.
+type chaosSession[BUILDER any] struct {
+	octobe.Session[BUILDER]
+	ctx      context.Context
+	injector *Injector
+}
+
+// Ensure chaosSession forwards the transaction and savepoints of its session.
+var (
+	_ octobe.Transactional = &chaosSession[any]{}
+	_ octobe.Savepoints    = &chaosSession[any]{}
+)
+
+// Commit commits the session, unless a fault is injected, which rolls the transaction back instead.
+func (s *chaosSession[BUILDER]) Commit() error {
+	if err := s.injector.inject(s.ctx, Commit); err != nil {
+		return errors.Join(rolledBackError{err}, s.Session.Rollback())
+	}
+	if err := s.Session.Commit(); err != nil {
+		return err
+	}
+	return s.injector.inject(s.ctx, CommitResult)
+}
+
+// rolledBackError is a fault injected at Commit, which rolled the transaction back instead of committing it.
+type rolledBackError struct {
+	error
+}
+
+// Unwrap returns the injected error.
+func (e rolledBackError) Unwrap() error {
+	return e.error
+}
+
+// Rollback rolls the session back, reporting an injected fault afterwards.
+func (s *chaosSession[BUILDER]) Rollback() error {
+	injected := s.injector.inject(s.ctx, Rollback)
+	return errors.Join(s.Session.Rollback(), injected)
+}
+
+// InTransaction reports whether the session runs in a transaction, if it implements octobe.Transactional.
+func (s *chaosSession[BUILDER]) InTransaction() bool {
+	t, ok := s.Session.(octobe.Transactional)
+	return ok && t.InTransaction()
+}
+
+// Savepoint creates a savepoint if the session supports savepoints.
+func (s *chaosSession[BUILDER]) Savepoint(name string) error {
+	savepoints, err := s.savepoints()
+	if err != nil {
+		return err
+	}
+	return savepoints.Savepoint(name)
+}
+
+// RollbackToSavepoint rolls back to a savepoint if the session supports savepoints.
+func (s *chaosSession[BUILDER]) RollbackToSavepoint(name string) error {
+	savepoints, err := s.savepoints()
+	if err != nil {
+		return err
+	}
+	return savepoints.RollbackToSavepoint(name)
+}
+
+// ReleaseSavepoint releases a savepoint if the session supports savepoints.
+func (s *chaosSession[BUILDER]) ReleaseSavepoint(name string) error {
+	savepoints, err := s.savepoints()
+	if err != nil {
+		return err
+	}
+	return savepoints.ReleaseSavepoint(name)
+}
+
+// savepoints returns the session as octobe.Savepoints, or errors.ErrUnsupported if it does not support savepoints.
+func (s *chaosSession[BUILDER]) savepoints() (octobe.Savepoints, error) {
+	savepoints, ok := s.Session.(octobe.Savepoints)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+	return savepoints, nil
+}
@@ -0,0 +1,86 @@
+package octobe
+
+import (
+	"context"
+	"time"
+)
+
+// Clock is the source of time of an instance. Query and session events are timed with it, and the waits between
+// transaction retries, the pings of WaitForReady, the deadline of WithSessionTimeout and the grace period of
+// WithCloseGracePeriod are measured with it, so tests can advance time deterministically instead of sleeping.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// After returns a channel that receives the current time once d has elapsed.
+	After(d time.Duration) <-chan time.Time
+}
+
+// SystemClock is the Clock of the time package, it is the clock of instances created without WithClock.
+var SystemClock Clock = systemClock{}
+
+// systemClock implements Clock with the time package.
+type systemClock struct{}
+
+// Now returns time.Now.
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// After returns time.After.
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// ContextWithTimeout returns a copy of ctx that is cancelled with cause once timeout has elapsed on clock, like
+// context.WithTimeoutCause, which it uses for SystemClock. Other clocks are waited on by a goroutine, which returns when
+// the context is done. Its Err reports context.DeadlineExceeded once expired, so packages taking a Clock can bound their
+// waits by it.
+func ContextWithTimeout(ctx context.Context, clock Clock, timeout time.Duration, cause error) (context.Context, context.CancelFunc) {
+	if clock == SystemClock {
+		return context.WithTimeoutCause(ctx, timeout, cause)
+	}
+
+	deadline := clock.Now().Add(timeout)
+	if parent, ok := ctx.Deadline(); ok && parent.Before(deadline) {
+		deadline = parent
+	}
+	expired := clock.After(timeout)
+	ctx, cancel := context.WithCancelCause(ctx)
+	go func() {
+		select {
+		case <-expired:
+			cancel(cause)
+		case <-ctx.Done():
+		}
+	}()
+	return &clockContext{Context: ctx, deadline: deadline, cause: cause}, func() { cancel(context.Canceled) }
+}
+
+// clockContext is the context of ContextWithTimeout for clocks other than SystemClock, reporting its deadline and expiry like
+// the contexts of context.WithTimeoutCause.
+type clockContext struct {
+	context.Context
+	deadline time.Time
+	cause    error
+}
+
+// Deadline returns the time the context expires at on its clock.
+func (c *clockContext) Deadline() (time.Time, bool) {
+	return c.deadline, true
+}
+
+// Err returns context.DeadlineExceeded once the context expired, and the error of the context otherwise.
+func (c *clockContext) Err() error {
+	err := c.Context.Err()
+	if err != nil && context.Cause(c.Context) == c.cause {
+		return context.DeadlineExceeded
+	}
+	return err
+}
+
+// WithClock sets the clock of the instance, SystemClock by default.
+func WithClock(clock Clock) InstanceOption {
+	return func(cfg *instanceConfig) {
+		cfg.clock = clock
+	}
+}
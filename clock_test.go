@@ -0,0 +1,98 @@
+package octobe_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ponrove/octobe"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a clock that only moves when advanced, waiting on it advances it by the duration waited for.
+type fakeClock struct {
+	mu    sync.Mutex
+	now   time.Time
+	waits []time.Duration
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	c.waits = append(c.waits, d)
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestWithClock(t *testing.T) {
+	clock := newFakeClock()
+	var slow []octobe.QueryEvent
+	d := &fakeDriver{}
+	ob, err := octobe.New(d.open(), octobe.WithClock(clock),
+		octobe.WithSlowQueryThreshold(time.Second, func(_ context.Context, event octobe.QueryEvent) {
+			slow = append(slow, event)
+		}))
+	require.NoError(t, err)
+
+	_, err = ob.Begin(context.Background(), withoutTx())
+	require.NoError(t, err)
+
+	_, done := octobe.BeginQuery(d.sessions[0].ctx, octobe.OperationQuery, "SELECT 1", nil)
+	clock.Advance(time.Second)
+	done(1, nil)
+	_, done = octobe.BeginQuery(d.sessions[0].ctx, octobe.OperationQuery, "SELECT 2", nil)
+	clock.Advance(2 * time.Second)
+	done(1, nil)
+
+	require.Len(t, slow, 1)
+	require.Equal(t, "SELECT 2", slow[0].Query)
+	require.Equal(t, clock.Now().Add(-2*time.Second), slow[0].Start)
+	require.Equal(t, 2*time.Second, slow[0].Duration)
+}
+
+func TestWithClockWaitForReady(t *testing.T) {
+	clock := newFakeClock()
+	d := &fakeDriver{pingErr: errors.New("connection refused")}
+	ob, err := octobe.New(d.open(), octobe.WithClock(clock))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		for {
+			clock.mu.Lock()
+			waited := len(clock.waits)
+			clock.mu.Unlock()
+			if waited >= 4 {
+				cancel()
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	err = ob.WaitForReady(ctx, octobe.ExponentialBackoff(time.Second, time.Minute))
+	require.ErrorIs(t, err, context.Canceled)
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	require.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second}, clock.waits[:4])
+}
@@ -0,0 +1,77 @@
+// Command octobe is the command line tool of Octobe. Its gen command generates typed handlers from annotated .sql
+// files, see the gen package for the annotations:
+//
+//	octobe gen [-driver postgres|clickhouse] [-package name] [-out file] [dir]
+//
+// The queries are read from the .sql files in dir, the current directory by default, and the handlers are written to
+// queries.gen.go in dir, in a package named after dir. It is typically run with go generate:
+//
+//	//go:generate go run github.com/ponrove/octobe/cmd/octobe gen
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ponrove/octobe/gen"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, "octobe:", err)
+		os.Exit(1)
+	}
+}
+
+// run runs the command given by args.
+func run(args []string, stderr io.Writer) error {
+	if len(args) == 0 || args[0] != "gen" {
+		fmt.Fprintln(stderr, "usage: octobe gen [flags] [dir]")
+		return fmt.Errorf("unknown command, expected gen")
+	}
+
+	flags := flag.NewFlagSet("gen", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	driver := flags.String("driver", string(gen.Postgres), "driver to generate handlers for, postgres or clickhouse")
+	pkg := flags.String("package", "", "package name of the generated file, the name of the directory by default")
+	out := flags.String("out", "", "path of the generated file, queries.gen.go in the directory by default")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	dir := "."
+	switch flags.NArg() {
+	case 0:
+	case 1:
+		dir = flags.Arg(0)
+	default:
+		return fmt.Errorf("gen takes at most one directory")
+	}
+	if *out == "" {
+		*out = filepath.Join(dir, "queries.gen.go")
+	}
+	if *pkg == "" {
+		abs, err := filepath.Abs(filepath.Dir(*out))
+		if err != nil {
+			return err
+		}
+		*pkg = strings.NewReplacer("-", "_", ".", "_").Replace(filepath.Base(abs))
+	}
+
+	queries, err := gen.ParseFS(os.DirFS(dir), ".")
+	if err != nil {
+		return err
+	}
+	if len(queries) == 0 {
+		return fmt.Errorf("no queries found in %s", dir)
+	}
+	src, err := gen.Generate(gen.Config{Package: *pkg, Driver: gen.Driver(*driver)}, queries)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(*out, src, 0o644)
+}
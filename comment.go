@@ -0,0 +1,103 @@
+package octobe
+
+import (
+	"context"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// CommentTags adds the tags to comment a query with to tags, reading them from the context of the query.
+type CommentTags func(ctx context.Context, tags map[string]string)
+
+// WithQueryComments appends a comment in the sqlcommenter format, like /*application='shop',route='%2Forders'*/, to
+// every query of the sessions of the instance, with the tags added by each of sources. Tools like pg_stat_statements and
+// the ClickHouse query_log keep the comment, which attributes queries to the code paths running them. The query hooks
+// see the query without the comment. Tags that vary per request, like a trace id, make every query text unique, so
+// they defeat caches keyed by the query like WithStatementCache of the postgres driver.
+func WithQueryComments(sources ...CommentTags) InstanceOption {
+	return func(cfg *instanceConfig) {
+		cfg.comments = append(cfg.comments, sources...)
+	}
+}
+
+// StaticTags returns a source of tags that are the same for every query, like the name of the application.
+func StaticTags(tags map[string]string) CommentTags {
+	tags = maps.Clone(tags)
+	return func(_ context.Context, dest map[string]string) {
+		maps.Copy(dest, tags)
+	}
+}
+
+// commentTagsKey is the context key of the tags added with ContextWithCommentTags.
+type commentTagsKey struct{}
+
+// ContextWithCommentTags returns a copy of ctx carrying tags, which are added to the comment of the queries of sessions
+// begun with it through ContextTags, like the route of a request set by HTTP middleware. Tags of ctx are kept unless
+// overridden.
+func ContextWithCommentTags(ctx context.Context, tags map[string]string) context.Context {
+	merged := maps.Clone(contextCommentTags(ctx))
+	if merged == nil {
+		merged = make(map[string]string, len(tags))
+	}
+	maps.Copy(merged, tags)
+	return context.WithValue(ctx, commentTagsKey{}, merged)
+}
+
+// ContextTags returns a source of the tags set on the context of a query with ContextWithCommentTags.
+func ContextTags() CommentTags {
+	return func(ctx context.Context, dest map[string]string) {
+		maps.Copy(dest, contextCommentTags(ctx))
+	}
+}
+
+// contextCommentTags returns the tags set on ctx with ContextWithCommentTags.
+func contextCommentTags(ctx context.Context) map[string]string {
+	tags, _ := ctx.Value(commentTagsKey{}).(map[string]string)
+	return tags
+}
+
+// CommentQuery is called by drivers with the context returned by BeginQuery and returns query with the comment of the
+// instance appended, see WithQueryComments. It returns query as is if the instance does not comment queries or there
+// are no tags.
+func CommentQuery(ctx context.Context, query string) string {
+	state, _ := ctx.Value(hooksKey{}).(*hookState)
+	if state == nil || len(state.comments) == 0 {
+		return query
+	}
+
+	tags := make(map[string]string)
+	for _, source := range state.comments {
+		source(ctx, tags)
+	}
+	if len(tags) == 0 {
+		return query
+	}
+
+	var b strings.Builder
+	trimmed := strings.TrimRight(query, " \t\r\n")
+	statement, terminated := strings.CutSuffix(trimmed, ";")
+	b.WriteString(statement)
+	b.WriteString(" /*")
+	for i, key := range slices.Sorted(maps.Keys(tags)) {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(commentEscape(key))
+		b.WriteString("='")
+		b.WriteString(commentEscape(tags[key]))
+		b.WriteByte('\'')
+	}
+	b.WriteString("*/")
+	if terminated {
+		b.WriteByte(';')
+	}
+	return b.String()
+}
+
+// commentEscape percent-encodes s as the sqlcommenter format requires, which also keeps a value from closing the
+// comment.
+func commentEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
@@ -0,0 +1,43 @@
+package octobe_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ponrove/octobe"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommentQuery(t *testing.T) {
+	d := &fakeDriver{}
+	ob, err := octobe.New(d.open(), octobe.WithQueryComments(
+		octobe.StaticTags(map[string]string{"application": "shop"}),
+		octobe.ContextTags(),
+	))
+	require.NoError(t, err)
+
+	ctx := octobe.ContextWithCommentTags(context.Background(), map[string]string{"route": "/orders/{id}", "team": "x"})
+	ctx = octobe.ContextWithCommentTags(ctx, map[string]string{"team": "it's"})
+	_, err = ob.Begin(ctx)
+	require.NoError(t, err)
+
+	queryCtx, done := octobe.BeginQuery(d.sessions[0].ctx, octobe.OperationQuery, "SELECT 1;\n", nil)
+	defer done(1, nil)
+	require.Equal(t,
+		`SELECT 1 /*application='shop',route='%2Forders%2F%7Bid%7D',team='it%27s'*/;`,
+		octobe.CommentQuery(queryCtx, "SELECT 1;\n"),
+	)
+}
+
+func TestCommentQueryWithoutComments(t *testing.T) {
+	d := &fakeDriver{}
+	ob, err := octobe.New(d.open(), octobe.WithQueryComments(octobe.ContextTags()))
+	require.NoError(t, err)
+
+	_, err = ob.Begin(context.Background())
+	require.NoError(t, err)
+
+	// Without tags the query is left as is, as it is for instances that do not comment queries.
+	require.Equal(t, "SELECT 1", octobe.CommentQuery(d.sessions[0].ctx, "SELECT 1"))
+	require.Equal(t, "SELECT 1", octobe.CommentQuery(context.Background(), "SELECT 1"))
+}
@@ -0,0 +1,37 @@
+package dberr
+
+import (
+	"errors"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+)
+
+// Codes of the ClickHouse exceptions that are classified.
+const (
+	clickHouseSocketTimeout      = 209
+	clickHouseNetworkError       = 210
+	clickHouseViolatedConstraint = 469
+)
+
+// ClickHouseCode returns the code of the ClickHouse exception in the chain of err, for errors that have no predicate.
+func ClickHouseCode(err error) (int32, bool) {
+	var exception *clickhouse.Exception
+	if !errors.As(err, &exception) {
+		return 0, false
+	}
+	return exception.Code, true
+}
+
+// hasClickHouseCode reports whether the chain of err holds a ClickHouse exception with code.
+func hasClickHouseCode(err error, code int32) bool {
+	actual, ok := ClickHouseCode(err)
+	return ok && actual == code
+}
+
+// isClickHouseConnectionError reports whether err is a network failure reported by the server, or a timeout
+// acquiring a connection from the pool of the client.
+func isClickHouseConnectionError(err error) bool {
+	return hasClickHouseCode(err, clickHouseNetworkError) ||
+		hasClickHouseCode(err, clickHouseSocketTimeout) ||
+		errors.Is(err, clickhouse.ErrAcquireConnTimeout)
+}
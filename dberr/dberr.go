@@ -0,0 +1,83 @@
+// Package dberr classifies the errors of the Octobe drivers, so application code can branch on constraint violations
+// and transient failures without importing the error types of a driver. The predicates understand PostgreSQL errors
+// of the pgx, pgxpool and database/sql drivers, ClickHouse exceptions and connection failures, also when they are
+// wrapped, as in an *octobe.QueryError.
+package dberr
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+
+	"github.com/ponrove/octobe"
+)
+
+// IsUniqueViolation reports whether err is caused by a row violating a unique constraint or index.
+func IsUniqueViolation(err error) bool {
+	return hasSQLState(err, sqlStateUniqueViolation)
+}
+
+// IsForeignKeyViolation reports whether err is caused by a row referring to a row that does not exist, or by removing
+// a row that is still referred to.
+func IsForeignKeyViolation(err error) bool {
+	return hasSQLState(err, sqlStateForeignKeyViolation)
+}
+
+// IsNotNullViolation reports whether err is caused by a null value in a column that does not allow it.
+func IsNotNullViolation(err error) bool {
+	return hasSQLState(err, sqlStateNotNullViolation)
+}
+
+// IsCheckViolation reports whether err is caused by a row failing a check constraint, in ClickHouse a CONSTRAINT of
+// the table.
+func IsCheckViolation(err error) bool {
+	return hasSQLState(err, sqlStateCheckViolation) || hasClickHouseCode(err, clickHouseViolatedConstraint)
+}
+
+// Constraint returns the name of the constraint err violated, to tell the unique indexes or foreign keys of a table
+// apart, as reported in the *octobe.ConstraintError of the drivers. It returns false if err is no constraint violation
+// or the database did not name the constraint.
+func Constraint(err error) (string, bool) {
+	var constraintErr *octobe.ConstraintError
+	if !errors.As(err, &constraintErr) || constraintErr.Constraint == "" {
+		return "", false
+	}
+	return constraintErr.Constraint, true
+}
+
+// IsSerializationFailure reports whether err is caused by a transaction that could not be serialized with concurrent
+// transactions, running the transaction again may succeed.
+func IsSerializationFailure(err error) bool {
+	return hasSQLState(err, sqlStateSerializationFailure)
+}
+
+// IsDeadlock reports whether err is caused by a transaction that was aborted to resolve a deadlock, running the
+// transaction again may succeed.
+func IsDeadlock(err error) bool {
+	return hasSQLState(err, sqlStateDeadlockDetected)
+}
+
+// IsConnectionError reports whether err is caused by failing to connect to the database or by losing the connection,
+// rather than by the query itself. A context that was cancelled or passed its deadline is not a connection error.
+func IsConnectionError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if isPostgresConnectionError(err) || isClickHouseConnectionError(err) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) ||
+		errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE)
+}
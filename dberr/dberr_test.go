@@ -0,0 +1,124 @@
+package dberr_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"syscall"
+	"testing"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/dberr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPredicates(t *testing.T) {
+	pgErr := func(code string) error {
+		return octobe.WrapQueryError("pgx", "INSERT INTO products", nil, &pgconn.PgError{Code: code})
+	}
+	chErr := func(code int32) error {
+		return fmt.Errorf("insert: %w", &clickhouse.Exception{Code: code})
+	}
+
+	tests := []struct {
+		name      string
+		predicate func(error) bool
+		matches   []error
+		others    []error
+	}{
+		{
+			name:      "unique violation",
+			predicate: dberr.IsUniqueViolation,
+			matches:   []error{pgErr("23505")},
+			others:    []error{nil, pgErr("23503"), chErr(469), errors.New("duplicate")},
+		},
+		{
+			name:      "foreign key violation",
+			predicate: dberr.IsForeignKeyViolation,
+			matches:   []error{pgErr("23503")},
+			others:    []error{pgErr("23505")},
+		},
+		{
+			name:      "not null violation",
+			predicate: dberr.IsNotNullViolation,
+			matches:   []error{pgErr("23502")},
+			others:    []error{pgErr("23514")},
+		},
+		{
+			name:      "check violation",
+			predicate: dberr.IsCheckViolation,
+			matches:   []error{pgErr("23514"), chErr(469)},
+			others:    []error{pgErr("23502"), chErr(210)},
+		},
+		{
+			name:      "serialization failure",
+			predicate: dberr.IsSerializationFailure,
+			matches:   []error{pgErr("40001")},
+			others:    []error{pgErr("40P01")},
+		},
+		{
+			name:      "deadlock",
+			predicate: dberr.IsDeadlock,
+			matches:   []error{pgErr("40P01")},
+			others:    []error{pgErr("40001")},
+		},
+		{
+			name:      "connection error",
+			predicate: dberr.IsConnectionError,
+			matches: []error{
+				fmt.Errorf("query: %w", syscall.ECONNRESET),
+				io.ErrUnexpectedEOF,
+				driver.ErrBadConn,
+				&pgconn.ConnectError{},
+				chErr(210),
+				clickhouse.ErrAcquireConnTimeout,
+			},
+			others: []error{nil, pgErr("23505"), chErr(469), context.Canceled, context.DeadlineExceeded},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, err := range tt.matches {
+				require.True(t, tt.predicate(err), "%v", err)
+			}
+			for _, err := range tt.others {
+				require.False(t, tt.predicate(err), "%v", err)
+			}
+		})
+	}
+}
+
+func TestCodes(t *testing.T) {
+	state, ok := dberr.SQLState(fmt.Errorf("exec: %w", &pgconn.PgError{Code: "42P01"}))
+	require.True(t, ok)
+	require.Equal(t, "42P01", state)
+
+	_, ok = dberr.SQLState(errors.New("boom"))
+	require.False(t, ok)
+
+	code, ok := dberr.ClickHouseCode(&clickhouse.Exception{Code: 60})
+	require.True(t, ok)
+	require.Equal(t, int32(60), code)
+}
+
+func TestConstraint(t *testing.T) {
+	err := octobe.WrapQueryError("pgx", "INSERT INTO products", nil, &octobe.ConstraintError{
+		SQLState:   "23505",
+		Constraint: "products_sku_key",
+		Err:        errors.New("duplicate key value violates unique constraint"),
+	})
+	constraint, ok := dberr.Constraint(err)
+	require.True(t, ok)
+	require.Equal(t, "products_sku_key", constraint)
+	require.True(t, dberr.IsUniqueViolation(err))
+
+	_, ok = dberr.Constraint(&octobe.ConstraintError{SQLState: "23502", Err: errors.New("null value")})
+	require.False(t, ok)
+	_, ok = dberr.Constraint(errors.New("boom"))
+	require.False(t, ok)
+}
@@ -0,0 +1,46 @@
+package dberr
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/driver/postgres"
+)
+
+// SQLSTATE codes of the PostgreSQL errors that are classified.
+const (
+	sqlStateNotNullViolation     = "23502"
+	sqlStateForeignKeyViolation  = "23503"
+	sqlStateUniqueViolation      = "23505"
+	sqlStateCheckViolation       = "23514"
+	sqlStateSerializationFailure = postgres.SQLStateSerializationFailure
+	sqlStateDeadlockDetected     = postgres.SQLStateDeadlockDetected
+)
+
+// SQLState returns the SQLSTATE code of the PostgreSQL error in the chain of err, for errors that have no predicate.
+// The code of an *octobe.ConstraintError is returned if the chain holds no PostgreSQL error.
+func SQLState(err error) (string, bool) {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code, true
+	}
+	var constraintErr *octobe.ConstraintError
+	if errors.As(err, &constraintErr) {
+		return constraintErr.SQLState, true
+	}
+	return "", false
+}
+
+// hasSQLState reports whether the chain of err holds a PostgreSQL error with code.
+func hasSQLState(err error, code string) bool {
+	state, ok := SQLState(err)
+	return ok && state == code
+}
+
+// isPostgresConnectionError reports whether err is a failure to connect, or a failure before the query reached the
+// server.
+func isPostgresConnectionError(err error) bool {
+	var connectErr *pgconn.ConnectError
+	return errors.As(err, &connectErr) || pgconn.SafeToRetry(err)
+}
@@ -0,0 +1,10 @@
+package clickhouse
+
+import "github.com/ponrove/octobe"
+
+// Named builds a segment for the query registered under name with octobe.RegisterQuery or octobe.RegisterQueries. It
+// panics if no query is registered under name. The driver does not implement octobe.QueryValidator, ClickHouse cannot
+// prepare queries on the server.
+func (b Builder) Named(name string) Segment {
+	return b(octobe.MustQuery(name))
+}
@@ -1,6 +1,10 @@
 package clickhouse
 
 import (
+	"context"
+	"iter"
+	"time"
+
 	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
 	"github.com/ponrove/octobe"
 )
@@ -22,6 +26,26 @@ func Execute[RESULT any](session octobe.BuilderSession[Builder], f Handler[RESUL
 	return f(session.Builder())
 }
 
+// ExecuteAll runs handlers in order within session and returns their results, stopping at the first handler that fails,
+// see octobe.ExecuteAll.
+func ExecuteAll[RESULT any](session octobe.BuilderSession[Builder], handlers ...Handler[RESULT]) ([]RESULT, error) {
+	return octobe.ExecuteAll(session, handlerFuncs(handlers)...)
+}
+
+// ExecuteEach runs all handlers in order within session even when some fail, see octobe.ExecuteEach.
+func ExecuteEach[RESULT any](session octobe.BuilderSession[Builder], handlers ...Handler[RESULT]) ([]RESULT, error) {
+	return octobe.ExecuteEach(session, handlerFuncs(handlers)...)
+}
+
+// handlerFuncs converts handlers to the functions taken by octobe.ExecuteAll and octobe.ExecuteEach.
+func handlerFuncs[RESULT any](handlers []Handler[RESULT]) []func(Builder) (RESULT, error) {
+	funcs := make([]func(Builder) (RESULT, error), len(handlers))
+	for i, handler := range handlers {
+		funcs[i] = handler
+	}
+	return funcs
+}
+
 // Segment is an interface that represents a specific query that can be run only once. It keeps track of the query,
 // arguments, and execution state.
 type Segment interface {
@@ -29,9 +53,44 @@ type Segment interface {
 	ServerVersion() (*ServerVersion, error)
 	Select(dest any) error
 	Arguments(args ...any) Segment
+	// ArgumentsFromStruct binds the fields of struct v, mapped to columns by their db tags, to the query. Named
+	// parameters like :name are bound to the field of the same name, a query without named parameters gets the values
+	// of all fields as arguments in declaration order. An error binding v is returned when the segment is executed.
+	ArgumentsFromStruct(v any) Segment
+	// NamedArguments binds args to the named parameters of the query, like :name or @name, which are rewritten to
+	// positional parameters. Server side query parameters like {id:UInt64} are left alone. A named parameter without a
+	// value in args is returned as an error when the segment is executed.
+	NamedArguments(args map[string]any) Segment
+	// WithMaxRows limits the number of rows Query and Select may read, reading stops with a *octobe.MaxRowsError once
+	// the query returns more than n rows. A value of zero or less disables the limit.
+	WithMaxRows(n int) Segment
+	// Timeout limits the execution of the statement to d, by deriving a context with that deadline from the context of
+	// the session for just this segment. For Query the deadline covers reading the rows in the callback, PrepareBatch is
+	// not limited as its batch outlives the call. A value of zero or less disables the timeout.
+	Timeout(d time.Duration) Segment
+	// Clone returns a copy of the segment with the same query, arguments and settings that has not been executed, so a
+	// statement can be run again on purpose, as in a polling loop, without tripping octobe.ErrAlreadyUsed. The segment
+	// may be cloned before or after it is executed.
+	Clone() Segment
 	Exec() error
 	Query(cb func(Rows) error) error
 	QueryRow(dest ...any) error
+	// Rows executes the query like Query and returns an iterator over its rows for a range loop. Every row is yielded
+	// with a nil error, an error executing the query or reading the rows is yielded last with a nil row. The rows are
+	// closed when the loop ends, also when it is left early with break or return. The query is executed once the loop
+	// starts, the iterator cannot be used again.
+	Rows() iter.Seq2[Row, error]
+	// QueryRowMap returns the first row of the result as a map keyed by column name, it is meant for tooling where the
+	// columns are not known at compile time. It returns sql.ErrNoRows if the result is empty.
+	QueryRowMap() (map[string]any, error)
+	// QueryMaps returns all rows of the result as maps keyed by column name.
+	QueryMaps() ([]map[string]any, error)
+	// QueryRowStruct reads the first row of the result into the struct dest points to, mapping columns to fields by
+	// their db tags. It returns sql.ErrNoRows if the result is empty, and an error if a column does not map to a field.
+	QueryRowStruct(dest any) error
+	// QueryStructs reads all rows of the result into the slice of structs or struct pointers dest points to, mapping
+	// columns to fields by their db tags. The contents of the slice are replaced.
+	QueryStructs(dest any) error
 	PrepareBatch(opts ...PrepareBatchOption) (Batch, error)
 	AsyncInsert(wait bool, args ...any) error
 }
@@ -50,3 +109,11 @@ type PrepareBatchOption = driver.PrepareBatchOption
 
 // Batch is a type that represents a batch of queries to be executed together.
 type Batch = driver.Batch
+
+// withTimeout derives a context with a timeout of d from ctx, or returns ctx as is if d is zero or less.
+func withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, d)
+}
@@ -0,0 +1,22 @@
+package clickhouse
+
+import "iter"
+
+// Rows executes the query and returns an iterator over its rows.
+func (s *nativeSegment) Rows() iter.Seq2[Row, error] {
+	return func(yield func(Row, error) bool) {
+		stopped := false
+		err := s.Query(func(rows Rows) error {
+			for rows.Next() {
+				if !yield(rows, nil) {
+					stopped = true
+					return nil
+				}
+			}
+			return rows.Err()
+		})
+		if err != nil && !stopped {
+			yield(nil, err)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package clickhouse
+
+// Middleware wraps a handler to add behaviour around it, like timing, retries, validation or caching, without knowing
+// the result the handler returns.
+type Middleware[RESULT any] func(next Handler[RESULT]) Handler[RESULT]
+
+// WrapHandler wraps f with middlewares. The first middleware is the outermost, it runs first and sees the result of all
+// others.
+func WrapHandler[RESULT any](f Handler[RESULT], middlewares ...Middleware[RESULT]) Handler[RESULT] {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		f = middlewares[i](f)
+	}
+	return f
+}
+
+// Chain composes middlewares into a single middleware, which can be shared between handlers. The first middleware is
+// the outermost.
+func Chain[RESULT any](middlewares ...Middleware[RESULT]) Middleware[RESULT] {
+	return func(next Handler[RESULT]) Handler[RESULT] {
+		return WrapHandler(next, middlewares...)
+	}
+}
@@ -0,0 +1,45 @@
+package clickhouse_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ponrove/octobe/driver/clickhouse"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrapHandler(t *testing.T) {
+	var calls []string
+	trace := func(name string) clickhouse.Middleware[int] {
+		return func(next clickhouse.Handler[int]) clickhouse.Handler[int] {
+			return func(builder clickhouse.Builder) (int, error) {
+				calls = append(calls, "before "+name)
+				result, err := next(builder)
+				calls = append(calls, "after "+name)
+				return result + 1, err
+			}
+		}
+	}
+	handler := func(clickhouse.Builder) (int, error) {
+		calls = append(calls, "handler")
+		return 1, nil
+	}
+
+	result, err := clickhouse.WrapHandler(handler, clickhouse.Chain(trace("a"), trace("b")), trace("c"))(nil)
+	require.NoError(t, err)
+	require.Equal(t, 4, result)
+	require.Equal(t, []string{"before a", "before b", "before c", "handler", "after c", "after b", "after a"}, calls)
+
+	errValidation := errors.New("invalid")
+	validate := func(next clickhouse.Handler[int]) clickhouse.Handler[int] {
+		return func(clickhouse.Builder) (int, error) {
+			return 0, errValidation
+		}
+	}
+	_, err = clickhouse.WrapHandler(handler, validate)(nil)
+	require.ErrorIs(t, err, errValidation)
+
+	result, err = clickhouse.WrapHandler(handler)(nil)
+	require.NoError(t, err)
+	require.Equal(t, 1, result)
+}
@@ -78,7 +78,7 @@ func TestMock(t *testing.T) {
 
 		err = session.Builder()(query).Exec()
 		require.Error(t, err)
-		require.Equal(t, expectedErr, err)
+		require.ErrorIs(t, err, expectedErr)
 		require.NoError(t, mock.AllExpectationsMet())
 	})
 
@@ -127,7 +127,7 @@ func TestMock(t *testing.T) {
 			return nil
 		})
 		require.Error(t, err)
-		require.Equal(t, expectedErr, err)
+		require.ErrorIs(t, err, expectedErr)
 		require.NoError(t, mock.AllExpectationsMet())
 	})
 
@@ -164,7 +164,7 @@ func TestMock(t *testing.T) {
 		var name string
 		err = session.Builder()(query).Arguments(1).QueryRow(&name)
 		require.Error(t, err)
-		require.Equal(t, expectedErr, err)
+		require.ErrorIs(t, err, expectedErr)
 		require.NoError(t, mock.AllExpectationsMet())
 	})
 
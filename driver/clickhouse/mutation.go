@@ -0,0 +1,192 @@
+package clickhouse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ponrove/octobe"
+)
+
+// DefaultMutationPollInterval is the interval at which system.mutations is polled when no interval is configured.
+const DefaultMutationPollInterval = time.Second
+
+// ErrMutationKilled is returned when a mutation was killed before it completed, for example with KILL MUTATION.
+var ErrMutationKilled = errors.New("mutation was killed")
+
+// MutationProgress is the state of a mutation as reported by system.mutations.
+type MutationProgress struct {
+	ID         string
+	PartsToDo  int64
+	Done       bool
+	FailReason string
+}
+
+// MutationOption is a signature for configuring ExecMutation.
+type MutationOption func(cfg *mutationConfig)
+
+// mutationConfig holds the configuration of ExecMutation.
+type mutationConfig struct {
+	timeout  time.Duration
+	interval time.Duration
+	progress func(MutationProgress)
+	clock    octobe.Clock
+}
+
+// WithMutationTimeout limits how long ExecMutation waits for the mutation to complete. The mutation itself keeps running
+// on the server when the timeout expires.
+func WithMutationTimeout(timeout time.Duration) MutationOption {
+	return func(cfg *mutationConfig) {
+		cfg.timeout = timeout
+	}
+}
+
+// WithMutationPollInterval sets the interval at which system.mutations is polled, DefaultMutationPollInterval is used if
+// not set.
+func WithMutationPollInterval(interval time.Duration) MutationOption {
+	return func(cfg *mutationConfig) {
+		cfg.interval = interval
+	}
+}
+
+// WithMutationClock sets the clock the interval between polls is measured with, octobe.SystemClock by default.
+func WithMutationClock(clock octobe.Clock) MutationOption {
+	return func(cfg *mutationConfig) {
+		cfg.clock = clock
+	}
+}
+
+// WithMutationProgress registers a callback that receives the state of the mutation after every poll.
+func WithMutationProgress(fn func(MutationProgress)) MutationOption {
+	return func(cfg *mutationConfig) {
+		cfg.progress = fn
+	}
+}
+
+// ExecMutation executes segment, an asynchronous ALTER TABLE ... UPDATE or ALTER TABLE ... DELETE on table, and blocks
+// until the mutation it created in system.mutations is done. Tables without a database qualifier are looked up in the
+// current database. The mutation is the first one that appears in system.mutations after segment is executed, which is
+// polled until it does, so a replica that has not fetched the mutation yet is not mistaken for one that completed it.
+// Mutations of other clients created afterwards are ignored, one created while segment executes is indistinguishable
+// from it. Waiting stops when ctx is done or the timeout, measured on the clock, expires, in which case the mutation
+// keeps running on the server. If the mutation is killed, ErrMutationKilled is returned, also when KILL MUTATION removed
+// it from system.mutations before it was seen as killed. A failing mutation is retried by ClickHouse, its latest fail
+// reason is reported through the progress callback.
+func ExecMutation(ctx context.Context, session octobe.BuilderSession[Builder], table string, segment Segment, opts ...MutationOption) error {
+	cfg := mutationConfig{interval: DefaultMutationPollInterval, clock: octobe.SystemClock}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.clock == nil {
+		cfg.clock = octobe.SystemClock
+	}
+
+	if cfg.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = octobe.ContextWithTimeout(ctx, cfg.clock, cfg.timeout, context.DeadlineExceeded)
+		defer cancel()
+	}
+
+	database, tableName, ok := strings.Cut(table, ".")
+	if !ok {
+		database, tableName = "", table
+	}
+
+	previous, err := mutations(ctx, cfg.clock, session, database, tableName)
+	if err != nil {
+		return fmt.Errorf("failed to read mutations of %s: %w", table, err)
+	}
+	existing := make(map[string]struct{}, len(previous))
+	for _, mutation := range previous {
+		existing[mutation.ID] = struct{}{}
+	}
+
+	if err = segment.Exec(); err != nil {
+		return err
+	}
+
+	// The ID of the mutation created by segment, once seen. If it disappears from system.mutations it was killed.
+	var id string
+	for {
+		current, err := mutations(ctx, cfg.clock, session, database, tableName)
+		if err != nil {
+			return fmt.Errorf("failed to read mutations of %s: %w", table, err)
+		}
+
+		var (
+			mutation mutationState
+			found    bool
+		)
+		for _, m := range current {
+			if _, ok := existing[m.ID]; ok {
+				continue
+			}
+			if id == "" || m.ID == id {
+				mutation, found = m, true
+				break
+			}
+		}
+		switch {
+		case found:
+			id = mutation.ID
+			if cfg.progress != nil {
+				cfg.progress(mutation.MutationProgress)
+			}
+			if mutation.killed {
+				return fmt.Errorf("%w: %s on %s", ErrMutationKilled, id, table)
+			}
+			if mutation.Done {
+				return nil
+			}
+		case id != "":
+			return fmt.Errorf("%w: %s on %s was removed", ErrMutationKilled, id, table)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("mutation on %s did not complete: %w", table, ctx.Err())
+		case <-cfg.clock.After(cfg.interval):
+		}
+	}
+}
+
+// mutationState is a row of system.mutations.
+type mutationState struct {
+	MutationProgress
+	killed bool
+}
+
+// mutations reads the mutations of a table in the order they were created. The query runs on the context of the
+// session, bounded by the deadline of ctx on clock so a hanging read does not outlast the timeout of ExecMutation.
+func mutations(ctx context.Context, clock octobe.Clock, session octobe.BuilderSession[Builder], database, table string) ([]mutationState, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var result []mutationState
+	query := session.Builder()(`
+		SELECT mutation_id, parts_to_do, is_done, is_killed, latest_fail_reason
+		FROM system.mutations
+		WHERE database = if(empty(?), currentDatabase(), ?) AND table = ?
+		ORDER BY create_time`)
+	if deadline, ok := ctx.Deadline(); ok {
+		query = query.Timeout(deadline.Sub(clock.Now()))
+	}
+	err := query.Arguments(database, database, table).Query(func(rows Rows) error {
+		for rows.Next() {
+			var (
+				state          mutationState
+				isDone, killed uint8
+			)
+			if err := rows.Scan(&state.ID, &state.PartsToDo, &isDone, &killed, &state.FailReason); err != nil {
+				return err
+			}
+			state.Done, state.killed = isDone == 1, killed == 1
+			result = append(result, state)
+		}
+		return rows.Err()
+	})
+	return result, err
+}
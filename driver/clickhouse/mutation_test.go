@@ -0,0 +1,245 @@
+package clickhouse_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/driver/clickhouse"
+	chmock "github.com/ponrove/octobe/driver/clickhouse/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func mutationRows() *chmock.MockRows {
+	return chmock.NewMockRows([]string{"mutation_id", "parts_to_do", "is_done", "is_killed", "latest_fail_reason"})
+}
+
+func TestExecMutation(t *testing.T) {
+	ctx := context.Background()
+	query := "ALTER TABLE events DELETE WHERE id = ?"
+
+	t.Run("waits for completion", func(t *testing.T) {
+		mock := chmock.NewMock()
+		mock.ExpectQuery("FROM system.mutations").WithArgs("", "", "events").
+			WillReturnRows(mutationRows().AddRow("mutation_1.txt", int64(0), uint8(1), uint8(0), ""))
+		mock.ExpectExec(query).WithArgs(1)
+		mock.ExpectQuery("FROM system.mutations").WithArgs("", "", "events").
+			WillReturnRows(mutationRows().
+				AddRow("mutation_1.txt", int64(0), uint8(1), uint8(0), "").
+				AddRow("mutation_2.txt", int64(2), uint8(0), uint8(0), ""))
+		mock.ExpectQuery("FROM system.mutations").WithArgs("", "", "events").
+			WillReturnRows(mutationRows().
+				AddRow("mutation_1.txt", int64(0), uint8(1), uint8(0), "").
+				AddRow("mutation_2.txt", int64(0), uint8(1), uint8(0), ""))
+
+		ob, err := octobe.New(clickhouse.OpenNativeWithConn(mock))
+		require.NoError(t, err)
+		session, err := ob.Begin(ctx)
+		require.NoError(t, err)
+
+		var progress []clickhouse.MutationProgress
+		clk := &clock{}
+		err = clickhouse.ExecMutation(ctx, session, "events", session.Builder()(query).Arguments(1),
+			clickhouse.WithMutationPollInterval(time.Hour),
+			clickhouse.WithMutationClock(clk),
+			clickhouse.WithMutationProgress(func(p clickhouse.MutationProgress) {
+				progress = append(progress, p)
+			}),
+		)
+		require.NoError(t, err)
+		require.Equal(t, []clickhouse.MutationProgress{
+			{ID: "mutation_2.txt", PartsToDo: 2},
+			{ID: "mutation_2.txt", Done: true},
+		}, progress)
+		require.Equal(t, []time.Duration{time.Hour}, clk.waits)
+		require.NoError(t, mock.AllExpectationsMet())
+	})
+
+	t.Run("not visible yet", func(t *testing.T) {
+		mock := chmock.NewMock()
+		mock.ExpectQuery("FROM system.mutations").WithArgs("", "", "events").WillReturnRows(mutationRows())
+		mock.ExpectExec(query).WithArgs(1)
+		// The replica has not fetched the mutation yet, which does not mean it completed.
+		mock.ExpectQuery("FROM system.mutations").WithArgs("", "", "events").WillReturnRows(mutationRows())
+		mock.ExpectQuery("FROM system.mutations").WithArgs("", "", "events").
+			WillReturnRows(mutationRows().AddRow("0000000001", int64(1), uint8(0), uint8(0), ""))
+		mock.ExpectQuery("FROM system.mutations").WithArgs("", "", "events").
+			WillReturnRows(mutationRows().AddRow("0000000001", int64(0), uint8(1), uint8(0), ""))
+
+		ob, err := octobe.New(clickhouse.OpenNativeWithConn(mock))
+		require.NoError(t, err)
+		session, err := ob.Begin(ctx)
+		require.NoError(t, err)
+
+		clk := &clock{}
+		err = clickhouse.ExecMutation(ctx, session, "events", session.Builder()(query).Arguments(1),
+			clickhouse.WithMutationClock(clk))
+		require.NoError(t, err)
+		require.Len(t, clk.waits, 2)
+		require.NoError(t, mock.AllExpectationsMet())
+	})
+
+	t.Run("ignores mutations of other clients", func(t *testing.T) {
+		mock := chmock.NewMock()
+		mock.ExpectQuery("FROM system.mutations").WithArgs("", "", "events").WillReturnRows(mutationRows())
+		mock.ExpectExec(query).WithArgs(1)
+		mock.ExpectQuery("FROM system.mutations").WithArgs("", "", "events").
+			WillReturnRows(mutationRows().AddRow("mutation_1.txt", int64(2), uint8(0), uint8(0), ""))
+		mock.ExpectQuery("FROM system.mutations").WithArgs("", "", "events").
+			WillReturnRows(mutationRows().
+				AddRow("mutation_1.txt", int64(1), uint8(0), uint8(0), "").
+				AddRow("mutation_2.txt", int64(4), uint8(0), uint8(1), ""))
+		mock.ExpectQuery("FROM system.mutations").WithArgs("", "", "events").
+			WillReturnRows(mutationRows().AddRow("mutation_1.txt", int64(0), uint8(1), uint8(0), ""))
+
+		ob, err := octobe.New(clickhouse.OpenNativeWithConn(mock))
+		require.NoError(t, err)
+		session, err := ob.Begin(ctx)
+		require.NoError(t, err)
+
+		var progress []string
+		err = clickhouse.ExecMutation(ctx, session, "events", session.Builder()(query).Arguments(1),
+			clickhouse.WithMutationClock(&clock{}),
+			clickhouse.WithMutationProgress(func(p clickhouse.MutationProgress) {
+				progress = append(progress, p.ID)
+			}),
+		)
+		require.NoError(t, err)
+		require.Equal(t, []string{"mutation_1.txt", "mutation_1.txt", "mutation_1.txt"}, progress)
+		require.NoError(t, mock.AllExpectationsMet())
+	})
+
+	t.Run("killed", func(t *testing.T) {
+		mock := chmock.NewMock()
+		mock.ExpectQuery("FROM system.mutations").WithArgs("analytics", "analytics", "events").
+			WillReturnRows(mutationRows())
+		mock.ExpectExec(query).WithArgs(1)
+		mock.ExpectQuery("FROM system.mutations").WithArgs("analytics", "analytics", "events").
+			WillReturnRows(mutationRows().AddRow("mutation_1.txt", int64(2), uint8(0), uint8(1), "Code: 341"))
+
+		ob, err := octobe.New(clickhouse.OpenNativeWithConn(mock))
+		require.NoError(t, err)
+		session, err := ob.Begin(ctx)
+		require.NoError(t, err)
+
+		err = clickhouse.ExecMutation(ctx, session, "analytics.events", session.Builder()(query).Arguments(1))
+		require.ErrorIs(t, err, clickhouse.ErrMutationKilled)
+		require.NoError(t, mock.AllExpectationsMet())
+	})
+
+	t.Run("killed and removed", func(t *testing.T) {
+		mock := chmock.NewMock()
+		mock.ExpectQuery("FROM system.mutations").WithArgs("", "", "events").WillReturnRows(mutationRows())
+		mock.ExpectExec(query).WithArgs(1)
+		mock.ExpectQuery("FROM system.mutations").WithArgs("", "", "events").
+			WillReturnRows(mutationRows().AddRow("mutation_1.txt", int64(2), uint8(0), uint8(0), ""))
+		// KILL MUTATION removes the row of the mutation.
+		mock.ExpectQuery("FROM system.mutations").WithArgs("", "", "events").WillReturnRows(mutationRows())
+
+		ob, err := octobe.New(clickhouse.OpenNativeWithConn(mock))
+		require.NoError(t, err)
+		session, err := ob.Begin(ctx)
+		require.NoError(t, err)
+
+		err = clickhouse.ExecMutation(ctx, session, "events", session.Builder()(query).Arguments(1),
+			clickhouse.WithMutationClock(&clock{}))
+		require.ErrorIs(t, err, clickhouse.ErrMutationKilled)
+		require.NoError(t, mock.AllExpectationsMet())
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		mock := chmock.NewMock()
+		mock.ExpectQuery("FROM system.mutations").WithArgs("", "", "events").WillReturnRows(mutationRows())
+		mock.ExpectExec(query).WithArgs(1)
+		mock.ExpectQuery("FROM system.mutations").WithArgs("", "", "events").
+			WillReturnRows(mutationRows().AddRow("mutation_1.txt", int64(2), uint8(0), uint8(0), ""))
+
+		// The polls are bounded by the timeout, not only the wait between them.
+		var unbounded int
+		ob, err := octobe.New(clickhouse.OpenNativeWithConn(mock), octobe.WithQueryHook(queryHook{
+			before: func(ctx context.Context, event *octobe.QueryEvent) {
+				if _, ok := ctx.Deadline(); !ok && event.Operation != octobe.OperationExec {
+					unbounded++
+				}
+			},
+		}))
+		require.NoError(t, err)
+		session, err := ob.Begin(ctx)
+		require.NoError(t, err)
+
+		err = clickhouse.ExecMutation(ctx, session, "events", session.Builder()(query).Arguments(1),
+			clickhouse.WithMutationTimeout(50*time.Millisecond),
+			clickhouse.WithMutationPollInterval(time.Hour),
+		)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.Zero(t, unbounded)
+		require.NoError(t, mock.AllExpectationsMet())
+	})
+
+	t.Run("timeout on the clock", func(t *testing.T) {
+		mock := chmock.NewMock()
+		mock.ExpectQuery("FROM system.mutations").WithArgs("", "", "events").WillReturnRows(mutationRows())
+		mock.ExpectExec(query).WithArgs(1)
+		mock.ExpectQuery("FROM system.mutations").WithArgs("", "", "events").
+			WillReturnRows(mutationRows().AddRow("mutation_1.txt", int64(2), uint8(0), uint8(0), ""))
+
+		ob, err := octobe.New(clickhouse.OpenNativeWithConn(mock))
+		require.NoError(t, err)
+		session, err := ob.Begin(ctx)
+		require.NoError(t, err)
+
+		clk := &timeoutClock{timeout: time.Minute, expired: make(chan time.Time)}
+		err = clickhouse.ExecMutation(ctx, session, "events", session.Builder()(query).Arguments(1),
+			clickhouse.WithMutationTimeout(time.Minute),
+			clickhouse.WithMutationPollInterval(time.Hour),
+			clickhouse.WithMutationClock(clk),
+			clickhouse.WithMutationProgress(func(clickhouse.MutationProgress) {
+				close(clk.expired)
+			}),
+		)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.NoError(t, mock.AllExpectationsMet())
+	})
+}
+
+// timeoutClock expires the timeout once expired is closed, other waits never end.
+type timeoutClock struct {
+	timeout time.Duration
+	expired chan time.Time
+}
+
+func (c *timeoutClock) Now() time.Time { return time.Time{} }
+
+func (c *timeoutClock) After(d time.Duration) <-chan time.Time {
+	if d == c.timeout {
+		return c.expired
+	}
+	return nil
+}
+
+// clock records the delays waited for and returns right away.
+type clock struct {
+	waits []time.Duration
+}
+
+func (c *clock) Now() time.Time { return time.Time{} }
+
+func (c *clock) After(d time.Duration) <-chan time.Time {
+	c.waits = append(c.waits, d)
+	ch := make(chan time.Time, 1)
+	ch <- time.Time{}
+	return ch
+}
+
+// queryHook is an octobe.QueryHook calling before for every query.
+type queryHook struct {
+	before func(ctx context.Context, event *octobe.QueryEvent)
+}
+
+func (h queryHook) BeforeQuery(ctx context.Context, event *octobe.QueryEvent) context.Context {
+	h.before(ctx, event)
+	return ctx
+}
+
+func (h queryHook) AfterQuery(context.Context, *octobe.QueryEvent) {}
@@ -2,11 +2,16 @@ package clickhouse
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"reflect"
+	"slices"
+	"time"
 
 	"github.com/ClickHouse/clickhouse-go/v2"
 	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
 	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/internal/named"
 )
 
 type NativeConn = driver.Conn
@@ -20,6 +25,10 @@ type nativeConn struct {
 var _ octobe.Driver[nativeConn, config, Builder] = &nativeConn{}
 
 // OpenNative creates a new database connection and returns a driver with the specified types.
+//
+// The connection always uses the native protocol, which has no session_id: temporary tables live on the TCP connection
+// that created them, while every segment may be executed on a different connection of the pool. Workloads that create
+// and query temporary tables across segments need a dedicated connection, opened with MaxOpenConns set to 1.
 func OpenNative(opts *clickhouse.Options) octobe.Open[nativeConn, config, Builder] {
 	return func() (octobe.Driver[nativeConn, config, Builder], error) {
 		conn, err := clickhouse.Open(opts)
@@ -53,11 +62,45 @@ func (d *nativeConn) Begin(ctx context.Context, opts ...octobe.Option[config]) (
 		opt(&cfg)
 	}
 
-	return &nativeSession{
+	session := &nativeSession{
 		ctx: ctx,
 		cfg: cfg,
 		d:   d,
-	}, nil
+	}
+	session.builder = session.build
+	return session, nil
+}
+
+// Ensure nativeConn reports that its sessions never begin a transaction.
+var _ octobe.TransactionReporter[config] = &nativeConn{}
+
+// BeginsTransaction reports false, ClickHouse has no transactions.
+func (d *nativeConn) BeginsTransaction(...octobe.Option[config]) bool {
+	return false
+}
+
+// Ensure nativeConn reports statistics.
+var _ octobe.StatsReporter = &nativeConn{}
+
+// Stats returns the driver.Stats of the connection.
+func (d *nativeConn) Stats() any {
+	return d.conn.Stats()
+}
+
+// Ensure nativeConn describes itself to query hooks.
+var _ octobe.Describer = &nativeConn{}
+
+// Describe returns the name of the driver and the database system it connects to.
+func (d *nativeConn) Describe() octobe.DriverInfo {
+	return octobe.DriverInfo{Name: "clickhouse", System: "clickhouse"}
+}
+
+// Ensure nativeConn reports its capabilities.
+var _ octobe.CapabilityReporter = &nativeConn{}
+
+// Capabilities returns the features supported by the clickhouse driver.
+func (d *nativeConn) Capabilities() octobe.Capabilities {
+	return octobe.Capabilities{Batch: true, AsyncInsert: true}
 }
 
 // Close closes the database connection.
@@ -72,17 +115,17 @@ func (d *nativeConn) Ping(ctx context.Context) error {
 
 // nativeSession holds nativeSession context, representing a series of related queries.
 type nativeSession struct {
-	ctx       context.Context
-	cfg       config
-	d         *nativeConn
-	committed bool
+	ctx     context.Context
+	cfg     config
+	d       *nativeConn
+	builder Builder
 }
 
 // Ensure session implements the Octobe Session interface.
 var _ octobe.Session[Builder] = &nativeSession{}
 
 // Commit commits a transaction. This is a no-op for ClickHouse as it does not support transactions in the same way as
-// other databases.
+// other databases, there is no transaction that could end, so it never fails with octobe.ErrTxDone.
 func (s *nativeSession) Commit() error {
 	return nil
 }
@@ -93,26 +136,33 @@ func (s *nativeSession) Rollback() error {
 	return nil
 }
 
-// Builder returns a new builder for building queries.
+// Builder returns the builder for building queries in the session.
 func (s *nativeSession) Builder() Builder {
-	return func(query string) Segment {
-		return &nativeSegment{
-			query: query,
-			args:  nil,
-			used:  false,
-			d:     s.d,
-			ctx:   s.ctx,
-		}
+	return s.builder
+}
+
+// build creates a segment for query in the session. It backs the builder of the session, which is created once so that
+// building segments in a loop only allocates the segments.
+func (s *nativeSession) build(query string) Segment {
+	return &nativeSegment{
+		query: query,
+		args:  nil,
+		used:  false,
+		d:     s.d,
+		ctx:   s.ctx,
 	}
 }
 
 // nativeSegment represents a specific query that can be run only once. It keeps track of the query, arguments, and execution state.
 type nativeSegment struct {
-	query string
-	args  []any
-	used  bool
-	d     *nativeConn
-	ctx   context.Context
+	query   string
+	args    []any
+	used    bool
+	d       *nativeConn
+	ctx     context.Context
+	maxRows int
+	timeout time.Duration
+	err     error
 }
 
 var _ Segment = &nativeSegment{}
@@ -122,12 +172,54 @@ func (s *nativeSegment) use() {
 	s.used = true
 }
 
-// Arguments sets the arguments to be used in the query.
+// Arguments sets the arguments to be used in the query. Arguments created with sql.Named are bound to the named
+// parameters of the query, like @name, as with NamedArguments.
 func (s *nativeSegment) Arguments(args ...any) Segment {
+	values, ok, err := named.Args(args)
+	if err != nil {
+		s.err = err
+		return s
+	}
+	if ok {
+		return s.NamedArguments(values)
+	}
 	s.args = args
 	return s
 }
 
+// ArgumentsFromStruct binds the fields of a struct to the query by their db tags. Named parameters like :name are bound
+// to the field of the same name, a query without named parameters gets all fields as arguments in declaration order.
+func (s *nativeSegment) ArgumentsFromStruct(v any) Segment {
+	s.query, s.args, s.err = named.Bind(s.query, named.Question, v)
+	return s
+}
+
+// NamedArguments binds the values of a map to the named parameters of the query, like :name or @name.
+func (s *nativeSegment) NamedArguments(args map[string]any) Segment {
+	s.query, s.args, s.err = named.BindMap(s.query, named.Question, args)
+	return s
+}
+
+// WithMaxRows limits the number of rows Query and Select may read before failing with a *octobe.MaxRowsError.
+func (s *nativeSegment) WithMaxRows(n int) Segment {
+	s.maxRows = n
+	return s
+}
+
+// Timeout sets a deadline of d for executing the statement, leaving the context of the session as is.
+func (s *nativeSegment) Timeout(d time.Duration) Segment {
+	s.timeout = d
+	return s
+}
+
+// Clone returns an unused copy of the segment with the same query, arguments and settings.
+func (s *nativeSegment) Clone() Segment {
+	c := *s
+	c.used = false
+	c.args = slices.Clone(s.args)
+	return &c
+}
+
 // Contributors returns the list of contributors for the driver.
 func (s *nativeSegment) Contributors() []string {
 	return s.d.conn.Contributors()
@@ -139,67 +231,196 @@ func (s *nativeSegment) ServerVersion() (*ServerVersion, error) {
 }
 
 // Select executes a query and scans the results into the destination.
-func (s *nativeSegment) Select(dest any) error {
+func (s *nativeSegment) Select(dest any) (err error) {
 	if s.used {
 		return octobe.ErrAlreadyUsed
 	}
 	defer s.use()
+	if s.err != nil {
+		return s.err
+	}
 
-	return s.d.conn.Select(s.ctx, dest, s.query, s.args...)
+	ctx, cancel := withTimeout(s.ctx, s.timeout)
+	defer cancel()
+	ctx, done := octobe.BeginQuery(ctx, octobe.OperationSelect, s.query, s.args)
+	defer func() {
+		err = octobe.WrapQueryError(s.d.Describe().Name, s.query, s.args, err)
+		done(selectCount(dest, err), err)
+	}()
+
+	if s.maxRows > 0 {
+		return s.selectLimited(ctx, dest)
+	}
+
+	return s.d.conn.Select(ctx, dest, octobe.CommentQuery(ctx, s.query), s.args...)
+}
+
+// selectLimited scans rows into the dest slice one by one, the same way the clickhouse driver does for Select, but
+// stops reading as soon as the row limit of the segment is exceeded.
+func (s *nativeSegment) selectLimited(ctx context.Context, dest any) error {
+	value := reflect.ValueOf(dest)
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Slice {
+		return errors.New("select destination must be a non-nil pointer to a slice")
+	}
+
+	direct := value.Elem()
+	direct.Set(reflect.MakeSlice(direct.Type(), 0, direct.Cap()))
+	base := direct.Type().Elem()
+
+	rows, err := s.d.conn.Query(ctx, octobe.CommentQuery(ctx, s.query), s.args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	limited := limitRows(rows, s.maxRows)
+	for limited.Next() {
+		elem := reflect.New(base)
+		if err = limited.ScanStruct(elem.Interface()); err != nil {
+			return err
+		}
+		direct.Set(reflect.Append(direct, elem.Elem()))
+	}
+
+	return limited.Err()
 }
 
 // Exec executes a query, typically used for inserts or updates.
-func (s *nativeSegment) Exec() error {
+func (s *nativeSegment) Exec() (err error) {
 	if s.used {
 		return octobe.ErrAlreadyUsed
 	}
 	defer s.use()
+	if s.err != nil {
+		return s.err
+	}
+
+	ctx, cancel := withTimeout(s.ctx, s.timeout)
+	defer cancel()
+	ctx, done := octobe.BeginQuery(ctx, octobe.OperationExec, s.query, s.args)
+	defer func() {
+		err = octobe.WrapQueryError(s.d.Describe().Name, s.query, s.args, err)
+		done(-1, err)
+	}()
 
-	return s.d.conn.Exec(s.ctx, s.query, s.args...)
+	return s.d.conn.Exec(ctx, octobe.CommentQuery(ctx, s.query), s.args...)
 }
 
 // Query performs a normal query against the database that returns rows.
-func (s *nativeSegment) Query(cb func(Rows) error) error {
+func (s *nativeSegment) Query(cb func(Rows) error) (err error) {
 	if s.used {
 		return octobe.ErrAlreadyUsed
 	}
 	defer s.use()
+	if s.err != nil {
+		return s.err
+	}
+
+	ctx, cancel := withTimeout(s.ctx, s.timeout)
+	defer cancel()
+	ctx, done := octobe.BeginQuery(ctx, octobe.OperationQuery, s.query, s.args)
+	defer func() {
+		err = octobe.WrapQueryError(s.d.Describe().Name, s.query, s.args, err)
+		done(-1, err)
+	}()
 
 	var rows driver.Rows
-	var err error
 
-	rows, err = s.d.conn.Query(s.ctx, s.query, s.args...)
+	rows, err = s.d.conn.Query(ctx, octobe.CommentQuery(ctx, s.query), s.args...)
 	if err != nil {
 		return err
 	}
 	defer rows.Close()
 
-	if err = cb(rows); err != nil {
+	limited := limitRows(rows, s.maxRows)
+	if err = cb(limited); err != nil {
 		return err
 	}
 
-	return rows.Err()
+	return limited.Err()
 }
 
 // QueryRow returns one result and puts it into destination pointers.
-func (s *nativeSegment) QueryRow(dest ...any) error {
+func (s *nativeSegment) QueryRow(dest ...any) (err error) {
 	if s.used {
 		return octobe.ErrAlreadyUsed
 	}
 	defer s.use()
+	if s.err != nil {
+		return s.err
+	}
+
+	ctx, cancel := withTimeout(s.ctx, s.timeout)
+	defer cancel()
+	ctx, done := octobe.BeginQuery(ctx, octobe.OperationQueryRow, s.query, s.args)
+	defer func() {
+		err = octobe.WrapQueryError(s.d.Describe().Name, s.query, s.args, err)
+		done(queryRowCount(err), err)
+	}()
 
-	row := s.d.conn.QueryRow(s.ctx, s.query, s.args...)
+	row := s.d.conn.QueryRow(ctx, octobe.CommentQuery(ctx, s.query), s.args...)
 	return row.Scan(dest...)
 }
 
+// QueryRowMap returns the first row of the result as a map keyed by column name.
+func (s *nativeSegment) QueryRowMap() (map[string]any, error) {
+	var row map[string]any
+	err := s.Query(func(rows Rows) error {
+		if !rows.Next() {
+			if err := rows.Err(); err != nil {
+				return err
+			}
+			return sql.ErrNoRows
+		}
+		var err error
+		row, err = rowMap(rows)
+		return err
+	})
+	return row, err
+}
+
+// QueryMaps returns all rows of the result as maps keyed by column name.
+func (s *nativeSegment) QueryMaps() ([]map[string]any, error) {
+	var result []map[string]any
+	err := s.Query(func(rows Rows) error {
+		var err error
+		result, err = collectMaps(rows)
+		return err
+	})
+	return result, err
+}
+
+// QueryRowStruct reads the first row of the result into the struct dest points to, mapping columns by db tags.
+func (s *nativeSegment) QueryRowStruct(dest any) error {
+	return s.Query(func(rows Rows) error {
+		return firstStruct(rows, dest)
+	})
+}
+
+// QueryStructs reads all rows of the result into the slice dest points to, mapping columns by db tags.
+func (s *nativeSegment) QueryStructs(dest any) error {
+	return s.Query(func(rows Rows) error {
+		return collectStructs(rows, dest)
+	})
+}
+
 // PrepareBatch prepares a batch for execution. This allows for multiple queries to be executed in a single batch.
-func (s *nativeSegment) PrepareBatch(opts ...driver.PrepareBatchOption) (driver.Batch, error) {
+func (s *nativeSegment) PrepareBatch(opts ...driver.PrepareBatchOption) (_ driver.Batch, err error) {
 	if s.used {
 		return nil, octobe.ErrAlreadyUsed
 	}
 	defer s.use()
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	ctx, done := octobe.BeginQuery(s.ctx, octobe.OperationBatch, s.query, s.args)
+	defer func() {
+		err = octobe.WrapQueryError(s.d.Describe().Name, s.query, s.args, err)
+		done(-1, err)
+	}()
 
-	batch, err := s.d.conn.PrepareBatch(s.ctx, s.query, opts...)
+	batch, err := s.d.conn.PrepareBatch(ctx, s.query, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -208,15 +429,26 @@ func (s *nativeSegment) PrepareBatch(opts ...driver.PrepareBatchOption) (driver.
 }
 
 // AsyncInsert performs an asynchronous insert operation. If `wait` is true, it will wait for the insert to complete.
-func (s *nativeSegment) AsyncInsert(wait bool, args ...any) error {
+func (s *nativeSegment) AsyncInsert(wait bool, args ...any) (err error) {
 	if s.used {
 		return octobe.ErrAlreadyUsed
 	}
 	defer s.use()
+	if s.err != nil {
+		return s.err
+	}
 
 	if len(args) > 0 {
 		s.args = args
 	}
 
-	return s.d.conn.AsyncInsert(s.ctx, s.query, wait, s.args...)
+	ctx, cancel := withTimeout(s.ctx, s.timeout)
+	defer cancel()
+	ctx, done := octobe.BeginQuery(ctx, octobe.OperationAsyncInsert, s.query, s.args)
+	defer func() {
+		err = octobe.WrapQueryError(s.d.Describe().Name, s.query, s.args, err)
+		done(-1, err)
+	}()
+
+	return s.d.conn.AsyncInsert(ctx, s.query, wait, s.args...)
 }
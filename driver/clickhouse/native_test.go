@@ -2,15 +2,19 @@ package clickhouse_test
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/ClickHouse/clickhouse-go/v2/lib/column"
 	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
 	"github.com/ClickHouse/clickhouse-go/v2/lib/proto"
 	"github.com/ponrove/octobe"
 	"github.com/ponrove/octobe/driver/clickhouse"
+	chmock "github.com/ponrove/octobe/driver/clickhouse/mock"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
@@ -234,6 +238,16 @@ func TestClose(t *testing.T) {
 	mockConn.AssertExpectations(t)
 }
 
+func TestStats(t *testing.T) {
+	mockConn := new(MockConn)
+	o, err := octobe.New(clickhouse.OpenNativeWithConn(mockConn))
+	require.NoError(t, err)
+
+	mockConn.On("Stats").Return(driver.Stats{MaxOpenConns: 10, Open: 2, Idle: 1})
+	require.Equal(t, octobe.Stats{Driver: driver.Stats{MaxOpenConns: 10, Open: 2, Idle: 1}}, o.Stats())
+	mockConn.AssertExpectations(t)
+}
+
 func TestPing(t *testing.T) {
 	ctx := context.Background()
 	mockConn := new(MockConn)
@@ -380,7 +394,7 @@ func TestSegmentError(t *testing.T) {
 		mockConn.On("Exec", ctx, query, sArgs).Return(expectedErr)
 		err := s.Exec()
 		require.Error(t, err)
-		require.Equal(t, expectedErr, err)
+		require.ErrorIs(t, err, expectedErr)
 		mockConn.AssertExpectations(t)
 	})
 
@@ -394,7 +408,7 @@ func TestSegmentError(t *testing.T) {
 
 		err := s.QueryRow(&dest)
 		require.Error(t, err)
-		require.Equal(t, expectedErr, err)
+		require.ErrorIs(t, err, expectedErr)
 		mockConn.AssertExpectations(t)
 		mockRow.AssertExpectations(t)
 	})
@@ -406,7 +420,7 @@ func TestSegmentError(t *testing.T) {
 
 		err := s.Query(func(rows driver.Rows) error { return nil })
 		require.Error(t, err)
-		require.Equal(t, expectedErr, err)
+		require.ErrorIs(t, err, expectedErr)
 		mockConn.AssertExpectations(t)
 	})
 
@@ -419,7 +433,7 @@ func TestSegmentError(t *testing.T) {
 
 		err := s.Query(func(rows driver.Rows) error { return expectedErr })
 		require.Error(t, err)
-		require.Equal(t, expectedErr, err)
+		require.ErrorIs(t, err, expectedErr)
 		mockConn.AssertExpectations(t)
 		mockRows.AssertExpectations(t)
 	})
@@ -434,7 +448,7 @@ func TestSegmentError(t *testing.T) {
 
 		err := s.Query(func(rows driver.Rows) error { return nil })
 		require.Error(t, err)
-		require.Equal(t, expectedErr, err)
+		require.ErrorIs(t, err, expectedErr)
 		mockConn.AssertExpectations(t)
 		mockRows.AssertExpectations(t)
 	})
@@ -543,3 +557,411 @@ func TestStartTransaction(t *testing.T) {
 		})
 	})
 }
+
+func TestSegmentMaxRows(t *testing.T) {
+	ctx := context.Background()
+	query := "SELECT n FROM numbers"
+	var args []any
+
+	setup := func(t *testing.T) (octobe.Session[clickhouse.Builder], *MockConn) {
+		mockConn := new(MockConn)
+		o, err := octobe.New(clickhouse.OpenNativeWithConn(mockConn))
+		require.NoError(t, err)
+		session, err := o.Begin(ctx)
+		require.NoError(t, err)
+		return session, mockConn
+	}
+
+	t.Run("Query", func(t *testing.T) {
+		session, mockConn := setup(t)
+
+		mockRows := new(MockRows)
+		mockRows.On("Next").Return(true).Times(3)
+		mockRows.On("Close").Return(nil).Once()
+		mockConn.On("Query", ctx, query, args).Return(mockRows, nil).Once()
+
+		var read int
+		err := session.Builder()(query).WithMaxRows(2).Query(func(rows clickhouse.Rows) error {
+			for rows.Next() {
+				read++
+			}
+			return nil
+		})
+		require.ErrorIs(t, err, octobe.ErrMaxRowsExceeded)
+		require.Equal(t, 2, read)
+		mockConn.AssertExpectations(t)
+		mockRows.AssertExpectations(t)
+	})
+
+	t.Run("Select", func(t *testing.T) {
+		session, mockConn := setup(t)
+
+		type row struct{ N int }
+		mockRows := new(MockRows)
+		mockRows.On("Next").Return(true).Times(3)
+		mockRows.On("ScanStruct", mock.AnythingOfType("*clickhouse_test.row")).Return(nil).Twice()
+		mockRows.On("Close").Return(nil).Once()
+		mockConn.On("Query", ctx, query, args).Return(mockRows, nil).Once()
+
+		var dest []row
+		err := session.Builder()(query).WithMaxRows(2).Select(&dest)
+		var maxRowsErr *octobe.MaxRowsError
+		require.ErrorAs(t, err, &maxRowsErr)
+		require.Equal(t, 2, maxRowsErr.Limit)
+		require.Len(t, dest, 2)
+		mockConn.AssertExpectations(t)
+		mockRows.AssertExpectations(t)
+	})
+
+	t.Run("Select within limit", func(t *testing.T) {
+		session, mockConn := setup(t)
+
+		type row struct{ N int }
+		mockRows := new(MockRows)
+		mockRows.On("Next").Return(true).Once()
+		mockRows.On("Next").Return(false).Once()
+		mockRows.On("ScanStruct", mock.AnythingOfType("*clickhouse_test.row")).Return(nil).Once()
+		mockRows.On("Err").Return(nil).Once()
+		mockRows.On("Close").Return(nil).Once()
+		mockConn.On("Query", ctx, query, args).Return(mockRows, nil).Once()
+
+		var dest []row
+		require.NoError(t, session.Builder()(query).WithMaxRows(2).Select(&dest))
+		require.Len(t, dest, 1)
+		mockConn.AssertExpectations(t)
+		mockRows.AssertExpectations(t)
+	})
+}
+
+// columnType is a static implementation of the driver.ColumnType interface.
+type columnType struct {
+	name     string
+	scanType reflect.Type
+}
+
+func (c columnType) Name() string             { return c.name }
+func (c columnType) Nullable() bool           { return false }
+func (c columnType) ScanType() reflect.Type   { return c.scanType }
+func (c columnType) DatabaseTypeName() string { return c.scanType.String() }
+
+func TestSegmentQueryMaps(t *testing.T) {
+	ctx := context.Background()
+	query := "SELECT id, name FROM products"
+	var args []any
+
+	setup := func(t *testing.T, rows [][]any) (octobe.Session[clickhouse.Builder], *MockConn, *MockRows) {
+		mockConn := new(MockConn)
+		o, err := octobe.New(clickhouse.OpenNativeWithConn(mockConn))
+		require.NoError(t, err)
+		session, err := o.Begin(ctx)
+		require.NoError(t, err)
+
+		mockRows := new(MockRows)
+		for _, row := range rows {
+			mockRows.On("Next").Return(true).Once()
+			mockRows.On("Columns").Return([]string{"id", "name"}).Once()
+			mockRows.On("ColumnTypes").Return([]driver.ColumnType{
+				columnType{name: "id", scanType: reflect.TypeOf(uint64(0))},
+				columnType{name: "name", scanType: reflect.TypeOf("")},
+			}).Once()
+			mockRows.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+				dest := args.Get(0).([]any)
+				*dest[0].(*uint64) = row[0].(uint64)
+				*dest[1].(*string) = row[1].(string)
+			}).Return(nil).Once()
+		}
+		mockRows.On("Next").Return(false)
+		mockRows.On("Err").Return(nil)
+		mockRows.On("Close").Return(nil).Once()
+		mockConn.On("Query", ctx, query, args).Return(mockRows, nil).Once()
+		return session, mockConn, mockRows
+	}
+
+	t.Run("QueryMaps", func(t *testing.T) {
+		session, mockConn, mockRows := setup(t, [][]any{{uint64(1), "a"}, {uint64(2), "b"}})
+
+		rows, err := session.Builder()(query).QueryMaps()
+		require.NoError(t, err)
+		require.Equal(t, []map[string]any{{"id": uint64(1), "name": "a"}, {"id": uint64(2), "name": "b"}}, rows)
+		mockConn.AssertExpectations(t)
+		mockRows.AssertExpectations(t)
+	})
+
+	t.Run("QueryRowMap", func(t *testing.T) {
+		session, mockConn, mockRows := setup(t, [][]any{{uint64(1), "a"}})
+
+		row, err := session.Builder()(query).QueryRowMap()
+		require.NoError(t, err)
+		require.Equal(t, map[string]any{"id": uint64(1), "name": "a"}, row)
+		mockConn.AssertExpectations(t)
+		mockRows.AssertExpectations(t)
+	})
+
+	t.Run("QueryRowMap no rows", func(t *testing.T) {
+		session, mockConn, _ := setup(t, nil)
+
+		_, err := session.Builder()(query).QueryRowMap()
+		require.ErrorIs(t, err, sql.ErrNoRows)
+		require.ErrorIs(t, err, octobe.ErrNoRows)
+		mockConn.AssertExpectations(t)
+	})
+}
+
+func TestSegmentQueryStructs(t *testing.T) {
+	ctx := context.Background()
+	query := "SELECT id, name FROM products"
+	var args []any
+
+	type Product struct {
+		ID   uint64 `db:"id"`
+		Name string `db:"name"`
+	}
+
+	setup := func(t *testing.T, rows [][]any) (octobe.Session[clickhouse.Builder], *MockConn, *MockRows) {
+		mockConn := new(MockConn)
+		o, err := octobe.New(clickhouse.OpenNativeWithConn(mockConn))
+		require.NoError(t, err)
+		session, err := o.Begin(ctx)
+		require.NoError(t, err)
+
+		mockRows := new(MockRows)
+		mockRows.On("Columns").Return([]string{"id", "name"}).Once()
+		for _, row := range rows {
+			mockRows.On("Next").Return(true).Once()
+			mockRows.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+				dest := args.Get(0).([]any)
+				*dest[0].(*uint64) = row[0].(uint64)
+				*dest[1].(*string) = row[1].(string)
+			}).Return(nil).Once()
+		}
+		mockRows.On("Next").Return(false)
+		mockRows.On("Err").Return(nil)
+		mockRows.On("Close").Return(nil).Once()
+		mockConn.On("Query", ctx, query, args).Return(mockRows, nil).Once()
+		return session, mockConn, mockRows
+	}
+
+	t.Run("QueryStructs", func(t *testing.T) {
+		session, mockConn, mockRows := setup(t, [][]any{{uint64(1), "a"}, {uint64(2), "b"}})
+
+		var products []*Product
+		err := session.Builder()(query).QueryStructs(&products)
+		require.NoError(t, err)
+		require.Equal(t, []*Product{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}}, products)
+		mockConn.AssertExpectations(t)
+		mockRows.AssertExpectations(t)
+	})
+
+	t.Run("QueryRowStruct", func(t *testing.T) {
+		session, mockConn, mockRows := setup(t, [][]any{{uint64(1), "a"}})
+
+		var product Product
+		err := session.Builder()(query).QueryRowStruct(&product)
+		require.NoError(t, err)
+		require.Equal(t, Product{ID: 1, Name: "a"}, product)
+		mockConn.AssertExpectations(t)
+		mockRows.AssertExpectations(t)
+	})
+
+	t.Run("QueryRowStruct no rows", func(t *testing.T) {
+		session, mockConn, _ := setup(t, nil)
+
+		var product Product
+		err := session.Builder()(query).QueryRowStruct(&product)
+		require.ErrorIs(t, err, sql.ErrNoRows)
+		mockConn.AssertExpectations(t)
+	})
+}
+
+func TestSegmentArgumentsFromStruct(t *testing.T) {
+	ctx := context.Background()
+
+	type Audit struct {
+		CreatedBy string `db:"created_by"`
+	}
+	type Event struct {
+		ID   uint64 `db:"id"`
+		Name string `db:"name"`
+		*Audit
+		Internal string `db:"-"`
+	}
+
+	setup := func(t *testing.T) (octobe.Session[clickhouse.Builder], *MockConn) {
+		mockConn := new(MockConn)
+		o, err := octobe.New(clickhouse.OpenNativeWithConn(mockConn))
+		require.NoError(t, err)
+		session, err := o.Begin(ctx)
+		require.NoError(t, err)
+		return session, mockConn
+	}
+
+	t.Run("named parameters", func(t *testing.T) {
+		session, mockConn := setup(t)
+		mockConn.On("Exec", ctx, "ALTER TABLE events UPDATE name = ? WHERE id = ? AND name != ?", []any{"b", uint64(1), "b"}).Return(nil).Once()
+
+		err := session.Builder()("ALTER TABLE events UPDATE name = :name WHERE id = :id AND name != :name").
+			ArgumentsFromStruct(Event{ID: 1, Name: "b"}).
+			Exec()
+		require.NoError(t, err)
+		mockConn.AssertExpectations(t)
+	})
+
+	t.Run("positional parameters", func(t *testing.T) {
+		session, mockConn := setup(t)
+		query := "INSERT INTO events (id, name, created_by) VALUES (?, ?, ?)"
+		mockConn.On("Exec", ctx, query, []any{uint64(1), "a", "alice"}).Return(nil).Once()
+
+		err := session.Builder()(query).ArgumentsFromStruct(&Event{ID: 1, Name: "a", Audit: &Audit{CreatedBy: "alice"}}).Exec()
+		require.NoError(t, err)
+		mockConn.AssertExpectations(t)
+	})
+
+	t.Run("missing field", func(t *testing.T) {
+		session, mockConn := setup(t)
+
+		err := session.Builder()("SELECT * FROM events WHERE owner = :owner").ArgumentsFromStruct(Event{}).Exec()
+		require.ErrorContains(t, err, ":owner")
+		mockConn.AssertExpectations(t)
+	})
+
+	t.Run("not a struct", func(t *testing.T) {
+		session, _ := setup(t)
+
+		err := session.Builder()("SELECT 1").ArgumentsFromStruct(1).Exec()
+		require.Error(t, err)
+	})
+}
+
+func TestSegmentNamedArguments(t *testing.T) {
+	ctx := context.Background()
+	mockConn := new(MockConn)
+	o, err := octobe.New(clickhouse.OpenNativeWithConn(mockConn))
+	require.NoError(t, err)
+	session, err := o.Begin(ctx)
+	require.NoError(t, err)
+
+	mockConn.On("Exec", ctx, "INSERT INTO events SELECT ?, ? WHERE {tenant:String} = ?", []any{uint64(1), "a", uint64(1)}).Return(nil).Once()
+
+	err = session.Builder()("INSERT INTO events SELECT @id, :name WHERE {tenant:String} = :id").
+		NamedArguments(map[string]any{"id": uint64(1), "name": "a"}).
+		Exec()
+	require.NoError(t, err)
+
+	err = session.Builder()("SELECT * FROM events WHERE id = :id").NamedArguments(nil).Exec()
+	require.ErrorContains(t, err, ":id")
+	mockConn.AssertExpectations(t)
+}
+
+func TestSegmentSQLNamedArguments(t *testing.T) {
+	ctx := context.Background()
+	mockConn := new(MockConn)
+	o, err := octobe.New(clickhouse.OpenNativeWithConn(mockConn))
+	require.NoError(t, err)
+	session, err := o.Begin(ctx)
+	require.NoError(t, err)
+
+	mockConn.On("Exec", ctx, "ALTER TABLE events DELETE WHERE id = ? AND tenant = ?", []any{uint64(1), "acme"}).Return(nil).Once()
+
+	err = session.Builder()("ALTER TABLE events DELETE WHERE id = @id AND tenant = @tenant").
+		Arguments(sql.Named("tenant", "acme"), sql.Named("id", uint64(1))).
+		Exec()
+	require.NoError(t, err)
+
+	err = session.Builder()("SELECT * FROM events WHERE id = @id").Arguments(sql.Named("id", 1), 2).Exec()
+	require.Error(t, err)
+	mockConn.AssertExpectations(t)
+}
+
+func TestSegmentTimeout(t *testing.T) {
+	ctx := context.Background()
+	mockConn := new(MockConn)
+	o, err := octobe.New(clickhouse.OpenNativeWithConn(mockConn))
+	require.NoError(t, err)
+	session, err := o.Begin(ctx)
+	require.NoError(t, err)
+
+	var statementCtx context.Context
+	hasDeadline := mock.MatchedBy(func(ctx context.Context) bool {
+		_, ok := ctx.Deadline()
+		return ok
+	})
+	mockConn.On("Exec", hasDeadline, "OPTIMIZE TABLE events", []any(nil)).Run(func(args mock.Arguments) {
+		statementCtx = args.Get(0).(context.Context)
+	}).Return(nil).Once()
+
+	err = session.Builder()("OPTIMIZE TABLE events").Timeout(time.Minute).Exec()
+	require.NoError(t, err)
+	require.ErrorIs(t, statementCtx.Err(), context.Canceled, "the statement context is released after execution")
+	mockConn.AssertExpectations(t)
+}
+
+func TestSegmentClone(t *testing.T) {
+	ctx := context.Background()
+	mockConn := new(MockConn)
+	o, err := octobe.New(clickhouse.OpenNativeWithConn(mockConn))
+	require.NoError(t, err)
+	session, err := o.Begin(ctx)
+	require.NoError(t, err)
+
+	query := "INSERT INTO events VALUES (?)"
+	mockConn.On("Exec", ctx, query, []any{uint64(1)}).Return(nil).Times(3)
+
+	s := session.Builder()(query).Arguments(uint64(1))
+	clone := s.Clone()
+	require.NoError(t, s.Exec())
+	require.ErrorIs(t, s.Exec(), octobe.ErrAlreadyUsed)
+
+	// Both the clone taken before and a clone taken after executing can run the statement again.
+	require.NoError(t, clone.Exec())
+	again := s.Clone()
+	require.NoError(t, again.Exec())
+	require.ErrorIs(t, again.Exec(), octobe.ErrAlreadyUsed)
+	mockConn.AssertNumberOfCalls(t, "Exec", 3)
+}
+
+func TestNativeFrom(t *testing.T) {
+	ctx := context.Background()
+	mockConn := new(MockConn)
+	o, err := octobe.New(clickhouse.OpenNativeWithConn(mockConn))
+	require.NoError(t, err)
+	session, err := o.Begin(ctx)
+	require.NoError(t, err)
+
+	query := octobe.SqlizerFunc(func() (string, []any, error) {
+		return "ALTER TABLE events DELETE WHERE id = ?", []any{1}, nil
+	})
+	mockConn.On("Exec", mock.Anything, "ALTER TABLE events DELETE WHERE id = ?", []any{1}).Return(nil).Once()
+	require.NoError(t, session.Builder().From(query).Exec())
+
+	expectedErr := errors.New("missing table")
+	failing := octobe.SqlizerFunc(func() (string, []any, error) { return "", nil, expectedErr })
+	require.ErrorIs(t, session.Builder().From(failing).Exec(), expectedErr)
+	mockConn.AssertExpectations(t)
+}
+
+func TestNativeRows(t *testing.T) {
+	ctx := context.Background()
+	conn := chmock.NewMock()
+	conn.ExpectQuery("SELECT id FROM events").WillReturnRows(chmock.NewMockRows([]string{"id"}).AddRow(uint64(1)).AddRow(uint64(2)))
+	conn.ExpectQuery("SELECT id FROM missing").WillReturnError(errors.New("table does not exist"))
+
+	o, err := octobe.New(clickhouse.OpenNativeWithConn(conn))
+	require.NoError(t, err)
+	session, err := o.Begin(ctx)
+	require.NoError(t, err)
+
+	var ids []uint64
+	for row, err := range session.Builder()("SELECT id FROM events").Rows() {
+		require.NoError(t, err)
+		var id uint64
+		require.NoError(t, row.Scan(&id))
+		ids = append(ids, id)
+	}
+	require.Equal(t, []uint64{1, 2}, ids)
+
+	for row, err := range session.Builder()("SELECT id FROM missing").Rows() {
+		require.Nil(t, row)
+		require.ErrorContains(t, err, "table does not exist")
+	}
+	require.NoError(t, conn.AllExpectationsMet())
+}
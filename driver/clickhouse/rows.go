@@ -0,0 +1,173 @@
+package clickhouse
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/internal/dbtag"
+)
+
+// maxRowsRows wraps Rows and stops iteration as soon as more rows than limit have been read.
+type maxRowsRows struct {
+	Rows
+	limit int
+	count int
+	err   error
+}
+
+// limitRows wraps rows with a row limit if limit is positive, otherwise rows is returned as is.
+func limitRows(rows Rows, limit int) Rows {
+	if limit <= 0 {
+		return rows
+	}
+	return &maxRowsRows{Rows: rows, limit: limit}
+}
+
+// Next advances to the next row, returning false once the limit has been exceeded.
+func (r *maxRowsRows) Next() bool {
+	if r.err != nil || !r.Rows.Next() {
+		return false
+	}
+	r.count++
+	if r.count > r.limit {
+		r.err = &octobe.MaxRowsError{Limit: r.limit}
+		return false
+	}
+	return true
+}
+
+// Err returns the limit error if the limit was exceeded, otherwise the error of the underlying rows.
+func (r *maxRowsRows) Err() error {
+	if r.err != nil {
+		return r.err
+	}
+	return r.Rows.Err()
+}
+
+// rowMap reads the current row into a map keyed by column name, allocating scan destinations from the column types
+// reported by the server.
+func rowMap(rows Rows) (map[string]any, error) {
+	columns := rows.Columns()
+	types := rows.ColumnTypes()
+	if len(types) != len(columns) {
+		return nil, fmt.Errorf("rows report %d column types for %d columns", len(types), len(columns))
+	}
+
+	dest := make([]any, len(columns))
+	for i, columnType := range types {
+		dest[i] = reflect.New(columnType.ScanType()).Interface()
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return nil, err
+	}
+
+	row := make(map[string]any, len(columns))
+	for i, column := range columns {
+		row[column] = reflect.ValueOf(dest[i]).Elem().Interface()
+	}
+	return row, nil
+}
+
+// RowMap reads the current row of a result into a map keyed by column name, like QueryRowMap, for use in the callback
+// of Query or with the rows of Segment.Rows. The values are typed by the column types reported by the server, row must
+// be rows of a query, a single Row of QueryRow has no column metadata.
+func RowMap(row Row) (map[string]any, error) {
+	rows, ok := row.(Rows)
+	if !ok {
+		return nil, fmt.Errorf("row of type %T does not expose column metadata", row)
+	}
+	return rowMap(rows)
+}
+
+// CollectMaps reads all remaining rows into maps keyed by column name, like QueryMaps, for use in the callback of Query.
+func CollectMaps(rows Rows) ([]map[string]any, error) {
+	return collectMaps(rows)
+}
+
+// collectMaps reads all remaining rows into maps keyed by column name.
+func collectMaps(rows Rows) ([]map[string]any, error) {
+	var result []map[string]any
+	for rows.Next() {
+		row, err := rowMap(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+// scanStruct reads the current row into the fields of the struct v that the columns map to by their db tags.
+func scanStruct(rows Rows, columns []string, v reflect.Value) error {
+	targets, err := dbtag.Targets(v, columns)
+	if err != nil {
+		return err
+	}
+	return rows.Scan(targets...)
+}
+
+// firstStruct reads the first row into the struct dest points to, returning sql.ErrNoRows if the result set is empty.
+func firstStruct(rows Rows, dest any) error {
+	v, err := dbtag.StructOf(dest)
+	if err != nil {
+		return err
+	}
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return err
+		}
+		return sql.ErrNoRows
+	}
+	return scanStruct(rows, rows.Columns(), v)
+}
+
+// collectStructs reads all remaining rows into the slice of structs or struct pointers dest points to, replacing its
+// contents.
+func collectStructs(rows Rows, dest any) error {
+	slice, elem, pointer, err := dbtag.SliceOf(dest)
+	if err != nil {
+		return err
+	}
+
+	columns := rows.Columns()
+	result := reflect.MakeSlice(slice.Type(), 0, 0)
+	for rows.Next() {
+		item := reflect.New(elem)
+		if err = scanStruct(rows, columns, item.Elem()); err != nil {
+			return err
+		}
+		if !pointer {
+			item = item.Elem()
+		}
+		result = reflect.Append(result, item)
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	slice.Set(result)
+	return nil
+}
+
+// queryRowCount returns the number of rows a QueryRow that failed with err returned, for query hooks.
+func queryRowCount(err error) int64 {
+	switch {
+	case err == nil:
+		return 1
+	case errors.Is(err, sql.ErrNoRows):
+		return 0
+	default:
+		return -1
+	}
+}
+
+// selectCount returns the number of rows a Select that failed with err scanned into dest, for query hooks.
+func selectCount(dest any, err error) int64 {
+	value := reflect.ValueOf(dest)
+	if err != nil || value.Kind() != reflect.Pointer || value.IsNil() || value.Elem().Kind() != reflect.Slice {
+		return -1
+	}
+	return int64(value.Elem().Len())
+}
@@ -0,0 +1,96 @@
+package clickhouse
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ponrove/octobe"
+)
+
+// ExecScript splits a script of multiple statements and executes them one after another within the session. ClickHouse
+// has no transactions, so statements executed before a failing statement remain applied. The returned error states
+// which statement failed.
+func ExecScript(session octobe.BuilderSession[Builder], script string) error {
+	builder := session.Builder()
+	for i, statement := range SplitStatements(script) {
+		if err := builder(statement).Exec(); err != nil {
+			return fmt.Errorf("statement %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// SplitStatements splits a script into separate statements on semicolons. Semicolons inside string literals, quoted
+// or backquoted identifiers and comments do not end a statement. Statements that consist only of whitespace and
+// comments are left out.
+func SplitStatements(script string) []string {
+	var (
+		statements []string
+		start      int
+		content    bool
+	)
+
+	for i := 0; i < len(script); {
+		c := script[i]
+		switch {
+		case c == ';':
+			if content {
+				statements = append(statements, strings.TrimSpace(script[start:i]))
+			}
+			i++
+			start, content = i, false
+			continue
+		case c == '-' && strings.HasPrefix(script[i:], "--"), c == '#':
+			end := strings.IndexByte(script[i:], '\n')
+			if end < 0 {
+				i = len(script)
+			} else {
+				i += end + 1
+			}
+			continue
+		case c == '/' && strings.HasPrefix(script[i:], "/*"):
+			end := strings.Index(script[i+2:], "*/")
+			if end < 0 {
+				i = len(script)
+			} else {
+				i += 2 + end + 2
+			}
+			continue
+		case c == '\'' || c == '"' || c == '`':
+			i = skipQuoted(script, i, c)
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			i++
+			continue
+		default:
+			i++
+		}
+		content = true
+	}
+
+	if content {
+		statements = append(statements, strings.TrimSpace(script[start:]))
+	}
+
+	return statements
+}
+
+// skipQuoted returns the index after the quoted literal or identifier that starts at i. Both a backslash and a doubled
+// quote character escape a quote.
+func skipQuoted(script string, i int, quote byte) int {
+	i++
+	for i < len(script) {
+		switch script[i] {
+		case '\\':
+			i += 2
+			continue
+		case quote:
+			if i+1 < len(script) && script[i+1] == quote {
+				i += 2
+				continue
+			}
+			return i + 1
+		}
+		i++
+	}
+	return i
+}
@@ -0,0 +1,87 @@
+package clickhouse_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/driver/clickhouse"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name     string
+		script   string
+		expected []string
+	}{
+		{
+			name:     "simple statements",
+			script:   "CREATE TABLE a (id UInt64) ENGINE = Memory;\nDROP TABLE b;\n",
+			expected: []string{"CREATE TABLE a (id UInt64) ENGINE = Memory", "DROP TABLE b"},
+		},
+		{
+			name:     "semicolon in string literal",
+			script:   `INSERT INTO a VALUES ('x;y'), ('it\'s;'), ('it''s;'); SELECT 1`,
+			expected: []string{`INSERT INTO a VALUES ('x;y'), ('it\'s;'), ('it''s;')`, "SELECT 1"},
+		},
+		{
+			name:     "quoted identifiers",
+			script:   "SELECT `a;b`, \"c;d\" FROM t; SELECT 2;",
+			expected: []string{"SELECT `a;b`, \"c;d\" FROM t", "SELECT 2"},
+		},
+		{
+			name:     "comments",
+			script:   "-- first; statement\nSELECT 1; /* block; comment */ SELECT 2;\n# trailing; comment",
+			expected: []string{"-- first; statement\nSELECT 1", "/* block; comment */ SELECT 2"},
+		},
+		{
+			name:     "empty statements",
+			script:   ";;\n",
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, clickhouse.SplitStatements(tt.script))
+		})
+	}
+}
+
+func TestExecScript(t *testing.T) {
+	ctx := context.Background()
+	script := "CREATE TABLE a (id UInt64) ENGINE = Memory; INSERT INTO a VALUES (1);"
+	var args []any
+
+	t.Run("success", func(t *testing.T) {
+		mockConn := new(MockConn)
+		o, err := octobe.New(clickhouse.OpenNativeWithConn(mockConn))
+		require.NoError(t, err)
+		session, err := o.Begin(ctx)
+		require.NoError(t, err)
+
+		mockConn.On("Exec", ctx, "CREATE TABLE a (id UInt64) ENGINE = Memory", args).Return(nil).Once()
+		mockConn.On("Exec", ctx, "INSERT INTO a VALUES (1)", args).Return(nil).Once()
+
+		require.NoError(t, clickhouse.ExecScript(session, script))
+		mockConn.AssertExpectations(t)
+	})
+
+	t.Run("failing statement", func(t *testing.T) {
+		mockConn := new(MockConn)
+		o, err := octobe.New(clickhouse.OpenNativeWithConn(mockConn))
+		require.NoError(t, err)
+		session, err := o.Begin(ctx)
+		require.NoError(t, err)
+
+		expectedErr := errors.New("table exists")
+		mockConn.On("Exec", ctx, "CREATE TABLE a (id UInt64) ENGINE = Memory", args).Return(expectedErr).Once()
+
+		err = clickhouse.ExecScript(session, script)
+		require.ErrorIs(t, err, expectedErr)
+		require.ErrorContains(t, err, "statement 1")
+		mockConn.AssertExpectations(t)
+	})
+}
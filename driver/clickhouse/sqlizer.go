@@ -0,0 +1,14 @@
+package clickhouse
+
+import "github.com/ponrove/octobe"
+
+// From builds a segment for the query and arguments of a query builder, like squirrel or sqltpl, an error building the
+// query is returned when the segment is executed. Builders must produce ? placeholders.
+func (b Builder) From(q octobe.Sqlizer) Segment {
+	query, args, err := q.ToSql()
+	segment := b(query).Arguments(args...)
+	if s, ok := segment.(*nativeSegment); ok && err != nil {
+		s.err = err
+	}
+	return segment
+}
@@ -0,0 +1,276 @@
+package clickhouse
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// Nullable holds a value of a Nullable or LowCardinality(Nullable) column, it mirrors sql.Null but converts values the
+// way ScanValue does. It can be passed directly as destination to Segment.QueryRow or Rows.Scan, which avoids scanning
+// into double pointers. Valid is false when the column is NULL.
+type Nullable[T any] struct {
+	V     T
+	Valid bool
+}
+
+var (
+	_ sql.Scanner   = &Nullable[string]{}
+	_ driver.Valuer = Nullable[string]{}
+)
+
+// Scan implements sql.Scanner, converting src into T.
+func (n *Nullable[T]) Scan(src any) error {
+	var zero T
+	n.V, n.Valid = zero, false
+	if isNil(src) {
+		return nil
+	}
+	if err := convertValue(src, reflect.ValueOf(&n.V).Elem()); err != nil {
+		return err
+	}
+	n.Valid = true
+	return nil
+}
+
+// Value implements driver.Valuer, so a Nullable can be used as argument for Nullable columns as well.
+func (n Nullable[T]) Value() (driver.Value, error) {
+	if !n.Valid {
+		return nil, nil
+	}
+	return n.V, nil
+}
+
+// Ptr returns a pointer to the value, or nil if the column was NULL.
+func (n Nullable[T]) Ptr() *T {
+	if !n.Valid {
+		return nil
+	}
+	return &n.V
+}
+
+// ScanValue converts a value read from a column into T. It is meant for values read without a typed destination, such
+// as the values returned by Segment.QueryRowMap, where Nullable columns are returned as pointers and LowCardinality
+// columns as their underlying type. Pointers are dereferenced as needed, a NULL value can only be converted into a
+// pointer, slice, map or interface type.
+func ScanValue[T any](src any) (T, error) {
+	var dest T
+	err := convertValue(src, reflect.ValueOf(&dest).Elem())
+	return dest, err
+}
+
+// ScanArray converts the value of an Array column into a []T, converting every element like ScanValue. The value may be
+// a slice of any element type, including the []any returned for arrays of tuples or nested types.
+func ScanArray[T any](src any) ([]T, error) {
+	if isNil(src) {
+		return nil, nil
+	}
+	value := reflect.Indirect(reflect.ValueOf(src))
+	if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+		return nil, fmt.Errorf("cannot scan %T as array", src)
+	}
+
+	result := make([]T, value.Len())
+	for i := range result {
+		if err := convertValue(value.Index(i).Interface(), reflect.ValueOf(&result[i]).Elem()); err != nil {
+			return nil, fmt.Errorf("array element %d: %w", i, err)
+		}
+	}
+	return result, nil
+}
+
+// ScanMap converts the value of a Map column, or of a named Tuple, into a map[K]V, converting every key and value like
+// ScanValue.
+func ScanMap[K comparable, V any](src any) (map[K]V, error) {
+	if isNil(src) {
+		return nil, nil
+	}
+	value := reflect.Indirect(reflect.ValueOf(src))
+	if value.Kind() != reflect.Map {
+		return nil, fmt.Errorf("cannot scan %T as map", src)
+	}
+
+	result := make(map[K]V, value.Len())
+	iter := value.MapRange()
+	for iter.Next() {
+		var (
+			key  K
+			elem V
+		)
+		if err := convertValue(iter.Key().Interface(), reflect.ValueOf(&key).Elem()); err != nil {
+			return nil, fmt.Errorf("map key %v: %w", iter.Key(), err)
+		}
+		if err := convertValue(iter.Value().Interface(), reflect.ValueOf(&elem).Elem()); err != nil {
+			return nil, fmt.Errorf("map value of key %v: %w", iter.Key(), err)
+		}
+		result[key] = elem
+	}
+	return result, nil
+}
+
+// ScanTuple assigns the elements of an unnamed Tuple value, which ClickHouse returns as []any, to dest in order. Every
+// element of dest must be a non-nil pointer, and the number of destinations must match the number of tuple elements.
+func ScanTuple(src any, dest ...any) error {
+	value := reflect.Indirect(reflect.ValueOf(src))
+	if !value.IsValid() || (value.Kind() != reflect.Slice && value.Kind() != reflect.Array) {
+		return fmt.Errorf("cannot scan %T as tuple", src)
+	}
+	if value.Len() != len(dest) {
+		return fmt.Errorf("tuple has %d elements, but %d destinations were given", value.Len(), len(dest))
+	}
+
+	for i, d := range dest {
+		target := reflect.ValueOf(d)
+		if target.Kind() != reflect.Pointer || target.IsNil() {
+			return fmt.Errorf("tuple destination %d is not a non-nil pointer", i)
+		}
+		if err := convertValue(value.Index(i).Interface(), target.Elem()); err != nil {
+			return fmt.Errorf("tuple element %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// isNil reports whether src is nil or a nil pointer.
+func isNil(src any) bool {
+	if src == nil {
+		return true
+	}
+	value := reflect.ValueOf(src)
+	return value.Kind() == reflect.Pointer && value.IsNil()
+}
+
+// convertValue converts src into dest, which must be settable. Pointers in src are dereferenced and pointers in dest are
+// allocated as needed, slices and maps are converted element by element. Numeric values are converted between numeric
+// types when they fit the destination, any other conversion requires the types to be convertible and of the same kind.
+func convertValue(src any, dest reflect.Value) error {
+	if scanner, ok := dest.Addr().Interface().(sql.Scanner); ok {
+		if isNil(src) {
+			return scanner.Scan(nil)
+		}
+		return scanner.Scan(reflect.Indirect(reflect.ValueOf(src)).Interface())
+	}
+
+	if isNil(src) {
+		switch dest.Kind() {
+		case reflect.Pointer, reflect.Interface, reflect.Slice, reflect.Map:
+			dest.Set(reflect.Zero(dest.Type()))
+			return nil
+		}
+		return fmt.Errorf("cannot scan NULL into %s", dest.Type())
+	}
+
+	value := reflect.ValueOf(src)
+	if value.Type().AssignableTo(dest.Type()) {
+		dest.Set(value)
+		return nil
+	}
+
+	switch dest.Kind() {
+	case reflect.Pointer:
+		elem := reflect.New(dest.Type().Elem())
+		if err := convertValue(src, elem.Elem()); err != nil {
+			return err
+		}
+		dest.Set(elem)
+		return nil
+	case reflect.Interface:
+		// Only reached for interfaces src does not implement.
+		return fmt.Errorf("cannot scan %T into %s", src, dest.Type())
+	}
+
+	if value.Kind() == reflect.Pointer {
+		return convertValue(value.Elem().Interface(), dest)
+	}
+
+	switch {
+	case dest.Kind() == reflect.Slice && (value.Kind() == reflect.Slice || value.Kind() == reflect.Array):
+		slice := reflect.MakeSlice(dest.Type(), value.Len(), value.Len())
+		for i := 0; i < value.Len(); i++ {
+			if err := convertValue(value.Index(i).Interface(), slice.Index(i)); err != nil {
+				return fmt.Errorf("element %d: %w", i, err)
+			}
+		}
+		dest.Set(slice)
+		return nil
+	case dest.Kind() == reflect.Map && value.Kind() == reflect.Map:
+		m := reflect.MakeMapWithSize(dest.Type(), value.Len())
+		iter := value.MapRange()
+		for iter.Next() {
+			key := reflect.New(dest.Type().Key()).Elem()
+			if err := convertValue(iter.Key().Interface(), key); err != nil {
+				return fmt.Errorf("key %v: %w", iter.Key(), err)
+			}
+			elem := reflect.New(dest.Type().Elem()).Elem()
+			if err := convertValue(iter.Value().Interface(), elem); err != nil {
+				return fmt.Errorf("value of key %v: %w", iter.Key(), err)
+			}
+			m.SetMapIndex(key, elem)
+		}
+		dest.Set(m)
+		return nil
+	case isNumeric(value.Kind()) && isNumeric(dest.Kind()):
+		return convertNumeric(value, dest)
+	case value.Kind() == dest.Kind() && value.Type().ConvertibleTo(dest.Type()):
+		dest.Set(value.Convert(dest.Type()))
+		return nil
+	}
+
+	return fmt.Errorf("cannot scan %T into %s", src, dest.Type())
+}
+
+// convertNumeric converts the numeric value into the numeric dest. Values that would overflow dest, negative values
+// for unsigned destinations and floating point values with a fraction for integer destinations are rejected instead of
+// being wrapped or truncated.
+func convertNumeric(value, dest reflect.Value) error {
+	var overflows bool
+	switch {
+	case value.CanInt():
+		i := value.Int()
+		switch {
+		case dest.CanInt():
+			overflows = dest.OverflowInt(i)
+		case dest.CanUint():
+			overflows = i < 0 || dest.OverflowUint(uint64(i))
+		}
+	case value.CanUint():
+		u := value.Uint()
+		switch {
+		case dest.CanInt():
+			overflows = u > math.MaxInt64 || dest.OverflowInt(int64(u))
+		case dest.CanUint():
+			overflows = dest.OverflowUint(u)
+		}
+	case value.CanFloat():
+		f := value.Float()
+		switch {
+		case dest.CanFloat():
+			overflows = dest.OverflowFloat(f)
+		case f != math.Trunc(f):
+			// Also true for NaN, infinities are out of range below.
+			return fmt.Errorf("cannot scan %T %v into %s: not an integer", value.Interface(), f, dest.Type())
+		case dest.CanInt():
+			overflows = f < math.MinInt64 || f >= math.MaxInt64 || dest.OverflowInt(int64(f))
+		case dest.CanUint():
+			overflows = f < 0 || f >= math.MaxUint64 || dest.OverflowUint(uint64(f))
+		}
+	}
+	if overflows {
+		return fmt.Errorf("cannot scan %T %v into %s: value out of range", value.Interface(), value, dest.Type())
+	}
+	dest.Set(value.Convert(dest.Type()))
+	return nil
+}
+
+// isNumeric reports whether kind is an integer or floating point kind.
+func isNumeric(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,161 @@
+package clickhouse_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ponrove/octobe/driver/clickhouse"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNullable(t *testing.T) {
+	var n clickhouse.Nullable[string]
+	require.NoError(t, n.Scan("octobe"))
+	require.True(t, n.Valid)
+	require.Equal(t, "octobe", n.V)
+	require.Equal(t, "octobe", *n.Ptr())
+
+	value, err := n.Value()
+	require.NoError(t, err)
+	require.Equal(t, "octobe", value)
+
+	require.NoError(t, n.Scan(nil))
+	require.False(t, n.Valid)
+	require.Empty(t, n.V)
+	require.Nil(t, n.Ptr())
+
+	value, err = n.Value()
+	require.NoError(t, err)
+	require.Nil(t, value)
+
+	var i clickhouse.Nullable[int64]
+	require.NoError(t, i.Scan(uint8(42)))
+	require.Equal(t, int64(42), i.V)
+	require.Error(t, i.Scan("42"))
+	require.False(t, i.Valid)
+}
+
+func TestScanValue(t *testing.T) {
+	name := "octobe"
+	var missing *string
+
+	s, err := clickhouse.ScanValue[string](&name)
+	require.NoError(t, err)
+	require.Equal(t, "octobe", s)
+
+	p, err := clickhouse.ScanValue[*string](missing)
+	require.NoError(t, err)
+	require.Nil(t, p)
+
+	_, err = clickhouse.ScanValue[string](missing)
+	require.ErrorContains(t, err, "cannot scan NULL into string")
+
+	n, err := clickhouse.ScanValue[clickhouse.Nullable[string]](missing)
+	require.NoError(t, err)
+	require.False(t, n.Valid)
+
+	f, err := clickhouse.ScanValue[float64](int32(3))
+	require.NoError(t, err)
+	require.Equal(t, 3.0, f)
+}
+
+func TestScanValueOutOfRange(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		scan func() error
+		err  string
+	}{
+		{"int64 into uint8", scan[uint8](int64(300)), "value out of range"},
+		{"negative into uint32", scan[uint32](int64(-1)), "value out of range"},
+		{"uint64 into int64", scan[int64](uint64(math.MaxUint64)), "value out of range"},
+		{"uint16 into int8", scan[int8](uint16(128)), "value out of range"},
+		{"fraction into int", scan[int](1.9), "not an integer"},
+		{"NaN into int32", scan[int32](math.NaN()), "not an integer"},
+		{"float into int16", scan[int16](float64(40000)), "value out of range"},
+		{"negative float into uint", scan[uint](float64(-1)), "value out of range"},
+		{"infinity into int64", scan[int64](math.Inf(1)), "value out of range"},
+		{"float64 into float32", scan[float32](1e40), "value out of range"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.ErrorContains(t, tc.scan(), tc.err)
+		})
+	}
+
+	u, err := clickhouse.ScanValue[uint8](int64(255))
+	require.NoError(t, err)
+	require.Equal(t, uint8(255), u)
+
+	i, err := clickhouse.ScanValue[int](float64(-2))
+	require.NoError(t, err)
+	require.Equal(t, -2, i)
+
+	_, err = clickhouse.ScanArray[uint8]([]int64{1, 256})
+	require.ErrorContains(t, err, "element 1")
+}
+
+func scan[T any](src any) func() error {
+	return func() error {
+		_, err := clickhouse.ScanValue[T](src)
+		return err
+	}
+}
+
+func TestScanArray(t *testing.T) {
+	ints, err := clickhouse.ScanArray[int]([]uint32{1, 2, 3})
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 2, 3}, ints)
+
+	a, b := "a", "b"
+	nullable, err := clickhouse.ScanArray[clickhouse.Nullable[string]]([]*string{&a, nil, &b})
+	require.NoError(t, err)
+	require.Equal(t, []clickhouse.Nullable[string]{{V: "a", Valid: true}, {}, {V: "b", Valid: true}}, nullable)
+
+	nested, err := clickhouse.ScanArray[[]string]([]any{[]string{"a"}, []any{"b", "c"}})
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"a"}, {"b", "c"}}, nested)
+
+	empty, err := clickhouse.ScanArray[string](nil)
+	require.NoError(t, err)
+	require.Nil(t, empty)
+
+	_, err = clickhouse.ScanArray[string]("not an array")
+	require.ErrorContains(t, err, "cannot scan string as array")
+
+	_, err = clickhouse.ScanArray[string]([]any{"a", 1})
+	require.ErrorContains(t, err, "array element 1")
+}
+
+func TestScanMap(t *testing.T) {
+	m, err := clickhouse.ScanMap[string, uint64](map[string]uint8{"a": 1, "b": 2})
+	require.NoError(t, err)
+	require.Equal(t, map[string]uint64{"a": 1, "b": 2}, m)
+
+	arrays, err := clickhouse.ScanMap[string, []int](map[string]any{"a": []int64{1, 2}})
+	require.NoError(t, err)
+	require.Equal(t, map[string][]int{"a": {1, 2}}, arrays)
+
+	_, err = clickhouse.ScanMap[string, int]([]int{1})
+	require.ErrorContains(t, err, "cannot scan []int as map")
+
+	_, err = clickhouse.ScanMap[string, int](map[string]any{"a": "b"})
+	require.ErrorContains(t, err, "map value of key a")
+}
+
+func TestScanTuple(t *testing.T) {
+	var (
+		name  string
+		count int
+		tags  []string
+		note  clickhouse.Nullable[string]
+	)
+	require.NoError(t, clickhouse.ScanTuple([]any{"octobe", uint64(3), []string{"go"}, nil}, &name, &count, &tags, &note))
+	require.Equal(t, "octobe", name)
+	require.Equal(t, 3, count)
+	require.Equal(t, []string{"go"}, tags)
+	require.False(t, note.Valid)
+
+	require.ErrorContains(t, clickhouse.ScanTuple([]any{"octobe"}, &name, &count), "tuple has 1 elements, but 2 destinations were given")
+	require.ErrorContains(t, clickhouse.ScanTuple([]any{"octobe"}, name), "tuple destination 0 is not a non-nil pointer")
+	require.ErrorContains(t, clickhouse.ScanTuple(nil, &name), "cannot scan <nil> as tuple")
+	require.ErrorContains(t, clickhouse.ScanTuple([]any{1}, &name), "tuple element 0")
+}
@@ -0,0 +1,237 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/ponrove/octobe"
+)
+
+var (
+	// ErrBatchUnsupported is returned by NewBatch for a session of the database/sql driver, which cannot send several
+	// statements in one round trip.
+	ErrBatchUnsupported = errors.New("batch requires a session of the pgx or pgxpool driver")
+	// ErrBatchNotSent is returned by BatchResult.Get before the batch of the result has been sent.
+	ErrBatchNotSent = errors.New("batch has not been sent")
+)
+
+// Batch queues queries of a session and sends them to the database in a single round trip with Send, which saves the
+// latency of a round trip per statement. Queue, QueueOne, QueueAll and QueueExec return a BatchResult for every query,
+// holding its typed result once the batch has been sent:
+//
+//	batch, err := postgres.NewBatch(session)
+//	product := postgres.QueueOne(batch, "SELECT name FROM products WHERE id = $1", []any{id}, scanName)
+//	updated := batch.QueueExec("UPDATE stock SET reserved = reserved + 1 WHERE product_id = $1", id)
+//	err = batch.Send()
+//	name, err := product.Get()
+//
+// The queries run in order, in the transaction of the session if it has one, and otherwise in an implicit transaction
+// of their own, so a failing query rolls back the queries before it. A Batch is not safe for concurrent use.
+type Batch struct {
+	ctx    context.Context
+	driver string
+	send   func(ctx context.Context, batch *pgx.Batch) pgx.BatchResults
+	// acquire takes a slot of the priority queue of a pool for sending the batch, if the session needs one.
+	acquire func(ctx context.Context) (release func(), err error)
+	queries []batchQuery
+	sent    bool
+}
+
+// batchQuery is a query queued in a batch.
+type batchQuery struct {
+	operation octobe.Operation
+	query     string
+	args      []any
+	// read reads the result of the query from results, returning the number of rows it returned or affected.
+	read   func(results pgx.BatchResults) (int64, error)
+	result interface{ resolve(err error) }
+}
+
+// BatchResult is the result of a query queued in a Batch, it is available once the batch has been sent.
+type BatchResult[T any] struct {
+	value T
+	err   error
+}
+
+// Get returns the result of the query, or the error the query failed with. It returns ErrBatchNotSent before the
+// batch has been sent.
+func (r *BatchResult[T]) Get() (T, error) {
+	return r.value, r.err
+}
+
+// resolve completes the result with err, dropping the value if the query failed.
+func (r *BatchResult[T]) resolve(err error) {
+	r.err = err
+	if err != nil {
+		var zero T
+		r.value = zero
+	}
+}
+
+// NewBatch returns an empty batch for session of the pgx or pgxpool driver. Sessions of the database/sql driver fail
+// with ErrBatchUnsupported.
+func NewBatch(session octobe.BuilderSession[Builder]) (*Batch, error) {
+	var current any = session
+	for {
+		switch s := current.(type) {
+		case *pgxSession:
+			b := &Batch{ctx: s.ctx, driver: s.d.Describe().Name, send: s.d.conn.SendBatch}
+			if s.tx != nil {
+				b.send = s.tx.SendBatch
+			}
+			return b, nil
+		case *pgxpoolSession:
+			b := &Batch{ctx: s.ctx, driver: s.d.Describe().Name, send: s.d.pool.SendBatch}
+			if s.tx != nil {
+				b.send = s.tx.SendBatch
+				return b, nil
+			}
+			d, priority := s.d, s.cfg.priority
+			b.acquire = func(ctx context.Context) (func(), error) {
+				return d.acquire(ctx, priority)
+			}
+			return b, nil
+		case sessionUnwrapper:
+			current = s.Unwrap()
+		default:
+			return nil, ErrBatchUnsupported
+		}
+	}
+}
+
+// Queue queues query with args in b and returns its result, which read produces from the rows of the query once the
+// batch is sent. Rows left unread by read are discarded.
+func Queue[T any](b *Batch, query string, args []any, read func(rows Rows) (T, error)) *BatchResult[T] {
+	result := &BatchResult[T]{err: ErrBatchNotSent}
+	b.queries = append(b.queries, batchQuery{
+		operation: octobe.OperationQuery,
+		query:     query,
+		args:      args,
+		result:    result,
+		read: func(results pgx.BatchResults) (int64, error) {
+			rows, err := results.Query()
+			if err != nil {
+				return -1, err
+			}
+			result.value, err = read(rows)
+			rows.Close()
+			if err == nil {
+				err = rows.Err()
+			}
+			return rows.CommandTag().RowsAffected(), err
+		},
+	})
+	return result
+}
+
+// QueueAll queues query with args in b and returns its result, every row scanned with scan, see QueryAll.
+func QueueAll[T any](b *Batch, query string, args []any, scan ScanFunc[T]) *BatchResult[[]T] {
+	return Queue(b, query, args, func(rows Rows) ([]T, error) {
+		values := []T{}
+		for rows.Next() {
+			value, err := scan(rows)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, rows.Err()
+	})
+}
+
+// QueueOne queues query with args in b and returns its result, the first row scanned with scan. The result holds
+// pgx.ErrNoRows if the query returns no rows, see QueryOne.
+func QueueOne[T any](b *Batch, query string, args []any, scan ScanFunc[T]) *BatchResult[T] {
+	return Queue(b, query, args, func(rows Rows) (T, error) {
+		if !rows.Next() {
+			var zero T
+			if err := rows.Err(); err != nil {
+				return zero, err
+			}
+			return zero, pgx.ErrNoRows
+		}
+		return scan(rows)
+	})
+}
+
+// QueueExec queues the statement query with args in b and returns its result.
+func (b *Batch) QueueExec(query string, args ...any) *BatchResult[ExecResult] {
+	result := &BatchResult[ExecResult]{err: ErrBatchNotSent}
+	b.queries = append(b.queries, batchQuery{
+		operation: octobe.OperationExec,
+		query:     query,
+		args:      args,
+		result:    result,
+		read: func(results pgx.BatchResults) (int64, error) {
+			tag, err := results.Exec()
+			if err != nil {
+				return -1, err
+			}
+			result.value = tagResult(tag)
+			return result.value.RowsAffected, nil
+		},
+	})
+	return result
+}
+
+// Len returns the number of queries queued in b.
+func (b *Batch) Len() int {
+	return len(b.queries)
+}
+
+// Send sends the queued queries in a single round trip and reads their results, it returns the error of the first
+// query that failed. A failed query aborts the transaction the batch runs in, so the queries after it fail as well.
+// Query hooks see every query of the batch as a statement of its own. A batch can be sent once, sending it again
+// returns octobe.ErrAlreadyUsed.
+func (b *Batch) Send() error {
+	if b.sent {
+		return octobe.ErrAlreadyUsed
+	}
+	b.sent = true
+	if len(b.queries) == 0 {
+		return nil
+	}
+
+	batch := &pgx.Batch{}
+	dones := make([]func(rows int64, err error), len(b.queries))
+	for i, q := range b.queries {
+		var ctx context.Context
+		ctx, dones[i] = octobe.BeginQuery(b.ctx, q.operation, q.query, q.args)
+		batch.Queue(octobe.CommentQuery(ctx, q.query), q.args...)
+	}
+	if b.acquire != nil {
+		release, err := b.acquire(b.ctx)
+		if err != nil {
+			// The batch cannot be sent, every query fails with the error.
+			first := b.finish(0, dones[0], -1, err)
+			for i := 1; i < len(b.queries); i++ {
+				b.finish(i, dones[i], -1, err)
+			}
+			return first
+		}
+		defer release()
+	}
+
+	results := b.send(b.ctx, batch)
+	var err error
+	for i, q := range b.queries {
+		rows, queryErr := q.read(results)
+		if queryErr = b.finish(i, dones[i], rows, queryErr); queryErr != nil && err == nil {
+			err = queryErr
+		}
+	}
+	if closeErr := results.Close(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// finish resolves the result of the query at index i with err and reports the query to the hooks with done.
+func (b *Batch) finish(i int, done func(rows int64, err error), rows int64, err error) error {
+	q := b.queries[i]
+	err = wrapQueryError(b.driver, q.query, q.args, err)
+	q.result.resolve(err)
+	done(rows, err)
+	return err
+}
@@ -0,0 +1,139 @@
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/driver/postgres"
+	"github.com/stretchr/testify/require"
+)
+
+func scanName(rows postgres.Rows) (string, error) {
+	var name string
+	err := rows.Scan(&name)
+	return name, err
+}
+
+func TestBatch(t *testing.T) {
+	ctx := context.Background()
+	mock, err := pgxmock.NewConn()
+	require.NoError(t, err)
+	defer mock.Close(ctx)
+
+	mock.ExpectBegin()
+	batch := mock.ExpectBatch()
+	batch.ExpectQuery("SELECT name FROM products WHERE id").WithArgs(int64(1)).
+		WillReturnRows(pgxmock.NewRows([]string{"name"}).AddRow("chair"))
+	batch.ExpectQuery("SELECT name FROM products ORDER BY name").
+		WillReturnRows(pgxmock.NewRows([]string{"name"}).AddRow("chair").AddRow("table"))
+	batch.ExpectExec("UPDATE stock").WithArgs(int64(1)).WillReturnResult(pgxmock.NewResult("UPDATE", 3))
+	mock.ExpectCommit()
+
+	hook := &dbtxHook{}
+	ob, err := octobe.New(postgres.OpenPGXWithConn(mock), octobe.WithQueryHook(hook))
+	require.NoError(t, err)
+	require.True(t, ob.Capabilities().Batch)
+
+	err = ob.StartTransaction(ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+		batch, err := postgres.NewBatch(session)
+		require.NoError(t, err)
+		product := postgres.QueueOne(batch, "SELECT name FROM products WHERE id = $1", []any{int64(1)}, scanName)
+		products := postgres.QueueAll(batch, "SELECT name FROM products ORDER BY name", nil, scanName)
+		updated := batch.QueueExec("UPDATE stock SET reserved = reserved + 1 WHERE product_id = $1", int64(1))
+		require.Equal(t, 3, batch.Len())
+
+		_, err = product.Get()
+		require.ErrorIs(t, err, postgres.ErrBatchNotSent)
+
+		require.NoError(t, batch.Send())
+		require.ErrorIs(t, batch.Send(), octobe.ErrAlreadyUsed)
+
+		name, err := product.Get()
+		require.NoError(t, err)
+		require.Equal(t, "chair", name)
+		names, err := products.Get()
+		require.NoError(t, err)
+		require.Equal(t, []string{"chair", "table"}, names)
+		result, err := updated.Get()
+		require.NoError(t, err)
+		require.Equal(t, int64(3), result.RowsAffected)
+		return nil
+	}, postgres.WithPGXTxOptions(postgres.PGXTxOptions{}))
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	require.Len(t, hook.events, 3)
+	require.Equal(t, octobe.OperationQuery, hook.events[0].Operation)
+	require.Equal(t, octobe.OperationExec, hook.events[2].Operation)
+	require.Equal(t, int64(3), hook.events[2].Rows)
+}
+
+func TestBatchQueryError(t *testing.T) {
+	ctx := context.Background()
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	failed := errors.New("relation does not exist")
+	batch := mock.ExpectBatch()
+	batch.ExpectQuery("SELECT name FROM missing").WillReturnError(failed)
+	batch.ExpectQuery("SELECT name FROM products WHERE id").WithArgs(int64(2)).
+		WillReturnRows(pgxmock.NewRows([]string{"name"}))
+
+	ob, err := octobe.New(postgres.OpenPGXPoolWithPool(mock))
+	require.NoError(t, err)
+	session, err := ob.Begin(ctx)
+	require.NoError(t, err)
+
+	b, err := postgres.NewBatch(session)
+	require.NoError(t, err)
+	missing := postgres.QueueAll(b, "SELECT name FROM missing", nil, scanName)
+	product := postgres.QueueOne(b, "SELECT name FROM products WHERE id = $1", []any{int64(2)}, scanName)
+
+	err = b.Send()
+	require.ErrorIs(t, err, failed)
+	var queryErr *octobe.QueryError
+	require.ErrorAs(t, err, &queryErr)
+	require.Equal(t, "SELECT name FROM missing", queryErr.Query)
+
+	names, err := missing.Get()
+	require.ErrorIs(t, err, failed)
+	require.Nil(t, names)
+	_, err = product.Get()
+	require.ErrorIs(t, err, pgx.ErrNoRows)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBatchEmpty(t *testing.T) {
+	mock, err := pgxmock.NewConn()
+	require.NoError(t, err)
+	ob, err := octobe.New(postgres.OpenPGXWithConn(mock))
+	require.NoError(t, err)
+	session, err := ob.Begin(context.Background())
+	require.NoError(t, err)
+
+	b, err := postgres.NewBatch(session)
+	require.NoError(t, err)
+	require.NoError(t, b.Send())
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBatchUnsupported(t *testing.T) {
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	ob, err := octobe.New(postgres.OpenWithConn(db))
+	require.NoError(t, err)
+	require.False(t, ob.Capabilities().Batch)
+	session, err := ob.Begin(context.Background())
+	require.NoError(t, err)
+
+	_, err = postgres.NewBatch(session)
+	require.ErrorIs(t, err, postgres.ErrBatchUnsupported)
+}
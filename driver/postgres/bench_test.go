@@ -0,0 +1,49 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/driver/postgres"
+)
+
+// benchConn is a connection that answers every QueryRow with a single value, so benchmarks measure the overhead of the
+// driver rather than of a mock. Calling any other method panics.
+type benchConn struct {
+	postgres.PGXConn
+}
+
+// QueryRow returns a row that scans 1 into its destination.
+func (benchConn) QueryRow(context.Context, string, ...any) pgx.Row {
+	return benchRow{}
+}
+
+// benchRow is a row with a single integer column.
+type benchRow struct{}
+
+// Scan sets the first destination to 1.
+func (benchRow) Scan(dest ...any) error {
+	*dest[0].(*int) = 1
+	return nil
+}
+
+func BenchmarkPGXQueryRow(b *testing.B) {
+	ob, err := octobe.New(postgres.OpenPGXWithConn(benchConn{}))
+	if err != nil {
+		b.Fatal(err)
+	}
+	session, err := ob.Begin(context.Background())
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	var id int
+	for b.Loop() {
+		if err := session.Builder()("SELECT id FROM products WHERE id = $1").Arguments(1).QueryRow(&id); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ponrove/octobe"
+)
+
+// Ensure the drivers can validate the queries of the catalog.
+var (
+	_ octobe.QueryValidator = &pgxConn{}
+	_ octobe.QueryValidator = &pgxpoolConn{}
+	_ octobe.QueryValidator = &sqlConn{}
+)
+
+// Named builds a segment for the query registered under name with octobe.RegisterQuery or octobe.RegisterQueries. It
+// panics if no query is registered under name.
+func (b Builder) Named(name string) Segment {
+	return b(octobe.MustQuery(name))
+}
+
+// ValidateQuery prepares query as an unnamed statement, which checks its syntax and the tables and columns it refers to.
+func (d *pgxConn) ValidateQuery(ctx context.Context, query string) error {
+	_, err := d.conn.Prepare(ctx, "", query)
+	return err
+}
+
+// ValidateQuery prepares query as an unnamed statement within a transaction that is rolled back, so that the statement
+// is prepared and released on the same connection of the pool.
+func (d *pgxpoolConn) ValidateQuery(ctx context.Context, query string) error {
+	tx, err := d.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Prepare(ctx, "", query)
+	return errors.Join(err, tx.Rollback(ctx))
+}
+
+// ValidateQuery prepares query and closes the prepared statement.
+func (d *sqlConn) ValidateQuery(ctx context.Context, query string) error {
+	stmt, err := d.sqlDB.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	return stmt.Close()
+}
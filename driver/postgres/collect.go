@@ -0,0 +1,61 @@
+package postgres
+
+import (
+	"database/sql"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/ponrove/octobe"
+)
+
+// ScanFunc scans the current row of rows into a value of type T.
+type ScanFunc[T any] func(rows Rows) (T, error)
+
+// QueryAll performs query with args in session and scans every row of the result with scan, returning the values in
+// the order of the rows. An empty result returns an empty slice.
+func QueryAll[T any](session octobe.BuilderSession[Builder], query string, args []any, scan ScanFunc[T]) ([]T, error) {
+	result := []T{}
+	err := session.Builder()(query).Arguments(args...).Query(func(rows Rows) error {
+		for rows.Next() {
+			value, err := scan(rows)
+			if err != nil {
+				return err
+			}
+			result = append(result, value)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// QueryOne performs query with args in session and scans the first row of the result with scan. It returns the no rows
+// error of the driver, pgx.ErrNoRows or sql.ErrNoRows, if the result is empty, which matches octobe.ErrNoRows.
+func QueryOne[T any](session octobe.BuilderSession[Builder], query string, args []any, scan ScanFunc[T]) (T, error) {
+	var value T
+	err := session.Builder()(query).Arguments(args...).Query(func(rows Rows) error {
+		if !rows.Next() {
+			if err := rows.Err(); err != nil {
+				return err
+			}
+			return noRows(rows)
+		}
+		var err error
+		value, err = scan(rows)
+		return err
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return value, nil
+}
+
+// noRows returns the no rows error of the driver behind rows.
+func noRows(rows Rows) error {
+	if _, ok := unwrapRows(rows).(pgx.Rows); ok {
+		return pgx.ErrNoRows
+	}
+	return sql.ErrNoRows
+}
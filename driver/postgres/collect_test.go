@@ -0,0 +1,120 @@
+package postgres_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/driver/postgres"
+	"github.com/stretchr/testify/assert"
+)
+
+type product struct {
+	ID   int
+	Name string
+}
+
+func scanProduct(rows postgres.Rows) (product, error) {
+	var p product
+	err := rows.Scan(&p.ID, &p.Name)
+	return p, err
+}
+
+func TestQueryAll(t *testing.T) {
+	mock, err := pgxmock.NewConn()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	ctx := context.Background()
+	defer mock.Close(ctx)
+
+	mock.ExpectQuery("SELECT id, name FROM products WHERE price").WithArgs(10).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "name"}).AddRow(1, "a").AddRow(2, "b"))
+	mock.ExpectQuery("SELECT id, name FROM products WHERE price").WithArgs(20).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "name"}))
+
+	ob, err := octobe.New(postgres.OpenPGXWithConn(mock))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	session, err := ob.Begin(ctx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	products, err := postgres.QueryAll(session, "SELECT id, name FROM products WHERE price > $1", []any{10}, scanProduct)
+	assert.NoError(t, err)
+	assert.Equal(t, []product{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}}, products)
+
+	products, err = postgres.QueryAll(session, "SELECT id, name FROM products WHERE price > $1", []any{20}, scanProduct)
+	assert.NoError(t, err)
+	assert.Empty(t, products)
+	assert.NotNil(t, products)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestQueryOne(t *testing.T) {
+	mock, err := pgxmock.NewConn()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	ctx := context.Background()
+	defer mock.Close(ctx)
+
+	mock.ExpectQuery("SELECT id, name FROM products WHERE id").WithArgs(1).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "name"}).AddRow(1, "a"))
+	mock.ExpectQuery("SELECT id, name FROM products WHERE id").WithArgs(2).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "name"}))
+
+	ob, err := octobe.New(postgres.OpenPGXWithConn(mock))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	session, err := ob.Begin(ctx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	p, err := postgres.QueryOne(session, "SELECT id, name FROM products WHERE id = $1", []any{1}, scanProduct)
+	assert.NoError(t, err)
+	assert.Equal(t, product{ID: 1, Name: "a"}, p)
+
+	_, err = postgres.QueryOne(session, "SELECT id, name FROM products WHERE id = $1", []any{2}, scanProduct)
+	assert.ErrorIs(t, err, pgx.ErrNoRows)
+	assert.ErrorIs(t, err, octobe.ErrNoRows)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLQueryOneNoRows(t *testing.T) {
+	t.Parallel()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	query := "SELECT id, name FROM products WHERE id = $1"
+	mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+
+	instance, err := octobe.New(postgres.OpenWithConn(db))
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := instance.Begin(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = postgres.QueryOne(session, query, []any{1}, scanProduct); !errors.Is(err, sql.ErrNoRows) || !errors.Is(err, octobe.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows, got %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/internal/dbtag"
+)
+
+// ErrCopyUnsupported is returned by CopyFrom for a session of the database/sql driver, which has no access to the COPY
+// protocol.
+var ErrCopyUnsupported = errors.New("copy requires a session of the pgx or pgxpool driver")
+
+// copier copies rows into a table on the transaction or connection of a session.
+type copier struct {
+	ctx    context.Context
+	driver string
+	copy   func(ctx context.Context, table pgx.Identifier, columns []string, source pgx.CopyFromSource) (int64, error)
+}
+
+// copierOf returns the copier of the pgx or pgxpool session behind session, unwrapping the session of octobe.
+func copierOf(session any) (copier, bool) {
+	for {
+		switch s := session.(type) {
+		case *pgxSession:
+			if s.tx != nil {
+				return copier{ctx: s.ctx, driver: s.d.Describe().Name, copy: s.tx.CopyFrom}, true
+			}
+			return copier{ctx: s.ctx, driver: s.d.Describe().Name, copy: s.d.conn.CopyFrom}, true
+		case *pgxpoolSession:
+			if s.tx != nil {
+				return copier{ctx: s.ctx, driver: s.d.Describe().Name, copy: s.tx.CopyFrom}, true
+			}
+			d, priority := s.d, s.cfg.priority
+			return copier{ctx: s.ctx, driver: d.Describe().Name, copy: func(ctx context.Context, table pgx.Identifier, columns []string, source pgx.CopyFromSource) (int64, error) {
+				release, err := d.acquire(ctx, priority)
+				if err != nil {
+					return 0, err
+				}
+				defer release()
+				return d.pool.CopyFrom(ctx, table, columns, source)
+			}}, true
+		case sessionUnwrapper:
+			session = s.Unwrap()
+		default:
+			return copier{}, false
+		}
+	}
+}
+
+// CopyFrom bulk loads the rows of source into columns of table with the COPY protocol, within the transaction of
+// session if it has one, and returns the number of rows copied. It is much faster than inserting the rows one by one,
+// but fails as a whole if any row is rejected. Query hooks see the copy as a statement of octobe.OperationCopy. Sessions
+// of the database/sql driver fail with ErrCopyUnsupported.
+func CopyFrom(session octobe.BuilderSession[Builder], table pgx.Identifier, columns []string, source pgx.CopyFromSource) (rows int64, err error) {
+	c, ok := copierOf(session)
+	if !ok {
+		return 0, ErrCopyUnsupported
+	}
+
+	query := "COPY " + table.Sanitize() + " (" + quoteIdentifiers(columns) + ") FROM STDIN"
+
+	ctx, done := octobe.BeginQuery(c.ctx, octobe.OperationCopy, query, nil)
+	defer func() {
+		err = wrapQueryError(c.driver, query, nil, err)
+		done(rows, err)
+	}()
+	return c.copy(ctx, table, columns, source)
+}
+
+// CopyFromStructs bulk loads rows, structs or struct pointers, into table with CopyFrom. The columns are the db tags of
+// the fields of the struct, see QueryStructs.
+func CopyFromStructs[T any](session octobe.BuilderSession[Builder], table pgx.Identifier, rows []T) (int64, error) {
+	t := reflect.TypeFor[T]()
+	pointer := t.Kind() == reflect.Pointer
+	if pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return 0, fmt.Errorf("cannot copy rows of %s, expected a struct or struct pointer", reflect.TypeFor[T]())
+	}
+	fields := dbtag.Fields(t)
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("cannot copy rows of %s, it has no fields mapped to columns", t)
+	}
+
+	columns := make([]string, len(fields))
+	for i, field := range fields {
+		columns[i] = field.Column
+	}
+	source := pgx.CopyFromSlice(len(rows), func(i int) ([]any, error) {
+		value := reflect.ValueOf(&rows[i]).Elem()
+		if pointer {
+			if value.IsNil() {
+				return nil, fmt.Errorf("row %d is nil", i)
+			}
+			value = value.Elem()
+		}
+		values := make([]any, len(fields))
+		for j, field := range fields {
+			// A field behind a nil embedded pointer is copied as NULL.
+			if v, err := value.FieldByIndexErr(field.Index); err == nil {
+				values[j] = v.Interface()
+			}
+		}
+		return values, nil
+	})
+	return CopyFrom(session, table, columns, source)
+}
@@ -0,0 +1,82 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/driver/postgres"
+	"github.com/stretchr/testify/require"
+)
+
+type copyProduct struct {
+	ID       int64  `db:"id"`
+	Name     string `db:"name"`
+	Internal string `db:"-"`
+}
+
+func TestCopyFrom(t *testing.T) {
+	ctx := context.Background()
+	mock, err := pgxmock.NewConn()
+	require.NoError(t, err)
+	defer mock.Close(ctx)
+
+	mock.ExpectBegin()
+	mock.ExpectCopyFrom(pgx.Identifier{"public", "products"}, []string{"id", "name"}).WillReturnResult(2)
+	mock.ExpectCopyFrom(pgx.Identifier{"products"}, []string{"id", "name"}).WillReturnResult(2)
+	mock.ExpectCommit()
+
+	hook := &dbtxHook{}
+	ob, err := octobe.New(postgres.OpenPGXWithConn(mock), octobe.WithQueryHook(hook))
+	require.NoError(t, err)
+	require.True(t, ob.Capabilities().Copy)
+
+	err = ob.StartTransaction(ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+		rows, err := postgres.CopyFrom(session, pgx.Identifier{"public", "products"}, []string{"id", "name"},
+			pgx.CopyFromRows([][]any{{int64(1), "chair"}, {int64(2), "table"}}))
+		require.NoError(t, err)
+		require.Equal(t, int64(2), rows)
+
+		rows, err = postgres.CopyFromStructs(session, pgx.Identifier{"products"}, []*copyProduct{{ID: 1, Name: "chair"}, {ID: 2, Name: "table"}})
+		require.NoError(t, err)
+		require.Equal(t, int64(2), rows)
+		return nil
+	}, postgres.WithPGXTxOptions(postgres.PGXTxOptions{}))
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	require.Len(t, hook.events, 2)
+	require.Equal(t, octobe.OperationCopy, hook.events[0].Operation)
+	require.Equal(t, `COPY "public"."products" ("id", "name") FROM STDIN`, hook.events[0].Query)
+	require.Equal(t, int64(2), hook.events[0].Rows)
+}
+
+func TestCopyFromStructsInvalid(t *testing.T) {
+	mock, err := pgxmock.NewConn()
+	require.NoError(t, err)
+	ob, err := octobe.New(postgres.OpenPGXWithConn(mock))
+	require.NoError(t, err)
+	session, err := ob.Begin(context.Background())
+	require.NoError(t, err)
+
+	_, err = postgres.CopyFromStructs(session, pgx.Identifier{"products"}, []int{1})
+	require.ErrorContains(t, err, "expected a struct")
+}
+
+func TestCopyFromUnsupported(t *testing.T) {
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	ob, err := octobe.New(postgres.OpenWithConn(db))
+	require.NoError(t, err)
+	require.False(t, ob.Capabilities().Copy)
+	session, err := ob.Begin(context.Background())
+	require.NoError(t, err)
+
+	_, err = postgres.CopyFrom(session, pgx.Identifier{"products"}, []string{"id"}, pgx.CopyFromRows(nil))
+	require.ErrorIs(t, err, postgres.ErrCopyUnsupported)
+}
@@ -0,0 +1,40 @@
+package postgres
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/ponrove/octobe"
+)
+
+// sqlStateClassIntegrity is the SQLSTATE class of integrity constraint violations.
+const sqlStateClassIntegrity = "23"
+
+// wrapQueryError wraps the error of a query like octobe.WrapQueryError, after wrapping a PostgreSQL error violating
+// an integrity constraint in an *octobe.ConstraintError.
+func wrapQueryError(driver, query string, args []any, err error) error {
+	return octobe.WrapQueryError(driver, query, args, constraintError(err))
+}
+
+// constraintError returns err wrapped in an *octobe.ConstraintError if it is a PostgreSQL error of an integrity
+// constraint violation, and err as is otherwise.
+func constraintError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || !strings.HasPrefix(pgErr.Code, sqlStateClassIntegrity) {
+		return err
+	}
+	var constraintErr *octobe.ConstraintError
+	if errors.As(err, &constraintErr) {
+		return err
+	}
+	return &octobe.ConstraintError{
+		SQLState:   pgErr.Code,
+		Constraint: pgErr.ConstraintName,
+		Schema:     pgErr.SchemaName,
+		Table:      pgErr.TableName,
+		Column:     pgErr.ColumnName,
+		Detail:     pgErr.Detail,
+		Err:        err,
+	}
+}
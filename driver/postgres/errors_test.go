@@ -0,0 +1,57 @@
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/driver/postgres"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConstraintError(t *testing.T) {
+	ctx := context.Background()
+	mock, err := pgxmock.NewConn()
+	require.NoError(t, err)
+	defer mock.Close(ctx)
+
+	mock.ExpectExec("INSERT INTO products").WithArgs("CH-1").WillReturnError(&pgconn.PgError{
+		Code:           "23505",
+		Message:        "duplicate key value violates unique constraint",
+		Detail:         "Key (sku)=(CH-1) already exists.",
+		SchemaName:     "public",
+		TableName:      "products",
+		ConstraintName: "products_sku_key",
+	})
+	mock.ExpectExec("INSERT INTO missing").WithArgs("CH-1").WillReturnError(&pgconn.PgError{Code: "42P01"})
+
+	ob, err := octobe.New(postgres.OpenPGXWithConn(mock))
+	require.NoError(t, err)
+	session, err := ob.Begin(ctx)
+	require.NoError(t, err)
+
+	_, err = session.Builder()("INSERT INTO products (sku) VALUES ($1)").Arguments("CH-1").Exec()
+	var constraintErr *octobe.ConstraintError
+	require.ErrorAs(t, err, &constraintErr)
+	require.Equal(t, "23505", constraintErr.SQLState)
+	require.Equal(t, "products_sku_key", constraintErr.Constraint)
+	require.Equal(t, "public", constraintErr.Schema)
+	require.Equal(t, "products", constraintErr.Table)
+	require.Equal(t, "Key (sku)=(CH-1) already exists.", constraintErr.Detail)
+
+	// The error of the driver and the query stay available.
+	var pgErr *pgconn.PgError
+	require.ErrorAs(t, err, &pgErr)
+	var queryErr *octobe.QueryError
+	require.ErrorAs(t, err, &queryErr)
+	require.Equal(t, "INSERT INTO products (sku) VALUES ($1)", queryErr.Query)
+
+	// Errors other than constraint violations are not wrapped.
+	_, err = session.Builder()("INSERT INTO missing (sku) VALUES ($1)").Arguments("CH-1").Exec()
+	require.Error(t, err)
+	require.False(t, errors.As(err, &constraintErr))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
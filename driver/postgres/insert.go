@@ -0,0 +1,140 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/ponrove/octobe"
+)
+
+// maxParameters is the number of parameters PostgreSQL accepts in a single statement.
+const maxParameters = 65535
+
+// InsertOption is a signature for configuring how InsertRows inserts the rows.
+type InsertOption func(cfg *insertOptions)
+
+// insertOptions holds the configuration of InsertRows.
+type insertOptions struct {
+	chunkSize     int
+	copyThreshold int
+}
+
+// WithInsertChunkSize limits the number of rows InsertRows inserts with a single statement to n. By default a
+// statement holds as many rows as fit into the 65535 parameters PostgreSQL accepts, a value of zero or less restores
+// that.
+func WithInsertChunkSize(n int) InsertOption {
+	return func(cfg *insertOptions) {
+		cfg.chunkSize = n
+	}
+}
+
+// WithCopyThreshold makes InsertRows load the rows with CopyFrom instead of INSERT statements once there are at least
+// n of them, for sessions of the pgx and pgxpool drivers. COPY is much faster for many rows, but fails as a whole if
+// any row is rejected. Sessions of the database/sql driver keep inserting the rows. A value of zero or less, the
+// default, never copies.
+func WithCopyThreshold(n int) InsertOption {
+	return func(cfg *insertOptions) {
+		cfg.copyThreshold = n
+	}
+}
+
+// InsertRows inserts rows, each holding the values of columns in order, into table and returns the number of rows
+// inserted:
+//
+//	inserted, err := postgres.InsertRows(session, "products", []string{"id", "name"}, [][]any{
+//		{1, "chair"},
+//		{2, "table"},
+//	})
+//
+// The rows are inserted with multi-row INSERT statements, split into chunks so no statement exceeds the parameter
+// limit of PostgreSQL, which run in session one after another like any other segment. Outside a transaction, the
+// chunks before a failing one stay inserted. The table may be qualified by its schema, like public.products, table and
+// columns are quoted as identifiers.
+func InsertRows(session octobe.BuilderSession[Builder], table string, columns []string, rows [][]any, opts ...InsertOption) (int64, error) {
+	var cfg insertOptions
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if len(columns) == 0 {
+		return 0, errors.New("cannot insert rows without columns")
+	}
+	for i, row := range rows {
+		if len(row) != len(columns) {
+			return 0, fmt.Errorf("row %d has %d values, expected %d for the columns", i, len(row), len(columns))
+		}
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	if cfg.copyThreshold > 0 && len(rows) >= cfg.copyThreshold {
+		if _, ok := copierOf(session); ok {
+			return CopyFrom(session, tableIdentifier(table), columns, pgx.CopyFromRows(rows))
+		}
+	}
+
+	chunkSize := maxParameters / len(columns)
+	if cfg.chunkSize > 0 && cfg.chunkSize < chunkSize {
+		chunkSize = cfg.chunkSize
+	}
+
+	prefix := insertPrefix(table, columns)
+
+	var inserted int64
+	for start := 0; start < len(rows); start += chunkSize {
+		chunk := rows[start:min(start+chunkSize, len(rows))]
+		query, args := insertStatement(prefix, len(columns), chunk)
+		result, err := session.Builder()(query).Arguments(args...).Exec()
+		if err != nil {
+			return inserted, err
+		}
+		inserted += result.RowsAffected
+	}
+	return inserted, nil
+}
+
+// tableIdentifier returns the identifier of table, which may be qualified by its schema.
+func tableIdentifier(table string) pgx.Identifier {
+	return pgx.Identifier(strings.Split(table, "."))
+}
+
+// quoteIdentifiers returns the quoted identifiers of columns, separated by commas.
+func quoteIdentifiers(columns []string) string {
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = pgx.Identifier{column}.Sanitize()
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// insertPrefix returns the start of an INSERT statement into columns of table, up to the rows of its VALUES clause.
+func insertPrefix(table string, columns []string) string {
+	return "INSERT INTO " + tableIdentifier(table).Sanitize() + " (" + quoteIdentifiers(columns) + ") VALUES "
+}
+
+// insertStatement returns the INSERT statement starting with prefix for rows with the given number of columns, and
+// its arguments.
+func insertStatement(prefix string, columns int, rows [][]any) (string, []any) {
+	var query strings.Builder
+	query.WriteString(prefix)
+	args := make([]any, 0, len(rows)*columns)
+	for i, row := range rows {
+		if i > 0 {
+			query.WriteString(", ")
+		}
+		query.WriteByte('(')
+		for j, value := range row {
+			if j > 0 {
+				query.WriteString(", ")
+			}
+			args = append(args, value)
+			query.WriteByte('$')
+			query.WriteString(strconv.Itoa(len(args)))
+		}
+		query.WriteByte(')')
+	}
+	return query.String(), args
+}
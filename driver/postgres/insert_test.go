@@ -0,0 +1,93 @@
+package postgres_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/driver/postgres"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInsertRows(t *testing.T) {
+	ctx := context.Background()
+	mock, err := pgxmock.NewConn()
+	require.NoError(t, err)
+	defer mock.Close(ctx)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "public"."products" ("id", "name") VALUES ($1, $2), ($3, $4)`)).
+		WithArgs(1, "chair", 2, "table").WillReturnResult(pgxmock.NewResult("INSERT", 2))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "public"."products" ("id", "name") VALUES ($1, $2)`)).
+		WithArgs(3, "lamp").WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectCopyFrom(pgx.Identifier{"products"}, []string{"id", "name"}).WillReturnResult(3)
+	mock.ExpectCommit()
+
+	hook := &dbtxHook{}
+	ob, err := octobe.New(postgres.OpenPGXWithConn(mock), octobe.WithQueryHook(hook))
+	require.NoError(t, err)
+
+	rows := [][]any{{1, "chair"}, {2, "table"}, {3, "lamp"}}
+	err = ob.StartTransaction(ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+		inserted, err := postgres.InsertRows(session, "public.products", []string{"id", "name"}, rows,
+			postgres.WithInsertChunkSize(2))
+		require.NoError(t, err)
+		require.Equal(t, int64(3), inserted)
+
+		inserted, err = postgres.InsertRows(session, "products", []string{"id", "name"}, rows,
+			postgres.WithCopyThreshold(3))
+		require.NoError(t, err)
+		require.Equal(t, int64(3), inserted)
+		return nil
+	}, postgres.WithPGXTxOptions(postgres.PGXTxOptions{}))
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	require.Len(t, hook.events, 3)
+	require.Equal(t, octobe.OperationExec, hook.events[0].Operation)
+	require.Equal(t, octobe.OperationCopy, hook.events[2].Operation)
+}
+
+func TestInsertRowsSQL(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "products" ("id", "name") VALUES ($1, $2), ($3, $4)`)).
+		WithArgs(1, "chair", 2, "table").WillReturnResult(sqlmock.NewResult(0, 2))
+
+	ob, err := octobe.New(postgres.OpenWithConn(db))
+	require.NoError(t, err)
+	session, err := ob.Begin(context.Background())
+	require.NoError(t, err)
+
+	// The database/sql driver cannot copy, the rows are inserted instead.
+	inserted, err := postgres.InsertRows(session, "products", []string{"id", "name"}, [][]any{{1, "chair"}, {2, "table"}},
+		postgres.WithCopyThreshold(1))
+	require.NoError(t, err)
+	require.Equal(t, int64(2), inserted)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInsertRowsInvalid(t *testing.T) {
+	mock, err := pgxmock.NewConn()
+	require.NoError(t, err)
+	ob, err := octobe.New(postgres.OpenPGXWithConn(mock))
+	require.NoError(t, err)
+	session, err := ob.Begin(context.Background())
+	require.NoError(t, err)
+
+	_, err = postgres.InsertRows(session, "products", nil, [][]any{{1}})
+	require.Error(t, err)
+	_, err = postgres.InsertRows(session, "products", []string{"id", "name"}, [][]any{{1}})
+	require.ErrorContains(t, err, "row 0 has 1 values")
+
+	inserted, err := postgres.InsertRows(session, "products", []string{"id"}, nil)
+	require.NoError(t, err)
+	require.Zero(t, inserted)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
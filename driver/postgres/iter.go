@@ -0,0 +1,45 @@
+package postgres
+
+import "iter"
+
+// Row is the current row of a result iterated with Segment.Rows.
+type Row interface {
+	// Scan reads the values of the row into dest values positionally, like Rows.Scan.
+	Scan(dest ...any) error
+}
+
+// Rows executes the query and returns an iterator over its rows.
+func (s *pgxSegment) Rows() iter.Seq2[Row, error] {
+	return rowsSeq(s.Query)
+}
+
+// Rows executes the query and returns an iterator over its rows.
+func (s *pgxpoolSegment) Rows() iter.Seq2[Row, error] {
+	return rowsSeq(s.Query)
+}
+
+// Rows executes the query and returns an iterator over its rows.
+func (s *sqlSegment) Rows() iter.Seq2[Row, error] {
+	return rowsSeq(s.Query)
+}
+
+// rowsSeq returns an iterator over the rows query passes to its callback. Every row is yielded with a nil error, an
+// error of the query, including the error of the rows once iteration ends, is yielded last with a nil row. Stopping the
+// iteration early closes the rows without reporting an error.
+func rowsSeq(query func(cb func(Rows) error) error) iter.Seq2[Row, error] {
+	return func(yield func(Row, error) bool) {
+		stopped := false
+		err := query(func(rows Rows) error {
+			for rows.Next() {
+				if !yield(rows, nil) {
+					stopped = true
+					return nil
+				}
+			}
+			return rows.Err()
+		})
+		if err != nil && !stopped {
+			yield(nil, err)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/ponrove/octobe"
+)
+
+// ErrLargeObjectsUnsupported is returned by NewLargeObjects for a session without a transaction, or of the database/sql
+// driver. Large objects can only be accessed in a transaction of the pgx or pgxpool driver.
+var ErrLargeObjectsUnsupported = errors.New("large objects require a transaction of the pgx or pgxpool driver")
+
+// LargeObject is a large object opened with LargeObjects.Open. It implements io.Reader, io.Writer, io.Seeker and
+// io.Closer, so its content can be streamed without holding it in memory, until the transaction it was opened in ends.
+type LargeObject = pgx.LargeObject
+
+// LargeObjectMode is the mode a large object is opened in.
+type LargeObjectMode = pgx.LargeObjectMode
+
+const (
+	// LargeObjectModeRead opens a large object for reading.
+	LargeObjectModeRead = pgx.LargeObjectModeRead
+	// LargeObjectModeWrite opens a large object for writing. Combine it with LargeObjectModeRead to read as well.
+	LargeObjectModeWrite = pgx.LargeObjectModeWrite
+)
+
+// LargeObjects creates, opens and removes the large objects of the database in the transaction of a session, see
+// NewLargeObjects. Large objects store values too big for a bytea column, which are read and written in chunks.
+type LargeObjects struct {
+	ctx context.Context
+	lo  pgx.LargeObjects
+}
+
+// NewLargeObjects returns the large objects of the database in the transaction of session, which must be a session of
+// the pgx or pgxpool driver running in a transaction:
+//
+//	objects, err := postgres.NewLargeObjects(session)
+//	oid, err := objects.Create(0)
+//	object, err := objects.Open(oid, postgres.LargeObjectModeWrite)
+//	_, err = io.Copy(object, file)
+//	err = object.Close()
+//
+// The operations run with the context of the session and are not reported to the query hooks.
+func NewLargeObjects(session octobe.BuilderSession[Builder]) (*LargeObjects, error) {
+	var current any = session
+	for {
+		switch s := current.(type) {
+		case *pgxSession:
+			if s.tx == nil {
+				return nil, ErrLargeObjectsUnsupported
+			}
+			return &LargeObjects{ctx: s.ctx, lo: s.tx.LargeObjects()}, nil
+		case *pgxpoolSession:
+			if s.tx == nil {
+				return nil, ErrLargeObjectsUnsupported
+			}
+			return &LargeObjects{ctx: s.ctx, lo: s.tx.LargeObjects()}, nil
+		case sessionUnwrapper:
+			current = s.Unwrap()
+		default:
+			return nil, ErrLargeObjectsUnsupported
+		}
+	}
+}
+
+// Create creates a new large object with oid and returns its oid. An oid of zero lets the database assign one.
+func (o *LargeObjects) Create(oid uint32) (uint32, error) {
+	return o.lo.Create(o.ctx, oid)
+}
+
+// Open opens the large object with oid in mode. The object must be closed before the transaction ends.
+func (o *LargeObjects) Open(oid uint32, mode LargeObjectMode) (*LargeObject, error) {
+	return o.lo.Open(o.ctx, oid, mode)
+}
+
+// Unlink removes the large object with oid.
+func (o *LargeObjects) Unlink(oid uint32) error {
+	return o.lo.Unlink(o.ctx, oid)
+}
@@ -0,0 +1,54 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/driver/postgres"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLargeObjects(t *testing.T) {
+	ctx := context.Background()
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	ob, err := octobe.New(postgres.OpenPGXPoolWithPool(mock))
+	require.NoError(t, err)
+
+	session, err := ob.Begin(ctx)
+	require.NoError(t, err)
+	_, err = postgres.NewLargeObjects(session)
+	require.ErrorIs(t, err, postgres.ErrLargeObjectsUnsupported)
+
+	err = ob.StartTransaction(ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+		objects, err := postgres.NewLargeObjects(session)
+		require.NoError(t, err)
+		require.NotNil(t, objects)
+		return nil
+	}, postgres.WithPGXTxOptions(postgres.PGXTxOptions{}))
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestNewLargeObjectsSQL(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	ob, err := octobe.New(postgres.OpenWithConn(db))
+	require.NoError(t, err)
+	session, err := ob.Begin(context.Background(), postgres.WithSQLTxOptions(postgres.SQLTxOptions{}))
+	require.NoError(t, err)
+
+	_, err = postgres.NewLargeObjects(session)
+	require.ErrorIs(t, err, postgres.ErrLargeObjectsUnsupported)
+}
@@ -0,0 +1,151 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ConnHooks are callbacks invoked as the connections of the pgx and pgxpool drivers come and go, to set session
+// variables, warm caches or emit metrics. Callbacks that are nil are skipped.
+type ConnHooks struct {
+	// OnOpen is called after a connection has been established and before it is used. An error closes the connection,
+	// for the pgx driver opening the driver fails with it.
+	OnOpen func(ctx context.Context, conn *pgx.Conn) error
+	// OnClose is called right before a connection is closed.
+	OnClose func(conn *pgx.Conn)
+	// OnAcquire is called before a connection of the pool is handed to a session or query. An error destroys the
+	// connection and another one is acquired instead. Only the pgxpool driver acquires connections.
+	OnAcquire func(ctx context.Context, conn *pgx.Conn) error
+	// OnRelease is called after a connection has been returned to the pool. An error destroys the connection instead of
+	// keeping it for reuse. Only the pgxpool driver releases connections.
+	OnRelease func(conn *pgx.Conn) error
+	// OnReconnect is called after the pgx driver replaced its broken connection with conn, once OnOpen succeeded, see
+	// WithReconnect. Only the pgx driver reconnects, the pool replaces broken connections on its own.
+	OnReconnect func(ctx context.Context, conn *pgx.Conn)
+}
+
+// WithConnHooks registers hooks for the connection of the pgx driver. OnOpen is called by OpenPGX and
+// OpenPGXWithOptions once they connected, a connection passed to OpenPGXWithConn is already in use. OnClose is called
+// when the driver is closed.
+func WithConnHooks(hooks ConnHooks) PGXOption {
+	return func(cfg *pgxOptions) {
+		cfg.hooks = hooks
+	}
+}
+
+// WithPoolConnHooks registers hooks for the connections of the pool created by OpenPGXPool. The configuration of a pool
+// passed to OpenPGXPoolWithPool cannot be changed anymore, so the hooks are not called for it, they can be set on its
+// pgxpool.Config before creating it instead.
+func WithPoolConnHooks(hooks ConnHooks) PGXPoolOption {
+	return func(cfg *pgxpoolOptions) {
+		cfg.hooks = hooks
+	}
+}
+
+// WithAfterConnect registers fn to run on the connection of the pgx driver once OpenPGX or OpenPGXWithOptions
+// connected, before anything else uses it. It is the place to register custom types like enums, composite types or
+// the types of extensions such as postgis with the type map of the connection. Functions registered several times run
+// in order, before OnOpen of WithConnHooks. An error closes the connection and fails opening the driver. A connection
+// passed to OpenPGXWithConn is already in use, fn is not called for it.
+func WithAfterConnect(fn func(ctx context.Context, conn *pgx.Conn) error) PGXOption {
+	return func(cfg *pgxOptions) {
+		cfg.afterConnect = append(cfg.afterConnect, fn)
+	}
+}
+
+// WithPoolAfterConnect registers fn to run on every new connection of the pool created by OpenPGXPool or
+// OpenPGXPoolWithConfig, before the pool hands it out, to register custom types with its type map:
+//
+//	postgres.WithPoolAfterConnect(func(ctx context.Context, conn *pgx.Conn) error {
+//		t, err := conn.LoadType(ctx, "order_status")
+//		if err != nil {
+//			return err
+//		}
+//		conn.TypeMap().RegisterType(t)
+//		return nil
+//	})
+//
+// Functions registered several times run in order, after the AfterConnect of the pgxpool.Config and before OnOpen of
+// WithPoolConnHooks. An error closes the connection. Like the hooks, fn is not called for a pool passed to
+// OpenPGXPoolWithPool.
+func WithPoolAfterConnect(fn func(ctx context.Context, conn *pgx.Conn) error) PGXPoolOption {
+	return func(cfg *pgxpoolOptions) {
+		cfg.afterConnect = append(cfg.afterConnect, fn)
+	}
+}
+
+// afterConnect returns the hooks with fns run in order before OnOpen.
+func (h ConnHooks) afterConnect(fns []func(ctx context.Context, conn *pgx.Conn) error) ConnHooks {
+	if len(fns) == 0 {
+		return h
+	}
+	onOpen := h.OnOpen
+	h.OnOpen = func(ctx context.Context, conn *pgx.Conn) error {
+		for _, fn := range fns {
+			if err := fn(ctx, conn); err != nil {
+				return err
+			}
+		}
+		if onOpen != nil {
+			return onOpen(ctx, conn)
+		}
+		return nil
+	}
+	return h
+}
+
+// open calls OnOpen for a connection the pgx driver established, closing it if OnOpen fails.
+func (h ConnHooks) open(ctx context.Context, conn *pgx.Conn) error {
+	if h.OnOpen == nil {
+		return nil
+	}
+	if err := h.OnOpen(ctx, conn); err != nil {
+		return errors.Join(err, conn.Close(ctx))
+	}
+	return nil
+}
+
+// register sets the hooks on the configuration of a pool, after the callbacks it already has.
+func (h ConnHooks) register(config *pgxpool.Config) {
+	if h.OnOpen != nil {
+		afterConnect := config.AfterConnect
+		config.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
+			if afterConnect != nil {
+				if err := afterConnect(ctx, conn); err != nil {
+					return err
+				}
+			}
+			return h.OnOpen(ctx, conn)
+		}
+	}
+	if h.OnClose != nil {
+		beforeClose := config.BeforeClose
+		config.BeforeClose = func(conn *pgx.Conn) {
+			if beforeClose != nil {
+				beforeClose(conn)
+			}
+			h.OnClose(conn)
+		}
+	}
+	if h.OnAcquire != nil {
+		beforeAcquire := config.BeforeAcquire
+		config.BeforeAcquire = func(ctx context.Context, conn *pgx.Conn) bool {
+			if beforeAcquire != nil && !beforeAcquire(ctx, conn) {
+				return false
+			}
+			return h.OnAcquire(ctx, conn) == nil
+		}
+	}
+	if h.OnRelease != nil {
+		afterRelease := config.AfterRelease
+		config.AfterRelease = func(conn *pgx.Conn) bool {
+			if afterRelease != nil && !afterRelease(conn) {
+				return false
+			}
+			return h.OnRelease(conn) == nil
+		}
+	}
+}
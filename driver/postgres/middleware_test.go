@@ -0,0 +1,45 @@
+package postgres_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ponrove/octobe/driver/postgres"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrapHandler(t *testing.T) {
+	var calls []string
+	trace := func(name string) postgres.Middleware[int] {
+		return func(next postgres.Handler[int]) postgres.Handler[int] {
+			return func(builder postgres.Builder) (int, error) {
+				calls = append(calls, "before "+name)
+				result, err := next(builder)
+				calls = append(calls, "after "+name)
+				return result + 1, err
+			}
+		}
+	}
+	handler := func(postgres.Builder) (int, error) {
+		calls = append(calls, "handler")
+		return 1, nil
+	}
+
+	result, err := postgres.WrapHandler(handler, postgres.Chain(trace("a"), trace("b")), trace("c"))(nil)
+	require.NoError(t, err)
+	require.Equal(t, 4, result)
+	require.Equal(t, []string{"before a", "before b", "before c", "handler", "after c", "after b", "after a"}, calls)
+
+	errValidation := errors.New("invalid")
+	validate := func(next postgres.Handler[int]) postgres.Handler[int] {
+		return func(postgres.Builder) (int, error) {
+			return 0, errValidation
+		}
+	}
+	_, err = postgres.WrapHandler(handler, validate)(nil)
+	require.ErrorIs(t, err, errValidation)
+
+	result, err = postgres.WrapHandler(handler)(nil)
+	require.NoError(t, err)
+	require.Equal(t, 1, result)
+}
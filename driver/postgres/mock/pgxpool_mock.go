@@ -274,7 +274,10 @@ func (m *PGXPoolMock) AcquireFunc(context.Context, func(*pgxpool.Conn) error) er
 func (m *PGXPoolMock) AcquireAllIdle(context.Context) []*pgxpool.Conn { panic("not implemented") }
 func (m *PGXPoolMock) Reset()                                         { panic("not implemented") }
 func (m *PGXPoolMock) Config() *pgxpool.Config                        { panic("not implemented") }
-func (m *PGXPoolMock) Stat() *pgxpool.Stat                            { panic("not implemented") }
+
+// Stat returns an empty snapshot, the mock has no connections to report statistics of.
+func (m *PGXPoolMock) Stat() *pgxpool.Stat { return &pgxpool.Stat{} }
+
 func (m *PGXPoolMock) SendBatch(context.Context, *pgx.Batch) pgx.BatchResults {
 	panic("not implemented")
 }
@@ -79,7 +79,7 @@ func TestPoolMock(t *testing.T) {
 
 		_, err = session.Builder()(query).Exec()
 		require.Error(t, err)
-		require.Equal(t, expectedErr, err)
+		require.ErrorIs(t, err, expectedErr)
 		require.NoError(t, mock.AllExpectationsMet())
 	})
 
@@ -128,7 +128,7 @@ func TestPoolMock(t *testing.T) {
 			return nil
 		})
 		require.Error(t, err)
-		require.Equal(t, expectedErr, err)
+		require.ErrorIs(t, err, expectedErr)
 		require.NoError(t, mock.AllExpectationsMet())
 	})
 
@@ -165,7 +165,7 @@ func TestPoolMock(t *testing.T) {
 		var name string
 		err = session.Builder()(query).Arguments(1).QueryRow(&name)
 		require.Error(t, err)
-		require.Equal(t, expectedErr, err)
+		require.ErrorIs(t, err, expectedErr)
 		require.NoError(t, mock.AllExpectationsMet())
 	})
 
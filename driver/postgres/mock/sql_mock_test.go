@@ -95,7 +95,7 @@ func TestSQLMock(t *testing.T) {
 
 		_, err = session.Builder()(query).Exec()
 		require.Error(t, err)
-		require.Equal(t, expectedErr, err)
+		require.ErrorIs(t, err, expectedErr)
 		require.NoError(t, mock.AllExpectationsMet())
 	})
 
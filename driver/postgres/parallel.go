@@ -0,0 +1,82 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ponrove/octobe"
+)
+
+// ErrNotParallel is returned by ExecuteParallel for a session that cannot run queries concurrently, which is any
+// session that is not a pgxpool session without a transaction.
+var ErrNotParallel = errors.New("parallel execution requires a pgxpool session without a transaction")
+
+// ExecuteParallel runs independent handlers concurrently within a pgxpool session without a transaction, and returns
+// their results in the order of the handlers. Every query acquires its own connection of the pool, at most limit
+// handlers run at the same time, a limit of zero or less runs all of them at once. All handlers run even when some of
+// them fail, the results of failed handlers are zero values and the returned error joins their errors. Sessions in a
+// transaction or on a single connection fail with ErrNotParallel, their queries share one connection. The handlers may
+// use the session concurrently even with octobe.WithConcurrencyGuard. A handler that panics does not crash the program
+// from its goroutine, the panic is raised again on the caller once all handlers returned.
+func ExecuteParallel[RESULT any](session octobe.BuilderSession[Builder], limit int, handlers ...Handler[RESULT]) ([]RESULT, error) {
+	pool, ok := parallelSession(session)
+	if !ok {
+		return nil, ErrNotParallel
+	}
+	if limit <= 0 || limit > len(handlers) {
+		limit = len(handlers)
+	}
+
+	parallel := &pgxpoolSession{ctx: octobe.AllowConcurrentUse(pool.ctx), cfg: pool.cfg, d: pool.d}
+	builder := Builder(parallel.build)
+	results := make([]RESULT, len(handlers))
+	errs := make([]error, len(handlers))
+	panics := make([]any, len(handlers))
+	slots := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i, handler := range handlers {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer func() {
+				panics[i] = recover()
+				<-slots
+				wg.Done()
+			}()
+			result, err := handler(builder)
+			if err != nil {
+				errs[i] = fmt.Errorf("handler %d: %w", i, err)
+				return
+			}
+			results[i] = result
+		}()
+	}
+	wg.Wait()
+	for _, p := range panics {
+		if p != nil {
+			panic(p)
+		}
+	}
+	return results, errors.Join(errs...)
+}
+
+// sessionUnwrapper is implemented by the sessions of octobe, which wrap the session of the driver.
+type sessionUnwrapper interface {
+	Unwrap() octobe.Session[Builder]
+}
+
+// parallelSession returns the pgxpool session behind session, unwrapping the session of octobe, if it has no
+// transaction.
+func parallelSession(session any) (*pgxpoolSession, bool) {
+	for {
+		switch s := session.(type) {
+		case *pgxpoolSession:
+			return s, s.tx == nil
+		case sessionUnwrapper:
+			session = s.Unwrap()
+		default:
+			return nil, false
+		}
+	}
+}
@@ -3,10 +3,13 @@ package postgres
 import (
 	"context"
 	"errors"
+	"slices"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/internal/named"
 )
 
 // PGXConn defines the interface for a PGX postgres connection.
@@ -31,7 +34,94 @@ var _ PGXConn = &pgx.Conn{} // Ensure pgx.Conn implements the PGXConn interface.
 
 // conn holds the connection and default configuration for the pgx driver.
 type pgxConn struct {
-	conn PGXConn
+	conn       PGXConn
+	statements *statementCache
+	hooks      ConnHooks
+	reconnect  *reconnect // Set if the driver connects again once its connection broke
+	closed     bool       // Set by Close, so a driver that reconnects does not connect again
+}
+
+// PGXOption is a signature for configuring the pgx driver when it is opened.
+type PGXOption func(cfg *pgxOptions)
+
+// pgxOptions holds the configuration given when opening the pgx driver.
+type pgxOptions struct {
+	statementCacheSize int
+	hooks              ConnHooks
+	afterConnect       []func(ctx context.Context, conn *pgx.Conn) error
+	connConfig         []func(config *pgx.ConnConfig)
+	reconnectAttempts  int
+	reconnectBackoff   octobe.Backoff
+	reconnectClock     octobe.Clock
+}
+
+// WithStatementCache prepares the queries of segments on the connection the first time they run and executes the
+// prepared statements from then on, keeping at most size of them and deallocating the least recently used one when
+// more are needed. It saves parsing and planning hot queries again when pgx is configured not to cache statements
+// itself, as with pgx.QueryExecModeExec. A size of zero or less disables the cache.
+func WithStatementCache(size int) PGXOption {
+	return func(cfg *pgxOptions) {
+		cfg.statementCacheSize = size
+	}
+}
+
+// PGXStatementCache configures the caches pgx keeps on every connection, which the pgx driver otherwise sets up with
+// the defaults of pgx or the statement_cache_capacity and description_cache_capacity parameters of the DSN. The
+// statements pgx prepares for its cache fail behind PgBouncer in transaction pooling mode, where the next query may
+// run on another server connection, Disabled turns them off.
+type PGXStatementCache struct {
+	// Capacity is the number of prepared statements cached for pgx.QueryExecModeCacheStatement, the default mode of
+	// pgx. Zero keeps the configured capacity.
+	Capacity int
+	// DescriptionCapacity is the number of statement descriptions cached for pgx.QueryExecModeCacheDescribe. Zero keeps
+	// the configured capacity.
+	DescriptionCapacity int
+	// Disabled disables both caches and makes pgx.QueryExecModeExec the default mode, which runs a query in a single
+	// round trip without preparing it. The capacities are ignored.
+	Disabled bool
+}
+
+// WithPGXStatementCache configures the caches pgx keeps on the connection of OpenPGX and OpenPGXWithOptions. A
+// connection passed to OpenPGXWithConn is already configured. Unlike WithStatementCache, it does not change the
+// statements the driver prepares itself.
+func WithPGXStatementCache(cache PGXStatementCache) PGXOption {
+	return func(cfg *pgxOptions) {
+		cfg.connConfig = append(cfg.connConfig, cache.apply)
+	}
+}
+
+// apply sets the cache configuration on config.
+func (c PGXStatementCache) apply(config *pgx.ConnConfig) {
+	if c.Disabled {
+		config.StatementCacheCapacity = 0
+		config.DescriptionCacheCapacity = 0
+		config.DefaultQueryExecMode = pgx.QueryExecModeExec
+		return
+	}
+	if c.Capacity > 0 {
+		config.StatementCacheCapacity = c.Capacity
+	}
+	if c.DescriptionCapacity > 0 {
+		config.DescriptionCacheCapacity = c.DescriptionCapacity
+	}
+}
+
+// newPGXOptions returns the configuration with opts applied.
+func newPGXOptions(opts []PGXOption) pgxOptions {
+	var cfg pgxOptions
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// newPGXConn creates the driver for conn with the given configuration.
+func newPGXConn(conn PGXConn, cfg pgxOptions) *pgxConn {
+	d := &pgxConn{conn: conn, hooks: cfg.hooks.afterConnect(cfg.afterConnect)}
+	if cfg.statementCacheSize > 0 {
+		d.statements = newStatementCache(cfg.statementCacheSize)
+	}
+	return d
 }
 
 // Ensure conn implements the Octobe Driver interface.
@@ -42,16 +132,13 @@ var _ octobe.Driver[pgxConn, pgxConfig, Builder] = &pgxConn{}
 // The returned function, when called, initializes a new connection using the provided DSN.
 // If the connection creation fails, it returns an error.
 // Otherwise, it returns a new conn instance with the created connection.
-func OpenPGX(ctx context.Context, dsn string) octobe.Open[pgxConn, pgxConfig, Builder] {
+func OpenPGX(ctx context.Context, dsn string, opts ...PGXOption) octobe.Open[pgxConn, pgxConfig, Builder] {
 	return func() (octobe.Driver[pgxConn, pgxConfig, Builder], error) {
-		conn, err := pgx.Connect(ctx, dsn)
+		config, err := pgx.ParseConfig(dsn)
 		if err != nil {
 			return nil, err
 		}
-
-		return &pgxConn{
-			conn: conn,
-		}, nil
+		return openPGX(ctx, config, opts)
 	}
 }
 
@@ -65,32 +152,57 @@ type ParseConfigOptions struct {
 // The returned function, when called, initializes a new connection using the provided DSN and options.
 // If the connection creation fails, it returns an error.
 // Otherwise, it returns a new conn instance with the created connection.
-func OpenPGXWithOptions(ctx context.Context, dsn string, options ParseConfigOptions) octobe.Open[pgxConn, pgxConfig, Builder] {
+func OpenPGXWithOptions(ctx context.Context, dsn string, options ParseConfigOptions, opts ...PGXOption) octobe.Open[pgxConn, pgxConfig, Builder] {
 	return func() (octobe.Driver[pgxConn, pgxConfig, Builder], error) {
-		conn, err := pgx.ConnectWithOptions(ctx, dsn, pgx.ParseConfigOptions{ParseConfigOptions: options.ParseConfigOptions})
+		config, err := pgx.ParseConfigWithOptions(dsn, pgx.ParseConfigOptions{ParseConfigOptions: options.ParseConfigOptions})
 		if err != nil {
 			return nil, err
 		}
+		return openPGX(ctx, config, opts)
+	}
+}
+
+// openPGX connects with config, after applying the options to it, and returns the driver for the connection.
+func openPGX(ctx context.Context, config *pgx.ConnConfig, opts []PGXOption) (octobe.Driver[pgxConn, pgxConfig, Builder], error) {
+	cfg := newPGXOptions(opts)
+	for _, apply := range cfg.connConfig {
+		apply(config)
+	}
 
-		return &pgxConn{
-			conn: conn,
-		}, nil
+	conn, err := pgx.ConnectConfig(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	d := newPGXConn(conn, cfg)
+	if err = d.hooks.open(ctx, conn); err != nil {
+		return nil, err
 	}
+	if cfg.reconnectAttempts > 0 {
+		d.reconnect = &reconnect{
+			config:      config,
+			maxAttempts: cfg.reconnectAttempts,
+			backoff:     cfg.reconnectBackoff,
+			clock:       cfg.reconnectClock,
+		}
+		if d.reconnect.clock == nil {
+			d.reconnect.clock = octobe.SystemClock
+		}
+	}
+	return d, nil
 }
 
 // OpenPGXWithConn creates a new database connection using an existing connection.
 // It takes an existing connection as a parameter.
 // The returned function, when called, returns a new conn instance with the provided connection.
 // If the provided connection is nil, it returns an error.
-func OpenPGXWithConn(c PGXConn) octobe.Open[pgxConn, pgxConfig, Builder] {
+func OpenPGXWithConn(c PGXConn, opts ...PGXOption) octobe.Open[pgxConn, pgxConfig, Builder] {
 	return func() (octobe.Driver[pgxConn, pgxConfig, Builder], error) {
 		if c == nil {
 			return nil, errors.New("conn is nil")
 		}
 
-		return &pgxConn{
-			conn: c,
-		}, nil
+		return newPGXConn(c, newPGXOptions(opts)), nil
 	}
 }
 
@@ -104,10 +216,14 @@ func (d *pgxConn) Begin(ctx context.Context, opts ...octobe.Option[pgxConfig]) (
 		opt(&cfg)
 	}
 
+	conn, err := d.connection(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	var tx pgx.Tx
-	var err error
 	if cfg.txOptions != nil {
-		tx, err = d.conn.BeginTx(ctx, pgx.TxOptions{
+		tx, err = conn.BeginTx(ctx, pgx.TxOptions{
 			IsoLevel:       cfg.txOptions.IsoLevel,
 			AccessMode:     cfg.txOptions.AccessMode,
 			DeferrableMode: cfg.txOptions.DeferrableMode,
@@ -119,12 +235,39 @@ func (d *pgxConn) Begin(ctx context.Context, opts ...octobe.Option[pgxConfig]) (
 		return nil, err
 	}
 
-	return &pgxSession{
+	if tx == nil {
+		// The session runs on the connection of the driver, which keeps the settings.
+		if err = applySettings(ctx, cfg.settings, false, conn.Exec); err != nil {
+			return nil, err
+		}
+	} else if err = applySettings(ctx, cfg.settings, true, tx.Exec); err != nil {
+		return nil, errors.Join(err, tx.Rollback(context.WithoutCancel(ctx)))
+	}
+
+	session := &pgxSession{
 		ctx: ctx,
 		cfg: cfg,
 		tx:  tx,
 		d:   d,
-	}, nil
+	}
+	session.builder = session.build
+	return session, nil
+}
+
+// Ensure pgxConn describes itself to query hooks.
+var _ octobe.Describer = &pgxConn{}
+
+// Describe returns the name of the driver and the database system it connects to.
+func (d *pgxConn) Describe() octobe.DriverInfo {
+	return octobe.DriverInfo{Name: "pgx", System: "postgresql"}
+}
+
+// Ensure pgxConn reports its capabilities.
+var _ octobe.CapabilityReporter = &pgxConn{}
+
+// Capabilities returns the features supported by the pgx driver.
+func (d *pgxConn) Capabilities() octobe.Capabilities {
+	return octobe.Capabilities{Transactions: true, Savepoints: true, Batch: true, Copy: true, Returning: true}
 }
 
 // Close closes the database connection.
@@ -132,6 +275,10 @@ func (d *pgxConn) Close(ctx context.Context) error {
 	if d.conn == nil {
 		return errors.New("connection is nil")
 	}
+	d.closed = true
+	if conn, ok := d.conn.(*pgx.Conn); ok && d.hooks.OnClose != nil {
+		d.hooks.OnClose(conn)
+	}
 	return d.conn.Close(ctx)
 }
 
@@ -139,36 +286,40 @@ func (d *pgxConn) Ping(ctx context.Context) error {
 	if d.conn == nil {
 		return errors.New("connection is nil")
 	}
-	return d.conn.Ping(ctx)
+	conn, err := d.connection(ctx)
+	if err != nil {
+		return err
+	}
+	return conn.Ping(ctx)
 }
 
 // pgxSession holds pgxSession context, representing a series of related queries.
 // A pgxSession can be transactional or non-transactional. If transactional, it enforces the usage of commit and rollback.
 // A pgxSession is not thread-safe and should only be used in one thread at a time.
 type pgxSession struct {
-	ctx       context.Context
-	cfg       pgxConfig
-	tx        pgx.Tx
-	d         *pgxConn
-	committed bool
+	ctx     context.Context
+	cfg     pgxConfig
+	tx      pgx.Tx
+	d       *pgxConn
+	state   txState
+	builder Builder
 }
 
 // Ensure session implements the Octobe Session interface.
 var _ octobe.Session[Builder] = &pgxSession{}
+var _ octobe.Transactional = &pgxSession{}
 
 // Commit commits a transaction. This only works if the session is transactional.
 func (s *pgxSession) Commit() error {
-	if s.committed {
-		return errors.New("cannot commit a session that has already been committed")
-	}
-
 	if s.cfg.txOptions == nil {
 		return errors.New("cannot commit without transaction")
 	}
-	defer func() {
-		s.committed = true
-	}()
-	return s.tx.Commit(s.ctx)
+	if err := s.state.check(); err != nil {
+		return err
+	}
+	err := s.tx.Commit(s.ctx)
+	s.state.end(err == nil)
+	return err
 }
 
 // Rollback rolls back a transaction. This only works if the session is transactional.
@@ -176,31 +327,55 @@ func (s *pgxSession) Rollback() error {
 	if s.cfg.txOptions == nil {
 		return errors.New("cannot rollback without transaction")
 	}
+	if err := s.state.check(); err != nil {
+		return err
+	}
+	s.state.end(false)
 	return s.tx.Rollback(s.ctx)
 }
 
-// Builder returns a new builder for building queries.
+// InTransaction reports whether the session runs in a transaction.
+func (s *pgxSession) InTransaction() bool {
+	return s.cfg.txOptions != nil
+}
+
+// Builder returns the builder for building queries in the session.
 func (s *pgxSession) Builder() Builder {
-	return func(query string) Segment {
-		return &pgxSegment{
-			query: query,
-			args:  nil,
-			used:  false,
-			tx:    s.tx,
-			d:     s.d,
-			ctx:   s.ctx,
-		}
+	return s.builder
+}
+
+// build creates a segment for query in the session. It backs the builder of the session, which is created once so that
+// building segments in a loop only allocates the segments. Segments are not recycled through a pool once used, callers
+// may still hold them to get octobe.ErrAlreadyUsed or to Clone them, and a recycled segment would run another query.
+func (s *pgxSession) build(query string) Segment {
+	return &pgxSegment{
+		query:    query,
+		args:     nil,
+		used:     false,
+		tx:       s.tx,
+		d:        s.d,
+		ctx:      s.ctx,
+		rewrite:  s.cfg.rewrite,
+		execMode: s.cfg.execMode,
+		readOnly: s.cfg.readOnly,
 	}
 }
 
 // Segment represents a specific query that can be run only once. It keeps track of the query, arguments, and execution state.
 type pgxSegment struct {
-	query string          // SQL query to be executed
-	args  []any           // Argument values
-	used  bool            // Indicates if this Segment has been executed
-	tx    pgx.Tx          // Database transaction, initiated by BeginTx
-	d     *pgxConn        // Driver used for the session
-	ctx   context.Context // Context to interrupt a query
+	query    string             // SQL query to be executed
+	args     []any              // Argument values
+	used     bool               // Indicates if this Segment has been executed
+	tx       pgx.Tx             // Database transaction, initiated by BeginTx
+	d        *pgxConn           // Driver used for the session
+	ctx      context.Context    // Context to interrupt a query
+	maxRows  int                // Maximum number of rows Query may read, zero means no limit
+	timeout  time.Duration      // Deadline of the statement relative to its execution, zero means none
+	prepared string             // Name of the prepared statement executed in place of the query, see Prepare
+	rewrite  Rewriter           // Rewriter of the session applied before execution, nil once applied
+	execMode *pgx.QueryExecMode // Mode pgx sends the statement in, nil for the default of the connection
+	readOnly bool               // Whether the session is read-only, rejecting Exec
+	err      error              // Error from building the Segment, returned when it is executed
 }
 
 var _ Segment = &pgxSegment{}
@@ -210,75 +385,235 @@ func (s *pgxSegment) use() {
 	s.used = true
 }
 
-// Arguments sets the arguments to be used in the query.
+// rewriteQuery applies the rewriter of the session to the query and arguments, once, so a clone of an executed segment
+// is not rewritten again.
+func (s *pgxSegment) rewriteQuery() {
+	if s.rewrite != nil {
+		s.query, s.args = s.rewrite(s.query, s.args)
+		s.rewrite = nil
+	}
+}
+
+// Arguments sets the arguments to be used in the query, rewriting the query for a pgx.QueryRewriter like pgx.NamedArgs.
 func (s *pgxSegment) Arguments(args ...any) Segment {
-	s.args = args
+	conn, _ := s.d.conn.(*pgx.Conn)
+	var mode *pgx.QueryExecMode
+	s.query, s.args, mode, s.err = rewriteArgs(s.ctx, conn, s.query, args)
+	if mode != nil {
+		s.execMode = mode
+	}
 	return s
 }
 
+// ArgumentsFromStruct binds the fields of a struct to the query by their db tags. Named parameters like :name are bound
+// to the field of the same name, a query without named parameters gets all fields as arguments in declaration order.
+func (s *pgxSegment) ArgumentsFromStruct(v any) Segment {
+	s.query, s.args, s.err = named.Bind(s.query, named.Dollar, v)
+	return s
+}
+
+// NamedArguments binds the values of a map to the named parameters of the query, like :name or @name.
+func (s *pgxSegment) NamedArguments(args map[string]any) Segment {
+	s.query, s.args, s.err = named.BindMap(s.query, named.Dollar, args)
+	return s
+}
+
+// WithMaxRows limits the number of rows Query may read before failing with a *octobe.MaxRowsError.
+func (s *pgxSegment) WithMaxRows(n int) Segment {
+	s.maxRows = n
+	return s
+}
+
+// Timeout sets a deadline of d for executing the statement, leaving the context of the session as is.
+func (s *pgxSegment) Timeout(d time.Duration) Segment {
+	s.timeout = d
+	return s
+}
+
+// Clone returns an unused copy of the segment with the same query, arguments and settings.
+func (s *pgxSegment) Clone() Segment {
+	c := *s
+	c.used = false
+	c.args = slices.Clone(s.args)
+	return &c
+}
+
 // Exec executes a query, typically used for inserts or updates.
-func (s *pgxSegment) Exec() (ExecResult, error) {
+func (s *pgxSegment) Exec() (result ExecResult, err error) {
 	if s.used {
 		return ExecResult{}, octobe.ErrAlreadyUsed
 	}
 	defer s.use()
-	if s.tx == nil {
-		res, err := s.d.conn.Exec(s.ctx, s.query, s.args...)
-		if err != nil {
-			return ExecResult{}, err
-		}
+	if s.err != nil {
+		return ExecResult{}, s.err
+	}
+	if s.readOnly {
+		return ExecResult{}, ErrReadOnly
+	}
+	s.rewriteQuery()
+
+	ctx, cancel := withTimeout(s.ctx, s.timeout)
+	defer cancel()
+	ctx, done := octobe.BeginQuery(ctx, octobe.OperationExec, s.query, s.args)
+	defer func() {
+		err = wrapQueryError(s.d.Describe().Name, s.query, s.args, err)
+		done(result.RowsAffected, err)
+	}()
 
-		return ExecResult{
-			RowsAffected: res.RowsAffected(),
-		}, nil
+	conn, err := s.conn(ctx)
+	if err != nil {
+		return ExecResult{}, err
+	}
+	query, err := s.statement(ctx)
+	if err != nil {
+		return ExecResult{}, err
 	}
 
-	res, err := s.tx.Exec(s.ctx, s.query, s.args...)
+	res, err := conn.Exec(ctx, query, execArgs(s.execMode, s.args)...)
 	if err != nil {
 		return ExecResult{}, err
 	}
-	return ExecResult{
-		RowsAffected: res.RowsAffected(),
-	}, nil
+	return tagResult(res), nil
 }
 
 // QueryRow returns one result and puts it into destination pointers.
-func (s *pgxSegment) QueryRow(dest ...any) error {
+func (s *pgxSegment) QueryRow(dest ...any) (err error) {
 	if s.used {
 		return octobe.ErrAlreadyUsed
 	}
 	defer s.use()
-	if s.tx == nil {
-		return s.d.conn.QueryRow(s.ctx, s.query, s.args...).Scan(dest...)
+	if s.err != nil {
+		return s.err
+	}
+	s.rewriteQuery()
+
+	ctx, cancel := withTimeout(s.ctx, s.timeout)
+	defer cancel()
+	ctx, done := octobe.BeginQuery(ctx, octobe.OperationQueryRow, s.query, s.args)
+	defer func() {
+		err = wrapQueryError(s.d.Describe().Name, s.query, s.args, err)
+		done(queryRowCount(err), err)
+	}()
+
+	conn, err := s.conn(ctx)
+	if err != nil {
+		return err
 	}
-	return s.tx.QueryRow(s.ctx, s.query, s.args...).Scan(dest...)
+	query, err := s.statement(ctx)
+	if err != nil {
+		return err
+	}
+
+	return conn.QueryRow(ctx, query, execArgs(s.execMode, s.args)...).Scan(dest...)
 }
 
 // Query performs a normal query against the database that returns rows.
-func (s *pgxSegment) Query(cb func(Rows) error) error {
+func (s *pgxSegment) Query(cb func(Rows) error) (err error) {
 	if s.used {
 		return octobe.ErrAlreadyUsed
 	}
 	defer s.use()
+	if s.err != nil {
+		return s.err
+	}
+	s.rewriteQuery()
 
-	var err error
-	var rows pgx.Rows
-	if s.tx == nil {
-		rows, err = s.d.conn.Query(s.ctx, s.query, s.args...)
-		if err != nil {
-			return err
-		}
-	} else {
-		rows, err = s.tx.Query(s.ctx, s.query, s.args...)
-		if err != nil {
-			return err
-		}
+	ctx, cancel := withTimeout(s.ctx, s.timeout)
+	defer cancel()
+	ctx, done := octobe.BeginQuery(ctx, octobe.OperationQuery, s.query, s.args)
+	rowCount := int64(-1)
+	defer func() {
+		err = wrapQueryError(s.d.Describe().Name, s.query, s.args, err)
+		done(rowCount, err)
+	}()
+
+	conn, err := s.conn(ctx)
+	if err != nil {
+		return err
+	}
+	query, err := s.statement(ctx)
+	if err != nil {
+		return err
 	}
 
-	defer rows.Close()
-	if err = cb(rows); err != nil {
+	rows, err := conn.Query(ctx, query, execArgs(s.execMode, s.args)...)
+	if err != nil {
 		return err
 	}
 
-	return nil
+	defer func() {
+		rows.Close()
+		rowCount = rows.CommandTag().RowsAffected()
+	}()
+	limited := limitRows(rows, s.maxRows)
+	if err = cb(limited); err != nil {
+		return err
+	}
+
+	return limitErr(limited)
+}
+
+// pgxQueryer executes queries on the connection or a transaction of the pgx driver.
+type pgxQueryer interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// conn returns what the segment executes its query on: the transaction, or else the connection of the driver, which is
+// replaced first if it broke and the driver reconnects.
+func (s *pgxSegment) conn(ctx context.Context) (pgxQueryer, error) {
+	if s.tx != nil {
+		return s.tx, nil
+	}
+	return s.d.connection(ctx)
+}
+
+// statement returns the SQL to send for the query of the segment with its comment, which is the name of its prepared
+// statement if the driver caches statements or the segment executes a statement prepared with Prepare.
+func (s *pgxSegment) statement(ctx context.Context) (string, error) {
+	if s.prepared != "" {
+		return s.prepared, nil
+	}
+	query := octobe.CommentQuery(ctx, s.query)
+	if s.d.statements == nil {
+		return query, nil
+	}
+	return s.d.statements.prepare(ctx, s.d.conn, query)
+}
+
+// QueryRowMap returns the first row of the result as a map keyed by column name.
+func (s *pgxSegment) QueryRowMap() (map[string]any, error) {
+	var row map[string]any
+	err := s.Query(func(rows Rows) error {
+		var err error
+		row, err = firstMap(rows, pgx.ErrNoRows)
+		return err
+	})
+	return row, err
+}
+
+// QueryMaps returns all rows of the result as maps keyed by column name.
+func (s *pgxSegment) QueryMaps() ([]map[string]any, error) {
+	var result []map[string]any
+	err := s.Query(func(rows Rows) error {
+		var err error
+		result, err = collectMaps(rows)
+		return err
+	})
+	return result, err
+}
+
+// QueryRowStruct reads the first row of the result into the struct dest points to, mapping columns by db tags.
+func (s *pgxSegment) QueryRowStruct(dest any) error {
+	return s.Query(func(rows Rows) error {
+		return firstStruct(rows, dest, pgx.ErrNoRows)
+	})
+}
+
+// QueryStructs reads all rows of the result into the slice dest points to, mapping columns by db tags.
+func (s *pgxSegment) QueryStructs(dest any) error {
+	return s.Query(func(rows Rows) error {
+		return collectStructs(rows, dest)
+	})
 }
@@ -2,13 +2,18 @@ package postgres_test
 
 import (
 	"context"
+	"database/sql/driver"
 	"errors"
+	"fmt"
+	"syscall"
 	"testing"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/pashagolub/pgxmock/v4"
 	"github.com/ponrove/octobe"
 	"github.com/ponrove/octobe/driver/postgres"
+	"github.com/ponrove/octobe/sqltpl"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -882,3 +887,794 @@ func TestSegmentQueryError(t *testing.T) {
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 }
+
+func TestPGXSegmentQueryMaxRows(t *testing.T) {
+	query := func(t *testing.T, maxRows int, ids ...int) (int, error) {
+		mock, err := pgxmock.NewConn()
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		ctx := context.Background()
+		defer mock.Close(ctx)
+
+		rows := pgxmock.NewRows([]string{"id"})
+		for _, id := range ids {
+			rows.AddRow(id)
+		}
+		mock.ExpectQuery("SELECT id FROM products").WillReturnRows(rows)
+
+		ob, err := octobe.New(postgres.OpenPGXWithConn(mock))
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+
+		session, err := ob.Begin(ctx)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+
+		var read int
+		err = session.Builder()("SELECT id FROM products").WithMaxRows(maxRows).Query(func(rows postgres.Rows) error {
+			for rows.Next() {
+				read++
+			}
+			return nil
+		})
+		assert.NoError(t, mock.ExpectationsWereMet())
+		return read, err
+	}
+
+	t.Run("within limit", func(t *testing.T) {
+		read, err := query(t, 2, 1, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, read)
+	})
+
+	t.Run("limit exceeded", func(t *testing.T) {
+		read, err := query(t, 2, 1, 2, 3)
+		assert.ErrorIs(t, err, octobe.ErrMaxRowsExceeded)
+		var maxRowsErr *octobe.MaxRowsError
+		if assert.ErrorAs(t, err, &maxRowsErr) {
+			assert.Equal(t, 2, maxRowsErr.Limit)
+		}
+		assert.Equal(t, 2, read)
+	})
+
+	t.Run("no limit", func(t *testing.T) {
+		read, err := query(t, 0, 1, 2, 3)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, read)
+	})
+}
+
+func TestPGXSegmentQueryMaps(t *testing.T) {
+	mock, err := pgxmock.NewConn()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	ctx := context.Background()
+	defer mock.Close(ctx)
+
+	mock.ExpectQuery("SELECT id, name FROM products").WillReturnRows(pgxmock.NewRows([]string{"id", "name"}).AddRow(1, "a").AddRow(2, "b"))
+	mock.ExpectQuery("SELECT id, name FROM products WHERE id").WithArgs(1).WillReturnRows(pgxmock.NewRows([]string{"id", "name"}).AddRow(1, "a"))
+	mock.ExpectQuery("SELECT id, name FROM products WHERE id").WithArgs(3).WillReturnRows(pgxmock.NewRows([]string{"id", "name"}))
+
+	ob, err := octobe.New(postgres.OpenPGXWithConn(mock))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	session, err := ob.Begin(ctx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	rows, err := session.Builder()("SELECT id, name FROM products").QueryMaps()
+	assert.NoError(t, err)
+	assert.Equal(t, []map[string]any{{"id": 1, "name": "a"}, {"id": 2, "name": "b"}}, rows)
+
+	row, err := session.Builder()("SELECT id, name FROM products WHERE id = $1").Arguments(1).QueryRowMap()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"id": 1, "name": "a"}, row)
+
+	_, err = session.Builder()("SELECT id, name FROM products WHERE id = $1").Arguments(3).QueryRowMap()
+	assert.ErrorIs(t, err, pgx.ErrNoRows)
+
+	var queryErr *octobe.QueryError
+	if assert.ErrorAs(t, err, &queryErr) {
+		assert.Equal(t, "pgx", queryErr.Driver)
+		assert.Equal(t, "SELECT id, name FROM products WHERE id = $1", queryErr.Query)
+		assert.Equal(t, []string{"int"}, queryErr.ArgTypes)
+	}
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPGXSegmentQueryStructs(t *testing.T) {
+	type Product struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+	}
+
+	mock, err := pgxmock.NewConn()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	ctx := context.Background()
+	defer mock.Close(ctx)
+
+	mock.ExpectQuery("SELECT id, name FROM products").WillReturnRows(pgxmock.NewRows([]string{"id", "name"}).AddRow(1, "a").AddRow(2, "b"))
+	mock.ExpectQuery("SELECT id, name FROM products").WillReturnRows(pgxmock.NewRows([]string{"id", "name"}).AddRow(1, "a"))
+	mock.ExpectQuery("SELECT id, name FROM products WHERE id").WithArgs(1).WillReturnRows(pgxmock.NewRows([]string{"name", "id"}).AddRow("a", 1))
+	mock.ExpectQuery("SELECT id, name FROM products WHERE id").WithArgs(3).WillReturnRows(pgxmock.NewRows([]string{"id", "name"}))
+	mock.ExpectQuery("SELECT id, price FROM products").WillReturnRows(pgxmock.NewRows([]string{"id", "price"}).AddRow(1, 10))
+
+	ob, err := octobe.New(postgres.OpenPGXWithConn(mock))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	session, err := ob.Begin(ctx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	var products []Product
+	err = session.Builder()("SELECT id, name FROM products").QueryStructs(&products)
+	assert.NoError(t, err)
+	assert.Equal(t, []Product{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}}, products)
+
+	var pointers []*Product
+	err = session.Builder()("SELECT id, name FROM products").QueryStructs(&pointers)
+	assert.NoError(t, err)
+	assert.Equal(t, []*Product{{ID: 1, Name: "a"}}, pointers)
+
+	var product Product
+	err = session.Builder()("SELECT id, name FROM products WHERE id = $1").Arguments(1).QueryRowStruct(&product)
+	assert.NoError(t, err)
+	assert.Equal(t, Product{ID: 1, Name: "a"}, product)
+
+	err = session.Builder()("SELECT id, name FROM products WHERE id = $1").Arguments(3).QueryRowStruct(&product)
+	assert.ErrorIs(t, err, pgx.ErrNoRows)
+
+	err = session.Builder()("SELECT id, price FROM products").QueryRowStruct(&product)
+	assert.ErrorContains(t, err, `column "price"`)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPGXSegmentArgumentsFromStruct(t *testing.T) {
+	type Product struct {
+		ID    int    `db:"id"`
+		Name  string `db:"name"`
+		Price int
+	}
+
+	mock, err := pgxmock.NewConn()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	ctx := context.Background()
+	defer mock.Close(ctx)
+
+	mock.ExpectExec(`UPDATE products SET name = \$1, price = \$2 WHERE id = \$3`).WithArgs("b", 20, 1).WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec(`INSERT INTO products \(id, name, price\) VALUES \(\$1, \$2, \$3\)`).WithArgs(2, "c", 30).WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+	ob, err := octobe.New(postgres.OpenPGXWithConn(mock))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	session, err := ob.Begin(ctx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	_, err = session.Builder()("UPDATE products SET name = :name, price = :price WHERE id = :id").
+		ArgumentsFromStruct(Product{ID: 1, Name: "b", Price: 20}).
+		Exec()
+	assert.NoError(t, err)
+
+	_, err = session.Builder()("INSERT INTO products (id, name, price) VALUES ($1, $2, $3)").
+		ArgumentsFromStruct(&Product{ID: 2, Name: "c", Price: 30}).
+		Exec()
+	assert.NoError(t, err)
+
+	err = session.Builder()("SELECT name FROM products WHERE sku = :sku").ArgumentsFromStruct(Product{}).QueryRow()
+	assert.ErrorContains(t, err, ":sku")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPGXSegmentNamedArguments(t *testing.T) {
+	mock, err := pgxmock.NewConn()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	ctx := context.Background()
+	defer mock.Close(ctx)
+
+	mock.ExpectExec(`INSERT INTO products \(id, name, price\) VALUES \(\$1, \$2, \$3\)`).WithArgs(1, "a", 10).WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+	ob, err := octobe.New(postgres.OpenPGXWithConn(mock))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	session, err := ob.Begin(ctx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	_, err = session.Builder()("INSERT INTO products (id, name, price) VALUES (@id, :name, @price)").
+		NamedArguments(map[string]any{"id": 1, "name": "a", "price": 10}).
+		Exec()
+	assert.NoError(t, err)
+
+	_, err = session.Builder()("DELETE FROM products WHERE id = @id").NamedArguments(map[string]any{"sku": 1}).Exec()
+	assert.ErrorContains(t, err, ":id")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPGXStartTransactionRetry(t *testing.T) {
+	mock, err := pgxmock.NewConn()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	ctx := context.Background()
+	defer mock.Close(ctx)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE accounts").WillReturnError(&pgconn.PgError{Code: postgres.SQLStateSerializationFailure})
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE accounts").WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectCommit()
+
+	ob, err := octobe.New(postgres.OpenPGXWithConn(mock), octobe.WithTxRetry(3, nil))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	err = ob.StartTransaction(ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+		_, err := session.Builder()("UPDATE accounts SET balance = balance - 1").Exec()
+		return err
+	}, postgres.WithPGXTxOptions(postgres.PGXTxOptions{}))
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPGXStartTransactionCommitRetry(t *testing.T) {
+	run := func(t *testing.T, commitErr error) (int, error) {
+		mock, err := pgxmock.NewConn()
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		ctx := context.Background()
+		defer mock.Close(ctx)
+
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE accounts").WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mock.ExpectCommit().WillReturnError(commitErr)
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE accounts").WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mock.ExpectCommit()
+
+		ob, err := octobe.New(postgres.OpenPGXWithConn(mock), octobe.WithTxRetry(3, nil))
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+
+		attempts := 0
+		err = ob.StartTransaction(ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+			attempts++
+			_, err := session.Builder()("UPDATE accounts SET balance = balance - 1").Exec()
+			return err
+		}, postgres.WithPGXTxOptions(postgres.PGXTxOptions{}))
+		return attempts, err
+	}
+
+	t.Run("connection reset", func(t *testing.T) {
+		// The server may have committed before the connection was reset, running the transaction again could apply
+		// it twice.
+		attempts, err := run(t, fmt.Errorf("commit: %w", syscall.ECONNRESET))
+		assert.ErrorIs(t, err, syscall.ECONNRESET)
+		assert.Equal(t, 1, attempts)
+	})
+
+	t.Run("serialization failure", func(t *testing.T) {
+		attempts, err := run(t, &pgconn.PgError{Code: postgres.SQLStateSerializationFailure})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, attempts)
+	})
+}
+
+func TestIsRetryable(t *testing.T) {
+	assert.True(t, postgres.IsRetryable(&pgconn.PgError{Code: postgres.SQLStateSerializationFailure}))
+	assert.True(t, postgres.IsRetryable(fmt.Errorf("update: %w", &pgconn.PgError{Code: postgres.SQLStateDeadlockDetected})))
+	assert.False(t, postgres.IsRetryable(&pgconn.PgError{Code: "23505"}))
+	assert.False(t, postgres.IsRetryable(errors.New("failed")))
+	assert.False(t, postgres.IsRetryable(nil))
+	assert.True(t, postgres.IsRetryable(syscall.ECONNRESET))
+	assert.True(t, postgres.IsRetryable(driver.ErrBadConn))
+
+	assert.True(t, postgres.IsRetryableCommit(&pgconn.PgError{Code: postgres.SQLStateSerializationFailure}))
+	assert.True(t, postgres.IsRetryableCommit(&pgconn.PgError{Code: postgres.SQLStateDeadlockDetected}))
+	assert.False(t, postgres.IsRetryableCommit(syscall.ECONNRESET))
+	assert.False(t, postgres.IsRetryableCommit(driver.ErrBadConn))
+	assert.False(t, postgres.IsRetryableCommit(errors.New("failed")))
+	assert.False(t, postgres.IsRetryableCommit(nil))
+}
+
+// queryRecorder is a query hook that records the events of finished queries.
+type queryRecorder struct {
+	events []octobe.QueryEvent
+}
+
+func (r *queryRecorder) BeforeQuery(ctx context.Context, _ *octobe.QueryEvent) context.Context {
+	return ctx
+}
+
+func (r *queryRecorder) AfterQuery(_ context.Context, event *octobe.QueryEvent) {
+	r.events = append(r.events, *event)
+}
+
+func TestPGXQueryHook(t *testing.T) {
+	mock, err := pgxmock.NewConn()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	ctx := context.Background()
+	defer mock.Close(ctx)
+
+	expectedErr := errors.New("query failed")
+	mock.ExpectExec("DELETE FROM products").WithArgs(1).WillReturnResult(pgxmock.NewResult("DELETE", 1))
+	mock.ExpectQuery("SELECT name FROM products").WithArgs(2).WillReturnError(expectedErr)
+
+	recorder := &queryRecorder{}
+	ob, err := octobe.New(postgres.OpenPGXWithConn(mock), octobe.WithQueryHook(recorder))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	session, err := ob.Begin(ctx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	_, err = session.Builder()("DELETE FROM products WHERE id = $1").Arguments(1).Exec()
+	assert.NoError(t, err)
+
+	var name string
+	err = session.Builder()("SELECT name FROM products WHERE id = $1").Arguments(2).QueryRow(&name)
+	assert.ErrorIs(t, err, expectedErr)
+
+	if assert.Len(t, recorder.events, 2) {
+		assert.Equal(t, octobe.OperationExec, recorder.events[0].Operation)
+		assert.Equal(t, []any{1}, recorder.events[0].Args)
+		assert.NoError(t, recorder.events[0].Err)
+		assert.Equal(t, octobe.OperationQueryRow, recorder.events[1].Operation)
+		assert.ErrorIs(t, recorder.events[1].Err, expectedErr)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPGXNestedStartTransaction(t *testing.T) {
+	mock, err := pgxmock.NewConn()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	ctx := context.Background()
+	defer mock.Close(ctx)
+
+	expectedErr := errors.New("duplicate")
+	mock.ExpectBegin()
+	mock.ExpectExec(`SAVEPOINT "octobe_savepoint_1"`).WillReturnResult(pgxmock.NewResult("SAVEPOINT", 0))
+	mock.ExpectExec("INSERT INTO audit").WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec(`RELEASE SAVEPOINT "octobe_savepoint_1"`).WillReturnResult(pgxmock.NewResult("RELEASE", 0))
+	mock.ExpectExec(`SAVEPOINT "octobe_savepoint_2"`).WillReturnResult(pgxmock.NewResult("SAVEPOINT", 0))
+	mock.ExpectExec("INSERT INTO tags").WillReturnError(expectedErr)
+	mock.ExpectExec(`ROLLBACK TO SAVEPOINT "octobe_savepoint_2"`).WillReturnResult(pgxmock.NewResult("ROLLBACK", 0))
+	mock.ExpectExec("UPDATE products").WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectCommit()
+
+	ob, err := octobe.New(postgres.OpenPGXWithConn(mock))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	insert := func(ctx context.Context, query string) error {
+		return ob.StartTransaction(ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+			_, err := session.Builder()(query).Exec()
+			return err
+		})
+	}
+
+	err = ob.StartTransaction(ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+		txCtx := octobe.TransactionContext(ctx, session)
+		if err := insert(txCtx, "INSERT INTO audit (action) VALUES ('update')"); err != nil {
+			return err
+		}
+		assert.ErrorIs(t, insert(txCtx, "INSERT INTO tags (name) VALUES ('a')"), expectedErr)
+
+		_, err := session.Builder()("UPDATE products SET price = 0").Exec()
+		return err
+	}, postgres.WithPGXTxOptions(postgres.PGXTxOptions{}))
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPGXStatementCache(t *testing.T) {
+	mock, err := pgxmock.NewConn()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	ctx := context.Background()
+	defer mock.Close(ctx)
+
+	mock.ExpectPrepare("octobe_stmt_1", "INSERT INTO events")
+	mock.ExpectExec("octobe_stmt_1").WithArgs(1).WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec("octobe_stmt_1").WithArgs(2).WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectPrepare("octobe_stmt_2", "SELECT count")
+	mock.ExpectDeallocate("octobe_stmt_1")
+	mock.ExpectQuery("octobe_stmt_2").WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(2))
+
+	ob, err := octobe.New(postgres.OpenPGXWithConn(mock, postgres.WithStatementCache(1)))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	session, err := ob.Begin(ctx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	for _, id := range []int{1, 2} {
+		_, err = session.Builder()("INSERT INTO events (id) VALUES ($1)").Arguments(id).Exec()
+		assert.NoError(t, err)
+	}
+
+	var count int
+	err = session.Builder()("SELECT count(*) FROM events").QueryRow(&count)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPGXTxDone(t *testing.T) {
+	mock, err := pgxmock.NewConn()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	ctx := context.Background()
+	defer mock.Close(ctx)
+	mock.ExpectBeginTx(pgx.TxOptions{})
+	mock.ExpectCommit()
+	mock.ExpectBeginTx(pgx.TxOptions{})
+	mock.ExpectRollback()
+
+	ob, err := octobe.New(postgres.OpenPGXWithConn(mock))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	committed, err := ob.Begin(ctx, postgres.WithPGXTxOptions(postgres.PGXTxOptions{}))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.NoError(t, committed.Commit())
+	assert.ErrorIs(t, committed.Commit(), octobe.ErrAlreadyCommitted)
+	assert.ErrorIs(t, committed.Rollback(), octobe.ErrAlreadyCommitted)
+
+	rolledBack, err := ob.Begin(ctx, postgres.WithPGXTxOptions(postgres.PGXTxOptions{}))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.NoError(t, rolledBack.Rollback())
+	err = rolledBack.Commit()
+	assert.ErrorIs(t, err, octobe.ErrTxDone)
+	assert.NotErrorIs(t, err, octobe.ErrAlreadyCommitted)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPGXExecuteAll(t *testing.T) {
+	mock, err := pgxmock.NewConn()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	ctx := context.Background()
+	defer mock.Close(ctx)
+
+	mock.ExpectBeginTx(pgx.TxOptions{})
+	mock.ExpectQuery("INSERT INTO products").WithArgs("first").WillReturnRows(pgxmock.NewRows([]string{"id", "name"}).AddRow(1, "first"))
+	mock.ExpectQuery("INSERT INTO products").WithArgs("second").WillReturnError(errors.New("duplicate"))
+	mock.ExpectRollback()
+
+	ob, err := octobe.New(postgres.OpenPGXWithConn(mock))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	err = ob.StartTransaction(ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+		products, err := postgres.ExecuteAll(session, AddProduct("first"), AddProduct("second"), AddProduct("third"))
+		assert.Len(t, products, 1)
+		return err
+	}, postgres.WithPGXTxOptions(postgres.PGXTxOptions{}))
+	assert.ErrorContains(t, err, "handler 1:")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPGXNamedQuery(t *testing.T) {
+	mock, err := pgxmock.NewConn()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	ctx := context.Background()
+	defer mock.Close(ctx)
+
+	octobe.RegisterQuery("pgx_test_product_by_name", "SELECT id FROM products WHERE name = $1")
+	octobe.RegisterQuery("pgx_test_product_typo", "SELECT id FROM prodcts")
+	mock.ExpectPrepare("", "SELECT id FROM products")
+	mock.ExpectPrepare("", "SELECT id FROM prodcts").WillReturnError(errors.New(`relation "prodcts" does not exist`))
+	mock.ExpectQuery("SELECT id FROM products WHERE name").WithArgs("octobe").
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(1))
+
+	ob, err := octobe.New(postgres.OpenPGXWithConn(mock))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	err = ob.ValidateQueries(ctx, "pgx_test_product_by_name", "pgx_test_product_typo")
+	assert.ErrorContains(t, err, `query "pgx_test_product_typo": relation "prodcts" does not exist`)
+
+	session, err := ob.Begin(ctx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	var id int
+	assert.NoError(t, session.Builder().Named("pgx_test_product_by_name").Arguments("octobe").QueryRow(&id))
+	assert.Equal(t, 1, id)
+	assert.Panics(t, func() { session.Builder().Named("pgx_test_missing") })
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPGXFrom(t *testing.T) {
+	mock, err := pgxmock.NewConn()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	ctx := context.Background()
+	defer mock.Close(ctx)
+
+	mock.ExpectQuery(`SELECT id FROM "products" WHERE name = \$1 AND price >= \$2`).WithArgs("octobe", 10).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(1))
+
+	ob, err := octobe.New(postgres.OpenPGXWithConn(mock))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	session, err := ob.Begin(ctx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	query := sqltpl.Postgres().Append("SELECT id FROM ").Ident("products").Where("name = ?", "octobe").Where("price >= ?", 10)
+	var id int
+	assert.NoError(t, session.Builder().From(query).QueryRow(&id))
+	assert.Equal(t, 1, id)
+
+	expectedErr := errors.New("unsupported dialect")
+	_, err = session.Builder().From(octobe.SqlizerFunc(func() (string, []any, error) {
+		return "", nil, expectedErr
+	})).Exec()
+	assert.ErrorIs(t, err, expectedErr)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPGXRows(t *testing.T) {
+	mock, err := pgxmock.NewConn()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	ctx := context.Background()
+	defer mock.Close(ctx)
+
+	readErr := errors.New("connection reset")
+	mock.ExpectQuery("SELECT id FROM products").
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(1).AddRow(2).AddRow(3)).RowsWillBeClosed()
+	mock.ExpectQuery("SELECT id FROM products").
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(1).RowError(1, readErr))
+
+	ob, err := octobe.New(postgres.OpenPGXWithConn(mock))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	session, err := ob.Begin(ctx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	var ids []int
+	for row, err := range session.Builder()("SELECT id FROM products").Rows() {
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		var id int
+		assert.NoError(t, row.Scan(&id))
+		if ids = append(ids, id); id == 2 {
+			break
+		}
+	}
+	assert.Equal(t, []int{1, 2}, ids)
+
+	ids = nil
+	var iterErr error
+	for row, err := range session.Builder()("SELECT id FROM products").Rows() {
+		if err != nil {
+			iterErr = err
+			break
+		}
+		var id int
+		assert.NoError(t, row.Scan(&id))
+		ids = append(ids, id)
+	}
+	assert.Equal(t, []int{1}, ids)
+	assert.ErrorIs(t, iterErr, readErr)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPGXRowMaps(t *testing.T) {
+	mock, err := pgxmock.NewConn()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	ctx := context.Background()
+	defer mock.Close(ctx)
+
+	mock.ExpectQuery("SELECT id, name FROM products").WillReturnRows(pgxmock.NewRows([]string{"id", "name"}).AddRow(1, "a").AddRow(2, "b"))
+	mock.ExpectQuery("SELECT id, name FROM products").WillReturnRows(pgxmock.NewRows([]string{"id", "name"}).AddRow(1, "a"))
+
+	ob, err := octobe.New(postgres.OpenPGXWithConn(mock))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	session, err := ob.Begin(ctx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	err = session.Builder()("SELECT id, name FROM products").WithMaxRows(5).Query(func(rows postgres.Rows) error {
+		columns, err := postgres.Columns(rows)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"id", "name"}, columns)
+
+		maps, err := postgres.CollectMaps(rows)
+		assert.Equal(t, []map[string]any{{"id": 1, "name": "a"}, {"id": 2, "name": "b"}}, maps)
+		return err
+	})
+	assert.NoError(t, err)
+
+	for row, err := range session.Builder()("SELECT id, name FROM products").Rows() {
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		m, err := postgres.RowMap(row)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]any{"id": 1, "name": "a"}, m)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPGXExecResult(t *testing.T) {
+	mock, err := pgxmock.NewConn()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	ctx := context.Background()
+	defer mock.Close(ctx)
+
+	mock.ExpectExec("INSERT INTO products").WillReturnResult(pgxmock.NewResult("INSERT", 2))
+
+	ob, err := octobe.New(postgres.OpenPGXWithConn(mock))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	session, err := ob.Begin(ctx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	result, err := session.Builder()("INSERT INTO products (name) VALUES ('a'), ('b')").Exec()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), result.RowsAffected)
+	assert.Equal(t, "INSERT", result.Command)
+	assert.True(t, result.Insert())
+	assert.Equal(t, "INSERT 2", result.Tag.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPGXNamedArgs(t *testing.T) {
+	mock, err := pgxmock.NewConn()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	ctx := context.Background()
+	defer mock.Close(ctx)
+
+	mock.ExpectExec(`UPDATE products SET name = \$1 WHERE id = \$2`).WithArgs("chair", 1).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	hook := &dbtxHook{}
+	ob, err := octobe.New(postgres.OpenPGXWithConn(mock), octobe.WithQueryHook(hook))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	session, err := ob.Begin(ctx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	_, err = session.Builder()("UPDATE products SET name = @name WHERE id = @id").
+		Arguments(pgx.NamedArgs{"id": 1, "name": "chair"}).Exec()
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	// The query hooks see the rewritten query with positional arguments.
+	if assert.Len(t, hook.events, 1) {
+		assert.Equal(t, "UPDATE products SET name = $1 WHERE id = $2", hook.events[0].Query)
+		assert.Equal(t, []any{"chair", 1}, hook.events[0].Args)
+	}
+}
+
+func TestPGXRewriter(t *testing.T) {
+	mock, err := pgxmock.NewConn()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	ctx := context.Background()
+	defer mock.Close(ctx)
+
+	mock.ExpectQuery(`SELECT name FROM products WHERE id = \$1 AND deleted_at IS NULL /\* replica \*/`).WithArgs(1).
+		WillReturnRows(pgxmock.NewRows([]string{"name"}).AddRow("chair"))
+	mock.ExpectQuery(`SELECT name FROM products WHERE id = \$1 AND deleted_at IS NULL /\* replica \*/`).WithArgs(1).
+		WillReturnRows(pgxmock.NewRows([]string{"name"}).AddRow("chair"))
+
+	softDelete := func(query string, args []any) (string, []any) {
+		return query + " AND deleted_at IS NULL", args
+	}
+	hint := func(query string, args []any) (string, []any) {
+		return query + " /* replica */", args
+	}
+	ob, err := octobe.New(postgres.OpenPGXWithConn(mock))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	session, err := ob.Begin(ctx, postgres.WithRewriter(softDelete), postgres.WithRewriter(hint))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	var name string
+	segment := session.Builder()("SELECT name FROM products WHERE id = $1").Arguments(1)
+	assert.NoError(t, segment.QueryRow(&name))
+	assert.Equal(t, "chair", name)
+
+	// A clone of the executed segment runs the rewritten query as is.
+	assert.NoError(t, segment.Clone().QueryRow(&name))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOpenPGXStatementCache(t *testing.T) {
+	ctx := context.Background()
+	cache := postgres.WithPGXStatementCache(postgres.PGXStatementCache{Capacity: 64, DescriptionCapacity: 64})
+
+	_, err := octobe.New(postgres.OpenPGX(ctx, "postgres://octobe@127.0.0.1:1/octobe?statement_cache_capacity=x", cache))
+	assert.ErrorContains(t, err, "statement_cache_capacity")
+
+	_, err = octobe.New(postgres.OpenPGXWithOptions(ctx, "postgres://octobe@127.0.0.1:1/octobe?connect_timeout=1",
+		postgres.ParseConfigOptions{}, cache))
+	assert.Error(t, err)
+}
@@ -3,11 +3,14 @@ package postgres
 import (
 	"context"
 	"errors"
+	"slices"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/internal/named"
 )
 
 // PGXPool defines the interface for a connection pool.
@@ -33,36 +36,189 @@ var _ PGXPool = &pgxpool.Pool{} // Ensure pgxpool.Pool implements the PGXPool in
 
 // conn holds the connection pool and default configuration for the conn driver.
 type pgxpoolConn struct {
-	pool PGXPool
+	pool  PGXPool
+	queue *priorityQueue
+}
+
+// PGXPoolOption is a signature for configuring the pgxpool driver when it is opened.
+type PGXPoolOption func(cfg *pgxpoolOptions)
+
+// pgxpoolOptions holds the configuration given when opening the pgxpool driver.
+type pgxpoolOptions struct {
+	queueSize    int
+	hooks        ConnHooks
+	afterConnect []func(ctx context.Context, conn *pgx.Conn) error
+	tune         []func(config *pgxpool.Config)
+}
+
+// WithPriorityQueue puts a client-side priority queue with size slots in front of the pool. Transactional sessions hold
+// a slot from Begin until Commit or Rollback, other sessions hold a slot while a query runs. When all slots are taken,
+// sessions wait and are served by their level set with WithPriority, so low priority background work yields to request
+// path sessions. A size of zero or less uses the maximum number of connections of the pool, opening the driver fails if
+// the pool cannot report it, like a mocked pool. Only the pgxpool driver has a priority queue, the pools of the
+// database/sql and ClickHouse drivers are bounded by their own settings.
+func WithPriorityQueue(size int) PGXPoolOption {
+	return func(cfg *pgxpoolOptions) {
+		if size <= 0 {
+			size = -1
+		}
+		cfg.queueSize = size
+	}
+}
+
+// WithMaxConns sets the maximum number of connections of the pool created by OpenPGXPool or OpenPGXPoolWithConfig,
+// overriding pool_max_conns of the DSN. Like the other tuning options it has no effect for a pool passed to
+// OpenPGXPoolWithPool, which is already created.
+func WithMaxConns(n int32) PGXPoolOption {
+	return tunePool(func(config *pgxpool.Config) {
+		config.MaxConns = n
+	})
+}
+
+// WithMinConns sets the number of connections the pool keeps open even when they are idle, see WithMaxConns.
+func WithMinConns(n int32) PGXPoolOption {
+	return tunePool(func(config *pgxpool.Config) {
+		config.MinConns = n
+	})
+}
+
+// WithMaxConnLifetime sets how long a connection of the pool is used before it is closed and replaced, see
+// WithMaxConns.
+func WithMaxConnLifetime(d time.Duration) PGXPoolOption {
+	return tunePool(func(config *pgxpool.Config) {
+		config.MaxConnLifetime = d
+	})
+}
+
+// WithMaxConnIdleTime sets how long a connection of the pool may stay idle before it is closed, see WithMaxConns.
+func WithMaxConnIdleTime(d time.Duration) PGXPoolOption {
+	return tunePool(func(config *pgxpool.Config) {
+		config.MaxConnIdleTime = d
+	})
+}
+
+// WithHealthCheckPeriod sets how often the pool checks its idle connections and tops them up to the minimum, see
+// WithMaxConns.
+func WithHealthCheckPeriod(d time.Duration) PGXPoolOption {
+	return tunePool(func(config *pgxpool.Config) {
+		config.HealthCheckPeriod = d
+	})
+}
+
+// WithPoolStatementCache configures the caches pgx keeps on every connection of the pool, see PGXStatementCache and
+// WithMaxConns.
+func WithPoolStatementCache(cache PGXStatementCache) PGXPoolOption {
+	return tunePool(func(config *pgxpool.Config) {
+		cache.apply(config.ConnConfig)
+	})
+}
+
+// tunePool returns an option changing the configuration of the pool with tune before it is created.
+func tunePool(tune func(config *pgxpool.Config)) PGXPoolOption {
+	return func(cfg *pgxpoolOptions) {
+		cfg.tune = append(cfg.tune, tune)
+	}
+}
+
+// newPGXPoolOptions returns the configuration of the pgxpool driver with the given options applied.
+func newPGXPoolOptions(opts []PGXPoolOption) pgxpoolOptions {
+	var cfg pgxpoolOptions
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// newPGXPoolConn creates the driver for pool with the given configuration.
+func newPGXPoolConn(pool PGXPool, cfg pgxpoolOptions) (*pgxpoolConn, error) {
+	conn := &pgxpoolConn{pool: pool}
+	if cfg.queueSize < 0 {
+		stat := poolStat(pool)
+		if stat == nil {
+			return nil, errors.New("priority queue size cannot be derived from the pool, pass a size to WithPriorityQueue")
+		}
+		cfg.queueSize = int(stat.MaxConns())
+	}
+	if cfg.queueSize > 0 {
+		conn.queue = newPriorityQueue(cfg.queueSize)
+	}
+	return conn, nil
+}
+
+// poolStat returns the statistics of pool, or nil for a mocked pool returning a nil or empty Stat.
+func poolStat(pool PGXPool) *pgxpool.Stat {
+	if stat := pool.Stat(); stat != nil && *stat != (pgxpool.Stat{}) {
+		return stat
+	}
+	return nil
+}
+
+// acquire waits for a slot in the priority queue if the driver has one, the returned function releases the slot.
+func (d *pgxpoolConn) acquire(ctx context.Context, priority int) (func(), error) {
+	if d.queue == nil {
+		return func() {}, nil
+	}
+	if err := d.queue.acquire(ctx, priority); err != nil {
+		return nil, err
+	}
+	return d.queue.release, nil
 }
 
 // Ensure conn implements the octobe.Driver interface.
 var _ octobe.Driver[pgxpoolConn, pgxConfig, Builder] = &pgxpoolConn{}
 
 // Open creates a new database connection and returns a driver with the specified types.
-func OpenPGXPool(ctx context.Context, dsn string) octobe.Open[pgxpoolConn, pgxConfig, Builder] {
+func OpenPGXPool(ctx context.Context, dsn string, opts ...PGXPoolOption) octobe.Open[pgxpoolConn, pgxConfig, Builder] {
 	return func() (octobe.Driver[pgxpoolConn, pgxConfig, Builder], error) {
-		pool, err := pgxpool.New(ctx, dsn)
+		config, err := pgxpool.ParseConfig(dsn)
 		if err != nil {
 			return nil, err
 		}
+		return openPGXPool(ctx, config, opts)
+	}
+}
+
+// OpenPGXPoolWithConfig creates a connection pool from config and returns a driver with the specified types, so the
+// pool can be tuned beyond what the DSN of OpenPGXPool expresses, like with MaxConns, HealthCheckPeriod, AfterConnect or
+// the Tracer of its ConnConfig. The config must be created with pgxpool.ParseConfig, the pool is created from a copy of
+// it. Hooks registered with WithPoolConnHooks run after the callbacks already set on it.
+func OpenPGXPoolWithConfig(ctx context.Context, config *pgxpool.Config, opts ...PGXPoolOption) octobe.Open[pgxpoolConn, pgxConfig, Builder] {
+	return func() (octobe.Driver[pgxpoolConn, pgxConfig, Builder], error) {
+		if config == nil {
+			return nil, errors.New("config is nil")
+		}
+		return openPGXPool(ctx, config.Copy(), opts)
+	}
+}
+
+// openPGXPool creates the pool of config with the given options applied and returns the driver for it.
+func openPGXPool(ctx context.Context, config *pgxpool.Config, opts []PGXPoolOption) (octobe.Driver[pgxpoolConn, pgxConfig, Builder], error) {
+	cfg := newPGXPoolOptions(opts)
+	for _, tune := range cfg.tune {
+		tune(config)
+	}
+	cfg.hooks.afterConnect(cfg.afterConnect).register(config)
 
-		return &pgxpoolConn{
-			pool: pool,
-		}, nil
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	d, err := newPGXPoolConn(pool, cfg)
+	if err != nil {
+		pool.Close()
+		return nil, err
 	}
+	return d, nil
 }
 
 // OpenWithPool creates a new database connection using an existing connection pool.
-func OpenPGXPoolWithPool(pool PGXPool) octobe.Open[pgxpoolConn, pgxConfig, Builder] {
+func OpenPGXPoolWithPool(pool PGXPool, opts ...PGXPoolOption) octobe.Open[pgxpoolConn, pgxConfig, Builder] {
 	return func() (octobe.Driver[pgxpoolConn, pgxConfig, Builder], error) {
 		if pool == nil {
 			return nil, errors.New("pool is nil")
 		}
 
-		return &pgxpoolConn{
-			pool: pool,
-		}, nil
+		return newPGXPoolConn(pool, newPGXPoolOptions(opts))
 	}
 }
 
@@ -73,9 +229,19 @@ func (d *pgxpoolConn) Begin(ctx context.Context, opts ...octobe.Option[pgxConfig
 		opt(&cfg)
 	}
 
+	if cfg.txOptions == nil && len(cfg.settings) > 0 {
+		return nil, ErrSettingsUnsupported
+	}
+
 	var tx pgx.Tx
 	var err error
+	release := func() {}
 	if cfg.txOptions != nil {
+		release, err = d.acquire(ctx, cfg.priority)
+		if err != nil {
+			return nil, err
+		}
+
 		tx, err = d.pool.BeginTx(ctx, pgx.TxOptions{
 			IsoLevel:       cfg.txOptions.IsoLevel,
 			AccessMode:     cfg.txOptions.AccessMode,
@@ -85,15 +251,84 @@ func (d *pgxpoolConn) Begin(ctx context.Context, opts ...octobe.Option[pgxConfig
 	}
 
 	if err != nil {
+		release()
 		return nil, err
 	}
 
-	return &pgxpoolSession{
-		ctx: ctx,
-		cfg: cfg,
-		tx:  tx,
-		d:   d,
-	}, nil
+	if tx != nil {
+		if err = applySettings(ctx, cfg.settings, true, tx.Exec); err != nil {
+			err = errors.Join(err, tx.Rollback(context.WithoutCancel(ctx)))
+			release()
+			return nil, err
+		}
+	}
+
+	session := &pgxpoolSession{
+		ctx:     ctx,
+		cfg:     cfg,
+		tx:      tx,
+		d:       d,
+		release: release,
+	}
+	session.builder = session.build
+	return session, nil
+}
+
+// PoolStats is a snapshot of the statistics of the pgxpool driver, reported through octobe.Octobe.Stats.
+type PoolStats struct {
+	AcquireCount         int64         `json:"acquire_count"`
+	AcquireDuration      time.Duration `json:"acquire_duration"`
+	AcquiredConns        int32         `json:"acquired_conns"`
+	CanceledAcquireCount int64         `json:"canceled_acquire_count"`
+	ConstructingConns    int32         `json:"constructing_conns"`
+	EmptyAcquireCount    int64         `json:"empty_acquire_count"`
+	IdleConns            int32         `json:"idle_conns"`
+	MaxConns             int32         `json:"max_conns"`
+	TotalConns           int32         `json:"total_conns"`
+	NewConnsCount        int64         `json:"new_conns_count"`
+	QueueWaiting         int           `json:"queue_waiting"` // Sessions waiting in the priority queue, if configured
+}
+
+// Ensure pgxpoolConn reports statistics.
+var _ octobe.StatsReporter = &pgxpoolConn{}
+
+// Stats returns a PoolStats snapshot of the pool. Mocked pools returning a nil or empty Stat report zero statistics.
+func (d *pgxpoolConn) Stats() any {
+	var stats PoolStats
+	if stat := poolStat(d.pool); stat != nil {
+		stats = PoolStats{
+			AcquireCount:         stat.AcquireCount(),
+			AcquireDuration:      stat.AcquireDuration(),
+			AcquiredConns:        stat.AcquiredConns(),
+			CanceledAcquireCount: stat.CanceledAcquireCount(),
+			ConstructingConns:    stat.ConstructingConns(),
+			EmptyAcquireCount:    stat.EmptyAcquireCount(),
+			IdleConns:            stat.IdleConns(),
+			MaxConns:             stat.MaxConns(),
+			TotalConns:           stat.TotalConns(),
+			NewConnsCount:        stat.NewConnsCount(),
+		}
+	}
+	if d.queue != nil {
+		stats.QueueWaiting = d.queue.waiting()
+	}
+	return stats
+}
+
+// Ensure pgxpoolConn describes itself to query hooks.
+var _ octobe.Describer = &pgxpoolConn{}
+
+// Describe returns the name of the driver and the database system it connects to.
+func (d *pgxpoolConn) Describe() octobe.DriverInfo {
+	return octobe.DriverInfo{Name: "pgxpool", System: "postgresql"}
+}
+
+// Ensure pgxpoolConn reports its capabilities.
+var _ octobe.CapabilityReporter = &pgxpoolConn{}
+
+// Capabilities returns the features supported by the pgxpool driver.
+func (d *pgxpoolConn) Capabilities() octobe.Capabilities {
+	return octobe.Capabilities{Transactions: true, Savepoints: true, Batch: true, Copy: true, Returning: true}
 }
 
 // Close closes the database connection.
@@ -112,28 +347,32 @@ func (d *pgxpoolConn) Ping(ctx context.Context) error {
 
 // session holds session context and manages a series of related queries.
 type pgxpoolSession struct {
-	ctx       context.Context
-	cfg       pgxConfig
-	tx        pgx.Tx
-	d         *pgxpoolConn
-	committed bool
+	ctx      context.Context
+	cfg      pgxConfig
+	tx       pgx.Tx
+	d        *pgxpoolConn
+	state    txState
+	release  func()
+	released bool
+	builder  Builder
 }
 
 // Ensure session implements the octobe.Session interface.
 var _ octobe.Session[Builder] = &pgxpoolSession{}
+var _ octobe.Transactional = &pgxpoolSession{}
 
 // Commit commits a transaction if the session is transactional.
 func (s *pgxpoolSession) Commit() error {
-	if s.committed {
-		return errors.New("cannot commit a session that has already been committed")
-	}
 	if s.cfg.txOptions == nil {
 		return errors.New("cannot commit without transaction")
 	}
-	defer func() {
-		s.committed = true
-	}()
-	return s.tx.Commit(s.ctx)
+	if err := s.state.check(); err != nil {
+		return err
+	}
+	defer s.releaseSlot()
+	err := s.tx.Commit(s.ctx)
+	s.state.end(err == nil)
+	return err
 }
 
 // Rollback rolls back a transaction if the session is transactional.
@@ -141,31 +380,66 @@ func (s *pgxpoolSession) Rollback() error {
 	if s.cfg.txOptions == nil {
 		return errors.New("cannot rollback without transaction")
 	}
+	if err := s.state.check(); err != nil {
+		return err
+	}
+	defer s.releaseSlot()
+	s.state.end(false)
 	return s.tx.Rollback(s.ctx)
 }
 
-// Builder returns a new builder for building queries.
+// InTransaction reports whether the session runs in a transaction.
+func (s *pgxpoolSession) InTransaction() bool {
+	return s.cfg.txOptions != nil
+}
+
+// releaseSlot returns the slot of a transactional session to the priority queue, once.
+func (s *pgxpoolSession) releaseSlot() {
+	if s.released {
+		return
+	}
+	s.released = true
+	s.release()
+}
+
+// Builder returns the builder for building queries in the session.
 func (s *pgxpoolSession) Builder() Builder {
-	return func(query string) Segment {
-		return &pgxpoolSegment{
-			query: query,
-			args:  nil,
-			used:  false,
-			tx:    s.tx,
-			d:     s.d,
-			ctx:   s.ctx,
-		}
+	return s.builder
+}
+
+// build creates a segment for query in the session. It backs the builder of the session, which is created once so that
+// building segments in a loop only allocates the segments.
+func (s *pgxpoolSession) build(query string) Segment {
+	return &pgxpoolSegment{
+		query:    query,
+		args:     nil,
+		used:     false,
+		tx:       s.tx,
+		d:        s.d,
+		ctx:      s.ctx,
+		priority: s.cfg.priority,
+		rewrite:  s.cfg.rewrite,
+		execMode: s.cfg.execMode,
+		readOnly: s.cfg.readOnly,
 	}
 }
 
 // Segment represents a specific query that can be run only once.
 type pgxpoolSegment struct {
-	query string          // SQL query to be executed
-	args  []any           // Argument values for the query
-	used  bool            // Indicates if the Segment has been executed
-	tx    pgx.Tx          // Database transaction, initiated by BeginTx
-	d     *pgxpoolConn    // Driver used for the session
-	ctx   context.Context // Context to interrupt a query
+	query    string             // SQL query to be executed
+	args     []any              // Argument values for the query
+	used     bool               // Indicates if the Segment has been executed
+	tx       pgx.Tx             // Database transaction, initiated by BeginTx
+	d        *pgxpoolConn       // Driver used for the session
+	ctx      context.Context    // Context to interrupt a query
+	maxRows  int                // Maximum number of rows Query may read, zero means no limit
+	timeout  time.Duration      // Deadline of the statement relative to its execution, zero means none
+	priority int                // Priority of the session in the priority queue of the driver
+	prepared string             // Name of the prepared statement executed in place of the query, see Prepare
+	rewrite  Rewriter           // Rewriter of the session applied before execution, nil once applied
+	execMode *pgx.QueryExecMode // Mode pgx sends the statement in, nil for the default of the connection
+	readOnly bool               // Whether the session is read-only, rejecting Exec
+	err      error              // Error from building the Segment, returned when it is executed
 }
 
 var _ Segment = &pgxpoolSegment{}
@@ -175,75 +449,225 @@ func (s *pgxpoolSegment) use() {
 	s.used = true
 }
 
-// Arguments sets the arguments for the query.
+// rewriteQuery applies the rewriter of the session to the query and arguments, once.
+func (s *pgxpoolSegment) rewriteQuery() {
+	if s.rewrite != nil {
+		s.query, s.args = s.rewrite(s.query, s.args)
+		s.rewrite = nil
+	}
+}
+
+// Arguments sets the arguments for the query, rewriting the query for a pgx.QueryRewriter like pgx.NamedArgs.
 func (s *pgxpoolSegment) Arguments(args ...any) Segment {
-	s.args = args
+	var mode *pgx.QueryExecMode
+	s.query, s.args, mode, s.err = rewriteArgs(s.ctx, nil, s.query, args)
+	if mode != nil {
+		s.execMode = mode
+	}
 	return s
 }
 
+// ArgumentsFromStruct binds the fields of a struct to the query by their db tags. Named parameters like :name are bound
+// to the field of the same name, a query without named parameters gets all fields as arguments in declaration order.
+func (s *pgxpoolSegment) ArgumentsFromStruct(v any) Segment {
+	s.query, s.args, s.err = named.Bind(s.query, named.Dollar, v)
+	return s
+}
+
+// NamedArguments binds the values of a map to the named parameters of the query, like :name or @name.
+func (s *pgxpoolSegment) NamedArguments(args map[string]any) Segment {
+	s.query, s.args, s.err = named.BindMap(s.query, named.Dollar, args)
+	return s
+}
+
+// WithMaxRows limits the number of rows Query may read before failing with a *octobe.MaxRowsError.
+func (s *pgxpoolSegment) WithMaxRows(n int) Segment {
+	s.maxRows = n
+	return s
+}
+
+// Timeout sets a deadline of d for executing the statement, leaving the context of the session as is.
+func (s *pgxpoolSegment) Timeout(d time.Duration) Segment {
+	s.timeout = d
+	return s
+}
+
+// Clone returns an unused copy of the segment with the same query, arguments and settings.
+func (s *pgxpoolSegment) Clone() Segment {
+	c := *s
+	c.used = false
+	c.args = slices.Clone(s.args)
+	return &c
+}
+
 // Exec executes a query for inserts or updates.
-func (s *pgxpoolSegment) Exec() (ExecResult, error) {
+func (s *pgxpoolSegment) Exec() (result ExecResult, err error) {
 	if s.used {
 		return ExecResult{}, octobe.ErrAlreadyUsed
 	}
 	defer s.use()
+	if s.err != nil {
+		return ExecResult{}, s.err
+	}
+	if s.readOnly {
+		return ExecResult{}, ErrReadOnly
+	}
+	s.rewriteQuery()
+
+	ctx, cancel := withTimeout(s.ctx, s.timeout)
+	defer cancel()
+	ctx, done := octobe.BeginQuery(ctx, octobe.OperationExec, s.query, s.args)
+	defer func() {
+		err = wrapQueryError(s.d.Describe().Name, s.query, s.args, err)
+		done(result.RowsAffected, err)
+	}()
+
 	if s.tx == nil {
-		res, err := s.d.pool.Exec(s.ctx, s.query, s.args...)
+		release, err := s.d.acquire(ctx, s.priority)
+		if err != nil {
+			return ExecResult{}, err
+		}
+		defer release()
+
+		res, err := s.d.pool.Exec(ctx, s.statement(ctx), execArgs(s.execMode, s.args)...)
 		if err != nil {
 			return ExecResult{}, err
 		}
 
-		return ExecResult{
-			RowsAffected: res.RowsAffected(),
-		}, nil
+		return tagResult(res), nil
 	}
 
-	res, err := s.tx.Exec(s.ctx, s.query, s.args...)
+	res, err := s.tx.Exec(ctx, s.statement(ctx), execArgs(s.execMode, s.args)...)
 	if err != nil {
 		return ExecResult{}, err
 	}
-	return ExecResult{
-		RowsAffected: res.RowsAffected(),
-	}, nil
+	return tagResult(res), nil
 }
 
 // QueryRow returns one result and puts it into destination pointers.
-func (s *pgxpoolSegment) QueryRow(dest ...any) error {
+func (s *pgxpoolSegment) QueryRow(dest ...any) (err error) {
 	if s.used {
 		return octobe.ErrAlreadyUsed
 	}
 	defer s.use()
+	if s.err != nil {
+		return s.err
+	}
+	s.rewriteQuery()
+
+	ctx, cancel := withTimeout(s.ctx, s.timeout)
+	defer cancel()
+	ctx, done := octobe.BeginQuery(ctx, octobe.OperationQueryRow, s.query, s.args)
+	defer func() {
+		err = wrapQueryError(s.d.Describe().Name, s.query, s.args, err)
+		done(queryRowCount(err), err)
+	}()
+
 	if s.tx == nil {
-		return s.d.pool.QueryRow(s.ctx, s.query, s.args...).Scan(dest...)
+		release, err := s.d.acquire(ctx, s.priority)
+		if err != nil {
+			return err
+		}
+		defer release()
+
+		return s.d.pool.QueryRow(ctx, s.statement(ctx), execArgs(s.execMode, s.args)...).Scan(dest...)
 	}
-	return s.tx.QueryRow(s.ctx, s.query, s.args...).Scan(dest...)
+	return s.tx.QueryRow(ctx, s.statement(ctx), execArgs(s.execMode, s.args)...).Scan(dest...)
 }
 
 // Query performs a normal query against the database that returns rows.
-func (s *pgxpoolSegment) Query(cb func(Rows) error) error {
+func (s *pgxpoolSegment) Query(cb func(Rows) error) (err error) {
 	if s.used {
 		return octobe.ErrAlreadyUsed
 	}
 	defer s.use()
+	if s.err != nil {
+		return s.err
+	}
+	s.rewriteQuery()
+
+	ctx, cancel := withTimeout(s.ctx, s.timeout)
+	defer cancel()
+	ctx, done := octobe.BeginQuery(ctx, octobe.OperationQuery, s.query, s.args)
+	rowCount := int64(-1)
+	defer func() {
+		err = wrapQueryError(s.d.Describe().Name, s.query, s.args, err)
+		done(rowCount, err)
+	}()
 
-	var err error
 	var rows pgx.Rows
 	if s.tx == nil {
-		rows, err = s.d.pool.Query(s.ctx, s.query, s.args...)
+		var release func()
+		release, err = s.d.acquire(ctx, s.priority)
+		if err != nil {
+			return err
+		}
+		defer release()
+
+		rows, err = s.d.pool.Query(ctx, s.statement(ctx), execArgs(s.execMode, s.args)...)
 		if err != nil {
 			return err
 		}
 	} else {
-		rows, err = s.tx.Query(s.ctx, s.query, s.args...)
+		rows, err = s.tx.Query(ctx, s.statement(ctx), execArgs(s.execMode, s.args)...)
 		if err != nil {
 			return err
 		}
 	}
 
-	defer rows.Close()
-	if err = cb(rows); err != nil {
+	defer func() {
+		rows.Close()
+		rowCount = rows.CommandTag().RowsAffected()
+	}()
+	limited := limitRows(rows, s.maxRows)
+	if err = cb(limited); err != nil {
 		return err
 	}
 
-	return nil
+	return limitErr(limited)
+}
+
+// statement returns the SQL to send for the query of the segment with its comment, or the name of its prepared
+// statement if it has one.
+func (s *pgxpoolSegment) statement(ctx context.Context) string {
+	if s.prepared != "" {
+		return s.prepared
+	}
+	return octobe.CommentQuery(ctx, s.query)
+}
+
+// QueryRowMap returns the first row of the result as a map keyed by column name.
+func (s *pgxpoolSegment) QueryRowMap() (map[string]any, error) {
+	var row map[string]any
+	err := s.Query(func(rows Rows) error {
+		var err error
+		row, err = firstMap(rows, pgx.ErrNoRows)
+		return err
+	})
+	return row, err
+}
+
+// QueryMaps returns all rows of the result as maps keyed by column name.
+func (s *pgxpoolSegment) QueryMaps() ([]map[string]any, error) {
+	var result []map[string]any
+	err := s.Query(func(rows Rows) error {
+		var err error
+		result, err = collectMaps(rows)
+		return err
+	})
+	return result, err
+}
+
+// QueryRowStruct reads the first row of the result into the struct dest points to, mapping columns by db tags.
+func (s *pgxpoolSegment) QueryRowStruct(dest any) error {
+	return s.Query(func(rows Rows) error {
+		return firstStruct(rows, dest, pgx.ErrNoRows)
+	})
+}
+
+// QueryStructs reads all rows of the result into the slice dest points to, mapping columns by db tags.
+func (s *pgxpoolSegment) QueryStructs(dest any) error {
+	return s.Query(func(rows Rows) error {
+		return collectStructs(rows, dest)
+	})
 }
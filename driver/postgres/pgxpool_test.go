@@ -3,12 +3,16 @@ package postgres_test
 import (
 	"context"
 	"errors"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/pashagolub/pgxmock/v4"
 	"github.com/ponrove/octobe"
 	"github.com/ponrove/octobe/driver/postgres"
+	postgresmock "github.com/ponrove/octobe/driver/postgres/mock"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -819,3 +823,623 @@ func TestPGXPoolSegmentQueryError(t *testing.T) {
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 }
+
+func TestPGXPoolSegmentQueryMaxRows(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	ctx := context.Background()
+	defer mock.Close()
+
+	mock.ExpectBeginTx(pgx.TxOptions{})
+	mock.ExpectQuery("SELECT id FROM products").WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(1).AddRow(2).AddRow(3))
+	mock.ExpectRollback()
+
+	ob, err := octobe.New(postgres.OpenPGXPoolWithPool(mock))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	var read int
+	err = ob.StartTransaction(ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+		return session.Builder()("SELECT id FROM products").WithMaxRows(2).Query(func(rows postgres.Rows) error {
+			for rows.Next() {
+				read++
+			}
+			return rows.Err()
+		})
+	}, postgres.WithPGXTxOptions(postgres.PGXTxOptions{}))
+
+	assert.ErrorIs(t, err, octobe.ErrMaxRowsExceeded)
+	assert.Equal(t, 2, read)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPGXPoolSegmentQueryMaps(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	ctx := context.Background()
+	defer mock.Close()
+
+	mock.ExpectQuery("SELECT id, name FROM products").WillReturnRows(pgxmock.NewRows([]string{"id", "name"}).AddRow(1, "a").AddRow(2, "b"))
+	mock.ExpectQuery("SELECT id, name FROM products WHERE id").WithArgs(1).WillReturnRows(pgxmock.NewRows([]string{"id", "name"}).AddRow(1, "a"))
+
+	ob, err := octobe.New(postgres.OpenPGXPoolWithPool(mock))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	session, err := ob.Begin(ctx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	rows, err := session.Builder()("SELECT id, name FROM products").WithMaxRows(2).QueryMaps()
+	assert.NoError(t, err)
+	assert.Equal(t, []map[string]any{{"id": 1, "name": "a"}, {"id": 2, "name": "b"}}, rows)
+
+	row, err := session.Builder()("SELECT id, name FROM products WHERE id = $1").Arguments(1).QueryRowMap()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"id": 1, "name": "a"}, row)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPGXPoolPriorityQueue(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	ctx := context.Background()
+	defer mock.Close()
+
+	for i := 0; i < 3; i++ {
+		mock.ExpectBeginTx(pgx.TxOptions{})
+		mock.ExpectCommit()
+	}
+
+	ob, err := octobe.New(postgres.OpenPGXPoolWithPool(mock, postgres.WithPriorityQueue(1)))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	holder, err := ob.Begin(ctx, postgres.WithPGXTxOptions(postgres.PGXTxOptions{}))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	var (
+		mu    sync.Mutex
+		order []string
+		wg    sync.WaitGroup
+	)
+	begin := func(name string, level int) {
+		defer wg.Done()
+		session, err := ob.Begin(ctx, postgres.WithPGXTxOptions(postgres.PGXTxOptions{}), postgres.WithPriority(level))
+		if !assert.NoError(t, err) {
+			return
+		}
+		mu.Lock()
+		order = append(order, name)
+		mu.Unlock()
+		assert.NoError(t, session.Commit())
+	}
+
+	wg.Add(2)
+	go begin("background", -1)
+	go begin("request", 10)
+
+	// Give both sessions time to queue up behind the holder.
+	time.Sleep(50 * time.Millisecond)
+	mu.Lock()
+	assert.Empty(t, order)
+	mu.Unlock()
+
+	assert.NoError(t, holder.Commit())
+	wg.Wait()
+
+	assert.Equal(t, []string{"request", "background"}, order)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPGXPoolPriorityQueueContextDone(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	ctx := context.Background()
+	defer mock.Close()
+
+	mock.ExpectBeginTx(pgx.TxOptions{})
+	mock.ExpectRollback()
+	mock.ExpectExec("UPDATE products").WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	ob, err := octobe.New(postgres.OpenPGXPoolWithPool(mock, postgres.WithPriorityQueue(1)))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	holder, err := ob.Begin(ctx, postgres.WithPGXTxOptions(postgres.PGXTxOptions{}))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+
+	_, err = ob.Begin(timeoutCtx, postgres.WithPGXTxOptions(postgres.PGXTxOptions{}))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	session, err := ob.Begin(timeoutCtx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	_, err = session.Builder()("UPDATE products SET name = 'octobe'").Exec()
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// The slot is released on rollback and picked up by the next query.
+	assert.NoError(t, holder.Rollback())
+	session, err = ob.Begin(ctx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	_, err = session.Builder()("UPDATE products SET name = 'octobe'").Exec()
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPGXPoolStats(t *testing.T) {
+	ctx := context.Background()
+
+	// The pool connects lazily, so statistics are available without a database.
+	ob, err := octobe.New(postgres.OpenPGXPool(ctx, "postgres://octobe@127.0.0.1:1/octobe?pool_max_conns=3", postgres.WithPriorityQueue(0)))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer ob.Close(ctx)
+
+	stats, ok := ob.Stats().Driver.(postgres.PoolStats)
+	if !assert.True(t, ok) {
+		t.FailNow()
+	}
+	assert.Equal(t, int32(3), stats.MaxConns)
+	assert.Zero(t, stats.TotalConns)
+	assert.Zero(t, stats.QueueWaiting)
+}
+
+func TestPGXPoolPriorityQueueSizeOfMockedPool(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer mock.Close()
+
+	// A mocked pool reports no maximum number of connections to size the queue with.
+	_, err = octobe.New(postgres.OpenPGXPoolWithPool(mock, postgres.WithPriorityQueue(0)))
+	assert.ErrorContains(t, err, "pass a size to WithPriorityQueue")
+	_, err = octobe.New(postgres.OpenPGXPoolWithPool(postgresmock.NewPGXPoolMock(), postgres.WithPriorityQueue(0)))
+	assert.ErrorContains(t, err, "pass a size to WithPriorityQueue")
+}
+
+func TestPGXPoolStatsMocked(t *testing.T) {
+	ctx := context.Background()
+
+	mock, err := pgxmock.NewPool()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer mock.Close()
+
+	// A mocked pool has no statistics, they are reported as zero instead of panicking.
+	ob, err := octobe.New(postgres.OpenPGXPoolWithPool(mock))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer ob.Close(ctx)
+
+	assert.Equal(t, postgres.PoolStats{}, ob.Stats().Driver)
+}
+
+func TestOpenPGXPoolWithConfig(t *testing.T) {
+	ctx := context.Background()
+
+	_, err := octobe.New(postgres.OpenPGXPoolWithConfig(ctx, nil))
+	assert.Error(t, err)
+
+	config, err := pgxpool.ParseConfig("postgres://octobe@127.0.0.1:1/octobe")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	config.MaxConns = 7
+	config.HealthCheckPeriod = time.Minute
+
+	ob, err := octobe.New(postgres.OpenPGXPoolWithConfig(ctx, config, postgres.WithPoolConnHooks(postgres.ConnHooks{
+		OnOpen: func(context.Context, *pgx.Conn) error { return nil },
+	})))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer ob.Close(ctx)
+
+	stats, ok := ob.Stats().Driver.(postgres.PoolStats)
+	if !assert.True(t, ok) {
+		t.FailNow()
+	}
+	assert.Equal(t, int32(7), stats.MaxConns)
+	// The hooks are registered on a copy, the config of the caller stays as it was.
+	assert.Nil(t, config.AfterConnect)
+}
+
+func TestPGXPoolTuning(t *testing.T) {
+	ctx := context.Background()
+
+	ob, err := octobe.New(postgres.OpenPGXPool(ctx, "postgres://octobe@127.0.0.1:1/octobe?pool_max_conns=3",
+		postgres.WithMaxConns(9),
+		postgres.WithMaxConnLifetime(time.Hour),
+		postgres.WithMaxConnIdleTime(time.Minute),
+		postgres.WithHealthCheckPeriod(time.Minute),
+		postgres.WithPoolStatementCache(postgres.PGXStatementCache{Disabled: true}),
+		postgres.WithPriorityQueue(0),
+	))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer ob.Close(ctx)
+
+	stats, ok := ob.Stats().Driver.(postgres.PoolStats)
+	if !assert.True(t, ok) {
+		t.FailNow()
+	}
+	assert.Equal(t, int32(9), stats.MaxConns)
+}
+
+func TestPGXPoolConnHooks(t *testing.T) {
+	ctx := context.Background()
+
+	var opened bool
+	hooks := postgres.ConnHooks{
+		OnOpen: func(context.Context, *pgx.Conn) error {
+			opened = true
+			return nil
+		},
+	}
+
+	_, err := octobe.New(postgres.OpenPGXPool(ctx, "postgres://octobe@127.0.0.1:1/octobe?pool_max_conns=x", postgres.WithPoolConnHooks(hooks)))
+	assert.Error(t, err)
+
+	ob, err := octobe.New(postgres.OpenPGXPool(ctx, "postgres://octobe@127.0.0.1:1/octobe?connect_timeout=1", postgres.WithPoolConnHooks(hooks)))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer ob.Close(ctx)
+
+	// A connection that cannot be established is never handed to the hooks.
+	session, err := ob.Begin(ctx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	_, err = session.Builder()("SELECT 1").Exec()
+	assert.Error(t, err)
+	assert.False(t, opened)
+}
+
+func TestPGXPoolAfterConnect(t *testing.T) {
+	ctx := context.Background()
+
+	var registered []string
+	register := func(name string) func(context.Context, *pgx.Conn) error {
+		return func(context.Context, *pgx.Conn) error {
+			registered = append(registered, name)
+			return nil
+		}
+	}
+
+	ob, err := octobe.New(postgres.OpenPGXPool(ctx, "postgres://octobe@127.0.0.1:1/octobe?connect_timeout=1",
+		postgres.WithPoolAfterConnect(register("order_status")),
+		postgres.WithPoolAfterConnect(register("geometry")),
+	))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer ob.Close(ctx)
+
+	// Types are only registered on connections that were established.
+	session, err := ob.Begin(ctx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	_, err = session.Builder()("SELECT 1").Exec()
+	assert.Error(t, err)
+	assert.Empty(t, registered)
+
+	_, err = octobe.New(postgres.OpenPGX(ctx, "postgres://octobe@127.0.0.1:1/octobe?connect_timeout=1",
+		postgres.WithAfterConnect(register("order_status")),
+	))
+	assert.Error(t, err)
+	assert.Empty(t, registered)
+}
+
+func TestPGXPoolReplicated(t *testing.T) {
+	primary, err := pgxmock.NewPool()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	replica, err := pgxmock.NewPool()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	ctx := context.Background()
+
+	readOnly := pgx.TxOptions{AccessMode: pgx.ReadOnly}
+	replica.ExpectBeginTx(readOnly)
+	replica.ExpectQuery("SELECT id, name FROM products").WillReturnRows(pgxmock.NewRows([]string{"id", "name"}).AddRow(1, "octobe"))
+	replica.ExpectCommit()
+	primary.ExpectBeginTx(pgx.TxOptions{})
+	primary.ExpectExec("UPDATE products").WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	primary.ExpectCommit()
+	replica.ExpectClose()
+	primary.ExpectClose()
+
+	ob, err := octobe.New(octobe.OpenReplicated(octobe.RoundRobin, postgres.OpenPGXPoolWithPool(primary), postgres.OpenPGXPoolWithPool(replica)))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	err = ob.StartTransaction(ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+		var id int
+		var name string
+		return session.Builder()("SELECT id, name FROM products").QueryRow(&id, &name)
+	}, postgres.WithPGXTxOptions(postgres.PGXTxOptions(readOnly)))
+	assert.NoError(t, err)
+
+	err = ob.StartTransaction(ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+		_, err := session.Builder()("UPDATE products SET name = 'octobe'").Exec()
+		return err
+	}, postgres.WithPGXTxOptions(postgres.PGXTxOptions{}))
+	assert.NoError(t, err)
+
+	assert.NoError(t, ob.Close(ctx))
+	assert.NoError(t, primary.ExpectationsWereMet())
+	assert.NoError(t, replica.ExpectationsWereMet())
+}
+
+func TestPGXPoolTxDone(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	ctx := context.Background()
+	defer mock.Close()
+	mock.ExpectBeginTx(pgx.TxOptions{})
+	mock.ExpectCommit()
+
+	ob, err := octobe.New(postgres.OpenPGXPoolWithPool(mock))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	session, err := ob.Begin(ctx, postgres.WithPGXTxOptions(postgres.PGXTxOptions{}))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.NoError(t, session.Commit())
+	assert.ErrorIs(t, session.Commit(), octobe.ErrAlreadyCommitted)
+	assert.ErrorIs(t, session.Rollback(), octobe.ErrAlreadyCommitted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPGXPoolExecuteParallel(t *testing.T) {
+	mock, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherEqual))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	ctx := context.Background()
+	defer mock.Close()
+	mock.MatchExpectationsInOrder(false)
+
+	count := func(table string) postgres.Handler[int] {
+		return func(builder postgres.Builder) (int, error) {
+			var n int
+			err := builder("SELECT count(*) FROM " + table).QueryRow(&n)
+			return n, err
+		}
+	}
+	mock.ExpectQuery("SELECT count(*) FROM orders").WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(3))
+	mock.ExpectQuery("SELECT count(*) FROM products").WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(5))
+	mock.ExpectQuery("SELECT count(*) FROM users").WillReturnError(errors.New("permission denied"))
+	mock.ExpectBeginTx(pgx.TxOptions{})
+	mock.ExpectRollback()
+
+	ob, err := octobe.New(postgres.OpenPGXPoolWithPool(mock))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	session, err := ob.Begin(ctx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	counts, err := postgres.ExecuteParallel(session, 2, count("orders"), count("products"), count("users"))
+	assert.ErrorContains(t, err, "handler 2:")
+	assert.Equal(t, []int{3, 5, 0}, counts)
+
+	tx, err := ob.Begin(ctx, postgres.WithPGXTxOptions(postgres.PGXTxOptions{}))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	_, err = postgres.ExecuteParallel(tx, 2, count("orders"))
+	assert.ErrorIs(t, err, postgres.ErrNotParallel)
+	assert.NoError(t, tx.Rollback())
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPGXPoolExecuteParallelWithConcurrencyGuard(t *testing.T) {
+	mock, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherEqual))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer mock.Close()
+	mock.MatchExpectationsInOrder(false)
+
+	const handlers = 3
+	for range handlers {
+		mock.ExpectExec("UPDATE counters SET n = n + 1").WillReturnResult(pgxmock.NewResult("UPDATE", 1)).
+			WillDelayFor(20 * time.Millisecond)
+	}
+
+	ob, err := octobe.New(postgres.OpenPGXPoolWithPool(mock), octobe.WithConcurrencyGuard())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	session, err := ob.Begin(context.Background())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	// All handlers start their query at once, so the queries overlap.
+	var ready sync.WaitGroup
+	ready.Add(handlers)
+	increment := func(builder postgres.Builder) (int64, error) {
+		ready.Done()
+		ready.Wait()
+		result, err := builder("UPDATE counters SET n = n + 1").Exec()
+		return result.RowsAffected, err
+	}
+	affected, err := postgres.ExecuteParallel(session, 0, increment, increment, increment)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1, 1, 1}, affected)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPGXPoolExecuteParallelPanic(t *testing.T) {
+	mock, err := pgxmock.NewPool(pgxmock.QueryMatcherOption(pgxmock.QueryMatcherEqual))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer mock.Close()
+
+	mock.ExpectQuery("SELECT count(*) FROM orders").WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(3))
+
+	ob, err := octobe.New(postgres.OpenPGXPoolWithPool(mock))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	session, err := ob.Begin(context.Background())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	var counted int
+	count := func(builder postgres.Builder) (int, error) {
+		err := builder("SELECT count(*) FROM orders").QueryRow(&counted)
+		return counted, err
+	}
+	fail := func(postgres.Builder) (int, error) {
+		panic("handler failed")
+	}
+
+	// The panic reaches the caller, after the other handlers finished.
+	assert.PanicsWithValue(t, "handler failed", func() {
+		_, _ = postgres.ExecuteParallel(session, 0, count, fail)
+	})
+	assert.Equal(t, 3, counted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPGXPoolNamedArgs(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer mock.Close()
+
+	mock.ExpectQuery(`SELECT name FROM products WHERE id = \$1`).WithArgs(1).
+		WillReturnRows(pgxmock.NewRows([]string{"name"}).AddRow("chair"))
+
+	ob, err := octobe.New(postgres.OpenPGXPoolWithPool(mock))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	session, err := ob.Begin(context.Background())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	var name string
+	err = session.Builder()("SELECT name FROM products WHERE id = @id").Arguments(pgx.NamedArgs{"id": 1}).QueryRow(&name)
+	assert.NoError(t, err)
+	assert.Equal(t, "chair", name)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPGXPoolQueryExecMode(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer mock.Close()
+
+	// pgx takes the mode as the first argument of a statement.
+	mock.ExpectExec("UPDATE products").WithArgs(pgx.QueryExecModeSimpleProtocol, "chair").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec("UPDATE products").WithArgs(pgx.QueryExecModeExec, "table").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	hook := &dbtxHook{}
+	ob, err := octobe.New(postgres.OpenPGXPoolWithPool(mock), octobe.WithQueryHook(hook))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	session, err := ob.Begin(context.Background(), postgres.WithQueryExecMode(pgx.QueryExecModeSimpleProtocol))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	_, err = session.Builder()("UPDATE products SET name = $1").Arguments("chair").Exec()
+	assert.NoError(t, err)
+	_, err = session.Builder()("UPDATE products SET name = $1").Arguments(pgx.QueryExecModeExec, "table").Exec()
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	// The mode is not reported as an argument of the statement.
+	if assert.Len(t, hook.events, 2) {
+		assert.Equal(t, []any{"table"}, hook.events[1].Args)
+	}
+}
+
+func TestPGXPoolReadOnlyTx(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer mock.Close()
+	ctx := context.Background()
+
+	mock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	mock.ExpectQuery("SELECT count").WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(int64(3)))
+	mock.ExpectCommit()
+
+	ob, err := octobe.New(postgres.OpenPGXPoolWithPool(mock))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	// Transaction options given after the read-only option keep the transaction read-only.
+	err = ob.StartTransaction(ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+		var count int64
+		if err := session.Builder()("SELECT count(*) FROM orders").QueryRow(&count); err != nil {
+			return err
+		}
+		assert.Equal(t, int64(3), count)
+
+		_, err := session.Builder()("DELETE FROM orders").Exec()
+		assert.ErrorIs(t, err, postgres.ErrReadOnly)
+		return nil
+	}, postgres.WithReadOnlyTx(), postgres.WithPGXTxOptions(postgres.PGXTxOptions{IsoLevel: pgx.RepeatableRead}))
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -1,9 +1,15 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"iter"
+	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/ponrove/octobe"
 )
 
@@ -25,24 +31,147 @@ type SQLTxOptions sql.TxOptions
 // pgxConfig defines various configurations possible for the pgx driver.
 type pgxConfig struct {
 	txOptions *PGXTxOptions
+	priority  int
+	rewrite   Rewriter
+	execMode  *pgx.QueryExecMode
+	readOnly  bool
+	settings  []setting
 }
 
 // sqlConfig defines various configurations possible for the sql driver.
 type sqlConfig struct {
 	txOptions *SQLTxOptions
+	rewrite   Rewriter
+	readOnly  bool
+	settings  []setting
+}
+
+// Rewriter rewrites the query and arguments of a statement before it is executed, see WithRewriter.
+type Rewriter func(query string, args []any) (string, []any)
+
+// txState tracks whether the transaction of a session has ended, so committing or rolling it back again fails with a
+// sentinel of octobe instead of an error of the driver.
+type txState struct {
+	done      bool
+	committed bool
+}
+
+// check returns octobe.ErrAlreadyCommitted or octobe.ErrTxDone once the transaction has ended.
+func (s *txState) check() error {
+	switch {
+	case s.committed:
+		return octobe.ErrAlreadyCommitted
+	case s.done:
+		return octobe.ErrTxDone
+	}
+	return nil
+}
+
+// end marks the transaction as ended, it was committed if the commit did not fail.
+func (s *txState) end(committed bool) {
+	s.done = true
+	s.committed = committed
 }
 
 // WithTransaction enables the use of a transaction for the session.
 func WithPGXTxOptions(options PGXTxOptions) octobe.Option[pgxConfig] {
 	return func(c *pgxConfig) {
+		if c.readOnly {
+			options.AccessMode = pgx.ReadOnly
+		}
 		c.txOptions = &options
 	}
 }
 
+// WithPriority sets the priority of the session in the priority queue of a pool opened with WithPriorityQueue. When all
+// slots of the queue are taken, waiting sessions with a higher level are served first. The default level is zero, it
+// has no effect for drivers without a priority queue.
+func WithPriority(level int) octobe.Option[pgxConfig] {
+	return func(c *pgxConfig) {
+		c.priority = level
+	}
+}
+
 // WithTransaction enables the use of a transaction for the session.
 func WithSQLTxOptions(options SQLTxOptions) octobe.Option[sqlConfig] {
 	return func(c *sqlConfig) {
+		if c.readOnly {
+			options.ReadOnly = true
+		}
+		c.txOptions = &options
+	}
+}
+
+// ErrReadOnly is returned by Exec for the segments of a session started with WithReadOnlyTx or WithSQLReadOnlyTx.
+var ErrReadOnly = errors.New("cannot execute a statement with Exec in a read-only session")
+
+// WithReadOnlyTx runs the session in a read-only transaction, so code that should only read, like reporting, cannot
+// change data by mistake. The database rejects any write in the transaction, and segments of the session fail with
+// ErrReadOnly on Exec before the statement is sent. The session starts a transaction even without WithPGXTxOptions,
+// since setting default_transaction_read_only outside of one would outlive the session on the connection. Its other
+// transaction options still apply, whatever their access mode. Replicated drivers route the session to a replica.
+func WithReadOnlyTx() octobe.Option[pgxConfig] {
+	return func(c *pgxConfig) {
+		var options PGXTxOptions
+		if c.txOptions != nil {
+			options = *c.txOptions
+		}
+		options.AccessMode = pgx.ReadOnly
+		c.txOptions = &options
+		c.readOnly = true
+	}
+}
+
+// WithSQLReadOnlyTx runs the session of the database/sql driver in a read-only transaction, like WithReadOnlyTx.
+func WithSQLReadOnlyTx() octobe.Option[sqlConfig] {
+	return func(c *sqlConfig) {
+		var options SQLTxOptions
+		if c.txOptions != nil {
+			options = *c.txOptions
+		}
+		options.ReadOnly = true
 		c.txOptions = &options
+		c.readOnly = true
+	}
+}
+
+// WithQueryExecMode sets how the statements of the session are sent by pgx, overriding the DefaultQueryExecMode of the
+// connection config. pgx.QueryExecModeSimpleProtocol or pgx.QueryExecModeExec avoid the implicit prepared statements
+// that break behind poolers in transaction mode, like PgBouncer. A single segment can use another mode by passing it as
+// its first argument, see Segment.Arguments. It is an option of the pgx and pgxpool drivers.
+func WithQueryExecMode(mode pgx.QueryExecMode) octobe.Option[pgxConfig] {
+	return func(c *pgxConfig) {
+		c.execMode = &mode
+	}
+}
+
+// WithRewriter rewrites the query and arguments of every statement of the session with rewrite before it is executed,
+// for concerns like prefixing a schema, adding a soft delete filter or injecting planner hints in one place. The query
+// hooks and errors of the statements report the rewritten query. Multiple rewriters are applied in the order they are
+// given. It is an option of the pgx and pgxpool drivers, see WithSQLRewriter for the database/sql driver.
+func WithRewriter(rewrite Rewriter) octobe.Option[pgxConfig] {
+	return func(c *pgxConfig) {
+		c.rewrite = chainRewriters(c.rewrite, rewrite)
+	}
+}
+
+// WithSQLRewriter rewrites the statements of a session of the database/sql driver, see WithRewriter.
+func WithSQLRewriter(rewrite Rewriter) octobe.Option[sqlConfig] {
+	return func(c *sqlConfig) {
+		c.rewrite = chainRewriters(c.rewrite, rewrite)
+	}
+}
+
+// chainRewriters returns a rewriter applying first and then next, either may be nil.
+func chainRewriters(first, next Rewriter) Rewriter {
+	if first == nil {
+		return next
+	}
+	if next == nil {
+		return first
+	}
+	return func(query string, args []any) (string, []any) {
+		return next(first(query, args))
 	}
 }
 
@@ -54,18 +183,175 @@ func Execute[RESULT any](session octobe.BuilderSession[Builder], f Handler[RESUL
 	return f(session.Builder())
 }
 
+// ExecuteAll runs handlers in order within session and returns their results, stopping at the first handler that fails,
+// see octobe.ExecuteAll.
+func ExecuteAll[RESULT any](session octobe.BuilderSession[Builder], handlers ...Handler[RESULT]) ([]RESULT, error) {
+	return octobe.ExecuteAll(session, handlerFuncs(handlers)...)
+}
+
+// ExecuteEach runs all handlers in order within session even when some fail, see octobe.ExecuteEach.
+func ExecuteEach[RESULT any](session octobe.BuilderSession[Builder], handlers ...Handler[RESULT]) ([]RESULT, error) {
+	return octobe.ExecuteEach(session, handlerFuncs(handlers)...)
+}
+
+// handlerFuncs converts handlers to the functions taken by octobe.ExecuteAll and octobe.ExecuteEach.
+func handlerFuncs[RESULT any](handlers []Handler[RESULT]) []func(Builder) (RESULT, error) {
+	funcs := make([]func(Builder) (RESULT, error), len(handlers))
+	for i, handler := range handlers {
+		funcs[i] = handler
+	}
+	return funcs
+}
+
 // PGXSegment is an interface that represents a specific query that can be run only once. It keeps track of the query,
 // arguments, and execution state.
 type Segment interface {
+	// Arguments binds args to the placeholders of the query, like $1. For the pgx and pgxpool drivers a
+	// pgx.QueryRewriter as the first argument, like pgx.NamedArgs for queries with parameters like @name, rewrites the
+	// query and the remaining arguments as pgx does, before the query is executed or reported to the query hooks. An
+	// error rewriting the query is returned when the segment is executed. A pgx.QueryExecMode before the arguments, or
+	// before the rewriter, sets how pgx sends the statement, see WithQueryExecMode. For the database/sql driver,
+	// arguments created with sql.Named are bound to the named parameters of the query like with NamedArguments.
 	Arguments(args ...any) Segment
+	// ArgumentsFromStruct binds the fields of struct v, mapped to columns by their db tags, to the query. Named
+	// parameters like :name are bound to the field of the same name, a query without named parameters gets the values
+	// of all fields as arguments in declaration order. An error binding v is returned when the segment is executed.
+	ArgumentsFromStruct(v any) Segment
+	// NamedArguments binds args to the named parameters of the query, like :name or @name, which are rewritten to
+	// positional parameters. A named parameter without a value in args is returned as an error when the segment is
+	// executed.
+	NamedArguments(args map[string]any) Segment
+	// WithMaxRows limits the number of rows Query may read, iteration stops with a *octobe.MaxRowsError once the
+	// query returns more than n rows. A value of zero or less disables the limit.
+	WithMaxRows(n int) Segment
+	// Timeout limits the execution of the statement to d, by deriving a context with that deadline from the context of
+	// the session for just this segment. For Query the deadline covers reading the rows in the callback. A value of zero
+	// or less disables the timeout.
+	Timeout(d time.Duration) Segment
+	// Clone returns a copy of the segment with the same query, arguments and settings that has not been executed, so a
+	// statement can be run again on purpose, as in a polling loop, without tripping octobe.ErrAlreadyUsed. The segment
+	// may be cloned before or after it is executed.
+	Clone() Segment
 	Exec() (ExecResult, error)
 	QueryRow(dest ...any) error
 	Query(cb func(Rows) error) error
+	// Rows executes the query like Query and returns an iterator over its rows for a range loop. Every row is yielded
+	// with a nil error, an error executing the query or reading the rows is yielded last with a nil row. The rows are
+	// closed when the loop ends, also when it is left early with break or return. The query is executed once the loop
+	// starts, the iterator cannot be used again.
+	Rows() iter.Seq2[Row, error]
+	// QueryRowMap returns the first row of the result as a map keyed by column name, it is meant for tooling where the
+	// columns are not known at compile time. It returns the no rows error of the driver if the result is empty.
+	QueryRowMap() (map[string]any, error)
+	// QueryMaps returns all rows of the result as maps keyed by column name.
+	QueryMaps() ([]map[string]any, error)
+	// QueryRowStruct reads the first row of the result into the struct dest points to, mapping columns to fields by
+	// their db tags. It returns the no rows error of the driver if the result is empty, and an error if a column does
+	// not map to a field.
+	QueryRowStruct(dest any) error
+	// QueryStructs reads all rows of the result into the slice of structs or struct pointers dest points to, mapping
+	// columns to fields by their db tags. The contents of the slice are replaced.
+	QueryStructs(dest any) error
+}
+
+// rewriteArgs returns query and args rewritten by a pgx.QueryRewriter passed as the first argument, like pgx.NamedArgs,
+// or query and args as they are. A pgx.QueryExecMode passed before the arguments is removed from them and returned. The
+// pgxpool driver passes no conn, which pgx.NamedArgs does not need.
+func rewriteArgs(ctx context.Context, conn *pgx.Conn, query string, args []any) (string, []any, *pgx.QueryExecMode, error) {
+	var mode *pgx.QueryExecMode
+	var rewriter pgx.QueryRewriter
+options:
+	for len(args) > 0 {
+		switch arg := args[0].(type) {
+		case pgx.QueryExecMode:
+			mode = &arg
+		case pgx.QueryRewriter:
+			rewriter = arg
+		default:
+			break options
+		}
+		args = args[1:]
+	}
+	if rewriter == nil {
+		return query, args, mode, nil
+	}
+	query, args, err := rewriter.RewriteQuery(ctx, conn, query, args)
+	return query, args, mode, err
+}
+
+// execArgs returns args led by mode if it is set, the arguments pgx takes to send a statement in that mode.
+func execArgs(mode *pgx.QueryExecMode, args []any) []any {
+	if mode == nil {
+		return args
+	}
+	return append([]any{*mode}, args...)
+}
+
+// withTimeout derives a context with a timeout of d from ctx, or returns ctx as is if d is zero or less.
+func withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, d)
 }
 
 // ExecResult is a struct that holds the result of an execution, specifically the number of rows affected by the query.
+// It is the same for all postgres drivers, only Tag depends on the driver.
 type ExecResult struct {
 	RowsAffected int64
+	// Command is the verb of the statement in upper case, like INSERT, UPDATE or DELETE. The pgx and pgxpool drivers take
+	// it from the command tag of the database, the database/sql driver from the first keyword of the query, and leave it
+	// empty for a query starting with WITH, whose verb follows the common table expressions.
+	Command string
+	// Tag is the command tag the database completed the statement with, like "INSERT 0 1". It is only set by the pgx and
+	// pgxpool drivers, the database/sql driver does not expose it.
+	Tag pgconn.CommandTag
+}
+
+// Insert reports whether the statement was an INSERT.
+func (r ExecResult) Insert() bool {
+	return r.Command == "INSERT"
+}
+
+// Update reports whether the statement was an UPDATE.
+func (r ExecResult) Update() bool {
+	return r.Command == "UPDATE"
+}
+
+// Delete reports whether the statement was a DELETE.
+func (r ExecResult) Delete() bool {
+	return r.Command == "DELETE"
+}
+
+// tagResult returns the result of a statement the database completed with tag.
+func tagResult(tag pgconn.CommandTag) ExecResult {
+	command, _, _ := strings.Cut(tag.String(), " ")
+	return ExecResult{RowsAffected: tag.RowsAffected(), Command: command, Tag: tag}
+}
+
+// queryCommand returns the first keyword of query in upper case, skipping comments before it, or an empty string if
+// the query starts with WITH.
+func queryCommand(query string) string {
+	for {
+		query = strings.TrimSpace(query)
+		switch {
+		case strings.HasPrefix(query, "--"):
+			_, query, _ = strings.Cut(query, "\n")
+		case strings.HasPrefix(query, "/*"):
+			_, query, _ = strings.Cut(query, "*/")
+		default:
+			end := strings.IndexFunc(query, func(r rune) bool {
+				return !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z')
+			})
+			if end >= 0 {
+				query = query[:end]
+			}
+			if command := strings.ToUpper(query); command != "WITH" {
+				return command
+			}
+			return ""
+		}
+	}
 }
 
 // Rows is an interface that represents a set of rows returned by a query. It provides methods to iterate over the rows
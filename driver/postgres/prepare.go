@@ -0,0 +1,120 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/ponrove/octobe"
+)
+
+// ErrPrepareUnsupported is returned by Prepare for a session of the pgxpool driver without a transaction, whose queries
+// do not run on a single connection a statement could be prepared on.
+var ErrPrepareUnsupported = errors.New("prepare requires a session of the pgxpool driver to run in a transaction")
+
+// preparedID numbers the prepared statements without a name.
+var preparedID atomic.Uint64
+
+// Statement is a query prepared on the connection of a session, see Prepare. Its segments execute the prepared
+// statement with their own arguments, so the database parses and plans the query only once.
+type Statement struct {
+	name  string
+	query string
+	build func() Segment
+	close func() error
+}
+
+// Prepare prepares query on the connection behind session, as name for the pgx drivers, and returns the statement to
+// build segments executing it:
+//
+//	stmt, err := postgres.Prepare(session, "reserve_stock", "UPDATE stock SET reserved = reserved + $2 WHERE id = $1")
+//	defer stmt.Close()
+//	for _, item := range items {
+//		_, err = stmt.Segment().Arguments(item.ID, item.Quantity).Exec()
+//	}
+//
+// An empty name is replaced by a generated one. The query must use positional parameters like $1. For the pgx driver
+// the statement lives on the connection until it is closed, the database/sql driver prepares it on the transaction of
+// session or else on the database, which prepares it again on other connections as needed. Sessions of the pgxpool
+// driver must run in a transaction, the statement is prepared on its connection and must be closed before the
+// transaction ends. Segments of the statement report the query to the query hooks, not the name.
+func Prepare(session octobe.BuilderSession[Builder], name, query string) (*Statement, error) {
+	if name == "" {
+		name = "octobe_prepared_" + strconv.FormatUint(preparedID.Add(1), 10)
+	}
+
+	var current any = session
+	for {
+		switch s := current.(type) {
+		case *pgxSession:
+			// A transaction runs on the connection of the driver, which holds the statement for both.
+			if _, err := s.d.conn.Prepare(s.ctx, name, query); err != nil {
+				return nil, wrapQueryError(s.d.Describe().Name, query, nil, err)
+			}
+			return &Statement{name: name, query: query, build: func() Segment {
+				segment := s.build(query).(*pgxSegment)
+				segment.prepared = name
+				return segment
+			}, close: func() error {
+				return s.d.conn.Deallocate(context.WithoutCancel(s.ctx), name)
+			}}, nil
+		case *pgxpoolSession:
+			if s.tx == nil {
+				return nil, ErrPrepareUnsupported
+			}
+			if _, err := s.tx.Prepare(s.ctx, name, query); err != nil {
+				return nil, wrapQueryError(s.d.Describe().Name, query, nil, err)
+			}
+			return &Statement{name: name, query: query, build: func() Segment {
+				segment := s.build(query).(*pgxpoolSegment)
+				segment.prepared = name
+				return segment
+			}, close: func() error {
+				return s.tx.Conn().Deallocate(context.WithoutCancel(s.ctx), name)
+			}}, nil
+		case *sqlSession:
+			var stmt *sql.Stmt
+			var err error
+			if s.tx != nil {
+				stmt, err = s.tx.PrepareContext(s.ctx, query)
+			} else {
+				stmt, err = s.d.sqlDB.PrepareContext(s.ctx, query)
+			}
+			if err != nil {
+				return nil, wrapQueryError(s.d.Describe().Name, query, nil, err)
+			}
+			return &Statement{name: name, query: query, build: func() Segment {
+				segment := s.build(query).(*sqlSegment)
+				segment.stmt = stmt
+				return segment
+			}, close: stmt.Close}, nil
+		case sessionUnwrapper:
+			current = s.Unwrap()
+		default:
+			return nil, ErrPrepareUnsupported
+		}
+	}
+}
+
+// Name returns the name the statement is prepared as.
+func (s *Statement) Name() string {
+	return s.name
+}
+
+// Query returns the query of the statement.
+func (s *Statement) Query() string {
+	return s.query
+}
+
+// Segment returns a new segment executing the statement, which is bound to its arguments with Arguments like any other
+// segment and can be executed once. Named arguments are not supported.
+func (s *Statement) Segment() Segment {
+	return s.build()
+}
+
+// Close deallocates the statement, its segments cannot be executed afterwards.
+func (s *Statement) Close() error {
+	return s.close()
+}
@@ -0,0 +1,97 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/driver/postgres"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreparePGX(t *testing.T) {
+	ctx := context.Background()
+	mock, err := pgxmock.NewConn()
+	require.NoError(t, err)
+	defer mock.Close(ctx)
+
+	const query = "UPDATE stock SET reserved = reserved + $2 WHERE id = $1"
+	mock.ExpectBegin()
+	mock.ExpectPrepare("reserve_stock", "UPDATE stock")
+	mock.ExpectExec("reserve_stock").WithArgs(int64(1), 2).WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec("reserve_stock").WithArgs(int64(2), 5).WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectDeallocate("reserve_stock")
+	mock.ExpectCommit()
+
+	hook := &dbtxHook{}
+	ob, err := octobe.New(postgres.OpenPGXWithConn(mock), octobe.WithQueryHook(hook))
+	require.NoError(t, err)
+
+	err = ob.StartTransaction(ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+		stmt, err := postgres.Prepare(session, "reserve_stock", query)
+		require.NoError(t, err)
+		require.Equal(t, "reserve_stock", stmt.Name())
+		require.Equal(t, query, stmt.Query())
+
+		for _, item := range []struct {
+			id       int64
+			quantity int
+		}{{1, 2}, {2, 5}} {
+			result, err := stmt.Segment().Arguments(item.id, item.quantity).Exec()
+			require.NoError(t, err)
+			require.Equal(t, int64(1), result.RowsAffected)
+		}
+		return stmt.Close()
+	}, postgres.WithPGXTxOptions(postgres.PGXTxOptions{}))
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	require.Len(t, hook.events, 2)
+	require.Equal(t, query, hook.events[0].Query)
+}
+
+func TestPrepareSQL(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	prepared := mock.ExpectPrepare("SELECT name FROM products WHERE id")
+	prepared.ExpectQuery().WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("chair"))
+	prepared.ExpectQuery().WithArgs(int64(2)).WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("table"))
+	prepared.WillBeClosed()
+
+	ob, err := octobe.New(postgres.OpenWithConn(db))
+	require.NoError(t, err)
+	session, err := ob.Begin(context.Background())
+	require.NoError(t, err)
+
+	stmt, err := postgres.Prepare(session, "", "SELECT name FROM products WHERE id = $1")
+	require.NoError(t, err)
+	require.NotEmpty(t, stmt.Name())
+
+	var names []string
+	for _, id := range []int64{1, 2} {
+		var name string
+		require.NoError(t, stmt.Segment().Arguments(id).QueryRow(&name))
+		names = append(names, name)
+	}
+	require.Equal(t, []string{"chair", "table"}, names)
+	require.NoError(t, stmt.Close())
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPreparePGXPoolWithoutTx(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	ob, err := octobe.New(postgres.OpenPGXPoolWithPool(mock))
+	require.NoError(t, err)
+	session, err := ob.Begin(context.Background())
+	require.NoError(t, err)
+
+	_, err = postgres.Prepare(session, "reserve_stock", "SELECT 1")
+	require.ErrorIs(t, err, postgres.ErrPrepareUnsupported)
+}
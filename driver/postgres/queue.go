@@ -0,0 +1,112 @@
+package postgres
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+)
+
+// priorityQueue hands out a fixed number of slots, serving waiters with a higher priority first and waiters with equal
+// priority in the order they arrived.
+type priorityQueue struct {
+	mu      sync.Mutex
+	free    int
+	seq     uint64
+	waiters waiters
+}
+
+// waiter is a caller waiting for a slot in the priority queue.
+type waiter struct {
+	priority int
+	seq      uint64
+	ready    chan struct{}
+	index    int // Position in the heap, -1 once the waiter has been handed a slot
+}
+
+// newPriorityQueue creates a priority queue with size slots.
+func newPriorityQueue(size int) *priorityQueue {
+	return &priorityQueue{free: size}
+}
+
+// acquire blocks until a slot is available for the priority or ctx is done.
+func (q *priorityQueue) acquire(ctx context.Context, priority int) error {
+	q.mu.Lock()
+	if q.free > 0 && len(q.waiters) == 0 {
+		q.free--
+		q.mu.Unlock()
+		return nil
+	}
+
+	w := &waiter{priority: priority, seq: q.seq, ready: make(chan struct{})}
+	q.seq++
+	heap.Push(&q.waiters, w)
+	q.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		q.mu.Lock()
+		if w.index >= 0 {
+			heap.Remove(&q.waiters, w.index)
+			q.mu.Unlock()
+			return ctx.Err()
+		}
+		q.mu.Unlock()
+		// The slot was handed over while giving up, pass it on.
+		q.release()
+		return ctx.Err()
+	}
+}
+
+// release returns a slot, handing it to the waiter with the highest priority if there is one.
+func (q *priorityQueue) release() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.waiters) == 0 {
+		q.free++
+		return
+	}
+	w := heap.Pop(&q.waiters).(*waiter)
+	close(w.ready)
+}
+
+// waiting returns the number of callers waiting for a slot.
+func (q *priorityQueue) waiting() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.waiters)
+}
+
+// waiters implements heap.Interface, ordering waiters by priority and arrival.
+type waiters []*waiter
+
+func (w waiters) Len() int { return len(w) }
+
+func (w waiters) Less(i, j int) bool {
+	if w[i].priority != w[j].priority {
+		return w[i].priority > w[j].priority
+	}
+	return w[i].seq < w[j].seq
+}
+
+func (w waiters) Swap(i, j int) {
+	w[i], w[j] = w[j], w[i]
+	w[i].index = i
+	w[j].index = j
+}
+
+func (w *waiters) Push(x any) {
+	item := x.(*waiter)
+	item.index = len(*w)
+	*w = append(*w, item)
+}
+
+func (w *waiters) Pop() any {
+	old := *w
+	item := old[len(old)-1]
+	old[len(old)-1] = nil
+	item.index = -1
+	*w = old[:len(old)-1]
+	return item
+}
@@ -0,0 +1,108 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/ponrove/octobe"
+)
+
+// errConnClosed is returned for a session or statement of a pgx driver that reconnects once it has been closed, so
+// closing it does not lead to connecting again.
+var errConnClosed = errors.New("connection is closed")
+
+// reconnect holds the configuration the pgx driver connects again with, see WithReconnect.
+type reconnect struct {
+	config      *pgx.ConnConfig
+	maxAttempts int
+	backoff     octobe.Backoff
+	clock       octobe.Clock
+}
+
+// WithReconnect makes the pgx driver replace its connection once it broke, after a network failure or a restart of the
+// server, instead of failing every statement from then on. Begin, Ping and statements outside a transaction check the
+// connection first, and if it was closed connect again with the configuration of OpenPGX or OpenPGXWithOptions, at
+// most maxAttempts times with the wait between attempts given by backoff, which may be nil to retry immediately, and
+// measured with the clock of WithReconnectClock:
+//
+//	postgres.OpenPGX(ctx, dsn, postgres.WithReconnect(5, octobe.ExponentialBackoff(100*time.Millisecond, 5*time.Second)))
+//
+// The statement during which the connection breaks still fails, it may have reached the server and is not run again.
+// Transactions are not resumed on the new connection, WithTxRetry runs them again from the start. The new connection
+// runs the functions of WithAfterConnect and WithTypes and OnOpen, then OnReconnect of WithConnHooks. Statements
+// prepared on the old connection are gone, WithStatementCache prepares them again while statements of Prepare fail. A
+// connection passed to OpenPGXWithConn has no configuration to connect again with, the option has no effect for it.
+func WithReconnect(maxAttempts int, backoff octobe.Backoff) PGXOption {
+	return func(cfg *pgxOptions) {
+		cfg.reconnectAttempts = max(maxAttempts, 1)
+		cfg.reconnectBackoff = backoff
+	}
+}
+
+// WithReconnectClock sets the clock the waits between the attempts of WithReconnect are measured with,
+// octobe.SystemClock by default.
+func WithReconnectClock(clock octobe.Clock) PGXOption {
+	return func(cfg *pgxOptions) {
+		cfg.reconnectClock = clock
+	}
+}
+
+// connection returns the connection of the driver, connecting again first if it was closed and the driver reconnects.
+func (d *pgxConn) connection(ctx context.Context) (PGXConn, error) {
+	if d.reconnect == nil {
+		return d.conn, nil
+	}
+	if d.closed {
+		return nil, errConnClosed
+	}
+	if !d.conn.PgConn().IsClosed() {
+		return d.conn, nil
+	}
+
+	var err error
+	for attempt := 1; attempt <= d.reconnect.maxAttempts; attempt++ {
+		if attempt > 1 {
+			if err := d.reconnect.wait(ctx, attempt-1); err != nil {
+				return nil, err
+			}
+		}
+
+		var conn *pgx.Conn
+		if conn, err = pgx.ConnectConfig(ctx, d.reconnect.config); err != nil {
+			continue
+		}
+		if err = d.hooks.open(ctx, conn); err != nil {
+			continue
+		}
+
+		d.conn = conn
+		if d.statements != nil {
+			d.statements.reset()
+		}
+		if d.hooks.OnReconnect != nil {
+			d.hooks.OnReconnect(ctx, conn)
+		}
+		return conn, nil
+	}
+	return nil, fmt.Errorf("reconnect after %d attempts: %w", d.reconnect.maxAttempts, err)
+}
+
+// wait blocks for the backoff before the given retry, returning early with the error of ctx if it is done first.
+func (r *reconnect) wait(ctx context.Context, retry int) error {
+	if r.backoff == nil {
+		return ctx.Err()
+	}
+	delay := r.backoff(retry)
+	if delay <= 0 {
+		return ctx.Err()
+	}
+
+	select {
+	case <-r.clock.After(delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,188 @@
+package postgres_test
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgproto3"
+	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/driver/postgres"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeServer speaks just enough of the PostgreSQL protocol to accept connections and answer simple queries, so tests
+// can break connections like a network failure would.
+type fakeServer struct {
+	listener net.Listener
+	mu       sync.Mutex
+	conns    []net.Conn
+	started  int
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &fakeServer{listener: listener}
+	t.Cleanup(s.stop)
+	go s.serve()
+	return s
+}
+
+func (s *fakeServer) dsn() string {
+	return "postgres://octobe@" + s.listener.Addr().String() + "/octobe?sslmode=disable&connect_timeout=1"
+}
+
+func (s *fakeServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns = append(s.conns, conn)
+		s.mu.Unlock()
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeServer) handle(conn net.Conn) {
+	defer conn.Close()
+	backend := pgproto3.NewBackend(conn, conn)
+	// Cancel requests of pgx arrive on connections of their own and are ignored.
+	msg, err := backend.ReceiveStartupMessage()
+	if _, ok := msg.(*pgproto3.StartupMessage); !ok || err != nil {
+		return
+	}
+	s.mu.Lock()
+	s.started++
+	s.mu.Unlock()
+
+	backend.Send(&pgproto3.AuthenticationOk{})
+	backend.Send(&pgproto3.BackendKeyData{ProcessID: 1, SecretKey: 1})
+	backend.Send(&pgproto3.ReadyForQuery{TxStatus: 'I'})
+	if err := backend.Flush(); err != nil {
+		return
+	}
+	for {
+		msg, err := backend.Receive()
+		if err != nil {
+			return
+		}
+		switch msg.(type) {
+		case *pgproto3.Query:
+			backend.Send(&pgproto3.CommandComplete{CommandTag: []byte("SELECT 1")})
+			backend.Send(&pgproto3.ReadyForQuery{TxStatus: 'I'})
+			if err := backend.Flush(); err != nil {
+				return
+			}
+		case *pgproto3.Terminate:
+			return
+		}
+	}
+}
+
+// drop closes the open connections, like a restart of the server.
+func (s *fakeServer) drop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+	s.conns = nil
+}
+
+// connections returns the number of connections that were started.
+func (s *fakeServer) connections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.started
+}
+
+func (s *fakeServer) stop() {
+	s.listener.Close()
+	s.drop()
+}
+
+// clock records the delays waited for and returns right away.
+type clock struct {
+	waits []time.Duration
+}
+
+func (c *clock) Now() time.Time { return time.Time{} }
+
+func (c *clock) After(d time.Duration) <-chan time.Time {
+	c.waits = append(c.waits, d)
+	ch := make(chan time.Time, 1)
+	ch <- time.Time{}
+	return ch
+}
+
+func TestWithReconnect(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeServer(t)
+
+	var opened, reconnected int
+	ob, err := octobe.New(postgres.OpenPGX(ctx, server.dsn(),
+		postgres.WithReconnect(3, octobe.ConstantBackoff(0)),
+		postgres.WithConnHooks(postgres.ConnHooks{
+			OnOpen: func(context.Context, *pgx.Conn) error {
+				opened++
+				return nil
+			},
+			OnReconnect: func(context.Context, *pgx.Conn) {
+				reconnected++
+			},
+		}),
+	))
+	require.NoError(t, err)
+	defer ob.Close(ctx)
+
+	session, err := ob.Begin(ctx)
+	require.NoError(t, err)
+	_, err = session.Builder()("SELECT 1").Exec()
+	require.NoError(t, err)
+
+	// The statement running when the connection breaks fails, the next one runs on a new connection.
+	server.drop()
+	_, err = session.Builder()("SELECT 1").Exec()
+	require.Error(t, err)
+	_, err = session.Builder()("SELECT 1").Exec()
+	require.NoError(t, err)
+	require.NoError(t, ob.Ping(ctx))
+
+	require.Equal(t, 2, server.connections())
+	require.Equal(t, 2, opened)
+	require.Equal(t, 1, reconnected)
+}
+
+func TestWithReconnectFails(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeServer(t)
+
+	clk := &clock{}
+	ob, err := octobe.New(postgres.OpenPGX(ctx, server.dsn(),
+		postgres.WithReconnect(3, octobe.ConstantBackoff(time.Hour)),
+		postgres.WithReconnectClock(clk),
+	))
+	require.NoError(t, err)
+
+	// Without a server to connect to, the attempts are used up.
+	server.stop()
+	session, err := ob.Begin(ctx)
+	require.NoError(t, err)
+	_, err = session.Builder()("SELECT 1").Exec()
+	require.Error(t, err)
+	_, err = ob.Begin(ctx)
+	require.ErrorContains(t, err, "reconnect after 3 attempts")
+	// The waits between the attempts are measured with the clock, which returns right away.
+	require.Equal(t, []time.Duration{time.Hour, time.Hour}, clk.waits)
+
+	// A closed driver does not connect again.
+	require.NoError(t, ob.Close(ctx))
+	_, err = ob.Begin(ctx)
+	require.Error(t, err)
+}
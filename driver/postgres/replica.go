@@ -0,0 +1,82 @@
+package postgres
+
+import (
+	"github.com/jackc/pgx/v5"
+	"github.com/ponrove/octobe"
+)
+
+// Ensure the drivers report read-only sessions and their load to octobe.OpenReplicated, and which sessions begin a
+// transaction to Octobe.
+var (
+	_ octobe.TransactionReporter[pgxConfig] = &pgxConn{}
+	_ octobe.TransactionReporter[pgxConfig] = &pgxpoolConn{}
+	_ octobe.TransactionReporter[sqlConfig] = &sqlConn{}
+	_ octobe.ReadOnlyReporter[pgxConfig]    = &pgxConn{}
+	_ octobe.ReadOnlyReporter[pgxConfig]    = &pgxpoolConn{}
+	_ octobe.ReadOnlyReporter[sqlConfig]    = &sqlConn{}
+	_ octobe.LoadReporter                   = &pgxpoolConn{}
+	_ octobe.LoadReporter                   = &sqlConn{}
+)
+
+// BeginsTransaction reports whether opts start a transaction.
+func (d *pgxConn) BeginsTransaction(opts ...octobe.Option[pgxConfig]) bool {
+	return newPGXConfig(opts).txOptions != nil
+}
+
+// BeginsTransaction reports whether opts start a transaction.
+func (d *pgxpoolConn) BeginsTransaction(opts ...octobe.Option[pgxConfig]) bool {
+	return newPGXConfig(opts).txOptions != nil
+}
+
+// BeginsTransaction reports whether opts start a transaction.
+func (d *sqlConn) BeginsTransaction(opts ...octobe.Option[sqlConfig]) bool {
+	var cfg sqlConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg.txOptions != nil
+}
+
+// ReadOnly reports whether opts start a read-only transaction.
+func (d *pgxConn) ReadOnly(opts ...octobe.Option[pgxConfig]) bool {
+	return pgxReadOnly(opts)
+}
+
+// ReadOnly reports whether opts start a read-only transaction.
+func (d *pgxpoolConn) ReadOnly(opts ...octobe.Option[pgxConfig]) bool {
+	return pgxReadOnly(opts)
+}
+
+// ReadOnly reports whether opts start a read-only transaction.
+func (d *sqlConn) ReadOnly(opts ...octobe.Option[sqlConfig]) bool {
+	var cfg sqlConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg.txOptions != nil && cfg.txOptions.ReadOnly
+}
+
+// Load returns the number of connections of the pool that are in use.
+func (d *pgxpoolConn) Load() int {
+	return int(d.pool.Stat().AcquiredConns())
+}
+
+// Load returns the number of connections of the database that are in use.
+func (d *sqlConn) Load() int {
+	return d.sqlDB.Stats().InUse
+}
+
+// pgxReadOnly reports whether the options of the pgx drivers start a read-only transaction.
+func pgxReadOnly(opts []octobe.Option[pgxConfig]) bool {
+	cfg := newPGXConfig(opts)
+	return cfg.txOptions != nil && cfg.txOptions.AccessMode == pgx.ReadOnly
+}
+
+// newPGXConfig returns the session configuration of the pgx drivers with opts applied.
+func newPGXConfig(opts []octobe.Option[pgxConfig]) pgxConfig {
+	var cfg pgxConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
@@ -0,0 +1,89 @@
+package postgres
+
+import (
+	"database/sql/driver"
+	"errors"
+	"syscall"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/ponrove/octobe"
+)
+
+const (
+	// SQLStateSerializationFailure is reported when a transaction cannot be serialized with concurrent transactions.
+	SQLStateSerializationFailure = "40001"
+	// SQLStateDeadlockDetected is reported when a transaction was aborted to resolve a deadlock.
+	SQLStateDeadlockDetected = "40P01"
+)
+
+// IsRetryable reports whether err is a transient failure after which the whole transaction can be run again: a
+// serialization failure, a deadlock or a connection that was reset or failed before the query was sent. A failed commit
+// is classified by IsRetryableCommit instead.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == SQLStateSerializationFailure || pgErr.Code == SQLStateDeadlockDetected
+	}
+
+	return pgconn.SafeToRetry(err) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, driver.ErrBadConn)
+}
+
+// IsRetryableCommit reports whether err, returned by a commit, certainly left the transaction unapplied so it can be run
+// again: a serialization failure or deadlock reported by the server, or a failure before COMMIT was sent. A connection
+// that was reset or broke after COMMIT was sent is not retryable, the server may have committed the transaction.
+func IsRetryableCommit(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == SQLStateSerializationFailure || pgErr.Code == SQLStateDeadlockDetected
+	}
+
+	return pgconn.SafeToRetry(err)
+}
+
+// Ensure the drivers classify retryable errors.
+var (
+	_ octobe.RetryClassifier       = &pgxConn{}
+	_ octobe.RetryClassifier       = &pgxpoolConn{}
+	_ octobe.RetryClassifier       = &sqlConn{}
+	_ octobe.CommitRetryClassifier = &pgxConn{}
+	_ octobe.CommitRetryClassifier = &pgxpoolConn{}
+	_ octobe.CommitRetryClassifier = &sqlConn{}
+)
+
+// Retryable reports whether err is retryable, see IsRetryable.
+func (d *pgxConn) Retryable(err error) bool {
+	return IsRetryable(err)
+}
+
+// Retryable reports whether err is retryable, see IsRetryable.
+func (d *pgxpoolConn) Retryable(err error) bool {
+	return IsRetryable(err)
+}
+
+// Retryable reports whether err is retryable, see IsRetryable.
+func (d *sqlConn) Retryable(err error) bool {
+	return IsRetryable(err)
+}
+
+// RetryableCommit reports whether the failed commit err is retryable, see IsRetryableCommit.
+func (d *pgxConn) RetryableCommit(err error) bool {
+	return IsRetryableCommit(err)
+}
+
+// RetryableCommit reports whether the failed commit err is retryable, see IsRetryableCommit.
+func (d *pgxpoolConn) RetryableCommit(err error) bool {
+	return IsRetryableCommit(err)
+}
+
+// RetryableCommit reports whether the failed commit err is retryable, see IsRetryableCommit.
+func (d *sqlConn) RetryableCommit(err error) bool {
+	return IsRetryableCommit(err)
+}
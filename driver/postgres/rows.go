@@ -0,0 +1,239 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/internal/dbtag"
+)
+
+// maxRowsRows wraps Rows and stops iteration as soon as more rows than limit have been read.
+type maxRowsRows struct {
+	Rows
+	limit int
+	count int
+	err   error
+}
+
+// limitRows wraps rows with a row limit if limit is positive, otherwise rows is returned as is.
+func limitRows(rows Rows, limit int) Rows {
+	if limit <= 0 {
+		return rows
+	}
+	return &maxRowsRows{Rows: rows, limit: limit}
+}
+
+// limitErr returns the error recorded by a row limit wrapper, if rows is one.
+func limitErr(rows Rows) error {
+	if r, ok := rows.(*maxRowsRows); ok {
+		return r.err
+	}
+	return nil
+}
+
+// Next advances to the next row, returning false once the limit has been exceeded.
+func (r *maxRowsRows) Next() bool {
+	if r.err != nil || !r.Rows.Next() {
+		return false
+	}
+	r.count++
+	if r.count > r.limit {
+		r.err = &octobe.MaxRowsError{Limit: r.limit}
+		return false
+	}
+	return true
+}
+
+// Err returns the limit error if the limit was exceeded, otherwise the error of the underlying rows.
+func (r *maxRowsRows) Err() error {
+	if r.err != nil {
+		return r.err
+	}
+	return r.Rows.Err()
+}
+
+// unwrapRows returns the driver rows behind any wrapper added by the segment.
+func unwrapRows(rows Rows) Rows {
+	if r, ok := rows.(*maxRowsRows); ok {
+		return r.Rows
+	}
+	return rows
+}
+
+// rowMap reads the current row into a map keyed by column name, using the column metadata of the driver rows.
+func rowMap(rows Rows) (map[string]any, error) {
+	switch r := unwrapRows(rows).(type) {
+	case pgx.Rows:
+		values, err := r.Values()
+		if err != nil {
+			return nil, err
+		}
+		fields := r.FieldDescriptions()
+		row := make(map[string]any, len(fields))
+		for i, field := range fields {
+			row[field.Name] = values[i]
+		}
+		return row, nil
+	case *sql.Rows:
+		columns, err := r.Columns()
+		if err != nil {
+			return nil, err
+		}
+		values := make([]any, len(columns))
+		dest := make([]any, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err = r.Scan(dest...); err != nil {
+			return nil, err
+		}
+		row := make(map[string]any, len(columns))
+		for i, column := range columns {
+			row[column] = values[i]
+		}
+		return row, nil
+	default:
+		return nil, fmt.Errorf("rows of type %T do not expose column metadata", r)
+	}
+}
+
+// RowMap reads the current row of a result into a map keyed by column name, like QueryRowMap, for use in the callback
+// of Query or with the rows of Segment.Rows. The values are typed by the column metadata of the driver, row must be
+// rows of a segment.
+func RowMap(row Row) (map[string]any, error) {
+	rows, ok := row.(Rows)
+	if !ok {
+		return nil, fmt.Errorf("row of type %T does not expose column metadata", row)
+	}
+	return rowMap(rows)
+}
+
+// CollectMaps reads all remaining rows into maps keyed by column name, like QueryMaps, for use in the callback of Query.
+func CollectMaps(rows Rows) ([]map[string]any, error) {
+	return collectMaps(rows)
+}
+
+// Columns returns the column names of a result in order, for use in the callback of Query or with the rows of
+// Segment.Rows.
+func Columns(row Row) ([]string, error) {
+	rows, ok := row.(Rows)
+	if !ok {
+		return nil, fmt.Errorf("row of type %T does not expose column metadata", row)
+	}
+	return rowColumns(rows)
+}
+
+// collectMaps reads all remaining rows into maps keyed by column name.
+func collectMaps(rows Rows) ([]map[string]any, error) {
+	var result []map[string]any
+	for rows.Next() {
+		row, err := rowMap(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+// firstMap reads the first row into a map keyed by column name, returning noRows if the result set is empty.
+func firstMap(rows Rows, noRows error) (map[string]any, error) {
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, noRows
+	}
+	return rowMap(rows)
+}
+
+// rowColumns returns the column names of the driver rows.
+func rowColumns(rows Rows) ([]string, error) {
+	switch r := unwrapRows(rows).(type) {
+	case pgx.Rows:
+		fields := r.FieldDescriptions()
+		columns := make([]string, len(fields))
+		for i, field := range fields {
+			columns[i] = field.Name
+		}
+		return columns, nil
+	case *sql.Rows:
+		return r.Columns()
+	default:
+		return nil, fmt.Errorf("rows of type %T do not expose column metadata", r)
+	}
+}
+
+// scanStruct reads the current row into the fields of the struct v that the columns map to by their db tags.
+func scanStruct(rows Rows, columns []string, v reflect.Value) error {
+	targets, err := dbtag.Targets(v, columns)
+	if err != nil {
+		return err
+	}
+	return rows.Scan(targets...)
+}
+
+// firstStruct reads the first row into the struct dest points to, returning noRows if the result set is empty.
+func firstStruct(rows Rows, dest any, noRows error) error {
+	v, err := dbtag.StructOf(dest)
+	if err != nil {
+		return err
+	}
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return err
+		}
+		return noRows
+	}
+	columns, err := rowColumns(rows)
+	if err != nil {
+		return err
+	}
+	return scanStruct(rows, columns, v)
+}
+
+// collectStructs reads all remaining rows into the slice of structs or struct pointers dest points to, replacing its
+// contents.
+func collectStructs(rows Rows, dest any) error {
+	slice, elem, pointer, err := dbtag.SliceOf(dest)
+	if err != nil {
+		return err
+	}
+	columns, err := rowColumns(rows)
+	if err != nil {
+		return err
+	}
+
+	result := reflect.MakeSlice(slice.Type(), 0, 0)
+	for rows.Next() {
+		item := reflect.New(elem)
+		if err = scanStruct(rows, columns, item.Elem()); err != nil {
+			return err
+		}
+		if !pointer {
+			item = item.Elem()
+		}
+		result = reflect.Append(result, item)
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	slice.Set(result)
+	return nil
+}
+
+// queryRowCount returns the number of rows a QueryRow that failed with err returned, for query hooks.
+func queryRowCount(err error) int64 {
+	switch {
+	case err == nil:
+		return 1
+	case errors.Is(err, pgx.ErrNoRows), errors.Is(err, sql.ErrNoRows):
+		return 0
+	default:
+		return -1
+	}
+}
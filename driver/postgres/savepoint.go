@@ -0,0 +1,88 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/ponrove/octobe"
+)
+
+// errNoTransaction is returned for savepoints of a session without a transaction.
+var errNoTransaction = errors.New("cannot use savepoints without transaction")
+
+// Ensure the sessions support nested transactions through savepoints.
+var (
+	_ octobe.Savepoints = &pgxSession{}
+	_ octobe.Savepoints = &pgxpoolSession{}
+	_ octobe.Savepoints = &sqlSession{}
+)
+
+// Savepoint creates a savepoint with the given name in the transaction of the session.
+func (s *pgxSession) Savepoint(name string) error {
+	return pgxSavepoint(s.ctx, s.tx, "SAVEPOINT "+pgx.Identifier{name}.Sanitize())
+}
+
+// RollbackToSavepoint rolls the transaction of the session back to the savepoint with the given name.
+func (s *pgxSession) RollbackToSavepoint(name string) error {
+	return pgxSavepoint(s.ctx, s.tx, "ROLLBACK TO SAVEPOINT "+pgx.Identifier{name}.Sanitize())
+}
+
+// ReleaseSavepoint releases the savepoint with the given name, keeping its changes in the transaction of the session.
+func (s *pgxSession) ReleaseSavepoint(name string) error {
+	return pgxSavepoint(s.ctx, s.tx, "RELEASE SAVEPOINT "+pgx.Identifier{name}.Sanitize())
+}
+
+// Savepoint creates a savepoint with the given name in the transaction of the session.
+func (s *pgxpoolSession) Savepoint(name string) error {
+	return pgxSavepoint(s.ctx, s.tx, "SAVEPOINT "+pgx.Identifier{name}.Sanitize())
+}
+
+// RollbackToSavepoint rolls the transaction of the session back to the savepoint with the given name.
+func (s *pgxpoolSession) RollbackToSavepoint(name string) error {
+	return pgxSavepoint(s.ctx, s.tx, "ROLLBACK TO SAVEPOINT "+pgx.Identifier{name}.Sanitize())
+}
+
+// ReleaseSavepoint releases the savepoint with the given name, keeping its changes in the transaction of the session.
+func (s *pgxpoolSession) ReleaseSavepoint(name string) error {
+	return pgxSavepoint(s.ctx, s.tx, "RELEASE SAVEPOINT "+pgx.Identifier{name}.Sanitize())
+}
+
+// Savepoint creates a savepoint with the given name in the transaction of the session
+func (s *sqlSession) Savepoint(name string) error {
+	return s.savepoint("SAVEPOINT " + pgx.Identifier{name}.Sanitize())
+}
+
+// RollbackToSavepoint rolls the transaction of the session back to the savepoint with the given name
+func (s *sqlSession) RollbackToSavepoint(name string) error {
+	return s.savepoint("ROLLBACK TO SAVEPOINT " + pgx.Identifier{name}.Sanitize())
+}
+
+// ReleaseSavepoint releases the savepoint with the given name, keeping its changes in the transaction of the session
+func (s *sqlSession) ReleaseSavepoint(name string) error {
+	return s.savepoint("RELEASE SAVEPOINT " + pgx.Identifier{name}.Sanitize())
+}
+
+// savepoint executes a savepoint statement in the transaction of the session
+func (s *sqlSession) savepoint(statement string) (err error) {
+	if s.tx == nil {
+		return errNoTransaction
+	}
+	ctx, done := octobe.BeginQuery(s.ctx, octobe.OperationExec, statement, nil)
+	defer func() { done(-1, err) }()
+
+	_, err = s.tx.ExecContext(ctx, statement)
+	return err
+}
+
+// pgxSavepoint executes a savepoint statement in tx.
+func pgxSavepoint(ctx context.Context, tx pgx.Tx, statement string) (err error) {
+	if tx == nil {
+		return errNoTransaction
+	}
+	ctx, done := octobe.BeginQuery(ctx, octobe.OperationExec, statement, nil)
+	defer func() { done(-1, err) }()
+
+	_, err = tx.Exec(ctx, statement)
+	return err
+}
@@ -0,0 +1,155 @@
+package postgres
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ponrove/octobe"
+)
+
+// ExecScript splits a script of multiple SQL statements and executes them one after another within the session. If the
+// session is transactional, the statements are executed within the transaction, and a failing statement leaves it to
+// the caller to roll back. The returned error states which statement failed.
+func ExecScript(session octobe.BuilderSession[Builder], script string) error {
+	builder := session.Builder()
+	for i, statement := range SplitStatements(script) {
+		if _, err := builder(statement).Exec(); err != nil {
+			return fmt.Errorf("statement %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// SplitStatements splits a script into separate statements on semicolons. Semicolons inside string literals, escape
+// string literals, quoted identifiers, dollar-quoted strings and comments do not end a statement. Statements that
+// consist only of whitespace and comments are left out.
+func SplitStatements(script string) []string {
+	var (
+		statements []string
+		start      int
+		content    bool
+	)
+
+	for i := 0; i < len(script); {
+		c := script[i]
+		switch {
+		case c == ';':
+			if content {
+				statements = append(statements, strings.TrimSpace(script[start:i]))
+			}
+			i++
+			start, content = i, false
+			continue
+		case c == '-' && strings.HasPrefix(script[i:], "--"):
+			i = skipLineComment(script, i)
+			continue
+		case c == '/' && strings.HasPrefix(script[i:], "/*"):
+			i = skipBlockComment(script, i)
+			continue
+		case c == '\'':
+			escapes := i > 0 && (script[i-1] == 'E' || script[i-1] == 'e') && (i == 1 || !isIdentifierChar(script[i-2]))
+			i = skipQuoted(script, i, '\'', escapes)
+		case c == '"':
+			i = skipQuoted(script, i, '"', false)
+		case c == '$':
+			if tag, ok := dollarTag(script, i); ok {
+				end := strings.Index(script[i+len(tag):], tag)
+				if end < 0 {
+					i = len(script)
+				} else {
+					i += len(tag) + end + len(tag)
+				}
+			} else {
+				i++
+			}
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			i++
+			continue
+		default:
+			i++
+		}
+		content = true
+	}
+
+	if content {
+		statements = append(statements, strings.TrimSpace(script[start:]))
+	}
+
+	return statements
+}
+
+// skipLineComment returns the index after the line comment that starts at i.
+func skipLineComment(script string, i int) int {
+	end := strings.IndexByte(script[i:], '\n')
+	if end < 0 {
+		return len(script)
+	}
+	return i + end + 1
+}
+
+// skipBlockComment returns the index after the block comment that starts at i, block comments may be nested.
+func skipBlockComment(script string, i int) int {
+	depth := 0
+	for i < len(script) {
+		switch {
+		case strings.HasPrefix(script[i:], "/*"):
+			depth++
+			i += 2
+		case strings.HasPrefix(script[i:], "*/"):
+			depth--
+			i += 2
+			if depth == 0 {
+				return i
+			}
+		default:
+			i++
+		}
+	}
+	return i
+}
+
+// skipQuoted returns the index after the quoted literal that starts at i. A doubled quote character is an escaped
+// quote, and if escapes is set a backslash escapes the following character.
+func skipQuoted(script string, i int, quote byte, escapes bool) int {
+	i++
+	for i < len(script) {
+		switch script[i] {
+		case '\\':
+			if escapes {
+				i += 2
+				continue
+			}
+		case quote:
+			if i+1 < len(script) && script[i+1] == quote {
+				i += 2
+				continue
+			}
+			return i + 1
+		}
+		i++
+	}
+	return i
+}
+
+// dollarTag returns the dollar quote tag, such as $$ or $body$, that starts at i. Positional parameters like $1 are not
+// tags since a tag cannot start with a digit.
+func dollarTag(script string, i int) (string, bool) {
+	if i > 0 && isIdentifierChar(script[i-1]) {
+		return "", false
+	}
+	for j := i + 1; j < len(script); j++ {
+		c := script[j]
+		if c == '$' {
+			return script[i : j+1], true
+		}
+		if !isIdentifierChar(c) || (j == i+1 && c >= '0' && c <= '9') {
+			return "", false
+		}
+	}
+	return "", false
+}
+
+// isIdentifierChar reports whether c can be part of an unquoted identifier.
+func isIdentifierChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c >= 0x80
+}
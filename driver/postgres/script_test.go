@@ -0,0 +1,141 @@
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/driver/postgres"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name     string
+		script   string
+		expected []string
+	}{
+		{
+			name:     "simple statements",
+			script:   "CREATE TABLE a (id INT);\nCREATE TABLE b (id INT);\n",
+			expected: []string{"CREATE TABLE a (id INT)", "CREATE TABLE b (id INT)"},
+		},
+		{
+			name:     "missing trailing semicolon",
+			script:   "SELECT 1; SELECT 2",
+			expected: []string{"SELECT 1", "SELECT 2"},
+		},
+		{
+			name:     "semicolon in string literal",
+			script:   "INSERT INTO a VALUES ('x;y', 'it''s;'); SELECT 1;",
+			expected: []string{"INSERT INTO a VALUES ('x;y', 'it''s;')", "SELECT 1"},
+		},
+		{
+			name:     "escape string literal",
+			script:   `SELECT E'a\';b'; SELECT 2;`,
+			expected: []string{`SELECT E'a\';b'`, "SELECT 2"},
+		},
+		{
+			name:     "quoted identifier",
+			script:   `SELECT "a;b" FROM t; SELECT 2;`,
+			expected: []string{`SELECT "a;b" FROM t`, "SELECT 2"},
+		},
+		{
+			name: "dollar quoted function body",
+			script: `CREATE FUNCTION f() RETURNS void AS $$
+BEGIN
+	PERFORM 1;
+END;
+$$ LANGUAGE plpgsql;
+CREATE FUNCTION g() RETURNS text AS $body$ SELECT ';' $body$ LANGUAGE sql;`,
+			expected: []string{
+				"CREATE FUNCTION f() RETURNS void AS $$\nBEGIN\n\tPERFORM 1;\nEND;\n$$ LANGUAGE plpgsql",
+				"CREATE FUNCTION g() RETURNS text AS $body$ SELECT ';' $body$ LANGUAGE sql",
+			},
+		},
+		{
+			name:     "positional parameters are not dollar quotes",
+			script:   "SELECT $1; SELECT $2;",
+			expected: []string{"SELECT $1", "SELECT $2"},
+		},
+		{
+			name:     "comments",
+			script:   "-- first; statement\nSELECT 1; /* block; /* nested; */ comment */ SELECT 2;\n-- trailing comment",
+			expected: []string{"-- first; statement\nSELECT 1", "/* block; /* nested; */ comment */ SELECT 2"},
+		},
+		{
+			name:     "empty statements",
+			script:   " ; ;\n",
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, postgres.SplitStatements(tt.script))
+		})
+	}
+}
+
+func TestExecScript(t *testing.T) {
+	script := `
+		CREATE TABLE IF NOT EXISTS products (id SERIAL PRIMARY KEY, name TEXT NOT NULL);
+		INSERT INTO products (name) VALUES ('first;');
+	`
+
+	t.Run("success", func(t *testing.T) {
+		mock, err := pgxmock.NewConn()
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		ctx := context.Background()
+		defer mock.Close(ctx)
+
+		mock.ExpectBeginTx(pgx.TxOptions{})
+		mock.ExpectExec("CREATE TABLE IF NOT EXISTS products").WillReturnResult(pgxmock.NewResult("CREATE TABLE", 0))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO products (name) VALUES ('first;')")).WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectCommit()
+
+		ob, err := octobe.New(postgres.OpenPGXWithConn(mock))
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+
+		err = ob.StartTransaction(ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+			return postgres.ExecScript(session, script)
+		}, postgres.WithPGXTxOptions(postgres.PGXTxOptions{}))
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("failing statement", func(t *testing.T) {
+		mock, err := pgxmock.NewConn()
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		ctx := context.Background()
+		defer mock.Close(ctx)
+
+		expectedErr := errors.New("insert failed")
+		mock.ExpectBeginTx(pgx.TxOptions{})
+		mock.ExpectExec("CREATE TABLE IF NOT EXISTS products").WillReturnResult(pgxmock.NewResult("CREATE TABLE", 0))
+		mock.ExpectExec("INSERT INTO products").WillReturnError(expectedErr)
+		mock.ExpectRollback()
+
+		ob, err := octobe.New(postgres.OpenPGXWithConn(mock))
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+
+		err = ob.StartTransaction(ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+			return postgres.ExecScript(session, script)
+		}, postgres.WithPGXTxOptions(postgres.PGXTxOptions{}))
+		assert.ErrorIs(t, err, expectedErr)
+		assert.ErrorContains(t, err, "statement 2")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
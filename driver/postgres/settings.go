@@ -0,0 +1,117 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ponrove/octobe"
+)
+
+// ErrSettingsUnsupported is returned by Begin for a session with settings that neither runs in a transaction nor on
+// the single connection of the pgx driver. The statements of such a session may run on any connection of the pool, a
+// setting made on one of them would not apply to the others and outlive the session.
+var ErrSettingsUnsupported = errors.New("session settings require a transaction or a session of the pgx driver")
+
+// ErrMissingContextValue is returned by the functions of ContextValue for a context without the value of their key.
+var ErrMissingContextValue = errors.New("context has no value for the setting")
+
+// setConfigQuery sets a run-time parameter like SET, or like SET LOCAL if its third argument is true. Unlike SET it
+// takes the name and value as arguments, so they need no quoting.
+const setConfigQuery = "SELECT set_config($1, $2, $3)"
+
+// setting is a run-time parameter set when a session begins, see WithSetting.
+type setting struct {
+	name  string
+	value string
+	from  func(ctx context.Context) (string, error) // Value from the context of the session, if set
+}
+
+// WithSetting sets the run-time parameter name to value when the session begins, like SET. In a transaction it is set
+// with SET LOCAL and reset when the transaction ends. A session of the pgx driver without a transaction sets it on the
+// connection of the driver, where it stays after the session. Sessions of the pgxpool driver without a transaction
+// fail to begin with ErrSettingsUnsupported. A setting that cannot be made fails Begin and rolls back the transaction.
+// Custom parameters, like those read by row-level security policies, must contain a dot, as in app.tenant_id.
+func WithSetting(name, value string) octobe.Option[pgxConfig] {
+	return func(c *pgxConfig) {
+		c.settings = append(c.settings, setting{name: name, value: value})
+	}
+}
+
+// WithSearchPath sets the search_path of the session to schemas, see WithSetting, so unqualified tables resolve to the
+// schema of a tenant in applications with a schema per tenant:
+//
+//	err := ob.StartTransaction(ctx, handler, postgres.WithPGXTxOptions(postgres.PGXTxOptions{}),
+//		postgres.WithSearchPath("tenant_123", "public"))
+func WithSearchPath(schemas ...string) octobe.Option[pgxConfig] {
+	return WithSetting("search_path", quoteIdentifiers(schemas))
+}
+
+// WithContextSetting sets the run-time parameter name to the value returned by from for the context of the session
+// when it begins, see WithSetting. It binds the transactions of a request to the identity row-level security policies
+// check, like the user or tenant read with current_setting('app.current_user_id'), so every handler in the
+// transaction runs under it without setting it again. Passed to octobe.WithDefaultOptions, it applies to every
+// session:
+//
+//	ob, err := octobe.New(postgres.OpenPGXPool(ctx, dsn), octobe.WithDefaultOptions(
+//		postgres.WithContextSetting("app.current_user_id", postgres.ContextValue(userIDKey{})),
+//	))
+//
+// An error returned by from fails Begin and rolls back the transaction, so no statement runs without the identity.
+func WithContextSetting(name string, from func(ctx context.Context) (string, error)) octobe.Option[pgxConfig] {
+	return func(c *pgxConfig) {
+		c.settings = append(c.settings, setting{name: name, from: from})
+	}
+}
+
+// ContextValue returns a function for WithContextSetting and WithSQLContextSetting returning the value of key in the
+// context, formatted with fmt.Sprint, or ErrMissingContextValue if the context has none.
+func ContextValue(key any) func(ctx context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		value := ctx.Value(key)
+		if value == nil {
+			return "", fmt.Errorf("%w: %v", ErrMissingContextValue, key)
+		}
+		return fmt.Sprint(value), nil
+	}
+}
+
+// WithSQLSetting sets the run-time parameter name to value when the session of the database/sql driver begins, see
+// WithSetting. Sessions without a transaction fail to begin with ErrSettingsUnsupported, their statements may run on
+// any connection of the database.
+func WithSQLSetting(name, value string) octobe.Option[sqlConfig] {
+	return func(c *sqlConfig) {
+		c.settings = append(c.settings, setting{name: name, value: value})
+	}
+}
+
+// WithSQLSearchPath sets the search_path of the session of the database/sql driver to schemas, see WithSearchPath.
+func WithSQLSearchPath(schemas ...string) octobe.Option[sqlConfig] {
+	return WithSQLSetting("search_path", quoteIdentifiers(schemas))
+}
+
+// WithSQLContextSetting sets the run-time parameter name to the value returned by from for the context of the session
+// of the database/sql driver when it begins, see WithContextSetting.
+func WithSQLContextSetting(name string, from func(ctx context.Context) (string, error)) octobe.Option[sqlConfig] {
+	return func(c *sqlConfig) {
+		c.settings = append(c.settings, setting{name: name, from: from})
+	}
+}
+
+// applySettings makes settings with the Exec method of a connection or transaction, local to the transaction if local
+// is set.
+func applySettings[R any](ctx context.Context, settings []setting, local bool, exec func(ctx context.Context, query string, args ...any) (R, error)) error {
+	for _, s := range settings {
+		value := s.value
+		if s.from != nil {
+			var err error
+			if value, err = s.from(ctx); err != nil {
+				return fmt.Errorf("setting %s: %w", s.name, err)
+			}
+		}
+		if _, err := exec(ctx, setConfigQuery, s.name, value, local); err != nil {
+			return err
+		}
+	}
+	return nil
+}
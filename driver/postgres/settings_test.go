@@ -0,0 +1,131 @@
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/driver/postgres"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithSearchPath(t *testing.T) {
+	ctx := context.Background()
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	mock.ExpectBeginTx(pgx.TxOptions{})
+	mock.ExpectExec("SELECT set_config").WithArgs("search_path", `"tenant_123", "public"`, true).
+		WillReturnResult(pgxmock.NewResult("SELECT", 1))
+	mock.ExpectExec("SELECT set_config").WithArgs("app.tenant_id", "123", true).
+		WillReturnResult(pgxmock.NewResult("SELECT", 1))
+	mock.ExpectQuery("SELECT name FROM products").WillReturnRows(pgxmock.NewRows([]string{"name"}).AddRow("chair"))
+	mock.ExpectCommit()
+
+	hook := &dbtxHook{}
+	ob, err := octobe.New(postgres.OpenPGXPoolWithPool(mock), octobe.WithQueryHook(hook))
+	require.NoError(t, err)
+
+	err = ob.StartTransaction(ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+		var name string
+		return session.Builder()("SELECT name FROM products").QueryRow(&name)
+	}, postgres.WithPGXTxOptions(postgres.PGXTxOptions{}), postgres.WithSearchPath("tenant_123", "public"),
+		postgres.WithSetting("app.tenant_id", "123"))
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	// The settings are part of beginning the session, not statements of it.
+	require.Len(t, hook.events, 1)
+
+	_, err = ob.Begin(ctx, postgres.WithSearchPath("tenant_123"))
+	require.ErrorIs(t, err, postgres.ErrSettingsUnsupported)
+}
+
+func TestWithSettingPGX(t *testing.T) {
+	ctx := context.Background()
+	mock, err := pgxmock.NewConn()
+	require.NoError(t, err)
+	defer mock.Close(ctx)
+
+	failed := errors.New("unrecognized configuration parameter")
+	mock.ExpectExec("SELECT set_config").WithArgs("search_path", `"tenant_123"`, false).
+		WillReturnResult(pgxmock.NewResult("SELECT", 1))
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("statement_timeout", "5s", true).WillReturnError(failed)
+	mock.ExpectRollback()
+
+	ob, err := octobe.New(postgres.OpenPGXWithConn(mock))
+	require.NoError(t, err)
+
+	// Without a transaction the setting is made on the connection of the driver.
+	_, err = ob.Begin(ctx, postgres.WithSearchPath("tenant_123"))
+	require.NoError(t, err)
+
+	_, err = ob.Begin(ctx, postgres.WithPGXTxOptions(postgres.PGXTxOptions{}), postgres.WithSetting("statement_timeout", "5s"))
+	require.ErrorIs(t, err, failed)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWithSQLSearchPath(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config").WithArgs("search_path", `"tenant_123"`, true).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	ob, err := octobe.New(postgres.OpenWithConn(db))
+	require.NoError(t, err)
+	session, err := ob.Begin(context.Background(), postgres.WithSQLTxOptions(postgres.SQLTxOptions{}),
+		postgres.WithSQLSearchPath("tenant_123"))
+	require.NoError(t, err)
+	require.NoError(t, session.Commit())
+
+	_, err = ob.Begin(context.Background(), postgres.WithSQLSetting("app.tenant_id", "123"))
+	require.ErrorIs(t, err, postgres.ErrSettingsUnsupported)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+type userIDKey struct{}
+
+func TestWithContextSetting(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	mock.ExpectBeginTx(pgx.TxOptions{})
+	mock.ExpectExec("SELECT set_config").WithArgs("app.current_user_id", "42", true).
+		WillReturnResult(pgxmock.NewResult("SELECT", 1))
+	mock.ExpectQuery("SELECT id FROM documents").WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int64(7)))
+	mock.ExpectCommit()
+	mock.ExpectBeginTx(pgx.TxOptions{})
+	mock.ExpectRollback()
+
+	ob, err := octobe.New(postgres.OpenPGXPoolWithPool(mock), octobe.WithDefaultOptions(
+		postgres.WithPGXTxOptions(postgres.PGXTxOptions{}),
+		postgres.WithContextSetting("app.current_user_id", postgres.ContextValue(userIDKey{})),
+	))
+	require.NoError(t, err)
+
+	ctx := context.WithValue(context.Background(), userIDKey{}, 42)
+	err = ob.StartTransaction(ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+		var id int64
+		return session.Builder()("SELECT id FROM documents").QueryRow(&id)
+	})
+	require.NoError(t, err)
+
+	// Without an identity the transaction is rolled back before any handler runs.
+	err = ob.StartTransaction(context.Background(), func(octobe.BuilderSession[postgres.Builder]) error {
+		t.Fatal("handler ran without an identity")
+		return nil
+	})
+	require.ErrorIs(t, err, postgres.ErrMissingContextValue)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -5,9 +5,13 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"slices"
 	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
 	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/internal/named"
 )
 
 // SQL defines the interface for the database/sql connection.
@@ -34,24 +38,126 @@ var _ SQL = &sql.DB{} // Ensure sql.DB implements the DB interface.
 
 // sqlConn holds the connection db and default configuration for the sqlConn driver
 type sqlConn struct {
-	sqlDB SQL
+	sqlDB      SQL
+	statements *sqlStatementCache
+}
+
+// SQLOption is a signature for configuring the database/sql driver when it is opened
+type SQLOption func(cfg *sqlOptions)
+
+// sqlOptions holds the configuration given when opening the database/sql driver
+type sqlOptions struct {
+	statementCacheSize int
+	tune               []func(db *sql.DB) // Applied to the database opened by OpenSQL
+}
+
+// WithSQLStatementCache prepares the queries of segments on the database the first time they run and executes the
+// prepared statements from then on, keeping at most size of them and closing the least recently used one when more
+// are needed. It saves preparing hot queries again on every call, database/sql prepares a statement on each connection
+// it is used on. In a transaction the statement is bound with Tx.StmtContext, which prepares it again on the connection
+// of the transaction unless it was prepared there before. Statements are closed when the driver is closed. A size of
+// zero or less disables the cache
+func WithSQLStatementCache(size int) SQLOption {
+	return func(cfg *sqlOptions) {
+		cfg.statementCacheSize = size
+	}
+}
+
+// WithSQLMaxOpenConns sets the maximum number of open connections of the database opened by OpenSQL, see
+// sql.DB.SetMaxOpenConns. Like the other tuning options it has no effect for a database passed to OpenWithConn, which
+// is tuned by its owner
+func WithSQLMaxOpenConns(n int) SQLOption {
+	return tuneSQL(func(db *sql.DB) {
+		db.SetMaxOpenConns(n)
+	})
+}
+
+// WithSQLMaxIdleConns sets the maximum number of idle connections the database opened by OpenSQL keeps, see
+// WithSQLMaxOpenConns
+func WithSQLMaxIdleConns(n int) SQLOption {
+	return tuneSQL(func(db *sql.DB) {
+		db.SetMaxIdleConns(n)
+	})
+}
+
+// WithSQLConnMaxLifetime sets how long a connection of the database opened by OpenSQL is used before it is closed and
+// replaced, see WithSQLMaxOpenConns
+func WithSQLConnMaxLifetime(d time.Duration) SQLOption {
+	return tuneSQL(func(db *sql.DB) {
+		db.SetConnMaxLifetime(d)
+	})
+}
+
+// WithSQLConnMaxIdleTime sets how long a connection of the database opened by OpenSQL may stay idle before it is
+// closed, see WithSQLMaxOpenConns
+func WithSQLConnMaxIdleTime(d time.Duration) SQLOption {
+	return tuneSQL(func(db *sql.DB) {
+		db.SetConnMaxIdleTime(d)
+	})
+}
+
+// tuneSQL returns an option changing the database opened by OpenSQL with tune before it is used
+func tuneSQL(tune func(db *sql.DB)) SQLOption {
+	return func(cfg *sqlOptions) {
+		cfg.tune = append(cfg.tune, tune)
+	}
+}
+
+// newSQLOptions returns the configuration of the database/sql driver with the given options applied
+func newSQLOptions(opts []SQLOption) sqlOptions {
+	var cfg sqlOptions
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
 }
 
 // Type check to make sure that the conn driver implements the Octobe Driver interface
 var _ octobe.Driver[sqlConn, sqlConfig, Builder] = &sqlConn{}
 
+// OpenSQL opens a database/sql database for dsn with the pgx driver and returns the database/sql driver for it, so the
+// database does not have to be opened and tuned outside Octobe:
+//
+//	ob, err := octobe.New(postgres.OpenSQL(dsn, postgres.WithSQLMaxOpenConns(20), postgres.WithSQLMaxIdleConns(5)))
+//
+// An invalid dsn fails to open, but like sql.Open no connection is made until the first session needs one. The
+// database is closed with the driver
+func OpenSQL(dsn string, opts ...SQLOption) octobe.Open[sqlConn, sqlConfig, Builder] {
+	return func() (octobe.Driver[sqlConn, sqlConfig, Builder], error) {
+		config, err := pgx.ParseConfig(dsn)
+		if err != nil {
+			return nil, err
+		}
+
+		cfg := newSQLOptions(opts)
+		db := stdlib.OpenDB(*config)
+		for _, tune := range cfg.tune {
+			tune(db)
+		}
+		return newSQLConn(db, cfg), nil
+	}
+}
+
 // OpenWithConn is a function that can be used for opening a new database connection, it should always return a driver
 // with set signature of types for the local driver. This function is used when a connection db is already available.
-func OpenWithConn(db SQL) octobe.Open[sqlConn, sqlConfig, Builder] {
+func OpenWithConn(db SQL, opts ...SQLOption) octobe.Open[sqlConn, sqlConfig, Builder] {
 	return func() (octobe.Driver[sqlConn, sqlConfig, Builder], error) {
 		if db == nil {
 			return nil, errors.New("db is nil")
 		}
+		return newSQLConn(db, newSQLOptions(opts)), nil
+	}
+}
 
-		return &sqlConn{
-			sqlDB: db,
-		}, nil
+// newSQLConn returns the database/sql driver for db with the configuration cfg
+func newSQLConn(db SQL, cfg sqlOptions) *sqlConn {
+	d := &sqlConn{
+		sqlDB: db,
+	}
+	if cfg.statementCacheSize > 0 {
+		d.statements = newSQLStatementCache(cfg.statementCacheSize)
 	}
+	return d
 }
 
 // Begin will start a new session with the database, this will return a Session instance that can be used for handling
@@ -64,6 +170,10 @@ func (d *sqlConn) Begin(ctx context.Context, opts ...octobe.Option[sqlConfig]) (
 		opt(&cfg)
 	}
 
+	if cfg.txOptions == nil && len(cfg.settings) > 0 {
+		return nil, ErrSettingsUnsupported
+	}
+
 	var tx *sql.Tx
 	var err error
 	if cfg.txOptions != nil {
@@ -77,16 +187,53 @@ func (d *sqlConn) Begin(ctx context.Context, opts ...octobe.Option[sqlConfig]) (
 		return nil, err
 	}
 
-	return &sqlSession{
+	if tx != nil {
+		if err = applySettings(ctx, cfg.settings, true, tx.ExecContext); err != nil {
+			return nil, errors.Join(err, tx.Rollback())
+		}
+	}
+
+	session := &sqlSession{
 		ctx: ctx,
 		cfg: cfg,
 		tx:  tx,
 		d:   d,
-	}, nil
+	}
+	session.builder = session.build
+	return session, nil
+}
+
+// Type check to make sure that the conn driver reports statistics
+var _ octobe.StatsReporter = &sqlConn{}
+
+// Stats returns the sql.DBStats of the database
+func (d *sqlConn) Stats() any {
+	return d.sqlDB.Stats()
+}
+
+// Ensure sqlConn describes itself to query hooks
+var _ octobe.Describer = &sqlConn{}
+
+// Describe returns the name of the driver and the database system it connects to
+func (d *sqlConn) Describe() octobe.DriverInfo {
+	return octobe.DriverInfo{Name: "database/sql", System: "postgresql"}
+}
+
+// Ensure sqlConn reports its capabilities.
+var _ octobe.CapabilityReporter = &sqlConn{}
+
+// Capabilities returns the features supported by the database/sql driver.
+func (d *sqlConn) Capabilities() octobe.Capabilities {
+	return octobe.Capabilities{Transactions: true, Savepoints: true, Returning: true}
 }
 
 // Close will close the database connection.
 func (d *sqlConn) Close(_ context.Context) error {
+	if d.statements != nil {
+		if err := d.statements.close(); err != nil {
+			return errors.Join(err, d.sqlDB.Close())
+		}
+	}
 	return d.sqlDB.Close()
 }
 
@@ -103,25 +250,29 @@ func (d *sqlConn) Ping(ctx context.Context) error {
 // of commit and rollback. If it is non-transactional, it will not enforce the usage of commit and rollback.
 // A sqlSession is not thread safe, it should only be used in one thread at a time.
 type sqlSession struct {
-	ctx       context.Context
-	cfg       sqlConfig
-	tx        *sql.Tx
-	d         *sqlConn
-	committed bool
+	ctx     context.Context
+	cfg     sqlConfig
+	tx      *sql.Tx
+	d       *sqlConn
+	state   txState
+	builder Builder
 }
 
 // Type check to make sure that the session implements the Octobe Session interface
 var _ octobe.Session[Builder] = &sqlSession{}
+var _ octobe.Transactional = &sqlSession{}
 
 // Commit will commit a transaction, this will only work if the session is transactional.
 func (s *sqlSession) Commit() error {
 	if s.cfg.txOptions == nil {
 		return errors.New("cannot commit without transaction")
 	}
-	defer func() {
-		s.committed = true
-	}()
-	return s.tx.Commit()
+	if err := s.state.check(); err != nil {
+		return err
+	}
+	err := s.tx.Commit()
+	s.state.end(err == nil)
+	return err
 }
 
 // Rollback will rollback a transaction, this will only work if the session is transactional.
@@ -129,20 +280,35 @@ func (s *sqlSession) Rollback() error {
 	if s.cfg.txOptions == nil {
 		return errors.New("cannot rollback without transaction")
 	}
+	if err := s.state.check(); err != nil {
+		return err
+	}
+	s.state.end(false)
 	return s.tx.Rollback()
 }
 
-// Builder will return a new builder for building queries
+// InTransaction reports whether the session runs in a transaction
+func (s *sqlSession) InTransaction() bool {
+	return s.cfg.txOptions != nil
+}
+
+// Builder will return the builder for building queries in the session
 func (s *sqlSession) Builder() Builder {
-	return func(query string) Segment {
-		return &sqlSegment{
-			query: query,
-			args:  nil,
-			used:  false,
-			tx:    s.tx,
-			d:     s.d,
-			ctx:   s.ctx,
-		}
+	return s.builder
+}
+
+// build creates a segment for query in the session. It backs the builder of the session, which is created once so that
+// building segments in a loop only allocates the segments.
+func (s *sqlSession) build(query string) Segment {
+	return &sqlSegment{
+		query:    query,
+		args:     nil,
+		used:     false,
+		tx:       s.tx,
+		d:        s.d,
+		ctx:      s.ctx,
+		rewrite:  s.cfg.rewrite,
+		readOnly: s.cfg.readOnly,
 	}
 }
 
@@ -160,45 +326,113 @@ type sqlSegment struct {
 	d *sqlConn
 	// ctx is a context that can be used to interrupt a query
 	ctx context.Context
+	// maxRows is the maximum number of rows Query may read, zero means no limit
+	maxRows int
+	// timeout is the deadline of the statement relative to its execution, zero means none
+	timeout time.Duration
+	// stmt is the prepared statement executed in place of the query, see Prepare
+	stmt *sql.Stmt
+	// rewrite is the rewriter of the session applied before execution, nil once applied
+	rewrite Rewriter
+	// readOnly reports whether the session is read-only, rejecting Exec
+	readOnly bool
+	// err is an error from building the Segment, returned when it is executed
+	err error
 }
 
-var _ Segment = &pgxSegment{}
+var _ Segment = &sqlSegment{}
 
 // use will set used to true after a Segment has been performed
 func (s *sqlSegment) use() {
 	s.used = true
 }
 
-// Arguments receives unknown amount of arguments to use in the query
+// rewriteQuery applies the rewriter of the session to the query and arguments, once
+func (s *sqlSegment) rewriteQuery() {
+	if s.rewrite != nil {
+		s.query, s.args = s.rewrite(s.query, s.args)
+		s.rewrite = nil
+	}
+}
+
+// Arguments receives unknown amount of arguments to use in the query. Arguments created with sql.Named are bound to
+// the named parameters of the query, like @name, as with NamedArguments
 func (s *sqlSegment) Arguments(args ...any) Segment {
+	values, ok, err := named.Args(args)
+	if err != nil {
+		s.err = err
+		return s
+	}
+	if ok {
+		return s.NamedArguments(values)
+	}
 	s.args = args
 	return s
 }
 
+// ArgumentsFromStruct binds the fields of a struct to the query by their db tags. Named parameters like :name are bound
+// to the field of the same name, a query without named parameters gets all fields as arguments in declaration order
+func (s *sqlSegment) ArgumentsFromStruct(v any) Segment {
+	s.query, s.args, s.err = named.Bind(s.query, named.Dollar, v)
+	return s
+}
+
+// NamedArguments binds the values of a map to the named parameters of the query, like :name or @name
+func (s *sqlSegment) NamedArguments(args map[string]any) Segment {
+	s.query, s.args, s.err = named.BindMap(s.query, named.Dollar, args)
+	return s
+}
+
+// WithMaxRows limits the number of rows Query may read before failing with a *octobe.MaxRowsError
+func (s *sqlSegment) WithMaxRows(n int) Segment {
+	s.maxRows = n
+	return s
+}
+
+// Timeout sets a deadline of d for executing the statement, leaving the context of the session as is
+func (s *sqlSegment) Timeout(d time.Duration) Segment {
+	s.timeout = d
+	return s
+}
+
+// Clone returns an unused copy of the segment with the same query, arguments and settings
+func (s *sqlSegment) Clone() Segment {
+	c := *s
+	c.used = false
+	c.args = slices.Clone(s.args)
+	return &c
+}
+
 // Exec will execute a query. Used for inserts or updates
-func (s *sqlSegment) Exec() (ExecResult, error) {
+func (s *sqlSegment) Exec() (result ExecResult, err error) {
 	if s.used {
 		return ExecResult{}, octobe.ErrAlreadyUsed
 	}
 	defer s.use()
-	if s.tx == nil {
-		res, err := s.d.sqlDB.ExecContext(s.ctx, s.query, s.args...)
-		if err != nil {
-			return ExecResult{}, err
-		}
+	if s.err != nil {
+		return ExecResult{}, s.err
+	}
+	if s.readOnly {
+		return ExecResult{}, ErrReadOnly
+	}
+	s.rewriteQuery()
 
-		rowsAffected, err := res.RowsAffected()
-		if err != nil {
-			return ExecResult{}, fmt.Errorf("failed to get rows affected: %w", err)
-		}
+	ctx, cancel := withTimeout(s.ctx, s.timeout)
+	defer cancel()
+	ctx, done := octobe.BeginQuery(ctx, octobe.OperationExec, s.query, s.args)
+	defer func() {
+		err = wrapQueryError(s.d.Describe().Name, s.query, s.args, err)
+		done(result.RowsAffected, err)
+	}()
 
-		return ExecResult{
-			RowsAffected: rowsAffected,
-		}, nil
+	query := octobe.CommentQuery(ctx, s.query)
+	conn, release, err := s.conn(ctx, query)
+	if err != nil {
+		return ExecResult{}, err
 	}
+	defer release()
 
-	// If we have a transaction, we execute the query in the transaction context
-	res, err := s.tx.ExecContext(s.ctx, s.query, s.args...)
+	res, err := conn.ExecContext(ctx, query, s.args...)
 	if err != nil {
 		return ExecResult{}, err
 	}
@@ -210,46 +444,164 @@ func (s *sqlSegment) Exec() (ExecResult, error) {
 
 	return ExecResult{
 		RowsAffected: rowsAffected,
+		Command:      queryCommand(s.query),
 	}, nil
 }
 
 // QueryRow will return one result and put them into destination pointers
-func (s *sqlSegment) QueryRow(dest ...any) error {
+func (s *sqlSegment) QueryRow(dest ...any) (err error) {
 	if s.used {
 		return octobe.ErrAlreadyUsed
 	}
 	defer s.use()
-	if s.tx == nil {
-		return s.d.sqlDB.QueryRowContext(s.ctx, s.query, s.args...).Scan(dest...)
+	if s.err != nil {
+		return s.err
 	}
-	return s.tx.QueryRowContext(s.ctx, s.query, s.args...).Scan(dest...)
+	s.rewriteQuery()
+
+	ctx, cancel := withTimeout(s.ctx, s.timeout)
+	defer cancel()
+	ctx, done := octobe.BeginQuery(ctx, octobe.OperationQueryRow, s.query, s.args)
+	defer func() {
+		err = wrapQueryError(s.d.Describe().Name, s.query, s.args, err)
+		done(queryRowCount(err), err)
+	}()
+
+	query := octobe.CommentQuery(ctx, s.query)
+	conn, release, err := s.conn(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return conn.QueryRowContext(ctx, query, s.args...).Scan(dest...)
 }
 
 // Query will perform a normal query against database that returns rows
-func (s *sqlSegment) Query(cb func(Rows) error) error {
+func (s *sqlSegment) Query(cb func(Rows) error) (err error) {
 	if s.used {
 		return octobe.ErrAlreadyUsed
 	}
 	defer s.use()
+	if s.err != nil {
+		return s.err
+	}
+	s.rewriteQuery()
 
-	var err error
-	var rows *sql.Rows
-	if s.tx == nil {
-		rows, err = s.d.sqlDB.QueryContext(s.ctx, s.query, s.args...)
-		if err != nil {
-			return err
-		}
-	} else {
-		rows, err = s.tx.QueryContext(s.ctx, s.query, s.args...)
-		if err != nil {
-			return err
-		}
+	ctx, cancel := withTimeout(s.ctx, s.timeout)
+	defer cancel()
+	ctx, done := octobe.BeginQuery(ctx, octobe.OperationQuery, s.query, s.args)
+	defer func() {
+		err = wrapQueryError(s.d.Describe().Name, s.query, s.args, err)
+		done(-1, err)
+	}()
+
+	query := octobe.CommentQuery(ctx, s.query)
+	conn, release, err := s.conn(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	rows, err := conn.QueryContext(ctx, query, s.args...)
+	if err != nil {
+		return err
 	}
 
-	if err = cb(rows); err != nil {
+	limited := limitRows(rows, s.maxRows)
+	if err = cb(limited); err != nil {
 		err2 := rows.Close()
 		return fmt.Errorf("error in callback: %w, error in closing rows: %w", err, err2)
 	}
 
+	if err = limitErr(limited); err != nil {
+		return errors.Join(err, rows.Close())
+	}
+
 	return rows.Close()
 }
+
+// conn returns what the segment executes query on: its prepared statement, the statement of the cache of the driver,
+// the transaction or the database. The returned function must be called once the query is done
+func (s *sqlSegment) conn(ctx context.Context, query string) (sqlQueryer, func(), error) {
+	switch {
+	case s.stmt != nil:
+		return sqlStmt{stmt: s.stmt}, func() {}, nil
+	case s.d.statements != nil:
+		stmt, release, err := s.d.statements.acquire(ctx, s.d.sqlDB, query)
+		if err != nil {
+			return nil, nil, err
+		}
+		if s.tx != nil {
+			// The statement of the transaction is closed when the transaction ends.
+			stmt = s.tx.StmtContext(ctx, stmt)
+		}
+		return sqlStmt{stmt: stmt}, release, nil
+	case s.tx != nil:
+		return s.tx, func() {}, nil
+	}
+	return s.d.sqlDB, func() {}, nil
+}
+
+// sqlQueryer executes queries on a database or a transaction of the database/sql driver
+type sqlQueryer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// sqlStmt executes a prepared statement as a sqlQueryer, the query passed to it is ignored since the statement holds it
+type sqlStmt struct {
+	stmt *sql.Stmt
+}
+
+// ExecContext executes the statement
+func (s sqlStmt) ExecContext(ctx context.Context, _ string, args ...any) (sql.Result, error) {
+	return s.stmt.ExecContext(ctx, args...)
+}
+
+// QueryContext executes the statement and returns its rows
+func (s sqlStmt) QueryContext(ctx context.Context, _ string, args ...any) (*sql.Rows, error) {
+	return s.stmt.QueryContext(ctx, args...)
+}
+
+// QueryRowContext executes the statement and returns its first row
+func (s sqlStmt) QueryRowContext(ctx context.Context, _ string, args ...any) *sql.Row {
+	return s.stmt.QueryRowContext(ctx, args...)
+}
+
+// QueryRowMap returns the first row of the result as a map keyed by column name
+func (s *sqlSegment) QueryRowMap() (map[string]any, error) {
+	var row map[string]any
+	err := s.Query(func(rows Rows) error {
+		var err error
+		row, err = firstMap(rows, sql.ErrNoRows)
+		return err
+	})
+	return row, err
+}
+
+// QueryMaps returns all rows of the result as maps keyed by column name
+func (s *sqlSegment) QueryMaps() ([]map[string]any, error) {
+	var result []map[string]any
+	err := s.Query(func(rows Rows) error {
+		var err error
+		result, err = collectMaps(rows)
+		return err
+	})
+	return result, err
+}
+
+// QueryRowStruct reads the first row of the result into the struct dest points to, mapping columns by db tags
+func (s *sqlSegment) QueryRowStruct(dest any) error {
+	return s.Query(func(rows Rows) error {
+		return firstStruct(rows, dest, sql.ErrNoRows)
+	})
+}
+
+// QueryStructs reads all rows of the result into the slice dest points to, mapping columns by db tags
+func (s *sqlSegment) QueryStructs(dest any) error {
+	return s.Query(func(rows Rows) error {
+		return collectStructs(rows, dest)
+	})
+}
@@ -5,7 +5,9 @@ import (
 	"database/sql"
 	"errors"
 	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/ponrove/octobe"
@@ -471,6 +473,43 @@ func TestSQLCommitError(t *testing.T) {
 	}
 }
 
+func TestSQLTxDone(t *testing.T) {
+	t.Parallel()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	instance, err := octobe.New(postgres.OpenWithConn(db))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := instance.Begin(context.Background(), postgres.WithSQLTxOptions(postgres.SQLTxOptions{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := session.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if err := session.Rollback(); !errors.Is(err, octobe.ErrTxDone) {
+		t.Fatalf("expected error %v, got %v", octobe.ErrTxDone, err)
+	}
+	if err := session.Commit(); !errors.Is(err, octobe.ErrTxDone) {
+		t.Fatalf("expected error %v, got %v", octobe.ErrTxDone, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
 func TestSQLSegmentExecError(t *testing.T) {
 	t.Parallel()
 
@@ -731,3 +770,692 @@ func TestSQLSegmentQueryCloseRowsError(t *testing.T) {
 		t.Fatal("expected error, got nil")
 	}
 }
+
+func TestSQLSegmentQueryMaxRows(t *testing.T) {
+	t.Parallel()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	query := "SELECT id FROM users"
+	rows := sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2).AddRow(3)
+	mock.ExpectQuery(regexp.QuoteMeta(query)).WillReturnRows(rows)
+
+	instance, err := octobe.New(postgres.OpenWithConn(db))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := instance.Begin(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var read int
+	err = session.Builder()(query).WithMaxRows(2).Query(func(r postgres.Rows) error {
+		for r.Next() {
+			read++
+		}
+		return nil
+	})
+
+	var maxRowsErr *octobe.MaxRowsError
+	if !errors.As(err, &maxRowsErr) || maxRowsErr.Limit != 2 {
+		t.Fatalf("expected max rows error with limit 2, got %v", err)
+	}
+
+	if read != 2 {
+		t.Errorf("expected 2 rows to be read, got %d", read)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestSQLSegmentQueryMaps(t *testing.T) {
+	t.Parallel()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	query := "SELECT id, name FROM users"
+	mock.ExpectQuery(regexp.QuoteMeta(query)).WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(int64(1), "a").AddRow(int64(2), "b"))
+	mock.ExpectQuery(regexp.QuoteMeta(query)).WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+
+	instance, err := octobe.New(postgres.OpenWithConn(db))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := instance.Begin(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := session.Builder()(query).QueryMaps()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rows) != 2 || rows[0]["id"] != int64(1) || rows[1]["name"] != "b" {
+		t.Errorf("unexpected rows %v", rows)
+	}
+
+	_, err = session.Builder()(query).QueryRowMap()
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestSQLSegmentQueryStructs(t *testing.T) {
+	t.Parallel()
+
+	type User struct {
+		ID   int64  `db:"id"`
+		Name string `db:"name"`
+	}
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	query := "SELECT id, name FROM users"
+	mock.ExpectQuery(regexp.QuoteMeta(query)).WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(int64(1), "a").AddRow(int64(2), "b"))
+	mock.ExpectQuery(regexp.QuoteMeta(query)).WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(int64(1), "a"))
+	mock.ExpectQuery(regexp.QuoteMeta(query)).WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+
+	instance, err := octobe.New(postgres.OpenWithConn(db))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := instance.Begin(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var users []User
+	if err = session.Builder()(query).QueryStructs(&users); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(users) != 2 || users[0] != (User{ID: 1, Name: "a"}) || users[1] != (User{ID: 2, Name: "b"}) {
+		t.Errorf("unexpected users %v", users)
+	}
+
+	var user User
+	if err = session.Builder()(query).QueryRowStruct(&user); err != nil {
+		t.Fatal(err)
+	}
+
+	if user != (User{ID: 1, Name: "a"}) {
+		t.Errorf("unexpected user %v", user)
+	}
+
+	err = session.Builder()(query).QueryRowStruct(&user)
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestSQLStats(t *testing.T) {
+	t.Parallel()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+
+	instance, err := octobe.New(postgres.OpenWithConn(db))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = instance.Begin(context.Background(), postgres.WithSQLTxOptions(postgres.SQLTxOptions{})); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := instance.Stats()
+	if stats.ActiveSessions != 1 {
+		t.Fatalf("expected 1 active session, got %d", stats.ActiveSessions)
+	}
+	dbStats, ok := stats.Driver.(sql.DBStats)
+	if !ok {
+		t.Fatalf("expected sql.DBStats, got %T", stats.Driver)
+	}
+	if dbStats.InUse != 1 {
+		t.Fatalf("expected 1 connection in use, got %d", dbStats.InUse)
+	}
+}
+
+func TestSQLSegmentArgumentsFromStruct(t *testing.T) {
+	t.Parallel()
+
+	type User struct {
+		ID    int64  `db:"id"`
+		Email string `db:"email"`
+	}
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE users SET email = $1 WHERE id = $2 AND email <> $1")).
+		WithArgs("a@example.com", int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	instance, err := octobe.New(postgres.OpenWithConn(db))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := instance.Begin(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = session.Builder()("UPDATE users SET email = :email WHERE id = :id AND email <> :email").
+		ArgumentsFromStruct(User{ID: 1, Email: "a@example.com"}).
+		Exec()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ptr *User
+	_, err = session.Builder()("UPDATE users SET email = :email").ArgumentsFromStruct(ptr).Exec()
+	if err == nil {
+		t.Error("expected an error binding a nil struct pointer")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestSQLSegmentTimeout(t *testing.T) {
+	t.Parallel()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	query := "UPDATE users SET name = $1"
+	mock.ExpectExec(regexp.QuoteMeta(query)).WillDelayFor(time.Second).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(query)).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	instance, err := octobe.New(postgres.OpenWithConn(db))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := instance.Begin(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = session.Builder()(query).Arguments("a").Timeout(10 * time.Millisecond).Exec()
+	if !errors.Is(err, sqlmock.ErrCancelled) && !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the statement to time out, got %v", err)
+	}
+
+	// The timeout applies to its segment only, the session stays usable.
+	if _, err = session.Builder()(query).Arguments("b").Exec(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestSQLExecResult(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec("delete FROM products").WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec("WITH stale AS").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	instance, err := octobe.New(postgres.OpenWithConn(db))
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := instance.Begin(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := session.Builder()("-- purge\n/* all */ delete FROM products").Exec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.RowsAffected != 3 || result.Command != "DELETE" || !result.Delete() {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	result, err = session.Builder()("WITH stale AS (SELECT id FROM products) DELETE FROM products USING stale").Exec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.RowsAffected != 1 || result.Command != "" {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestSQLRewriter(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE tenant_1.products SET name = $1")).WithArgs("chair").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	instance, err := octobe.New(postgres.OpenWithConn(db))
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := instance.Begin(context.Background(), postgres.WithSQLRewriter(func(query string, args []any) (string, []any) {
+		return strings.ReplaceAll(query, "{schema}", "tenant_1"), args
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = session.Builder()("UPDATE {schema}.products SET name = $1").Arguments("chair").Exec(); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestSQLReadOnlyTx(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	ob, err := octobe.New(postgres.OpenWithConn(db))
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := ob.Begin(context.Background(), postgres.WithSQLReadOnlyTx())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = session.Builder()("DELETE FROM orders").Exec(); !errors.Is(err, postgres.ErrReadOnly) {
+		t.Fatalf("expected ErrReadOnly, got %v", err)
+	}
+	if err = session.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSQLSegmentNamedArguments(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO products (id, name) VALUES ($1, $2)")).WithArgs(1, "a").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	ob, err := octobe.New(postgres.OpenWithConn(db))
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := ob.Begin(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = session.Builder()("INSERT INTO products (id, name) VALUES (:id, @name)").
+		NamedArguments(map[string]any{"id": 1, "name": "a"}).
+		Exec()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = session.Builder()("DELETE FROM products WHERE id = :id").NamedArguments(map[string]any{"sku": 1}).Exec()
+	if err == nil || !strings.Contains(err.Error(), ":id") {
+		t.Fatalf("expected an error for the missing parameter, got %v", err)
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSQLSegmentCloneAndRows(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for range 2 {
+		mock.ExpectQuery("SELECT id FROM products").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+	}
+
+	ob, err := octobe.New(postgres.OpenWithConn(db))
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := ob.Begin(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	segment := session.Builder()("SELECT id FROM products")
+	for _, s := range []postgres.Segment{segment, segment.Clone()} {
+		var ids []int
+		for row, err := range s.Rows() {
+			if err != nil {
+				t.Fatal(err)
+			}
+			var id int
+			if err = row.Scan(&id); err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, id)
+		}
+		if len(ids) != 2 || ids[0] != 1 || ids[1] != 2 {
+			t.Fatalf("expected ids 1 and 2, got %v", ids)
+		}
+	}
+
+	if err = segment.Query(func(postgres.Rows) error { return nil }); !errors.Is(err, octobe.ErrAlreadyUsed) {
+		t.Fatalf("expected ErrAlreadyUsed, got %v", err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSQLPreparedStatement(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	prepared := mock.ExpectPrepare("UPDATE stock")
+	prepared.ExpectExec().WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+	prepared.ExpectQuery().WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"reserved"}).AddRow(5))
+	prepared.WillBeClosed()
+	mock.ExpectCommit()
+
+	ob, err := octobe.New(postgres.OpenWithConn(db))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ob.StartTransaction(context.Background(), func(session octobe.BuilderSession[postgres.Builder]) error {
+		stmt, err := postgres.Prepare(session, "", "UPDATE stock SET reserved = reserved + 1 WHERE id = $1 RETURNING reserved")
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		if _, err = stmt.Segment().Arguments(1).Exec(); err != nil {
+			return err
+		}
+		return stmt.Segment().Arguments(2).Query(func(rows postgres.Rows) error {
+			for rows.Next() {
+				var reserved int
+				if err := rows.Scan(&reserved); err != nil {
+					return err
+				}
+				if reserved != 5 {
+					t.Errorf("expected 5 reserved, got %d", reserved)
+				}
+			}
+			return rows.Err()
+		})
+	}, postgres.WithSQLTxOptions(postgres.SQLTxOptions{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSQLPing(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	failed := errors.New("connection refused")
+	mock.ExpectPing()
+	mock.ExpectPing().WillReturnError(failed)
+
+	ob, err := octobe.New(postgres.OpenWithConn(db))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ob.Ping(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err = ob.Ping(context.Background()); !errors.Is(err, failed) {
+		t.Fatalf("expected the ping to fail, got %v", err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSQLSegmentSQLNamedArguments(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE products SET name = $1 WHERE id = $2")).WithArgs("chair", 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	ob, err := octobe.New(postgres.OpenWithConn(db))
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := ob.Begin(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = session.Builder()("UPDATE products SET name = @name WHERE id = @id").
+		Arguments(sql.Named("id", 1), sql.Named("name", "chair")).
+		Exec()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = session.Builder()("UPDATE products SET name = @name WHERE id = $2").Arguments(sql.Named("name", "chair"), 1).Exec()
+	if err == nil {
+		t.Fatal("expected an error mixing named and positional arguments")
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSQLStatementCache(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	selectName := mock.ExpectPrepare("SELECT name FROM products")
+	selectName.ExpectQuery().WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("chair"))
+	selectName.ExpectQuery().WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("table"))
+	selectName.WillBeClosed()
+	update := mock.ExpectPrepare("UPDATE products")
+	update.ExpectExec().WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+	update.WillBeClosed()
+	mock.ExpectClose()
+
+	ctx := context.Background()
+	ob, err := octobe.New(postgres.OpenWithConn(db, postgres.WithSQLStatementCache(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := ob.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The second query reuses the statement prepared for the first.
+	for _, id := range []int{1, 2} {
+		var name string
+		if err = session.Builder()("SELECT name FROM products WHERE id = $1").Arguments(id).QueryRow(&name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Another query evicts the statement, which is closed, and the driver closes the rest.
+	if _, err = session.Builder()("UPDATE products SET stock = stock - 1 WHERE id = $1").Arguments(1).Exec(); err != nil {
+		t.Fatal(err)
+	}
+	if err = ob.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSQLStatementCachePrepareUnlocked(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.MatchExpectationsInOrder(false)
+
+	mock.ExpectPrepare("SELECT name FROM products").
+		ExpectQuery().WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("chair"))
+	// database/sql prepares the cached statement again on the connection it runs on while the first one is busy.
+	mock.ExpectPrepare("SELECT name FROM products").
+		ExpectQuery().WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("chair"))
+	mock.ExpectPrepare("SELECT count").WillDelayFor(200 * time.Millisecond).
+		ExpectQuery().WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	ctx := context.Background()
+	ob, err := octobe.New(postgres.OpenWithConn(db, postgres.WithSQLStatementCache(2)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := ob.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var name string
+	if err = session.Builder()("SELECT name FROM products").QueryRow(&name); err != nil {
+		t.Fatal(err)
+	}
+
+	// A slow prepare of another query does not hold up the cached statement.
+	counted := make(chan error)
+	go func() {
+		var count int
+		counted <- session.Builder()("SELECT count(*) FROM products").QueryRow(&count)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	if err = session.Builder()("SELECT name FROM products").QueryRow(&name); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err = <-counted:
+		t.Fatalf("expected the cached statement to run while the other query is prepared, got %v", err)
+	default:
+	}
+	if err = <-counted; err != nil {
+		t.Fatal(err)
+	}
+
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOpenSQL(t *testing.T) {
+	ctx := context.Background()
+
+	if _, err := octobe.New(postgres.OpenSQL("postgres://octobe@127.0.0.1:invalid/octobe")); err == nil {
+		t.Fatal("expected an invalid dsn to fail")
+	}
+
+	ob, err := octobe.New(postgres.OpenSQL("postgres://octobe@127.0.0.1:1/octobe?connect_timeout=1",
+		postgres.WithSQLMaxOpenConns(3),
+		postgres.WithSQLMaxIdleConns(2),
+		postgres.WithSQLConnMaxLifetime(time.Minute),
+		postgres.WithSQLConnMaxIdleTime(time.Second),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stats, ok := ob.Stats().Driver.(sql.DBStats)
+	if !ok {
+		t.Fatalf("expected sql.DBStats, got %T", ob.Stats().Driver)
+	}
+	if stats.MaxOpenConnections != 3 {
+		t.Fatalf("expected 3 max open connections, got %d", stats.MaxOpenConnections)
+	}
+
+	// The database connects when the first statement runs.
+	session, err := ob.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = session.Builder()("SELECT 1").Exec(); err == nil {
+		t.Fatal("expected the statement to fail to connect")
+	}
+	if err = ob.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,233 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/ponrove/octobe"
+)
+
+// ErrNoDBTX is returned by NewDBTX and NewSQLDBTX for a session of another driver than the one they adapt.
+var ErrNoDBTX = errors.New("session does not belong to a driver the DBTX adapts")
+
+// DBTX is the interface sqlc generates for queries of the pgx/v5 driver, the generated New function accepts it. Batch
+// and copy methods are only required by sqlc if queries use :batch or :copyfrom.
+type DBTX interface {
+	Exec(ctx context.Context, query string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, query string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, query string, args ...any) pgx.Row
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+}
+
+// SQLDBTX is the interface sqlc generates for queries of the database/sql driver.
+type SQLDBTX interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// NewDBTX returns a DBTX for code generated by sqlc that runs its queries in session of the pgx or pgxpool driver, in
+// its transaction if it has one, so queries of sqlc and of segments can be mixed in one unit of work:
+//
+//	db, err := postgres.NewDBTX(session)
+//	author, err := sqlcdb.New(db).GetAuthor(ctx, id)
+//
+// Exec, Query and QueryRow invoke the query hooks of the instance. The context passed by the generated code cancels the
+// query, the values the session hooks added to the context of the session are visible to the query hooks as well.
+// Ending the session is left to octobe, the DBTX must not be used after the session is committed or rolled back.
+func NewDBTX(session octobe.BuilderSession[Builder]) (DBTX, error) {
+	var current any = session
+	for {
+		switch s := current.(type) {
+		case *pgxSession:
+			if s.tx != nil {
+				return &pgxDBTX{ctx: s.ctx, conn: s.tx}, nil
+			}
+			return &pgxDBTX{ctx: s.ctx, conn: s.d.conn}, nil
+		case *pgxpoolSession:
+			if s.tx != nil {
+				return &pgxDBTX{ctx: s.ctx, conn: s.tx}, nil
+			}
+			return &pgxDBTX{ctx: s.ctx, conn: s.d.pool}, nil
+		case sessionUnwrapper:
+			current = s.Unwrap()
+		default:
+			return nil, ErrNoDBTX
+		}
+	}
+}
+
+// NewSQLDBTX returns a SQLDBTX for code generated by sqlc that runs its queries in session of the database/sql driver,
+// see NewDBTX. The rows of QueryContext and the statements of PrepareContext are not reported to the query hooks.
+func NewSQLDBTX(session octobe.BuilderSession[Builder]) (SQLDBTX, error) {
+	var current any = session
+	for {
+		switch s := current.(type) {
+		case *sqlSession:
+			if s.tx != nil {
+				return &sqlDBTX{ctx: s.ctx, conn: s.tx}, nil
+			}
+			return &sqlDBTX{ctx: s.ctx, conn: s.d.sqlDB}, nil
+		case sessionUnwrapper:
+			current = s.Unwrap()
+		default:
+			return nil, ErrNoDBTX
+		}
+	}
+}
+
+// sessionContext is the context of a query, cancelled by the context of the caller and carrying the values of the
+// context of the session as well.
+type sessionContext struct {
+	context.Context
+	session context.Context
+}
+
+// Value returns the value of the context of the caller for key, or of the context of the session if it has none.
+func (c sessionContext) Value(key any) any {
+	if value := c.Context.Value(key); value != nil {
+		return value
+	}
+	return c.session.Value(key)
+}
+
+// pgxDBTX runs the queries of sqlc on the transaction or connection of a pgx or pgxpool session.
+type pgxDBTX struct {
+	ctx  context.Context
+	conn DBTX
+}
+
+// Ensure pgxDBTX implements the DBTX interface.
+var _ DBTX = &pgxDBTX{}
+
+// context returns the context for a query with ctx of the caller.
+func (d *pgxDBTX) context(ctx context.Context) context.Context {
+	return sessionContext{Context: ctx, session: d.ctx}
+}
+
+// Exec executes query.
+func (d *pgxDBTX) Exec(ctx context.Context, query string, args ...any) (pgconn.CommandTag, error) {
+	ctx, done := octobe.BeginQuery(d.context(ctx), octobe.OperationExec, query, args)
+	tag, err := d.conn.Exec(ctx, query, args...)
+	done(tag.RowsAffected(), err)
+	return tag, err
+}
+
+// Query executes query, the query hooks see it finish when the rows are closed.
+func (d *pgxDBTX) Query(ctx context.Context, query string, args ...any) (pgx.Rows, error) {
+	ctx, done := octobe.BeginQuery(d.context(ctx), octobe.OperationQuery, query, args)
+	rows, err := d.conn.Query(ctx, query, args...)
+	if err != nil {
+		done(-1, err)
+		return nil, err
+	}
+	return &dbtxRows{Rows: rows, done: done}, nil
+}
+
+// QueryRow executes query, the query hooks see it finish when the row is scanned.
+func (d *pgxDBTX) QueryRow(ctx context.Context, query string, args ...any) pgx.Row {
+	ctx, done := octobe.BeginQuery(d.context(ctx), octobe.OperationQueryRow, query, args)
+	return &dbtxRow{row: d.conn.QueryRow(ctx, query, args...), done: done}
+}
+
+// CopyFrom copies the rows of rowSrc into tableName.
+func (d *pgxDBTX) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	return d.conn.CopyFrom(d.context(ctx), tableName, columnNames, rowSrc)
+}
+
+// SendBatch sends the queries of b.
+func (d *pgxDBTX) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	return d.conn.SendBatch(d.context(ctx), b)
+}
+
+// dbtxRows reports the query of rows to the query hooks once they are closed.
+type dbtxRows struct {
+	pgx.Rows
+	done  func(rows int64, err error)
+	count int64
+}
+
+// Next prepares the next row for reading and counts it.
+func (r *dbtxRows) Next() bool {
+	if !r.Rows.Next() {
+		return false
+	}
+	r.count++
+	return true
+}
+
+// Close closes the rows and ends the query.
+func (r *dbtxRows) Close() {
+	r.Rows.Close()
+	if r.done != nil {
+		r.done(r.count, r.Rows.Err())
+		r.done = nil
+	}
+}
+
+// dbtxRow reports the query of row to the query hooks once it is scanned.
+type dbtxRow struct {
+	row  pgx.Row
+	done func(rows int64, err error)
+}
+
+// Scan reads the row into dest and ends the query.
+func (r *dbtxRow) Scan(dest ...any) error {
+	err := r.row.Scan(dest...)
+	if r.done != nil {
+		r.done(queryRowCount(err), err)
+		r.done = nil
+	}
+	return err
+}
+
+// sqlDBTX runs the queries of sqlc on the transaction or database of a database/sql session.
+type sqlDBTX struct {
+	ctx  context.Context
+	conn SQLDBTX
+}
+
+// Ensure sqlDBTX implements the SQLDBTX interface.
+var _ SQLDBTX = &sqlDBTX{}
+
+// context returns the context for a query with ctx of the caller.
+func (d *sqlDBTX) context(ctx context.Context) context.Context {
+	return sessionContext{Context: ctx, session: d.ctx}
+}
+
+// ExecContext executes query.
+func (d *sqlDBTX) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, done := octobe.BeginQuery(d.context(ctx), octobe.OperationExec, query, args)
+	result, err := d.conn.ExecContext(ctx, query, args...)
+	rows := int64(-1)
+	if err == nil {
+		if affected, err := result.RowsAffected(); err == nil {
+			rows = affected
+		}
+	}
+	done(rows, err)
+	return result, err
+}
+
+// PrepareContext prepares query.
+func (d *sqlDBTX) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return d.conn.PrepareContext(d.context(ctx), query)
+}
+
+// QueryContext executes query.
+func (d *sqlDBTX) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return d.conn.QueryContext(d.context(ctx), query, args...)
+}
+
+// QueryRowContext executes query, the query hooks see it finish before the row is scanned.
+func (d *sqlDBTX) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	ctx, done := octobe.BeginQuery(d.context(ctx), octobe.OperationQueryRow, query, args)
+	row := d.conn.QueryRowContext(ctx, query, args...)
+	done(-1, row.Err())
+	return row
+}
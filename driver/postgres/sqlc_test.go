@@ -0,0 +1,176 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/driver/postgres"
+	"github.com/stretchr/testify/require"
+)
+
+// authorQueries mirrors the code sqlc generates for the pgx/v5 driver.
+type authorQueries struct {
+	db postgres.DBTX
+}
+
+func (q *authorQueries) CreateAuthor(ctx context.Context, name string) error {
+	_, err := q.db.Exec(ctx, "INSERT INTO authors (name) VALUES ($1)", name)
+	return err
+}
+
+func (q *authorQueries) GetAuthor(ctx context.Context, id int64) (string, error) {
+	var name string
+	err := q.db.QueryRow(ctx, "SELECT name FROM authors WHERE id = $1", id).Scan(&name)
+	return name, err
+}
+
+func (q *authorQueries) ListAuthors(ctx context.Context) ([]string, error) {
+	rows, err := q.db.Query(ctx, "SELECT name FROM authors ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+type dbtxHook struct {
+	events []octobe.QueryEvent
+}
+
+func (h *dbtxHook) BeforeQuery(ctx context.Context, _ *octobe.QueryEvent) context.Context {
+	return ctx
+}
+
+func (h *dbtxHook) AfterQuery(_ context.Context, event *octobe.QueryEvent) {
+	h.events = append(h.events, *event)
+}
+
+func TestDBTX(t *testing.T) {
+	ctx := context.Background()
+	mock, err := pgxmock.NewConn()
+	require.NoError(t, err)
+	defer mock.Close(ctx)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO authors").WithArgs("ada").WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectQuery("SELECT name FROM authors WHERE id").WithArgs(int64(1)).
+		WillReturnRows(pgxmock.NewRows([]string{"name"}).AddRow("ada"))
+	mock.ExpectQuery("SELECT name FROM authors ORDER BY name").
+		WillReturnRows(pgxmock.NewRows([]string{"name"}).AddRow("ada").AddRow("grace"))
+	mock.ExpectExec("UPDATE authors").WillReturnResult(pgxmock.NewResult("UPDATE", 2))
+	mock.ExpectCommit()
+
+	hook := &dbtxHook{}
+	ob, err := octobe.New(postgres.OpenPGXWithConn(mock), octobe.WithQueryHook(hook))
+	require.NoError(t, err)
+
+	err = ob.StartTransaction(ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+		db, err := postgres.NewDBTX(session)
+		if err != nil {
+			return err
+		}
+		queries := &authorQueries{db: db}
+		if err = queries.CreateAuthor(ctx, "ada"); err != nil {
+			return err
+		}
+		name, err := queries.GetAuthor(ctx, 1)
+		if err != nil {
+			return err
+		}
+		require.Equal(t, "ada", name)
+		names, err := queries.ListAuthors(ctx)
+		if err != nil {
+			return err
+		}
+		require.Equal(t, []string{"ada", "grace"}, names)
+
+		// Segments run in the same transaction.
+		_, err = session.Builder()("UPDATE authors SET active = true").Exec()
+		return err
+	}, postgres.WithPGXTxOptions(postgres.PGXTxOptions{}))
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	require.Len(t, hook.events, 4)
+	require.Equal(t, octobe.OperationExec, hook.events[0].Operation)
+	require.Equal(t, int64(1), hook.events[0].Rows)
+	require.Equal(t, octobe.OperationQueryRow, hook.events[1].Operation)
+	require.Equal(t, int64(1), hook.events[1].Rows)
+	require.Equal(t, octobe.OperationQuery, hook.events[2].Operation)
+	require.Equal(t, int64(2), hook.events[2].Rows)
+}
+
+func TestDBTXNoRows(t *testing.T) {
+	ctx := context.Background()
+	mock, err := pgxmock.NewConn()
+	require.NoError(t, err)
+	defer mock.Close(ctx)
+
+	mock.ExpectQuery("SELECT name FROM authors WHERE id").WithArgs(int64(2)).
+		WillReturnRows(pgxmock.NewRows([]string{"name"}))
+
+	ob, err := octobe.New(postgres.OpenPGXWithConn(mock))
+	require.NoError(t, err)
+	session, err := ob.Begin(ctx)
+	require.NoError(t, err)
+	db, err := postgres.NewDBTX(session)
+	require.NoError(t, err)
+
+	_, err = (&authorQueries{db: db}).GetAuthor(ctx, 2)
+	require.ErrorIs(t, err, pgx.ErrNoRows)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLDBTX(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO authors").WithArgs("ada").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT name FROM authors").WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("ada"))
+	mock.ExpectRollback()
+
+	ctx := context.Background()
+	ob, err := octobe.New(postgres.OpenWithConn(db))
+	require.NoError(t, err)
+	session, err := ob.Begin(ctx, postgres.WithSQLTxOptions(postgres.SQLTxOptions{}))
+	require.NoError(t, err)
+
+	dbtx, err := postgres.NewSQLDBTX(session)
+	require.NoError(t, err)
+	_, err = dbtx.ExecContext(ctx, "INSERT INTO authors (name) VALUES ($1)", "ada")
+	require.NoError(t, err)
+	var name string
+	require.NoError(t, dbtx.QueryRowContext(ctx, "SELECT name FROM authors WHERE id = $1", 1).Scan(&name))
+	require.Equal(t, "ada", name)
+	require.NoError(t, session.Rollback())
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	_, err = postgres.NewDBTX(session)
+	require.ErrorIs(t, err, postgres.ErrNoDBTX)
+}
+
+func TestNewSQLDBTXUnsupported(t *testing.T) {
+	mock, err := pgxmock.NewConn()
+	require.NoError(t, err)
+	ob, err := octobe.New(postgres.OpenPGXWithConn(mock))
+	require.NoError(t, err)
+	session, err := ob.Begin(context.Background())
+	require.NoError(t, err)
+
+	_, err = postgres.NewSQLDBTX(session)
+	require.ErrorIs(t, err, postgres.ErrNoDBTX)
+}
@@ -0,0 +1,42 @@
+package postgres
+
+import "github.com/ponrove/octobe"
+
+// failer is implemented by the segments of the drivers, it records an error returned when the segment is executed.
+type failer interface {
+	fail(err error)
+}
+
+// Ensure the segments can record errors.
+var (
+	_ failer = &pgxSegment{}
+	_ failer = &pgxpoolSegment{}
+	_ failer = &sqlSegment{}
+)
+
+// From builds a segment for the query and arguments of a query builder, like squirrel or sqltpl, an error building the
+// query is returned when the segment is executed. Builders must produce the $1 placeholders of postgres, like squirrel
+// with its Dollar placeholder format.
+func (b Builder) From(q octobe.Sqlizer) Segment {
+	query, args, err := q.ToSql()
+	segment := b(query).Arguments(args...)
+	if f, ok := segment.(failer); ok && err != nil {
+		f.fail(err)
+	}
+	return segment
+}
+
+// fail records err to be returned when the segment is executed.
+func (s *pgxSegment) fail(err error) {
+	s.err = err
+}
+
+// fail records err to be returned when the segment is executed.
+func (s *pgxpoolSegment) fail(err error) {
+	s.err = err
+}
+
+// fail records err to be returned when the segment is executed.
+func (s *sqlSegment) fail(err error) {
+	s.err = err
+}
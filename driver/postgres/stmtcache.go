@@ -0,0 +1,183 @@
+package postgres
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"sync"
+)
+
+// statementCache prepares queries on a connection once and reuses the prepared statements, keeping at most size of
+// them. When the cache is full, the least recently used statement is deallocated to make room.
+type statementCache struct {
+	mu    sync.Mutex
+	size  int
+	next  uint64
+	order *list.List               // Statements from most to least recently used
+	items map[string]*list.Element // Statements by query
+}
+
+// cachedStatement is a query prepared under name.
+type cachedStatement struct {
+	query string
+	name  string
+}
+
+// newStatementCache creates a statement cache holding at most size statements.
+func newStatementCache(size int) *statementCache {
+	return &statementCache{
+		size:  size,
+		order: list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// prepare returns the name of the prepared statement for query on conn, preparing it first if it is not cached. The
+// name can be passed to conn in place of the query.
+func (c *statementCache) prepare(ctx context.Context, conn PGXConn, query string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[query]; ok {
+		c.order.MoveToFront(elem)
+		return elem.Value.(*cachedStatement).name, nil
+	}
+
+	c.next++
+	name := "octobe_stmt_" + strconv.FormatUint(c.next, 10)
+	if _, err := conn.Prepare(ctx, name, query); err != nil {
+		return "", err
+	}
+
+	c.items[query] = c.order.PushFront(&cachedStatement{query: query, name: name})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		stmt := c.order.Remove(oldest).(*cachedStatement)
+		delete(c.items, stmt.query)
+		// A statement that cannot be deallocated now is released with the connection at the latest.
+		_ = conn.Deallocate(ctx, stmt.name)
+	}
+	return name, nil
+}
+
+// reset empties the cache without deallocating the statements, for a connection that has been replaced and took them
+// with it.
+func (c *statementCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.order.Init()
+	clear(c.items)
+}
+
+// sqlStatementCache prepares queries on the database of the database/sql driver once and reuses the statements,
+// keeping at most size of them. When the cache is full, the least recently used statement is closed to make room, once
+// the segments executing it are done.
+type sqlStatementCache struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List               // Statements from most to least recently used
+	items map[string]*list.Element // Statements by query
+}
+
+// sqlCachedStatement is a statement prepared for query, used by refs segments.
+type sqlCachedStatement struct {
+	query   string
+	stmt    *sql.Stmt
+	refs    int
+	evicted bool
+}
+
+// newSQLStatementCache creates a statement cache of the database/sql driver holding at most size statements.
+func newSQLStatementCache(size int) *sqlStatementCache {
+	return &sqlStatementCache{
+		size:  size,
+		order: list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// acquire returns the statement for query on db, preparing it first if it is not cached. The returned function must be
+// called once the statement is no longer used, an evicted statement is only closed then. Statements are prepared
+// without holding the lock, so a slow prepare does not block the segments of other queries. If another segment prepared
+// the same query meanwhile, its statement is used and the duplicate is closed.
+func (c *sqlStatementCache) acquire(ctx context.Context, db SQL, query string) (*sql.Stmt, func(), error) {
+	if cached := c.lookup(query); cached != nil {
+		return cached.stmt, func() { c.release(cached) }, nil
+	}
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[query]; ok {
+		_ = stmt.Close()
+		cached := c.use(elem)
+		return cached.stmt, func() { c.release(cached) }, nil
+	}
+
+	cached := &sqlCachedStatement{query: query, stmt: stmt, refs: 1}
+	c.items[query] = c.order.PushFront(cached)
+	for c.order.Len() > c.size {
+		oldest := c.order.Remove(c.order.Back()).(*sqlCachedStatement)
+		delete(c.items, oldest.query)
+		oldest.evicted = true
+		if oldest.refs == 0 {
+			// An error closing the statement leaves it to be released with its connections.
+			_ = oldest.stmt.Close()
+		}
+	}
+	return stmt, func() { c.release(cached) }, nil
+}
+
+// lookup returns the cached statement for query marked as used, nil if it is not cached.
+func (c *sqlStatementCache) lookup(query string) *sqlCachedStatement {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[query]
+	if !ok {
+		return nil
+	}
+	return c.use(elem)
+}
+
+// use marks the statement of elem as most recently used and adds a use of it. The lock must be held.
+func (c *sqlStatementCache) use(elem *list.Element) *sqlCachedStatement {
+	c.order.MoveToFront(elem)
+	cached := elem.Value.(*sqlCachedStatement)
+	cached.refs++
+	return cached
+}
+
+// release marks a use of cached as done, closing it if it was evicted and this was its last use.
+func (c *sqlStatementCache) release(cached *sqlCachedStatement) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached.refs--
+	if cached.evicted && cached.refs == 0 {
+		_ = cached.stmt.Close()
+	}
+}
+
+// close closes the statements of the cache and empties it. Statements still in use are closed by database/sql once
+// their segments are done.
+func (c *sqlStatementCache) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var err error
+	for elem := c.order.Front(); elem != nil; elem = elem.Next() {
+		err = errors.Join(err, elem.Value.(*sqlCachedStatement).stmt.Close())
+	}
+	c.order.Init()
+	clear(c.items)
+	return err
+}
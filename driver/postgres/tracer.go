@@ -0,0 +1,38 @@
+package postgres
+
+import (
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/multitracer"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// WithTracer sets a pgx.QueryTracer, like the tracers of pgx-zap or otelpgx, on the connection of OpenPGX and
+// OpenPGXWithOptions, so existing tracing keeps working next to the query hooks of octobe. The tracer may implement the
+// other tracer interfaces of pgx as well, like pgx.BatchTracer. A tracer given several times or already set in the
+// DSN config is combined with the others, they are called in the order they were added. A connection passed to
+// OpenPGXWithConn already has its tracer.
+func WithTracer(tracer pgx.QueryTracer) PGXOption {
+	return func(cfg *pgxOptions) {
+		cfg.connConfig = append(cfg.connConfig, func(config *pgx.ConnConfig) {
+			addTracer(config, tracer)
+		})
+	}
+}
+
+// WithPoolTracer sets a pgx.QueryTracer on the connections of the pool created by OpenPGXPool or
+// OpenPGXPoolWithConfig, see WithTracer. Tracers implementing pgxpool.AcquireTracer or pgxpool.ReleaseTracer also
+// trace the connections the pool hands out. It has no effect for a pool passed to OpenPGXPoolWithPool.
+func WithPoolTracer(tracer pgx.QueryTracer) PGXPoolOption {
+	return tunePool(func(config *pgxpool.Config) {
+		addTracer(config.ConnConfig, tracer)
+	})
+}
+
+// addTracer adds tracer to the tracer of config, combining them if config already has one.
+func addTracer(config *pgx.ConnConfig, tracer pgx.QueryTracer) {
+	if config.Tracer == nil {
+		config.Tracer = tracer
+		return
+	}
+	config.Tracer = multitracer.New(config.Tracer, tracer)
+}
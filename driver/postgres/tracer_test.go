@@ -0,0 +1,66 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/driver/postgres"
+	"github.com/stretchr/testify/require"
+)
+
+// connectTracer records the connection attempts it traced.
+type connectTracer struct {
+	name     string
+	attempts *[]string
+}
+
+func (t connectTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, _ pgx.TraceQueryStartData) context.Context {
+	return ctx
+}
+
+func (t connectTracer) TraceQueryEnd(context.Context, *pgx.Conn, pgx.TraceQueryEndData) {}
+
+func (t connectTracer) TraceConnectStart(ctx context.Context, _ pgx.TraceConnectStartData) context.Context {
+	return ctx
+}
+
+func (t connectTracer) TraceConnectEnd(_ context.Context, data pgx.TraceConnectEndData) {
+	if data.Err != nil {
+		*t.attempts = append(*t.attempts, t.name)
+	}
+}
+
+func TestWithTracer(t *testing.T) {
+	ctx := context.Background()
+	var attempts []string
+
+	_, err := octobe.New(postgres.OpenPGX(ctx, "postgres://octobe@127.0.0.1:1/octobe?connect_timeout=1",
+		postgres.WithTracer(connectTracer{name: "zap", attempts: &attempts}),
+		postgres.WithTracer(connectTracer{name: "otel", attempts: &attempts}),
+	))
+	require.Error(t, err)
+	require.Equal(t, []string{"zap", "otel"}, attempts)
+}
+
+func TestWithPoolTracer(t *testing.T) {
+	ctx := context.Background()
+	var attempts []string
+
+	config, err := pgxpool.ParseConfig("postgres://octobe@127.0.0.1:1/octobe?connect_timeout=1")
+	require.NoError(t, err)
+	config.ConnConfig.Tracer = connectTracer{name: "config", attempts: &attempts}
+
+	ob, err := octobe.New(postgres.OpenPGXPoolWithConfig(ctx, config,
+		postgres.WithPoolTracer(connectTracer{name: "otel", attempts: &attempts})))
+	require.NoError(t, err)
+	defer ob.Close(ctx)
+
+	session, err := ob.Begin(ctx)
+	require.NoError(t, err)
+	_, err = session.Builder()("SELECT 1").Exec()
+	require.Error(t, err)
+	require.Equal(t, []string{"config", "otel"}, attempts)
+}
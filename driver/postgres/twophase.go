@@ -0,0 +1,161 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/ponrove/octobe"
+)
+
+// errPrepareNoTransaction is returned when preparing a session without a transaction.
+var errPrepareNoTransaction = errors.New("cannot prepare without transaction")
+
+// preparedTransactionsQuery lists the prepared transactions of the current database with an id starting with $1.
+const preparedTransactionsQuery = "SELECT gid FROM pg_prepared_xacts WHERE database = current_database() AND left(gid, length($1)) = $1 ORDER BY prepared"
+
+// Ensure the drivers support two-phase commits. The server must allow them with max_prepared_transactions.
+var (
+	_ octobe.Preparer          = &pgxSession{}
+	_ octobe.Preparer          = &pgxpoolSession{}
+	_ octobe.Preparer          = &sqlSession{}
+	_ octobe.TwoPhaseCommitter = &pgxConn{}
+	_ octobe.TwoPhaseCommitter = &pgxpoolConn{}
+	_ octobe.TwoPhaseCommitter = &sqlConn{}
+)
+
+// Prepare prepares the transaction of the session with PREPARE TRANSACTION and releases its connection.
+func (s *pgxSession) Prepare(id string) error {
+	if s.cfg.txOptions == nil {
+		return errPrepareNoTransaction
+	}
+	if err := s.state.check(); err != nil {
+		return err
+	}
+	s.state.end(false)
+	return pgxPrepare(s.ctx, s.tx, id)
+}
+
+// Prepare prepares the transaction of the session with PREPARE TRANSACTION and releases its connection.
+func (s *pgxpoolSession) Prepare(id string) error {
+	if s.cfg.txOptions == nil {
+		return errPrepareNoTransaction
+	}
+	if err := s.state.check(); err != nil {
+		return err
+	}
+	defer s.releaseSlot()
+	s.state.end(false)
+	return pgxPrepare(s.ctx, s.tx, id)
+}
+
+// Prepare prepares the transaction of the session with PREPARE TRANSACTION and releases its connection.
+func (s *sqlSession) Prepare(id string) (err error) {
+	if s.cfg.txOptions == nil {
+		return errPrepareNoTransaction
+	}
+	if err = s.state.check(); err != nil {
+		return err
+	}
+	s.state.end(false)
+
+	statement := "PREPARE TRANSACTION " + quoteLiteral(id)
+	ctx, done := octobe.BeginQuery(s.ctx, octobe.OperationExec, statement, nil)
+	defer func() { done(-1, err) }()
+
+	_, err = s.tx.ExecContext(ctx, statement)
+	// The transaction is no longer associated with the connection once it is prepared, ending it only returns the
+	// connection to the pool. If preparing failed, it rolls the transaction back.
+	return errors.Join(err, s.tx.Rollback())
+}
+
+// CommitPrepared commits the prepared transaction id with COMMIT PREPARED.
+func (d *pgxConn) CommitPrepared(ctx context.Context, id string) error {
+	_, err := d.conn.Exec(ctx, "COMMIT PREPARED "+quoteLiteral(id))
+	return err
+}
+
+// RollbackPrepared rolls back the prepared transaction id with ROLLBACK PREPARED.
+func (d *pgxConn) RollbackPrepared(ctx context.Context, id string) error {
+	_, err := d.conn.Exec(ctx, "ROLLBACK PREPARED "+quoteLiteral(id))
+	return err
+}
+
+// PreparedTransactions returns the ids of the prepared transactions of the database starting with prefix.
+func (d *pgxConn) PreparedTransactions(ctx context.Context, prefix string) ([]string, error) {
+	rows, err := d.conn.Query(ctx, preparedTransactionsQuery, prefix)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+// CommitPrepared commits the prepared transaction id with COMMIT PREPARED.
+func (d *pgxpoolConn) CommitPrepared(ctx context.Context, id string) error {
+	_, err := d.pool.Exec(ctx, "COMMIT PREPARED "+quoteLiteral(id))
+	return err
+}
+
+// RollbackPrepared rolls back the prepared transaction id with ROLLBACK PREPARED.
+func (d *pgxpoolConn) RollbackPrepared(ctx context.Context, id string) error {
+	_, err := d.pool.Exec(ctx, "ROLLBACK PREPARED "+quoteLiteral(id))
+	return err
+}
+
+// PreparedTransactions returns the ids of the prepared transactions of the database starting with prefix.
+func (d *pgxpoolConn) PreparedTransactions(ctx context.Context, prefix string) ([]string, error) {
+	rows, err := d.pool.Query(ctx, preparedTransactionsQuery, prefix)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+// CommitPrepared commits the prepared transaction id with COMMIT PREPARED.
+func (d *sqlConn) CommitPrepared(ctx context.Context, id string) error {
+	_, err := d.sqlDB.ExecContext(ctx, "COMMIT PREPARED "+quoteLiteral(id))
+	return err
+}
+
+// RollbackPrepared rolls back the prepared transaction id with ROLLBACK PREPARED.
+func (d *sqlConn) RollbackPrepared(ctx context.Context, id string) error {
+	_, err := d.sqlDB.ExecContext(ctx, "ROLLBACK PREPARED "+quoteLiteral(id))
+	return err
+}
+
+// PreparedTransactions returns the ids of the prepared transactions of the database starting with prefix.
+func (d *sqlConn) PreparedTransactions(ctx context.Context, prefix string) ([]string, error) {
+	rows, err := d.sqlDB.QueryContext(ctx, preparedTransactionsQuery, prefix)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// pgxPrepare prepares tx with the given id and releases its connection.
+func pgxPrepare(ctx context.Context, tx pgx.Tx, id string) (err error) {
+	statement := "PREPARE TRANSACTION " + quoteLiteral(id)
+	queryCtx, done := octobe.BeginQuery(ctx, octobe.OperationExec, statement, nil)
+	defer func() { done(-1, err) }()
+
+	_, err = tx.Exec(queryCtx, statement)
+	// The transaction is no longer associated with the connection once it is prepared, ending it only returns the
+	// connection to the pool. If preparing failed, it rolls the transaction back.
+	return errors.Join(err, tx.Rollback(ctx))
+}
+
+// quoteLiteral quotes s as a string literal, the transaction statements do not accept parameters.
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
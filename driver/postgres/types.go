@@ -0,0 +1,57 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Types are custom types registered with the type map of every connection of the pgx and pgxpool drivers, so segments
+// scan and encode them natively, without converting arguments and destinations by hand. Fields that are empty are
+// skipped, the others are registered in the order of the fields.
+type Types struct {
+	// Types are registered as they are, for codecs of types whose OID is fixed.
+	Types []*pgtype.Type
+	// Names are loaded from the database by name, for enums, domains, composite types and the types of extensions like
+	// postgis, whose OIDs differ between databases. Types a name depends on must be listed before it, or be built in.
+	Names []string
+	// Register is called with the type map of the connection, for libraries registering their own codecs such as
+	// pgx-gofrs-uuid or pgx-shopspring-decimal:
+	//
+	//	postgres.Types{Register: func(m *pgtype.Map) { pgxdecimal.Register(m) }}
+	Register func(m *pgtype.Map)
+}
+
+// WithTypes registers types with the connection of the pgx driver once OpenPGX or OpenPGXWithOptions connected, like
+// a function passed to WithAfterConnect. A connection passed to OpenPGXWithConn is already in use, its type map can be
+// changed directly instead.
+func WithTypes(types Types) PGXOption {
+	return WithAfterConnect(types.register)
+}
+
+// WithPoolTypes registers types with every new connection of the pool created by OpenPGXPool or
+// OpenPGXPoolWithConfig, like a function passed to WithPoolAfterConnect. It has no effect for a pool passed to
+// OpenPGXPoolWithPool.
+func WithPoolTypes(types Types) PGXPoolOption {
+	return WithPoolAfterConnect(types.register)
+}
+
+// register registers the types with the type map of conn, loading the named ones from the database.
+func (t Types) register(ctx context.Context, conn *pgx.Conn) error {
+	m := conn.TypeMap()
+	for _, typ := range t.Types {
+		m.RegisterType(typ)
+	}
+	if len(t.Names) > 0 {
+		loaded, err := conn.LoadTypes(ctx, t.Names)
+		if err != nil {
+			return err
+		}
+		m.RegisterTypes(loaded)
+	}
+	if t.Register != nil {
+		t.Register(m)
+	}
+	return nil
+}
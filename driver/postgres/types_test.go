@@ -0,0 +1,41 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/driver/postgres"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithTypes(t *testing.T) {
+	ctx := context.Background()
+
+	var registered bool
+	types := postgres.Types{
+		Types: []*pgtype.Type{{Name: "order_status", OID: 90001, Codec: &pgtype.EnumCodec{}}},
+		Names: []string{"geometry"},
+		Register: func(*pgtype.Map) {
+			registered = true
+		},
+	}
+
+	ob, err := octobe.New(postgres.OpenPGXPool(ctx, "postgres://octobe@127.0.0.1:1/octobe?connect_timeout=1",
+		postgres.WithPoolTypes(types)))
+	require.NoError(t, err)
+	defer ob.Close(ctx)
+
+	// Types are only registered with connections that were established.
+	session, err := ob.Begin(ctx)
+	require.NoError(t, err)
+	_, err = session.Builder()("SELECT 1").Exec()
+	require.Error(t, err)
+	require.False(t, registered)
+
+	_, err = octobe.New(postgres.OpenPGX(ctx, "postgres://octobe@127.0.0.1:1/octobe?connect_timeout=1",
+		postgres.WithTypes(types)))
+	require.Error(t, err)
+	require.False(t, registered)
+}
@@ -0,0 +1,157 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/ponrove/octobe"
+)
+
+// UpsertBuilder builds an INSERT ... ON CONFLICT statement, see Upsert. Its methods change the builder and return it,
+// so a statement is built in a single expression. An invalid statement is reported by ToSql, and returned when the
+// segment built from it with Builder.From is executed.
+type UpsertBuilder struct {
+	table      string
+	columns    []string
+	rows       [][]any
+	conflict   []string
+	constraint string
+	nothing    bool
+	update     []upsertSet
+	returning  []string
+}
+
+// upsertSet is an assignment of the DO UPDATE clause, to the excluded value of column or to value.
+type upsertSet struct {
+	column   string
+	value    any
+	excluded bool
+}
+
+// Ensure UpsertBuilder can build a segment with Builder.From.
+var _ octobe.Sqlizer = &UpsertBuilder{}
+
+// Upsert starts an INSERT into table that updates or skips the rows which conflict with existing ones:
+//
+//	var id int64
+//	err := session.Builder().From(postgres.Upsert("products").
+//		Columns("sku", "name", "price").
+//		Values("CH-1", "chair", 49).
+//		OnConflict("sku").
+//		DoUpdate("name", "price").
+//		Returning("id")).QueryRow(&id)
+//
+// The table may be qualified by its schema, like public.products. Table and columns are quoted as identifiers, the
+// values are passed as arguments.
+func Upsert(table string) *UpsertBuilder {
+	return &UpsertBuilder{table: table}
+}
+
+// Columns sets the columns the values of the rows are inserted into.
+func (u *UpsertBuilder) Columns(columns ...string) *UpsertBuilder {
+	u.columns = columns
+	return u
+}
+
+// Values adds a row to insert, holding a value for each of the columns in order. It may be called for several rows.
+func (u *UpsertBuilder) Values(values ...any) *UpsertBuilder {
+	u.rows = append(u.rows, values)
+	return u
+}
+
+// OnConflict sets the columns of the unique index or constraint whose conflicts are handled.
+func (u *UpsertBuilder) OnConflict(columns ...string) *UpsertBuilder {
+	u.conflict = columns
+	return u
+}
+
+// OnConstraint handles the conflicts of the named unique or exclusion constraint instead of those on columns.
+func (u *UpsertBuilder) OnConstraint(name string) *UpsertBuilder {
+	u.constraint = name
+	return u
+}
+
+// DoNothing skips the rows that conflict, leaving the existing rows as they are. The conflict target is optional for
+// DO NOTHING, without one every conflict is skipped.
+func (u *UpsertBuilder) DoNothing() *UpsertBuilder {
+	u.nothing = true
+	return u
+}
+
+// DoUpdate updates the existing rows that conflict, setting columns to the values the rows would have been inserted
+// with.
+func (u *UpsertBuilder) DoUpdate(columns ...string) *UpsertBuilder {
+	for _, column := range columns {
+		u.update = append(u.update, upsertSet{column: column, excluded: true})
+	}
+	return u
+}
+
+// DoUpdateSet updates the existing rows that conflict, setting column to value.
+func (u *UpsertBuilder) DoUpdateSet(column string, value any) *UpsertBuilder {
+	u.update = append(u.update, upsertSet{column: column, value: value})
+	return u
+}
+
+// Returning returns columns of the inserted or updated rows, to be read with QueryRow or Query. Rows skipped by
+// DoNothing are not returned.
+func (u *UpsertBuilder) Returning(columns ...string) *UpsertBuilder {
+	u.returning = columns
+	return u
+}
+
+// ToSql returns the statement and its arguments, or an error if the statement is incomplete.
+func (u *UpsertBuilder) ToSql() (string, []any, error) {
+	switch {
+	case len(u.columns) == 0:
+		return "", nil, errors.New("upsert has no columns")
+	case len(u.rows) == 0:
+		return "", nil, errors.New("upsert has no values")
+	case u.nothing == (len(u.update) > 0):
+		return "", nil, errors.New("upsert needs either DoNothing or DoUpdate")
+	case len(u.update) > 0 && len(u.conflict) == 0 && u.constraint == "":
+		return "", nil, errors.New("upsert with DoUpdate needs OnConflict or OnConstraint")
+	case len(u.conflict) > 0 && u.constraint != "":
+		return "", nil, errors.New("upsert cannot have both OnConflict and OnConstraint")
+	}
+	for i, row := range u.rows {
+		if len(row) != len(u.columns) {
+			return "", nil, fmt.Errorf("upsert row %d has %d values, expected %d for the columns", i, len(row), len(u.columns))
+		}
+	}
+
+	query, args := insertStatement(insertPrefix(u.table, u.columns), len(u.columns), u.rows)
+	var b strings.Builder
+	b.WriteString(query)
+	b.WriteString(" ON CONFLICT")
+	if len(u.conflict) > 0 {
+		b.WriteString(" (" + quoteIdentifiers(u.conflict) + ")")
+	}
+	if u.constraint != "" {
+		b.WriteString(" ON CONSTRAINT " + pgx.Identifier{u.constraint}.Sanitize())
+	}
+	if u.nothing {
+		b.WriteString(" DO NOTHING")
+	} else {
+		b.WriteString(" DO UPDATE SET ")
+		for i, set := range u.update {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			column := pgx.Identifier{set.column}.Sanitize()
+			if set.excluded {
+				b.WriteString(column + " = EXCLUDED." + column)
+				continue
+			}
+			args = append(args, set.value)
+			b.WriteString(column + " = $" + strconv.Itoa(len(args)))
+		}
+	}
+	if len(u.returning) > 0 {
+		b.WriteString(" RETURNING " + quoteIdentifiers(u.returning))
+	}
+	return b.String(), args, nil
+}
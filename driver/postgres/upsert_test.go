@@ -0,0 +1,86 @@
+package postgres_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/driver/postgres"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpsert(t *testing.T) {
+	query, args, err := postgres.Upsert("public.products").
+		Columns("sku", "name", "price").
+		Values("CH-1", "chair", 49).
+		Values("TA-1", "table", 199).
+		OnConflict("sku").
+		DoUpdate("name", "price").
+		DoUpdateSet("updated_by", "importer").
+		Returning("id").
+		ToSql()
+	require.NoError(t, err)
+	require.Equal(t, `INSERT INTO "public"."products" ("sku", "name", "price") VALUES ($1, $2, $3), ($4, $5, $6)`+
+		` ON CONFLICT ("sku") DO UPDATE SET "name" = EXCLUDED."name", "price" = EXCLUDED."price", "updated_by" = $7`+
+		` RETURNING "id"`, query)
+	require.Equal(t, []any{"CH-1", "chair", 49, "TA-1", "table", 199, "importer"}, args)
+
+	query, args, err = postgres.Upsert("products").Columns("sku").Values("CH-1").DoNothing().ToSql()
+	require.NoError(t, err)
+	require.Equal(t, `INSERT INTO "products" ("sku") VALUES ($1) ON CONFLICT DO NOTHING`, query)
+	require.Equal(t, []any{"CH-1"}, args)
+
+	query, _, err = postgres.Upsert("products").Columns("sku", "name").Values("CH-1", "chair").
+		OnConstraint("products_sku_key").DoUpdate("name").ToSql()
+	require.NoError(t, err)
+	require.Equal(t, `INSERT INTO "products" ("sku", "name") VALUES ($1, $2)`+
+		` ON CONFLICT ON CONSTRAINT "products_sku_key" DO UPDATE SET "name" = EXCLUDED."name"`, query)
+}
+
+func TestUpsertInvalid(t *testing.T) {
+	for name, upsert := range map[string]*postgres.UpsertBuilder{
+		"no columns":      postgres.Upsert("products").Values(1).DoNothing(),
+		"no values":       postgres.Upsert("products").Columns("id").DoNothing(),
+		"no action":       postgres.Upsert("products").Columns("id").Values(1).OnConflict("id"),
+		"both actions":    postgres.Upsert("products").Columns("id").Values(1).OnConflict("id").DoNothing().DoUpdate("id"),
+		"no target":       postgres.Upsert("products").Columns("id").Values(1).DoUpdate("id"),
+		"both targets":    postgres.Upsert("products").Columns("id").Values(1).OnConflict("id").OnConstraint("key").DoNothing(),
+		"values mismatch": postgres.Upsert("products").Columns("id", "name").Values(1).DoNothing(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := upsert.ToSql()
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestUpsertSegment(t *testing.T) {
+	ctx := context.Background()
+	mock, err := pgxmock.NewConn()
+	require.NoError(t, err)
+	defer mock.Close(ctx)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "products" ("sku", "name") VALUES ($1, $2) ON CONFLICT ("sku") DO UPDATE`)).
+		WithArgs("CH-1", "chair").WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int64(7)))
+
+	ob, err := octobe.New(postgres.OpenPGXWithConn(mock))
+	require.NoError(t, err)
+	session, err := ob.Begin(ctx)
+	require.NoError(t, err)
+
+	var id int64
+	err = session.Builder().From(postgres.Upsert("products").
+		Columns("sku", "name").
+		Values("CH-1", "chair").
+		OnConflict("sku").
+		DoUpdate("name").
+		Returning("id")).QueryRow(&id)
+	require.NoError(t, err)
+	require.Equal(t, int64(7), id)
+
+	_, err = session.Builder().From(postgres.Upsert("products").Columns("sku")).Exec()
+	require.Error(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,68 @@
+package octobe
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNoRows is matched by the errors of queries that returned no rows where one was expected, whichever driver ran
+// them, so handlers can check for it without knowing the driver of the session. The error of the driver, like
+// pgx.ErrNoRows or sql.ErrNoRows, stays wrapped and can still be matched as well.
+var ErrNoRows = errors.New("no rows in result set")
+
+// ErrMaxRowsExceeded is matched by MaxRowsError, it can be used with errors.Is to detect that a query was aborted
+// because it returned more rows than allowed.
+var ErrMaxRowsExceeded = errors.New("query returned more rows than allowed")
+
+// MaxRowsError is returned by a segment with a row limit when the query produces more rows than the limit allows.
+// Iteration is stopped as soon as the limit is passed, so the remaining rows are never read into memory.
+type MaxRowsError struct {
+	Limit int
+}
+
+// Error returns a description of the exceeded limit.
+func (e *MaxRowsError) Error() string {
+	return fmt.Sprintf("query returned more than %d rows", e.Limit)
+}
+
+// Is reports whether target is ErrMaxRowsExceeded.
+func (e *MaxRowsError) Is(target error) bool {
+	return target == ErrMaxRowsExceeded
+}
+
+// ConstraintError is returned by the drivers when a statement violates an integrity constraint of the database, like a
+// unique index or a foreign key. It exposes which constraint was violated and where, so handlers can respond to it,
+// like mapping a duplicate key to a conflict, without importing the error types of the driver:
+//
+//	var constraintErr *octobe.ConstraintError
+//	if errors.As(err, &constraintErr) && constraintErr.Constraint == "products_sku_key" {
+//		return ErrDuplicateSKU
+//	}
+//
+// The error of the driver stays wrapped and can still be matched as well.
+type ConstraintError struct {
+	// SQLState is the SQLSTATE code of the error, like 23505 for a unique violation.
+	SQLState string
+	// Constraint is the name of the violated constraint, if the database reported one.
+	Constraint string
+	// Schema is the schema of Table.
+	Schema string
+	// Table is the table the statement violated the constraint of.
+	Table string
+	// Column is the column the constraint applies to, reported for not null violations.
+	Column string
+	// Detail is the detail message of the database, like the conflicting key. It may contain values of the rows.
+	Detail string
+	// Err is the error of the driver.
+	Err error
+}
+
+// Error returns the error of the driver.
+func (e *ConstraintError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the error of the driver.
+func (e *ConstraintError) Unwrap() error {
+	return e.Err
+}
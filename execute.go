@@ -0,0 +1,40 @@
+package octobe
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ExecuteAll runs handlers in order with the builder of session and returns their results in the same order. It stops
+// at the first handler that fails, returning the results of the handlers before it along with the error, which tells
+// the position of the failed handler. The drivers offer the same function for their own handler type.
+func ExecuteAll[RESULT any, BUILDER any](session BuilderSession[BUILDER], handlers ...func(BUILDER) (RESULT, error)) ([]RESULT, error) {
+	builder := session.Builder()
+	results := make([]RESULT, 0, len(handlers))
+	for i, handler := range handlers {
+		result, err := handler(builder)
+		if err != nil {
+			return results, fmt.Errorf("handler %d: %w", i, err)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// ExecuteEach works like ExecuteAll, but runs all handlers even when some of them fail. The results of failed handlers
+// are zero values, and the returned error joins the errors of all failed handlers. Within a transaction a failed query
+// usually aborts the transaction, so ExecuteEach is meant for sessions without one.
+func ExecuteEach[RESULT any, BUILDER any](session BuilderSession[BUILDER], handlers ...func(BUILDER) (RESULT, error)) ([]RESULT, error) {
+	builder := session.Builder()
+	results := make([]RESULT, len(handlers))
+	var errs []error
+	for i, handler := range handlers {
+		result, err := handler(builder)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("handler %d: %w", i, err))
+			continue
+		}
+		results[i] = result
+	}
+	return results, errors.Join(errs...)
+}
@@ -0,0 +1,45 @@
+package octobe_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ponrove/octobe"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecuteAll(t *testing.T) {
+	ob, err := octobe.New((&fakeDriver{}).open())
+	require.NoError(t, err)
+	session, err := ob.Begin(context.Background())
+	require.NoError(t, err)
+
+	failed := errors.New("failed")
+	var ran []int
+	handler := func(i int, err error) func(string) (int, error) {
+		return func(builder string) (int, error) {
+			require.Equal(t, "builder", builder)
+			ran = append(ran, i)
+			return i, err
+		}
+	}
+
+	results, err := octobe.ExecuteAll(session, handler(1, nil), handler(2, nil))
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 2}, results)
+
+	ran = nil
+	results, err = octobe.ExecuteAll(session, handler(1, nil), handler(2, failed), handler(3, nil))
+	require.ErrorIs(t, err, failed)
+	require.ErrorContains(t, err, "handler 1: failed")
+	require.Equal(t, []int{1}, results)
+	require.Equal(t, []int{1, 2}, ran)
+
+	ran = nil
+	results, err = octobe.ExecuteEach(session, handler(1, failed), handler(2, nil), handler(3, failed))
+	require.ErrorContains(t, err, "handler 0: failed")
+	require.ErrorContains(t, err, "handler 2: failed")
+	require.Equal(t, []int{0, 2, 0}, results)
+	require.Equal(t, []int{1, 2, 3}, ran)
+}
@@ -0,0 +1,391 @@
+// Package gen generates typed handlers from annotated .sql files, similar to sqlc but targeting the Builder and Segment
+// API of the drivers directly. Every query becomes a function returning a postgres.Handler or clickhouse.Handler, with a
+// struct for its arguments and one for the row it returns:
+//
+//	-- name: GetProduct :one
+//	-- param: id int64
+//	-- column: id int64
+//	-- column: name string
+//	SELECT id, name FROM products WHERE id = :id;
+//
+// generates
+//
+//	func GetProduct(args GetProductArgs) postgres.Handler[GetProductRow]
+//
+// to be run with postgres.Execute(session, GetProduct(GetProductArgs{ID: 1})). The types of parameters and columns are
+// declared by the annotations, the database is not consulted. See Parse for the annotations and the octobe command for
+// running the generator.
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/ponrove/octobe/internal/named"
+)
+
+// Driver is the driver the handlers are generated for.
+type Driver string
+
+const (
+	// Postgres generates handlers for the postgres drivers.
+	Postgres Driver = "postgres"
+	// ClickHouse generates handlers for the clickhouse driver.
+	ClickHouse Driver = "clickhouse"
+)
+
+// Import paths of the packages generated code uses.
+const (
+	octobePath = "github.com/ponrove/octobe"
+	driverPath = octobePath + "/driver/"
+)
+
+// stdImports are the import paths of standard library packages whose name differs from their path, used for types of
+// packages without an import annotation.
+var stdImports = map[string]string{
+	"json":   "encoding/json",
+	"sql":    "database/sql",
+	"netip":  "net/netip",
+	"big":    "math/big",
+	"url":    "net/url",
+	"driver": "database/sql/driver",
+}
+
+// initialisms are the parts of column names written in upper case in Go names, following the Go naming conventions.
+var initialisms = map[string]string{
+	"id": "ID", "ids": "IDs", "uuid": "UUID", "url": "URL", "uri": "URI", "api": "API", "http": "HTTP", "json": "JSON",
+	"sql": "SQL", "ip": "IP", "html": "HTML", "xml": "XML", "utc": "UTC", "db": "DB", "ttl": "TTL", "cpu": "CPU",
+}
+
+// qualifier matches the package names used in Go types, like time in []time.Time.
+var qualifier = regexp.MustCompile(`([A-Za-z_][A-Za-z0-9_]*)\.[A-Za-z_]`)
+
+// Config configures the generated file.
+type Config struct {
+	// Package is the name of the package of the generated file.
+	Package string
+	// Driver is the driver the handlers are generated for, Postgres if empty.
+	Driver Driver
+}
+
+// Generate returns the formatted Go source of a file with the handlers of queries.
+func Generate(cfg Config, queries []Query) ([]byte, error) {
+	if cfg.Driver == "" {
+		cfg.Driver = Postgres
+	}
+	if cfg.Driver != Postgres && cfg.Driver != ClickHouse {
+		return nil, fmt.Errorf("unknown driver %q", cfg.Driver)
+	}
+	if cfg.Package == "" {
+		return nil, fmt.Errorf("no package name")
+	}
+
+	data := fileData{
+		Package: cfg.Package,
+		Driver:  string(cfg.Driver),
+		imports: map[string]string{string(cfg.Driver): driverPath + string(cfg.Driver)},
+	}
+	for _, query := range queries {
+		q, err := data.query(cfg.Driver, query)
+		if err != nil {
+			return nil, err
+		}
+		data.Queries = append(data.Queries, q)
+	}
+	// Standard library packages are imported in a group of their own, like goimports does.
+	for _, name := range sortedKeys(data.imports) {
+		importPath := data.imports[name]
+		spec := strconv.Quote(importPath)
+		if path.Base(importPath) != name {
+			spec = name + " " + spec
+		}
+		if strings.Contains(strings.Split(importPath, "/")[0], ".") {
+			data.Imports = append(data.Imports, spec)
+		} else {
+			data.StdImports = append(data.StdImports, spec)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := fileTemplate.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to format generated code, check the types of the annotations: %w", err)
+	}
+	return src, nil
+}
+
+// fileData is the data of the file template.
+type fileData struct {
+	Package    string
+	Driver     string
+	StdImports []string
+	Imports    []string
+	Queries    []queryData
+	imports    map[string]string
+}
+
+// queryData is the data of a query in the file template.
+type queryData struct {
+	Name    string
+	Command Command
+	Doc     []string
+	Const   string
+	SQL     string
+	Args    []fieldData
+	Columns []fieldData
+	// Result is the type the handler returns, Row the type of a row.
+	Result string
+	Row    string
+	// Arguments is the call binding the arguments, Scan the destinations of a row.
+	Arguments string
+	Scan      string
+}
+
+// fieldData is a field of a generated struct.
+type fieldData struct {
+	GoName string
+	Column string
+	Type   string
+}
+
+// query returns the template data of query, and records the imports of its types.
+func (f *fileData) query(driver Driver, query Query) (queryData, error) {
+	q := queryData{
+		Name:    query.Name,
+		Command: query.Command,
+		Doc:     query.Doc,
+		Const:   strings.ToLower(query.Name[:1]) + query.Name[1:] + "Query",
+		SQL:     query.SQL,
+	}
+	fail := func(format string, args ...any) (queryData, error) {
+		return queryData{}, fmt.Errorf("%s: query %s: %s", query.Source, query.Name, fmt.Sprintf(format, args...))
+	}
+
+	var err error
+	if q.Args, err = fields(query.Params); err != nil {
+		return fail("%v", err)
+	}
+	if q.Columns, err = fields(query.Columns); err != nil {
+		return fail("%v", err)
+	}
+	for _, field := range append(append([]Field(nil), query.Params...), query.Columns...) {
+		if err = f.use(field.Type, query.Imports); err != nil {
+			return fail("%v", err)
+		}
+	}
+
+	// Named parameters are compiled to placeholders now, so the handlers do not rewrite queries at runtime.
+	style := named.Dollar
+	if driver == ClickHouse {
+		style = named.Question
+	}
+	sql, names := named.Compile(query.SQL, style)
+	var args []string
+	if len(names) > 0 {
+		q.SQL = sql
+		byName := make(map[string]fieldData, len(q.Args))
+		for _, arg := range q.Args {
+			byName[arg.Column] = arg
+		}
+		used := make(map[string]bool, len(names))
+		for _, name := range names {
+			arg, ok := byName[name]
+			if !ok {
+				return fail("parameter :%s has no param annotation", name)
+			}
+			used[name] = true
+			args = append(args, "args."+arg.GoName)
+		}
+		for _, arg := range q.Args {
+			if !used[arg.Column] {
+				return fail("param %s is not used by the query", arg.Column)
+			}
+		}
+	} else {
+		for _, arg := range q.Args {
+			args = append(args, "args."+arg.GoName)
+		}
+	}
+	if len(args) > 0 {
+		q.Arguments = ".Arguments(" + strings.Join(args, ", ") + ")"
+	}
+
+	switch query.Command {
+	case One, Many:
+		switch len(q.Columns) {
+		case 0:
+			return fail("%s query has no column annotations", query.Command)
+		case 1:
+			q.Row, q.Scan = q.Columns[0].Type, "&row"
+		default:
+			q.Row = query.Name + "Row"
+			scan := make([]string, len(q.Columns))
+			for i, column := range q.Columns {
+				scan[i] = "&row." + column.GoName
+			}
+			q.Scan = strings.Join(scan, ", ")
+		}
+		q.Result = q.Row
+		if query.Command == Many {
+			q.Result = "[]" + q.Row
+		}
+	case Exec:
+		q.Result = "postgres.ExecResult"
+		if driver == ClickHouse {
+			q.Result = "octobe.Void"
+			f.imports["octobe"] = octobePath
+		}
+	case ExecRows:
+		if driver == ClickHouse {
+			return fail("%s is not supported by the clickhouse driver", query.Command)
+		}
+		q.Result = "int64"
+	}
+	if query.Command == Exec || query.Command == ExecRows {
+		if len(q.Columns) > 0 {
+			return fail("%s query cannot have column annotations", query.Command)
+		}
+	}
+	return q, nil
+}
+
+// use records the imports of the packages typ refers to.
+func (f *fileData) use(typ string, imports map[string]string) error {
+	for _, match := range qualifier.FindAllStringSubmatch(typ, -1) {
+		name := match[1]
+		importPath, ok := imports[name]
+		if !ok {
+			importPath, ok = stdImports[name]
+		}
+		if !ok {
+			importPath = name
+		}
+		if existing, ok := f.imports[name]; ok && existing != importPath {
+			return fmt.Errorf("package name %s refers to both %s and %s", name, existing, importPath)
+		}
+		f.imports[name] = importPath
+	}
+	return nil
+}
+
+// fields returns the struct fields of params or columns.
+func fields(fs []Field) ([]fieldData, error) {
+	data := make([]fieldData, len(fs))
+	seen := make(map[string]string, len(fs))
+	for i, field := range fs {
+		name := goName(field.Name)
+		if other, ok := seen[name]; ok {
+			return nil, fmt.Errorf("%s and %s have the same Go name %s", other, field.Name, name)
+		}
+		seen[name] = field.Name
+		data[i] = fieldData{GoName: name, Column: field.Name, Type: field.Type}
+	}
+	return data, nil
+}
+
+// goName returns the exported Go name of a column name, like ProductID for product_id. It returns an empty string if
+// the name cannot be converted.
+func goName(name string) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool { return r == '_' || r == '-' || r == ' ' }) {
+		if initialism, ok := initialisms[strings.ToLower(part)]; ok {
+			b.WriteString(initialism)
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	result := b.String()
+	for i, r := range result {
+		if !unicode.IsLetter(r) && r != '_' && (i == 0 || !unicode.IsDigit(r)) {
+			return ""
+		}
+	}
+	return result
+}
+
+// literal returns s as a Go string literal, a raw string literal if possible.
+func literal(s string) string {
+	if strings.Contains(s, "`") || strings.Contains(s, "\r") {
+		return strconv.Quote(s)
+	}
+	return "`" + s + "`"
+}
+
+var fileTemplate = template.Must(template.New("file").Funcs(template.FuncMap{"literal": literal}).Parse(
+	`// Code generated by octobe gen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+{{- range .StdImports}}
+	{{.}}
+{{- end}}
+{{if .StdImports}}
+{{end}}
+{{- range .Imports}}
+	{{.}}
+{{- end}}
+)
+{{range .Queries}}
+const {{.Const}} = {{literal .SQL}}
+{{if .Args}}
+// {{.Name}}Args are the arguments of {{.Name}}.
+type {{.Name}}Args struct {
+{{- range .Args}}
+	{{.GoName}} {{.Type}} ` + "`" + `db:"{{.Column}}"` + "`" + `
+{{- end}}
+}
+{{end}}
+{{- if gt (len .Columns) 1}}
+// {{.Name}}Row is a row returned by {{.Name}}.
+type {{.Name}}Row struct {
+{{- range .Columns}}
+	{{.GoName}} {{.Type}} ` + "`" + `db:"{{.Column}}"` + "`" + `
+{{- end}}
+}
+{{end}}
+{{- range .Doc}}
+// {{.}}
+{{- else}}
+// {{.Name}} returns a handler executing the {{.Name}} query.
+{{- end}}
+func {{.Name}}({{if .Args}}args {{.Name}}Args{{end}}) {{$.Driver}}.Handler[{{.Result}}] {
+	return func(builder {{$.Driver}}.Builder) ({{.Result}}, error) {
+{{- if eq .Command ":one"}}
+		var row {{.Row}}
+		err := builder({{.Const}}){{.Arguments}}.QueryRow({{.Scan}})
+		return row, err
+{{- else if eq .Command ":many"}}
+		var items []{{.Row}}
+		err := builder({{.Const}}){{.Arguments}}.Query(func(rows {{$.Driver}}.Rows) error {
+			for rows.Next() {
+				var row {{.Row}}
+				if err := rows.Scan({{.Scan}}); err != nil {
+					return err
+				}
+				items = append(items, row)
+			}
+			return rows.Err()
+		})
+		return items, err
+{{- else if eq .Command ":execrows"}}
+		result, err := builder({{.Const}}){{.Arguments}}.Exec()
+		return result.RowsAffected, err
+{{- else if eq $.Driver "clickhouse"}}
+		return nil, builder({{.Const}}){{.Arguments}}.Exec()
+{{- else}}
+		return builder({{.Const}}){{.Arguments}}.Exec()
+{{- end}}
+	}
+}
+{{end}}`))
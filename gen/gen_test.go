@@ -0,0 +1,157 @@
+package gen_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/driver/postgres"
+	"github.com/ponrove/octobe/gen"
+	"github.com/ponrove/octobe/gen/internal/example"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	queries, err := gen.Parse("products.sql", `
+-- import: pgtype github.com/jackc/pgx/v5/pgtype
+
+-- name: GetProduct :one
+-- GetProduct returns the product with id.
+-- param: id int64
+-- column: name string
+SELECT name
+FROM products
+WHERE id = :id;
+
+-- name: DeleteProduct :exec
+-- import: github.com/google/uuid
+-- param: id uuid.UUID
+DELETE FROM products WHERE id = $1;
+`)
+	require.NoError(t, err)
+	require.Equal(t, []gen.Query{
+		{
+			Name:    "GetProduct",
+			Command: gen.One,
+			Doc:     []string{"GetProduct returns the product with id."},
+			SQL:     "SELECT name\nFROM products\nWHERE id = :id",
+			Params:  []gen.Field{{Name: "id", Type: "int64"}},
+			Columns: []gen.Field{{Name: "name", Type: "string"}},
+			Imports: map[string]string{"pgtype": "github.com/jackc/pgx/v5/pgtype"},
+			Source:  "products.sql:4",
+		},
+		{
+			Name:    "DeleteProduct",
+			Command: gen.Exec,
+			SQL:     "DELETE FROM products WHERE id = $1",
+			Params:  []gen.Field{{Name: "id", Type: "uuid.UUID"}},
+			Imports: map[string]string{"pgtype": "github.com/jackc/pgx/v5/pgtype", "uuid": "github.com/google/uuid"},
+			Source:  "products.sql:12",
+		},
+	}, queries)
+}
+
+func TestParseErrors(t *testing.T) {
+	for name, src := range map[string]string{
+		"unknown command":     "-- name: GetProduct :first\nSELECT 1",
+		"unexported name":     "-- name: getProduct :one\nSELECT 1",
+		"missing command":     "-- name: GetProduct\nSELECT 1",
+		"missing type":        "-- name: GetProduct :one\n-- column: id\nSELECT 1",
+		"no statement":        "-- name: GetProduct :one\n-- column: id int64\n",
+		"param before":        "-- param: id int64\n-- name: GetProduct :one\nSELECT 1",
+		"duplicate name":      "-- name: GetProduct :one\nSELECT 1\n-- name: GetProduct :one\nSELECT 2",
+		"invalid import":      "-- import: 1x example.com/x\n",
+		"invalid field":       "-- name: GetProduct :one\n-- column: 1st int64\nSELECT 1",
+		"invalid import path": "-- import: a b c\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := gen.Parse("products.sql", src)
+			require.ErrorContains(t, err, "products.sql:")
+		})
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	for name, tc := range map[string]struct {
+		driver gen.Driver
+		src    string
+	}{
+		"undeclared parameter": {src: "-- name: A :exec\nDELETE FROM a WHERE id = :id"},
+		"unused param":         {src: "-- name: A :exec\n-- param: id int64\n-- param: name string\nDELETE FROM a WHERE id = :id"},
+		"no columns":           {src: "-- name: A :one\nSELECT 1"},
+		"columns of exec":      {src: "-- name: A :exec\n-- column: id int64\nDELETE FROM a"},
+		"execrows":             {driver: gen.ClickHouse, src: "-- name: A :execrows\nDELETE FROM a"},
+		"duplicate field":      {src: "-- name: A :one\n-- column: id int64\n-- column: ID int64\nSELECT 1, 2"},
+		"invalid type":         {src: "-- name: A :one\n-- column: id int64)\nSELECT 1"},
+		"conflicting imports":  {src: "-- name: A :one\n-- import: x example.com/x\n-- column: a x.T\n-- column: b json.RawMessage\nSELECT 1\n-- name: B :one\n-- import: x example.com/y\n-- column: a x.T\nSELECT 1"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			queries, err := gen.Parse("a.sql", tc.src)
+			require.NoError(t, err)
+			_, err = gen.Generate(gen.Config{Package: "db", Driver: tc.driver}, queries)
+			require.Error(t, err)
+		})
+	}
+
+	_, err := gen.Generate(gen.Config{Package: "db", Driver: "mysql"}, nil)
+	require.ErrorContains(t, err, "unknown driver")
+	_, err = gen.Generate(gen.Config{}, nil)
+	require.ErrorContains(t, err, "no package name")
+}
+
+// TestGenerateExamples checks that the generated examples, which are compiled with the module, are up to date.
+func TestGenerateExamples(t *testing.T) {
+	for _, tc := range []struct {
+		dir, pkg string
+		driver   gen.Driver
+	}{
+		{dir: "internal/example", pkg: "example", driver: gen.Postgres},
+		{dir: "internal/example/events", pkg: "events", driver: gen.ClickHouse},
+	} {
+		queries, err := gen.ParseFS(os.DirFS(tc.dir), ".")
+		require.NoError(t, err)
+		src, err := gen.Generate(gen.Config{Package: tc.pkg, Driver: tc.driver}, queries)
+		require.NoError(t, err)
+		want, err := os.ReadFile(filepath.Join(tc.dir, "queries.gen.go"))
+		require.NoError(t, err)
+		require.Equal(t, string(want), string(src), "run go generate ./gen/...")
+	}
+}
+
+func TestGeneratedHandlers(t *testing.T) {
+	ctx := context.Background()
+	mock, err := pgxmock.NewConn()
+	require.NoError(t, err)
+	defer mock.Close(ctx)
+
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	mock.ExpectQuery(`SELECT id, name, created_at FROM products WHERE id = \$1`).WithArgs(int64(1)).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "name", "created_at"}).AddRow(int64(1), "chair", created))
+	mock.ExpectQuery(`SELECT name FROM products ORDER BY name`).
+		WillReturnRows(pgxmock.NewRows([]string{"name"}).AddRow("chair").AddRow("table"))
+	mock.ExpectExec(`DELETE FROM products WHERE id = ANY\(\$1\)`).WithArgs([]int64{1, 2}).
+		WillReturnResult(pgxmock.NewResult("DELETE", 2))
+
+	ob, err := octobe.New(postgres.OpenPGXWithConn(mock))
+	require.NoError(t, err)
+	session, err := ob.Begin(ctx)
+	require.NoError(t, err)
+
+	product, err := postgres.Execute(session, example.GetProduct(example.GetProductArgs{ID: 1}))
+	require.NoError(t, err)
+	require.Equal(t, example.GetProductRow{ID: 1, Name: "chair", CreatedAt: created}, product)
+
+	names, err := postgres.Execute(session, example.ProductNames())
+	require.NoError(t, err)
+	require.Equal(t, []string{"chair", "table"}, names)
+
+	deleted, err := postgres.Execute(session, example.DeleteProducts(example.DeleteProductsArgs{IDs: []int64{1, 2}}))
+	require.NoError(t, err)
+	require.Equal(t, int64(2), deleted)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
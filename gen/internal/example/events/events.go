@@ -0,0 +1,4 @@
+// Package events holds handlers generated from events.sql for the clickhouse driver.
+package events
+
+//go:generate go run ../../../../cmd/octobe gen -driver clickhouse
@@ -0,0 +1,9 @@
+-- name: CountEvents :one
+-- param: kind string
+-- column: count uint64
+SELECT count() FROM `events` WHERE kind = :kind;
+
+-- name: InsertEvent :exec
+-- param: kind string
+-- param: payload json.RawMessage
+INSERT INTO events (kind, payload) VALUES (?, ?);
@@ -0,0 +1,41 @@
+// Code generated by octobe gen. DO NOT EDIT.
+
+package events
+
+import (
+	"encoding/json"
+
+	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/driver/clickhouse"
+)
+
+const countEventsQuery = "SELECT count() FROM `events` WHERE kind = ?"
+
+// CountEventsArgs are the arguments of CountEvents.
+type CountEventsArgs struct {
+	Kind string `db:"kind"`
+}
+
+// CountEvents returns a handler executing the CountEvents query.
+func CountEvents(args CountEventsArgs) clickhouse.Handler[uint64] {
+	return func(builder clickhouse.Builder) (uint64, error) {
+		var row uint64
+		err := builder(countEventsQuery).Arguments(args.Kind).QueryRow(&row)
+		return row, err
+	}
+}
+
+const insertEventQuery = `INSERT INTO events (kind, payload) VALUES (?, ?)`
+
+// InsertEventArgs are the arguments of InsertEvent.
+type InsertEventArgs struct {
+	Kind    string          `db:"kind"`
+	Payload json.RawMessage `db:"payload"`
+}
+
+// InsertEvent returns a handler executing the InsertEvent query.
+func InsertEvent(args InsertEventArgs) clickhouse.Handler[octobe.Void] {
+	return func(builder clickhouse.Builder) (octobe.Void, error) {
+		return nil, builder(insertEventQuery).Arguments(args.Kind, args.Payload).Exec()
+	}
+}
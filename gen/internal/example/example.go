@@ -0,0 +1,5 @@
+// Package example holds handlers generated from products.sql for the postgres drivers, it is compiled and tested to
+// keep the output of the generator valid.
+package example
+
+//go:generate go run ../../../cmd/octobe gen -package example
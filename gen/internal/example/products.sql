@@ -0,0 +1,33 @@
+-- import: github.com/google/uuid
+
+-- name: GetProduct :one
+-- GetProduct returns the product with id.
+-- param: id int64
+-- column: id int64
+-- column: name string
+-- column: created_at time.Time
+SELECT id, name, created_at FROM products WHERE id = :id;
+
+-- name: ListProducts :many
+-- param: min_price float64
+-- param: max_price float64
+-- column: id int64
+-- column: name string
+-- column: created_at time.Time
+SELECT id, name, created_at
+FROM products
+WHERE price BETWEEN $1 AND $2
+ORDER BY id;
+
+-- name: ProductNames :many
+-- column: name string
+SELECT name FROM products ORDER BY name;
+
+-- name: TagProduct :exec
+-- param: product_id int64
+-- param: tag_id uuid.UUID
+INSERT INTO product_tags (product_id, tag_id) VALUES (:product_id, :tag_id);
+
+-- name: DeleteProducts :execrows
+-- param: ids []int64
+DELETE FROM products WHERE id = ANY(:ids);
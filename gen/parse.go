@@ -0,0 +1,265 @@
+package gen
+
+import (
+	"fmt"
+	"go/token"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Command is the kind of result a query returns.
+type Command string
+
+const (
+	// One returns the first row of the result, or the no rows error of the driver if the result is empty.
+	One Command = ":one"
+	// Many returns all rows of the result.
+	Many Command = ":many"
+	// Exec executes the query and returns its result, which is postgres.ExecResult for the postgres drivers and
+	// octobe.Void for the clickhouse driver.
+	Exec Command = ":exec"
+	// ExecRows executes the query and returns the number of affected rows, it is only supported by the postgres drivers.
+	ExecRows Command = ":execrows"
+)
+
+// Field is a parameter or a column of a query, with its Go type, like int64, time.Time or []string.
+type Field struct {
+	Name string
+	Type string
+}
+
+// Query is an annotated query of a .sql file.
+type Query struct {
+	// Name is the name of the generated handler, it must be an exported Go identifier.
+	Name    string
+	Command Command
+	// Doc is the documentation of the handler, from plain comments following the name annotation.
+	Doc []string
+	// SQL is the statement without the annotations and a trailing semicolon.
+	SQL     string
+	Params  []Field
+	Columns []Field
+	// Imports maps package names used by types to their import paths, from import annotations.
+	Imports map[string]string
+	// Source is the file and line of the name annotation, for error messages.
+	Source string
+}
+
+// ParseFS parses every .sql file in dir of fsys, in the order of their names.
+func ParseFS(fsys fs.FS, dir string) ([]Query, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	var queries []Query
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := Parse(entry.Name(), string(content))
+		if err != nil {
+			return nil, err
+		}
+		queries = append(queries, parsed...)
+	}
+	return queries, nil
+}
+
+// Parse parses the queries of a .sql file, filename is only used for error messages. Every query starts with a name
+// annotation and ends with the next one or the end of the file:
+//
+//	-- name: GetProduct :one
+//	-- GetProduct returns the product with id.
+//	-- param: id int64
+//	-- column: id int64
+//	-- column: name string
+//	SELECT id, name FROM products WHERE id = :id;
+//
+// Plain comments between the name annotation and the statement document the handler, like a Go doc comment they should
+// start with the name. Params are the arguments of the query. Named parameters like :id are bound to the param of the
+// same name, otherwise the params are bound to the placeholders in order. Columns are the columns the query returns,
+// in order. Types of other packages are imported by the name of the package, unless an import annotation like
+// "-- import: github.com/google/uuid" or "-- import: pgtype github.com/jackc/pgx/v5/pgtype" maps the name to a path.
+// Import annotations before the first query apply to all queries of the file. Text before the first query that is not
+// an annotation is ignored.
+func Parse(filename, src string) ([]Query, error) {
+	var (
+		queries []Query
+		current *Query
+		body    []string
+		imports = make(map[string]string)
+	)
+	finish := func() error {
+		if current == nil {
+			return nil
+		}
+		current.SQL = strings.TrimSuffix(strings.TrimSpace(strings.Join(body, "\n")), ";")
+		current.SQL = strings.TrimSpace(current.SQL)
+		if current.SQL == "" {
+			return fmt.Errorf("%s: query %s has no statement", current.Source, current.Name)
+		}
+		queries = append(queries, *current)
+		return nil
+	}
+
+	for i, line := range strings.Split(src, "\n") {
+		line = strings.TrimRight(line, "\r")
+		position := fmt.Sprintf("%s:%d", filename, i+1)
+		key, value, ok := annotation(line)
+		if !ok {
+			comment, isComment := strings.CutPrefix(strings.TrimSpace(line), "--")
+			switch {
+			case current == nil:
+			case isComment && len(body) == 0:
+				current.Doc = append(current.Doc, strings.TrimSpace(comment))
+			default:
+				body = append(body, line)
+			}
+			continue
+		}
+
+		switch key {
+		case "name":
+			if err := finish(); err != nil {
+				return nil, err
+			}
+			query, err := parseName(value, position)
+			if err != nil {
+				return nil, err
+			}
+			query.Imports = make(map[string]string, len(imports))
+			for name, importPath := range imports {
+				query.Imports[name] = importPath
+			}
+			current, body = &query, nil
+		case "param", "column":
+			if current == nil {
+				return nil, fmt.Errorf("%s: %s annotation before the first query", position, key)
+			}
+			field, err := parseField(value, position)
+			if err != nil {
+				return nil, err
+			}
+			if key == "param" {
+				current.Params = append(current.Params, field)
+			} else {
+				current.Columns = append(current.Columns, field)
+			}
+		case "import":
+			name, importPath, err := parseImport(value, position)
+			if err != nil {
+				return nil, err
+			}
+			if current == nil {
+				imports[name] = importPath
+			} else {
+				current.Imports[name] = importPath
+			}
+		default:
+			return nil, fmt.Errorf("%s: unknown annotation %q", position, key)
+		}
+	}
+	if err := finish(); err != nil {
+		return nil, err
+	}
+
+	if err := checkNames(queries); err != nil {
+		return nil, err
+	}
+	return queries, nil
+}
+
+// annotation returns the key and value of an annotation comment like "-- name: GetProduct :one".
+func annotation(line string) (string, string, bool) {
+	comment, ok := strings.CutPrefix(strings.TrimSpace(line), "--")
+	if !ok {
+		return "", "", false
+	}
+	key, value, ok := strings.Cut(strings.TrimSpace(comment), ":")
+	if !ok || strings.ContainsAny(key, " \t") {
+		return "", "", false
+	}
+	switch key {
+	case "name", "param", "column", "import":
+		return key, strings.TrimSpace(value), true
+	}
+	return "", "", false
+}
+
+// parseName parses the value of a name annotation.
+func parseName(value, position string) (Query, error) {
+	parts := strings.Fields(value)
+	if len(parts) != 2 {
+		return Query{}, fmt.Errorf("%s: name annotation must have a name and a command, like GetProduct :one", position)
+	}
+	if !token.IsIdentifier(parts[0]) || !token.IsExported(parts[0]) {
+		return Query{}, fmt.Errorf("%s: query name %q is not an exported Go identifier", position, parts[0])
+	}
+	command := Command(parts[1])
+	switch command {
+	case One, Many, Exec, ExecRows:
+	default:
+		return Query{}, fmt.Errorf("%s: unknown command %q, expected :one, :many, :exec or :execrows", position, parts[1])
+	}
+	return Query{Name: parts[0], Command: command, Source: position}, nil
+}
+
+// parseField parses the value of a param or column annotation, a name followed by a Go type.
+func parseField(value, position string) (Field, error) {
+	name, typ, ok := strings.Cut(value, " ")
+	typ = strings.TrimSpace(typ)
+	if !ok || name == "" || typ == "" {
+		return Field{}, fmt.Errorf("%s: annotation must have a name and a type, like id int64", position)
+	}
+	if goName(name) == "" {
+		return Field{}, fmt.Errorf("%s: %q cannot be used as a Go field name", position, name)
+	}
+	return Field{Name: name, Type: typ}, nil
+}
+
+// parseImport parses the value of an import annotation, an import path optionally preceded by a package name.
+func parseImport(value, position string) (string, string, error) {
+	parts := strings.Fields(value)
+	switch len(parts) {
+	case 1:
+		name := path.Base(parts[0])
+		if strings.HasPrefix(name, "v") && strings.Trim(name[1:], "0123456789") == "" {
+			name = path.Base(path.Dir(parts[0]))
+		}
+		return name, parts[0], nil
+	case 2:
+		if !token.IsIdentifier(parts[0]) {
+			return "", "", fmt.Errorf("%s: import name %q is not a Go identifier", position, parts[0])
+		}
+		return parts[0], parts[1], nil
+	}
+	return "", "", fmt.Errorf("%s: import annotation must have a path, optionally preceded by a name", position)
+}
+
+// checkNames returns an error if queries share a name.
+func checkNames(queries []Query) error {
+	seen := make(map[string]string, len(queries))
+	for _, query := range queries {
+		if source, ok := seen[query.Name]; ok {
+			return fmt.Errorf("%s: query %s is already declared at %s", query.Source, query.Name, source)
+		}
+		seen[query.Name] = query.Source
+	}
+	return nil
+}
+
+// sortedKeys returns the keys of m in order.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,91 @@
+package octobe
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strconv"
+	"sync"
+)
+
+// ErrConcurrentUse is returned by Commit and Rollback, and the value of the panic of a query, when a session is used
+// from a goroutine while another goroutine is still using it, see WithConcurrencyGuard.
+var ErrConcurrentUse = errors.New("session is used concurrently by another goroutine")
+
+// WithConcurrencyGuard detects the use of a session from several goroutines at the same time, which sessions do not
+// support. A query started while a query of another goroutine is still running, including reading its rows, panics
+// with ErrConcurrentUse, and committing or rolling back fails with it. Handing a session over to another goroutine and
+// nesting queries in the same goroutine are allowed. Looking up the goroutine is slow compared to a query's own
+// overhead, the guard is meant for tests and development like the race detector.
+func WithConcurrencyGuard() InstanceOption {
+	return func(cfg *instanceConfig) {
+		cfg.guard = true
+	}
+}
+
+// concurrencyGuard tracks the goroutine using a session.
+type concurrencyGuard struct {
+	mu    sync.Mutex
+	owner uint64
+	depth int
+}
+
+// enter marks the session as used by the current goroutine, it returns a function that must be called once it no
+// longer is, or ErrConcurrentUse if another goroutine is using the session. A nil guard allows any use.
+func (g *concurrencyGuard) enter() (func(), error) {
+	if g == nil {
+		return func() {}, nil
+	}
+	id := goroutineID()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.depth > 0 && g.owner != id {
+		return nil, fmt.Errorf("%w: goroutine %d while in use by goroutine %d", ErrConcurrentUse, id, g.owner)
+	}
+	g.owner = id
+	g.depth++
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			g.mu.Lock()
+			defer g.mu.Unlock()
+			g.depth--
+		})
+	}, nil
+}
+
+// AllowConcurrentUse returns ctx for queries of its session that may run concurrently, so BeginQuery does not check
+// them against WithConcurrencyGuard. It is meant for drivers that run the queries of a session from several goroutines
+// on connections of their own, like ExecuteParallel of the postgres driver. Without a guard it returns ctx as is.
+func AllowConcurrentUse(ctx context.Context) context.Context {
+	state, _ := ctx.Value(hooksKey{}).(*hookState)
+	if state == nil || state.guard == nil {
+		return ctx
+	}
+	unguarded := *state
+	unguarded.guard = nil
+	return context.WithValue(ctx, hooksKey{}, &unguarded)
+}
+
+// guardFrom returns the concurrency guard of the session of ctx, nil if the instance does not guard its sessions.
+func guardFrom(ctx context.Context) *concurrencyGuard {
+	state, _ := ctx.Value(hooksKey{}).(*hookState)
+	if state == nil {
+		return nil
+	}
+	return state.guard
+}
+
+// goroutineID returns the id of the current goroutine from the header of its stack trace, "goroutine 1 [running]:".
+func goroutineID() uint64 {
+	var buf [64]byte
+	header := buf[:runtime.Stack(buf[:], false)]
+	header = bytes.TrimPrefix(header, []byte("goroutine "))
+	if i := bytes.IndexByte(header, ' '); i >= 0 {
+		header = header[:i]
+	}
+	id, _ := strconv.ParseUint(string(header), 10, 64)
+	return id
+}
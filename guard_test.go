@@ -0,0 +1,41 @@
+package octobe_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ponrove/octobe"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithConcurrencyGuard(t *testing.T) {
+	d := &fakeDriver{}
+	ob, err := octobe.New(d.open(), octobe.WithConcurrencyGuard())
+	require.NoError(t, err)
+
+	session, err := ob.Begin(context.Background(), withTx())
+	require.NoError(t, err)
+	ctx := d.sessions[0].ctx
+
+	// Nested queries of the same goroutine are allowed.
+	_, done := octobe.BeginQuery(ctx, octobe.OperationQuery, "SELECT 1", nil)
+	_, nested := octobe.BeginQuery(ctx, octobe.OperationQuery, "SELECT 2", nil)
+	nested(1, nil)
+
+	panicked := make(chan any)
+	go func() {
+		defer func() { panicked <- recover() }()
+		octobe.BeginQuery(ctx, octobe.OperationQuery, "SELECT 3", nil)
+	}()
+	value := <-panicked
+	require.ErrorIs(t, value.(error), octobe.ErrConcurrentUse)
+
+	committed := make(chan error)
+	go func() { committed <- session.Commit() }()
+	require.ErrorIs(t, <-committed, octobe.ErrConcurrentUse)
+
+	// Once the query has finished the session can be handed over to another goroutine.
+	done(1, nil)
+	go func() { committed <- session.Commit() }()
+	require.NoError(t, <-committed)
+}
@@ -0,0 +1,87 @@
+package octobe
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// Health statuses reported by HealthHandler.
+const (
+	HealthStatusUp   = "up"
+	HealthStatusDown = "down"
+)
+
+// HealthChecker is implemented by anything that can check its connection to a database, every Octobe instance is a
+// HealthChecker.
+type HealthChecker interface {
+	Ping(ctx context.Context) error
+}
+
+// namedHealthChecker is a HealthChecker with a name used in health reports.
+type namedHealthChecker struct {
+	HealthChecker
+	name string
+}
+
+// NamedHealthChecker gives a checker a name that identifies it in the report of HealthHandler. Checkers without a name
+// are identified by their position.
+func NamedHealthChecker(name string, checker HealthChecker) HealthChecker {
+	return namedHealthChecker{HealthChecker: checker, name: name}
+}
+
+// HealthReport is the JSON document written by HealthHandler.
+type HealthReport struct {
+	Status string        `json:"status"`
+	Checks []HealthCheck `json:"checks"`
+}
+
+// HealthCheck is the result of a single checker in a HealthReport.
+type HealthCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// HealthHandler returns a http.Handler that pings all checkers concurrently with the context of the request, and
+// responds with a HealthReport as JSON. The status code is 200 when all checkers are up and 503 when any is down, which
+// makes the handler usable as readiness and liveness probe.
+func HealthHandler(checkers ...HealthChecker) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := HealthReport{
+			Status: HealthStatusUp,
+			Checks: make([]HealthCheck, len(checkers)),
+		}
+
+		var wg sync.WaitGroup
+		for i, checker := range checkers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				check := HealthCheck{Name: strconv.Itoa(i), Status: HealthStatusUp}
+				if named, ok := checker.(namedHealthChecker); ok {
+					check.Name = named.name
+				}
+				if err := checker.Ping(r.Context()); err != nil {
+					check.Status, check.Error = HealthStatusDown, err.Error()
+				}
+				report.Checks[i] = check
+			}()
+		}
+		wg.Wait()
+
+		code := http.StatusOK
+		for _, check := range report.Checks {
+			if check.Status != HealthStatusUp {
+				report.Status, code = HealthStatusDown, http.StatusServiceUnavailable
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(code)
+		_ = json.NewEncoder(w).Encode(report)
+	})
+}
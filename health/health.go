@@ -0,0 +1,208 @@
+// Package health keeps track of the health of a database in the background for readiness and liveness probes. A
+// Checker pings the database periodically, and probes are answered from the outcome of the last pings instead of
+// pinging the database on every probe, so a slow database does not make probes time out and a single failed ping does
+// not take a service out of rotation.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ponrove/octobe"
+)
+
+// Defaults of the options of a Checker.
+const (
+	DefaultInterval         = 10 * time.Second
+	DefaultTimeout          = 2 * time.Second
+	DefaultFailureThreshold = 1
+)
+
+// ErrNotChecked is returned by Check until the first ping finished.
+var ErrNotChecked = errors.New("health has not been checked yet")
+
+// Option configures a Checker.
+type Option func(cfg *config)
+
+// config holds the configuration of a Checker.
+type config struct {
+	interval  time.Duration
+	timeout   time.Duration
+	threshold int
+	clock     octobe.Clock
+}
+
+// WithInterval sets the time between two pings, DefaultInterval by default.
+func WithInterval(interval time.Duration) Option {
+	return func(cfg *config) {
+		cfg.interval = interval
+	}
+}
+
+// WithTimeout sets the time a single ping may take before it counts as failed, DefaultTimeout by default.
+func WithTimeout(timeout time.Duration) Option {
+	return func(cfg *config) {
+		cfg.timeout = timeout
+	}
+}
+
+// WithFailureThreshold sets the number of consecutive failed pings after which the database is reported unhealthy,
+// DefaultFailureThreshold by default.
+func WithFailureThreshold(n int) Option {
+	return func(cfg *config) {
+		cfg.threshold = max(n, 1)
+	}
+}
+
+// WithClock sets the clock the interval between pings and the times of the Status are measured with,
+// octobe.SystemClock by default.
+func WithClock(clock octobe.Clock) Option {
+	return func(cfg *config) {
+		cfg.clock = clock
+	}
+}
+
+// Status is the outcome of the pings of a Checker so far.
+type Status struct {
+	// Checked reports whether a ping has finished.
+	Checked bool
+	// ConsecutiveFailures is the number of pings that failed since the last successful one.
+	ConsecutiveFailures int
+	// LastCheck is the time the last ping finished.
+	LastCheck time.Time
+	// LastSuccess is the time the last successful ping finished, zero if none succeeded.
+	LastSuccess time.Time
+	// LastError is the error of the last ping, nil if it succeeded.
+	LastError error
+}
+
+// Checker pings a database periodically and tracks the outcome. It implements octobe.HealthChecker with the state of the
+// last pings, so it can be reported through octobe.HealthHandler as well.
+type Checker struct {
+	checker octobe.HealthChecker
+	cfg     config
+
+	mu     sync.Mutex
+	status Status
+}
+
+// Ensure Checker can be used where a live health check is expected.
+var (
+	_ octobe.HealthChecker = &Checker{}
+	_ http.Handler         = &Checker{}
+)
+
+// New creates a Checker that pings checker, which is usually an Octobe instance. Pinging starts with Run.
+func New(checker octobe.HealthChecker, opts ...Option) *Checker {
+	cfg := config{interval: DefaultInterval, timeout: DefaultTimeout, threshold: DefaultFailureThreshold, clock: octobe.SystemClock}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.clock == nil {
+		cfg.clock = octobe.SystemClock
+	}
+	return &Checker{checker: checker, cfg: cfg}
+}
+
+// Run pings the database right away and then at every interval, until ctx is done. It is meant to run in its own
+// goroutine for the lifetime of the service.
+func (c *Checker) Run(ctx context.Context) {
+	for {
+		c.ping(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.cfg.clock.After(c.cfg.interval):
+		}
+	}
+}
+
+// ping pings the database once and records the outcome, a ping cut short because ctx is done is not recorded.
+func (c *Checker) ping(ctx context.Context) {
+	pingCtx, cancel := context.WithTimeout(ctx, c.cfg.timeout)
+	err := c.checker.Ping(pingCtx)
+	cancel()
+	if ctx.Err() != nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.cfg.clock.Now()
+	c.status.Checked = true
+	c.status.LastCheck = now
+	c.status.LastError = err
+	if err != nil {
+		c.status.ConsecutiveFailures++
+		return
+	}
+	c.status.ConsecutiveFailures = 0
+	c.status.LastSuccess = now
+}
+
+// Status returns the outcome of the pings so far.
+func (c *Checker) Status() Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.status
+}
+
+// Check returns nil if the database is healthy, and otherwise an error wrapping the error of the last ping once the
+// failure threshold has been reached. It returns ErrNotChecked until the first ping finished. ctx is not used, the
+// outcome of the last pings is returned right away.
+func (c *Checker) Check(_ context.Context) error {
+	status := c.Status()
+	switch {
+	case !status.Checked:
+		return ErrNotChecked
+	case status.ConsecutiveFailures < c.cfg.threshold:
+		return nil
+	case status.LastSuccess.IsZero():
+		return fmt.Errorf("%d consecutive pings failed: %w", status.ConsecutiveFailures, status.LastError)
+	default:
+		return fmt.Errorf("%d consecutive pings failed since %s: %w", status.ConsecutiveFailures,
+			status.LastSuccess.Format(time.RFC3339), status.LastError)
+	}
+}
+
+// Ping returns the result of Check, so a Checker can be used as an octobe.HealthChecker.
+func (c *Checker) Ping(ctx context.Context) error {
+	return c.Check(ctx)
+}
+
+// Report is the JSON document written by the http.Handler of a Checker.
+type Report struct {
+	Status              string     `json:"status"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastCheck           *time.Time `json:"last_check,omitempty"`
+	LastSuccess         *time.Time `json:"last_success,omitempty"`
+	Error               string     `json:"error,omitempty"`
+}
+
+// ServeHTTP responds with a Report as JSON, with status code 200 when the database is healthy and 503 otherwise, which
+// makes the Checker usable as readiness and liveness probe.
+func (c *Checker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	status := c.Status()
+	report := Report{Status: octobe.HealthStatusUp, ConsecutiveFailures: status.ConsecutiveFailures}
+	if status.Checked {
+		report.LastCheck = &status.LastCheck
+	}
+	if !status.LastSuccess.IsZero() {
+		report.LastSuccess = &status.LastSuccess
+	}
+
+	code := http.StatusOK
+	if err := c.Check(r.Context()); err != nil {
+		report.Status, report.Error, code = octobe.HealthStatusDown, err.Error(), http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(report)
+}
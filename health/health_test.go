@@ -0,0 +1,95 @@
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/health"
+	"github.com/stretchr/testify/require"
+)
+
+// pinger is a health checker whose ping result can be changed while it is checked.
+type pinger struct {
+	mu    sync.Mutex
+	err   error
+	pings int
+}
+
+func (p *pinger) Ping(context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pings++
+	return p.err
+}
+
+func (p *pinger) set(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.err = err
+}
+
+func (p *pinger) count() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.pings
+}
+
+func serve(checker *health.Checker) (int, health.Report) {
+	rec := httptest.NewRecorder()
+	checker.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	var report health.Report
+	_ = json.NewDecoder(rec.Body).Decode(&report)
+	return rec.Code, report
+}
+
+func TestChecker(t *testing.T) {
+	db := &pinger{}
+	checker := health.New(db, health.WithInterval(time.Millisecond), health.WithFailureThreshold(3))
+	require.ErrorIs(t, checker.Check(context.Background()), health.ErrNotChecked)
+	code, report := serve(checker)
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Equal(t, octobe.HealthStatusDown, report.Status)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		checker.Run(ctx)
+	}()
+
+	require.Eventually(t, func() bool { return checker.Check(ctx) == nil }, time.Second, time.Millisecond)
+	code, report = serve(checker)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, octobe.HealthStatusUp, report.Status)
+	require.NotNil(t, report.LastSuccess)
+
+	refused := errors.New("connection refused")
+	db.set(refused)
+	require.Eventually(t, func() bool { return checker.Status().ConsecutiveFailures >= 3 }, time.Second, time.Millisecond)
+	err := checker.Check(ctx)
+	require.ErrorIs(t, err, refused)
+	require.ErrorContains(t, err, "consecutive pings failed since")
+	require.False(t, checker.Status().LastSuccess.IsZero())
+
+	// The checker reports through octobe.HealthHandler as well.
+	rec := httptest.NewRecorder()
+	octobe.HealthHandler(checker).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	db.set(nil)
+	require.Eventually(t, func() bool { return checker.Check(ctx) == nil }, time.Second, time.Millisecond)
+	require.Zero(t, checker.Status().ConsecutiveFailures)
+
+	cancel()
+	<-stopped
+	pings := db.count()
+	time.Sleep(5 * time.Millisecond)
+	require.Equal(t, pings, db.count(), "no pings after Run returned")
+}
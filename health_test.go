@@ -0,0 +1,52 @@
+package octobe_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ponrove/octobe"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthHandler(t *testing.T) {
+	primary, err := octobe.New((&fakeDriver{}).open())
+	require.NoError(t, err)
+	replica, err := octobe.New((&fakeDriver{pingErr: errors.New("connection refused")}).open())
+	require.NoError(t, err)
+
+	t.Run("up", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		octobe.HealthHandler(primary).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+		var report octobe.HealthReport
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&report))
+		require.Equal(t, octobe.HealthReport{
+			Status: octobe.HealthStatusUp,
+			Checks: []octobe.HealthCheck{{Name: "0", Status: octobe.HealthStatusUp}},
+		}, report)
+	})
+
+	t.Run("down", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler := octobe.HealthHandler(
+			octobe.NamedHealthChecker("primary", primary),
+			octobe.NamedHealthChecker("replica", replica),
+		)
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+		require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		require.JSONEq(t, `{
+			"status": "down",
+			"checks": [
+				{"name": "primary", "status": "up"},
+				{"name": "replica", "status": "down", "error": "connection refused"}
+			]
+		}`, rec.Body.String())
+	})
+}
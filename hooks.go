@@ -0,0 +1,188 @@
+package octobe
+
+import (
+	"context"
+	"time"
+)
+
+// Operation is the kind of database operation a query is performed with.
+type Operation string
+
+const (
+	OperationExec        Operation = "exec"
+	OperationQuery       Operation = "query"
+	OperationQueryRow    Operation = "query_row"
+	OperationSelect      Operation = "select"
+	OperationBatch       Operation = "batch"
+	OperationAsyncInsert Operation = "async_insert"
+	OperationCopy        Operation = "copy"
+)
+
+// DriverInfo describes a driver to instrumentation such as query hooks.
+type DriverInfo struct {
+	// Name is the name of the driver, like pgx or clickhouse.
+	Name string
+	// System is the database system the driver connects to, like postgresql or clickhouse.
+	System string
+}
+
+// Describer is implemented by drivers that describe themselves to instrumentation.
+type Describer interface {
+	Describe() DriverInfo
+}
+
+// QueryEvent describes a query performed by a driver. Duration, Rows and Err are set once the query has finished.
+type QueryEvent struct {
+	Driver    DriverInfo
+	Operation Operation
+	Query     string
+	Args      []any
+	Start     time.Time
+	Duration  time.Duration
+	// Rows is the number of rows affected or returned by the query, or -1 if the driver does not know.
+	Rows int64
+	Err  error
+}
+
+// QueryHook is invoked by the drivers around every query, giving a single place to add logging, metrics and tracing.
+// BeforeQuery returns the context the query is performed with, so a hook can for example start a span that AfterQuery
+// ends. AfterQuery is called with the context returned by BeforeQuery once the query, including reading its rows, has
+// finished.
+type QueryHook interface {
+	BeforeQuery(ctx context.Context, event *QueryEvent) context.Context
+	AfterQuery(ctx context.Context, event *QueryEvent)
+}
+
+// SessionEvent describes a session. Wait is the time the session waited for admission before it began, see
+// WithMaxConcurrentSessions. Transaction is set once the session has begun, Duration, Committed and Err once it has
+// ended.
+type SessionEvent struct {
+	Driver      DriverInfo
+	Transaction bool
+	Start       time.Time
+	Wait        time.Duration
+	Duration    time.Duration
+	Committed   bool
+	Err         error
+}
+
+// SessionHook can be implemented by a QueryHook to also be invoked around sessions. BeforeSession is called before the
+// session begins and returns the context of the session, which is the parent context of the queries of the session.
+// AfterSession is called when a session in a transaction is committed or rolled back. A session without a transaction
+// has no end that Octobe can observe, AfterSession is called for it as soon as it has begun.
+type SessionHook interface {
+	BeforeSession(ctx context.Context, event *SessionEvent) context.Context
+	AfterSession(ctx context.Context, event *SessionEvent)
+}
+
+// WithQueryHook adds hooks that are invoked around every query of the sessions of the instance, in the order they are
+// given for BeforeQuery and in reverse order for AfterQuery. Hooks that implement SessionHook are invoked around the
+// sessions of the instance as well.
+func WithQueryHook(hooks ...QueryHook) InstanceOption {
+	return func(cfg *instanceConfig) {
+		cfg.hooks = append(cfg.hooks, hooks...)
+	}
+}
+
+// hooksKey is the context key of the hookState of a session.
+type hooksKey struct{}
+
+// hookState holds the query hooks of a session and the driver they are invoked for.
+type hookState struct {
+	hooks    []QueryHook
+	driver   DriverInfo
+	comments []CommentTags
+	guard    *concurrencyGuard
+	stats    *statsCounter
+	clock    Clock
+}
+
+// describe returns the DriverInfo of the driver of the instance.
+func (ob *Octobe[DRIVER, CONFIG, BUILDER]) describe() DriverInfo {
+	if d, ok := ob.driver.(Describer); ok {
+		return d.Describe()
+	}
+	return DriverInfo{}
+}
+
+// beginSession invokes the session hooks of the instance and returns the context of the session, carrying the query
+// hooks for BeginQuery, a function to end the session with and the recorder of the session if the instance records
+// queries. wait is the time the session waited for admission. Hooks of an outer session in ctx are not inherited.
+func (ob *Octobe[DRIVER, CONFIG, BUILDER]) beginSession(ctx context.Context, wait time.Duration) (context.Context, func(transaction, committed bool, err error), *recorder) {
+	if len(ob.cfg.hooks) == 0 && !ob.cfg.record && len(ob.cfg.comments) == 0 && !ob.cfg.guard && !ob.cfg.stats {
+		if ctx.Value(hooksKey{}) != nil {
+			ctx = context.WithValue(ctx, hooksKey{}, (*hookState)(nil))
+		}
+		return ctx, func(bool, bool, error) {}, nil
+	}
+
+	state := &hookState{hooks: ob.cfg.hooks, driver: ob.describe(), comments: ob.cfg.comments, clock: ob.cfg.clock}
+	if ob.cfg.guard {
+		state.guard = &concurrencyGuard{}
+	}
+	var rec *recorder
+	if ob.cfg.record {
+		rec = &recorder{limit: ob.cfg.recordLimit}
+		state.hooks = append([]QueryHook{rec}, ob.cfg.hooks...)
+	}
+	if ob.cfg.stats {
+		state.stats = &statsCounter{total: ob.stats}
+		state.hooks = append([]QueryHook{state.stats}, state.hooks...)
+	}
+
+	event := &SessionEvent{Driver: state.driver, Start: state.clock.Now(), Wait: wait}
+	var (
+		hooks    []SessionHook
+		contexts []context.Context
+	)
+	for _, hook := range ob.cfg.hooks {
+		if sessionHook, ok := hook.(SessionHook); ok {
+			ctx = sessionHook.BeforeSession(ctx, event)
+			hooks = append(hooks, sessionHook)
+			contexts = append(contexts, ctx)
+		}
+	}
+
+	return context.WithValue(ctx, hooksKey{}, state), func(transaction, committed bool, err error) {
+		event.Transaction = transaction
+		event.Duration = state.clock.Now().Sub(event.Start)
+		event.Committed = committed
+		event.Err = err
+		for i := len(hooks) - 1; i >= 0; i-- {
+			hooks[i].AfterSession(contexts[i], event)
+		}
+	}, rec
+}
+
+// BeginQuery is called by drivers before performing a query with the context of the session. It invokes the query
+// hooks of the session and returns the context to perform the query with, and a function that must be called once the
+// query has finished with the number of rows it affected or returned, -1 if unknown, and its error. Without hooks it
+// returns ctx as is. It panics with ErrConcurrentUse if the instance was created with WithConcurrencyGuard and another
+// goroutine is using the session.
+func BeginQuery(ctx context.Context, op Operation, query string, args []any) (context.Context, func(rows int64, err error)) {
+	state, _ := ctx.Value(hooksKey{}).(*hookState)
+	if state == nil {
+		return ctx, func(int64, error) {}
+	}
+	leave, err := state.guard.enter()
+	if err != nil {
+		panic(err)
+	}
+
+	event := &QueryEvent{Driver: state.driver, Operation: op, Query: query, Args: args, Start: state.clock.Now(), Rows: -1}
+	contexts := make([]context.Context, len(state.hooks))
+	for i, hook := range state.hooks {
+		ctx = hook.BeforeQuery(ctx, event)
+		contexts[i] = ctx
+	}
+
+	return ctx, func(rows int64, err error) {
+		event.Duration = state.clock.Now().Sub(event.Start)
+		event.Rows = rows
+		event.Err = err
+		for i := len(state.hooks) - 1; i >= 0; i-- {
+			state.hooks[i].AfterQuery(contexts[i], event)
+		}
+		leave()
+	}
+}
@@ -0,0 +1,119 @@
+package octobe_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ponrove/octobe"
+	"github.com/stretchr/testify/require"
+)
+
+type hookKey struct{}
+
+// recordingHook records the calls made to it in calls.
+type recordingHook struct {
+	name  string
+	calls *[]string
+	event *octobe.QueryEvent
+}
+
+func (h *recordingHook) BeforeQuery(ctx context.Context, event *octobe.QueryEvent) context.Context {
+	*h.calls = append(*h.calls, "before "+h.name)
+	return context.WithValue(ctx, hookKey{}, h.name)
+}
+
+func (h *recordingHook) AfterQuery(ctx context.Context, event *octobe.QueryEvent) {
+	*h.calls = append(*h.calls, "after "+h.name+" "+ctx.Value(hookKey{}).(string))
+	h.event = event
+}
+
+func TestQueryHook(t *testing.T) {
+	var calls []string
+	first := &recordingHook{name: "first", calls: &calls}
+	second := &recordingHook{name: "second", calls: &calls}
+
+	d := &fakeDriver{}
+	ob, err := octobe.New(d.open(), octobe.WithQueryHook(first, second))
+	require.NoError(t, err)
+
+	_, err = ob.Begin(context.Background())
+	require.NoError(t, err)
+
+	expected := errors.New("failed")
+	ctx, done := octobe.BeginQuery(d.sessions[0].ctx, octobe.OperationExec, "DELETE FROM products WHERE id = $1", []any{1})
+	require.Equal(t, "second", ctx.Value(hookKey{}))
+	done(-1, expected)
+
+	require.Equal(t, []string{"before first", "before second", "after second second", "after first first"}, calls)
+	require.Equal(t, octobe.OperationExec, first.event.Operation)
+	require.Equal(t, "DELETE FROM products WHERE id = $1", first.event.Query)
+	require.Equal(t, []any{1}, first.event.Args)
+	require.ErrorIs(t, first.event.Err, expected)
+	require.False(t, first.event.Start.IsZero())
+}
+
+func TestBeginQueryWithoutHooks(t *testing.T) {
+	ctx := context.Background()
+	queryCtx, done := octobe.BeginQuery(ctx, octobe.OperationQuery, "SELECT 1", nil)
+	require.Equal(t, ctx, queryCtx)
+	done(0, nil)
+}
+
+// sessionRecorder records the session events it is invoked with.
+type sessionRecorder struct {
+	events []octobe.SessionEvent
+}
+
+func (r *sessionRecorder) BeforeQuery(ctx context.Context, _ *octobe.QueryEvent) context.Context {
+	return ctx
+}
+func (r *sessionRecorder) AfterQuery(context.Context, *octobe.QueryEvent) {}
+func (r *sessionRecorder) BeforeSession(ctx context.Context, _ *octobe.SessionEvent) context.Context {
+	return ctx
+}
+
+func (r *sessionRecorder) AfterSession(_ context.Context, event *octobe.SessionEvent) {
+	r.events = append(r.events, *event)
+}
+
+func TestSessionHook(t *testing.T) {
+	recorder := &sessionRecorder{}
+	ob, err := octobe.New((&fakeDriver{}).open(), octobe.WithQueryHook(recorder))
+	require.NoError(t, err)
+
+	_, err = ob.Begin(context.Background())
+	require.NoError(t, err)
+	require.Len(t, recorder.events, 1)
+	require.False(t, recorder.events[0].Transaction)
+
+	session, err := ob.Begin(context.Background(), withTx())
+	require.NoError(t, err)
+	require.Len(t, recorder.events, 1)
+
+	require.NoError(t, session.Commit())
+	require.NoError(t, session.Rollback())
+	require.Len(t, recorder.events, 2)
+	require.True(t, recorder.events[1].Transaction)
+	require.True(t, recorder.events[1].Committed)
+}
+
+func TestQueryHookNotInherited(t *testing.T) {
+	outerDriver := &fakeDriver{}
+	outer, err := octobe.New(outerDriver.open(), octobe.WithQueryHook(&sessionRecorder{}))
+	require.NoError(t, err)
+	innerDriver := &fakeDriver{}
+	inner, err := octobe.New(innerDriver.open())
+	require.NoError(t, err)
+
+	_, err = outer.Begin(context.Background())
+	require.NoError(t, err)
+
+	// A session of an instance without hooks, started within a session of one with hooks, does not invoke them.
+	_, err = inner.Begin(outerDriver.sessions[0].ctx)
+	require.NoError(t, err)
+	ctx := innerDriver.sessions[0].ctx
+	queryCtx, done := octobe.BeginQuery(ctx, octobe.OperationExec, "SELECT 1", nil)
+	require.Equal(t, ctx, queryCtx)
+	done(0, nil)
+}
@@ -0,0 +1,152 @@
+// Package dbtag maps struct fields to database column names using db struct tags. A field tagged `db:"name"` maps to
+// the column name, a field tagged `db:"-"` is ignored, and an exported field without a tag maps to its lowercased
+// name. Anonymous embedded structs without a tag are flattened into the parent struct.
+package dbtag
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Field is a struct field mapped to a column.
+type Field struct {
+	// Column is the name of the column the field maps to.
+	Column string
+	// Index is the index sequence of the field, for use with reflect.Value.FieldByIndex.
+	Index []int
+}
+
+// cache holds the fields of previously inspected struct types.
+var cache sync.Map // map[reflect.Type][]Field
+
+// Fields returns the fields of struct type t that map to columns, in declaration order.
+func Fields(t reflect.Type) []Field {
+	if fields, ok := cache.Load(t); ok {
+		return fields.([]Field)
+	}
+	fields := collect(t, nil)
+	cache.Store(t, fields)
+	return fields
+}
+
+// Columns returns the column names of struct type t, in declaration order.
+func Columns(t reflect.Type) []string {
+	fields := Fields(t)
+	columns := make([]string, len(fields))
+	for i, field := range fields {
+		columns[i] = field.Column
+	}
+	return columns
+}
+
+// StructType returns the struct type behind v, dereferencing pointers and slice element types, so that a struct, a
+// pointer to a struct or a slice of either can be used to describe columns.
+func StructType(v any) (reflect.Type, bool) {
+	t := reflect.TypeOf(v)
+	for t != nil && (t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice) {
+		t = t.Elem()
+	}
+	return t, t != nil && t.Kind() == reflect.Struct
+}
+
+// collect returns the mapped fields of t, prefixing their index with parent.
+func collect(t reflect.Type, parent []int) []Field {
+	var fields []Field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, tagged := sf.Tag.Lookup("db")
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "-" {
+			continue
+		}
+
+		index := make([]int, len(parent)+1)
+		copy(index, parent)
+		index[len(parent)] = i
+
+		if sf.Anonymous && !tagged {
+			ft := sf.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				fields = append(fields, collect(ft, index)...)
+				continue
+			}
+		}
+
+		if !sf.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = strings.ToLower(sf.Name)
+		}
+		fields = append(fields, Field{Column: name, Index: index})
+	}
+	return fields
+}
+
+// Targets returns pointers to the fields of the addressable struct v that the columns map to, in the order of columns,
+// for use as scan destinations. Nil embedded struct pointers leading to a field are allocated. It fails if a column
+// does not map to a field.
+func Targets(v reflect.Value, columns []string) ([]any, error) {
+	index := make(map[string][]int)
+	for _, field := range Fields(v.Type()) {
+		index[field.Column] = field.Index
+	}
+
+	targets := make([]any, len(columns))
+	for i, column := range columns {
+		fieldIndex, ok := index[column]
+		if !ok {
+			return nil, fmt.Errorf("column %q does not map to a field of %s", column, v.Type())
+		}
+		targets[i] = fieldByIndex(v, fieldIndex).Addr().Interface()
+	}
+	return targets, nil
+}
+
+// fieldByIndex returns the field of v at index, allocating nil embedded struct pointers on the way.
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// SliceOf returns the slice dest points to and the struct type of its elements, and whether the elements are pointers
+// to structs. It fails if dest is not a non-nil pointer to a slice of structs or struct pointers.
+func SliceOf(dest any) (reflect.Value, reflect.Type, bool, error) {
+	value := reflect.ValueOf(dest)
+	if value.Kind() != reflect.Pointer || value.IsNil() || value.Elem().Kind() != reflect.Slice {
+		return reflect.Value{}, nil, false, fmt.Errorf("destination must be a non-nil pointer to a slice of structs, got %T", dest)
+	}
+	slice := value.Elem()
+	elem := slice.Type().Elem()
+	pointer := elem.Kind() == reflect.Pointer
+	if pointer {
+		elem = elem.Elem()
+	}
+	if elem.Kind() != reflect.Struct {
+		return reflect.Value{}, nil, false, fmt.Errorf("destination must be a non-nil pointer to a slice of structs, got %T", dest)
+	}
+	return slice, elem, pointer, nil
+}
+
+// StructOf returns the struct dest points to. It fails if dest is not a non-nil pointer to a struct.
+func StructOf(dest any) (reflect.Value, error) {
+	value := reflect.ValueOf(dest)
+	if value.Kind() != reflect.Pointer || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}, fmt.Errorf("destination must be a non-nil pointer to a struct, got %T", dest)
+	}
+	return value.Elem(), nil
+}
@@ -0,0 +1,88 @@
+package dbtag_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ponrove/octobe/internal/dbtag"
+	"github.com/stretchr/testify/require"
+)
+
+type Base struct {
+	ID      int `db:"id"`
+	Created string
+}
+
+type Product struct {
+	Base
+	Name     string `db:"name"`
+	Price    int    `db:"price,omitempty"`
+	Ignored  string `db:"-"`
+	internal string
+}
+
+func TestFields(t *testing.T) {
+	fields := dbtag.Fields(reflect.TypeOf(Product{}))
+	require.Equal(t, []dbtag.Field{
+		{Column: "id", Index: []int{0, 0}},
+		{Column: "created", Index: []int{0, 1}},
+		{Column: "name", Index: []int{1}},
+		{Column: "price", Index: []int{2}},
+	}, fields)
+
+	// The second lookup is served from the cache and must be identical.
+	require.Equal(t, fields, dbtag.Fields(reflect.TypeOf(Product{})))
+	require.Equal(t, []string{"id", "created", "name", "price"}, dbtag.Columns(reflect.TypeOf(Product{})))
+}
+
+func TestStructType(t *testing.T) {
+	for _, v := range []any{Product{}, &Product{}, []Product{}, &[]*Product{}} {
+		typ, ok := dbtag.StructType(v)
+		require.True(t, ok)
+		require.Equal(t, reflect.TypeOf(Product{}), typ)
+	}
+
+	_, ok := dbtag.StructType(1)
+	require.False(t, ok)
+	_, ok = dbtag.StructType(nil)
+	require.False(t, ok)
+}
+
+func TestTargets(t *testing.T) {
+	type Audit struct {
+		CreatedBy string `db:"created_by"`
+	}
+	type Event struct {
+		ID int `db:"id"`
+		*Audit
+	}
+
+	var event Event
+	targets, err := dbtag.Targets(reflect.ValueOf(&event).Elem(), []string{"created_by", "id"})
+	require.NoError(t, err)
+	*targets[0].(*string) = "alice"
+	*targets[1].(*int) = 1
+	require.Equal(t, Event{ID: 1, Audit: &Audit{CreatedBy: "alice"}}, event)
+
+	_, err = dbtag.Targets(reflect.ValueOf(&event).Elem(), []string{"id", "missing"})
+	require.ErrorContains(t, err, `column "missing"`)
+}
+
+func TestStructOfAndSliceOf(t *testing.T) {
+	_, err := dbtag.StructOf(&Product{})
+	require.NoError(t, err)
+	_, err = dbtag.StructOf(Product{})
+	require.Error(t, err)
+	_, err = dbtag.StructOf((*Product)(nil))
+	require.Error(t, err)
+
+	_, elem, pointer, err := dbtag.SliceOf(&[]*Product{})
+	require.NoError(t, err)
+	require.Equal(t, reflect.TypeOf(Product{}), elem)
+	require.True(t, pointer)
+
+	_, _, _, err = dbtag.SliceOf([]Product{})
+	require.Error(t, err)
+	_, _, _, err = dbtag.SliceOf(&[]int{})
+	require.Error(t, err)
+}
@@ -0,0 +1,277 @@
+// Package named rewrites queries with named parameters of the form :name or @name into queries with the positional
+// placeholders of a driver. Named parameters inside string literals, quoted identifiers and comments are left alone, as are
+// PostgreSQL casts like value::text and ClickHouse query parameters like {id:UInt64}.
+package named
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/ponrove/octobe/internal/dbtag"
+)
+
+// Style is the placeholder style of a driver.
+type Style int
+
+const (
+	// Dollar rewrites named parameters to $1, $2 and so on, a repeated name reuses its placeholder. It is the style of
+	// PostgreSQL, where dollar quoted strings are skipped as well.
+	Dollar Style = iota
+	// Question rewrites every named parameter to ?, a repeated name repeats its argument. It is the style of ClickHouse,
+	// where backquoted identifiers, backslash escapes and query parameters in braces are skipped as well.
+	Question
+)
+
+// Compile rewrites the named parameters in query to positional placeholders of style. It returns the rewritten query
+// and the names in the order their arguments must be passed, names is empty if query has no named parameters.
+func Compile(query string, style Style) (string, []string) {
+	var (
+		b         strings.Builder
+		names     []string
+		positions map[string]int
+		last      int
+		brackets  int
+	)
+
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			i = skipLineComment(query, i)
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			i = skipBlockComment(query, i)
+		case c == '\'' || c == '"' || (c == '`' && style == Question):
+			i = skipQuoted(query, i, c, style == Question)
+		case c == '$' && style == Dollar:
+			i = skipDollarQuoted(query, i)
+		case c == '{' && style == Question:
+			end := strings.IndexByte(query[i:], '}')
+			if end < 0 {
+				i = len(query)
+			} else {
+				i += end + 1
+			}
+		case c == ':' && i+1 < len(query) && query[i+1] == ':':
+			i += 2
+		case c == '[' || c == ']':
+			if c == '[' {
+				brackets++
+			} else if brackets > 0 {
+				brackets--
+			}
+			i++
+		case (c == ':' || c == '@') && i+1 < len(query) && isNameStart(query[i+1]) &&
+			(i == 0 || !isParameterPrefix(c, query[i-1], brackets > 0)):
+			end := i + 1
+			for end < len(query) && isNameChar(query[end]) {
+				end++
+			}
+			name := query[i+1 : end]
+
+			b.WriteString(query[last:i])
+			switch style {
+			case Dollar:
+				if positions == nil {
+					positions = make(map[string]int)
+				}
+				position, ok := positions[name]
+				if !ok {
+					names = append(names, name)
+					position = len(names)
+					positions[name] = position
+				}
+				b.WriteString("$" + strconv.Itoa(position))
+			case Question:
+				names = append(names, name)
+				b.WriteByte('?')
+			}
+			i, last = end, end
+		default:
+			i++
+		}
+	}
+
+	if len(names) == 0 {
+		return query, nil
+	}
+	b.WriteString(query[last:])
+	return b.String(), names
+}
+
+// Bind binds the fields of struct v, mapped to columns by their db tags, to the parameters of query. If query has named
+// parameters, they are rewritten to placeholders of style and each is bound to the field of the same name. Otherwise
+// the query is returned as is, with the values of all fields in declaration order as positional arguments.
+func Bind(query string, style Style, v any) (string, []any, error) {
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return "", nil, fmt.Errorf("cannot bind arguments from nil %T", v)
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return "", nil, fmt.Errorf("cannot bind arguments from %T, expected a struct", v)
+	}
+
+	fields := dbtag.Fields(value.Type())
+	query, names := Compile(query, style)
+	if len(names) == 0 {
+		args := make([]any, len(fields))
+		for i, field := range fields {
+			args[i] = fieldValue(value, field.Index)
+		}
+		return query, args, nil
+	}
+
+	index := make(map[string][]int, len(fields))
+	for _, field := range fields {
+		index[field.Column] = field.Index
+	}
+
+	args := make([]any, len(names))
+	for i, name := range names {
+		fieldIndex, ok := index[name]
+		if !ok {
+			return "", nil, fmt.Errorf("no field of %s for parameter :%s", value.Type(), name)
+		}
+		args[i] = fieldValue(value, fieldIndex)
+	}
+	return query, args, nil
+}
+
+// BindMap binds the values of args to the named parameters of query, rewriting them to placeholders of style. Every
+// named parameter must have a value in args, values without a parameter are ignored.
+func BindMap(query string, style Style, args map[string]any) (string, []any, error) {
+	query, names := Compile(query, style)
+	values := make([]any, len(names))
+	for i, name := range names {
+		value, ok := args[name]
+		if !ok {
+			return "", nil, fmt.Errorf("no argument for parameter :%s", name)
+		}
+		values[i] = value
+	}
+	return query, values, nil
+}
+
+// Args returns the values of args by name if they are sql.NamedArg values, as created by sql.Named, to be bound with
+// BindMap. It returns false if args holds no sql.NamedArg, and an error if named and positional arguments are mixed.
+func Args(args []any) (map[string]any, bool, error) {
+	var values map[string]any
+	var count int
+	for _, arg := range args {
+		namedArg, ok := arg.(sql.NamedArg)
+		if !ok {
+			continue
+		}
+		if values == nil {
+			values = make(map[string]any, len(args))
+		}
+		values[namedArg.Name] = namedArg.Value
+		count++
+	}
+	if values == nil {
+		return nil, false, nil
+	}
+	if count != len(args) {
+		return nil, false, errors.New("cannot mix sql.Named arguments with positional arguments")
+	}
+	return values, true, nil
+}
+
+// fieldValue returns the value of the field at index, or nil if the field is behind a nil embedded pointer.
+func fieldValue(value reflect.Value, index []int) any {
+	field, err := value.FieldByIndexErr(index)
+	if err != nil {
+		return nil
+	}
+	return field.Interface()
+}
+
+// skipLineComment returns the index after the line comment that starts at i.
+func skipLineComment(query string, i int) int {
+	end := strings.IndexByte(query[i:], '\n')
+	if end < 0 {
+		return len(query)
+	}
+	return i + end + 1
+}
+
+// skipBlockComment returns the index after the block comment that starts at i.
+func skipBlockComment(query string, i int) int {
+	end := strings.Index(query[i+2:], "*/")
+	if end < 0 {
+		return len(query)
+	}
+	return i + 2 + end + 2
+}
+
+// skipQuoted returns the index after the quoted literal or identifier that starts at i. A doubled quote character is
+// an escaped quote, and if backslashes is set a backslash escapes the following character.
+func skipQuoted(query string, i int, quote byte, backslashes bool) int {
+	i++
+	for i < len(query) {
+		switch query[i] {
+		case '\\':
+			if backslashes {
+				i += 2
+				continue
+			}
+		case quote:
+			if i+1 < len(query) && query[i+1] == quote {
+				i += 2
+				continue
+			}
+			return i + 1
+		}
+		i++
+	}
+	return i
+}
+
+// skipDollarQuoted returns the index after the dollar quoted string that starts at i, or the index after the dollar
+// sign if it does not start a dollar quoted string, as is the case for positional parameters like $1.
+func skipDollarQuoted(query string, i int) int {
+	if i > 0 && isNameChar(query[i-1]) {
+		return i + 1
+	}
+	for j := i + 1; j < len(query); j++ {
+		c := query[j]
+		if c == '$' {
+			tag := query[i : j+1]
+			end := strings.Index(query[j+1:], tag)
+			if end < 0 {
+				return len(query)
+			}
+			return j + 1 + end + len(tag)
+		}
+		if !isNameChar(c) || (j == i+1 && !isNameStart(c)) {
+			return i + 1
+		}
+	}
+	return i + 1
+}
+
+// isParameterPrefix reports whether the character before a parameter marker c prevents it from starting a named
+// parameter, as in casts like value::text, operators like @@, addresses like user@host and, inside brackets, array
+// slices like arr[1:n] and arr[lo:hi].
+func isParameterPrefix(c, before byte, inBrackets bool) bool {
+	if before == c || (c == '@' && isNameChar(before)) {
+		return true
+	}
+	return c == ':' && inBrackets && (isNameChar(before) || before == ']')
+}
+
+// isNameStart reports whether c can start a parameter name.
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// isNameChar reports whether c can be part of a parameter name.
+func isNameChar(c byte) bool {
+	return isNameStart(c) || (c >= '0' && c <= '9')
+}
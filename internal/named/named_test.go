@@ -0,0 +1,118 @@
+package named_test
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/ponrove/octobe/internal/named"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name          string
+		query         string
+		style         named.Style
+		expectedQuery string
+		expectedNames []string
+	}{
+		{
+			name:          "no named parameters",
+			query:         "SELECT id FROM products WHERE id = $1",
+			style:         named.Dollar,
+			expectedQuery: "SELECT id FROM products WHERE id = $1",
+		},
+		{
+			name:          "dollar placeholders reuse positions",
+			query:         "UPDATE products SET name = :name, updated_by = :user WHERE id = :id AND created_by = :user",
+			style:         named.Dollar,
+			expectedQuery: "UPDATE products SET name = $1, updated_by = $2 WHERE id = $3 AND created_by = $2",
+			expectedNames: []string{"name", "user", "id"},
+		},
+		{
+			name:          "question placeholders repeat arguments",
+			query:         "SELECT * FROM events WHERE user = :user OR owner = :user",
+			style:         named.Question,
+			expectedQuery: "SELECT * FROM events WHERE user = ? OR owner = ?",
+			expectedNames: []string{"user", "user"},
+		},
+		{
+			name:          "casts, literals and comments are skipped",
+			query:         "SELECT ':x', \":y\", $$ :z $$ -- :c\n, :id::text /* :b */ FROM t",
+			style:         named.Dollar,
+			expectedQuery: "SELECT ':x', \":y\", $$ :z $$ -- :c\n, $1::text /* :b */ FROM t",
+			expectedNames: []string{"id"},
+		},
+		{
+			name:          "clickhouse query parameters and identifiers are skipped",
+			query:         "SELECT `a:b`, 'it\\':s' FROM t WHERE id = {id:UInt64} AND name = :name",
+			style:         named.Question,
+			expectedQuery: "SELECT `a:b`, 'it\\':s' FROM t WHERE id = {id:UInt64} AND name = ?",
+			expectedNames: []string{"name"},
+		},
+		{
+			name:          "at sign parameters share positions with colon parameters",
+			query:         "SELECT * FROM t WHERE id = @id AND (owner = :id OR 'a@b' = @email) AND doc @@ q AND tags @> '{a}'",
+			style:         named.Dollar,
+			expectedQuery: "SELECT * FROM t WHERE id = $1 AND (owner = $1 OR 'a@b' = $2) AND doc @@ q AND tags @> '{a}'",
+			expectedNames: []string{"id", "email"},
+		},
+		{
+			name:          "array slices are not parameters",
+			query:         "SELECT tags[1:2] FROM t WHERE id = :id",
+			style:         named.Dollar,
+			expectedQuery: "SELECT tags[1:2] FROM t WHERE id = $1",
+			expectedNames: []string{"id"},
+		},
+		{
+			name:          "array slices with bounds are not parameters",
+			query:         "SELECT tags[1:n], tags[lo:hi], grid[1:2][idx[1]:n], tags[:lo] FROM t WHERE id = :id",
+			style:         named.Dollar,
+			expectedQuery: "SELECT tags[1:n], tags[lo:hi], grid[1:2][idx[1]:n], tags[$1] FROM t WHERE id = $2",
+			expectedNames: []string{"lo", "id"},
+		},
+		{
+			name:          "parameters in array literals",
+			query:         "SELECT * FROM t WHERE has([:a, :b], id)",
+			style:         named.Question,
+			expectedQuery: "SELECT * FROM t WHERE has([?, ?], id)",
+			expectedNames: []string{"a", "b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, names := named.Compile(tt.query, tt.style)
+			require.Equal(t, tt.expectedQuery, query)
+			require.Equal(t, tt.expectedNames, names)
+		})
+	}
+}
+
+func TestBindMap(t *testing.T) {
+	query, args, err := named.BindMap("INSERT INTO t (id, name) VALUES (:id, @name)", named.Question, map[string]any{"id": 1, "name": "a", "unused": true})
+	require.NoError(t, err)
+	require.Equal(t, "INSERT INTO t (id, name) VALUES (?, ?)", query)
+	require.Equal(t, []any{1, "a"}, args)
+
+	_, _, err = named.BindMap("SELECT * FROM t WHERE id = :id", named.Dollar, map[string]any{})
+	require.EqualError(t, err, "no argument for parameter :id")
+}
+
+func TestArgs(t *testing.T) {
+	values, ok, err := named.Args([]any{sql.Named("id", 1), sql.Named("name", "chair")})
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, map[string]any{"id": 1, "name": "chair"}, values)
+
+	_, ok, err = named.Args([]any{1, "chair"})
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	_, ok, err = named.Args(nil)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	_, _, err = named.Args([]any{sql.Named("id", 1), "chair"})
+	require.Error(t, err)
+}
@@ -0,0 +1,143 @@
+package introspect
+
+import (
+	"strings"
+
+	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/driver/clickhouse"
+)
+
+// clickhouseSource reads the catalog from the system tables.
+type clickhouseSource struct{}
+
+// Ensure clickhouseSource implements the Source interface.
+var _ Source[clickhouse.Builder] = clickhouseSource{}
+
+// ClickHouse returns a source for the clickhouse driver that reads the catalog from system.tables, system.columns and
+// system.data_skipping_indices. The primary key of a table is reported as an index named PRIMARY, its data skipping
+// indexes with their expressions. ClickHouse keeps no catalog of constraints, so none are reported.
+func ClickHouse() Source[clickhouse.Builder] {
+	return clickhouseSource{}
+}
+
+// Tables returns the tables of a database, views are left out.
+func (clickhouseSource) Tables(session octobe.BuilderSession[clickhouse.Builder], database string) ([]Table, error) {
+	var tables []Table
+	query := session.Builder()(`
+		SELECT database, name
+		FROM system.tables
+		WHERE database = if(empty(?), currentDatabase(), ?) AND NOT is_temporary AND engine NOT LIKE '%View'
+		ORDER BY name`)
+	err := query.Arguments(database, database).Query(func(rows clickhouse.Rows) error {
+		for rows.Next() {
+			var t Table
+			if err := rows.Scan(&t.Schema, &t.Name); err != nil {
+				return err
+			}
+			tables = append(tables, t)
+		}
+		return rows.Err()
+	})
+	return tables, err
+}
+
+// Columns returns the columns of table in order, columns with a Nullable type are nullable.
+func (clickhouseSource) Columns(session octobe.BuilderSession[clickhouse.Builder], database, table string) ([]Column, error) {
+	var columns []Column
+	query := session.Builder()(`
+		SELECT name, type, default_expression, position
+		FROM system.columns
+		WHERE database = if(empty(?), currentDatabase(), ?) AND table = ?
+		ORDER BY position`)
+	err := query.Arguments(database, database, table).Query(func(rows clickhouse.Rows) error {
+		for rows.Next() {
+			var c Column
+			var position uint64
+			if err := rows.Scan(&c.Name, &c.Type, &c.Default, &position); err != nil {
+				return err
+			}
+			c.Position = int(position)
+			c.Nullable = strings.HasPrefix(c.Type, "Nullable(")
+			columns = append(columns, c)
+		}
+		return rows.Err()
+	})
+	return columns, err
+}
+
+// Indexes returns the primary key and the data skipping indexes of table.
+func (clickhouseSource) Indexes(session octobe.BuilderSession[clickhouse.Builder], database, table string) ([]Index, error) {
+	var indexes []Index
+	primary := session.Builder()(`
+		SELECT primary_key
+		FROM system.tables
+		WHERE database = if(empty(?), currentDatabase(), ?) AND name = ?`)
+	err := primary.Arguments(database, database, table).Query(func(rows clickhouse.Rows) error {
+		for rows.Next() {
+			var key string
+			if err := rows.Scan(&key); err != nil {
+				return err
+			}
+			if key != "" {
+				indexes = append(indexes, Index{Name: "PRIMARY", Columns: splitExpressions(key), Primary: true})
+			}
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	skipping := session.Builder()(`
+		SELECT name, expr
+		FROM system.data_skipping_indices
+		WHERE database = if(empty(?), currentDatabase(), ?) AND table = ?
+		ORDER BY name`)
+	err = skipping.Arguments(database, database, table).Query(func(rows clickhouse.Rows) error {
+		for rows.Next() {
+			var index Index
+			var expr string
+			if err := rows.Scan(&index.Name, &expr); err != nil {
+				return err
+			}
+			index.Columns = splitExpressions(expr)
+			indexes = append(indexes, index)
+		}
+		return rows.Err()
+	})
+	return indexes, err
+}
+
+// Constraints returns no constraints, ClickHouse keeps no catalog of them.
+func (clickhouseSource) Constraints(octobe.BuilderSession[clickhouse.Builder], string, string) ([]Constraint, error) {
+	return nil, nil
+}
+
+// splitExpressions splits a comma separated list of expressions, like a key of a table, ignoring commas within
+// parentheses and quotes.
+func splitExpressions(list string) []string {
+	var (
+		expressions []string
+		depth       int
+		quote       rune
+		start       int
+	)
+	for i, r := range list {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case r == ',' && depth == 0:
+			expressions = append(expressions, strings.TrimSpace(list[start:i]))
+			start = i + 1
+		}
+	}
+	return append(expressions, strings.TrimSpace(list[start:]))
+}
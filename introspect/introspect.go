@@ -0,0 +1,139 @@
+// Package introspect reads the structure of a database through an octobe session: its tables, their columns with types,
+// their indexes and constraints. The catalog queries are driver specific and provided by a Source, the results are
+// plain Go structs, meant as the foundation for code generators and verification tooling.
+package introspect
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ponrove/octobe"
+)
+
+// ErrTableNotFound is returned by Describe when the table does not exist.
+var ErrTableNotFound = errors.New("table not found")
+
+// ConstraintType is the type of a constraint, named like in SQL.
+type ConstraintType string
+
+// The types of constraints reported by a Source.
+const (
+	PrimaryKey ConstraintType = "PRIMARY KEY"
+	Unique     ConstraintType = "UNIQUE"
+	ForeignKey ConstraintType = "FOREIGN KEY"
+	Check      ConstraintType = "CHECK"
+)
+
+// Table describes a table and its structure. Schema is the schema, or for ClickHouse the database, of the table, it is
+// empty for a table described without qualifier, which is looked up in the current schema.
+type Table struct {
+	Schema      string
+	Name        string
+	Columns     []Column
+	Indexes     []Index
+	Constraints []Constraint
+}
+
+// Column returns the column of the table with the given name.
+func (t Table) Column(name string) (Column, bool) {
+	for _, c := range t.Columns {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return Column{}, false
+}
+
+// Column describes a column of a table. Type is the type as named by the database, Default the expression of the
+// default value, which is empty if the column has none. Position starts at 1.
+type Column struct {
+	Name     string
+	Type     string
+	Nullable bool
+	Default  string
+	Position int
+}
+
+// Index describes an index of a table. Columns are the names of the indexed columns, or the expressions of expression
+// indexes, in the order of the index.
+type Index struct {
+	Name    string
+	Columns []string
+	Unique  bool
+	Primary bool
+}
+
+// Constraint describes a constraint of a table. Columns are the constrained columns in order, References is only set
+// for foreign keys and Expression only for check constraints.
+type Constraint struct {
+	Name       string
+	Type       ConstraintType
+	Columns    []string
+	References *Reference
+	Expression string
+}
+
+// Reference is the table and columns referenced by a foreign key.
+type Reference struct {
+	Schema  string
+	Table   string
+	Columns []string
+}
+
+// Source reads the catalog of a database, it holds the driver specific catalog queries. An empty schema refers to the
+// current schema.
+type Source[BUILDER any] interface {
+	// Tables returns the tables of schema, only their Schema and Name are set.
+	Tables(session octobe.BuilderSession[BUILDER], schema string) ([]Table, error)
+	// Columns returns the columns of table in order, or no columns if the table does not exist.
+	Columns(session octobe.BuilderSession[BUILDER], schema, table string) ([]Column, error)
+	// Indexes returns the indexes of table.
+	Indexes(session octobe.BuilderSession[BUILDER], schema, table string) ([]Index, error)
+	// Constraints returns the constraints of table.
+	Constraints(session octobe.BuilderSession[BUILDER], schema, table string) ([]Constraint, error)
+}
+
+// Tables returns every table of schema, or of the current schema if empty, with its columns, indexes and constraints.
+func Tables[BUILDER any](session octobe.BuilderSession[BUILDER], source Source[BUILDER], schema string) ([]Table, error) {
+	tables, err := source.Tables(session, schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	for i := range tables {
+		if err = describe(session, source, &tables[i]); err != nil {
+			return nil, err
+		}
+	}
+	return tables, nil
+}
+
+// Describe returns table, which may be qualified with its schema, with its columns, indexes and constraints. It returns
+// ErrTableNotFound if the table does not exist.
+func Describe[BUILDER any](session octobe.BuilderSession[BUILDER], source Source[BUILDER], table string) (Table, error) {
+	t := Table{Name: table}
+	if schema, name, ok := strings.Cut(table, "."); ok {
+		t.Schema, t.Name = schema, name
+	}
+	if err := describe(session, source, &t); err != nil {
+		return Table{}, err
+	}
+	if len(t.Columns) == 0 {
+		return Table{}, fmt.Errorf("%w: %s", ErrTableNotFound, table)
+	}
+	return t, nil
+}
+
+// describe reads the columns, indexes and constraints of t.
+func describe[BUILDER any](session octobe.BuilderSession[BUILDER], source Source[BUILDER], t *Table) (err error) {
+	if t.Columns, err = source.Columns(session, t.Schema, t.Name); err != nil {
+		return fmt.Errorf("failed to read columns of table %s: %w", t.Name, err)
+	}
+	if t.Indexes, err = source.Indexes(session, t.Schema, t.Name); err != nil {
+		return fmt.Errorf("failed to read indexes of table %s: %w", t.Name, err)
+	}
+	if t.Constraints, err = source.Constraints(session, t.Schema, t.Name); err != nil {
+		return fmt.Errorf("failed to read constraints of table %s: %w", t.Name, err)
+	}
+	return nil
+}
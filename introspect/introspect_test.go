@@ -0,0 +1,137 @@
+package introspect_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/driver/clickhouse"
+	chmock "github.com/ponrove/octobe/driver/clickhouse/mock"
+	"github.com/ponrove/octobe/driver/postgres"
+	"github.com/ponrove/octobe/introspect"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDescribePostgres(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("table", func(t *testing.T) {
+		mock, err := pgxmock.NewConn()
+		require.NoError(t, err)
+		defer mock.Close(ctx)
+
+		mock.ExpectQuery("FROM information_schema.columns").WithArgs("shop", "orders").
+			WillReturnRows(pgxmock.NewRows([]string{"column_name", "data_type", "nullable", "column_default", "ordinal_position"}).
+				AddRow("id", "bigint", false, "nextval('orders_id_seq'::regclass)", 1).
+				AddRow("customer_id", "bigint", false, "", 2).
+				AddRow("note", "text", true, "", 3))
+		mock.ExpectQuery("FROM pg_catalog.pg_index").WithArgs("shop", "orders").
+			WillReturnRows(pgxmock.NewRows([]string{"relname", "indisunique", "indisprimary", "column"}).
+				AddRow("orders_customer_idx", false, false, "customer_id").
+				AddRow("orders_customer_idx", false, false, "lower(note)").
+				AddRow("orders_pkey", true, true, "id"))
+		mock.ExpectQuery("FROM information_schema.table_constraints").WithArgs("shop", "orders").
+			WillReturnRows(pgxmock.NewRows([]string{"constraint_name", "constraint_type", "column_name", "check_clause", "ref_schema", "ref_table", "ref_column"}).
+				AddRow("orders_customer_fkey", "FOREIGN KEY", "customer_id", "", "shop", "customers", "id").
+				AddRow("orders_note_check", "CHECK", "", "(length(note) < 100)", "", "", "").
+				AddRow("orders_pkey", "PRIMARY KEY", "id", "", "", "", ""))
+
+		ob, err := octobe.New(postgres.OpenPGXWithConn(mock))
+		require.NoError(t, err)
+		session, err := ob.Begin(ctx)
+		require.NoError(t, err)
+
+		table, err := introspect.Describe(session, introspect.Postgres(), "shop.orders")
+		require.NoError(t, err)
+		require.Equal(t, introspect.Table{
+			Schema: "shop",
+			Name:   "orders",
+			Columns: []introspect.Column{
+				{Name: "id", Type: "bigint", Default: "nextval('orders_id_seq'::regclass)", Position: 1},
+				{Name: "customer_id", Type: "bigint", Position: 2},
+				{Name: "note", Type: "text", Nullable: true, Position: 3},
+			},
+			Indexes: []introspect.Index{
+				{Name: "orders_customer_idx", Columns: []string{"customer_id", "lower(note)"}},
+				{Name: "orders_pkey", Columns: []string{"id"}, Unique: true, Primary: true},
+			},
+			Constraints: []introspect.Constraint{
+				{
+					Name:       "orders_customer_fkey",
+					Type:       introspect.ForeignKey,
+					Columns:    []string{"customer_id"},
+					References: &introspect.Reference{Schema: "shop", Table: "customers", Columns: []string{"id"}},
+				},
+				{Name: "orders_note_check", Type: introspect.Check, Expression: "(length(note) < 100)"},
+				{Name: "orders_pkey", Type: introspect.PrimaryKey, Columns: []string{"id"}},
+			},
+		}, table)
+
+		column, ok := table.Column("note")
+		require.True(t, ok)
+		require.True(t, column.Nullable)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("missing table", func(t *testing.T) {
+		mock, err := pgxmock.NewConn()
+		require.NoError(t, err)
+		defer mock.Close(ctx)
+
+		mock.ExpectQuery("FROM information_schema.columns").WithArgs("", "missing").
+			WillReturnRows(pgxmock.NewRows([]string{"column_name", "data_type", "nullable", "column_default", "ordinal_position"}))
+		mock.ExpectQuery("FROM pg_catalog.pg_index").WithArgs("", "missing").
+			WillReturnRows(pgxmock.NewRows([]string{"relname", "indisunique", "indisprimary", "column"}))
+		mock.ExpectQuery("FROM information_schema.table_constraints").WithArgs("", "missing").
+			WillReturnRows(pgxmock.NewRows([]string{"constraint_name", "constraint_type", "column_name", "check_clause", "ref_schema", "ref_table", "ref_column"}))
+
+		ob, err := octobe.New(postgres.OpenPGXWithConn(mock))
+		require.NoError(t, err)
+		session, err := ob.Begin(ctx)
+		require.NoError(t, err)
+
+		_, err = introspect.Describe(session, introspect.Postgres(), "missing")
+		require.ErrorIs(t, err, introspect.ErrTableNotFound)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestTablesClickHouse(t *testing.T) {
+	ctx := context.Background()
+
+	mock := chmock.NewMock()
+	mock.ExpectQuery("FROM system.tables").WithArgs("", "").
+		WillReturnRows(chmock.NewMockRows([]string{"database", "name"}).AddRow("default", "events"))
+	mock.ExpectQuery("FROM system.columns").WithArgs("default", "default", "events").
+		WillReturnRows(chmock.NewMockRows([]string{"name", "type", "default_expression", "position"}).
+			AddRow("id", "UInt64", "", uint64(1)).
+			AddRow("at", "DateTime", "now()", uint64(2)).
+			AddRow("user", "Nullable(String)", "", uint64(3)))
+	mock.ExpectQuery("SELECT primary_key").WithArgs("default", "default", "events").
+		WillReturnRows(chmock.NewMockRows([]string{"primary_key"}).AddRow("id, toStartOfHour(at, 'UTC')"))
+	mock.ExpectQuery("FROM system.data_skipping_indices").WithArgs("default", "default", "events").
+		WillReturnRows(chmock.NewMockRows([]string{"name", "expr"}).AddRow("user_idx", "user"))
+
+	ob, err := octobe.New(clickhouse.OpenNativeWithConn(mock))
+	require.NoError(t, err)
+	session, err := ob.Begin(ctx)
+	require.NoError(t, err)
+
+	tables, err := introspect.Tables(session, introspect.ClickHouse(), "")
+	require.NoError(t, err)
+	require.Equal(t, []introspect.Table{{
+		Schema: "default",
+		Name:   "events",
+		Columns: []introspect.Column{
+			{Name: "id", Type: "UInt64", Position: 1},
+			{Name: "at", Type: "DateTime", Default: "now()", Position: 2},
+			{Name: "user", Type: "Nullable(String)", Nullable: true, Position: 3},
+		},
+		Indexes: []introspect.Index{
+			{Name: "PRIMARY", Columns: []string{"id", "toStartOfHour(at, 'UTC')"}, Primary: true},
+			{Name: "user_idx", Columns: []string{"user"}},
+		},
+	}}, tables)
+	require.NoError(t, mock.AllExpectationsMet())
+}
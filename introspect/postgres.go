@@ -0,0 +1,152 @@
+package introspect
+
+import (
+	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/driver/postgres"
+)
+
+// postgresSource reads the catalog from information_schema and pg_catalog.
+type postgresSource struct{}
+
+// Ensure postgresSource implements the Source interface.
+var _ Source[postgres.Builder] = postgresSource{}
+
+// Postgres returns a source for the postgres drivers. Tables, columns and constraints are read from information_schema,
+// indexes, which it does not cover, from pg_catalog. Column types are the data types of information_schema, except for
+// arrays and user-defined types like enums, which are named by their underlying type, like _int4.
+func Postgres() Source[postgres.Builder] {
+	return postgresSource{}
+}
+
+// Tables returns the tables of schema.
+func (postgresSource) Tables(session octobe.BuilderSession[postgres.Builder], schema string) ([]Table, error) {
+	var tables []Table
+	query := session.Builder()(`
+		SELECT table_schema, table_name
+		FROM information_schema.tables
+		WHERE table_schema = COALESCE(NULLIF($1, ''), current_schema()) AND table_type = 'BASE TABLE'
+		ORDER BY table_name`)
+	err := query.Arguments(schema).Query(func(rows postgres.Rows) error {
+		for rows.Next() {
+			var t Table
+			if err := rows.Scan(&t.Schema, &t.Name); err != nil {
+				return err
+			}
+			tables = append(tables, t)
+		}
+		return rows.Err()
+	})
+	return tables, err
+}
+
+// Columns returns the columns of table in order.
+func (postgresSource) Columns(session octobe.BuilderSession[postgres.Builder], schema, table string) ([]Column, error) {
+	var columns []Column
+	query := session.Builder()(`
+		SELECT column_name,
+			CASE WHEN data_type IN ('ARRAY', 'USER-DEFINED') THEN udt_name ELSE data_type END,
+			is_nullable = 'YES',
+			COALESCE(column_default, ''),
+			ordinal_position
+		FROM information_schema.columns
+		WHERE table_schema = COALESCE(NULLIF($1, ''), current_schema()) AND table_name = $2
+		ORDER BY ordinal_position`)
+	err := query.Arguments(schema, table).Query(func(rows postgres.Rows) error {
+		for rows.Next() {
+			var c Column
+			if err := rows.Scan(&c.Name, &c.Type, &c.Nullable, &c.Default, &c.Position); err != nil {
+				return err
+			}
+			columns = append(columns, c)
+		}
+		return rows.Err()
+	})
+	return columns, err
+}
+
+// Indexes returns the indexes of table, the columns of expression indexes are their expressions.
+func (postgresSource) Indexes(session octobe.BuilderSession[postgres.Builder], schema, table string) ([]Index, error) {
+	var indexes []Index
+	query := session.Builder()(`
+		SELECT i.relname, ix.indisunique, ix.indisprimary, pg_catalog.pg_get_indexdef(ix.indexrelid, k.position::int, true)
+		FROM pg_catalog.pg_index ix
+		JOIN pg_catalog.pg_class t ON t.oid = ix.indrelid
+		JOIN pg_catalog.pg_namespace n ON n.oid = t.relnamespace
+		JOIN pg_catalog.pg_class i ON i.oid = ix.indexrelid
+		CROSS JOIN LATERAL unnest(ix.indkey::int2[]) WITH ORDINALITY AS k(attnum, position)
+		WHERE n.nspname = COALESCE(NULLIF($1, ''), current_schema()) AND t.relname = $2 AND k.position <= ix.indnkeyatts
+		ORDER BY i.relname, k.position`)
+	err := query.Arguments(schema, table).Query(func(rows postgres.Rows) error {
+		for rows.Next() {
+			var index Index
+			var column string
+			if err := rows.Scan(&index.Name, &index.Unique, &index.Primary, &column); err != nil {
+				return err
+			}
+			if n := len(indexes); n > 0 && indexes[n-1].Name == index.Name {
+				indexes[n-1].Columns = append(indexes[n-1].Columns, column)
+				continue
+			}
+			index.Columns = []string{column}
+			indexes = append(indexes, index)
+		}
+		return rows.Err()
+	})
+	return indexes, err
+}
+
+// Constraints returns the primary key, unique, foreign key and check constraints of table. The not-null constraints
+// listed by information_schema as check constraints are left out, they are reported by Column.Nullable.
+func (postgresSource) Constraints(session octobe.BuilderSession[postgres.Builder], schema, table string) ([]Constraint, error) {
+	var constraints []Constraint
+	query := session.Builder()(`
+		SELECT tc.constraint_name, tc.constraint_type, COALESCE(kcu.column_name, ''), COALESCE(cc.check_clause, ''),
+			COALESCE(rk.table_schema, ''), COALESCE(rk.table_name, ''), COALESCE(rk.column_name, '')
+		FROM information_schema.table_constraints tc
+		LEFT JOIN information_schema.key_column_usage kcu
+			ON kcu.constraint_schema = tc.constraint_schema AND kcu.constraint_name = tc.constraint_name
+			AND kcu.table_name = tc.table_name
+		LEFT JOIN information_schema.check_constraints cc
+			ON cc.constraint_schema = tc.constraint_schema AND cc.constraint_name = tc.constraint_name
+		LEFT JOIN information_schema.referential_constraints rc
+			ON rc.constraint_schema = tc.constraint_schema AND rc.constraint_name = tc.constraint_name
+		LEFT JOIN information_schema.key_column_usage rk
+			ON rk.constraint_schema = rc.unique_constraint_schema AND rk.constraint_name = rc.unique_constraint_name
+			AND rk.ordinal_position = kcu.position_in_unique_constraint
+		WHERE tc.table_schema = COALESCE(NULLIF($1, ''), current_schema()) AND tc.table_name = $2
+			AND tc.constraint_type IN ('PRIMARY KEY', 'UNIQUE', 'FOREIGN KEY', 'CHECK')
+			AND tc.constraint_name NOT LIKE '%\_not\_null'
+		ORDER BY tc.constraint_name, kcu.ordinal_position`)
+	err := query.Arguments(schema, table).Query(func(rows postgres.Rows) error {
+		for rows.Next() {
+			var (
+				c                      Constraint
+				constraintType, column string
+				ref                    Reference
+				refColumn              string
+			)
+			if err := rows.Scan(&c.Name, &constraintType, &column, &c.Expression, &ref.Schema, &ref.Table, &refColumn); err != nil {
+				return err
+			}
+			c.Type = ConstraintType(constraintType)
+			if n := len(constraints); n > 0 && constraints[n-1].Name == c.Name {
+				c := &constraints[n-1]
+				c.Columns = append(c.Columns, column)
+				if c.References != nil {
+					c.References.Columns = append(c.References.Columns, refColumn)
+				}
+				continue
+			}
+			if column != "" {
+				c.Columns = []string{column}
+			}
+			if c.Type == ForeignKey {
+				ref.Columns = []string{refColumn}
+				c.References = &ref
+			}
+			constraints = append(constraints, c)
+		}
+		return rows.Err()
+	})
+	return constraints, err
+}
@@ -0,0 +1,209 @@
+// Package keyset paginates queries by keyset, also known as cursor based pagination. Instead of skipping rows with an
+// OFFSET, which gets slower with every page and skips or repeats rows when the table changes in between, every page
+// continues after the sort key of the last row of the previous page. The position is handed to clients as an opaque
+// cursor. Queries are composed with sqltpl, so pagination works with the postgres and the clickhouse drivers alike.
+//
+//	pager := keyset.New(func(p Product) []any { return []any{p.CreatedAt, p.ID} },
+//		keyset.Desc("created_at"), keyset.Desc("id"))
+//	page, err := pager.Fetch(sqltpl.Postgres().Append("SELECT id, name, created_at FROM products"), cursor, 20,
+//		func(q octobe.Sqlizer) ([]Product, error) {
+//			var products []Product
+//			return products, builder.From(q).QueryStructs(&products)
+//		})
+package keyset
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/sqltpl"
+)
+
+// ErrInvalidCursor is returned for a cursor that was not created by the paginator, like a cursor that was tampered
+// with or created for different keys.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Key is a column, or an expression, the rows are sorted by.
+type Key struct {
+	Column sqltpl.Fragment
+	Desc   bool
+}
+
+// Asc sorts by column in ascending order.
+func Asc(column sqltpl.Fragment) Key {
+	return Key{Column: column}
+}
+
+// Desc sorts by column in descending order.
+func Desc(column sqltpl.Fragment) Key {
+	return Key{Column: column, Desc: true}
+}
+
+// Page is a page of items. NextCursor continues after the last item, it is empty for the last page.
+type Page[T any] struct {
+	Items      []T
+	NextCursor string
+}
+
+// Paginator paginates queries returning items of type T.
+type Paginator[T any] struct {
+	keys   []Key
+	values func(item T) []any
+}
+
+// New creates a paginator sorting by keys, values returns the values of the keys of an item in the same order. The keys
+// must identify a row, the last key is typically the primary key. The cursor is decoded into the types values returns
+// for the zero value of T, so the values should not be nil pointers or interfaces.
+func New[T any](values func(item T) []any, keys ...Key) *Paginator[T] {
+	if len(keys) == 0 {
+		panic("keyset: no keys given")
+	}
+	return &Paginator[T]{keys: keys, values: values}
+}
+
+// Apply adds the condition continuing after cursor, which is skipped for an empty cursor, the ORDER BY of the keys and a
+// LIMIT of one more than size to tpl. The template must end where a condition can be added, see sqltpl.Template.Where.
+// The extra row tells Page whether there is a next page.
+func (p *Paginator[T]) Apply(tpl *sqltpl.Template, cursor string, size int) error {
+	if size <= 0 {
+		return fmt.Errorf("invalid page size %d", size)
+	}
+	if cursor != "" {
+		values, err := p.decode(cursor)
+		if err != nil {
+			return err
+		}
+		condition, args := p.after(values)
+		tpl.Where(condition, args...)
+	}
+
+	order := make([]string, len(p.keys))
+	for i, key := range p.keys {
+		order[i] = string(key.Column) + direction(key.Desc)
+	}
+	tpl.Append(sqltpl.Fragment(" ORDER BY "+strings.Join(order, ", ")+" LIMIT ?"), size+1)
+	return nil
+}
+
+// Page returns the page of the items fetched from a query the paginator was applied to with size.
+func (p *Paginator[T]) Page(items []T, size int) (Page[T], error) {
+	if len(items) <= size {
+		return Page[T]{Items: items}, nil
+	}
+	items = items[:size]
+	cursor, err := p.encode(p.values(items[size-1]))
+	if err != nil {
+		return Page[T]{}, err
+	}
+	return Page[T]{Items: items, NextCursor: cursor}, nil
+}
+
+// Fetch applies the paginator to tpl and returns the page of the items fetch reads with the resulting query, it is a
+// shorthand for Apply and Page.
+func (p *Paginator[T]) Fetch(tpl *sqltpl.Template, cursor string, size int, fetch func(q octobe.Sqlizer) ([]T, error)) (Page[T], error) {
+	if err := p.Apply(tpl, cursor, size); err != nil {
+		return Page[T]{}, err
+	}
+	items, err := fetch(tpl)
+	if err != nil {
+		return Page[T]{}, err
+	}
+	return p.Page(items, size)
+}
+
+// after returns the condition selecting the rows after the row with values. Keys sorted in the same direction are
+// compared as a tuple, which postgres can answer with an index on the keys, mixed directions are expanded.
+func (p *Paginator[T]) after(values []any) (sqltpl.Fragment, []any) {
+	same := true
+	for _, key := range p.keys[1:] {
+		same = same && key.Desc == p.keys[0].Desc
+	}
+
+	if same {
+		columns := make([]string, len(p.keys))
+		for i, key := range p.keys {
+			columns[i] = string(key.Column)
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(p.keys)), ", ")
+		return sqltpl.Fragment("(" + strings.Join(columns, ", ") + ") " + comparison(p.keys[0].Desc) + " (" + placeholders + ")"), values
+	}
+
+	// (a > ?) OR (a = ? AND b < ?) OR ...
+	var (
+		alternatives []string
+		args         []any
+	)
+	for i, key := range p.keys {
+		var terms []string
+		for j := range i {
+			terms = append(terms, string(p.keys[j].Column)+" = ?")
+			args = append(args, values[j])
+		}
+		terms = append(terms, string(key.Column)+" "+comparison(key.Desc)+" ?")
+		args = append(args, values[i])
+		alternatives = append(alternatives, "("+strings.Join(terms, " AND ")+")")
+	}
+	return sqltpl.Fragment("(" + strings.Join(alternatives, " OR ") + ")"), args
+}
+
+// encode returns the cursor for the key values of an item.
+func (p *Paginator[T]) encode(values []any) (string, error) {
+	if len(values) != len(p.keys) {
+		return "", fmt.Errorf("got %d values for %d keys", len(values), len(p.keys))
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decode returns the key values of cursor, typed like the values of the zero item.
+func (p *Paginator[T]) decode(cursor string) ([]any, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var raw []json.RawMessage
+	if err = json.Unmarshal(data, &raw); err != nil || len(raw) != len(p.keys) {
+		return nil, ErrInvalidCursor
+	}
+
+	var zero T
+	types := p.values(zero)
+	values := make([]any, len(raw))
+	for i, r := range raw {
+		var target reflect.Value
+		if i < len(types) && types[i] != nil {
+			target = reflect.New(reflect.TypeOf(types[i]))
+		} else {
+			target = reflect.New(reflect.TypeFor[any]())
+		}
+		if err = json.Unmarshal(r, target.Interface()); err != nil {
+			return nil, ErrInvalidCursor
+		}
+		values[i] = target.Elem().Interface()
+	}
+	return values, nil
+}
+
+// direction returns the SQL sort direction.
+func direction(desc bool) string {
+	if desc {
+		return " DESC"
+	}
+	return " ASC"
+}
+
+// comparison returns the operator selecting the rows after a key in the given direction.
+func comparison(desc bool) string {
+	if desc {
+		return "<"
+	}
+	return ">"
+}
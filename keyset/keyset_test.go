@@ -0,0 +1,83 @@
+package keyset_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/driver/postgres"
+	"github.com/ponrove/octobe/keyset"
+	"github.com/ponrove/octobe/sqltpl"
+	"github.com/stretchr/testify/require"
+)
+
+type Product struct {
+	ID        int64     `db:"id"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+func productKeys(p Product) []any { return []any{p.CreatedAt, p.ID} }
+
+func TestFetch(t *testing.T) {
+	ctx := context.Background()
+	mock, err := pgxmock.NewConn()
+	require.NoError(t, err)
+	defer mock.Close(ctx)
+
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	columns := []string{"id", "created_at"}
+	mock.ExpectQuery(`SELECT id, created_at FROM products WHERE archived = \$1 ORDER BY created_at DESC, id DESC LIMIT \$2`).
+		WithArgs(false, 3).
+		WillReturnRows(pgxmock.NewRows(columns).AddRow(int64(3), day).AddRow(int64(2), day).AddRow(int64(1), day))
+	mock.ExpectQuery(`WHERE archived = \$1 AND \(created_at, id\) < \(\$2, \$3\) ORDER BY created_at DESC, id DESC LIMIT \$4`).
+		WithArgs(false, day, int64(2), 3).
+		WillReturnRows(pgxmock.NewRows(columns).AddRow(int64(1), day))
+
+	ob, err := octobe.New(postgres.OpenPGXWithConn(mock))
+	require.NoError(t, err)
+	session, err := ob.Begin(ctx)
+	require.NoError(t, err)
+
+	pager := keyset.New(productKeys, keyset.Desc("created_at"), keyset.Desc("id"))
+	fetch := func(q octobe.Sqlizer) ([]Product, error) {
+		var products []Product
+		return products, session.Builder().From(q).QueryStructs(&products)
+	}
+	query := func() *sqltpl.Template {
+		return sqltpl.Postgres().Append("SELECT id, created_at FROM products").Where("archived = ?", false)
+	}
+
+	page, err := pager.Fetch(query(), "", 2, fetch)
+	require.NoError(t, err)
+	require.Equal(t, []Product{{ID: 3, CreatedAt: day}, {ID: 2, CreatedAt: day}}, page.Items)
+	require.NotEmpty(t, page.NextCursor)
+
+	page, err = pager.Fetch(query(), page.NextCursor, 2, fetch)
+	require.NoError(t, err)
+	require.Equal(t, []Product{{ID: 1, CreatedAt: day}}, page.Items)
+	require.Empty(t, page.NextCursor)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestApplyMixedDirections(t *testing.T) {
+	pager := keyset.New(func(p Product) []any { return []any{p.CreatedAt, p.ID} }, keyset.Desc("created_at"), keyset.Asc("id"))
+	page, err := pager.Page([]Product{{ID: 7, CreatedAt: time.Unix(0, 0).UTC()}, {ID: 8}}, 1)
+	require.NoError(t, err)
+
+	tpl := sqltpl.ClickHouse().Append("SELECT id, created_at FROM products")
+	require.NoError(t, pager.Apply(tpl, page.NextCursor, 10))
+	query, args, err := tpl.ToSql()
+	require.NoError(t, err)
+	require.Equal(t, "SELECT id, created_at FROM products WHERE ((created_at < ?) OR (created_at = ? AND id > ?)) ORDER BY created_at DESC, id ASC LIMIT ?", query)
+	require.Equal(t, []any{time.Unix(0, 0).UTC(), time.Unix(0, 0).UTC(), int64(7), 11}, args)
+}
+
+func TestInvalidCursor(t *testing.T) {
+	pager := keyset.New(productKeys, keyset.Asc("created_at"), keyset.Asc("id"))
+	for _, cursor := range []string{"not base64!", "e30", "WzFd", `WyJ0b2RheSIsMV0`} {
+		require.ErrorIs(t, pager.Apply(sqltpl.Postgres(), cursor, 10), keyset.ErrInvalidCursor, cursor)
+	}
+	require.Error(t, pager.Apply(sqltpl.Postgres(), "", 0))
+}
@@ -0,0 +1,154 @@
+package octobe
+
+import (
+	"context"
+	"log/slog"
+	"unicode/utf8"
+)
+
+// DefaultLogQueryLength is the number of bytes of a query that is logged by default, longer queries are truncated.
+const DefaultLogQueryLength = 200
+
+// LogOption is a signature that can be used for configuring the logging of WithLogger.
+type LogOption func(cfg *logConfig)
+
+// logConfig holds the configuration of the logging hook.
+type logConfig struct {
+	level       slog.Level
+	queryLength int
+	logArgs     bool
+	redact      func(index int, value any) any
+}
+
+// WithLogLevel sets the level successful operations are logged at, slog.LevelDebug by default. Failed operations are
+// always logged at slog.LevelError.
+func WithLogLevel(level slog.Level) LogOption {
+	return func(cfg *logConfig) {
+		cfg.level = level
+	}
+}
+
+// WithLogQueryLength sets the number of bytes of a query that is logged before it is truncated, zero or less logs
+// queries in full.
+func WithLogQueryLength(length int) LogOption {
+	return func(cfg *logConfig) {
+		cfg.queryLength = length
+	}
+}
+
+// WithLogArgs logs the arguments of queries, which are left out by default. Every argument is passed through redact,
+// which can replace sensitive values, a nil redact logs the arguments as they are.
+func WithLogArgs(redact func(index int, value any) any) LogOption {
+	return func(cfg *logConfig) {
+		cfg.logArgs = true
+		cfg.redact = redact
+	}
+}
+
+// WithLogger logs the sessions and queries of the instance to logger: every query with its duration, truncated SQL and
+// error, the beginning of every session, and the commit or rollback of every transaction.
+func WithLogger(logger *slog.Logger, opts ...LogOption) InstanceOption {
+	cfg := logConfig{level: slog.LevelDebug, queryLength: DefaultLogQueryLength}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return WithQueryHook(&logHook{logger: logger, cfg: cfg})
+}
+
+// logHook is the query and session hook logging for WithLogger.
+type logHook struct {
+	logger *slog.Logger
+	cfg    logConfig
+}
+
+// Ensure logHook is invoked around both queries and sessions.
+var (
+	_ QueryHook   = &logHook{}
+	_ SessionHook = &logHook{}
+)
+
+// BeforeQuery does nothing, queries are logged once they have finished.
+func (h *logHook) BeforeQuery(ctx context.Context, _ *QueryEvent) context.Context {
+	return ctx
+}
+
+// AfterQuery logs a finished query.
+func (h *logHook) AfterQuery(ctx context.Context, event *QueryEvent) {
+	attrs := []slog.Attr{
+		slog.String("driver", event.Driver.Name),
+		slog.String("operation", string(event.Operation)),
+		slog.String("query", h.truncate(event.Query)),
+		slog.Duration("duration", event.Duration),
+	}
+	if event.Rows >= 0 {
+		attrs = append(attrs, slog.Int64("rows", event.Rows))
+	}
+	if h.cfg.logArgs {
+		attrs = append(attrs, slog.Any("args", h.args(event.Args)))
+	}
+	h.log(ctx, "query", event.Err, attrs...)
+}
+
+// BeforeSession logs the beginning of a session.
+func (h *logHook) BeforeSession(ctx context.Context, event *SessionEvent) context.Context {
+	if h.logger.Enabled(ctx, h.cfg.level) {
+		h.logger.LogAttrs(ctx, h.cfg.level, "begin session", slog.String("driver", event.Driver.Name))
+	}
+	return ctx
+}
+
+// AfterSession logs the commit or rollback of a transaction, or the error a session failed to begin with.
+func (h *logHook) AfterSession(ctx context.Context, event *SessionEvent) {
+	attrs := []slog.Attr{
+		slog.String("driver", event.Driver.Name),
+		slog.Duration("duration", event.Duration),
+	}
+	switch {
+	case event.Transaction && event.Committed:
+		h.log(ctx, "commit", event.Err, attrs...)
+	case event.Transaction:
+		h.log(ctx, "rollback", event.Err, attrs...)
+	case event.Err != nil:
+		h.log(ctx, "begin session", event.Err, attrs...)
+	}
+}
+
+// log logs msg at the configured level, or at the error level along with err if it is set.
+func (h *logHook) log(ctx context.Context, msg string, err error, attrs ...slog.Attr) {
+	level := h.cfg.level
+	if err != nil {
+		level = slog.LevelError
+		attrs = append(attrs, slog.Any("error", err))
+	}
+	h.logger.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// truncate returns query cut off at the configured length.
+func (h *logHook) truncate(query string) string {
+	return truncate(query, h.cfg.queryLength)
+}
+
+// truncate returns s cut off at length bytes without splitting a character, marking the cut with an ellipsis. A length
+// of zero or less disables truncation.
+func truncate(s string, length int) string {
+	if length <= 0 || len(s) <= length {
+		return s
+	}
+	end := length
+	for end > 0 && !utf8.RuneStart(s[end]) {
+		end--
+	}
+	return s[:end] + "..."
+}
+
+// args returns the arguments of a query passed through the redact function.
+func (h *logHook) args(args []any) []any {
+	if h.cfg.redact == nil {
+		return args
+	}
+	redacted := make([]any, len(args))
+	for i, arg := range args {
+		redacted[i] = h.cfg.redact(i, arg)
+	}
+	return redacted
+}
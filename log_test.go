@@ -0,0 +1,79 @@
+package octobe_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/ponrove/octobe"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(_ []string, attr slog.Attr) slog.Attr {
+			if attr.Key == slog.TimeKey || attr.Key == "duration" {
+				return slog.Attr{}
+			}
+			return attr
+		},
+	}))
+
+	d := &fakeDriver{}
+	ob, err := octobe.New(d.open(), octobe.WithLogger(logger,
+		octobe.WithLogQueryLength(20),
+		octobe.WithLogArgs(func(index int, value any) any {
+			if index == 1 {
+				return "***"
+			}
+			return value
+		}),
+	))
+	require.NoError(t, err)
+
+	session, err := ob.Begin(context.Background(), withTx())
+	require.NoError(t, err)
+
+	_, done := octobe.BeginQuery(d.sessions[0].ctx, octobe.OperationExec, "UPDATE users SET password = $2 WHERE id = $1", []any{1, "secret"})
+	done(1, nil)
+	_, done = octobe.BeginQuery(d.sessions[0].ctx, octobe.OperationQueryRow, "SELECT 1", nil)
+	done(-1, errors.New("failed"))
+	require.NoError(t, session.Commit())
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Equal(t, []string{
+		`level=DEBUG msg="begin session" driver=""`,
+		`level=DEBUG msg=query driver="" operation=exec query="UPDATE users SET pas..." rows=1 args="[1 ***]"`,
+		`level=ERROR msg=query driver="" operation=query_row query="SELECT 1" args=[] error=failed`,
+		`level=DEBUG msg=commit driver=""`,
+	}, lines)
+}
+
+func TestWithLoggerLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+	d := &fakeDriver{}
+	ob, err := octobe.New(d.open(), octobe.WithLogger(logger))
+	require.NoError(t, err)
+
+	_, err = ob.Begin(context.Background())
+	require.NoError(t, err)
+	_, done := octobe.BeginQuery(d.sessions[0].ctx, octobe.OperationExec, "DELETE FROM users", []any{"secret"})
+	done(1, nil)
+	require.Empty(t, buf.String())
+
+	ob, err = octobe.New(d.open(), octobe.WithLogger(logger, octobe.WithLogLevel(slog.LevelInfo)))
+	require.NoError(t, err)
+	_, err = ob.Begin(context.Background())
+	require.NoError(t, err)
+	_, done = octobe.BeginQuery(d.sessions[1].ctx, octobe.OperationExec, "DELETE FROM users", []any{"secret"})
+	done(1, nil)
+	require.Contains(t, buf.String(), `msg=query`)
+	require.NotContains(t, buf.String(), "secret")
+}
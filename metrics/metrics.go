@@ -0,0 +1,198 @@
+// Package metrics exposes Prometheus metrics for Octobe. It provides a query hook that counts queries, errors and
+// transaction outcomes and observes query latency, labeled by driver, operation and an optional query name.
+package metrics
+
+import (
+	"context"
+	"strings"
+
+	"github.com/ponrove/octobe"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Result label values of the transactions counter.
+const (
+	ResultCommit   = "commit"
+	ResultRollback = "rollback"
+)
+
+// Option configures the metrics collector.
+type Option func(cfg *config)
+
+// config holds the configuration of the metrics collector.
+type config struct {
+	namespace   string
+	constLabels prometheus.Labels
+	buckets     []float64
+	namer       func(event *octobe.QueryEvent) string
+}
+
+// WithNamespace sets the namespace of the metric names, octobe by default.
+func WithNamespace(namespace string) Option {
+	return func(cfg *config) {
+		cfg.namespace = namespace
+	}
+}
+
+// WithConstLabels adds labels with fixed values to all metrics, like the name of the database.
+func WithConstLabels(labels prometheus.Labels) Option {
+	return func(cfg *config) {
+		cfg.constLabels = labels
+	}
+}
+
+// WithBuckets sets the buckets of the query latency histogram, in seconds.
+func WithBuckets(buckets []float64) Option {
+	return func(cfg *config) {
+		cfg.buckets = buckets
+	}
+}
+
+// WithQueryNamer sets the function that names a query for the query label. By default queries are named by a leading
+// "-- name: GetUser" comment, as used by sqlc, and queries without one get an empty name. The namer must return a
+// small, fixed set of names, never the query text itself.
+func WithQueryNamer(namer func(event *octobe.QueryEvent) string) Option {
+	return func(cfg *config) {
+		cfg.namer = namer
+	}
+}
+
+// Collector is an octobe.QueryHook and octobe.SessionHook recording metrics, and a prometheus.Collector to register
+// them with.
+type Collector struct {
+	namer        func(event *octobe.QueryEvent) string
+	queries      *prometheus.CounterVec
+	errors       *prometheus.CounterVec
+	duration     *prometheus.HistogramVec
+	transactions *prometheus.CounterVec
+	wait         *prometheus.HistogramVec
+}
+
+// Ensure Collector records metrics for queries and sessions and can be registered.
+var (
+	_ octobe.QueryHook     = &Collector{}
+	_ octobe.SessionHook   = &Collector{}
+	_ prometheus.Collector = &Collector{}
+)
+
+// New creates a metrics collector, to be passed to octobe.New with octobe.WithQueryHook and registered with a
+// prometheus.Registerer. A single collector can be shared by several instances.
+func New(opts ...Option) *Collector {
+	cfg := config{namespace: "octobe", buckets: prometheus.DefBuckets, namer: QueryName}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	queryLabels := []string{"driver", "operation", "query"}
+	return &Collector{
+		namer: cfg.namer,
+		queries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   cfg.namespace,
+			Name:        "queries_total",
+			Help:        "Number of queries performed.",
+			ConstLabels: cfg.constLabels,
+		}, queryLabels),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   cfg.namespace,
+			Name:        "query_errors_total",
+			Help:        "Number of queries that failed.",
+			ConstLabels: cfg.constLabels,
+		}, queryLabels),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   cfg.namespace,
+			Name:        "query_duration_seconds",
+			Help:        "Latency of queries, including reading their rows.",
+			ConstLabels: cfg.constLabels,
+			Buckets:     cfg.buckets,
+		}, queryLabels),
+		transactions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   cfg.namespace,
+			Name:        "transactions_total",
+			Help:        "Number of transactions that ended, by whether they were committed or rolled back.",
+			ConstLabels: cfg.constLabels,
+		}, []string{"driver", "result"}),
+		wait: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   cfg.namespace,
+			Name:        "session_wait_seconds",
+			Help:        "Time sessions waited for admission by octobe.WithMaxConcurrentSessions.",
+			ConstLabels: cfg.constLabels,
+			Buckets:     cfg.buckets,
+		}, []string{"driver"}),
+	}
+}
+
+// Describe sends the descriptors of the metrics to ch.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.queries.Describe(ch)
+	c.errors.Describe(ch)
+	c.duration.Describe(ch)
+	c.transactions.Describe(ch)
+	c.wait.Describe(ch)
+}
+
+// Collect sends the metrics to ch.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.queries.Collect(ch)
+	c.errors.Collect(ch)
+	c.duration.Collect(ch)
+	c.transactions.Collect(ch)
+	c.wait.Collect(ch)
+}
+
+// BeforeQuery does nothing, queries are recorded once they have finished.
+func (c *Collector) BeforeQuery(ctx context.Context, _ *octobe.QueryEvent) context.Context {
+	return ctx
+}
+
+// AfterQuery records a finished query.
+func (c *Collector) AfterQuery(_ context.Context, event *octobe.QueryEvent) {
+	labels := prometheus.Labels{
+		"driver":    event.Driver.Name,
+		"operation": string(event.Operation),
+		"query":     c.namer(event),
+	}
+	c.queries.With(labels).Inc()
+	c.duration.With(labels).Observe(event.Duration.Seconds())
+	if event.Err != nil {
+		c.errors.With(labels).Inc()
+	}
+}
+
+// BeforeSession records the time the session waited for admission, transactions are recorded once they have ended.
+func (c *Collector) BeforeSession(ctx context.Context, event *octobe.SessionEvent) context.Context {
+	c.wait.WithLabelValues(event.Driver.Name).Observe(event.Wait.Seconds())
+	return ctx
+}
+
+// AfterSession records the outcome of a transaction, sessions without a transaction are not recorded.
+func (c *Collector) AfterSession(_ context.Context, event *octobe.SessionEvent) {
+	if !event.Transaction {
+		return
+	}
+	result := ResultRollback
+	if event.Committed {
+		result = ResultCommit
+	}
+	c.transactions.WithLabelValues(event.Driver.Name, result).Inc()
+}
+
+// QueryName returns the name given to the query of event by a "-- name: GetUser" comment on one of its leading lines,
+// as used by sqlc, ignoring any annotation after the name. It returns an empty name if there is no such comment.
+func QueryName(event *octobe.QueryEvent) string {
+	query := event.Query
+	for query != "" {
+		var line string
+		line, query, _ = strings.Cut(strings.TrimLeft(query, " \t\r\n"), "\n")
+		comment, ok := strings.CutPrefix(strings.TrimSpace(line), "--")
+		if !ok {
+			return ""
+		}
+		if name, ok := strings.CutPrefix(strings.TrimSpace(comment), "name:"); ok {
+			if fields := strings.Fields(name); len(fields) > 0 {
+				return fields[0]
+			}
+			return ""
+		}
+	}
+	return ""
+}
@@ -0,0 +1,86 @@
+package metrics_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/driver/postgres"
+	"github.com/ponrove/octobe/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollector(t *testing.T) {
+	ctx := context.Background()
+	collector := metrics.New()
+	registry := prometheus.NewPedanticRegistry()
+	require.NoError(t, registry.Register(collector))
+
+	mock, err := pgxmock.NewConn()
+	require.NoError(t, err)
+	defer mock.Close(ctx)
+
+	expectedErr := errors.New("insert failed")
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE products").WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO products").WillReturnError(expectedErr)
+	mock.ExpectRollback()
+
+	ob, err := octobe.New(postgres.OpenPGXWithConn(mock), octobe.WithQueryHook(collector))
+	require.NoError(t, err)
+
+	err = ob.StartTransaction(ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+		_, err := session.Builder()("-- name: ClearPrices :exec\nUPDATE products SET price = 0").Exec()
+		return err
+	}, postgres.WithPGXTxOptions(postgres.PGXTxOptions{}))
+	require.NoError(t, err)
+
+	err = ob.StartTransaction(ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+		_, err := session.Builder()("INSERT INTO products (name) VALUES ('a')").Exec()
+		return err
+	}, postgres.WithPGXTxOptions(postgres.PGXTxOptions{}))
+	require.ErrorIs(t, err, expectedErr)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	expected := `
+# HELP octobe_queries_total Number of queries performed.
+# TYPE octobe_queries_total counter
+octobe_queries_total{driver="pgx",operation="exec",query=""} 1
+octobe_queries_total{driver="pgx",operation="exec",query="ClearPrices"} 1
+# HELP octobe_query_errors_total Number of queries that failed.
+# TYPE octobe_query_errors_total counter
+octobe_query_errors_total{driver="pgx",operation="exec",query=""} 1
+# HELP octobe_transactions_total Number of transactions that ended, by whether they were committed or rolled back.
+# TYPE octobe_transactions_total counter
+octobe_transactions_total{driver="pgx",result="commit"} 1
+octobe_transactions_total{driver="pgx",result="rollback"} 1
+`
+	require.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected),
+		"octobe_queries_total", "octobe_query_errors_total", "octobe_transactions_total"))
+	require.Equal(t, 2, testutil.CollectAndCount(collector, "octobe_query_duration_seconds"))
+	require.Equal(t, 1, testutil.CollectAndCount(collector, "octobe_session_wait_seconds"))
+}
+
+func TestQueryName(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{query: "-- name: GetUser :one\nSELECT * FROM users WHERE id = $1", expected: "GetUser"},
+		{query: "\n  -- generated\n  --name:ListUsers\nSELECT * FROM users", expected: "ListUsers"},
+		{query: "SELECT 1 -- name: NotLeading", expected: ""},
+		{query: "-- just a comment\nSELECT 1", expected: ""},
+		{query: "", expected: ""},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, metrics.QueryName(&octobe.QueryEvent{Query: tt.query}), tt.query)
+	}
+}
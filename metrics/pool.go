@@ -0,0 +1,100 @@
+package metrics
+
+import (
+	"database/sql"
+
+	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/driver/postgres"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PoolCollector is a prometheus.Collector exporting the connection pool statistics an Octobe instance reports through
+// its Stats method, so the saturation of the pool shows in dashboards. It exports the postgres.PoolStats of the pgxpool
+// driver and the sql.DBStats of the database/sql driver, labeled by driver. Instances of other drivers export nothing.
+type PoolCollector struct {
+	stats            func() octobe.Stats
+	acquiredConns    *prometheus.Desc
+	idleConns        *prometheus.Desc
+	totalConns       *prometheus.Desc
+	maxConns         *prometheus.Desc
+	queueWaiting     *prometheus.Desc
+	acquires         *prometheus.Desc
+	emptyAcquires    *prometheus.Desc
+	canceledAcquires *prometheus.Desc
+	acquireWait      *prometheus.Desc
+	newConns         *prometheus.Desc
+}
+
+// Ensure PoolCollector can be registered.
+var _ prometheus.Collector = &PoolCollector{}
+
+// NewPoolCollector creates a collector of the pool statistics returned by stats, to be registered with a
+// prometheus.Registerer. It is usually given the Stats method of an instance:
+//
+//	prometheus.MustRegister(metrics.NewPoolCollector(ob.Stats))
+//
+// The statistics are read when the metrics are collected. WithBuckets and WithQueryNamer have no effect on it.
+func NewPoolCollector(stats func() octobe.Stats, opts ...Option) *PoolCollector {
+	cfg := config{namespace: "octobe"}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(cfg.namespace, "pool", name), help, []string{"driver"},
+			cfg.constLabels)
+	}
+	return &PoolCollector{
+		stats:            stats,
+		acquiredConns:    desc("acquired_conns", "Number of connections currently in use."),
+		idleConns:        desc("idle_conns", "Number of idle connections."),
+		totalConns:       desc("total_conns", "Number of open connections, in use or idle."),
+		maxConns:         desc("max_conns", "Maximum number of open connections, zero if unlimited."),
+		queueWaiting:     desc("queue_waiting", "Number of sessions waiting in the priority queue of the pool."),
+		acquires:         desc("acquires_total", "Number of connections acquired from the pool."),
+		emptyAcquires:    desc("empty_acquires_total", "Number of acquires that waited for a connection."),
+		canceledAcquires: desc("canceled_acquires_total", "Number of acquires canceled by their context."),
+		acquireWait:      desc("acquire_wait_seconds_total", "Time spent acquiring connections from the pool."),
+		newConns:         desc("new_conns_total", "Number of connections opened by the pool."),
+	}
+}
+
+// Describe sends the descriptors of the metrics to ch.
+func (c *PoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{
+		c.acquiredConns, c.idleConns, c.totalConns, c.maxConns, c.queueWaiting,
+		c.acquires, c.emptyAcquires, c.canceledAcquires, c.acquireWait, c.newConns,
+	} {
+		ch <- desc
+	}
+}
+
+// Collect reads the pool statistics and sends the metrics to ch.
+func (c *PoolCollector) Collect(ch chan<- prometheus.Metric) {
+	metric := func(desc *prometheus.Desc, valueType prometheus.ValueType, value float64, driver string) {
+		ch <- prometheus.MustNewConstMetric(desc, valueType, value, driver)
+	}
+
+	switch stats := c.stats().Driver.(type) {
+	case postgres.PoolStats:
+		const driver = "pgxpool"
+		metric(c.acquiredConns, prometheus.GaugeValue, float64(stats.AcquiredConns), driver)
+		metric(c.idleConns, prometheus.GaugeValue, float64(stats.IdleConns), driver)
+		metric(c.totalConns, prometheus.GaugeValue, float64(stats.TotalConns), driver)
+		metric(c.maxConns, prometheus.GaugeValue, float64(stats.MaxConns), driver)
+		metric(c.queueWaiting, prometheus.GaugeValue, float64(stats.QueueWaiting), driver)
+		metric(c.acquires, prometheus.CounterValue, float64(stats.AcquireCount), driver)
+		metric(c.emptyAcquires, prometheus.CounterValue, float64(stats.EmptyAcquireCount), driver)
+		metric(c.canceledAcquires, prometheus.CounterValue, float64(stats.CanceledAcquireCount), driver)
+		metric(c.acquireWait, prometheus.CounterValue, stats.AcquireDuration.Seconds(), driver)
+		metric(c.newConns, prometheus.CounterValue, float64(stats.NewConnsCount), driver)
+	case sql.DBStats:
+		const driver = "database/sql"
+		metric(c.acquiredConns, prometheus.GaugeValue, float64(stats.InUse), driver)
+		metric(c.idleConns, prometheus.GaugeValue, float64(stats.Idle), driver)
+		metric(c.totalConns, prometheus.GaugeValue, float64(stats.OpenConnections), driver)
+		metric(c.maxConns, prometheus.GaugeValue, float64(stats.MaxOpenConnections), driver)
+		metric(c.emptyAcquires, prometheus.CounterValue, float64(stats.WaitCount), driver)
+		metric(c.acquireWait, prometheus.CounterValue, stats.WaitDuration.Seconds(), driver)
+	}
+}
@@ -0,0 +1,80 @@
+package metrics_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/driver/postgres"
+	"github.com/ponrove/octobe/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolCollector(t *testing.T) {
+	ctx := context.Background()
+
+	// The pool connects lazily, so statistics are available without a database.
+	ob, err := octobe.New(postgres.OpenPGXPool(ctx, "postgres://octobe@127.0.0.1:1/octobe?pool_max_conns=3"))
+	require.NoError(t, err)
+	defer ob.Close(ctx)
+
+	registry := prometheus.NewPedanticRegistry()
+	require.NoError(t, registry.Register(metrics.NewPoolCollector(ob.Stats,
+		metrics.WithConstLabels(prometheus.Labels{"db": "main"}))))
+
+	expected := `
+# HELP octobe_pool_max_conns Maximum number of open connections, zero if unlimited.
+# TYPE octobe_pool_max_conns gauge
+octobe_pool_max_conns{db="main",driver="pgxpool"} 3
+# HELP octobe_pool_total_conns Number of open connections, in use or idle.
+# TYPE octobe_pool_total_conns gauge
+octobe_pool_total_conns{db="main",driver="pgxpool"} 0
+# HELP octobe_pool_new_conns_total Number of connections opened by the pool.
+# TYPE octobe_pool_new_conns_total counter
+octobe_pool_new_conns_total{db="main",driver="pgxpool"} 0
+`
+	require.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected),
+		"octobe_pool_max_conns", "octobe_pool_total_conns", "octobe_pool_new_conns_total"))
+	count, err := testutil.GatherAndCount(registry)
+	require.NoError(t, err)
+	require.Equal(t, 10, count)
+}
+
+func TestPoolCollectorSQL(t *testing.T) {
+	ctx := context.Background()
+
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	db.SetMaxOpenConns(5)
+
+	ob, err := octobe.New(postgres.OpenWithConn(db))
+	require.NoError(t, err)
+	defer ob.Close(ctx)
+
+	registry := prometheus.NewPedanticRegistry()
+	require.NoError(t, registry.Register(metrics.NewPoolCollector(ob.Stats, metrics.WithNamespace("app"))))
+
+	expected := `
+# HELP app_pool_max_conns Maximum number of open connections, zero if unlimited.
+# TYPE app_pool_max_conns gauge
+app_pool_max_conns{driver="database/sql"} 5
+`
+	require.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected), "app_pool_max_conns"))
+	// The database/sql driver has no statistics for the priority queue, acquires and new connections.
+	count, err := testutil.GatherAndCount(registry)
+	require.NoError(t, err)
+	require.Equal(t, 6, count)
+}
+
+func TestPoolCollectorWithoutPool(t *testing.T) {
+	registry := prometheus.NewPedanticRegistry()
+	require.NoError(t, registry.Register(metrics.NewPoolCollector(func() octobe.Stats { return octobe.Stats{} })))
+
+	count, err := testutil.GatherAndCount(registry)
+	require.NoError(t, err)
+	require.Zero(t, count)
+}
@@ -0,0 +1,108 @@
+package migrate
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/driver/clickhouse"
+)
+
+// clickhouseStore records applied migrations in a ClickHouse table.
+type clickhouseStore struct {
+	table string
+	lock  string
+}
+
+// Ensure clickhouseStore implements the Store interface.
+var _ Store[clickhouse.Builder] = &clickhouseStore{}
+
+// ClickHouse returns a store for the clickhouse driver that records applied migrations in table, which may be database
+// qualified. DefaultTable is used if table is empty. ClickHouse has neither transactions nor unique constraints: a
+// migration that fails halfway leaves the statements it executed before failing, and the lock cannot be taken
+// atomically, it only guards against runners that do not start at the very same moment.
+func ClickHouse(table string) Store[clickhouse.Builder] {
+	if table == "" {
+		table = DefaultTable
+	}
+	parts := strings.Split(table, ".")
+	for i, part := range parts {
+		parts[i] = "`" + strings.ReplaceAll(part, "`", "\\`") + "`"
+	}
+	name := strings.Join(parts, ".")
+	return &clickhouseStore{table: name, lock: strings.TrimSuffix(name, "`") + "_lock`"}
+}
+
+// Init creates the bookkeeping and lock tables if they do not exist. Reverted migrations are recorded as not applied,
+// the latest record of a version replaces the earlier ones.
+func (s *clickhouseStore) Init(session octobe.BuilderSession[clickhouse.Builder]) error {
+	err := session.Builder()(fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			version    Int64,
+			name       String,
+			checksum   String,
+			applied    Bool,
+			applied_at DateTime64(6) DEFAULT now64(6)
+		) ENGINE = ReplacingMergeTree(applied_at) ORDER BY version`, s.table)).Exec()
+	if err != nil {
+		return err
+	}
+	return session.Builder()(fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			id        UInt8,
+			locked_at DateTime DEFAULT now()
+		) ENGINE = MergeTree ORDER BY id`, s.lock)).Exec()
+}
+
+// Lock takes the migration lock by inserting a row into the empty lock table.
+func (s *clickhouseStore) Lock(session octobe.BuilderSession[clickhouse.Builder]) error {
+	var locks uint64
+	err := session.Builder()(fmt.Sprintf(`SELECT count() FROM %s`, s.lock)).QueryRow(&locks)
+	if err != nil {
+		return err
+	}
+	if locks > 0 {
+		return ErrLocked
+	}
+	return session.Builder()(fmt.Sprintf(`INSERT INTO %s (id) VALUES (1)`, s.lock)).Exec()
+}
+
+// Unlock releases the migration lock.
+func (s *clickhouseStore) Unlock(session octobe.BuilderSession[clickhouse.Builder]) error {
+	return session.Builder()(fmt.Sprintf(`TRUNCATE TABLE %s`, s.lock)).Exec()
+}
+
+// Applied returns the checksums of all applied migrations, keyed by version.
+func (s *clickhouseStore) Applied(session octobe.BuilderSession[clickhouse.Builder]) (map[int64]string, error) {
+	applied := make(map[int64]string)
+	query := session.Builder()(fmt.Sprintf(`SELECT version, checksum FROM %s FINAL WHERE applied`, s.table))
+	err := query.Query(func(rows clickhouse.Rows) error {
+		for rows.Next() {
+			var version int64
+			var checksum string
+			if err := rows.Scan(&version, &checksum); err != nil {
+				return err
+			}
+			applied[version] = checksum
+		}
+		return rows.Err()
+	})
+	return applied, err
+}
+
+// Record marks a migration as applied.
+func (s *clickhouseStore) Record(session octobe.BuilderSession[clickhouse.Builder], version int64, name, checksum string) error {
+	query := session.Builder()(fmt.Sprintf(`INSERT INTO %s (version, name, checksum, applied) VALUES (?, ?, ?, true)`, s.table))
+	return query.Arguments(version, name, checksum).Exec()
+}
+
+// Remove marks a migration as no longer applied.
+func (s *clickhouseStore) Remove(session octobe.BuilderSession[clickhouse.Builder], version int64) error {
+	query := session.Builder()(fmt.Sprintf(`INSERT INTO %s (version, name, checksum, applied) VALUES (?, '', '', false)`, s.table))
+	return query.Arguments(version).Exec()
+}
+
+// ExecScript executes a script of one or more statements.
+func (s *clickhouseStore) ExecScript(session octobe.BuilderSession[clickhouse.Builder], script string) error {
+	return clickhouse.ExecScript(session, script)
+}
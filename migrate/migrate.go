@@ -0,0 +1,378 @@
+// Package migrate applies versioned schema migrations through an Octobe instance. Migrations are SQL scripts or Go
+// functions with an up and an optional down direction, identified by a version number. Applied migrations are recorded
+// in a bookkeeping table, and a lock table keeps concurrent runners, like several replicas of a service starting at the
+// same time, from applying migrations twice.
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ponrove/octobe"
+)
+
+// DefaultTable is the name of the bookkeeping table used when a store is created without a table name. The lock table
+// is named after it with a _lock suffix.
+const DefaultTable = "schema_migrations"
+
+var (
+	// ErrLocked is returned when another runner holds the migration lock. A lock left behind by a crashed runner can be
+	// released with Runner.Unlock.
+	ErrLocked = errors.New("migrations are locked by another runner")
+
+	// ErrChecksumMismatch is returned when a migration has already been applied, but its content has changed since.
+	ErrChecksumMismatch = errors.New("migration has changed since it was applied")
+
+	// ErrDuplicateMigration is returned when registering a migration with a version that is already registered.
+	ErrDuplicateMigration = errors.New("migration is already registered")
+
+	// ErrUnknownMigration is returned by Down when an applied migration to revert is not registered.
+	ErrUnknownMigration = errors.New("applied migration is not registered")
+
+	// ErrIrreversible is returned by Down when a migration to revert has no down direction.
+	ErrIrreversible = errors.New("migration cannot be reverted")
+
+	// ErrNoTransaction is returned by Up and Down when the driver supports transactions, but the options do not begin
+	// one, so a failing migration could not be rolled back.
+	ErrNoTransaction = errors.New("migrations must run in a transaction, pass transaction options")
+)
+
+// Migration is a versioned change of the schema. The up direction is either a Script, executed statement by statement
+// through the store, or an UpFunc for migrations that need Go code, the down direction likewise. The Checksum identifies
+// the content of the migration, it is derived from the up Script if left empty. Migrations with an UpFunc and no
+// Checksum never report changes.
+type Migration[BUILDER any] struct {
+	Version    int64
+	Name       string
+	Script     string
+	DownScript string
+	UpFunc     func(session octobe.BuilderSession[BUILDER]) error
+	DownFunc   func(session octobe.BuilderSession[BUILDER]) error
+	Checksum   string
+}
+
+// SQL creates a migration from SQL scripts, down may be empty for a migration that cannot be reverted.
+func SQL[BUILDER any](version int64, name, up, down string) Migration[BUILDER] {
+	return Migration[BUILDER]{Version: version, Name: name, Script: up, DownScript: down}
+}
+
+// Func creates a migration from functions, down may be nil for a migration that cannot be reverted.
+func Func[BUILDER any](version int64, name string, up, down func(session octobe.BuilderSession[BUILDER]) error) Migration[BUILDER] {
+	return Migration[BUILDER]{Version: version, Name: name, UpFunc: up, DownFunc: down}
+}
+
+// FromFS loads the migrations in dir of fsys, typically embedded with go:embed. Every migration is a file named
+// <version>_<name>.up.sql, with an optional <version>_<name>.down.sql reverting it. Other files are ignored.
+func FromFS[BUILDER any](fsys fs.FS, dir string) ([]Migration[BUILDER], error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration[BUILDER])
+	for _, entry := range entries {
+		base, down := strings.CutSuffix(entry.Name(), ".down.sql")
+		if !down {
+			var up bool
+			if base, up = strings.CutSuffix(entry.Name(), ".up.sql"); !up {
+				continue
+			}
+		}
+		if entry.IsDir() {
+			continue
+		}
+
+		version, name, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("migration file %q is not named <version>_<name>", entry.Name())
+		}
+		v, err := strconv.ParseInt(version, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration file %q has an invalid version: %w", entry.Name(), err)
+		}
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[v]
+		if !ok {
+			m = &Migration[BUILDER]{Version: v, Name: name}
+			byVersion[v] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("%w: version %d is used by %q and %q", ErrDuplicateMigration, v, m.Name, name)
+		}
+		if down {
+			m.DownScript = string(content)
+		} else {
+			m.Script = string(content)
+		}
+	}
+
+	migrations := make([]Migration[BUILDER], 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Script == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// String returns the version and name of the migration, like the file names read by FromFS.
+func (m Migration[BUILDER]) String() string {
+	return fmt.Sprintf("%d_%s", m.Version, m.Name)
+}
+
+// checksum returns the checksum of the migration, deriving it from the script if none is set.
+func (m Migration[BUILDER]) checksum() string {
+	if m.Checksum != "" || m.Script == "" {
+		return m.Checksum
+	}
+	sum := sha256.Sum256([]byte(m.Script))
+	return hex.EncodeToString(sum[:])
+}
+
+// up applies the migration.
+func (m Migration[BUILDER]) up(session octobe.BuilderSession[BUILDER], store Store[BUILDER]) error {
+	if m.UpFunc != nil {
+		return m.UpFunc(session)
+	}
+	return store.ExecScript(session, m.Script)
+}
+
+// down reverts the migration.
+func (m Migration[BUILDER]) down(session octobe.BuilderSession[BUILDER], store Store[BUILDER]) error {
+	if m.DownFunc != nil {
+		return m.DownFunc(session)
+	}
+	return store.ExecScript(session, m.DownScript)
+}
+
+// reversible reports whether the migration has a down direction.
+func (m Migration[BUILDER]) reversible() bool {
+	return m.DownFunc != nil || m.DownScript != ""
+}
+
+// Store holds the driver specific parts of migrating, the bookkeeping of applied migrations, the lock and execution of
+// SQL scripts.
+type Store[BUILDER any] interface {
+	// Init creates the bookkeeping and lock tables if they do not exist.
+	Init(session octobe.BuilderSession[BUILDER]) error
+	// Lock takes the migration lock, it returns ErrLocked if the lock is held.
+	Lock(session octobe.BuilderSession[BUILDER]) error
+	// Unlock releases the migration lock.
+	Unlock(session octobe.BuilderSession[BUILDER]) error
+	// Applied returns the checksums of all applied migrations, keyed by version.
+	Applied(session octobe.BuilderSession[BUILDER]) (map[int64]string, error)
+	// Record marks a migration as applied.
+	Record(session octobe.BuilderSession[BUILDER], version int64, name, checksum string) error
+	// Remove marks a migration as no longer applied.
+	Remove(session octobe.BuilderSession[BUILDER], version int64) error
+	// ExecScript executes a script of one or more statements.
+	ExecScript(session octobe.BuilderSession[BUILDER], script string) error
+}
+
+// Option is a signature for configuring a Runner.
+type Option func(cfg *config)
+
+// config holds the configuration of a Runner.
+type config struct {
+	dryRun bool
+}
+
+// WithDryRun makes Up and Down only return the migrations they would apply or revert, without executing them or taking
+// the lock. The bookkeeping tables are still created if they do not exist.
+func WithDryRun() Option {
+	return func(cfg *config) {
+		cfg.dryRun = true
+	}
+}
+
+// Runner applies and reverts registered migrations through an Octobe instance.
+type Runner[DRIVER any, CONFIG any, BUILDER any] struct {
+	ob         *octobe.Octobe[DRIVER, CONFIG, BUILDER]
+	store      Store[BUILDER]
+	cfg        config
+	migrations []Migration[BUILDER]
+}
+
+// NewRunner creates a Runner that migrates through ob, using store for bookkeeping.
+func NewRunner[DRIVER any, CONFIG any, BUILDER any](ob *octobe.Octobe[DRIVER, CONFIG, BUILDER], store Store[BUILDER], opts ...Option) *Runner[DRIVER, CONFIG, BUILDER] {
+	var cfg config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return &Runner[DRIVER, CONFIG, BUILDER]{
+		ob:    ob,
+		store: store,
+		cfg:   cfg,
+	}
+}
+
+// Register adds migrations to the runner, they are applied in the order of their versions.
+func (r *Runner[DRIVER, CONFIG, BUILDER]) Register(migrations ...Migration[BUILDER]) error {
+	for _, m := range migrations {
+		if m.Version <= 0 {
+			return fmt.Errorf("migration %q has no positive version", m.Name)
+		}
+		if m.Script == "" && m.UpFunc == nil {
+			return fmt.Errorf("migration %s has neither a script nor an up function", m)
+		}
+		for _, registered := range r.migrations {
+			if registered.Version == m.Version {
+				return fmt.Errorf("%w: version %d of %s", ErrDuplicateMigration, m.Version, m)
+			}
+		}
+		r.migrations = append(r.migrations, m)
+	}
+	sort.SliceStable(r.migrations, func(i, j int) bool { return r.migrations[i].Version < r.migrations[j].Version })
+	return nil
+}
+
+// Up applies all registered migrations that have not been applied yet in the order of their versions, including
+// migrations older than the latest applied one, and returns the migrations it applied. Every migration is applied in
+// its own transaction together with its bookkeeping record, so a failing migration leaves no trace and can be retried.
+// Options are passed on to the driver when starting the transactions, for a driver that supports transactions they must
+// begin one, or ErrNoTransaction is returned. Before anything is applied, all registered
+// migrations are compared with the recorded checksums, and ErrChecksumMismatch is returned if any applied migration has
+// changed. Applied migrations that are not registered are ignored.
+func (r *Runner[DRIVER, CONFIG, BUILDER]) Up(ctx context.Context, opts ...octobe.Option[CONFIG]) ([]Migration[BUILDER], error) {
+	return r.run(ctx, func(applied map[int64]string) ([]Migration[BUILDER], error) {
+		var pending []Migration[BUILDER]
+		for _, m := range r.migrations {
+			checksum, ok := applied[m.Version]
+			if !ok {
+				pending = append(pending, m)
+				continue
+			}
+			if m.checksum() != "" && checksum != m.checksum() {
+				return nil, fmt.Errorf("%w: %s", ErrChecksumMismatch, m)
+			}
+		}
+		return pending, nil
+	}, func(session octobe.BuilderSession[BUILDER], m Migration[BUILDER]) error {
+		if err := m.up(session, r.store); err != nil {
+			return err
+		}
+		return r.store.Record(session, m.Version, m.Name, m.checksum())
+	}, opts...)
+}
+
+// Down reverts the last steps applied migrations in reverse order of their versions and returns the migrations it
+// reverted, each in its own transaction together with its bookkeeping record. Before anything is reverted, it returns
+// ErrUnknownMigration if a migration to revert is not registered, and ErrIrreversible if it has no down direction.
+func (r *Runner[DRIVER, CONFIG, BUILDER]) Down(ctx context.Context, steps int, opts ...octobe.Option[CONFIG]) ([]Migration[BUILDER], error) {
+	return r.run(ctx, func(applied map[int64]string) ([]Migration[BUILDER], error) {
+		versions := make([]int64, 0, len(applied))
+		for version := range applied {
+			versions = append(versions, version)
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+
+		var reverting []Migration[BUILDER]
+		for _, version := range versions[:min(max(steps, 0), len(versions))] {
+			m, ok := r.migration(version)
+			if !ok {
+				return nil, fmt.Errorf("%w: version %d", ErrUnknownMigration, version)
+			}
+			if !m.reversible() {
+				return nil, fmt.Errorf("%w: %s", ErrIrreversible, m)
+			}
+			reverting = append(reverting, m)
+		}
+		return reverting, nil
+	}, func(session octobe.BuilderSession[BUILDER], m Migration[BUILDER]) error {
+		if err := m.down(session, r.store); err != nil {
+			return err
+		}
+		return r.store.Remove(session, m.Version)
+	}, opts...)
+}
+
+// Unlock releases the migration lock, e.g. when it was left behind by a runner that crashed while migrating. It must
+// only be used when no other runner is migrating. Options are passed on to the driver like with Up.
+func (r *Runner[DRIVER, CONFIG, BUILDER]) Unlock(ctx context.Context, opts ...octobe.Option[CONFIG]) error {
+	return r.ob.StartTransaction(ctx, r.store.Unlock, opts...)
+}
+
+// run takes the lock, selects the migrations to execute from the applied ones with plan and executes each of them with
+// exec in its own transaction.
+func (r *Runner[DRIVER, CONFIG, BUILDER]) run(
+	ctx context.Context,
+	plan func(applied map[int64]string) ([]Migration[BUILDER], error),
+	exec func(session octobe.BuilderSession[BUILDER], m Migration[BUILDER]) error,
+	opts ...octobe.Option[CONFIG],
+) (done []Migration[BUILDER], err error) {
+	if r.ob.Capabilities().Transactions && !r.ob.BeginsTransaction(opts...) {
+		return nil, ErrNoTransaction
+	}
+
+	// The lock and the bookkeeping tables are committed before the migrations run, so that other runners see the lock
+	// and the migrations do not wait for a session of their own while it is held.
+	var applied map[int64]string
+	err = r.ob.StartTransaction(ctx, func(session octobe.BuilderSession[BUILDER]) error {
+		if err := r.store.Init(session); err != nil {
+			return fmt.Errorf("failed to initialize migration tables: %w", err)
+		}
+		if !r.cfg.dryRun {
+			if err := r.store.Lock(session); err != nil {
+				return fmt.Errorf("failed to take migration lock: %w", err)
+			}
+		}
+		var err error
+		if applied, err = r.store.Applied(session); err != nil {
+			return fmt.Errorf("failed to read applied migrations: %w", err)
+		}
+		return nil
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if !r.cfg.dryRun {
+		defer func() {
+			// The lock must be released even when ctx is done, or it blocks all future runs.
+			unlockErr := r.Unlock(context.WithoutCancel(ctx), opts...)
+			if unlockErr != nil {
+				err = errors.Join(err, fmt.Errorf("failed to release migration lock: %w", unlockErr))
+			}
+		}()
+	}
+
+	planned, err := plan(applied)
+	if err != nil || r.cfg.dryRun {
+		return planned, err
+	}
+
+	for _, m := range planned {
+		err = r.ob.StartTransaction(ctx, func(session octobe.BuilderSession[BUILDER]) error {
+			return exec(session, m)
+		}, opts...)
+		if err != nil {
+			return done, fmt.Errorf("failed to migrate %s: %w", m, err)
+		}
+		done = append(done, m)
+	}
+
+	return done, nil
+}
+
+// migration returns the registered migration with the given version.
+func (r *Runner[DRIVER, CONFIG, BUILDER]) migration(version int64) (Migration[BUILDER], bool) {
+	for _, m := range r.migrations {
+		if m.Version == version {
+			return m, true
+		}
+	}
+	return Migration[BUILDER]{}, false
+}
@@ -0,0 +1,346 @@
+package migrate_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/driver/clickhouse"
+	chmock "github.com/ponrove/octobe/driver/clickhouse/mock"
+	"github.com/ponrove/octobe/driver/postgres"
+	"github.com/ponrove/octobe/migrate"
+	"github.com/stretchr/testify/require"
+)
+
+func checksum(script string) string {
+	sum := sha256.Sum256([]byte(script))
+	return hex.EncodeToString(sum[:])
+}
+
+const (
+	createUsers = "CREATE TABLE users (id BIGINT PRIMARY KEY);"
+	dropUsers   = "DROP TABLE users;"
+	createPosts = "CREATE TABLE posts (id BIGINT PRIMARY KEY, user_id BIGINT);"
+	dropPosts   = "DROP TABLE posts;"
+)
+
+// expectInit expects the transaction creating the bookkeeping tables, taking the lock unless locked is nil and reading
+// the applied migrations, which returns applied. A held lock rolls the transaction back.
+func expectInit(mock pgxmock.PgxConnIface, locked *bool, applied *pgxmock.Rows) {
+	mock.ExpectBeginTx(pgx.TxOptions{})
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS "schema_migrations"`).WillReturnResult(pgxmock.NewResult("CREATE TABLE", 0))
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS "schema_migrations_lock"`).WillReturnResult(pgxmock.NewResult("CREATE TABLE", 0))
+	if locked != nil {
+		rows := int64(1)
+		if *locked {
+			rows = 0
+		}
+		mock.ExpectExec(`INSERT INTO "schema_migrations_lock" \(id\) VALUES \(1\) ON CONFLICT DO NOTHING`).WillReturnResult(pgxmock.NewResult("INSERT", rows))
+		if *locked {
+			mock.ExpectRollback()
+			return
+		}
+	}
+	mock.ExpectQuery(`SELECT version, checksum FROM "schema_migrations"`).WillReturnRows(applied)
+	mock.ExpectCommit()
+}
+
+// expectUnlock expects the transaction releasing the lock.
+func expectUnlock(mock pgxmock.PgxConnIface) {
+	mock.ExpectBeginTx(pgx.TxOptions{})
+	mock.ExpectExec(`DELETE FROM "schema_migrations_lock" WHERE id = 1`).WillReturnResult(pgxmock.NewResult("DELETE", 1))
+	mock.ExpectCommit()
+}
+
+func TestUpPostgres(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("applies pending migrations", func(t *testing.T) {
+		mock, err := pgxmock.NewConn()
+		require.NoError(t, err)
+		defer mock.Close(ctx)
+
+		unlocked := false
+		expectInit(mock, &unlocked,
+			pgxmock.NewRows([]string{"version", "checksum"}).AddRow(int64(1), checksum(createUsers)))
+		mock.ExpectBeginTx(pgx.TxOptions{})
+		mock.ExpectExec("CREATE TABLE posts").WillReturnResult(pgxmock.NewResult("CREATE TABLE", 0))
+		mock.ExpectExec(`INSERT INTO "schema_migrations"`).WithArgs(int64(2), "posts", checksum(createPosts)).WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectCommit()
+		mock.ExpectBeginTx(pgx.TxOptions{})
+		mock.ExpectExec("UPDATE posts").WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+		mock.ExpectExec(`INSERT INTO "schema_migrations"`).WithArgs(int64(3), "backfill", "").WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectCommit()
+		expectUnlock(mock)
+
+		ob, err := octobe.New(postgres.OpenPGXWithConn(mock))
+		require.NoError(t, err)
+
+		runner := migrate.NewRunner(ob, migrate.Postgres(""))
+		require.NoError(t, runner.Register(
+			migrate.Func(3, "backfill", func(session octobe.BuilderSession[postgres.Builder]) error {
+				_, err := session.Builder()("UPDATE posts SET user_id = 0 WHERE user_id IS NULL").Exec()
+				return err
+			}, nil),
+			migrate.SQL[postgres.Builder](2, "posts", createPosts, dropPosts),
+			migrate.SQL[postgres.Builder](1, "users", createUsers, dropUsers),
+		))
+
+		applied, err := runner.Up(ctx, postgres.WithPGXTxOptions(postgres.PGXTxOptions{}))
+		require.NoError(t, err)
+		require.Len(t, applied, 2)
+		require.Equal(t, "2_posts", applied[0].String())
+		require.Equal(t, "3_backfill", applied[1].String())
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("locked", func(t *testing.T) {
+		mock, err := pgxmock.NewConn()
+		require.NoError(t, err)
+		defer mock.Close(ctx)
+
+		locked := true
+		expectInit(mock, &locked, nil)
+
+		ob, err := octobe.New(postgres.OpenPGXWithConn(mock))
+		require.NoError(t, err)
+
+		runner := migrate.NewRunner(ob, migrate.Postgres(""))
+		require.NoError(t, runner.Register(migrate.SQL[postgres.Builder](1, "users", createUsers, dropUsers)))
+
+		_, err = runner.Up(ctx, postgres.WithPGXTxOptions(postgres.PGXTxOptions{}))
+		require.ErrorIs(t, err, migrate.ErrLocked)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("changed migration", func(t *testing.T) {
+		mock, err := pgxmock.NewConn()
+		require.NoError(t, err)
+		defer mock.Close(ctx)
+
+		unlocked := false
+		expectInit(mock, &unlocked,
+			pgxmock.NewRows([]string{"version", "checksum"}).AddRow(int64(1), "outdated"))
+		expectUnlock(mock)
+
+		ob, err := octobe.New(postgres.OpenPGXWithConn(mock))
+		require.NoError(t, err)
+
+		runner := migrate.NewRunner(ob, migrate.Postgres(""))
+		require.NoError(t, runner.Register(migrate.SQL[postgres.Builder](1, "users", createUsers, dropUsers)))
+
+		_, err = runner.Up(ctx, postgres.WithPGXTxOptions(postgres.PGXTxOptions{}))
+		require.ErrorIs(t, err, migrate.ErrChecksumMismatch)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("failing migration is rolled back", func(t *testing.T) {
+		mock, err := pgxmock.NewConn()
+		require.NoError(t, err)
+		defer mock.Close(ctx)
+
+		expectedErr := errors.New("syntax error")
+		unlocked := false
+		expectInit(mock, &unlocked, pgxmock.NewRows([]string{"version", "checksum"}))
+		mock.ExpectBeginTx(pgx.TxOptions{})
+		mock.ExpectExec("CREATE TABLE users").WillReturnError(expectedErr)
+		mock.ExpectRollback()
+		expectUnlock(mock)
+
+		ob, err := octobe.New(postgres.OpenPGXWithConn(mock))
+		require.NoError(t, err)
+
+		runner := migrate.NewRunner(ob, migrate.Postgres(""))
+		require.NoError(t, runner.Register(migrate.SQL[postgres.Builder](1, "users", createUsers, dropUsers)))
+
+		applied, err := runner.Up(ctx, postgres.WithPGXTxOptions(postgres.PGXTxOptions{}))
+		require.ErrorIs(t, err, expectedErr)
+		require.Empty(t, applied)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		mock, err := pgxmock.NewConn()
+		require.NoError(t, err)
+		defer mock.Close(ctx)
+
+		expectInit(mock, nil, pgxmock.NewRows([]string{"version", "checksum"}))
+
+		ob, err := octobe.New(postgres.OpenPGXWithConn(mock))
+		require.NoError(t, err)
+
+		runner := migrate.NewRunner(ob, migrate.Postgres(""), migrate.WithDryRun())
+		require.NoError(t, runner.Register(migrate.SQL[postgres.Builder](1, "users", createUsers, dropUsers)))
+
+		pending, err := runner.Up(ctx, postgres.WithPGXTxOptions(postgres.PGXTxOptions{}))
+		require.NoError(t, err)
+		require.Len(t, pending, 1)
+		require.Equal(t, createUsers, pending[0].Script)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("without transaction", func(t *testing.T) {
+		mock, err := pgxmock.NewConn()
+		require.NoError(t, err)
+		defer mock.Close(ctx)
+
+		ob, err := octobe.New(postgres.OpenPGXWithConn(mock))
+		require.NoError(t, err)
+
+		runner := migrate.NewRunner(ob, migrate.Postgres(""))
+		require.NoError(t, runner.Register(migrate.SQL[postgres.Builder](1, "users", createUsers, dropUsers)))
+
+		_, err = runner.Up(ctx)
+		require.ErrorIs(t, err, migrate.ErrNoTransaction)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("sessions end with a session limit", func(t *testing.T) {
+		mock, err := pgxmock.NewConn()
+		require.NoError(t, err)
+		defer mock.Close(ctx)
+
+		unlocked := false
+		expectInit(mock, &unlocked, pgxmock.NewRows([]string{"version", "checksum"}))
+		mock.ExpectBeginTx(pgx.TxOptions{})
+		mock.ExpectExec("CREATE TABLE users").WillReturnResult(pgxmock.NewResult("CREATE TABLE", 0))
+		mock.ExpectExec(`INSERT INTO "schema_migrations"`).WithArgs(int64(1), "users", checksum(createUsers)).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectCommit()
+		expectUnlock(mock)
+
+		// Default options begin every session in a transaction, a bookkeeping session left open would hold the only slot.
+		ob, err := octobe.New(postgres.OpenPGXWithConn(mock), octobe.WithMaxConcurrentSessions(1, time.Second),
+			octobe.WithDefaultOptions(postgres.WithPGXTxOptions(postgres.PGXTxOptions{})))
+		require.NoError(t, err)
+
+		runner := migrate.NewRunner(ob, migrate.Postgres(""))
+		require.NoError(t, runner.Register(migrate.SQL[postgres.Builder](1, "users", createUsers, dropUsers)))
+
+		applied, err := runner.Up(ctx)
+		require.NoError(t, err)
+		require.Len(t, applied, 1)
+		require.Zero(t, ob.Stats().ActiveSessions)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestDownPostgres(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("reverts the latest migrations", func(t *testing.T) {
+		mock, err := pgxmock.NewConn()
+		require.NoError(t, err)
+		defer mock.Close(ctx)
+
+		unlocked := false
+		expectInit(mock, &unlocked,
+			pgxmock.NewRows([]string{"version", "checksum"}).
+				AddRow(int64(1), checksum(createUsers)).
+				AddRow(int64(2), checksum(createPosts)))
+		mock.ExpectBeginTx(pgx.TxOptions{})
+		mock.ExpectExec("DROP TABLE posts").WillReturnResult(pgxmock.NewResult("DROP TABLE", 0))
+		mock.ExpectExec(`DELETE FROM "schema_migrations" WHERE version = \$1`).WithArgs(int64(2)).WillReturnResult(pgxmock.NewResult("DELETE", 1))
+		mock.ExpectCommit()
+		expectUnlock(mock)
+
+		ob, err := octobe.New(postgres.OpenPGXWithConn(mock))
+		require.NoError(t, err)
+
+		runner := migrate.NewRunner(ob, migrate.Postgres(""))
+		require.NoError(t, runner.Register(
+			migrate.SQL[postgres.Builder](1, "users", createUsers, dropUsers),
+			migrate.SQL[postgres.Builder](2, "posts", createPosts, dropPosts),
+		))
+
+		reverted, err := runner.Down(ctx, 1, postgres.WithPGXTxOptions(postgres.PGXTxOptions{}))
+		require.NoError(t, err)
+		require.Len(t, reverted, 1)
+		require.Equal(t, int64(2), reverted[0].Version)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("irreversible", func(t *testing.T) {
+		mock, err := pgxmock.NewConn()
+		require.NoError(t, err)
+		defer mock.Close(ctx)
+
+		unlocked := false
+		expectInit(mock, &unlocked,
+			pgxmock.NewRows([]string{"version", "checksum"}).AddRow(int64(1), checksum(createUsers)))
+		expectUnlock(mock)
+
+		ob, err := octobe.New(postgres.OpenPGXWithConn(mock))
+		require.NoError(t, err)
+
+		runner := migrate.NewRunner(ob, migrate.Postgres(""))
+		require.NoError(t, runner.Register(migrate.SQL[postgres.Builder](1, "users", createUsers, "")))
+
+		_, err = runner.Down(ctx, 1, postgres.WithPGXTxOptions(postgres.PGXTxOptions{}))
+		require.ErrorIs(t, err, migrate.ErrIrreversible)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestUpClickHouse(t *testing.T) {
+	ctx := context.Background()
+	script := "CREATE TABLE events (id UInt64) ENGINE = MergeTree ORDER BY id"
+
+	mock := chmock.NewMock()
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS `schema_migrations`")
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS `schema_migrations_lock`")
+	mock.ExpectQueryRow("SELECT count() FROM `schema_migrations_lock`").WillReturnRow(chmock.NewMockRow(uint64(0)))
+	mock.ExpectExec("INSERT INTO `schema_migrations_lock`")
+	mock.ExpectQuery("SELECT version, checksum FROM `schema_migrations` FINAL WHERE applied").
+		WillReturnRows(chmock.NewMockRows([]string{"version", "checksum"}))
+	mock.ExpectExec(script)
+	mock.ExpectExec("INSERT INTO `schema_migrations`").WithArgs(int64(1), "events", checksum(script))
+	mock.ExpectExec("TRUNCATE TABLE `schema_migrations_lock`")
+
+	ob, err := octobe.New(clickhouse.OpenNativeWithConn(mock))
+	require.NoError(t, err)
+
+	runner := migrate.NewRunner(ob, migrate.ClickHouse(""))
+	require.NoError(t, runner.Register(migrate.SQL[clickhouse.Builder](1, "events", script, "")))
+
+	applied, err := runner.Up(ctx)
+	require.NoError(t, err)
+	require.Len(t, applied, 1)
+	require.NoError(t, mock.AllExpectationsMet())
+}
+
+func TestRegister(t *testing.T) {
+	runner := migrate.NewRunner[any, any, postgres.Builder](nil, migrate.Postgres(""))
+	require.NoError(t, runner.Register(migrate.SQL[postgres.Builder](1, "a", "SELECT 1", "")))
+	require.ErrorIs(t, runner.Register(migrate.SQL[postgres.Builder](1, "b", "SELECT 2", "")), migrate.ErrDuplicateMigration)
+	require.Error(t, runner.Register(migrate.Migration[postgres.Builder]{Version: 2, Name: "empty"}))
+	require.Error(t, runner.Register(migrate.SQL[postgres.Builder](0, "zero", "SELECT 1", "")))
+}
+
+func TestFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/0002_posts.up.sql":   {Data: []byte(createPosts)},
+		"migrations/0002_posts.down.sql": {Data: []byte(dropPosts)},
+		"migrations/0001_users.up.sql":   {Data: []byte(createUsers)},
+		"migrations/README.md":           {Data: []byte("not a migration")},
+	}
+
+	migrations, err := migrate.FromFS[postgres.Builder](fsys, "migrations")
+	require.NoError(t, err)
+	require.Len(t, migrations, 2)
+	require.Equal(t, migrate.SQL[postgres.Builder](1, "users", createUsers, ""), migrations[0])
+	require.Equal(t, migrate.SQL[postgres.Builder](2, "posts", createPosts, dropPosts), migrations[1])
+
+	_, err = migrate.FromFS[postgres.Builder](fstest.MapFS{"m/0001_users.down.sql": {Data: []byte(dropUsers)}}, "m")
+	require.Error(t, err)
+	_, err = migrate.FromFS[postgres.Builder](fstest.MapFS{"m/users.up.sql": {Data: []byte(createUsers)}}, "m")
+	require.Error(t, err)
+	_, err = migrate.FromFS[postgres.Builder](fsys, "missing")
+	require.Error(t, err)
+}
@@ -0,0 +1,108 @@
+package migrate
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/driver/postgres"
+)
+
+// postgresStore records applied migrations in a postgres table.
+type postgresStore struct {
+	table string
+	lock  string
+}
+
+// Ensure postgresStore implements the Store interface.
+var _ Store[postgres.Builder] = &postgresStore{}
+
+// Postgres returns a store for the postgres drivers that records applied migrations in table, which may be schema
+// qualified. DefaultTable is used if table is empty. DDL is transactional in postgres, so a failing migration is rolled
+// back completely when the runner starts transactions.
+func Postgres(table string) Store[postgres.Builder] {
+	if table == "" {
+		table = DefaultTable
+	}
+	parts := strings.Split(table, ".")
+	lock := append(parts[:len(parts)-1:len(parts)-1], parts[len(parts)-1]+"_lock")
+	return &postgresStore{
+		table: pgx.Identifier(parts).Sanitize(),
+		lock:  pgx.Identifier(lock).Sanitize(),
+	}
+}
+
+// Init creates the bookkeeping and lock tables if they do not exist.
+func (s *postgresStore) Init(session octobe.BuilderSession[postgres.Builder]) error {
+	_, err := session.Builder()(fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			version    BIGINT NOT NULL PRIMARY KEY,
+			name       TEXT NOT NULL,
+			checksum   TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)`, s.table)).Exec()
+	if err != nil {
+		return err
+	}
+	_, err = session.Builder()(fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			id        INT NOT NULL PRIMARY KEY,
+			locked_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)`, s.lock)).Exec()
+	return err
+}
+
+// Lock takes the migration lock by inserting the single row of the lock table.
+func (s *postgresStore) Lock(session octobe.BuilderSession[postgres.Builder]) error {
+	result, err := session.Builder()(fmt.Sprintf(`INSERT INTO %s (id) VALUES (1) ON CONFLICT DO NOTHING`, s.lock)).Exec()
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected == 0 {
+		return ErrLocked
+	}
+	return nil
+}
+
+// Unlock releases the migration lock.
+func (s *postgresStore) Unlock(session octobe.BuilderSession[postgres.Builder]) error {
+	_, err := session.Builder()(fmt.Sprintf(`DELETE FROM %s WHERE id = 1`, s.lock)).Exec()
+	return err
+}
+
+// Applied returns the checksums of all applied migrations, keyed by version.
+func (s *postgresStore) Applied(session octobe.BuilderSession[postgres.Builder]) (map[int64]string, error) {
+	applied := make(map[int64]string)
+	query := session.Builder()(fmt.Sprintf(`SELECT version, checksum FROM %s`, s.table))
+	err := query.Query(func(rows postgres.Rows) error {
+		for rows.Next() {
+			var version int64
+			var checksum string
+			if err := rows.Scan(&version, &checksum); err != nil {
+				return err
+			}
+			applied[version] = checksum
+		}
+		return rows.Err()
+	})
+	return applied, err
+}
+
+// Record marks a migration as applied.
+func (s *postgresStore) Record(session octobe.BuilderSession[postgres.Builder], version int64, name, checksum string) error {
+	query := session.Builder()(fmt.Sprintf(`INSERT INTO %s (version, name, checksum) VALUES ($1, $2, $3)`, s.table))
+	_, err := query.Arguments(version, name, checksum).Exec()
+	return err
+}
+
+// Remove marks a migration as no longer applied.
+func (s *postgresStore) Remove(session octobe.BuilderSession[postgres.Builder], version int64) error {
+	_, err := session.Builder()(fmt.Sprintf(`DELETE FROM %s WHERE version = $1`, s.table)).Arguments(version).Exec()
+	return err
+}
+
+// ExecScript executes a script of one or more statements.
+func (s *postgresStore) ExecScript(session octobe.BuilderSession[postgres.Builder], script string) error {
+	return postgres.ExecScript(session, script)
+}
@@ -3,6 +3,10 @@ package octobe
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 var ErrAlreadyUsed = errors.New("query already used")
@@ -21,32 +25,192 @@ type Driver[DRIVER any, CONFIG any, BUILDER any] interface {
 // types for the local driver.
 type Open[DRIVER any, CONFIG any, BUILDER any] func() (Driver[DRIVER, CONFIG, BUILDER], error)
 
+// InstanceOption is a signature that can be used for configuring an Octobe instance when it is created.
+type InstanceOption func(cfg *instanceConfig)
+
+// instanceConfig holds the configuration of an Octobe instance.
+type instanceConfig struct {
+	cancelOnClose    bool
+	closeGrace       time.Duration
+	sessionTimeout   time.Duration
+	debugName        string
+	txAttempts       int
+	txBackoff        Backoff
+	hooks            []QueryHook
+	record           bool
+	recordLimit      int
+	comments         []CommentTags
+	flushers         []func(ctx context.Context, events []any) error
+	guard            bool
+	stats            bool
+	clock            Clock
+	maxSessions      int
+	admissionTimeout time.Duration
+	defaults         any
+}
+
+// WithCloseGracePeriod makes Close wait at most grace for active transactional sessions to finish, and then cancel the
+// contexts of the transactions still outstanding before closing the driver, so stuck queries cannot hang shutdown. To
+// make that possible every transaction runs with its own context derived from the context passed to Begin, released
+// when it commits or rolls back. Sessions without a transaction have no end that Octobe can observe, they run on the
+// context passed to Begin and are not cancelled by Close, see TransactionReporter.
+func WithCloseGracePeriod(grace time.Duration) InstanceOption {
+	return func(cfg *instanceConfig) {
+		cfg.cancelOnClose = true
+		cfg.closeGrace = grace
+	}
+}
+
+// WithDefaultOptions sets driver options that every Begin and StartTransaction of the instance starts from, options
+// passed to those calls are applied after the defaults and so override them. The options must be for the driver the
+// instance is created with.
+func WithDefaultOptions[CONFIG any](opts ...Option[CONFIG]) InstanceOption {
+	return func(cfg *instanceConfig) {
+		defaults, _ := cfg.defaults.([]Option[CONFIG])
+		cfg.defaults = append(defaults, opts...)
+	}
+}
+
 // Octobe struct that holds the database session
 type Octobe[DRIVER any, CONFIG any, BUILDER any] struct {
-	driver Driver[DRIVER, CONFIG, BUILDER]
+	driver   Driver[DRIVER, CONFIG, BUILDER]
+	cfg      instanceConfig
+	defaults []Option[CONFIG]
+	stats    *statsCounter
+	slots    chan struct{}
+	begun    atomic.Int64
+
+	mu          sync.Mutex
+	active      map[*session[DRIVER, CONFIG, BUILDER]]struct{}
+	cancellable map[*session[DRIVER, CONFIG, BUILDER]]struct{}
+	drained     chan struct{}
+	shutdown    bool
+	cancelled   bool
 }
 
 // New creates a new Octobe instance.
-func New[DRIVER any, CONFIG any, BUILDER any](init Open[DRIVER, CONFIG, BUILDER]) (*Octobe[DRIVER, CONFIG, BUILDER], error) {
+func New[DRIVER any, CONFIG any, BUILDER any](init Open[DRIVER, CONFIG, BUILDER], opts ...InstanceOption) (*Octobe[DRIVER, CONFIG, BUILDER], error) {
+	var cfg instanceConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.clock == nil {
+		cfg.clock = SystemClock
+	}
+
+	var defaults []Option[CONFIG]
+	if cfg.defaults != nil {
+		var ok bool
+		if defaults, ok = cfg.defaults.([]Option[CONFIG]); !ok {
+			return nil, fmt.Errorf("default options of type %T do not match the driver options of type %T", cfg.defaults, defaults)
+		}
+	}
+
 	driver, err := init()
 	if err != nil {
 		return nil, err
 	}
 
-	return &Octobe[DRIVER, CONFIG, BUILDER]{
-		driver: driver,
-	}, nil
+	ob := &Octobe[DRIVER, CONFIG, BUILDER]{
+		driver:   driver,
+		cfg:      cfg,
+		defaults: defaults,
+	}
+	if cfg.stats {
+		ob.stats = &statsCounter{}
+	}
+	if cfg.maxSessions > 0 {
+		ob.slots = make(chan struct{}, cfg.maxSessions)
+	}
+	ob.registerDebug()
+	return ob, nil
 }
 
 // Begin a new session of queries, this will return a Session instance that can be used for handling queries. Options can be
 // passed to the driver for specific configuration that overwrites the default configuration given at instantiation of
 // the Octobe instance.
 func (ob *Octobe[DRIVER, CONFIG, BUILDER]) Begin(ctx context.Context, opts ...Option[CONFIG]) (Session[BUILDER], error) {
-	return ob.driver.Begin(ctx, opts...)
+	ob.mu.Lock()
+	shutdown := ob.shutdown
+	ob.mu.Unlock()
+	if shutdown {
+		return nil, ErrShutdown
+	}
+
+	wait, release, err := ob.admit(ctx)
+	ctx, endSession, rec := ob.beginSession(ctx, wait)
+	if err != nil {
+		endSession(false, false, err)
+		return nil, err
+	}
+	if len(ob.defaults) > 0 {
+		opts = append(ob.defaults[:len(ob.defaults):len(ob.defaults)], opts...)
+	}
+
+	parent := ctx
+	var cancel context.CancelFunc
+	// Sessions without a transaction have no end to release their context, they run on the context of the caller.
+	if (ob.cfg.sessionTimeout > 0 || ob.cfg.cancelOnClose) && ob.beginsTransaction(opts) {
+		if ob.cfg.sessionTimeout > 0 {
+			ctx, cancel = ContextWithTimeout(ctx, ob.cfg.clock, ob.cfg.sessionTimeout, ErrSessionTimeout)
+		} else {
+			ctx, cancel = context.WithCancel(ctx)
+		}
+	}
+
+	driverSession, err := ob.driver.Begin(ctx, opts...)
+	if err != nil {
+		if cancel != nil {
+			cancel()
+		}
+		release()
+		endSession(false, false, err)
+		return nil, err
+	}
+
+	ob.begun.Add(1)
+	s := &session[DRIVER, CONFIG, BUILDER]{
+		Session:  driverSession,
+		ob:       ob,
+		parent:   parent,
+		cancel:   cancel,
+		release:  release,
+		recorder: rec,
+		guard:    guardFrom(parent),
+		stats:    statsFrom(parent),
+	}
+	if !inTransaction(driverSession) {
+		release()
+		endSession(false, false, nil)
+		return s, nil
+	}
+	if ob.cfg.cancelOnClose {
+		ob.registerCancel(parent, s)
+	}
+	s.end = endSession
+	if err = ob.track(s); err != nil {
+		err = errors.Join(err, driverSession.Rollback())
+		s.releaseContext()
+		release()
+		endSession(true, false, err)
+		return nil, err
+	}
+	s.watch(ctx)
+	return s, nil
 }
 
-// Close the database connection.
+// Close the database connection, Begin returns ErrShutdown afterwards. When the instance was created with
+// WithCloseGracePeriod, Close waits for active transactional sessions to commit or roll back up to the grace period, or
+// until ctx is done, and cancels the contexts of all outstanding sessions before closing the connection. Otherwise the
+// connection is closed right away, use Shutdown to wait for active sessions without a grace period.
 func (ob *Octobe[DRIVER, CONFIG, BUILDER]) Close(ctx context.Context) error {
+	ob.unregisterDebug()
+	if ob.cfg.cancelOnClose {
+		return ob.closeWithGrace(ctx)
+	}
+	ob.mu.Lock()
+	ob.shutdown = true
+	ob.mu.Unlock()
 	return ob.driver.Close(ctx)
 }
 
@@ -77,33 +241,74 @@ type BuilderSession[BUILDER any] interface {
 // Void is a type that can be used for returning nothing from a handler.
 type Void *struct{}
 
-// StartTransaction enables the use of a transaction for the session, enforcing the usage of commit and rollback.
-func (o *Octobe[DRIVER, CONFIG, BUILDER]) StartTransaction(ctx context.Context, fn func(session BuilderSession[BUILDER]) error, opts ...Option[CONFIG]) (err error) {
+// StartTransaction enables the use of a transaction for the session, enforcing the usage of commit and rollback. When
+// the instance was created with WithTxRetry, a transaction failing with a retryable error is retried with fn. When ctx
+// carries a transaction of the instance through TransactionContext, fn runs in a savepoint of that transaction and the
+// options are ignored. When fn fails and rolling back fails as well, the returned error joins both errors.
+func (o *Octobe[DRIVER, CONFIG, BUILDER]) StartTransaction(ctx context.Context, fn func(session BuilderSession[BUILDER]) error, opts ...Option[CONFIG]) error {
+	if outer, savepoints := o.transactionFrom(ctx); outer != nil {
+		return o.nestTransaction(outer, savepoints, fn)
+	}
+
+	for attempt := 1; ; attempt++ {
+		commit, err := o.startTransaction(ctx, fn, opts...)
+		if err == nil || attempt >= o.cfg.txAttempts || !o.retryable(err, commit) {
+			return err
+		}
+		if waitErr := o.wait(ctx, attempt); waitErr != nil {
+			return errors.Join(err, waitErr)
+		}
+	}
+}
+
+// startTransaction runs a single attempt of StartTransaction, commit reports whether the error was returned by the
+// commit.
+func (o *Octobe[DRIVER, CONFIG, BUILDER]) startTransaction(ctx context.Context, fn func(session BuilderSession[BUILDER]) error, opts ...Option[CONFIG]) (commit bool, err error) {
 	// Start the transaction
 	session, err := o.Begin(ctx, opts...)
 	if err != nil {
-		return err
+		return false, err
 	}
 
 	// Defer a function that will handle commit or rollback
+	ended := false
 	defer func() {
 		if p := recover(); p != nil {
 			// A panic occurred, rollback and re-panic
 			_ = session.Rollback()
 			panic(p)
-		} else if err != nil {
-			// An error occurred, rollback the transaction
-			_ = session.Rollback()
+		} else if err != nil && !ended {
+			// An error occurred, rollback the transaction, a failed rollback is reported along with the error
+			if rollbackErr := session.Rollback(); rollbackErr != nil && !withoutTransaction(session) {
+				err = errors.Join(err, fmt.Errorf("rollback: %w", rollbackErr))
+			}
 		}
 	}()
 
 	// Execute the user's code
 	err = fn(session)
 	if err != nil {
-		return err
+		return false, err
 	}
 
-	// No error, commit the transaction
+	// No error, commit the transaction. A failed commit ends the transaction as well, so it is not rolled back.
+	ended = true
 	err = session.Commit()
-	return err
+	return true, err
+}
+
+// StartTransactionWithResult works like StartTransaction, but lets fn return a result alongside the error. The result
+// is only returned if the transaction was committed, otherwise the zero value of RESULT is returned with the error.
+func StartTransactionWithResult[RESULT any, DRIVER, CONFIG, BUILDER any](ctx context.Context, o *Octobe[DRIVER, CONFIG, BUILDER], fn func(session BuilderSession[BUILDER]) (RESULT, error), opts ...Option[CONFIG]) (RESULT, error) {
+	var result RESULT
+	err := o.StartTransaction(ctx, func(session BuilderSession[BUILDER]) error {
+		var err error
+		result, err = fn(session)
+		return err
+	}, opts...)
+	if err != nil {
+		var zero RESULT
+		return zero, err
+	}
+	return result, nil
 }
@@ -0,0 +1,113 @@
+package octobe_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/ponrove/octobe"
+)
+
+// fakeConfig is the configuration of the fake driver.
+type fakeConfig struct {
+	tx bool
+}
+
+// withTx starts a transaction on the fake driver.
+func withTx() octobe.Option[fakeConfig] {
+	return func(cfg *fakeConfig) {
+		cfg.tx = true
+	}
+}
+
+// fakeDriver is a driver that records what happens to its sessions.
+type fakeDriver struct {
+	mu          sync.Mutex
+	closed      bool
+	closeErr    error
+	commitErr   error
+	rollbackErr error
+	pingErr     error
+	retryErr    error
+	sessions    []*fakeSession
+}
+
+func (d *fakeDriver) Begin(ctx context.Context, opts ...octobe.Option[fakeConfig]) (octobe.Session[string], error) {
+	var cfg fakeConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	s := &fakeSession{ctx: ctx, tx: cfg.tx, commitErr: d.commitErr, rollbackErr: d.rollbackErr}
+	d.mu.Lock()
+	d.sessions = append(d.sessions, s)
+	d.mu.Unlock()
+	return s, nil
+}
+
+func (d *fakeDriver) BeginsTransaction(opts ...octobe.Option[fakeConfig]) bool {
+	var cfg fakeConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg.tx
+}
+
+func (d *fakeDriver) Close(context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.closed = true
+	return d.closeErr
+}
+
+func (d *fakeDriver) Ping(context.Context) error { return d.pingErr }
+
+func (d *fakeDriver) Retryable(err error) bool {
+	return d.retryErr != nil && errors.Is(err, d.retryErr)
+}
+
+func (d *fakeDriver) Stats() any {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return map[string]int{"sessions": len(d.sessions)}
+}
+
+func (d *fakeDriver) open() octobe.Open[fakeDriver, fakeConfig, string] {
+	return func() (octobe.Driver[fakeDriver, fakeConfig, string], error) {
+		return d, nil
+	}
+}
+
+// fakeSession is a session of the fake driver.
+type fakeSession struct {
+	mu          sync.Mutex
+	ctx         context.Context
+	tx          bool
+	committed   bool
+	rolledBack  bool
+	commitErr   error
+	rollbackErr error
+}
+
+func (s *fakeSession) Commit() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.committed = s.commitErr == nil
+	return s.commitErr
+}
+
+func (s *fakeSession) Rollback() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rolledBack = true
+	return s.rollbackErr
+}
+
+func (s *fakeSession) Builder() string     { return "builder" }
+func (s *fakeSession) InTransaction() bool { return s.tx }
+
+// withoutTx overrides withTx, starting a session without a transaction.
+func withoutTx() octobe.Option[fakeConfig] {
+	return func(cfg *fakeConfig) {
+		cfg.tx = false
+	}
+}
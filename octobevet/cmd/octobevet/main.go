@@ -0,0 +1,11 @@
+// Command octobevet runs the octobevet analyzer, on its own or with go vet -vettool.
+package main
+
+import (
+	"github.com/ponrove/octobe/octobevet"
+	"golang.org/x/tools/go/analysis/singlechecker"
+)
+
+func main() {
+	singlechecker.Main(octobevet.Analyzer)
+}
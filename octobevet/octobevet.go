@@ -0,0 +1,315 @@
+// Package octobevet provides an analyzer for go vet that reports common mistakes using Octobe, which otherwise only
+// surface at runtime:
+//
+//   - a segment that is executed more than once, or in a loop while it was built before the loop, which fails with
+//     octobe.ErrAlreadyUsed. Segments meant to run again should be cloned with Clone.
+//   - a session begun in a transaction with Begin that is neither committed nor rolled back, and that is not handed
+//     on to other code, which leaks the transaction and its connection.
+//
+// The checks look at a single function at a time and give up on variables whose use they cannot follow, like segments
+// that are reassigned. Passing a session of one driver to the Execute functions of another driver needs no check, it
+// does not compile. The analyzer can be run with go vet through the octobevet command:
+//
+//	go install github.com/ponrove/octobe/octobevet/cmd/octobevet@latest
+//	go vet -vettool=$(which octobevet) ./...
+package octobevet
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+// Import paths the analyzer recognizes.
+const (
+	octobePath = "github.com/ponrove/octobe"
+	driverPath = octobePath + "/driver/"
+)
+
+// Analyzer reports segments that are executed more than once and transactions that are never ended.
+var Analyzer = &analysis.Analyzer{
+	Name:     "octobevet",
+	Doc:      "report segments executed more than once and transactions that are neither committed nor rolled back",
+	URL:      "https://pkg.go.dev/github.com/ponrove/octobe/octobevet",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+// executions are the methods of segments that execute them.
+var executions = map[string]bool{
+	"Exec":           true,
+	"Query":          true,
+	"QueryRow":       true,
+	"Rows":           true,
+	"QueryRowMap":    true,
+	"QueryMaps":      true,
+	"QueryRowStruct": true,
+	"QueryStructs":   true,
+	"Select":         true,
+	"PrepareBatch":   true,
+	"AsyncInsert":    true,
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	inspect.Preorder([]ast.Node{(*ast.FuncDecl)(nil), (*ast.FuncLit)(nil)}, func(n ast.Node) {
+		var body *ast.BlockStmt
+		switch fn := n.(type) {
+		case *ast.FuncDecl:
+			body = fn.Body
+		case *ast.FuncLit:
+			body = fn.Body
+		}
+		if body != nil {
+			checkFunc(pass, body)
+		}
+	})
+	return nil, nil
+}
+
+// execution is a call executing a segment, with the nodes enclosing it.
+type execution struct {
+	call  *ast.CallExpr
+	stack []ast.Node
+}
+
+// transaction is a session begun in a transaction.
+type transaction struct {
+	begin   *ast.CallExpr
+	ended   bool
+	escaped bool
+}
+
+// checkFunc checks the body of a function, function literals within it are checked on their own.
+func checkFunc(pass *analysis.Pass, body *ast.BlockStmt) {
+	var (
+		stack        []ast.Node
+		executed     = make(map[*types.Var][]execution)
+		reassigned   = make(map[*types.Var]bool)
+		transactions = make(map[*types.Var]*transaction)
+	)
+
+	ast.Inspect(body, func(n ast.Node) bool {
+		if n == nil {
+			stack = stack[:len(stack)-1]
+			return true
+		}
+		if _, ok := n.(*ast.FuncLit); ok {
+			return false
+		}
+		parent := ast.Node(nil)
+		if len(stack) > 0 {
+			parent = stack[len(stack)-1]
+		}
+		stack = append(stack, n)
+
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			for i, lhs := range n.Lhs {
+				v := variable(pass, lhs)
+				if v == nil {
+					if begin := beginInTransaction(pass, n, i); begin != nil && isBlank(lhs) {
+						pass.Reportf(begin.Pos(), "session begun in a transaction is discarded, it can never be committed or rolled back")
+					}
+					continue
+				}
+				if pass.TypesInfo.Defs[lhs.(*ast.Ident)] == nil {
+					reassigned[v] = true
+				}
+				if begin := beginInTransaction(pass, n, i); begin != nil {
+					transactions[v] = &transaction{begin: begin}
+				}
+			}
+		case *ast.UnaryExpr:
+			if v := variable(pass, n.X); v != nil && n.Op == token.AND {
+				reassigned[v] = true
+			}
+		case *ast.CallExpr:
+			selector, ok := n.Fun.(*ast.SelectorExpr)
+			if !ok || !executions[selector.Sel.Name] {
+				break
+			}
+			if v := variable(pass, selector.X); v != nil && isSegment(v.Type()) {
+				executed[v] = append(executed[v], execution{call: n, stack: append([]ast.Node(nil), stack...)})
+			}
+		case *ast.Ident:
+			v, ok := pass.TypesInfo.Uses[n].(*types.Var)
+			if !ok || transactions[v] == nil {
+				break
+			}
+			selector, ok := parent.(*ast.SelectorExpr)
+			switch {
+			case !ok || selector.X != n:
+				transactions[v].escaped = true
+			case selector.Sel.Name == "Commit" || selector.Sel.Name == "Rollback":
+				transactions[v].ended = true
+			}
+		}
+		return true
+	})
+
+	for v, calls := range executed {
+		if reassigned[v] {
+			continue
+		}
+		for i, current := range calls {
+			if loop := enclosingLoop(current.stack, v); loop != nil && !returns(current.stack) {
+				pass.Reportf(current.call.Pos(), "segment %s is executed in a loop but built before it, clone it to run it again", v.Name())
+				continue
+			}
+			for _, previous := range calls[:i] {
+				if !exclusive(previous.stack, current.stack) {
+					pass.Reportf(current.call.Pos(), "segment %s is executed more than once, clone it to run it again", v.Name())
+					break
+				}
+			}
+		}
+	}
+
+	for v, tx := range transactions {
+		if !tx.ended && !tx.escaped {
+			pass.Reportf(tx.begin.Pos(), "session %s is begun in a transaction but never committed or rolled back", v.Name())
+		}
+	}
+}
+
+// variable returns the local variable expr refers to, or nil if it is not an identifier of a variable.
+func variable(pass *analysis.Pass, expr ast.Expr) *types.Var {
+	ident, ok := ast.Unparen(expr).(*ast.Ident)
+	if !ok {
+		return nil
+	}
+	v, _ := pass.TypesInfo.ObjectOf(ident).(*types.Var)
+	if v == nil || v.IsField() || v.Parent() == nil || v.Parent() == v.Pkg().Scope() {
+		return nil
+	}
+	return v
+}
+
+// isBlank reports whether expr is the blank identifier.
+func isBlank(expr ast.Expr) bool {
+	ident, ok := expr.(*ast.Ident)
+	return ok && ident.Name == "_"
+}
+
+// isSegment reports whether t is a segment of a driver.
+func isSegment(t types.Type) bool {
+	named, ok := types.Unalias(t).(*types.Named)
+	if !ok || named.Obj().Pkg() == nil {
+		return false
+	}
+	return named.Obj().Name() == "Segment" && strings.HasPrefix(named.Obj().Pkg().Path(), driverPath)
+}
+
+// isNamed reports whether t is the type name of the octobe package.
+func isNamed(t types.Type, name string) bool {
+	named, ok := types.Unalias(t).(*types.Named)
+	return ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == octobePath && named.Obj().Name() == name
+}
+
+// beginInTransaction returns the call of assignment to Octobe.Begin whose session is assigned to the ith variable, if
+// it is given transaction options of a driver.
+func beginInTransaction(pass *analysis.Pass, assignment *ast.AssignStmt, i int) *ast.CallExpr {
+	if i != 0 || len(assignment.Rhs) != 1 {
+		return nil
+	}
+	call, ok := ast.Unparen(assignment.Rhs[0]).(*ast.CallExpr)
+	if !ok {
+		return nil
+	}
+	selector, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || selector.Sel.Name != "Begin" {
+		return nil
+	}
+	method, ok := pass.TypesInfo.Uses[selector.Sel].(*types.Func)
+	if !ok || method.Pkg() == nil || method.Pkg().Path() != octobePath {
+		return nil
+	}
+	recv := method.Type().(*types.Signature).Recv()
+	if recv == nil {
+		return nil
+	}
+	if ptr, ok := recv.Type().(*types.Pointer); !ok || !isNamed(ptr.Elem(), "Octobe") {
+		return nil
+	}
+
+	for _, arg := range call.Args {
+		option, ok := ast.Unparen(arg).(*ast.CallExpr)
+		if !ok {
+			continue
+		}
+		fn, ok := callee(pass, option).(*types.Func)
+		if ok && fn.Pkg() != nil && strings.HasPrefix(fn.Pkg().Path(), driverPath) && strings.HasSuffix(fn.Name(), "TxOptions") {
+			return call
+		}
+	}
+	return nil
+}
+
+// callee returns the function or method call calls, or nil for calls of function values.
+func callee(pass *analysis.Pass, call *ast.CallExpr) types.Object {
+	switch fun := ast.Unparen(call.Fun).(type) {
+	case *ast.Ident:
+		return pass.TypesInfo.Uses[fun]
+	case *ast.SelectorExpr:
+		return pass.TypesInfo.Uses[fun.Sel]
+	}
+	return nil
+}
+
+// enclosingLoop returns the innermost loop of stack that v was declared before, so every iteration executes the same
+// segment.
+func enclosingLoop(stack []ast.Node, v *types.Var) ast.Node {
+	for i := len(stack) - 1; i >= 0; i-- {
+		switch loop := stack[i].(type) {
+		case *ast.ForStmt, *ast.RangeStmt:
+			if v.Pos() < loop.Pos() {
+				return loop
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// returns reports whether the call of stack is part of a return statement, which does not reach the next iteration.
+func returns(stack []ast.Node) bool {
+	for i := len(stack) - 1; i >= 0; i-- {
+		switch stack[i].(type) {
+		case *ast.ReturnStmt:
+			return true
+		case *ast.ForStmt, *ast.RangeStmt:
+			return false
+		}
+	}
+	return false
+}
+
+// exclusive reports whether the calls of two stacks are in different branches of an if or switch statement, so at most
+// one of them is executed.
+func exclusive(a, b []ast.Node) bool {
+	k := 0
+	for k < len(a) && k < len(b) && a[k] == b[k] {
+		k++
+	}
+	if k == 0 || k >= len(a) || k >= len(b) {
+		return false
+	}
+	switch common := a[k-1].(type) {
+	case *ast.IfStmt:
+		return a[k] != common.Cond && b[k] != common.Cond && a[k] != common.Init && b[k] != common.Init
+	case *ast.BlockStmt:
+		// The clauses of a switch or select statement are the statements of its body.
+		_, aClause := a[k].(*ast.CaseClause)
+		_, bClause := b[k].(*ast.CaseClause)
+		_, aComm := a[k].(*ast.CommClause)
+		_, bComm := b[k].(*ast.CommClause)
+		return aClause && bClause || aComm && bComm
+	}
+	return false
+}
@@ -0,0 +1,12 @@
+package octobevet_test
+
+import (
+	"testing"
+
+	"github.com/ponrove/octobe/octobevet"
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestAnalyzer(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), octobevet.Analyzer, "example")
+}
@@ -0,0 +1,83 @@
+package example
+
+import (
+	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/driver/postgres"
+)
+
+func reuse(seg postgres.Segment) {
+	_ = seg.Exec()
+	_ = seg.Exec() // want `segment seg is executed more than once`
+}
+
+func branches(seg postgres.Segment, ok bool, n int) {
+	if ok {
+		_ = seg.Exec()
+	} else {
+		_ = seg.Exec()
+	}
+	switch n {
+	case 1:
+		_ = seg.Exec() // want `segment seg is executed more than once`
+	}
+}
+
+func clone(seg postgres.Segment) {
+	_ = seg.Clone().Exec()
+	_ = seg.Clone().Exec()
+}
+
+func loop(seg postgres.Segment, ids []int) {
+	for range ids {
+		_ = seg.Exec() // want `segment seg is executed in a loop`
+	}
+	for range ids {
+		s := seg
+		_ = s.Exec()
+	}
+	for {
+		return
+	}
+}
+
+func retry(seg postgres.Segment) error {
+	for {
+		return seg.Exec()
+	}
+}
+
+func reassigned(b postgres.Builder) {
+	seg := b("SELECT 1")
+	_ = seg.Exec()
+	seg = b("SELECT 2")
+	_ = seg.Exec()
+}
+
+func leak(ob *postgres.Octobe) {
+	session, _ := ob.Begin(postgres.WithPGXTxOptions(postgres.TxOptions{})) // want `session session is begun in a transaction but never committed or rolled back`
+	seg := session.Builder()("SELECT 1")
+	_ = seg.Exec()
+}
+
+func discard(ob *postgres.Octobe) {
+	_, _ = ob.Begin(postgres.WithPGXTxOptions(postgres.TxOptions{})) // want `session begun in a transaction is discarded`
+}
+
+func committed(ob *postgres.Octobe) error {
+	session, err := ob.Begin(postgres.WithPGXTxOptions(postgres.TxOptions{}))
+	if err != nil {
+		return err
+	}
+	defer session.Rollback()
+	return session.Commit()
+}
+
+func escapes(ob *postgres.Octobe) (octobe.Session[postgres.Builder], error) {
+	session, err := ob.Begin(postgres.WithPGXTxOptions(postgres.TxOptions{}))
+	return session, err
+}
+
+func withoutTransaction(ob *postgres.Octobe) {
+	session, _ := ob.Begin(postgres.WithoutTransaction())
+	_ = session.Builder()
+}
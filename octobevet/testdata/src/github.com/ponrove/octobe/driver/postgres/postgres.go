@@ -0,0 +1,23 @@
+package postgres
+
+import "github.com/ponrove/octobe"
+
+type Driver struct{}
+
+type Config struct{}
+
+type TxOptions struct{}
+
+type Builder func(query string) Segment
+
+type Segment struct{}
+
+func (s *Segment) Exec() error { return nil }
+
+func (s *Segment) Clone() *Segment { return s }
+
+func WithPGXTxOptions(options TxOptions) octobe.Option[Config] { return nil }
+
+func WithoutTransaction() octobe.Option[Config] { return nil }
+
+type Octobe = octobe.Octobe[Driver, Config, Builder]
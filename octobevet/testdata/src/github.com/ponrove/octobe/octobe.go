@@ -0,0 +1,15 @@
+package octobe
+
+type Option[CONFIG any] func(*CONFIG)
+
+type Octobe[DRIVER, CONFIG, BUILDER any] struct{}
+
+type Session[BUILDER any] interface {
+	Builder() BUILDER
+	Commit() error
+	Rollback() error
+}
+
+func (ob *Octobe[DRIVER, CONFIG, BUILDER]) Begin(opts ...Option[CONFIG]) (Session[BUILDER], error) {
+	return nil, nil
+}
@@ -0,0 +1,161 @@
+package octotest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Default images of the containers, chosen to start quickly.
+const (
+	DefaultPostgresImage   = "postgres:17-alpine"
+	DefaultClickHouseImage = "clickhouse/clickhouse-server:24.8-alpine"
+)
+
+// credentials are the user, password and database of the containers started by Postgres and ClickHouse.
+const credentials = "octotest"
+
+// Spec describes a database container.
+type Spec struct {
+	// Image is the image of the container.
+	Image string
+	// Port is the port of the database inside the container, it is published on a random port of the host.
+	Port int
+	// Env are the environment variables of the container.
+	Env map[string]string
+	// DSN returns the data source name of the database published at addr, a host and port.
+	DSN func(addr string) string
+}
+
+// PostgresSpec returns the spec of a PostgreSQL container with a user, password and database named octotest.
+func PostgresSpec() Spec {
+	return Spec{
+		Image: DefaultPostgresImage,
+		Port:  5432,
+		Env: map[string]string{
+			"POSTGRES_USER":     credentials,
+			"POSTGRES_PASSWORD": credentials,
+			"POSTGRES_DB":       credentials,
+		},
+		DSN: func(addr string) string {
+			return "postgres://" + credentials + ":" + credentials + "@" + addr + "/" + credentials + "?sslmode=disable"
+		},
+	}
+}
+
+// ClickHouseSpec returns the spec of a ClickHouse container with a user, password and database named octotest. The DSN
+// is for the native protocol, it can be parsed with clickhouse.ParseDSN of clickhouse-go.
+func ClickHouseSpec() Spec {
+	return Spec{
+		Image: DefaultClickHouseImage,
+		Port:  9000,
+		Env: map[string]string{
+			"CLICKHOUSE_USER":     credentials,
+			"CLICKHOUSE_PASSWORD": credentials,
+			"CLICKHOUSE_DB":       credentials,
+		},
+		DSN: func(addr string) string {
+			return "clickhouse://" + credentials + ":" + credentials + "@" + addr + "/" + credentials
+		},
+	}
+}
+
+// Container is a database container started for a test.
+type Container struct {
+	// ID is the id of the container.
+	ID string
+	// Addr is the host and port the database is published at.
+	Addr string
+	// DSN is the data source name of the database.
+	DSN string
+}
+
+// Postgres starts a PostgreSQL container, see Start.
+func Postgres(t testing.TB) *Container {
+	t.Helper()
+	return Start(t, PostgresSpec())
+}
+
+// ClickHouse starts a ClickHouse container, see Start.
+func ClickHouse(t testing.TB) *Container {
+	t.Helper()
+	return Start(t, ClickHouseSpec())
+}
+
+// Start starts a container of spec with the docker command, or the command named by the OCTOTEST_DOCKER environment
+// variable, like podman, and removes it when the test and its subtests have completed. The test is skipped if the
+// command is not installed, so integration tests pass on machines without a container runtime. The database in the
+// container may still be starting up when Start returns, New waits until it is ready.
+func Start(t testing.TB, spec Spec) *Container {
+	t.Helper()
+	docker := os.Getenv("OCTOTEST_DOCKER")
+	if docker == "" {
+		docker = "docker"
+	}
+	if _, err := exec.LookPath(docker); err != nil {
+		t.Skipf("octotest: %s is not installed, skipping test that needs a %s container", docker, spec.Image)
+	}
+
+	port := strconv.Itoa(spec.Port) + "/tcp"
+	args := []string{"run", "--detach", "--rm", "--publish", "127.0.0.1::" + port}
+	keys := make([]string, 0, len(spec.Env))
+	for key := range spec.Env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		args = append(args, "--env", key+"="+spec.Env[key])
+	}
+	args = append(args, spec.Image)
+
+	id, err := run(docker, args...)
+	if err != nil {
+		t.Fatalf("octotest: failed to start %s container: %v", spec.Image, err)
+	}
+	t.Cleanup(func() {
+		if _, err := run(docker, "rm", "--force", "--volumes", id); err != nil {
+			t.Errorf("octotest: failed to remove container %s: %v", id, err)
+		}
+	})
+
+	output, err := run(docker, "port", id, port)
+	if err != nil {
+		t.Fatalf("octotest: failed to look up the published port of container %s: %v", id, err)
+	}
+	addr := strings.TrimSpace(strings.SplitN(output, "\n", 2)[0])
+	host, published, err := net.SplitHostPort(addr)
+	if err != nil {
+		t.Fatalf("octotest: unexpected published port %q of container %s: %v", output, id, err)
+	}
+	if host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+	addr = net.JoinHostPort(host, published)
+
+	c := &Container{ID: id, Addr: addr}
+	if spec.DSN != nil {
+		c.DSN = spec.DSN(addr)
+	}
+	return c
+}
+
+// run runs the container command with args and returns its trimmed output.
+func run(docker string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, docker, args...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%s %s: %w: %s", docker, args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
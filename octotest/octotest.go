@@ -0,0 +1,203 @@
+// Package octotest bootstraps databases for integration tests. It starts PostgreSQL or ClickHouse in a container, opens
+// an Octobe instance once the database is ready, applies migrations and fixtures, and cleans everything up when the
+// test has completed:
+//
+//	func TestProducts(t *testing.T) {
+//		c := octotest.Postgres(t)
+//		ob := octotest.New(t, postgres.OpenPGXPool(context.Background(), c.DSN),
+//			octotest.WithTxOptions(postgres.WithPGXTxOptions(postgres.PGXTxOptions{})),
+//			octotest.WithMigrations(migrate.Postgres(""), migrations...),
+//			octotest.WithFixture(func(session octobe.BuilderSession[postgres.Builder]) error { ... }))
+//		...
+//	}
+//
+// Containers are run with the docker command rather than a client library, so the package adds no dependencies, tests
+// are skipped on machines without docker. A container per package, started in TestMain, is considerably faster than
+// one per test, Tx keeps the tests sharing it isolated by rolling back whatever each of them wrote.
+package octotest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/migrate"
+	"github.com/ponrove/octobe/seed"
+)
+
+// DefaultReadyTimeout is how long New waits for the database to become ready by default.
+const DefaultReadyTimeout = time.Minute
+
+// Option configures New.
+type Option func(*config)
+
+// config holds the configuration of New.
+type config struct {
+	instanceOptions []octobe.InstanceOption
+	readyTimeout    time.Duration
+	// steps are the setup steps, of type step for the builder of the instance.
+	steps []any
+	// txOptions are the options of WithTxOptions, of type []octobe.Option for the config of the instance.
+	txOptions any
+}
+
+// step is a setup step of an instance with builders of type BUILDER.
+type step[BUILDER any] func(ctx context.Context, db database[BUILDER]) error
+
+// database is the instance created by New, as seen by the setup steps.
+type database[BUILDER any] interface {
+	migrate(ctx context.Context, store migrate.Store[BUILDER], migrations []migrate.Migration[BUILDER]) error
+	seed(ctx context.Context, store seed.Store[BUILDER], seeds []seed.Seed[BUILDER], environment string) error
+	begin(ctx context.Context) (octobe.Session[BUILDER], error)
+}
+
+// WithInstanceOptions passes opts to octobe.New.
+func WithInstanceOptions(opts ...octobe.InstanceOption) Option {
+	return func(cfg *config) {
+		cfg.instanceOptions = append(cfg.instanceOptions, opts...)
+	}
+}
+
+// WithReadyTimeout sets how long New waits for the database to become ready, DefaultReadyTimeout by default.
+func WithReadyTimeout(d time.Duration) Option {
+	return func(cfg *config) {
+		cfg.readyTimeout = d
+	}
+}
+
+// WithTxOptions sets the options the migrations and seeds are run with. Drivers with transactions need options beginning
+// one, like postgres.WithPGXTxOptions, the config of the options must be the one of the instance.
+func WithTxOptions[CONFIG any](opts ...octobe.Option[CONFIG]) Option {
+	return func(cfg *config) {
+		cfg.txOptions = opts
+	}
+}
+
+// WithMigrations applies migrations with a migrate runner using store, in transactions begun with the options of
+// WithTxOptions. The builder of store must be the one of the instance, like with the other setup options.
+func WithMigrations[BUILDER any](store migrate.Store[BUILDER], migrations ...migrate.Migration[BUILDER]) Option {
+	return func(cfg *config) {
+		cfg.steps = append(cfg.steps, step[BUILDER](func(ctx context.Context, db database[BUILDER]) error {
+			return db.migrate(ctx, store, migrations)
+		}))
+	}
+}
+
+// WithSeeds applies seeds with a seed runner using store, in transactions begun with the options of WithTxOptions,
+// recording them under environment, or under seed.DefaultEnvironment if it is empty.
+func WithSeeds[BUILDER any](store seed.Store[BUILDER], environment string, seeds ...seed.Seed[BUILDER]) Option {
+	return func(cfg *config) {
+		cfg.steps = append(cfg.steps, step[BUILDER](func(ctx context.Context, db database[BUILDER]) error {
+			return db.seed(ctx, store, seeds, environment)
+		}))
+	}
+}
+
+// WithFixture runs fixture in a session without a transaction, to insert the rows tests rely on.
+func WithFixture[BUILDER any](fixture func(session octobe.BuilderSession[BUILDER]) error) Option {
+	return func(cfg *config) {
+		cfg.steps = append(cfg.steps, step[BUILDER](func(ctx context.Context, db database[BUILDER]) error {
+			session, err := db.begin(ctx)
+			if err != nil {
+				return err
+			}
+			return fixture(session)
+		}))
+	}
+}
+
+// New opens an instance with open, retrying until the database accepts connections, and waits until it answers pings.
+// It then runs the migrations, seeds and fixtures of opts in the order they are given, and closes the instance when the
+// test has completed. Any failure fails the test.
+func New[DRIVER, CONFIG, BUILDER any](t testing.TB, open octobe.Open[DRIVER, CONFIG, BUILDER], opts ...Option) *octobe.Octobe[DRIVER, CONFIG, BUILDER] {
+	t.Helper()
+	cfg := config{readyTimeout: DefaultReadyTimeout}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.readyTimeout)
+	defer cancel()
+
+	ob, err := openInstance(ctx, open, cfg.instanceOptions)
+	if err != nil {
+		t.Fatalf("octotest: failed to open instance: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := ob.Close(context.Background()); err != nil {
+			t.Errorf("octotest: failed to close instance: %v", err)
+		}
+	})
+	if err = ob.WaitForReady(ctx, nil); err != nil {
+		t.Fatalf("octotest: %v", err)
+	}
+
+	txOptions, ok := cfg.txOptions.([]octobe.Option[CONFIG])
+	if !ok && cfg.txOptions != nil {
+		t.Fatalf("octotest: transaction options are for another config than %T", *new(CONFIG))
+	}
+	db := instance[DRIVER, CONFIG, BUILDER]{ob: ob, txOptions: txOptions}
+	for i, s := range cfg.steps {
+		setup, ok := s.(step[BUILDER])
+		if !ok {
+			t.Fatalf("octotest: setup step %d is for another builder than %T", i+1, *new(BUILDER))
+		}
+		if err = setup(context.Background(), db); err != nil {
+			t.Fatalf("octotest: setup step %d failed: %v", i+1, err)
+		}
+	}
+	return ob
+}
+
+// openInstance creates an instance with open until it succeeds or ctx is done, as drivers that connect when opened
+// fail while the database is starting up.
+func openInstance[DRIVER, CONFIG, BUILDER any](ctx context.Context, open octobe.Open[DRIVER, CONFIG, BUILDER], opts []octobe.InstanceOption) (*octobe.Octobe[DRIVER, CONFIG, BUILDER], error) {
+	for attempt := 1; ; attempt++ {
+		ob, err := octobe.New(open, opts...)
+		if err == nil {
+			return ob, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%w: %w", err, ctx.Err())
+		case <-time.After(octobe.DefaultReadyBackoff(attempt)):
+		}
+	}
+}
+
+// instance implements database for an instance.
+type instance[DRIVER, CONFIG, BUILDER any] struct {
+	ob        *octobe.Octobe[DRIVER, CONFIG, BUILDER]
+	txOptions []octobe.Option[CONFIG]
+}
+
+// migrate applies migrations.
+func (i instance[DRIVER, CONFIG, BUILDER]) migrate(ctx context.Context, store migrate.Store[BUILDER], migrations []migrate.Migration[BUILDER]) error {
+	runner := migrate.NewRunner(i.ob, store)
+	if err := runner.Register(migrations...); err != nil {
+		return err
+	}
+	_, err := runner.Up(ctx, i.txOptions...)
+	return err
+}
+
+// seed applies seeds of environment.
+func (i instance[DRIVER, CONFIG, BUILDER]) seed(ctx context.Context, store seed.Store[BUILDER], seeds []seed.Seed[BUILDER], environment string) error {
+	var opts []seed.Option
+	if environment != "" {
+		opts = append(opts, seed.WithEnvironment(environment))
+	}
+	runner := seed.NewRunner(i.ob, store, opts...)
+	if err := runner.Register(seeds...); err != nil {
+		return err
+	}
+	_, err := runner.Run(ctx, i.txOptions...)
+	return err
+}
+
+// begin begins a session without options.
+func (i instance[DRIVER, CONFIG, BUILDER]) begin(ctx context.Context) (octobe.Session[BUILDER], error) {
+	return i.ob.Begin(ctx)
+}
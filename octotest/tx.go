@@ -0,0 +1,147 @@
+package octotest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ponrove/octobe"
+)
+
+// savepointID numbers the savepoints of the sessions of Tx and Savepoint.
+var savepointID atomic.Int64
+
+// Tx begins a session on ob in a transaction, started with opts like postgres.WithPGXTxOptions, and returns it wrapped
+// so the transaction is rolled back when the test has completed, see Savepoint. Tests sharing a database stay isolated
+// from each other without truncating tables, as long as every test runs its queries in its own transaction.
+func Tx[DRIVER, CONFIG, BUILDER any](t testing.TB, ob *octobe.Octobe[DRIVER, CONFIG, BUILDER], opts ...octobe.Option[CONFIG]) octobe.Session[BUILDER] {
+	t.Helper()
+	session, err := ob.Begin(context.Background(), opts...)
+	if err != nil {
+		t.Fatalf("octotest: failed to begin transaction: %v", err)
+	}
+	if tx, ok := session.(octobe.Transactional); !ok || !tx.InTransaction() {
+		_ = session.Rollback()
+		t.Fatal("octotest: Tx needs options starting a transaction, like postgres.WithPGXTxOptions")
+	}
+	t.Cleanup(func() {
+		if err := session.Rollback(); err != nil && !errors.Is(err, octobe.ErrTxDone) {
+			t.Errorf("octotest: failed to roll back transaction: %v", err)
+		}
+	})
+	return Savepoint(t, session)
+}
+
+// Savepoint returns a session running in a savepoint of the transaction of session, which is rolled back when the test
+// has completed. Code under test that commits the returned session releases the savepoint rather than committing the
+// transaction, and continues in a new savepoint, so the test can verify what was written and still leave no trace.
+// Rolling the returned session back rolls back to the savepoint. The driver must support savepoints.
+func Savepoint[BUILDER any](t testing.TB, session octobe.BuilderSession[BUILDER]) octobe.Session[BUILDER] {
+	t.Helper()
+	savepoints := savepointsOf[BUILDER](session)
+	if savepoints == nil {
+		t.Fatal("octotest: the session does not run in a transaction of a driver with savepoints")
+	}
+
+	// The changes of released savepoints are kept in an outer savepoint, which is never released by the session.
+	guard := fmt.Sprintf("octotest_%d", savepointID.Add(1))
+	if err := savepoints.Savepoint(guard); err != nil {
+		t.Fatalf("octotest: failed to create savepoint: %v", err)
+	}
+	t.Cleanup(func() {
+		err := savepoints.RollbackToSavepoint(guard)
+		if err == nil {
+			err = savepoints.ReleaseSavepoint(guard)
+		}
+		if err != nil && !errors.Is(err, octobe.ErrTxDone) {
+			t.Errorf("octotest: failed to roll back to savepoint: %v", err)
+		}
+	})
+
+	s := &txSession[BUILDER]{session: session, savepoints: savepoints}
+	if err := s.begin(); err != nil {
+		t.Fatalf("octotest: failed to create savepoint: %v", err)
+	}
+	return s
+}
+
+// savepointsOf returns the savepoints of the driver session behind session, or nil if it has none or does not run in a
+// transaction.
+func savepointsOf[BUILDER any](session any) octobe.Savepoints {
+	for {
+		if t, ok := session.(octobe.Transactional); ok && !t.InTransaction() {
+			return nil
+		}
+		if savepoints, ok := session.(octobe.Savepoints); ok {
+			return savepoints
+		}
+		unwrapper, ok := session.(sessionUnwrapper[BUILDER])
+		if !ok {
+			return nil
+		}
+		session = unwrapper.Unwrap()
+	}
+}
+
+// sessionUnwrapper is implemented by sessions wrapping the session of a driver, like the sessions of octobe.
+type sessionUnwrapper[BUILDER any] interface {
+	Unwrap() octobe.Session[BUILDER]
+}
+
+// txSession runs in a savepoint, which its Commit releases instead of committing the transaction.
+type txSession[BUILDER any] struct {
+	session    octobe.BuilderSession[BUILDER]
+	savepoints octobe.Savepoints
+	name       string
+}
+
+// Ensure txSession implements the octobe.Session interface.
+var _ octobe.Session[any] = &txSession[any]{}
+
+// begin creates a new savepoint to run in.
+func (s *txSession[BUILDER]) begin() error {
+	name := fmt.Sprintf("octotest_%d", savepointID.Add(1))
+	if err := s.savepoints.Savepoint(name); err != nil {
+		return err
+	}
+	s.name = name
+	return nil
+}
+
+// Builder returns the builder of the wrapped session.
+func (s *txSession[BUILDER]) Builder() BUILDER {
+	return s.session.Builder()
+}
+
+// Commit releases the savepoint, keeping its changes in the transaction, and continues in a new one.
+func (s *txSession[BUILDER]) Commit() error {
+	if err := s.savepoints.ReleaseSavepoint(s.name); err != nil {
+		return err
+	}
+	return s.begin()
+}
+
+// Rollback rolls back to the savepoint, which stays in place for the next statements.
+func (s *txSession[BUILDER]) Rollback() error {
+	return s.savepoints.RollbackToSavepoint(s.name)
+}
+
+// InTransaction reports that the session runs in a transaction.
+func (s *txSession[BUILDER]) InTransaction() bool {
+	return true
+}
+
+// Context returns the context of the wrapped session, see octobe.SessionContext.
+func (s *txSession[BUILDER]) Context() context.Context {
+	return octobe.SessionContext(s.session)
+}
+
+// Unwrap returns the wrapped session, so adapters of the drivers reach the transaction.
+func (s *txSession[BUILDER]) Unwrap() octobe.Session[BUILDER] {
+	if session, ok := s.session.(octobe.Session[BUILDER]); ok {
+		return session
+	}
+	return nil
+}
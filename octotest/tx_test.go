@@ -0,0 +1,56 @@
+package octotest_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/driver/postgres"
+	"github.com/ponrove/octobe/octotest"
+	"github.com/stretchr/testify/require"
+)
+
+// createUser stands for code under test that commits its session.
+func createUser(session octobe.Session[postgres.Builder], name string) error {
+	if _, err := session.Builder()("INSERT INTO users (name) VALUES ($1)").Arguments(name).Exec(); err != nil {
+		_ = session.Rollback()
+		return err
+	}
+	return session.Commit()
+}
+
+func TestTx(t *testing.T) {
+	mock, err := pgxmock.NewConn()
+	require.NoError(t, err)
+	savepoint := func(statement string) {
+		mock.ExpectExec(statement + ` "octotest_\d+"`).WillReturnResult(pgxmock.NewResult("SAVEPOINT", 0))
+	}
+
+	mock.ExpectBegin()
+	savepoint("SAVEPOINT")
+	savepoint("SAVEPOINT")
+	mock.ExpectExec("INSERT INTO users").WithArgs("ada").WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	savepoint("RELEASE SAVEPOINT")
+	savepoint("SAVEPOINT")
+	mock.ExpectExec("INSERT INTO users").WithArgs("").WillReturnError(errors.New("null value in column \"name\""))
+	savepoint("ROLLBACK TO SAVEPOINT")
+	mock.ExpectQuery("SELECT count").WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(1))
+	savepoint("ROLLBACK TO SAVEPOINT")
+	savepoint("RELEASE SAVEPOINT")
+	mock.ExpectRollback()
+
+	ob, err := octobe.New(postgres.OpenPGXWithConn(mock))
+	require.NoError(t, err)
+
+	t.Run("test", func(t *testing.T) {
+		session := octotest.Tx(t, ob, postgres.WithPGXTxOptions(postgres.PGXTxOptions{}))
+		require.NoError(t, createUser(session, "ada"))
+		require.Error(t, createUser(session, ""))
+
+		var count int
+		require.NoError(t, session.Builder()("SELECT count(*) FROM users").QueryRow(&count))
+		require.Equal(t, 1, count)
+	})
+	require.NoError(t, mock.ExpectationsWereMet())
+}
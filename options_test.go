@@ -0,0 +1,33 @@
+package octobe_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ponrove/octobe"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithDefaultOptions(t *testing.T) {
+	d := &fakeDriver{}
+	ob, err := octobe.New(d.open(), octobe.WithDefaultOptions(withTx()))
+	require.NoError(t, err)
+
+	_, err = ob.Begin(context.Background())
+	require.NoError(t, err)
+	_, err = ob.Begin(context.Background(), withoutTx())
+	require.NoError(t, err)
+	err = ob.StartTransaction(context.Background(), func(octobe.BuilderSession[string]) error { return nil })
+	require.NoError(t, err)
+
+	require.True(t, d.sessions[0].tx)
+	require.False(t, d.sessions[1].tx)
+	require.True(t, d.sessions[2].tx)
+	require.True(t, d.sessions[2].committed)
+}
+
+func TestWithDefaultOptionsMismatch(t *testing.T) {
+	type otherConfig struct{}
+	_, err := octobe.New((&fakeDriver{}).open(), octobe.WithDefaultOptions(func(*otherConfig) {}))
+	require.Error(t, err)
+}
@@ -0,0 +1,172 @@
+// Package otel traces Octobe with OpenTelemetry. It provides a query hook that creates a span for every session and
+// transaction, and a child span for every query performed within it, for all drivers.
+package otel
+
+import (
+	"context"
+	"time"
+
+	"github.com/ponrove/octobe"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope name of the tracer.
+const ScopeName = "github.com/ponrove/octobe/otel"
+
+// Attribute keys recorded on the spans.
+const (
+	AttributeDBSystem    = attribute.Key("db.system.name")
+	AttributeDBQueryText = attribute.Key("db.query.text")
+	AttributeDBOperation = attribute.Key("db.operation.name")
+	AttributeDriver      = attribute.Key("octobe.driver")
+	AttributeRows        = attribute.Key("octobe.rows")
+	AttributeTransaction = attribute.Key("octobe.transaction")
+	AttributeCommitted   = attribute.Key("octobe.committed")
+)
+
+// Names of the spans, query spans are named after their operation, like octobe.exec.
+const (
+	sessionSpanName     = "octobe.session"
+	transactionSpanName = "octobe.transaction"
+	querySpanNamePrefix = "octobe."
+)
+
+// Option configures the tracing hook.
+type Option func(cfg *config)
+
+// config holds the configuration of the tracing hook.
+type config struct {
+	provider   trace.TracerProvider
+	attributes []attribute.KeyValue
+	statements bool
+}
+
+// WithTracerProvider sets the tracer provider to create spans with, the global tracer provider is used by default.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(cfg *config) {
+		cfg.provider = provider
+	}
+}
+
+// WithAttributes adds attributes to every span, like the name of the database.
+func WithAttributes(attributes ...attribute.KeyValue) Option {
+	return func(cfg *config) {
+		cfg.attributes = append(cfg.attributes, attributes...)
+	}
+}
+
+// WithoutStatements stops recording the SQL statement of queries, for statements that may contain sensitive literals.
+func WithoutStatements() Option {
+	return func(cfg *config) {
+		cfg.statements = false
+	}
+}
+
+// Hook is an octobe.QueryHook and octobe.SessionHook that traces sessions and queries.
+type Hook struct {
+	tracer trace.Tracer
+	cfg    config
+}
+
+// Ensure Hook is invoked around both queries and sessions.
+var (
+	_ octobe.QueryHook   = &Hook{}
+	_ octobe.SessionHook = &Hook{}
+)
+
+// NewHook creates a tracing hook, to be passed to octobe.New with octobe.WithQueryHook.
+func NewHook(opts ...Option) *Hook {
+	cfg := config{statements: true}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.provider == nil {
+		cfg.provider = otel.GetTracerProvider()
+	}
+
+	return &Hook{
+		tracer: cfg.provider.Tracer(ScopeName),
+		cfg:    cfg,
+	}
+}
+
+// BeforeSession starts the span of a session, which is the parent span of the queries performed within it.
+func (h *Hook) BeforeSession(ctx context.Context, event *octobe.SessionEvent) context.Context {
+	ctx, _ = h.tracer.Start(ctx, sessionSpanName,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithTimestamp(event.Start),
+		trace.WithAttributes(h.driverAttributes(event.Driver)...),
+	)
+	return ctx
+}
+
+// AfterSession ends the span of a session, naming it after a transaction if the session ran in one.
+func (h *Hook) AfterSession(ctx context.Context, event *octobe.SessionEvent) {
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(AttributeTransaction.Bool(event.Transaction))
+	if event.Transaction {
+		span.SetName(transactionSpanName)
+		span.SetAttributes(AttributeCommitted.Bool(event.Committed))
+	}
+	end(span, event.Err, event.Start.Add(event.Duration))
+}
+
+// BeforeQuery starts the span of a query.
+func (h *Hook) BeforeQuery(ctx context.Context, event *octobe.QueryEvent) context.Context {
+	attributes := append(h.driverAttributes(event.Driver), AttributeDBOperation.String(string(event.Operation)))
+	if h.cfg.statements {
+		attributes = append(attributes, AttributeDBQueryText.String(event.Query))
+	}
+
+	ctx, _ = h.tracer.Start(ctx, querySpanNamePrefix+string(event.Operation),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithTimestamp(event.Start),
+		trace.WithAttributes(attributes...),
+	)
+	return ctx
+}
+
+// AfterQuery ends the span of a query, recording the number of rows if the driver reported it.
+func (h *Hook) AfterQuery(ctx context.Context, event *octobe.QueryEvent) {
+	span := trace.SpanFromContext(ctx)
+	if event.Rows >= 0 {
+		span.SetAttributes(AttributeRows.Int64(event.Rows))
+	}
+	end(span, event.Err, event.Start.Add(event.Duration))
+}
+
+// driverAttributes returns the attributes describing the driver, along with the configured attributes.
+func (h *Hook) driverAttributes(driver octobe.DriverInfo) []attribute.KeyValue {
+	attributes := make([]attribute.KeyValue, 0, len(h.cfg.attributes)+4)
+	attributes = append(attributes, h.cfg.attributes...)
+	if driver.System != "" {
+		attributes = append(attributes, AttributeDBSystem.String(driver.System))
+	}
+	if driver.Name != "" {
+		attributes = append(attributes, AttributeDriver.String(driver.Name))
+	}
+	return attributes
+}
+
+// end records err on span and ends it at the given time.
+func end(span trace.Span, err error, at time.Time) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End(trace.WithTimestamp(at))
+}
+
+// CommentTags returns a source of tags for octobe.WithQueryComments that adds the traceparent and tracestate of the
+// span in the context of a query in the W3C Trace Context format, as sqlcommenter does, so a query found in the
+// database can be linked to its trace.
+func CommentTags() octobe.CommentTags {
+	propagator := propagation.TraceContext{}
+	return func(ctx context.Context, tags map[string]string) {
+		propagator.Inject(ctx, propagation.MapCarrier(tags))
+	}
+}
@@ -0,0 +1,120 @@
+package otel_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/driver/postgres"
+	"github.com/ponrove/octobe/otel"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestHook(t *testing.T) {
+	ctx := context.Background()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	mock, err := pgxmock.NewConn()
+	require.NoError(t, err)
+	defer mock.Close(ctx)
+
+	expectedErr := errors.New("insert failed")
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE products").WillReturnResult(pgxmock.NewResult("UPDATE", 2))
+	mock.ExpectExec("INSERT INTO products").WillReturnError(expectedErr)
+	mock.ExpectRollback()
+
+	ob, err := octobe.New(postgres.OpenPGXWithConn(mock), octobe.WithQueryHook(otel.NewHook(
+		otel.WithTracerProvider(provider),
+		otel.WithAttributes(attribute.String("db.namespace", "shop")),
+	)))
+	require.NoError(t, err)
+
+	err = ob.StartTransaction(ctx, func(session octobe.BuilderSession[postgres.Builder]) error {
+		if _, err := session.Builder()("UPDATE products SET price = 0").Exec(); err != nil {
+			return err
+		}
+		_, err := session.Builder()("INSERT INTO products (name) VALUES ('a')").Exec()
+		return err
+	}, postgres.WithPGXTxOptions(postgres.PGXTxOptions{}))
+	require.ErrorIs(t, err, expectedErr)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+
+	update, insert, transaction := spans[0], spans[1], spans[2]
+	require.Equal(t, "octobe.exec", update.Name())
+	require.Equal(t, transaction.SpanContext().SpanID(), update.Parent().SpanID())
+	require.Contains(t, update.Attributes(), otel.AttributeDBQueryText.String("UPDATE products SET price = 0"))
+	require.Contains(t, update.Attributes(), otel.AttributeDBSystem.String("postgresql"))
+	require.Contains(t, update.Attributes(), otel.AttributeDriver.String("pgx"))
+	require.Contains(t, update.Attributes(), otel.AttributeRows.Int64(2))
+	require.Contains(t, update.Attributes(), attribute.String("db.namespace", "shop"))
+
+	require.Equal(t, codes.Error, insert.Status().Code)
+
+	require.Equal(t, "octobe.transaction", transaction.Name())
+	require.Contains(t, transaction.Attributes(), otel.AttributeTransaction.Bool(true))
+	require.Contains(t, transaction.Attributes(), otel.AttributeCommitted.Bool(false))
+}
+
+func TestHookWithoutStatements(t *testing.T) {
+	ctx := context.Background()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	mock, err := pgxmock.NewConn()
+	require.NoError(t, err)
+	defer mock.Close(ctx)
+
+	mock.ExpectExec("DELETE FROM sessions").WillReturnResult(pgxmock.NewResult("DELETE", 1))
+
+	ob, err := octobe.New(postgres.OpenPGXWithConn(mock), octobe.WithQueryHook(otel.NewHook(
+		otel.WithTracerProvider(provider),
+		otel.WithoutStatements(),
+	)))
+	require.NoError(t, err)
+
+	session, err := ob.Begin(ctx)
+	require.NoError(t, err)
+	_, err = session.Builder()("DELETE FROM sessions WHERE token = 'secret'").Exec()
+	require.NoError(t, err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	require.Equal(t, "octobe.session", spans[0].Name())
+	for _, kv := range spans[1].Attributes() {
+		require.NotEqual(t, otel.AttributeDBQueryText, kv.Key)
+	}
+}
+
+func TestCommentTags(t *testing.T) {
+	ctx := context.Background()
+	provider := sdktrace.NewTracerProvider()
+
+	mock, err := pgxmock.NewConn()
+	require.NoError(t, err)
+	defer mock.Close(ctx)
+
+	mock.ExpectExec(`^DELETE FROM products /\*traceparent='00-[0-9a-f]{32}-[0-9a-f]{16}-01'\*/$`).WillReturnResult(pgxmock.NewResult("DELETE", 1))
+
+	ob, err := octobe.New(postgres.OpenPGXWithConn(mock),
+		octobe.WithQueryHook(otel.NewHook(otel.WithTracerProvider(provider))),
+		octobe.WithQueryComments(otel.CommentTags()),
+	)
+	require.NoError(t, err)
+
+	session, err := ob.Begin(ctx)
+	require.NoError(t, err)
+	_, err = session.Builder()("DELETE FROM products").Exec()
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,48 @@
+package octobe
+
+import (
+	"context"
+	"runtime/pprof"
+)
+
+// Profiler labels set by WithProfilerLabels.
+const (
+	ProfilerLabelDriver    = "octobe_driver"
+	ProfilerLabelOperation = "octobe_operation"
+	ProfilerLabelQuery     = "octobe_query"
+)
+
+// WithProfilerLabels sets pprof labels on the goroutine performing a query while it runs, like pprof.Do, so CPU and
+// blocking profiles of a busy service can be attributed to queries. The labels are the driver, the operation and, for
+// queries registered with RegisterQuery or RegisterQueries, the name of the query. The labels of the goroutine are
+// restored once the query has finished.
+func WithProfilerLabels() InstanceOption {
+	return WithQueryHook(profilerHook{})
+}
+
+// profilerHook is the query hook setting the labels of WithProfilerLabels.
+type profilerHook struct{}
+
+// Ensure profilerHook is invoked around queries.
+var _ QueryHook = profilerHook{}
+
+// profilerParentKey is the context key of the context a query was labeled from, holding the labels to restore.
+type profilerParentKey struct{}
+
+// BeforeQuery labels the goroutine with the labels of the query.
+func (profilerHook) BeforeQuery(ctx context.Context, event *QueryEvent) context.Context {
+	labels := []string{ProfilerLabelDriver, event.Driver.Name, ProfilerLabelOperation, string(event.Operation)}
+	if name, ok := LookupQueryName(event.Query); ok {
+		labels = append(labels, ProfilerLabelQuery, name)
+	}
+	labeled := pprof.WithLabels(context.WithValue(ctx, profilerParentKey{}, ctx), pprof.Labels(labels...))
+	pprof.SetGoroutineLabels(labeled)
+	return labeled
+}
+
+// AfterQuery restores the labels the goroutine had before the query.
+func (profilerHook) AfterQuery(ctx context.Context, _ *QueryEvent) {
+	if parent, ok := ctx.Value(profilerParentKey{}).(context.Context); ok {
+		pprof.SetGoroutineLabels(parent)
+	}
+}
@@ -0,0 +1,34 @@
+package octobe_test
+
+import (
+	"context"
+	"runtime/pprof"
+	"testing"
+
+	"github.com/ponrove/octobe"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithProfilerLabels(t *testing.T) {
+	octobe.RegisterQuery("pprof_test_products", "SELECT id, name FROM products ORDER BY id")
+
+	d := &fakeDriver{}
+	ob, err := octobe.New(d.open(), octobe.WithProfilerLabels())
+	require.NoError(t, err)
+	_, err = ob.Begin(context.Background(), withoutTx())
+	require.NoError(t, err)
+
+	query, err := octobe.Query("pprof_test_products")
+	require.NoError(t, err)
+	ctx, done := octobe.BeginQuery(d.sessions[0].ctx, octobe.OperationQuery, query, nil)
+	name, _ := pprof.Label(ctx, octobe.ProfilerLabelQuery)
+	require.Equal(t, "pprof_test_products", name)
+	operation, _ := pprof.Label(ctx, octobe.ProfilerLabelOperation)
+	require.Equal(t, "query", operation)
+	done(1, nil)
+
+	ctx, done = octobe.BeginQuery(d.sessions[0].ctx, octobe.OperationExec, "DELETE FROM products", nil)
+	_, ok := pprof.Label(ctx, octobe.ProfilerLabelQuery)
+	require.False(t, ok, "unregistered queries have no name")
+	done(1, nil)
+}
@@ -0,0 +1,65 @@
+package octobe
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// queryErrorArgs is the number of arguments whose types are recorded in a QueryError, further arguments are left out.
+const queryErrorArgs = 10
+
+// QueryError is returned by the drivers when executing a query fails. It records which query failed next to the error
+// of the driver, so an error like "no rows in result set" can be traced back to its statement. errors.Is and errors.As
+// match the wrapped error as before.
+type QueryError struct {
+	// Driver is the name of the driver that executed the query.
+	Driver string
+	// Query is the SQL of the query, truncated to DefaultLogQueryLength bytes.
+	Query string
+	// ArgTypes are the Go types of the first arguments of the query, like string or []uint8. The values themselves are
+	// not recorded, errors travel far and may hold secrets. WithLogArgs logs them with a redactor of choice.
+	ArgTypes []string
+	// Err is the error the query failed with.
+	Err error
+}
+
+// Error returns the driver, the query and the error it failed with.
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("%s query %q: %v", e.Driver, e.Query, e.Err)
+}
+
+// Unwrap returns the error the query failed with.
+func (e *QueryError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrNoRows and the query failed because it returned no rows. The no rows errors of the
+// drivers all match sql.ErrNoRows, pgx.ErrNoRows wraps it.
+func (e *QueryError) Is(target error) bool {
+	return target == ErrNoRows && errors.Is(e.Err, sql.ErrNoRows)
+}
+
+// WrapQueryError wraps the error of a query in a *QueryError, for use by drivers when executing a segment fails. It
+// returns nil if err is nil, and err as is if it already is or wraps a *QueryError, as when a query fails inside the
+// callback of another.
+func WrapQueryError(driver, query string, args []any, err error) error {
+	if err == nil {
+		return nil
+	}
+	var queryErr *QueryError
+	if errors.As(err, &queryErr) {
+		return err
+	}
+
+	var argTypes []string
+	for i := 0; i < len(args) && i < queryErrorArgs; i++ {
+		argTypes = append(argTypes, fmt.Sprintf("%T", args[i]))
+	}
+	return &QueryError{
+		Driver:   driver,
+		Query:    truncate(query, DefaultLogQueryLength),
+		ArgTypes: argTypes,
+		Err:      err,
+	}
+}
@@ -0,0 +1,50 @@
+package octobe_test
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ponrove/octobe"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrapQueryError(t *testing.T) {
+	require.NoError(t, octobe.WrapQueryError("pgx", "SELECT 1", nil, nil))
+
+	cause := errors.New("no rows in result set")
+	args := []any{"secret", []byte("secret"), 3, nil}
+	err := octobe.WrapQueryError("pgx", "SELECT name FROM products WHERE id = $1", args, cause)
+	require.ErrorIs(t, err, cause)
+	require.EqualError(t, err, `pgx query "SELECT name FROM products WHERE id = $1": no rows in result set`)
+
+	var queryErr *octobe.QueryError
+	require.ErrorAs(t, err, &queryErr)
+	require.Equal(t, "pgx", queryErr.Driver)
+	// Only the types of the arguments are recorded, not their values.
+	require.Equal(t, []string{"string", "[]uint8", "int", "<nil>"}, queryErr.ArgTypes)
+	require.NotContains(t, fmt.Sprintf("%#v", *queryErr), "secret")
+
+	// An error that already carries a query error is not wrapped a second time.
+	require.Same(t, err, octobe.WrapQueryError("pgx", "SELECT 2", nil, err))
+
+	long := octobe.WrapQueryError("pgx", strings.Repeat("x", 2*octobe.DefaultLogQueryLength), make([]any, 20), cause)
+	require.ErrorAs(t, long, &queryErr)
+	require.Len(t, queryErr.Query, octobe.DefaultLogQueryLength+len("..."))
+	require.Len(t, queryErr.ArgTypes, 10)
+}
+
+func TestQueryErrorNoRows(t *testing.T) {
+	err := octobe.WrapQueryError("database/sql", "SELECT 1", nil, sql.ErrNoRows)
+	require.ErrorIs(t, err, octobe.ErrNoRows)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	// Drivers wrap sql.ErrNoRows in errors of their own, like pgx.ErrNoRows.
+	err = octobe.WrapQueryError("pgx", "SELECT 1", nil, fmt.Errorf("scan: %w", sql.ErrNoRows))
+	require.ErrorIs(t, err, octobe.ErrNoRows)
+
+	err = octobe.WrapQueryError("pgx", "SELECT 1", nil, errors.New("connection refused"))
+	require.NotErrorIs(t, err, octobe.ErrNoRows)
+}
@@ -0,0 +1,47 @@
+package octobe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultReadyBackoff is the backoff between the pings of WaitForReady when none is given.
+var DefaultReadyBackoff = ExponentialBackoff(100*time.Millisecond, 5*time.Second)
+
+// WaitForReady pings the database until a ping succeeds, waiting for backoff between the attempts, so a service started
+// next to its database in a container does not fail while the database is still starting up. A nil backoff uses
+// DefaultReadyBackoff. When ctx is done first, the returned error joins the error of the last ping and the error of ctx.
+func (ob *Octobe[DRIVER, CONFIG, BUILDER]) WaitForReady(ctx context.Context, backoff Backoff) error {
+	return waitForReady(ctx, ob, backoff, ob.cfg.clock)
+}
+
+// WaitForReady pings checker until a ping succeeds like the method of Octobe. It accepts any HealthChecker, like a
+// health.Checker running in the background, which makes it wait until the checker reports the database healthy.
+func WaitForReady(ctx context.Context, checker HealthChecker, backoff Backoff) error {
+	return waitForReady(ctx, checker, backoff, SystemClock)
+}
+
+// waitForReady pings checker until a ping succeeds, measuring the backoff with clock.
+func waitForReady(ctx context.Context, checker HealthChecker, backoff Backoff, clock Clock) error {
+	if backoff == nil {
+		backoff = DefaultReadyBackoff
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := checker.Ping(ctx)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return fmt.Errorf("database not ready: %w", errors.Join(err, ctx.Err()))
+		}
+
+		select {
+		case <-clock.After(backoff(attempt)):
+		case <-ctx.Done():
+			return fmt.Errorf("database not ready: %w", errors.Join(err, ctx.Err()))
+		}
+	}
+}
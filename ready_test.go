@@ -0,0 +1,49 @@
+package octobe_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ponrove/octobe"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyChecker fails its first pings.
+type flakyChecker struct {
+	failures int
+	pings    int
+}
+
+func (c *flakyChecker) Ping(context.Context) error {
+	c.pings++
+	if c.pings <= c.failures {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func TestWaitForReady(t *testing.T) {
+	checker := &flakyChecker{failures: 3}
+	require.NoError(t, octobe.WaitForReady(context.Background(), checker, octobe.ConstantBackoff(time.Millisecond)))
+	require.Equal(t, 4, checker.pings)
+
+	d := &fakeDriver{}
+	ob, err := octobe.New(d.open())
+	require.NoError(t, err)
+	require.NoError(t, ob.WaitForReady(context.Background(), nil))
+}
+
+func TestWaitForReadyTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	d := &fakeDriver{pingErr: errors.New("connection refused")}
+	ob, err := octobe.New(d.open())
+	require.NoError(t, err)
+
+	err = ob.WaitForReady(ctx, octobe.ConstantBackoff(time.Millisecond))
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.ErrorContains(t, err, "connection refused")
+}
@@ -0,0 +1,76 @@
+package octobe
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// WithQueryRecording makes every session of the instance record the statements it executes, with their arguments,
+// duration, number of rows and error, to be retrieved with ExecutedQueries. It is meant for asserting on the queries of
+// a code path in integration tests and for attaching them to error reports. A session keeps at most the last limit
+// statements, a limit of zero or less keeps all of them.
+func WithQueryRecording(limit int) InstanceOption {
+	return func(cfg *instanceConfig) {
+		cfg.record = true
+		cfg.recordLimit = limit
+	}
+}
+
+// QueryRecorder is implemented by the sessions of an instance created with WithQueryRecording.
+type QueryRecorder interface {
+	// ExecutedQueries returns the statements executed in the session so far, in the order they finished.
+	ExecutedQueries() []QueryEvent
+}
+
+// ExecutedQueries returns the statements executed in session so far, in the order they finished. It returns nil if the
+// instance of the session was not created with WithQueryRecording.
+func ExecutedQueries[BUILDER any](session BuilderSession[BUILDER]) []QueryEvent {
+	if r, ok := session.(QueryRecorder); ok {
+		return r.ExecutedQueries()
+	}
+	return nil
+}
+
+// recorder is a query hook that records the queries of a single session.
+type recorder struct {
+	mu     sync.Mutex
+	limit  int
+	events []QueryEvent
+}
+
+// Ensure recorder is invoked around queries.
+var _ QueryHook = &recorder{}
+
+// BeforeQuery returns ctx as is, queries are recorded once they finish.
+func (r *recorder) BeforeQuery(ctx context.Context, _ *QueryEvent) context.Context {
+	return ctx
+}
+
+// AfterQuery records a copy of the finished query, dropping the oldest query when the limit is reached.
+func (r *recorder) AfterQuery(_ context.Context, event *QueryEvent) {
+	recorded := *event
+	recorded.Args = slices.Clone(event.Args)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.limit > 0 && len(r.events) >= r.limit {
+		r.events = slices.Delete(r.events, 0, len(r.events)-r.limit+1)
+	}
+	r.events = append(r.events, recorded)
+}
+
+// executed returns a copy of the recorded queries.
+func (r *recorder) executed() []QueryEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.events)
+}
+
+// ExecutedQueries returns the statements executed in the session so far, or nil if the session does not record them.
+func (s *session[DRIVER, CONFIG, BUILDER]) ExecutedQueries() []QueryEvent {
+	if s.recorder == nil {
+		return nil
+	}
+	return s.recorder.executed()
+}
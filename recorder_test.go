@@ -0,0 +1,56 @@
+package octobe_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ponrove/octobe"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithQueryRecording(t *testing.T) {
+	d := &fakeDriver{}
+	ob, err := octobe.New(d.open(), octobe.WithQueryRecording(2))
+	require.NoError(t, err)
+
+	session, err := ob.Begin(context.Background())
+	require.NoError(t, err)
+	other, err := ob.Begin(context.Background())
+	require.NoError(t, err)
+
+	failed := errors.New("failed")
+	args := []any{1}
+	for i, query := range []string{"SELECT 1", "SELECT 2", "SELECT 3"} {
+		_, done := octobe.BeginQuery(d.sessions[0].ctx, octobe.OperationQuery, query, args)
+		if i == 2 {
+			done(-1, failed)
+		} else {
+			done(1, nil)
+		}
+	}
+	args[0] = 2
+
+	executed := octobe.ExecutedQueries[string](session)
+	require.Len(t, executed, 2, "only the last queries within the limit are kept")
+	require.Equal(t, "SELECT 2", executed[0].Query)
+	require.Equal(t, int64(1), executed[0].Rows)
+	require.Equal(t, []any{1}, executed[0].Args, "arguments are copied when recorded")
+	require.Equal(t, "SELECT 3", executed[1].Query)
+	require.ErrorIs(t, executed[1].Err, failed)
+
+	require.Empty(t, octobe.ExecutedQueries[string](other), "sessions record their own queries")
+}
+
+func TestExecutedQueriesWithoutRecording(t *testing.T) {
+	d := &fakeDriver{}
+	ob, err := octobe.New(d.open())
+	require.NoError(t, err)
+
+	session, err := ob.Begin(context.Background())
+	require.NoError(t, err)
+
+	_, done := octobe.BeginQuery(d.sessions[0].ctx, octobe.OperationExec, "SELECT 1", nil)
+	done(1, nil)
+	require.Nil(t, octobe.ExecutedQueries[string](session))
+}
@@ -0,0 +1,195 @@
+package octobe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var (
+	// ErrNotRegistered is returned when looking up a name that has no instance in the registry.
+	ErrNotRegistered = errors.New("no instance registered under this name")
+	// ErrAlreadyRegistered is returned when registering an instance under a name that is already taken.
+	ErrAlreadyRegistered = errors.New("an instance is already registered under this name")
+)
+
+// Instance is implemented by every Octobe instance regardless of its driver, it allows a Registry to manage instances
+// of different drivers together.
+type Instance interface {
+	HealthChecker
+	Close(ctx context.Context) error
+	Stats() Stats
+}
+
+// Ensure every Octobe instance can be registered.
+var _ Instance = &Octobe[any, any, any]{}
+
+// Opener opens an instance and registers it in a registry, see Opening.
+type Opener func(r *Registry) error
+
+// Registry manages named Octobe instances of a service that talks to several databases, e.g. "orders-pg" and
+// "analytics-ch". Instances are opened into the registry with OpenNamed or OpenAll, looked up by name with Lookup,
+// checked together with Ping and closed together with Close. A Registry is safe for concurrent use.
+type Registry struct {
+	mu        sync.RWMutex
+	names     []string
+	instances map[string]Instance
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{instances: make(map[string]Instance)}
+}
+
+// Register adds an instance under name. It fails with ErrAlreadyRegistered when the name is taken.
+func (r *Registry) Register(name string, instance Instance) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.instances[name]; ok {
+		return fmt.Errorf("%w: %q", ErrAlreadyRegistered, name)
+	}
+	r.names = append(r.names, name)
+	r.instances[name] = instance
+	return nil
+}
+
+// Get returns the instance registered under name, regardless of its driver.
+func (r *Registry) Get(name string) (Instance, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	instance, ok := r.instances[name]
+	return instance, ok
+}
+
+// Names returns the names of all registered instances in the order they were registered.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]string(nil), r.names...)
+}
+
+// OpenNamed creates a new Octobe instance like New and registers it under name. The instance is closed again when the
+// name is already taken.
+func OpenNamed[DRIVER any, CONFIG any, BUILDER any](r *Registry, name string, init Open[DRIVER, CONFIG, BUILDER], opts ...InstanceOption) (*Octobe[DRIVER, CONFIG, BUILDER], error) {
+	ob, err := New(init, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("open %q: %w", name, err)
+	}
+	if err = r.Register(name, ob); err != nil {
+		return nil, errors.Join(err, ob.Close(context.Background()))
+	}
+	return ob, nil
+}
+
+// Opening returns an Opener for OpenAll that opens an instance with OpenNamed.
+func Opening[DRIVER any, CONFIG any, BUILDER any](name string, init Open[DRIVER, CONFIG, BUILDER], opts ...InstanceOption) Opener {
+	return func(r *Registry) error {
+		_, err := OpenNamed(r, name, init, opts...)
+		return err
+	}
+}
+
+// OpenAll runs all openers in order. When one of them fails, the instances opened before it are closed and removed
+// from the registry again, so a service either starts with all its databases or with none of them.
+func (r *Registry) OpenAll(ctx context.Context, openers ...Opener) error {
+	opened := len(r.Names())
+	for _, open := range openers {
+		if err := open(r); err != nil {
+			return errors.Join(err, r.closeFrom(ctx, opened))
+		}
+	}
+	return nil
+}
+
+// Lookup returns the instance registered under name with its concrete type. It fails with ErrNotRegistered when there
+// is no such instance, and when the instance was opened with a different driver.
+func Lookup[DRIVER any, CONFIG any, BUILDER any](r *Registry, name string) (*Octobe[DRIVER, CONFIG, BUILDER], error) {
+	instance, ok := r.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrNotRegistered, name)
+	}
+	ob, ok := instance.(*Octobe[DRIVER, CONFIG, BUILDER])
+	if !ok {
+		return nil, fmt.Errorf("instance %q of type %T is not a %T", name, instance, ob)
+	}
+	return ob, nil
+}
+
+// Ping checks the connections of all registered instances concurrently, the returned error joins the errors of all
+// instances that are down with their names. A Registry is a HealthChecker itself.
+func (r *Registry) Ping(ctx context.Context) error {
+	r.mu.RLock()
+	names := append([]string(nil), r.names...)
+	instances := make([]Instance, len(names))
+	for i, name := range names {
+		instances[i] = r.instances[name]
+	}
+	r.mu.RUnlock()
+
+	errs := make([]error, len(instances))
+	var wg sync.WaitGroup
+	for i, instance := range instances {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := instance.Ping(ctx); err != nil {
+				errs[i] = fmt.Errorf("%s: %w", names[i], err)
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// HealthCheckers returns every registered instance as a named HealthChecker, to be passed to HealthHandler.
+func (r *Registry) HealthCheckers() []HealthChecker {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	checkers := make([]HealthChecker, len(r.names))
+	for i, name := range r.names {
+		checkers[i] = NamedHealthChecker(name, r.instances[name])
+	}
+	return checkers
+}
+
+// Stats returns the statistics of all registered instances by name.
+func (r *Registry) Stats() map[string]Stats {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	stats := make(map[string]Stats, len(r.instances))
+	for name, instance := range r.instances {
+		stats[name] = instance.Stats()
+	}
+	return stats
+}
+
+// Close closes all registered instances in the reverse order of their registration and removes them from the
+// registry. All instances are closed even when some of them fail, the returned error joins their errors.
+func (r *Registry) Close(ctx context.Context) error {
+	return r.closeFrom(ctx, 0)
+}
+
+// closeFrom closes and removes the instances registered after the first n, in the reverse order of their registration.
+func (r *Registry) closeFrom(ctx context.Context, n int) error {
+	r.mu.Lock()
+	if n > len(r.names) {
+		n = len(r.names)
+	}
+	names := r.names[n:]
+	instances := make([]Instance, len(names))
+	for i, name := range names {
+		instances[i] = r.instances[name]
+		delete(r.instances, name)
+	}
+	r.names = r.names[:n:n]
+	r.mu.Unlock()
+
+	var errs []error
+	for i := len(instances) - 1; i >= 0; i-- {
+		if err := instances[i].Close(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("close %q: %w", names[i], err))
+		}
+	}
+	return errors.Join(errs...)
+}
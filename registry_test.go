@@ -0,0 +1,62 @@
+package octobe_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ponrove/octobe"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	ctx := context.Background()
+	orders, analytics := &fakeDriver{}, &fakeDriver{pingErr: errors.New("unreachable")}
+
+	r := octobe.NewRegistry()
+	require.NoError(t, r.OpenAll(ctx,
+		octobe.Opening("orders", orders.open()),
+		octobe.Opening("analytics", analytics.open()),
+	))
+	require.Equal(t, []string{"orders", "analytics"}, r.Names())
+
+	ob, err := octobe.Lookup[fakeDriver, fakeConfig, string](r, "orders")
+	require.NoError(t, err)
+	_, err = ob.Begin(ctx)
+	require.NoError(t, err)
+	require.Equal(t, map[string]int{"sessions": 1}, r.Stats()["orders"].Driver)
+
+	_, err = octobe.Lookup[fakeDriver, fakeConfig, string](r, "billing")
+	require.ErrorIs(t, err, octobe.ErrNotRegistered)
+	_, err = octobe.Lookup[fakeDriver, fakeConfig, int](r, "orders")
+	require.Error(t, err)
+
+	_, err = octobe.OpenNamed(r, "orders", (&fakeDriver{}).open())
+	require.ErrorIs(t, err, octobe.ErrAlreadyRegistered)
+
+	err = r.Ping(ctx)
+	require.ErrorContains(t, err, "analytics: unreachable")
+	require.Len(t, r.HealthCheckers(), 2)
+
+	analytics.closeErr = errors.New("close failed")
+	err = r.Close(ctx)
+	require.ErrorContains(t, err, `close "analytics": close failed`)
+	require.True(t, orders.closed)
+	require.Empty(t, r.Names())
+}
+
+func TestRegistryOpenAllFailure(t *testing.T) {
+	ctx := context.Background()
+	orders := &fakeDriver{}
+
+	r := octobe.NewRegistry()
+	err := r.OpenAll(ctx,
+		octobe.Opening("orders", orders.open()),
+		octobe.Opening("analytics", func() (octobe.Driver[fakeDriver, fakeConfig, string], error) {
+			return nil, errors.New("connection refused")
+		}),
+	)
+	require.ErrorContains(t, err, `open "analytics": connection refused`)
+	require.True(t, orders.closed)
+	require.Empty(t, r.Names())
+}
@@ -0,0 +1,235 @@
+package octobe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// ReplicaPolicy decides which replica serves a read-only session of a driver opened with OpenReplicated.
+type ReplicaPolicy int
+
+const (
+	// RoundRobin hands read-only sessions to the replicas in turn.
+	RoundRobin ReplicaPolicy = iota
+	// LeastLoaded hands read-only sessions to the replica reporting the lowest load through LoadReporter. Replicas that
+	// do not report their load count as idle, ties are broken in turn.
+	LeastLoaded
+)
+
+// LoadReporter is implemented by drivers that can report their current load, such as the number of connections of a
+// pool that are in use. It is used by the LeastLoaded policy of OpenReplicated.
+type LoadReporter interface {
+	Load() int
+}
+
+// ReadOnlyReporter is implemented by drivers that can tell from the options of a session whether it only reads, e.g.
+// because it runs in a read-only transaction. OpenReplicated routes such sessions to a replica.
+type ReadOnlyReporter[CONFIG any] interface {
+	ReadOnly(opts ...Option[CONFIG]) bool
+}
+
+// routeKey is the context key of the routing chosen by ContextWithReadReplica and ContextWithPrimary.
+type routeKey struct{}
+
+// route is the routing of sessions carried by a context.
+type route int
+
+const (
+	routeReplica route = iota + 1
+	routePrimary
+)
+
+// ContextWithReadReplica returns a context derived from ctx that marks the sessions begun with it as read-only, so a
+// driver opened with OpenReplicated serves them from a replica. Only sessions that do not write may be marked, there is
+// no check that their queries only read.
+func ContextWithReadReplica(ctx context.Context) context.Context {
+	return context.WithValue(ctx, routeKey{}, routeReplica)
+}
+
+// ContextWithPrimary returns a context derived from ctx that makes a driver opened with OpenReplicated serve the
+// sessions begun with it from the primary, even when they are read-only. It gives read-after-write consistency to reads
+// that must see a write that replicas may not have replayed yet.
+func ContextWithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, routeKey{}, routePrimary)
+}
+
+// ReplicatedStats are the statistics of a driver opened with OpenReplicated, holding the statistics of the primary and
+// of every replica as reported through StatsReporter.
+type ReplicatedStats struct {
+	Primary  any   `json:"primary,omitempty"`
+	Replicas []any `json:"replicas"`
+}
+
+// replicated is a driver that splits sessions between a primary and its replicas.
+type replicated[DRIVER any, CONFIG any, BUILDER any] struct {
+	primary  Driver[DRIVER, CONFIG, BUILDER]
+	replicas []Driver[DRIVER, CONFIG, BUILDER]
+	policy   ReplicaPolicy
+	next     atomic.Uint64
+}
+
+// Ensure the replicated driver forwards the optional interfaces of its drivers.
+var (
+	_ Describer             = &replicated[any, any, any]{}
+	_ CapabilityReporter    = &replicated[any, any, any]{}
+	_ RetryClassifier       = &replicated[any, any, any]{}
+	_ CommitRetryClassifier = &replicated[any, any, any]{}
+	_ StatsReporter         = &replicated[any, any, any]{}
+)
+
+// BeginsTransaction reports whether opts begin a transaction on the primary, assuming they do if it cannot tell. The
+// replicas are opened with drivers of the same type.
+func (d *replicated[DRIVER, CONFIG, BUILDER]) BeginsTransaction(opts ...Option[CONFIG]) bool {
+	reporter, ok := d.primary.(TransactionReporter[CONFIG])
+	return !ok || reporter.BeginsTransaction(opts...)
+}
+
+// OpenReplicated opens a driver that owns a primary and any number of replicas, all opened with drivers of the same
+// type. Sessions begun with a context from ContextWithReadReplica, and sessions the primary reports as read-only
+// through ReadOnlyReporter, are served by a replica picked by policy. All other sessions, in particular writable
+// transactions, and sessions begun with a context from ContextWithPrimary are served by the primary. Routing applies
+// to whole sessions, all segments of a session run on the same connection. When opening any of the drivers fails, the
+// drivers opened before are closed again.
+func OpenReplicated[DRIVER any, CONFIG any, BUILDER any](policy ReplicaPolicy, primary Open[DRIVER, CONFIG, BUILDER], replicas ...Open[DRIVER, CONFIG, BUILDER]) Open[DRIVER, CONFIG, BUILDER] {
+	return func() (Driver[DRIVER, CONFIG, BUILDER], error) {
+		d := &replicated[DRIVER, CONFIG, BUILDER]{policy: policy}
+
+		var err error
+		if d.primary, err = primary(); err != nil {
+			return nil, fmt.Errorf("open primary: %w", err)
+		}
+		for i, replica := range replicas {
+			driver, err := replica()
+			if err != nil {
+				err = fmt.Errorf("open replica %d: %w", i, err)
+				return nil, errors.Join(err, d.Close(context.Background()))
+			}
+			d.replicas = append(d.replicas, driver)
+		}
+		return d, nil
+	}
+}
+
+// Begin begins the session on the primary or on a replica, depending on its context and options.
+func (d *replicated[DRIVER, CONFIG, BUILDER]) Begin(ctx context.Context, opts ...Option[CONFIG]) (Session[BUILDER], error) {
+	return d.route(ctx, opts).Begin(ctx, opts...)
+}
+
+// route returns the driver that serves a session begun with ctx and opts.
+func (d *replicated[DRIVER, CONFIG, BUILDER]) route(ctx context.Context, opts []Option[CONFIG]) Driver[DRIVER, CONFIG, BUILDER] {
+	if len(d.replicas) == 0 {
+		return d.primary
+	}
+	switch r, _ := ctx.Value(routeKey{}).(route); r {
+	case routePrimary:
+		return d.primary
+	case routeReplica:
+		return d.replica()
+	}
+	if reporter, ok := d.primary.(ReadOnlyReporter[CONFIG]); ok && reporter.ReadOnly(opts...) {
+		return d.replica()
+	}
+	return d.primary
+}
+
+// replica picks a replica according to the policy.
+func (d *replicated[DRIVER, CONFIG, BUILDER]) replica() Driver[DRIVER, CONFIG, BUILDER] {
+	start := int(d.next.Add(1)-1) % len(d.replicas)
+	if d.policy != LeastLoaded {
+		return d.replicas[start]
+	}
+
+	best, lowest := start, -1
+	for i := range d.replicas {
+		index := (start + i) % len(d.replicas)
+		load := 0
+		if reporter, ok := d.replicas[index].(LoadReporter); ok {
+			load = reporter.Load()
+		}
+		if lowest < 0 || load < lowest {
+			best, lowest = index, load
+		}
+	}
+	return d.replicas[best]
+}
+
+// Describe describes the primary if it implements Describer.
+func (d *replicated[DRIVER, CONFIG, BUILDER]) Describe() DriverInfo {
+	if describer, ok := d.primary.(Describer); ok {
+		return describer.Describe()
+	}
+	return DriverInfo{}
+}
+
+// Capabilities reports the capabilities of the primary if it implements CapabilityReporter.
+func (d *replicated[DRIVER, CONFIG, BUILDER]) Capabilities() Capabilities {
+	if reporter, ok := d.primary.(CapabilityReporter); ok {
+		return reporter.Capabilities()
+	}
+	return Capabilities{}
+}
+
+// Retryable classifies errors like the primary if it implements RetryClassifier.
+func (d *replicated[DRIVER, CONFIG, BUILDER]) Retryable(err error) bool {
+	classifier, ok := d.primary.(RetryClassifier)
+	return ok && classifier.Retryable(err)
+}
+
+// RetryableCommit classifies failed commits like the primary if it implements CommitRetryClassifier.
+func (d *replicated[DRIVER, CONFIG, BUILDER]) RetryableCommit(err error) bool {
+	classifier, ok := d.primary.(CommitRetryClassifier)
+	return ok && classifier.RetryableCommit(err)
+}
+
+// ValidateQuery validates query against the primary if it implements QueryValidator.
+func (d *replicated[DRIVER, CONFIG, BUILDER]) ValidateQuery(ctx context.Context, query string) error {
+	validator, ok := d.primary.(QueryValidator)
+	if !ok {
+		return ErrValidationUnsupported
+	}
+	return validator.ValidateQuery(ctx, query)
+}
+
+// Stats returns the statistics of the primary and the replicas as ReplicatedStats.
+func (d *replicated[DRIVER, CONFIG, BUILDER]) Stats() any {
+	stats := ReplicatedStats{Replicas: make([]any, len(d.replicas))}
+	if reporter, ok := d.primary.(StatsReporter); ok {
+		stats.Primary = reporter.Stats()
+	}
+	for i, replica := range d.replicas {
+		if reporter, ok := replica.(StatsReporter); ok {
+			stats.Replicas[i] = reporter.Stats()
+		}
+	}
+	return stats
+}
+
+// Close closes the replicas and the primary, all of them are closed even when some fail.
+func (d *replicated[DRIVER, CONFIG, BUILDER]) Close(ctx context.Context) error {
+	var errs []error
+	for i, replica := range d.replicas {
+		if err := replica.Close(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("close replica %d: %w", i, err))
+		}
+	}
+	if err := d.primary.Close(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("close primary: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// Ping checks the connections to the primary and all replicas.
+func (d *replicated[DRIVER, CONFIG, BUILDER]) Ping(ctx context.Context) error {
+	var errs []error
+	if err := d.primary.Ping(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("primary: %w", err))
+	}
+	for i, replica := range d.replicas {
+		if err := replica.Ping(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("replica %d: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
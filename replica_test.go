@@ -0,0 +1,80 @@
+package octobe_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ponrove/octobe"
+	"github.com/stretchr/testify/require"
+)
+
+// loadedDriver is a fake driver reporting a fixed load.
+type loadedDriver struct {
+	*fakeDriver
+	load int
+}
+
+func (d loadedDriver) Load() int { return d.load }
+
+func TestReplicatedRouting(t *testing.T) {
+	ctx := context.Background()
+	primary, first, second := &fakeDriver{}, &fakeDriver{}, &fakeDriver{}
+
+	ob, err := octobe.New(octobe.OpenReplicated(octobe.RoundRobin, primary.open(), first.open(), second.open()))
+	require.NoError(t, err)
+
+	_, err = ob.Begin(ctx, withTx())
+	require.NoError(t, err)
+	for range 3 {
+		_, err = ob.Begin(octobe.ContextWithReadReplica(ctx))
+		require.NoError(t, err)
+	}
+	_, err = ob.Begin(octobe.ContextWithPrimary(octobe.ContextWithReadReplica(ctx)))
+	require.NoError(t, err)
+
+	require.Len(t, primary.sessions, 2)
+	require.Len(t, first.sessions, 2)
+	require.Len(t, second.sessions, 1)
+	require.Equal(t, octobe.ReplicatedStats{
+		Primary:  map[string]int{"sessions": 2},
+		Replicas: []any{map[string]int{"sessions": 2}, map[string]int{"sessions": 1}},
+	}, ob.Stats().Driver)
+
+	second.pingErr = errors.New("unreachable")
+	require.ErrorContains(t, ob.Ping(ctx), "replica 1: unreachable")
+
+	require.NoError(t, ob.Close(ctx))
+	require.True(t, primary.closed)
+	require.True(t, first.closed)
+	require.True(t, second.closed)
+}
+
+func TestReplicatedLeastLoaded(t *testing.T) {
+	ctx := context.Background()
+	busy, idle := loadedDriver{fakeDriver: &fakeDriver{}, load: 5}, loadedDriver{fakeDriver: &fakeDriver{}, load: 1}
+	open := func(d loadedDriver) octobe.Open[fakeDriver, fakeConfig, string] {
+		return func() (octobe.Driver[fakeDriver, fakeConfig, string], error) {
+			return d, nil
+		}
+	}
+
+	ob, err := octobe.New(octobe.OpenReplicated(octobe.LeastLoaded, (&fakeDriver{}).open(), open(busy), open(idle)))
+	require.NoError(t, err)
+
+	for range 3 {
+		_, err = ob.Begin(octobe.ContextWithReadReplica(ctx))
+		require.NoError(t, err)
+	}
+	require.Empty(t, busy.sessions)
+	require.Len(t, idle.sessions, 3)
+}
+
+func TestReplicatedOpenError(t *testing.T) {
+	primary := &fakeDriver{}
+	_, err := octobe.New(octobe.OpenReplicated(octobe.RoundRobin, primary.open(), func() (octobe.Driver[fakeDriver, fakeConfig, string], error) {
+		return nil, errors.New("connection refused")
+	}))
+	require.ErrorContains(t, err, "open replica 0: connection refused")
+	require.True(t, primary.closed)
+}
@@ -0,0 +1,86 @@
+package octobe
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// RetryClassifier is implemented by drivers that can tell transient errors, like serialization failures and
+// deadlocks, apart from permanent ones. StartTransaction only retries errors the driver classifies as retryable.
+type RetryClassifier interface {
+	Retryable(err error) bool
+}
+
+// CommitRetryClassifier is implemented by drivers that can tell a commit that certainly did not apply the transaction,
+// like one rejected by the server or one that failed before it was sent, apart from a commit whose outcome is unknown.
+// StartTransaction only retries a failed commit the driver classifies as retryable through it, any other failed commit
+// might have been applied, and running the transaction again could apply it twice.
+type CommitRetryClassifier interface {
+	RetryableCommit(err error) bool
+}
+
+// Backoff returns how long to wait before the given retry attempt, the first retry is attempt 1.
+type Backoff func(attempt int) time.Duration
+
+// ConstantBackoff waits the same delay before every retry.
+func ConstantBackoff(delay time.Duration) Backoff {
+	return func(int) time.Duration {
+		return delay
+	}
+}
+
+// ExponentialBackoff doubles the delay with every retry, starting at base and never waiting longer than max.
+func ExponentialBackoff(base, max time.Duration) Backoff {
+	return func(attempt int) time.Duration {
+		delay := base
+		for i := 1; i < attempt && delay < max; i++ {
+			delay *= 2
+		}
+		return min(delay, max)
+	}
+}
+
+// WithTxRetry makes StartTransaction run the whole callback again in a new transaction, at most maxAttempts times in
+// total, when it fails with an error the driver classifies as retryable through RetryClassifier. A failed commit is only
+// retried when the driver classifies it through CommitRetryClassifier. The wait between attempts is given by backoff,
+// which may be nil to retry immediately. The callback must be safe to run more than once.
+func WithTxRetry(maxAttempts int, backoff Backoff) InstanceOption {
+	return func(cfg *instanceConfig) {
+		cfg.txAttempts = maxAttempts
+		cfg.txBackoff = backoff
+	}
+}
+
+// retryable reports whether err is classified as retryable by the driver, commit tells whether it was returned by a
+// failed commit.
+func (ob *Octobe[DRIVER, CONFIG, BUILDER]) retryable(err error, commit bool) bool {
+	if errors.Is(err, ErrFlush) {
+		// The transaction has been committed, running it again would apply it twice.
+		return false
+	}
+	if commit {
+		classifier, ok := ob.driver.(CommitRetryClassifier)
+		return ok && classifier.RetryableCommit(err)
+	}
+	classifier, ok := ob.driver.(RetryClassifier)
+	return ok && classifier.Retryable(err)
+}
+
+// wait blocks for the backoff of attempt, returning early with the error of ctx if it is done first.
+func (ob *Octobe[DRIVER, CONFIG, BUILDER]) wait(ctx context.Context, attempt int) error {
+	if ob.cfg.txBackoff == nil {
+		return ctx.Err()
+	}
+	delay := ob.cfg.txBackoff(attempt)
+	if delay <= 0 {
+		return ctx.Err()
+	}
+
+	select {
+	case <-ob.cfg.clock.After(delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
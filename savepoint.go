@@ -0,0 +1,66 @@
+package octobe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Savepoints is implemented by driver sessions that support savepoints within their transaction. It allows
+// StartTransaction to nest transactions.
+type Savepoints interface {
+	Savepoint(name string) error
+	RollbackToSavepoint(name string) error
+	ReleaseSavepoint(name string) error
+}
+
+// transactionKey is the context key of the session of TransactionContext.
+type transactionKey struct{}
+
+// TransactionContext returns a context derived from ctx that carries the transaction of session, which must have been
+// started by Begin or StartTransaction. StartTransaction called with that context on the same instance runs fn in a
+// savepoint of the transaction instead of starting an independent one, if the driver supports savepoints. An error
+// returned by fn rolls back to the savepoint and leaves the outer transaction usable, so handlers can be composed
+// without knowing whether they already run inside a transaction.
+func TransactionContext[BUILDER any](ctx context.Context, session BuilderSession[BUILDER]) context.Context {
+	return context.WithValue(ctx, transactionKey{}, session)
+}
+
+// transactionFrom returns the session of the instance carried by ctx, if it runs in a transaction with savepoints.
+func (o *Octobe[DRIVER, CONFIG, BUILDER]) transactionFrom(ctx context.Context) (*session[DRIVER, CONFIG, BUILDER], Savepoints) {
+	s, ok := ctx.Value(transactionKey{}).(*session[DRIVER, CONFIG, BUILDER])
+	if !ok || s.ob != o || !s.InTransaction() {
+		return nil, nil
+	}
+	savepoints, ok := s.Session.(Savepoints)
+	if !ok {
+		return nil, nil
+	}
+	return s, savepoints
+}
+
+// nestTransaction runs fn in a new savepoint of the transaction of s, releasing it if fn succeeds and rolling back to
+// it if fn fails or panics.
+func (o *Octobe[DRIVER, CONFIG, BUILDER]) nestTransaction(s *session[DRIVER, CONFIG, BUILDER], savepoints Savepoints, fn func(session BuilderSession[BUILDER]) error) (err error) {
+	name := fmt.Sprintf("octobe_savepoint_%d", s.savepoints.Add(1))
+	if err = savepoints.Savepoint(name); err != nil {
+		return err
+	}
+
+	mark := s.eventMark()
+	defer func() {
+		if p := recover(); p != nil {
+			s.dropEvents(mark)
+			_ = savepoints.RollbackToSavepoint(name)
+			panic(p)
+		} else if err != nil {
+			s.dropEvents(mark)
+			err = errors.Join(err, savepoints.RollbackToSavepoint(name))
+		}
+	}()
+
+	if err = fn(s); err != nil {
+		return err
+	}
+	return savepoints.ReleaseSavepoint(name)
+}
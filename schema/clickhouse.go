@@ -0,0 +1,46 @@
+package schema
+
+import (
+	"strings"
+
+	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/driver/clickhouse"
+)
+
+// clickhouseSource reads columns from system.columns.
+type clickhouseSource struct{}
+
+// Ensure clickhouseSource implements the Source interface.
+var _ Source[clickhouse.Builder] = clickhouseSource{}
+
+// ClickHouse returns a source for the clickhouse driver that reads columns from system.columns. Tables without a
+// database qualifier are looked up in the current database.
+func ClickHouse() Source[clickhouse.Builder] {
+	return clickhouseSource{}
+}
+
+// Columns returns the columns of table, or no columns if the table does not exist.
+func (clickhouseSource) Columns(session octobe.BuilderSession[clickhouse.Builder], table string) ([]string, error) {
+	database, tableName, ok := strings.Cut(table, ".")
+	if !ok {
+		database, tableName = "", table
+	}
+
+	var columns []string
+	query := session.Builder()(`
+		SELECT name
+		FROM system.columns
+		WHERE database = if(empty(?), currentDatabase(), ?) AND table = ?
+		ORDER BY position`)
+	err := query.Arguments(database, database, tableName).Query(func(rows clickhouse.Rows) error {
+		for rows.Next() {
+			var column string
+			if err := rows.Scan(&column); err != nil {
+				return err
+			}
+			columns = append(columns, column)
+		}
+		return rows.Err()
+	})
+	return columns, err
+}
@@ -0,0 +1,46 @@
+package schema
+
+import (
+	"strings"
+
+	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/driver/postgres"
+)
+
+// postgresSource reads columns from information_schema.
+type postgresSource struct{}
+
+// Ensure postgresSource implements the Source interface.
+var _ Source[postgres.Builder] = postgresSource{}
+
+// Postgres returns a source for the postgres drivers that reads columns from information_schema.columns. Tables without
+// a schema qualifier are looked up in the current schema.
+func Postgres() Source[postgres.Builder] {
+	return postgresSource{}
+}
+
+// Columns returns the columns of table, or no columns if the table does not exist.
+func (postgresSource) Columns(session octobe.BuilderSession[postgres.Builder], table string) ([]string, error) {
+	schemaName, tableName, ok := strings.Cut(table, ".")
+	if !ok {
+		schemaName, tableName = "", table
+	}
+
+	var columns []string
+	query := session.Builder()(`
+		SELECT column_name
+		FROM information_schema.columns
+		WHERE table_schema = COALESCE(NULLIF($1, ''), current_schema()) AND table_name = $2
+		ORDER BY ordinal_position`)
+	err := query.Arguments(schemaName, tableName).Query(func(rows postgres.Rows) error {
+		for rows.Next() {
+			var column string
+			if err := rows.Scan(&column); err != nil {
+				return err
+			}
+			columns = append(columns, column)
+		}
+		return rows.Err()
+	})
+	return columns, err
+}
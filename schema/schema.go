@@ -0,0 +1,189 @@
+// Package schema verifies at startup that the tables and columns an application depends on exist in the database. The
+// requirements are declared up front, as plain table and column lists, derived from models with db struct tags or as
+// the named queries of octobe.RegisterQuery, and Check compares them against the catalog of the database, so that a
+// missing column fails the service at startup with a complete report instead of at the first query that touches it.
+package schema
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/internal/dbtag"
+)
+
+// Requirement declares a table, optionally qualified with its schema or database, and the columns it must have, or
+// named queries that must be valid against the database.
+type Requirement struct {
+	Table   string
+	Columns []string
+	Queries []string
+
+	allQueries bool  // Set by Queries without names, every registered query is required to be valid
+	err        error // Set by Model for a model that is not a struct, returned by Check
+}
+
+// Table declares a requirement for table with the given columns.
+func Table(table string, columns ...string) Requirement {
+	return Requirement{Table: table, Columns: columns}
+}
+
+// Model declares a requirement for table with the columns of model, which must be a struct, a pointer to a struct or a
+// slice of either. Fields are mapped to columns by their db tag, fields without a tag by their lowercased name, and
+// fields tagged with `db:"-"` are ignored. Check fails before reading the catalog if model is not a struct.
+func Model(table string, model any) Requirement {
+	t, ok := dbtag.StructType(model)
+	if !ok {
+		return Requirement{Table: table, err: fmt.Errorf("schema: model for table %s is not a struct: %T", table, model)}
+	}
+	return Requirement{Table: table, Columns: dbtag.Columns(t)}
+}
+
+// Queries declares that the queries registered under names with octobe.RegisterQuery or octobe.RegisterQueries are
+// valid, all registered queries if no names are given. Check validates them through the octobe.QueryValidator of the
+// driver, which prepares them and so catches references to missing tables and columns along with typos. Applications
+// using several databases should pass the names of the queries meant for the instance that is checked.
+func Queries(names ...string) Requirement {
+	return Requirement{Queries: names, allQueries: len(names) == 0}
+}
+
+// Source lists the columns of tables in a database, it holds the driver specific catalog queries.
+type Source[BUILDER any] interface {
+	// Columns returns the columns of table, or no columns if the table does not exist.
+	Columns(session octobe.BuilderSession[BUILDER], table string) ([]string, error)
+}
+
+// Report describes every requirement that is not met by the database, it is returned as error by Check.
+type Report struct {
+	// MissingTables lists the required tables that do not exist.
+	MissingTables []string
+	// MissingColumns lists the required columns that do not exist, keyed by table.
+	MissingColumns map[string][]string
+	// InvalidQueries holds the error every invalid named query failed to validate with, keyed by name.
+	InvalidQueries map[string]error
+}
+
+// Error returns a description of all missing tables and columns.
+func (r *Report) Error() string {
+	var b strings.Builder
+	b.WriteString("schema does not match requirements:")
+	for _, table := range r.MissingTables {
+		fmt.Fprintf(&b, "\n\ttable %s does not exist", table)
+	}
+
+	tables := make([]string, 0, len(r.MissingColumns))
+	for table := range r.MissingColumns {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	for _, table := range tables {
+		fmt.Fprintf(&b, "\n\ttable %s is missing columns: %s", table, strings.Join(r.MissingColumns[table], ", "))
+	}
+
+	names := make([]string, 0, len(r.InvalidQueries))
+	for name := range r.InvalidQueries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&b, "\n\t%v", r.InvalidQueries[name])
+	}
+	return b.String()
+}
+
+// Check verifies that every required table and column exists, using source to read the catalog of the database behind
+// ob, and validating the required named queries, which fails with octobe.ErrValidationUnsupported for a driver that
+// cannot validate queries. Requirements for the same table are merged. If any requirement is not met, the returned
+// error is a *Report describing all of them.
+func Check[DRIVER any, CONFIG any, BUILDER any](ctx context.Context, ob *octobe.Octobe[DRIVER, CONFIG, BUILDER], source Source[BUILDER], requirements ...Requirement) error {
+	var (
+		tables  []string
+		queries []string
+	)
+	required := make(map[string][]string)
+	for _, requirement := range requirements {
+		if requirement.err != nil {
+			return requirement.err
+		}
+		if requirement.allQueries {
+			queries = append(queries, octobe.QueryNames()...)
+		}
+		queries = append(queries, requirement.Queries...)
+		if requirement.Table == "" {
+			continue
+		}
+		if _, ok := required[requirement.Table]; !ok {
+			tables = append(tables, requirement.Table)
+		}
+		required[requirement.Table] = append(required[requirement.Table], requirement.Columns...)
+	}
+
+	report := &Report{MissingColumns: make(map[string][]string), InvalidQueries: make(map[string]error)}
+	if err := checkTables(ctx, ob, source, tables, required, report); err != nil {
+		return err
+	}
+
+	for _, name := range queries {
+		if _, ok := report.InvalidQueries[name]; ok {
+			continue
+		}
+		err := ob.ValidateQueries(ctx, name)
+		if errors.Is(err, octobe.ErrValidationUnsupported) {
+			return err
+		}
+		if err != nil {
+			report.InvalidQueries[name] = err
+		}
+	}
+
+	if len(report.MissingTables) > 0 || len(report.MissingColumns) > 0 || len(report.InvalidQueries) > 0 {
+		return report
+	}
+	return nil
+}
+
+// checkTables adds the required tables that do not exist and the required columns they miss to report.
+func checkTables[DRIVER any, CONFIG any, BUILDER any](ctx context.Context, ob *octobe.Octobe[DRIVER, CONFIG, BUILDER], source Source[BUILDER], tables []string, required map[string][]string, report *Report) error {
+	if len(tables) == 0 {
+		return nil
+	}
+	session, err := ob.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	// The catalog is only read, a session that default options begin in a transaction is rolled back, and rolling back
+	// one without a transaction fails harmlessly.
+	defer func() { _ = session.Rollback() }()
+
+	for _, table := range tables {
+		columns, err := source.Columns(session, table)
+		if err != nil {
+			return fmt.Errorf("failed to read columns of table %s: %w", table, err)
+		}
+
+		if len(columns) == 0 {
+			report.MissingTables = append(report.MissingTables, table)
+			continue
+		}
+
+		existing := make(map[string]struct{}, len(columns))
+		for _, column := range columns {
+			existing[column] = struct{}{}
+		}
+
+		seen := make(map[string]struct{})
+		for _, column := range required[table] {
+			if _, ok := seen[column]; ok {
+				continue
+			}
+			seen[column] = struct{}{}
+			if _, ok := existing[column]; !ok {
+				report.MissingColumns[table] = append(report.MissingColumns[table], column)
+			}
+		}
+	}
+	return nil
+}
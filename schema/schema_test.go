@@ -0,0 +1,156 @@
+package schema_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/driver/clickhouse"
+	chmock "github.com/ponrove/octobe/driver/clickhouse/mock"
+	"github.com/ponrove/octobe/driver/postgres"
+	"github.com/ponrove/octobe/schema"
+	"github.com/stretchr/testify/require"
+)
+
+type Product struct {
+	ID    int    `db:"id"`
+	Name  string `db:"name"`
+	Price int    `db:"price"`
+	Notes string `db:"-"`
+}
+
+func TestCheckPostgres(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("requirements met", func(t *testing.T) {
+		mock, err := pgxmock.NewConn()
+		require.NoError(t, err)
+		defer mock.Close(ctx)
+
+		mock.ExpectQuery("FROM information_schema.columns").WithArgs("", "products").
+			WillReturnRows(pgxmock.NewRows([]string{"column_name"}).AddRow("id").AddRow("name").AddRow("price"))
+		mock.ExpectQuery("FROM information_schema.columns").WithArgs("audit", "events").
+			WillReturnRows(pgxmock.NewRows([]string{"column_name"}).AddRow("id"))
+
+		ob, err := octobe.New(postgres.OpenPGXWithConn(mock))
+		require.NoError(t, err)
+
+		err = schema.Check(ctx, ob, schema.Postgres(),
+			schema.Model("products", Product{}),
+			schema.Table("products", "id"),
+			schema.Table("audit.events", "id"),
+		)
+		require.NoError(t, err)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("requirements not met", func(t *testing.T) {
+		mock, err := pgxmock.NewConn()
+		require.NoError(t, err)
+		defer mock.Close(ctx)
+
+		mock.ExpectQuery("FROM information_schema.columns").WithArgs("", "products").
+			WillReturnRows(pgxmock.NewRows([]string{"column_name"}).AddRow("id"))
+		mock.ExpectQuery("FROM information_schema.columns").WithArgs("", "orders").
+			WillReturnRows(pgxmock.NewRows([]string{"column_name"}))
+
+		ob, err := octobe.New(postgres.OpenPGXWithConn(mock))
+		require.NoError(t, err)
+
+		err = schema.Check(ctx, ob, schema.Postgres(),
+			schema.Model("products", &[]Product{}),
+			schema.Table("orders", "id"),
+		)
+
+		var report *schema.Report
+		require.ErrorAs(t, err, &report)
+		require.Equal(t, []string{"orders"}, report.MissingTables)
+		require.Equal(t, map[string][]string{"products": {"name", "price"}}, report.MissingColumns)
+		require.Equal(t, "schema does not match requirements:\n\ttable orders does not exist\n\ttable products is missing columns: name, price", err.Error())
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("catalog error", func(t *testing.T) {
+		mock, err := pgxmock.NewConn()
+		require.NoError(t, err)
+		defer mock.Close(ctx)
+
+		expectedErr := errors.New("permission denied")
+		mock.ExpectQuery("FROM information_schema.columns").WithArgs("", "products").WillReturnError(expectedErr)
+
+		ob, err := octobe.New(postgres.OpenPGXWithConn(mock))
+		require.NoError(t, err)
+
+		err = schema.Check(ctx, ob, schema.Postgres(), schema.Table("products", "id"))
+		require.ErrorIs(t, err, expectedErr)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestCheckClickHouse(t *testing.T) {
+	ctx := context.Background()
+
+	mock := chmock.NewMock()
+	mock.ExpectQuery("FROM system.columns").WithArgs("analytics", "analytics", "events").
+		WillReturnRows(chmock.NewMockRows([]string{"name"}).AddRow("id").AddRow("timestamp"))
+
+	ob, err := octobe.New(clickhouse.OpenNativeWithConn(mock))
+	require.NoError(t, err)
+
+	err = schema.Check(ctx, ob, schema.ClickHouse(), schema.Table("analytics.events", "id", "timestamp", "payload"))
+	var report *schema.Report
+	require.ErrorAs(t, err, &report)
+	require.Equal(t, map[string][]string{"analytics.events": {"payload"}}, report.MissingColumns)
+	require.NoError(t, mock.AllExpectationsMet())
+}
+
+func TestCheckQueries(t *testing.T) {
+	ctx := context.Background()
+	octobe.RegisterQuery("schema_test_product", "SELECT id, name FROM products WHERE id = $1")
+	octobe.RegisterQuery("schema_test_typo", "SELECT id, nmae FROM products")
+
+	mock, err := pgxmock.NewConn()
+	require.NoError(t, err)
+	defer mock.Close(ctx)
+
+	mock.ExpectQuery("FROM information_schema.columns").WithArgs("", "orders").
+		WillReturnRows(pgxmock.NewRows([]string{"column_name"}).AddRow("id"))
+	mock.ExpectPrepare("", "SELECT id, name FROM products WHERE id = \\$1")
+	mock.ExpectPrepare("", "SELECT id, nmae FROM products").
+		WillReturnError(errors.New(`column "nmae" does not exist`))
+
+	ob, err := octobe.New(postgres.OpenPGXWithConn(mock))
+	require.NoError(t, err)
+
+	err = schema.Check(ctx, ob, schema.Postgres(),
+		schema.Table("orders", "id"),
+		schema.Queries("schema_test_product", "schema_test_typo"),
+	)
+	var report *schema.Report
+	require.ErrorAs(t, err, &report)
+	require.Empty(t, report.MissingTables)
+	require.Empty(t, report.MissingColumns)
+	require.Len(t, report.InvalidQueries, 1)
+	require.ErrorContains(t, report.InvalidQueries["schema_test_typo"], `column "nmae" does not exist`)
+	require.Equal(t, "schema does not match requirements:\n\tquery \"schema_test_typo\": column \"nmae\" does not exist", err.Error())
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	// ClickHouse cannot prepare queries, so they cannot be checked.
+	ch, err := octobe.New(clickhouse.OpenNativeWithConn(chmock.NewMock()))
+	require.NoError(t, err)
+	err = schema.Check(ctx, ch, schema.ClickHouse(), schema.Queries("schema_test_product"))
+	require.ErrorIs(t, err, octobe.ErrValidationUnsupported)
+}
+
+func TestModelOfNonStruct(t *testing.T) {
+	mock, err := pgxmock.NewConn()
+	require.NoError(t, err)
+	ob, err := octobe.New(postgres.OpenPGXWithConn(mock))
+	require.NoError(t, err)
+
+	err = schema.Check(context.Background(), ob, schema.Postgres(), schema.Model("products", 1))
+	require.ErrorContains(t, err, "model for table products is not a struct: int")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
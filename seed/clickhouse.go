@@ -0,0 +1,99 @@
+package seed
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/driver/clickhouse"
+)
+
+// clickhouseStore records applied seeds in a ClickHouse table.
+type clickhouseStore struct {
+	table string
+	lock  string
+}
+
+// Ensure clickhouseStore implements the Store interface.
+var _ Store[clickhouse.Builder] = &clickhouseStore{}
+
+// ClickHouse returns a store for the clickhouse driver that records applied seeds in table, which may be database
+// qualified. DefaultTable is used if table is empty. ClickHouse has neither transactions nor unique constraints: a seed
+// that fails halfway leaves the rows it inserted before failing, and the lock cannot be taken atomically, it only guards
+// against runners that do not start at the very same moment.
+func ClickHouse(table string) Store[clickhouse.Builder] {
+	if table == "" {
+		table = DefaultTable
+	}
+	parts := strings.Split(table, ".")
+	for i, part := range parts {
+		parts[i] = "`" + strings.ReplaceAll(part, "`", "\\`") + "`"
+	}
+	name := strings.Join(parts, ".")
+	return &clickhouseStore{table: name, lock: strings.TrimSuffix(name, "`") + "_lock`"}
+}
+
+// Init creates the bookkeeping and lock tables if they do not exist.
+func (s *clickhouseStore) Init(session octobe.BuilderSession[clickhouse.Builder]) error {
+	err := session.Builder()(fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			environment String,
+			name        String,
+			checksum    String,
+			applied_at  DateTime DEFAULT now()
+		) ENGINE = ReplacingMergeTree ORDER BY (environment, name)`, s.table)).Exec()
+	if err != nil {
+		return err
+	}
+	return session.Builder()(fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			id        UInt8,
+			locked_at DateTime DEFAULT now()
+		) ENGINE = MergeTree ORDER BY id`, s.lock)).Exec()
+}
+
+// Lock takes the seed lock by inserting a row into the empty lock table.
+func (s *clickhouseStore) Lock(session octobe.BuilderSession[clickhouse.Builder]) error {
+	var locks uint64
+	err := session.Builder()(fmt.Sprintf(`SELECT count() FROM %s`, s.lock)).QueryRow(&locks)
+	if err != nil {
+		return err
+	}
+	if locks > 0 {
+		return ErrLocked
+	}
+	return session.Builder()(fmt.Sprintf(`INSERT INTO %s (id) VALUES (1)`, s.lock)).Exec()
+}
+
+// Unlock releases the seed lock.
+func (s *clickhouseStore) Unlock(session octobe.BuilderSession[clickhouse.Builder]) error {
+	return session.Builder()(fmt.Sprintf(`TRUNCATE TABLE %s`, s.lock)).Exec()
+}
+
+// Applied returns the checksums of all seeds applied in the environment, keyed by seed name.
+func (s *clickhouseStore) Applied(session octobe.BuilderSession[clickhouse.Builder], environment string) (map[string]string, error) {
+	applied := make(map[string]string)
+	query := session.Builder()(fmt.Sprintf(`SELECT name, checksum FROM %s FINAL WHERE environment = ?`, s.table))
+	err := query.Arguments(environment).Query(func(rows clickhouse.Rows) error {
+		for rows.Next() {
+			var name, checksum string
+			if err := rows.Scan(&name, &checksum); err != nil {
+				return err
+			}
+			applied[name] = checksum
+		}
+		return rows.Err()
+	})
+	return applied, err
+}
+
+// Record marks a seed as applied in the environment.
+func (s *clickhouseStore) Record(session octobe.BuilderSession[clickhouse.Builder], environment, name, checksum string) error {
+	query := session.Builder()(fmt.Sprintf(`INSERT INTO %s (environment, name, checksum) VALUES (?, ?, ?)`, s.table))
+	return query.Arguments(environment, name, checksum).Exec()
+}
+
+// ExecScript executes a script of one or more statements.
+func (s *clickhouseStore) ExecScript(session octobe.BuilderSession[clickhouse.Builder], script string) error {
+	return clickhouse.ExecScript(session, script)
+}
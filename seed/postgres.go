@@ -0,0 +1,101 @@
+package seed
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/driver/postgres"
+)
+
+// postgresStore records applied seeds in a postgres table.
+type postgresStore struct {
+	table string
+	lock  string
+}
+
+// Ensure postgresStore implements the Store interface.
+var _ Store[postgres.Builder] = &postgresStore{}
+
+// Postgres returns a store for the postgres drivers that records applied seeds in table, which may be schema
+// qualified. DefaultTable is used if table is empty.
+func Postgres(table string) Store[postgres.Builder] {
+	if table == "" {
+		table = DefaultTable
+	}
+	parts := strings.Split(table, ".")
+	lock := append(parts[:len(parts)-1:len(parts)-1], parts[len(parts)-1]+"_lock")
+	return &postgresStore{
+		table: pgx.Identifier(parts).Sanitize(),
+		lock:  pgx.Identifier(lock).Sanitize(),
+	}
+}
+
+// Init creates the bookkeeping and lock tables if they do not exist.
+func (s *postgresStore) Init(session octobe.BuilderSession[postgres.Builder]) error {
+	_, err := session.Builder()(fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			environment TEXT NOT NULL,
+			name        TEXT NOT NULL,
+			checksum    TEXT NOT NULL,
+			applied_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+			PRIMARY KEY (environment, name)
+		)`, s.table)).Exec()
+	if err != nil {
+		return err
+	}
+	_, err = session.Builder()(fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			id        INT NOT NULL PRIMARY KEY,
+			locked_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)`, s.lock)).Exec()
+	return err
+}
+
+// Lock takes the seed lock by inserting the single row of the lock table.
+func (s *postgresStore) Lock(session octobe.BuilderSession[postgres.Builder]) error {
+	result, err := session.Builder()(fmt.Sprintf(`INSERT INTO %s (id) VALUES (1) ON CONFLICT DO NOTHING`, s.lock)).Exec()
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected == 0 {
+		return ErrLocked
+	}
+	return nil
+}
+
+// Unlock releases the seed lock.
+func (s *postgresStore) Unlock(session octobe.BuilderSession[postgres.Builder]) error {
+	_, err := session.Builder()(fmt.Sprintf(`DELETE FROM %s WHERE id = 1`, s.lock)).Exec()
+	return err
+}
+
+// Applied returns the checksums of all seeds applied in the environment, keyed by seed name.
+func (s *postgresStore) Applied(session octobe.BuilderSession[postgres.Builder], environment string) (map[string]string, error) {
+	applied := make(map[string]string)
+	query := session.Builder()(fmt.Sprintf(`SELECT name, checksum FROM %s WHERE environment = $1`, s.table))
+	err := query.Arguments(environment).Query(func(rows postgres.Rows) error {
+		for rows.Next() {
+			var name, checksum string
+			if err := rows.Scan(&name, &checksum); err != nil {
+				return err
+			}
+			applied[name] = checksum
+		}
+		return rows.Err()
+	})
+	return applied, err
+}
+
+// Record marks a seed as applied in the environment.
+func (s *postgresStore) Record(session octobe.BuilderSession[postgres.Builder], environment, name, checksum string) error {
+	query := session.Builder()(fmt.Sprintf(`INSERT INTO %s (environment, name, checksum) VALUES ($1, $2, $3)`, s.table))
+	_, err := query.Arguments(environment, name, checksum).Exec()
+	return err
+}
+
+// ExecScript executes a script of one or more statements.
+func (s *postgresStore) ExecScript(session octobe.BuilderSession[postgres.Builder], script string) error {
+	return postgres.ExecScript(session, script)
+}
@@ -0,0 +1,250 @@
+// Package seed provides idempotent provisioning of seed data through an Octobe instance. Seeds are named units of SQL or
+// Go code that run at most once per environment, every applied seed is recorded together with a checksum of its content
+// in a bookkeeping table so that subsequent runs skip it, and changed content is reported instead of silently ignored.
+package seed
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/ponrove/octobe"
+)
+
+// DefaultEnvironment is the environment seeds are recorded under when no environment is configured.
+const DefaultEnvironment = "default"
+
+// DefaultTable is the name of the bookkeeping table used when a store is created without a table name. The lock table
+// is named after it with a _lock suffix.
+const DefaultTable = "octobe_seeds"
+
+var (
+	// ErrChecksumMismatch is returned when a seed has already been applied in the environment, but its content has
+	// changed since.
+	ErrChecksumMismatch = errors.New("seed has changed since it was applied")
+
+	// ErrDuplicateSeed is returned when registering a seed with a name that is already registered.
+	ErrDuplicateSeed = errors.New("seed is already registered")
+
+	// ErrLocked is returned when another runner holds the seed lock. A lock left behind by a crashed runner can be
+	// released with Runner.Unlock.
+	ErrLocked = errors.New("seeds are locked by another runner")
+
+	// ErrNoTransaction is returned by Run when the driver supports transactions, but the options do not begin one, so
+	// a failing seed could not be rolled back.
+	ErrNoTransaction = errors.New("seeds must run in a transaction, pass transaction options")
+)
+
+// Seed is a named unit of seed data. A seed either has a Script, which is executed statement by statement through the
+// store, or a Run function for seeds that need Go code. The Checksum identifies the content of the seed, it is derived
+// from the Script if left empty. Seeds with a Run function and no Checksum never report changes.
+type Seed[BUILDER any] struct {
+	Name     string
+	Script   string
+	Run      func(session octobe.BuilderSession[BUILDER]) error
+	Checksum string
+}
+
+// SQL creates a seed from a SQL script.
+func SQL[BUILDER any](name, script string) Seed[BUILDER] {
+	return Seed[BUILDER]{Name: name, Script: script}
+}
+
+// Func creates a seed from a function, version is used as checksum and should be changed whenever the seed data
+// produced by fn changes.
+func Func[BUILDER any](name, version string, fn func(session octobe.BuilderSession[BUILDER]) error) Seed[BUILDER] {
+	return Seed[BUILDER]{Name: name, Run: fn, Checksum: version}
+}
+
+// FromFS loads every .sql file in dir of fsys as a seed, named after the file without its extension and ordered by
+// file name. It makes fixture directories, typically embedded with go:embed, usable as seeds.
+func FromFS[BUILDER any](fsys fs.FS, dir string) ([]Seed[BUILDER], error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var seeds []Seed[BUILDER]
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		seeds = append(seeds, SQL[BUILDER](strings.TrimSuffix(entry.Name(), ".sql"), string(content)))
+	}
+
+	sort.Slice(seeds, func(i, j int) bool { return seeds[i].Name < seeds[j].Name })
+	return seeds, nil
+}
+
+// checksum returns the checksum of the seed, deriving it from the script if none is set.
+func (s Seed[BUILDER]) checksum() string {
+	if s.Checksum != "" || s.Script == "" {
+		return s.Checksum
+	}
+	sum := sha256.Sum256([]byte(s.Script))
+	return hex.EncodeToString(sum[:])
+}
+
+// Store holds the driver specific parts of seeding, the bookkeeping of applied seeds, the lock and execution of SQL
+// scripts.
+type Store[BUILDER any] interface {
+	// Init creates the bookkeeping and lock tables if they do not exist.
+	Init(session octobe.BuilderSession[BUILDER]) error
+	// Lock takes the seed lock, it returns ErrLocked if the lock is held.
+	Lock(session octobe.BuilderSession[BUILDER]) error
+	// Unlock releases the seed lock.
+	Unlock(session octobe.BuilderSession[BUILDER]) error
+	// Applied returns the checksums of all seeds applied in the environment, keyed by seed name.
+	Applied(session octobe.BuilderSession[BUILDER], environment string) (map[string]string, error)
+	// Record marks a seed as applied in the environment.
+	Record(session octobe.BuilderSession[BUILDER], environment, name, checksum string) error
+	// ExecScript executes a script of one or more statements.
+	ExecScript(session octobe.BuilderSession[BUILDER], script string) error
+}
+
+// Option is a signature for configuring a Runner.
+type Option func(cfg *config)
+
+// config holds the configuration of a Runner.
+type config struct {
+	environment string
+}
+
+// WithEnvironment sets the environment seeds are recorded under, a seed runs once per environment.
+func WithEnvironment(environment string) Option {
+	return func(cfg *config) {
+		cfg.environment = environment
+	}
+}
+
+// Runner applies registered seeds through an Octobe instance.
+type Runner[DRIVER any, CONFIG any, BUILDER any] struct {
+	ob    *octobe.Octobe[DRIVER, CONFIG, BUILDER]
+	store Store[BUILDER]
+	cfg   config
+	seeds []Seed[BUILDER]
+}
+
+// NewRunner creates a Runner that applies seeds through ob, using store for bookkeeping.
+func NewRunner[DRIVER any, CONFIG any, BUILDER any](ob *octobe.Octobe[DRIVER, CONFIG, BUILDER], store Store[BUILDER], opts ...Option) *Runner[DRIVER, CONFIG, BUILDER] {
+	cfg := config{environment: DefaultEnvironment}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return &Runner[DRIVER, CONFIG, BUILDER]{
+		ob:    ob,
+		store: store,
+		cfg:   cfg,
+	}
+}
+
+// Register adds seeds to the runner, they are applied in the order they are registered.
+func (r *Runner[DRIVER, CONFIG, BUILDER]) Register(seeds ...Seed[BUILDER]) error {
+	for _, s := range seeds {
+		if s.Name == "" {
+			return errors.New("seed name is empty")
+		}
+		if s.Script == "" && s.Run == nil {
+			return fmt.Errorf("seed %q has neither a script nor a run function", s.Name)
+		}
+		for _, registered := range r.seeds {
+			if registered.Name == s.Name {
+				return fmt.Errorf("%w: %q", ErrDuplicateSeed, s.Name)
+			}
+		}
+		r.seeds = append(r.seeds, s)
+	}
+	return nil
+}
+
+// Run applies all registered seeds that have not been applied in the environment yet and returns the names of the
+// seeds it applied. Every seed is applied in its own transaction together with its bookkeeping record, so a failing
+// seed leaves no trace and can be retried. Options are passed on to the driver when starting the transactions, for a
+// driver that supports transactions they must begin one, or ErrNoTransaction is returned. A lock table keeps concurrent
+// runners from applying seeds twice, Run returns ErrLocked while another runner holds it. Before anything is applied,
+// all registered seeds are compared with the recorded checksums, and ErrChecksumMismatch is returned if any applied seed
+// has changed.
+func (r *Runner[DRIVER, CONFIG, BUILDER]) Run(ctx context.Context, opts ...octobe.Option[CONFIG]) (names []string, err error) {
+	if r.ob.Capabilities().Transactions && !r.ob.BeginsTransaction(opts...) {
+		return nil, ErrNoTransaction
+	}
+
+	// The lock and the bookkeeping table are committed before the seeds run, so that other runners see the lock and the
+	// seeds do not wait for a session of their own while it is held.
+	var applied map[string]string
+	err = r.ob.StartTransaction(ctx, func(session octobe.BuilderSession[BUILDER]) error {
+		if err := r.store.Init(session); err != nil {
+			return fmt.Errorf("failed to initialize seed tables: %w", err)
+		}
+		if err := r.store.Lock(session); err != nil {
+			return fmt.Errorf("failed to take seed lock: %w", err)
+		}
+		var err error
+		if applied, err = r.store.Applied(session, r.cfg.environment); err != nil {
+			return fmt.Errorf("failed to read applied seeds: %w", err)
+		}
+		return nil
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		// The lock must be released even when ctx is done, or it blocks all future runs.
+		if unlockErr := r.unlock(context.WithoutCancel(ctx), opts); unlockErr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to release seed lock: %w", unlockErr))
+		}
+	}()
+
+	var pending []Seed[BUILDER]
+	for _, s := range r.seeds {
+		checksum, ok := applied[s.Name]
+		if !ok {
+			pending = append(pending, s)
+			continue
+		}
+		if s.checksum() != "" && checksum != s.checksum() {
+			return nil, fmt.Errorf("%w: %q in environment %q", ErrChecksumMismatch, s.Name, r.cfg.environment)
+		}
+	}
+
+	for _, s := range pending {
+		err = r.ob.StartTransaction(ctx, func(session octobe.BuilderSession[BUILDER]) error {
+			if s.Run != nil {
+				if err := s.Run(session); err != nil {
+					return err
+				}
+			} else if err := r.store.ExecScript(session, s.Script); err != nil {
+				return err
+			}
+			return r.store.Record(session, r.cfg.environment, s.Name, s.checksum())
+		}, opts...)
+		if err != nil {
+			return names, fmt.Errorf("failed to apply seed %q: %w", s.Name, err)
+		}
+		names = append(names, s.Name)
+	}
+
+	return names, nil
+}
+
+// Unlock releases the seed lock, e.g. when it was left behind by a runner that crashed while seeding. It must only be
+// used when no other runner is seeding. Options are passed on to the driver like with Run.
+func (r *Runner[DRIVER, CONFIG, BUILDER]) Unlock(ctx context.Context, opts ...octobe.Option[CONFIG]) error {
+	return r.unlock(ctx, opts)
+}
+
+// unlock releases the seed lock in a transaction of its own.
+func (r *Runner[DRIVER, CONFIG, BUILDER]) unlock(ctx context.Context, opts []octobe.Option[CONFIG]) error {
+	return r.ob.StartTransaction(ctx, r.store.Unlock, opts...)
+}
@@ -0,0 +1,254 @@
+package seed_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/driver/clickhouse"
+	chmock "github.com/ponrove/octobe/driver/clickhouse/mock"
+	"github.com/ponrove/octobe/driver/postgres"
+	"github.com/ponrove/octobe/seed"
+	"github.com/stretchr/testify/require"
+)
+
+func checksum(script string) string {
+	sum := sha256.Sum256([]byte(script))
+	return hex.EncodeToString(sum[:])
+}
+
+// expectLock expects the transaction creating the tables in table, taking the lock and reading the seeds applied in
+// environment, which returns applied.
+func expectLock(mock pgxmock.PgxConnIface, table, environment string, applied *pgxmock.Rows) {
+	mock.ExpectBeginTx(pgx.TxOptions{})
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE IF NOT EXISTS " + table + " (")).
+		WillReturnResult(pgxmock.NewResult("CREATE TABLE", 0))
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE IF NOT EXISTS " + strings.TrimSuffix(table, `"`) + `_lock"`)).
+		WillReturnResult(pgxmock.NewResult("CREATE TABLE", 0))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO " + strings.TrimSuffix(table, `"`) + `_lock" (id) VALUES (1) ON CONFLICT DO NOTHING`)).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT name, checksum FROM " + table)).WithArgs(environment).WillReturnRows(applied)
+	mock.ExpectCommit()
+}
+
+// expectUnlock expects the transaction releasing the lock of table.
+func expectUnlock(mock pgxmock.PgxConnIface, table string) {
+	mock.ExpectBeginTx(pgx.TxOptions{})
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM " + strings.TrimSuffix(table, `"`) + `_lock" WHERE id = 1`)).
+		WillReturnResult(pgxmock.NewResult("DELETE", 1))
+	mock.ExpectCommit()
+}
+
+func TestRunPostgres(t *testing.T) {
+	ctx := context.Background()
+	categories := "INSERT INTO categories (name) VALUES ('books'); INSERT INTO categories (name) VALUES ('games');"
+	products := "INSERT INTO products (name) VALUES ('octobe');"
+
+	t.Run("applies pending seeds", func(t *testing.T) {
+		mock, err := pgxmock.NewConn()
+		require.NoError(t, err)
+		defer mock.Close(ctx)
+
+		expectLock(mock, `"octobe_seeds"`, "test",
+			pgxmock.NewRows([]string{"name", "checksum"}).AddRow("categories", checksum(categories)))
+		mock.ExpectBeginTx(pgx.TxOptions{})
+		mock.ExpectExec("INSERT INTO products").WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectExec(`INSERT INTO "octobe_seeds"`).WithArgs("test", "products", checksum(products)).WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectCommit()
+		mock.ExpectBeginTx(pgx.TxOptions{})
+		mock.ExpectExec("UPDATE settings").WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mock.ExpectExec(`INSERT INTO "octobe_seeds"`).WithArgs("test", "settings", "v1").WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectCommit()
+		expectUnlock(mock, `"octobe_seeds"`)
+
+		ob, err := octobe.New(postgres.OpenPGXWithConn(mock))
+		require.NoError(t, err)
+
+		runner := seed.NewRunner(ob, seed.Postgres(""), seed.WithEnvironment("test"))
+		require.NoError(t, runner.Register(
+			seed.SQL[postgres.Builder]("categories", categories),
+			seed.SQL[postgres.Builder]("products", products),
+			seed.Func("settings", "v1", func(session octobe.BuilderSession[postgres.Builder]) error {
+				_, err := session.Builder()("UPDATE settings SET value = 'on'").Exec()
+				return err
+			}),
+		))
+
+		applied, err := runner.Run(ctx, postgres.WithPGXTxOptions(postgres.PGXTxOptions{}))
+		require.NoError(t, err)
+		require.Equal(t, []string{"products", "settings"}, applied)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("changed seed", func(t *testing.T) {
+		mock, err := pgxmock.NewConn()
+		require.NoError(t, err)
+		defer mock.Close(ctx)
+
+		expectLock(mock, `"octobe_seeds"`, seed.DefaultEnvironment,
+			pgxmock.NewRows([]string{"name", "checksum"}).AddRow("categories", "outdated"))
+		expectUnlock(mock, `"octobe_seeds"`)
+
+		ob, err := octobe.New(postgres.OpenPGXWithConn(mock))
+		require.NoError(t, err)
+
+		runner := seed.NewRunner(ob, seed.Postgres(""))
+		require.NoError(t, runner.Register(seed.SQL[postgres.Builder]("categories", categories)))
+
+		_, err = runner.Run(ctx, postgres.WithPGXTxOptions(postgres.PGXTxOptions{}))
+		require.ErrorIs(t, err, seed.ErrChecksumMismatch)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("failing seed is rolled back", func(t *testing.T) {
+		mock, err := pgxmock.NewConn()
+		require.NoError(t, err)
+		defer mock.Close(ctx)
+
+		expectedErr := errors.New("insert failed")
+		expectLock(mock, `"public"."seeds"`, seed.DefaultEnvironment, pgxmock.NewRows([]string{"name", "checksum"}))
+		mock.ExpectBeginTx(pgx.TxOptions{})
+		mock.ExpectExec("INSERT INTO products").WillReturnError(expectedErr)
+		mock.ExpectRollback()
+		expectUnlock(mock, `"public"."seeds"`)
+
+		ob, err := octobe.New(postgres.OpenPGXWithConn(mock))
+		require.NoError(t, err)
+
+		runner := seed.NewRunner(ob, seed.Postgres("public.seeds"))
+		require.NoError(t, runner.Register(seed.SQL[postgres.Builder]("products", products)))
+
+		applied, err := runner.Run(ctx, postgres.WithPGXTxOptions(postgres.PGXTxOptions{}))
+		require.ErrorIs(t, err, expectedErr)
+		require.Empty(t, applied)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("locked", func(t *testing.T) {
+		mock, err := pgxmock.NewConn()
+		require.NoError(t, err)
+		defer mock.Close(ctx)
+
+		mock.ExpectBeginTx(pgx.TxOptions{})
+		mock.ExpectExec(`CREATE TABLE IF NOT EXISTS "octobe_seeds"`).WillReturnResult(pgxmock.NewResult("CREATE TABLE", 0))
+		mock.ExpectExec(`CREATE TABLE IF NOT EXISTS "octobe_seeds_lock"`).WillReturnResult(pgxmock.NewResult("CREATE TABLE", 0))
+		mock.ExpectExec(`INSERT INTO "octobe_seeds_lock"`).WillReturnResult(pgxmock.NewResult("INSERT", 0))
+		mock.ExpectRollback()
+
+		ob, err := octobe.New(postgres.OpenPGXWithConn(mock))
+		require.NoError(t, err)
+
+		runner := seed.NewRunner(ob, seed.Postgres(""))
+		require.NoError(t, runner.Register(seed.SQL[postgres.Builder]("products", products)))
+
+		_, err = runner.Run(ctx, postgres.WithPGXTxOptions(postgres.PGXTxOptions{}))
+		require.ErrorIs(t, err, seed.ErrLocked)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("without transaction", func(t *testing.T) {
+		mock, err := pgxmock.NewConn()
+		require.NoError(t, err)
+		defer mock.Close(ctx)
+
+		ob, err := octobe.New(postgres.OpenPGXWithConn(mock))
+		require.NoError(t, err)
+
+		runner := seed.NewRunner(ob, seed.Postgres(""))
+		require.NoError(t, runner.Register(seed.SQL[postgres.Builder]("products", products)))
+
+		_, err = runner.Run(ctx)
+		require.ErrorIs(t, err, seed.ErrNoTransaction)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("sessions end with a session limit", func(t *testing.T) {
+		mock, err := pgxmock.NewConn()
+		require.NoError(t, err)
+		defer mock.Close(ctx)
+
+		expectLock(mock, `"octobe_seeds"`, seed.DefaultEnvironment, pgxmock.NewRows([]string{"name", "checksum"}))
+		mock.ExpectBeginTx(pgx.TxOptions{})
+		mock.ExpectExec("INSERT INTO products").WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectExec(`INSERT INTO "octobe_seeds"`).WithArgs(seed.DefaultEnvironment, "products", checksum(products)).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectCommit()
+		expectUnlock(mock, `"octobe_seeds"`)
+
+		// Default options begin every session in a transaction, a bookkeeping session left open would hold the only slot.
+		ob, err := octobe.New(postgres.OpenPGXWithConn(mock), octobe.WithMaxConcurrentSessions(1, time.Second),
+			octobe.WithDefaultOptions(postgres.WithPGXTxOptions(postgres.PGXTxOptions{})))
+		require.NoError(t, err)
+
+		runner := seed.NewRunner(ob, seed.Postgres(""))
+		require.NoError(t, runner.Register(seed.SQL[postgres.Builder]("products", products)))
+
+		applied, err := runner.Run(ctx)
+		require.NoError(t, err)
+		require.Equal(t, []string{"products"}, applied)
+		require.Zero(t, ob.Stats().ActiveSessions)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestRunClickHouse(t *testing.T) {
+	ctx := context.Background()
+	script := "INSERT INTO events VALUES (1)"
+
+	mock := chmock.NewMock()
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS `octobe_seeds`")
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS `octobe_seeds_lock`")
+	mock.ExpectQueryRow("SELECT count() FROM `octobe_seeds_lock`").WillReturnRow(chmock.NewMockRow(uint64(0)))
+	mock.ExpectExec("INSERT INTO `octobe_seeds_lock`")
+	mock.ExpectQuery("SELECT name, checksum FROM `octobe_seeds` FINAL").WithArgs(seed.DefaultEnvironment).
+		WillReturnRows(chmock.NewMockRows([]string{"name", "checksum"}))
+	mock.ExpectExec(script)
+	mock.ExpectExec("INSERT INTO `octobe_seeds`").WithArgs(seed.DefaultEnvironment, "events", checksum(script))
+	mock.ExpectExec("TRUNCATE TABLE `octobe_seeds_lock`")
+
+	ob, err := octobe.New(clickhouse.OpenNativeWithConn(mock))
+	require.NoError(t, err)
+
+	runner := seed.NewRunner(ob, seed.ClickHouse(""))
+	require.NoError(t, runner.Register(seed.SQL[clickhouse.Builder]("events", script)))
+
+	applied, err := runner.Run(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"events"}, applied)
+	require.NoError(t, mock.AllExpectationsMet())
+}
+
+func TestRegister(t *testing.T) {
+	runner := seed.NewRunner[any, any, postgres.Builder](nil, seed.Postgres(""))
+	require.NoError(t, runner.Register(seed.SQL[postgres.Builder]("a", "SELECT 1")))
+	require.ErrorIs(t, runner.Register(seed.SQL[postgres.Builder]("a", "SELECT 2")), seed.ErrDuplicateSeed)
+	require.Error(t, runner.Register(seed.Seed[postgres.Builder]{Name: "empty"}))
+	require.Error(t, runner.Register(seed.SQL[postgres.Builder]("", "SELECT 1")))
+}
+
+func TestFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"fixtures/02_products.sql":   {Data: []byte("INSERT INTO products VALUES (1);")},
+		"fixtures/01_categories.sql": {Data: []byte("INSERT INTO categories VALUES (1);")},
+		"fixtures/README.md":         {Data: []byte("not a seed")},
+	}
+
+	seeds, err := seed.FromFS[postgres.Builder](fsys, "fixtures")
+	require.NoError(t, err)
+	require.Len(t, seeds, 2)
+	require.Equal(t, "01_categories", seeds[0].Name)
+	require.Equal(t, "INSERT INTO categories VALUES (1);", seeds[0].Script)
+	require.Equal(t, "02_products", seeds[1].Name)
+
+	_, err = seed.FromFS[postgres.Builder](fsys, "missing")
+	require.Error(t, err)
+}
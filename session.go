@@ -0,0 +1,248 @@
+package octobe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrSessionAborted is returned when committing or rolling back a session that was rolled back by Shutdown.
+	ErrSessionAborted = errors.New("session was aborted by shutdown")
+	// ErrSessionCancelled is returned when committing or rolling back a session in a transaction that was rolled back
+	// because its context was done.
+	ErrSessionCancelled = errors.New("session was rolled back because its context is done")
+	// ErrTxDone is returned by drivers when committing or rolling back a session whose transaction has already been
+	// committed or rolled back.
+	ErrTxDone = errors.New("transaction has already been committed or rolled back")
+	// ErrAlreadyCommitted is returned by drivers when committing or rolling back a session whose transaction has already
+	// been committed, it matches ErrTxDone.
+	ErrAlreadyCommitted = fmt.Errorf("%w: it was committed", ErrTxDone)
+	// ErrSessionTimeout is the cause of the context of a session that exceeded the timeout of WithSessionTimeout.
+	ErrSessionTimeout = errors.New("session timeout exceeded")
+)
+
+// WithSessionTimeout gives every session begun by Begin or StartTransaction a deadline of timeout for the session as a
+// whole, unlike the timeouts of single statements. A transaction that is still open when the deadline passes is rolled
+// back, committing or rolling it back afterwards fails with ErrSessionCancelled and ErrSessionTimeout, so a forgotten
+// transaction cannot hold on to its connection and locks. Sessions without a transaction get no deadline, they have no
+// end that would release it and run on the context passed to Begin, see TransactionReporter. Every attempt of
+// StartTransaction with WithTxRetry gets a deadline of its own. The deadline is measured with the clock of WithClock.
+func WithSessionTimeout(timeout time.Duration) InstanceOption {
+	return func(cfg *instanceConfig) {
+		cfg.sessionTimeout = timeout
+	}
+}
+
+// Transactional is implemented by driver sessions that can report whether they run in a transaction. Only sessions in a
+// transaction are tracked as active by Octobe, sessions without a transaction have no Commit or Rollback that marks
+// their end.
+type Transactional interface {
+	InTransaction() bool
+}
+
+// TransactionReporter is implemented by drivers that can tell from the options of a session whether Begin starts a
+// transaction. Begin gives only sessions in a transaction a context of their own, for WithCloseGracePeriod and
+// WithSessionTimeout, since sessions without one have no end that would release it. Sessions of drivers that do not
+// implement it get their own context either way.
+type TransactionReporter[CONFIG any] interface {
+	BeginsTransaction(opts ...Option[CONFIG]) bool
+}
+
+// BeginsTransaction reports whether Begin with opts, following the options of WithDefaultOptions, starts a session in a
+// transaction, assuming it does if the driver does not implement TransactionReporter. It lets generic code like the
+// seed and migration runners make sure their changes are atomic.
+func (ob *Octobe[DRIVER, CONFIG, BUILDER]) BeginsTransaction(opts ...Option[CONFIG]) bool {
+	if len(ob.defaults) > 0 {
+		opts = append(ob.defaults[:len(ob.defaults):len(ob.defaults)], opts...)
+	}
+	return ob.beginsTransaction(opts)
+}
+
+// beginsTransaction reports whether a session begun with opts runs in a transaction, assuming it does if the driver
+// cannot tell.
+func (ob *Octobe[DRIVER, CONFIG, BUILDER]) beginsTransaction(opts []Option[CONFIG]) bool {
+	reporter, ok := ob.driver.(TransactionReporter[CONFIG])
+	return !ok || reporter.BeginsTransaction(opts...)
+}
+
+// session wraps the session of a driver to keep track of it while it is active.
+type session[DRIVER any, CONFIG any, BUILDER any] struct {
+	Session[BUILDER]
+	ob      *Octobe[DRIVER, CONFIG, BUILDER]
+	parent  context.Context
+	cancel  context.CancelFunc
+	release func()
+	stop    func() bool
+	unwatch func() bool
+	end     func(transaction, committed bool, err error)
+	mu      sync.Mutex
+	active  bool
+	aborted error
+
+	savepoints atomic.Uint64
+	recorder   *recorder
+	events     []any
+	guard      *concurrencyGuard
+	stats      *statsCounter
+}
+
+// Ensure session implements the Session interface and exposes its recorded queries and statistics.
+var (
+	_ Session[any]         = &session[any, any, any]{}
+	_ QueryRecorder        = &session[any, any, any]{}
+	_ SessionStatsReporter = &session[any, any, any]{}
+)
+
+// Commit commits the session and marks it as no longer active. The deferred events are flushed once the session ended,
+// so flushers can begin sessions of their own.
+func (s *session[DRIVER, CONFIG, BUILDER]) Commit() error {
+	leave, err := s.guard.enter()
+	if err != nil {
+		return err
+	}
+	defer leave()
+	events, err := s.commit()
+	if err != nil {
+		return err
+	}
+	return s.flush(events)
+}
+
+// commit commits the driver session and marks the session as no longer active, returning the events deferred in its
+// transaction.
+func (s *session[DRIVER, CONFIG, BUILDER]) commit() ([]any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.aborted != nil {
+		return nil, s.aborted
+	}
+	defer s.finish()
+	err := s.Session.Commit()
+	s.endSession(err == nil, err)
+	events := s.events
+	s.events = nil
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// Rollback rolls back the session and marks it as no longer active.
+func (s *session[DRIVER, CONFIG, BUILDER]) Rollback() error {
+	leave, err := s.guard.enter()
+	if err != nil {
+		return err
+	}
+	defer leave()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.aborted != nil {
+		return s.aborted
+	}
+	defer s.finish()
+	s.events = nil
+	err = s.Session.Rollback()
+	s.endSession(false, err)
+	return err
+}
+
+// InTransaction reports whether the driver session runs in a transaction.
+func (s *session[DRIVER, CONFIG, BUILDER]) InTransaction() bool {
+	return inTransaction(s.Session)
+}
+
+// Context returns the context of the session, see SessionContext.
+func (s *session[DRIVER, CONFIG, BUILDER]) Context() context.Context {
+	return s.parent
+}
+
+// SessionContext returns the context session was begun with, carrying the values added by the session hooks of its
+// instance. It returns context.Background for sessions that were not begun by an Octobe instance.
+func SessionContext[BUILDER any](session BuilderSession[BUILDER]) context.Context {
+	if c, ok := session.(interface{ Context() context.Context }); ok {
+		return c.Context()
+	}
+	return context.Background()
+}
+
+// Unwrap returns the session of the driver.
+func (s *session[DRIVER, CONFIG, BUILDER]) Unwrap() Session[BUILDER] {
+	return s.Session
+}
+
+// finish stops tracking the session and releases its context, the caller must hold s.mu.
+func (s *session[DRIVER, CONFIG, BUILDER]) finish() {
+	if !s.active {
+		return
+	}
+	s.active = false
+	if s.unwatch != nil {
+		s.unwatch()
+	}
+	s.ob.untrack(s)
+	s.releaseContext()
+	s.release()
+}
+
+// endSession invokes the session hooks for the end of the session once, the caller must hold s.mu.
+func (s *session[DRIVER, CONFIG, BUILDER]) endSession(committed bool, err error) {
+	if s.end == nil {
+		return
+	}
+	end := s.end
+	s.end = nil
+	end(true, committed, err)
+}
+
+// releaseContext cancels the context of the session if it has its own, and stops it from being cancelled on close.
+func (s *session[DRIVER, CONFIG, BUILDER]) releaseContext() {
+	if s.cancel == nil {
+		return
+	}
+	if s.stop != nil {
+		s.stop()
+	}
+	s.ob.unregisterCancel(s)
+	s.cancel()
+}
+
+// abort rolls back an active session on behalf of Shutdown or the watchdog of its context, committing or rolling it
+// back afterwards fails with reason. It reports false if the session finished in the meantime.
+func (s *session[DRIVER, CONFIG, BUILDER]) abort(reason error) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.active {
+		return false, nil
+	}
+	s.aborted = reason
+	s.events = nil
+	defer s.finish()
+	err := s.Session.Rollback()
+	s.endSession(false, errors.Join(reason, err))
+	return true, err
+}
+
+// watch rolls back the session as soon as ctx is done while it is still active, so a cancelled context cannot leave
+// its transaction open until the connection is closed.
+func (s *session[DRIVER, CONFIG, BUILDER]) watch(ctx context.Context) {
+	s.unwatch = context.AfterFunc(ctx, func() {
+		_, _ = s.abort(fmt.Errorf("%w: %w", ErrSessionCancelled, context.Cause(ctx)))
+	})
+}
+
+// inTransaction reports whether a driver session implements Transactional and runs in a transaction.
+func inTransaction(session any) bool {
+	t, ok := session.(Transactional)
+	return ok && t.InTransaction()
+}
+
+// withoutTransaction reports whether a session implements Transactional and does not run in a transaction, so it has no
+// transaction that could be rolled back.
+func withoutTransaction(session any) bool {
+	t, ok := session.(Transactional)
+	return ok && !t.InTransaction()
+}
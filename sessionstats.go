@@ -0,0 +1,120 @@
+package octobe
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// WithSessionStats makes every session of the instance count the statements it executes, to be retrieved with
+// SessionStatsOf while the session runs or after it ended, and adds the totals of all sessions to the Stats of the
+// instance. Middleware can use it to enforce a budget of queries per request or to detect N+1 query patterns.
+func WithSessionStats() InstanceOption {
+	return func(cfg *instanceConfig) {
+		cfg.stats = true
+	}
+}
+
+// SessionStats counts the statements executed by a session, or by all sessions of an instance.
+type SessionStats struct {
+	// Statements is the number of statements executed, of any operation.
+	Statements int64 `json:"statements"`
+	// Execs is the number of statements executed with OperationExec.
+	Execs int64 `json:"execs"`
+	// Queries is the number of statements executed with OperationQuery, OperationQueryRow or OperationSelect.
+	Queries int64 `json:"queries"`
+	// Rows is the number of rows affected or returned by the statements, as far as the driver reported them.
+	Rows int64 `json:"rows"`
+	// Errors is the number of statements that failed.
+	Errors int64 `json:"errors"`
+	// Duration is the total time spent executing the statements, including reading their rows.
+	Duration time.Duration `json:"duration"`
+}
+
+// SessionStatsReporter is implemented by the sessions of an instance created with WithSessionStats.
+type SessionStatsReporter interface {
+	// SessionStats returns the statements executed in the session so far.
+	SessionStats() SessionStats
+}
+
+// SessionStatsOf returns the statistics of the statements executed in session so far. It returns zero statistics if
+// the instance of the session was not created with WithSessionStats.
+func SessionStatsOf[BUILDER any](session BuilderSession[BUILDER]) SessionStats {
+	if r, ok := session.(SessionStatsReporter); ok {
+		return r.SessionStats()
+	}
+	return SessionStats{}
+}
+
+// add counts the finished statement of event.
+func (s *SessionStats) add(event *QueryEvent) {
+	s.Statements++
+	switch event.Operation {
+	case OperationExec:
+		s.Execs++
+	case OperationQuery, OperationQueryRow, OperationSelect:
+		s.Queries++
+	}
+	if event.Rows > 0 {
+		s.Rows += event.Rows
+	}
+	if event.Err != nil {
+		s.Errors++
+	}
+	s.Duration += event.Duration
+}
+
+// statsCounter counts the statements of a session, or of all sessions of an instance.
+type statsCounter struct {
+	mu    sync.Mutex
+	stats SessionStats
+	total *statsCounter
+}
+
+// Ensure statsCounter is invoked around queries.
+var _ QueryHook = &statsCounter{}
+
+// BeforeQuery returns ctx as is, statements are counted once they finish.
+func (c *statsCounter) BeforeQuery(ctx context.Context, _ *QueryEvent) context.Context {
+	return ctx
+}
+
+// AfterQuery counts the finished statement, and adds it to the total of the instance.
+func (c *statsCounter) AfterQuery(_ context.Context, event *QueryEvent) {
+	c.count(event)
+	if c.total != nil {
+		c.total.count(event)
+	}
+}
+
+// count adds the finished statement of event to the statistics.
+func (c *statsCounter) count(event *QueryEvent) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stats.add(event)
+}
+
+// snapshot returns a copy of the counted statistics, zero statistics for a nil counter.
+func (c *statsCounter) snapshot() SessionStats {
+	if c == nil {
+		return SessionStats{}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// statsFrom returns the statistics counter of the session of ctx, nil if the instance does not count statements.
+func statsFrom(ctx context.Context) *statsCounter {
+	state, _ := ctx.Value(hooksKey{}).(*hookState)
+	if state == nil {
+		return nil
+	}
+	return state.stats
+}
+
+// SessionStats returns the statements executed in the session so far, zero statistics if the session does not count
+// them.
+func (s *session[DRIVER, CONFIG, BUILDER]) SessionStats() SessionStats {
+	return s.stats.snapshot()
+}
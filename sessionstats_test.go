@@ -0,0 +1,56 @@
+package octobe_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ponrove/octobe"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithSessionStats(t *testing.T) {
+	d := &fakeDriver{}
+	ob, err := octobe.New(d.open(), octobe.WithSessionStats())
+	require.NoError(t, err)
+
+	first, err := ob.Begin(context.Background(), withTx())
+	require.NoError(t, err)
+	_, done := octobe.BeginQuery(d.sessions[0].ctx, octobe.OperationExec, "UPDATE products SET price = $1", nil)
+	done(3, nil)
+	_, done = octobe.BeginQuery(d.sessions[0].ctx, octobe.OperationQuery, "SELECT id FROM products", nil)
+	done(2, nil)
+	_, done = octobe.BeginQuery(d.sessions[0].ctx, octobe.OperationQueryRow, "SELECT 1", nil)
+	done(-1, errors.New("failed"))
+	require.NoError(t, first.Commit())
+
+	second, err := ob.Begin(context.Background(), withoutTx())
+	require.NoError(t, err)
+	_, done = octobe.BeginQuery(d.sessions[1].ctx, octobe.OperationBatch, "INSERT INTO products", nil)
+	done(10, nil)
+
+	stats := octobe.SessionStatsOf(first)
+	require.Positive(t, stats.Duration)
+	stats.Duration = 0
+	require.Equal(t, octobe.SessionStats{Statements: 3, Execs: 1, Queries: 2, Rows: 5, Errors: 1}, stats)
+
+	stats = octobe.SessionStatsOf(second)
+	stats.Duration = 0
+	require.Equal(t, octobe.SessionStats{Statements: 1, Rows: 10}, stats)
+
+	total := ob.Stats().Statements
+	require.NotNil(t, total)
+	total.Duration = 0
+	require.Equal(t, octobe.SessionStats{Statements: 4, Execs: 1, Queries: 2, Rows: 15, Errors: 1}, *total)
+}
+
+func TestWithoutSessionStats(t *testing.T) {
+	d := &fakeDriver{}
+	ob, err := octobe.New(d.open())
+	require.NoError(t, err)
+
+	session, err := ob.Begin(context.Background(), withTx())
+	require.NoError(t, err)
+	require.Equal(t, octobe.SessionStats{}, octobe.SessionStatsOf(session))
+	require.Nil(t, ob.Stats().Statements)
+}
@@ -0,0 +1,167 @@
+// Package shard routes sessions to one of several Octobe instances, the shards, that each hold a part of the data. A
+// Set maps the shard key of a session, such as a tenant or user ID, to the shard that holds its data, and FanOut runs a
+// handler on every shard for queries that span all of them.
+package shard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+
+	"github.com/ponrove/octobe"
+)
+
+// ErrNoShards is returned by New when it is given no shards.
+var ErrNoShards = errors.New("no shards given")
+
+// Func maps a shard key to the index of a shard, n is the number of shards. It must return the same index for the same
+// key every time, as long as the number of shards does not change.
+type Func[KEY any] func(key KEY, n int) int
+
+// Hash maps string keys to shards by their FNV-1a hash.
+func Hash[KEY ~string]() Func[KEY] {
+	return func(key KEY, n int) int {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(key))
+		return int(h.Sum32() % uint32(n))
+	}
+}
+
+// Modulo maps integer keys to shards by the remainder of their division by the number of shards, negative keys
+// included. The remainder is computed in 64 bits, so the number of shards may exceed the range of KEY.
+func Modulo[KEY ~int | ~int8 | ~int16 | ~int32 | ~int64 | ~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64]() Func[KEY] {
+	return func(key KEY, n int) int {
+		if key >= 0 {
+			return int(uint64(key) % uint64(n))
+		}
+		i := int(int64(key) % int64(n))
+		if i < 0 {
+			i += n
+		}
+		return i
+	}
+}
+
+// Set is a set of shards, all opened with drivers of the same type, and the function that maps shard keys to them.
+type Set[KEY any, DRIVER any, CONFIG any, BUILDER any] struct {
+	shards   []*octobe.Octobe[DRIVER, CONFIG, BUILDER]
+	shardFor Func[KEY]
+}
+
+// New creates a set of the given shards, keys are mapped to them by shardFor. The order of the shards is part of the
+// mapping, it must stay the same across restarts.
+func New[KEY any, DRIVER any, CONFIG any, BUILDER any](shardFor Func[KEY], shards ...*octobe.Octobe[DRIVER, CONFIG, BUILDER]) (*Set[KEY, DRIVER, CONFIG, BUILDER], error) {
+	if len(shards) == 0 {
+		return nil, ErrNoShards
+	}
+	return &Set[KEY, DRIVER, CONFIG, BUILDER]{shards: shards, shardFor: shardFor}, nil
+}
+
+// Len returns the number of shards.
+func (s *Set[KEY, DRIVER, CONFIG, BUILDER]) Len() int {
+	return len(s.shards)
+}
+
+// Shard returns the shard at index i.
+func (s *Set[KEY, DRIVER, CONFIG, BUILDER]) Shard(i int) *octobe.Octobe[DRIVER, CONFIG, BUILDER] {
+	return s.shards[i]
+}
+
+// For returns the shard that holds the data of key.
+func (s *Set[KEY, DRIVER, CONFIG, BUILDER]) For(key KEY) (*octobe.Octobe[DRIVER, CONFIG, BUILDER], error) {
+	i := s.shardFor(key, len(s.shards))
+	if i < 0 || i >= len(s.shards) {
+		return nil, fmt.Errorf("shard function returned index %d for %d shards", i, len(s.shards))
+	}
+	return s.shards[i], nil
+}
+
+// BeginFor begins a session on the shard that holds the data of key.
+func (s *Set[KEY, DRIVER, CONFIG, BUILDER]) BeginFor(ctx context.Context, key KEY, opts ...octobe.Option[CONFIG]) (octobe.Session[BUILDER], error) {
+	shard, err := s.For(key)
+	if err != nil {
+		return nil, err
+	}
+	return shard.Begin(ctx, opts...)
+}
+
+// StartTransactionFor runs fn in a transaction on the shard that holds the data of key, see
+// octobe.Octobe.StartTransaction.
+func (s *Set[KEY, DRIVER, CONFIG, BUILDER]) StartTransactionFor(ctx context.Context, key KEY, fn func(session octobe.BuilderSession[BUILDER]) error, opts ...octobe.Option[CONFIG]) error {
+	shard, err := s.For(key)
+	if err != nil {
+		return err
+	}
+	return shard.StartTransaction(ctx, fn, opts...)
+}
+
+// Ping checks the connections of all shards, the returned error joins the errors of all shards that are down.
+func (s *Set[KEY, DRIVER, CONFIG, BUILDER]) Ping(ctx context.Context) error {
+	var errs []error
+	for i, shard := range s.shards {
+		if err := shard.Ping(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("shard %d: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes all shards, all of them are closed even when some fail.
+func (s *Set[KEY, DRIVER, CONFIG, BUILDER]) Close(ctx context.Context) error {
+	var errs []error
+	for i, shard := range s.shards {
+		if err := shard.Close(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("shard %d: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// FanOut runs fn concurrently on every shard, each in its own session started like octobe.StartTransactionWithResult
+// with opts, and returns the results in the order of the shards. When fn fails on a shard, the context of the other
+// shards is cancelled and the error is returned along with the errors of the other shards. There is no atomicity across
+// shards, shards that committed before the failure stay committed.
+func FanOut[RESULT any, KEY any, DRIVER any, CONFIG any, BUILDER any](ctx context.Context, s *Set[KEY, DRIVER, CONFIG, BUILDER], fn func(shard int, session octobe.BuilderSession[BUILDER]) (RESULT, error), opts ...octobe.Option[CONFIG]) ([]RESULT, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]RESULT, len(s.shards))
+	errs := make([]error, len(s.shards))
+	var wg sync.WaitGroup
+	for i, shard := range s.shards {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := octobe.StartTransactionWithResult(ctx, shard, func(session octobe.BuilderSession[BUILDER]) (RESULT, error) {
+				return fn(i, session)
+			}, opts...)
+			if err != nil {
+				errs[i] = fmt.Errorf("shard %d: %w", i, err)
+				cancel()
+				return
+			}
+			results[i] = result
+		}()
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// Concat merges the results of FanOut that are slices into a single slice, in the order of the shards.
+func Concat[T any](results [][]T) []T {
+	n := 0
+	for _, result := range results {
+		n += len(result)
+	}
+	merged := make([]T, 0, n)
+	for _, result := range results {
+		merged = append(merged, result...)
+	}
+	return merged
+}
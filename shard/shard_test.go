@@ -0,0 +1,119 @@
+package shard_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/driver/postgres"
+	"github.com/ponrove/octobe/shard"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFuncs(t *testing.T) {
+	hash := shard.Hash[string]()
+	require.Equal(t, hash("tenant-1", 4), hash("tenant-1", 4))
+	for _, key := range []string{"", "a", "tenant-1", "tenant-2"} {
+		require.GreaterOrEqual(t, hash(key, 3), 0)
+		require.Less(t, hash(key, 3), 3)
+	}
+
+	modulo := shard.Modulo[int64]()
+	require.Equal(t, 1, modulo(7, 3))
+	require.Equal(t, 2, modulo(-7, 3))
+	require.Equal(t, 0, modulo(-6, 3))
+
+	// The number of shards does not fit the key type.
+	require.Equal(t, 255, shard.Modulo[uint8]()(255, 256))
+	require.Equal(t, 200, shard.Modulo[uint8]()(200, 300))
+	require.Equal(t, 2, shard.Modulo[int8]()(-128, 130))
+	require.Equal(t, 127, shard.Modulo[int8]()(127, 1000))
+}
+
+func TestSet(t *testing.T) {
+	ctx := context.Background()
+	first, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	second, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	mocks := []pgxmock.PgxPoolIface{first, second}
+	for _, mock := range mocks {
+		mock.ExpectBeginTx(pgx.TxOptions{})
+		mock.ExpectQuery("SELECT name FROM products").
+			WillReturnRows(pgxmock.NewRows([]string{"name"}).AddRow("product").AddRow("other"))
+		mock.ExpectCommit()
+	}
+	second.ExpectBeginTx(pgx.TxOptions{})
+	second.ExpectExec("UPDATE products").WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	second.ExpectCommit()
+	for _, mock := range mocks {
+		mock.ExpectClose()
+	}
+
+	firstShard, err := octobe.New(postgres.OpenPGXPoolWithPool(first))
+	require.NoError(t, err)
+	secondShard, err := octobe.New(postgres.OpenPGXPoolWithPool(second))
+	require.NoError(t, err)
+
+	set, err := shard.New(shard.Modulo[int](), firstShard, secondShard)
+	require.NoError(t, err)
+	require.Equal(t, 2, set.Len())
+
+	names, err := shard.FanOut(ctx, set, func(_ int, session octobe.BuilderSession[postgres.Builder]) ([]string, error) {
+		var names []string
+		err := session.Builder()("SELECT name FROM products").Query(func(rows postgres.Rows) error {
+			for rows.Next() {
+				var name string
+				if err := rows.Scan(&name); err != nil {
+					return err
+				}
+				names = append(names, name)
+			}
+			return rows.Err()
+		})
+		return names, err
+	}, postgres.WithPGXTxOptions(postgres.PGXTxOptions{}))
+	require.NoError(t, err)
+	require.Equal(t, []string{"product", "other", "product", "other"}, shard.Concat(names))
+
+	err = set.StartTransactionFor(ctx, 3, func(session octobe.BuilderSession[postgres.Builder]) error {
+		_, err := session.Builder()("UPDATE products SET name = 'octobe'").Exec()
+		return err
+	}, postgres.WithPGXTxOptions(postgres.PGXTxOptions{}))
+	require.NoError(t, err)
+
+	require.NoError(t, set.Close(ctx))
+	for _, mock := range mocks {
+		require.NoError(t, mock.ExpectationsWereMet())
+	}
+}
+
+func TestFanOutError(t *testing.T) {
+	ctx := context.Background()
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	mock.ExpectBeginTx(pgx.TxOptions{})
+	mock.ExpectRollback()
+
+	ob, err := octobe.New(postgres.OpenPGXPoolWithPool(mock))
+	require.NoError(t, err)
+	set, err := shard.New(shard.Hash[string](), ob)
+	require.NoError(t, err)
+
+	_, err = shard.FanOut(ctx, set, func(int, octobe.BuilderSession[postgres.Builder]) (int, error) {
+		return 0, errors.New("failed")
+	}, postgres.WithPGXTxOptions(postgres.PGXTxOptions{}))
+	require.ErrorContains(t, err, "shard 0: failed")
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	_, err = shard.New(shard.Hash[string](), none(ob)...)
+	require.ErrorIs(t, err, shard.ErrNoShards)
+}
+
+// none returns an empty slice of the type of its argument, for types that cannot be named outside of their package.
+func none[T any](T) []T {
+	return nil
+}
@@ -0,0 +1,157 @@
+package octobe
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrShutdown is returned by Begin once Shutdown or Close has been called.
+var ErrShutdown = errors.New("octobe is shutting down")
+
+// track registers a session as active, it fails with ErrShutdown once Shutdown has been called.
+func (ob *Octobe[DRIVER, CONFIG, BUILDER]) track(s *session[DRIVER, CONFIG, BUILDER]) error {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+	if ob.shutdown {
+		return ErrShutdown
+	}
+	if ob.active == nil {
+		ob.active = make(map[*session[DRIVER, CONFIG, BUILDER]]struct{})
+	}
+	ob.active[s] = struct{}{}
+	s.active = true
+	return nil
+}
+
+// untrack removes a session from the active sessions, signalling Shutdown when the last one finishes.
+func (ob *Octobe[DRIVER, CONFIG, BUILDER]) untrack(s *session[DRIVER, CONFIG, BUILDER]) {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+	delete(ob.active, s)
+	if len(ob.active) == 0 && ob.drained != nil {
+		close(ob.drained)
+		ob.drained = nil
+	}
+}
+
+// registerCancel registers the context of a session so it can be cancelled on close. The registration ends when the
+// session finishes its transaction or when parent is done.
+func (ob *Octobe[DRIVER, CONFIG, BUILDER]) registerCancel(parent context.Context, s *session[DRIVER, CONFIG, BUILDER]) {
+	ob.mu.Lock()
+	if ob.cancelled {
+		ob.mu.Unlock()
+		s.cancel()
+		return
+	}
+	if ob.cancellable == nil {
+		ob.cancellable = make(map[*session[DRIVER, CONFIG, BUILDER]]struct{})
+	}
+	ob.cancellable[s] = struct{}{}
+	ob.mu.Unlock()
+
+	s.stop = context.AfterFunc(parent, func() {
+		ob.unregisterCancel(s)
+	})
+}
+
+// unregisterCancel removes the context of a session from the contexts cancelled on close.
+func (ob *Octobe[DRIVER, CONFIG, BUILDER]) unregisterCancel(s *session[DRIVER, CONFIG, BUILDER]) {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+	delete(ob.cancellable, s)
+}
+
+// cancelAll cancels the contexts of all outstanding sessions, and of sessions begun afterwards.
+func (ob *Octobe[DRIVER, CONFIG, BUILDER]) cancelAll() {
+	ob.mu.Lock()
+	ob.cancelled = true
+	sessions := ob.cancellable
+	ob.cancellable = nil
+	ob.mu.Unlock()
+
+	for s := range sessions {
+		s.cancel()
+	}
+}
+
+// drain stops accepting new sessions and waits for active sessions to finish until ctx is done. When the instance
+// cancels sessions on close, the contexts of all outstanding sessions are cancelled afterwards. Sessions that are still
+// active are then rolled back and returned.
+func (ob *Octobe[DRIVER, CONFIG, BUILDER]) drain(ctx context.Context) ([]Session[BUILDER], error) {
+	ob.mu.Lock()
+	ob.shutdown = true
+	var drained chan struct{}
+	if len(ob.active) > 0 {
+		if ob.drained == nil {
+			ob.drained = make(chan struct{})
+		}
+		drained = ob.drained
+	}
+	ob.mu.Unlock()
+
+	timedOut := false
+	if drained != nil {
+		select {
+		case <-drained:
+		case <-ctx.Done():
+			timedOut = true
+		}
+	}
+
+	if ob.cfg.cancelOnClose {
+		ob.cancelAll()
+	}
+	if !timedOut {
+		return nil, nil
+	}
+
+	ob.mu.Lock()
+	remaining := make([]*session[DRIVER, CONFIG, BUILDER], 0, len(ob.active))
+	for s := range ob.active {
+		remaining = append(remaining, s)
+	}
+	ob.mu.Unlock()
+
+	var (
+		aborted []Session[BUILDER]
+		errs    []error
+	)
+	for _, s := range remaining {
+		ok, err := s.abort(ErrSessionAborted)
+		if !ok {
+			continue
+		}
+		aborted = append(aborted, s)
+		errs = append(errs, err)
+	}
+	if len(aborted) > 0 {
+		errs = append([]error{ctx.Err()}, errs...)
+	}
+	return aborted, errors.Join(errs...)
+}
+
+// Shutdown stops accepting new sessions, waits for active transactional sessions to commit or roll back and closes the
+// driver. Begin returns ErrShutdown once Shutdown has been called. When ctx is done before all sessions finished, the
+// remaining sessions are rolled back and returned, committing or rolling them back afterwards fails with
+// ErrSessionAborted. The returned error then includes the error of ctx, together with any rollback or close errors.
+func (ob *Octobe[DRIVER, CONFIG, BUILDER]) Shutdown(ctx context.Context) ([]Session[BUILDER], error) {
+	ob.unregisterDebug()
+	aborted, err := ob.drain(ctx)
+	if err != nil {
+		// The deadline has passed, but closing the driver should still get the chance to finish cleanly.
+		return aborted, errors.Join(err, ob.driver.Close(context.WithoutCancel(ctx)))
+	}
+	return aborted, ob.driver.Close(ctx)
+}
+
+// closeWithGrace drains the sessions for at most the grace period measured on the clock of the instance, cancelling
+// everything that is still outstanding afterwards, and closes the driver.
+func (ob *Octobe[DRIVER, CONFIG, BUILDER]) closeWithGrace(ctx context.Context) error {
+	graceCtx, cancel := ContextWithTimeout(ctx, ob.cfg.clock, ob.cfg.closeGrace, context.DeadlineExceeded)
+	defer cancel()
+
+	if _, err := ob.drain(graceCtx); err != nil {
+		return errors.Join(err, ob.driver.Close(context.WithoutCancel(ctx)))
+	}
+	return ob.driver.Close(ctx)
+}
@@ -0,0 +1,197 @@
+package octobe_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ponrove/octobe"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShutdownWithoutSessions(t *testing.T) {
+	d := &fakeDriver{}
+	ob, err := octobe.New(d.open())
+	require.NoError(t, err)
+
+	// Sessions without a transaction are not waited for.
+	_, err = ob.Begin(context.Background())
+	require.NoError(t, err)
+
+	aborted, err := ob.Shutdown(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, aborted)
+	require.True(t, d.closed)
+
+	_, err = ob.Begin(context.Background(), withTx())
+	require.ErrorIs(t, err, octobe.ErrShutdown)
+}
+
+func TestShutdownDrainsSessions(t *testing.T) {
+	d := &fakeDriver{}
+	ob, err := octobe.New(d.open())
+	require.NoError(t, err)
+
+	session, err := ob.Begin(context.Background(), withTx())
+	require.NoError(t, err)
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		_ = session.Commit()
+	}()
+
+	aborted, err := ob.Shutdown(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, aborted)
+	require.True(t, d.sessions[0].committed)
+	require.True(t, d.closed)
+}
+
+func TestShutdownAbortsSessions(t *testing.T) {
+	d := &fakeDriver{closeErr: errors.New("close failed")}
+	ob, err := octobe.New(d.open())
+	require.NoError(t, err)
+
+	finished, err := ob.Begin(context.Background(), withTx())
+	require.NoError(t, err)
+	stuck, err := ob.Begin(context.Background(), withTx())
+	require.NoError(t, err)
+	require.NoError(t, finished.Rollback())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	aborted, err := ob.Shutdown(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.ErrorContains(t, err, "close failed")
+	require.Equal(t, []octobe.Session[string]{stuck}, aborted)
+	require.True(t, d.sessions[1].rolledBack)
+	require.True(t, d.closed)
+
+	require.ErrorIs(t, stuck.Commit(), octobe.ErrSessionAborted)
+	require.False(t, d.sessions[1].committed)
+}
+
+func TestCloseWithGracePeriod(t *testing.T) {
+	d := &fakeDriver{}
+	ob, err := octobe.New(d.open(), octobe.WithCloseGracePeriod(10*time.Millisecond))
+	require.NoError(t, err)
+
+	requestCtx, cancelRequest := context.WithCancel(context.Background())
+	defer cancelRequest()
+
+	stuck, err := ob.Begin(context.Background(), withTx())
+	require.NoError(t, err)
+	_, err = ob.Begin(requestCtx)
+	require.NoError(t, err)
+	finished, err := ob.Begin(context.Background(), withTx())
+	require.NoError(t, err)
+	require.NoError(t, finished.Commit())
+
+	// Committing releases the context of the session.
+	require.Error(t, d.sessions[2].ctx.Err())
+	require.NoError(t, d.sessions[0].ctx.Err())
+	require.NoError(t, d.sessions[1].ctx.Err())
+
+	err = ob.Close(context.Background())
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.ErrorIs(t, d.sessions[0].ctx.Err(), context.Canceled)
+	// Sessions without a transaction run on the context passed to Begin, which Close leaves alone.
+	require.NoError(t, d.sessions[1].ctx.Err())
+	require.True(t, d.sessions[0].rolledBack)
+	require.True(t, d.closed)
+	require.ErrorIs(t, stuck.Commit(), octobe.ErrSessionAborted)
+
+	_, err = ob.Begin(context.Background())
+	require.ErrorIs(t, err, octobe.ErrShutdown)
+}
+
+func TestCloseWithGracePeriodWithClock(t *testing.T) {
+	clock := &timerClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), expire: make(chan time.Time)}
+	d := &fakeDriver{}
+	ob, err := octobe.New(d.open(), octobe.WithClock(clock), octobe.WithCloseGracePeriod(time.Hour))
+	require.NoError(t, err)
+
+	stuck, err := ob.Begin(context.Background(), withTx())
+	require.NoError(t, err)
+
+	closed := make(chan error, 1)
+	go func() {
+		closed <- ob.Close(context.Background())
+	}()
+
+	// The grace period ends when the clock says so, not after an hour of wall time.
+	close(clock.expire)
+	select {
+	case err = <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("Close did not return once the grace period passed on the clock")
+	}
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.True(t, d.sessions[0].rolledBack)
+	require.True(t, d.closed)
+	require.ErrorIs(t, stuck.Commit(), octobe.ErrSessionAborted)
+}
+
+func TestCloseWithGracePeriodWithoutTransaction(t *testing.T) {
+	d := &fakeDriver{}
+	ob, err := octobe.New(d.open(), octobe.WithCloseGracePeriod(time.Second))
+	require.NoError(t, err)
+
+	// A long-lived context is passed on as-is, so sessions begun with it are not kept until it is done.
+	ctx := context.WithValue(context.Background(), struct{}{}, "request")
+	for range 3 {
+		_, err = ob.Begin(ctx)
+		require.NoError(t, err)
+	}
+	for _, session := range d.sessions {
+		require.Equal(t, ctx, session.ctx)
+	}
+	require.NoError(t, ob.Close(context.Background()))
+}
+
+func TestCloseWithGracePeriodDrained(t *testing.T) {
+	d := &fakeDriver{}
+	ob, err := octobe.New(d.open(), octobe.WithCloseGracePeriod(time.Second))
+	require.NoError(t, err)
+
+	session, err := ob.Begin(context.Background(), withTx())
+	require.NoError(t, err)
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		_ = session.Commit()
+	}()
+
+	require.NoError(t, ob.Close(context.Background()))
+	require.True(t, d.sessions[0].committed)
+	require.True(t, d.closed)
+}
+
+func TestCloseWithoutGracePeriod(t *testing.T) {
+	d := &fakeDriver{}
+	ob, err := octobe.New(d.open())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	_, err = ob.Begin(ctx, withTx())
+	require.NoError(t, err)
+
+	// Without a grace period the context is passed to the driver as is and Close does not wait.
+	require.Equal(t, ctx, d.sessions[0].ctx)
+	require.NoError(t, ob.Close(ctx))
+	require.True(t, d.closed)
+}
+
+func TestCloseRejectsBegin(t *testing.T) {
+	d := &fakeDriver{}
+	ob, err := octobe.New(d.open())
+	require.NoError(t, err)
+
+	require.NoError(t, ob.Close(context.Background()))
+	require.True(t, d.closed)
+
+	_, err = ob.Begin(context.Background())
+	require.ErrorIs(t, err, octobe.ErrShutdown)
+}
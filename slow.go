@@ -0,0 +1,52 @@
+package octobe
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// WithSlowQueryThreshold invokes callback for every query of the instance that takes longer than threshold, including
+// reading its rows. The event carries the query, its duration and the driver. A nil callback logs slow queries with
+// slog.Default at the warning level instead, with the query truncated to DefaultLogQueryLength bytes.
+func WithSlowQueryThreshold(threshold time.Duration, callback func(ctx context.Context, event QueryEvent)) InstanceOption {
+	if callback == nil {
+		callback = logSlowQuery
+	}
+	return WithQueryHook(&slowQueryHook{threshold: threshold, callback: callback})
+}
+
+// slowQueryHook is the query hook reporting slow queries for WithSlowQueryThreshold.
+type slowQueryHook struct {
+	threshold time.Duration
+	callback  func(ctx context.Context, event QueryEvent)
+}
+
+// Ensure slowQueryHook implements the QueryHook interface.
+var _ QueryHook = &slowQueryHook{}
+
+// BeforeQuery does nothing, the duration of a query is only known once it has finished.
+func (h *slowQueryHook) BeforeQuery(ctx context.Context, _ *QueryEvent) context.Context {
+	return ctx
+}
+
+// AfterQuery reports the query if it exceeded the threshold.
+func (h *slowQueryHook) AfterQuery(ctx context.Context, event *QueryEvent) {
+	if event.Duration > h.threshold {
+		h.callback(ctx, *event)
+	}
+}
+
+// logSlowQuery logs a slow query with the default logger.
+func logSlowQuery(ctx context.Context, event QueryEvent) {
+	attrs := []slog.Attr{
+		slog.String("driver", event.Driver.Name),
+		slog.String("operation", string(event.Operation)),
+		slog.String("query", truncate(event.Query, DefaultLogQueryLength)),
+		slog.Duration("duration", event.Duration),
+	}
+	if event.Err != nil {
+		attrs = append(attrs, slog.Any("error", event.Err))
+	}
+	slog.Default().LogAttrs(ctx, slog.LevelWarn, "slow query", attrs...)
+}
@@ -0,0 +1,33 @@
+package octobe_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ponrove/octobe"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithSlowQueryThreshold(t *testing.T) {
+	var slow []octobe.QueryEvent
+	d := &fakeDriver{}
+	ob, err := octobe.New(d.open(), octobe.WithSlowQueryThreshold(5*time.Millisecond, func(_ context.Context, event octobe.QueryEvent) {
+		slow = append(slow, event)
+	}))
+	require.NoError(t, err)
+
+	_, err = ob.Begin(context.Background(), withoutTx())
+	require.NoError(t, err)
+
+	_, done := octobe.BeginQuery(d.sessions[0].ctx, octobe.OperationQuery, "SELECT 1", nil)
+	done(1, nil)
+	_, done = octobe.BeginQuery(d.sessions[0].ctx, octobe.OperationQuery, "SELECT pg_sleep(1)", nil)
+	time.Sleep(10 * time.Millisecond)
+	done(1, nil)
+
+	require.Len(t, slow, 1)
+	require.Equal(t, "SELECT pg_sleep(1)", slow[0].Query)
+	require.Equal(t, octobe.OperationQuery, slow[0].Operation)
+	require.GreaterOrEqual(t, slow[0].Duration, 10*time.Millisecond)
+}
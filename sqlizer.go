@@ -0,0 +1,15 @@
+package octobe
+
+// Sqlizer is implemented by query builders like squirrel, and by sqltpl.Template. The From method of the builders of
+// the drivers builds a segment from it.
+type Sqlizer interface {
+	ToSql() (string, []any, error)
+}
+
+// SqlizerFunc adapts a function to a Sqlizer, like the ToSQL method of a goqu dataset.
+type SqlizerFunc func() (string, []any, error)
+
+// ToSql calls f.
+func (f SqlizerFunc) ToSql() (string, []any, error) {
+	return f()
+}
@@ -0,0 +1,163 @@
+// Package sqltpl composes queries from fixed SQL fragments, dynamic identifiers and optional conditions, while values
+// always stay arguments bound to placeholders. Fragments are of type Fragment, which string constants convert to
+// implicitly, but string variables do not: concatenating user input into a fragment does not compile without an
+// explicit conversion that stands out in review. Identifiers that are only known at runtime, like a column to sort by,
+// are quoted with Ident.
+//
+//	t := sqltpl.Postgres().Append("SELECT id, name FROM products")
+//	t.WhereIf(name != "", "name = ?", name)
+//	t.WhereIf(minPrice > 0, "price >= ?", minPrice)
+//	t.Append(" ORDER BY ").Ident(sortColumn)
+//	query, args, err := t.ToSql()
+package sqltpl
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Fragment is a piece of SQL written by the developer, ? marks a placeholder for an argument and ?? a literal question
+// mark.
+type Fragment string
+
+// Style is the placeholder and identifier quoting style of a database.
+type Style int
+
+const (
+	// Dollar numbers placeholders as $1, $2 and so on, and quotes identifiers with double quotes, like PostgreSQL.
+	Dollar Style = iota
+	// Question keeps placeholders as ?, and quotes identifiers with backquotes, like ClickHouse.
+	Question
+)
+
+// Template composes a query, it is not safe for concurrent use. Errors are collected and returned by ToSql, so calls
+// can be chained.
+type Template struct {
+	style Style
+	query strings.Builder
+	args  []any
+	where bool
+	err   error
+}
+
+// New creates an empty template of style.
+func New(style Style) *Template {
+	return &Template{style: style}
+}
+
+// Postgres creates an empty template for the postgres drivers.
+func Postgres() *Template {
+	return New(Dollar)
+}
+
+// ClickHouse creates an empty template for the clickhouse driver.
+func ClickHouse() *Template {
+	return New(Question)
+}
+
+// Append appends fragment, args are bound to its placeholders in order.
+func (t *Template) Append(fragment Fragment, args ...any) *Template {
+	t.where = false
+	t.append(fragment, args)
+	return t
+}
+
+// Ident appends an identifier quoted for the style of the template, several parts are joined with dots to a qualified
+// identifier, like a table qualified with its schema.
+func (t *Template) Ident(parts ...string) *Template {
+	t.where = false
+	if len(parts) == 0 {
+		t.fail(errors.New("identifier has no parts"))
+		return t
+	}
+	for i, part := range parts {
+		if part == "" {
+			t.fail(errors.New("identifier has an empty part"))
+			return t
+		}
+		if i > 0 {
+			t.query.WriteByte('.')
+		}
+		switch t.style {
+		case Dollar:
+			t.query.WriteString(`"` + strings.ReplaceAll(part, `"`, `""`) + `"`)
+		case Question:
+			t.query.WriteString("`" + strings.ReplaceAll(strings.ReplaceAll(part, `\`, `\\`), "`", "\\`") + "`")
+		}
+	}
+	return t
+}
+
+// Where appends condition to the WHERE clause at the end of the template, starting the clause with WHERE and joining
+// the condition to the previous one with AND if the template already ends with a condition. Conditions containing OR
+// should be wrapped in parentheses.
+func (t *Template) Where(condition Fragment, args ...any) *Template {
+	if t.where {
+		t.query.WriteString(" AND ")
+	} else {
+		t.query.WriteString(" WHERE ")
+	}
+	t.append(condition, args)
+	t.where = true
+	return t
+}
+
+// WhereIf appends condition like Where if ok is true, it makes optional filters a single line.
+func (t *Template) WhereIf(ok bool, condition Fragment, args ...any) *Template {
+	if ok {
+		t.Where(condition, args...)
+	}
+	return t
+}
+
+// ToSql returns the query and its arguments, or the first error encountered while composing it. The name matches the
+// query builders octobe accepts, so a template can be passed on as is.
+func (t *Template) ToSql() (string, []any, error) {
+	if t.err != nil {
+		return "", nil, t.err
+	}
+	return t.query.String(), t.args, nil
+}
+
+// append writes fragment with its placeholders in the style of the template, and collects args.
+func (t *Template) append(fragment Fragment, args []any) {
+	placeholders := 0
+	var quote byte
+	for i := 0; i < len(fragment); i++ {
+		c := fragment[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '?' && i+1 < len(fragment) && fragment[i+1] == '?':
+			t.query.WriteByte('?')
+			i++
+			continue
+		case c == '?':
+			placeholders++
+			if t.style == Dollar {
+				t.query.WriteString("$" + strconv.Itoa(len(t.args)+placeholders))
+				continue
+			}
+		}
+		t.query.WriteByte(c)
+	}
+
+	if placeholders != len(args) {
+		t.fail(fmt.Errorf("fragment %q has %d placeholders, but %d arguments were given", fragment, placeholders, len(args)))
+		return
+	}
+	t.args = append(t.args, args...)
+}
+
+// fail records err unless an earlier error has been recorded.
+func (t *Template) fail(err error) {
+	if t.err == nil {
+		t.err = err
+	}
+}
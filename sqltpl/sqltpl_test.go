@@ -0,0 +1,45 @@
+package sqltpl_test
+
+import (
+	"testing"
+
+	"github.com/ponrove/octobe/sqltpl"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgres(t *testing.T) {
+	name, minPrice, sort := "octobe", 0, `price"; DROP TABLE products; --`
+
+	tpl := sqltpl.Postgres().Append("SELECT id, name FROM ").Ident("shop", "products")
+	tpl.WhereIf(name != "", "name = ?", name)
+	tpl.WhereIf(minPrice > 0, "price >= ?", minPrice)
+	tpl.Where("(tags ?? 'sale' OR stock > ?)", 10)
+	tpl.Append(" ORDER BY ").Ident(sort).Append(" LIMIT ?", 20)
+
+	query, args, err := tpl.ToSql()
+	require.NoError(t, err)
+	require.Equal(t, `SELECT id, name FROM "shop"."products" WHERE name = $1 AND (tags ? 'sale' OR stock > $2) ORDER BY "price""; DROP TABLE products; --" LIMIT $3`, query)
+	require.Equal(t, []any{"octobe", 10, 20}, args)
+}
+
+func TestClickHouse(t *testing.T) {
+	query, args, err := sqltpl.ClickHouse().
+		Append("SELECT count() FROM ").Ident("events").
+		Where("type = ?", "click").
+		Where("note != '?'").
+		ToSql()
+	require.NoError(t, err)
+	require.Equal(t, "SELECT count() FROM `events` WHERE type = ? AND note != '?'", query)
+	require.Equal(t, []any{"click"}, args)
+}
+
+func TestErrors(t *testing.T) {
+	_, _, err := sqltpl.Postgres().Append("SELECT * FROM products WHERE id = ?").ToSql()
+	require.ErrorContains(t, err, "has 1 placeholders, but 0 arguments were given")
+
+	_, _, err = sqltpl.Postgres().Append("SELECT * FROM ").Ident("").Append(" WHERE id = ?", 1, 2).ToSql()
+	require.ErrorContains(t, err, "empty part")
+
+	_, _, err = sqltpl.Postgres().Ident().ToSql()
+	require.Error(t, err)
+}
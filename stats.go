@@ -0,0 +1,154 @@
+package octobe
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// StatsReporter is implemented by drivers that can report statistics, such as the state of their connection pool. The
+// returned value should marshal to JSON.
+type StatsReporter interface {
+	Stats() any
+}
+
+// Stats is a snapshot of the statistics of an Octobe instance.
+type Stats struct {
+	// ActiveSessions is the number of transactional sessions that have not been committed or rolled back yet.
+	ActiveSessions int `json:"active_sessions"`
+	// Sessions is the number of sessions begun, with or without a transaction.
+	Sessions int64 `json:"sessions"`
+	// Statements holds the totals of the statements of all sessions, like the number of queries and errors, it is nil
+	// unless the instance was created with WithSessionStats or WithDebugName.
+	Statements *SessionStats `json:"statements,omitempty"`
+	// Driver holds the statistics reported by the driver, it is nil if the driver does not implement StatsReporter.
+	Driver any `json:"driver,omitempty"`
+}
+
+// Stats returns a snapshot of the statistics of the instance.
+func (ob *Octobe[DRIVER, CONFIG, BUILDER]) Stats() Stats {
+	ob.mu.Lock()
+	stats := Stats{ActiveSessions: len(ob.active), Sessions: ob.begun.Load()}
+	ob.mu.Unlock()
+
+	if ob.stats != nil {
+		statements := ob.stats.snapshot()
+		stats.Statements = &statements
+	}
+	if reporter, ok := ob.driver.(StatsReporter); ok {
+		stats.Driver = reporter.Stats()
+	}
+	return stats
+}
+
+// statsSource is implemented by every Octobe instance, regardless of its type parameters.
+type statsSource interface {
+	Stats() Stats
+}
+
+var (
+	debugMu        sync.Mutex
+	debugInstances = map[string]statsSource{}
+)
+
+// WithDebugName registers the instance under name, publishing its statistics through DebugHandler, PublishDebugExpvar
+// and ReportDebugStats, and counts the statements of its sessions like WithSessionStats. The instance is unregistered
+// again when it is closed or shut down. Registering another instance under the same name replaces the previous one.
+func WithDebugName(name string) InstanceOption {
+	return func(cfg *instanceConfig) {
+		cfg.debugName = name
+		cfg.stats = true
+	}
+}
+
+// registerDebug registers the instance if it was given a debug name.
+func (ob *Octobe[DRIVER, CONFIG, BUILDER]) registerDebug() {
+	if ob.cfg.debugName == "" {
+		return
+	}
+	debugMu.Lock()
+	defer debugMu.Unlock()
+	debugInstances[ob.cfg.debugName] = ob
+}
+
+// unregisterDebug removes the instance from the registered instances, if it is still registered under its name.
+func (ob *Octobe[DRIVER, CONFIG, BUILDER]) unregisterDebug() {
+	if ob.cfg.debugName == "" {
+		return
+	}
+	debugMu.Lock()
+	defer debugMu.Unlock()
+	if registered, ok := debugInstances[ob.cfg.debugName]; ok && registered == statsSource(ob) {
+		delete(debugInstances, ob.cfg.debugName)
+	}
+}
+
+// DebugStats returns the statistics of all instances registered with WithDebugName, keyed by name.
+func DebugStats() map[string]Stats {
+	debugMu.Lock()
+	instances := make(map[string]statsSource, len(debugInstances))
+	for name, instance := range debugInstances {
+		instances[name] = instance
+	}
+	debugMu.Unlock()
+
+	stats := make(map[string]Stats, len(instances))
+	for name, instance := range instances {
+		stats[name] = instance.Stats()
+	}
+	return stats
+}
+
+// DebugHandler returns a http.Handler that responds with the statistics of all registered instances as JSON, keyed by
+// name. It is meant for quick inspection and should not be exposed publicly.
+func DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		_ = json.NewEncoder(w).Encode(DebugStats())
+	})
+}
+
+// PublishDebugExpvar publishes the statistics of all registered instances as expvar variable under name, making them
+// available at /debug/vars. Like expvar.Publish, it panics if name is already in use.
+func PublishDebugExpvar(name string) {
+	expvar.Publish(name, expvar.Func(func() any {
+		return DebugStats()
+	}))
+}
+
+// StatsSink receives the statistics of the registered instances from ReportDebugStats, like a statsd client or any other
+// metrics system that is pushed to.
+type StatsSink interface {
+	ReportStats(name string, stats Stats)
+}
+
+// StatsSinkFunc is a function that implements StatsSink.
+type StatsSinkFunc func(name string, stats Stats)
+
+// ReportStats calls f.
+func (f StatsSinkFunc) ReportStats(name string, stats Stats) {
+	f(name, stats)
+}
+
+// ReportDebugStats reports the statistics of all registered instances to sink right away and then at every interval,
+// measured with clock, until ctx is done. A nil clock is SystemClock. It is meant to run in its own goroutine for the
+// lifetime of the service.
+func ReportDebugStats(ctx context.Context, sink StatsSink, interval time.Duration, clock Clock) {
+	if clock == nil {
+		clock = SystemClock
+	}
+	for {
+		for name, stats := range DebugStats() {
+			sink.ReportStats(name, stats)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-clock.After(interval):
+		}
+	}
+}
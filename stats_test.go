@@ -0,0 +1,113 @@
+package octobe_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ponrove/octobe"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStats(t *testing.T) {
+	ob, err := octobe.New((&fakeDriver{}).open())
+	require.NoError(t, err)
+
+	session, err := ob.Begin(context.Background(), withTx())
+	require.NoError(t, err)
+	_, err = ob.Begin(context.Background())
+	require.NoError(t, err)
+
+	require.Equal(t, octobe.Stats{ActiveSessions: 1, Sessions: 2, Driver: map[string]int{"sessions": 2}}, ob.Stats())
+	require.NoError(t, session.Commit())
+	require.Equal(t, 0, ob.Stats().ActiveSessions)
+}
+
+func TestDebugHandler(t *testing.T) {
+	primary, err := octobe.New((&fakeDriver{}).open(), octobe.WithDebugName("debug-primary"))
+	require.NoError(t, err)
+	replica, err := octobe.New((&fakeDriver{}).open(), octobe.WithDebugName("debug-replica"))
+	require.NoError(t, err)
+
+	session, err := primary.Begin(context.Background(), withTx())
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	octobe.DebugHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/octobe", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var stats map[string]struct {
+		ActiveSessions int            `json:"active_sessions"`
+		Driver         map[string]int `json:"driver"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&stats))
+	require.Equal(t, 1, stats["debug-primary"].ActiveSessions)
+	require.Equal(t, map[string]int{"sessions": 1}, stats["debug-primary"].Driver)
+	require.Equal(t, 0, stats["debug-replica"].ActiveSessions)
+
+	require.NoError(t, replica.Close(context.Background()))
+	require.NotContains(t, octobe.DebugStats(), "debug-replica")
+	require.Contains(t, octobe.DebugStats(), "debug-primary")
+
+	require.NoError(t, session.Commit())
+	_, err = primary.Shutdown(context.Background())
+	require.NoError(t, err)
+	require.NotContains(t, octobe.DebugStats(), "debug-primary")
+}
+
+func TestPublishDebugExpvar(t *testing.T) {
+	ob, err := octobe.New((&fakeDriver{}).open(), octobe.WithDebugName("expvar-primary"))
+	require.NoError(t, err)
+	defer ob.Close(context.Background())
+
+	octobe.PublishDebugExpvar("octobe_test")
+
+	var stats map[string]octobe.Stats
+	require.NoError(t, json.Unmarshal([]byte(expvar.Get("octobe_test").String()), &stats))
+	require.Contains(t, stats, "expvar-primary")
+}
+
+func TestReportDebugStats(t *testing.T) {
+	d := &fakeDriver{}
+	ob, err := octobe.New(d.open(), octobe.WithDebugName("sink-primary"))
+	require.NoError(t, err)
+	defer ob.Close(context.Background())
+
+	_, err = ob.Begin(context.Background(), withoutTx())
+	require.NoError(t, err)
+	_, done := octobe.BeginQuery(d.sessions[0].ctx, octobe.OperationQuery, "SELECT 1", nil)
+	done(1, nil)
+	_, done = octobe.BeginQuery(d.sessions[0].ctx, octobe.OperationExec, "DELETE FROM products", nil)
+	done(-1, errors.New("failed"))
+
+	// The fake clock ends every wait right away, reporting stops once the context is cancelled.
+	clock := newFakeClock()
+	var reported []octobe.Stats
+	ctx, cancel := context.WithCancel(context.Background())
+	octobe.ReportDebugStats(ctx, octobe.StatsSinkFunc(func(name string, stats octobe.Stats) {
+		if name != "sink-primary" {
+			return
+		}
+		reported = append(reported, stats)
+		if len(reported) == 2 {
+			cancel()
+		}
+	}), time.Minute, clock)
+
+	require.GreaterOrEqual(t, len(reported), 2)
+	require.NotEmpty(t, clock.waits)
+	for _, wait := range clock.waits {
+		require.Equal(t, time.Minute, wait)
+	}
+	stats := reported[0]
+	require.Equal(t, int64(1), stats.Sessions)
+	require.NotNil(t, stats.Statements)
+	require.Equal(t, int64(2), stats.Statements.Statements)
+	require.Equal(t, int64(1), stats.Statements.Errors)
+}
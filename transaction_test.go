@@ -0,0 +1,324 @@
+package octobe_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ponrove/octobe"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartTransactionWithResult(t *testing.T) {
+	t.Run("commit", func(t *testing.T) {
+		d := &fakeDriver{}
+		ob, err := octobe.New(d.open())
+		require.NoError(t, err)
+
+		result, err := octobe.StartTransactionWithResult(context.Background(), ob, func(session octobe.BuilderSession[string]) (int, error) {
+			return len(session.Builder()), nil
+		}, withTx())
+		require.NoError(t, err)
+		require.Equal(t, len("builder"), result)
+		require.True(t, d.sessions[0].committed)
+		require.False(t, d.sessions[0].rolledBack)
+	})
+
+	t.Run("rollback on error", func(t *testing.T) {
+		d := &fakeDriver{}
+		ob, err := octobe.New(d.open())
+		require.NoError(t, err)
+
+		expected := errors.New("failed")
+		result, err := octobe.StartTransactionWithResult(context.Background(), ob, func(session octobe.BuilderSession[string]) (string, error) {
+			return "partial", expected
+		}, withTx())
+		require.ErrorIs(t, err, expected)
+		require.Empty(t, result)
+		require.False(t, d.sessions[0].committed)
+		require.True(t, d.sessions[0].rolledBack)
+	})
+
+	t.Run("rollback on panic", func(t *testing.T) {
+		d := &fakeDriver{}
+		ob, err := octobe.New(d.open())
+		require.NoError(t, err)
+
+		require.PanicsWithValue(t, "boom", func() {
+			_, _ = octobe.StartTransactionWithResult(context.Background(), ob, func(session octobe.BuilderSession[string]) (int, error) {
+				panic("boom")
+			}, withTx())
+		})
+		require.True(t, d.sessions[0].rolledBack)
+	})
+}
+
+func TestStartTransactionRetry(t *testing.T) {
+	errConflict := errors.New("conflict")
+
+	t.Run("retries retryable errors", func(t *testing.T) {
+		d := &fakeDriver{retryErr: errConflict}
+		ob, err := octobe.New(d.open(), octobe.WithTxRetry(3, octobe.ConstantBackoff(time.Millisecond)))
+		require.NoError(t, err)
+
+		attempts := 0
+		err = ob.StartTransaction(context.Background(), func(session octobe.BuilderSession[string]) error {
+			attempts++
+			if attempts < 3 {
+				return errConflict
+			}
+			return nil
+		}, withTx())
+		require.NoError(t, err)
+		require.Equal(t, 3, attempts)
+		require.Len(t, d.sessions, 3)
+		require.True(t, d.sessions[0].rolledBack)
+		require.True(t, d.sessions[2].committed)
+	})
+
+	t.Run("gives up after max attempts", func(t *testing.T) {
+		d := &fakeDriver{retryErr: errConflict}
+		ob, err := octobe.New(d.open(), octobe.WithTxRetry(2, nil))
+		require.NoError(t, err)
+
+		attempts := 0
+		err = ob.StartTransaction(context.Background(), func(session octobe.BuilderSession[string]) error {
+			attempts++
+			return errConflict
+		}, withTx())
+		require.ErrorIs(t, err, errConflict)
+		require.Equal(t, 2, attempts)
+	})
+
+	t.Run("does not retry other errors", func(t *testing.T) {
+		d := &fakeDriver{retryErr: errConflict}
+		ob, err := octobe.New(d.open(), octobe.WithTxRetry(3, nil))
+		require.NoError(t, err)
+
+		expected := errors.New("failed")
+		attempts := 0
+		err = ob.StartTransaction(context.Background(), func(session octobe.BuilderSession[string]) error {
+			attempts++
+			return expected
+		}, withTx())
+		require.ErrorIs(t, err, expected)
+		require.Equal(t, 1, attempts)
+	})
+
+	t.Run("does not retry failed commits", func(t *testing.T) {
+		// The driver does not implement CommitRetryClassifier, so it cannot tell whether the commit was applied.
+		d := &fakeDriver{retryErr: errConflict, commitErr: errConflict}
+		ob, err := octobe.New(d.open(), octobe.WithTxRetry(3, nil))
+		require.NoError(t, err)
+
+		attempts := 0
+		err = ob.StartTransaction(context.Background(), func(session octobe.BuilderSession[string]) error {
+			attempts++
+			return nil
+		}, withTx())
+		require.ErrorIs(t, err, errConflict)
+		require.Equal(t, 1, attempts)
+	})
+
+	t.Run("stops when the context is done", func(t *testing.T) {
+		d := &fakeDriver{retryErr: errConflict}
+		ob, err := octobe.New(d.open(), octobe.WithTxRetry(3, octobe.ConstantBackoff(time.Hour)))
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		err = ob.StartTransaction(ctx, func(session octobe.BuilderSession[string]) error {
+			cancel()
+			return errConflict
+		}, withTx())
+		require.ErrorIs(t, err, errConflict)
+		require.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	backoff := octobe.ExponentialBackoff(10*time.Millisecond, 50*time.Millisecond)
+	require.Equal(t, 10*time.Millisecond, backoff(1))
+	require.Equal(t, 20*time.Millisecond, backoff(2))
+	require.Equal(t, 40*time.Millisecond, backoff(3))
+	require.Equal(t, 50*time.Millisecond, backoff(4))
+}
+
+func TestStartTransactionNestedWithoutSavepoints(t *testing.T) {
+	d := &fakeDriver{}
+	ob, err := octobe.New(d.open())
+	require.NoError(t, err)
+
+	err = ob.StartTransaction(context.Background(), func(session octobe.BuilderSession[string]) error {
+		ctx := octobe.TransactionContext(context.Background(), session)
+		return ob.StartTransaction(ctx, func(octobe.BuilderSession[string]) error { return nil }, withTx())
+	}, withTx())
+	require.NoError(t, err)
+
+	// The fake driver has no savepoints, so the nested transaction is an independent one.
+	require.Len(t, d.sessions, 2)
+	require.True(t, d.sessions[0].committed)
+	require.True(t, d.sessions[1].committed)
+}
+
+func TestStartTransactionCommitErrorNotRolledBack(t *testing.T) {
+	commitErr := errors.New("commit failed")
+	d := &fakeDriver{commitErr: commitErr}
+	ob, err := octobe.New(d.open())
+	require.NoError(t, err)
+
+	err = ob.StartTransaction(context.Background(), func(octobe.BuilderSession[string]) error {
+		return nil
+	}, withTx())
+	require.ErrorIs(t, err, commitErr)
+	require.False(t, d.sessions[0].rolledBack)
+}
+
+func TestStartTransactionRollbackError(t *testing.T) {
+	fnErr, rollbackErr := errors.New("handler failed"), errors.New("connection lost")
+	d := &fakeDriver{rollbackErr: rollbackErr}
+	ob, err := octobe.New(d.open())
+	require.NoError(t, err)
+
+	err = ob.StartTransaction(context.Background(), func(octobe.BuilderSession[string]) error {
+		return fnErr
+	}, withTx())
+	require.ErrorIs(t, err, fnErr)
+	require.ErrorIs(t, err, rollbackErr)
+
+	// A session without a transaction has nothing to roll back, its rollback error is not reported.
+	err = ob.StartTransaction(context.Background(), func(octobe.BuilderSession[string]) error {
+		return fnErr
+	}, withoutTx())
+	require.ErrorIs(t, err, fnErr)
+	require.NotErrorIs(t, err, rollbackErr)
+}
+
+func TestSessionRolledBackWhenContextDone(t *testing.T) {
+	d := &fakeDriver{}
+	ob, err := octobe.New(d.open())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	session, err := ob.Begin(ctx, withTx())
+	require.NoError(t, err)
+	require.Equal(t, 1, ob.Stats().ActiveSessions)
+
+	cancel()
+	require.Eventually(t, func() bool {
+		return ob.Stats().ActiveSessions == 0
+	}, time.Second, time.Millisecond)
+
+	require.True(t, d.sessions[0].rolledBack)
+	err = session.Commit()
+	require.ErrorIs(t, err, octobe.ErrSessionCancelled)
+	require.ErrorIs(t, err, context.Canceled)
+	require.False(t, d.sessions[0].committed)
+}
+
+func TestSessionTimeout(t *testing.T) {
+	d := &fakeDriver{}
+	ob, err := octobe.New(d.open(), octobe.WithSessionTimeout(20*time.Millisecond))
+	require.NoError(t, err)
+
+	committed, err := ob.Begin(context.Background(), withTx())
+	require.NoError(t, err)
+	require.NoError(t, committed.Commit())
+
+	forgotten, err := ob.Begin(context.Background(), withTx())
+	require.NoError(t, err)
+	_, ok := d.sessions[1].ctx.Deadline()
+	require.True(t, ok)
+
+	require.Eventually(t, func() bool {
+		return ob.Stats().ActiveSessions == 0
+	}, time.Second, time.Millisecond)
+
+	require.True(t, d.sessions[1].rolledBack)
+	err = forgotten.Commit()
+	require.ErrorIs(t, err, octobe.ErrSessionCancelled)
+	require.ErrorIs(t, err, octobe.ErrSessionTimeout)
+	require.False(t, d.sessions[0].rolledBack)
+}
+
+// timerClock is a clock whose waits all end once the test closes expire.
+type timerClock struct {
+	now    time.Time
+	expire chan time.Time
+}
+
+func (c *timerClock) Now() time.Time {
+	return c.now
+}
+
+func (c *timerClock) After(time.Duration) <-chan time.Time {
+	return c.expire
+}
+
+func TestSessionTimeoutWithClock(t *testing.T) {
+	clock := &timerClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), expire: make(chan time.Time)}
+	d := &fakeDriver{}
+	ob, err := octobe.New(d.open(), octobe.WithClock(clock), octobe.WithSessionTimeout(time.Minute))
+	require.NoError(t, err)
+
+	forgotten, err := ob.Begin(context.Background(), withTx())
+	require.NoError(t, err)
+	deadline, ok := d.sessions[0].ctx.Deadline()
+	require.True(t, ok)
+	require.Equal(t, clock.now.Add(time.Minute), deadline)
+	require.NoError(t, d.sessions[0].ctx.Err())
+	require.Equal(t, 1, ob.Stats().ActiveSessions)
+
+	// The deadline passes when the clock says so, not after a minute of wall time.
+	close(clock.expire)
+	require.Eventually(t, func() bool {
+		return ob.Stats().ActiveSessions == 0
+	}, time.Second, time.Millisecond)
+	require.ErrorIs(t, d.sessions[0].ctx.Err(), context.DeadlineExceeded)
+	require.True(t, d.sessions[0].rolledBack)
+	err = forgotten.Commit()
+	require.ErrorIs(t, err, octobe.ErrSessionCancelled)
+	require.ErrorIs(t, err, octobe.ErrSessionTimeout)
+}
+
+func TestSessionTimeoutWithoutTransaction(t *testing.T) {
+	d := &fakeDriver{}
+	ob, err := octobe.New(d.open(), octobe.WithSessionTimeout(time.Hour))
+	require.NoError(t, err)
+
+	ctx := context.WithValue(context.Background(), struct{}{}, "request")
+	_, err = ob.Begin(ctx)
+	require.NoError(t, err)
+	require.Equal(t, ctx, d.sessions[0].ctx)
+	_, ok := d.sessions[0].ctx.Deadline()
+	require.False(t, ok)
+}
+
+func TestSessionNotRolledBackAfterCommit(t *testing.T) {
+	d := &fakeDriver{}
+	ob, err := octobe.New(d.open())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	session, err := ob.Begin(ctx, withTx())
+	require.NoError(t, err)
+	require.NoError(t, session.Commit())
+
+	cancel()
+	time.Sleep(10 * time.Millisecond)
+	require.False(t, d.sessions[0].rolledBack)
+}
+
+func TestSessionContext(t *testing.T) {
+	type key struct{}
+	ob, err := octobe.New((&fakeDriver{}).open())
+	require.NoError(t, err)
+
+	ctx := context.WithValue(context.Background(), key{}, "value")
+	err = ob.StartTransaction(ctx, func(session octobe.BuilderSession[string]) error {
+		require.Equal(t, "value", octobe.SessionContext(session).Value(key{}))
+		return nil
+	}, withTx())
+	require.NoError(t, err)
+	require.Equal(t, context.Background(), octobe.SessionContext[string](&fakeSession{}))
+}
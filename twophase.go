@@ -0,0 +1,77 @@
+package octobe
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrTwoPhaseUnsupported is returned for two-phase commit operations on a driver or session that does not support
+// them.
+var ErrTwoPhaseUnsupported = errors.New("driver does not support two-phase commit")
+
+// Preparer is implemented by driver sessions that can prepare their transaction for a two-phase commit. A prepared
+// transaction is no longer associated with the session, it survives a crash of the client and the database, and is
+// committed or rolled back by its id through TwoPhaseCommitter. Committing or rolling back the session afterwards fails
+// with ErrTxDone.
+type Preparer interface {
+	Prepare(id string) error
+}
+
+// TwoPhaseCommitter is implemented by drivers that can finish transactions prepared by a Preparer, and list the
+// prepared transactions that have not been finished, e.g. after a crash.
+type TwoPhaseCommitter interface {
+	CommitPrepared(ctx context.Context, id string) error
+	RollbackPrepared(ctx context.Context, id string) error
+	PreparedTransactions(ctx context.Context, prefix string) ([]string, error)
+}
+
+// Ensure sessions can be prepared when their driver supports it.
+var _ Preparer = &session[any, any, any]{}
+
+// Prepare prepares the transaction of the session with the given id, and marks the session as no longer active. Session
+// hooks see a prepared transaction as committed, it is expected to be committed by CommitPrepared. It fails with
+// ErrTwoPhaseUnsupported when the session of the driver does not implement Preparer.
+func (s *session[DRIVER, CONFIG, BUILDER]) Prepare(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.aborted != nil {
+		return s.aborted
+	}
+	preparer, ok := s.Session.(Preparer)
+	if !ok {
+		return ErrTwoPhaseUnsupported
+	}
+	defer s.finish()
+	// The outcome of a prepared transaction is decided by its coordinator, the session cannot flush its events.
+	s.events = nil
+	err := preparer.Prepare(id)
+	s.endSession(err == nil, err)
+	return err
+}
+
+// CommitPrepared commits the transaction prepared with the given id, on any connection of the instance.
+func (ob *Octobe[DRIVER, CONFIG, BUILDER]) CommitPrepared(ctx context.Context, id string) error {
+	committer, ok := ob.driver.(TwoPhaseCommitter)
+	if !ok {
+		return ErrTwoPhaseUnsupported
+	}
+	return committer.CommitPrepared(ctx, id)
+}
+
+// RollbackPrepared rolls back the transaction prepared with the given id, on any connection of the instance.
+func (ob *Octobe[DRIVER, CONFIG, BUILDER]) RollbackPrepared(ctx context.Context, id string) error {
+	committer, ok := ob.driver.(TwoPhaseCommitter)
+	if !ok {
+		return ErrTwoPhaseUnsupported
+	}
+	return committer.RollbackPrepared(ctx, id)
+}
+
+// PreparedTransactions returns the ids of the prepared transactions of the database that start with prefix.
+func (ob *Octobe[DRIVER, CONFIG, BUILDER]) PreparedTransactions(ctx context.Context, prefix string) ([]string, error) {
+	committer, ok := ob.driver.(TwoPhaseCommitter)
+	if !ok {
+		return nil, ErrTwoPhaseUnsupported
+	}
+	return committer.PreparedTransactions(ctx, prefix)
+}
@@ -0,0 +1,108 @@
+package octobe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNoTransaction is returned by Defer for a session that does not run in a transaction.
+	ErrNoTransaction = errors.New("session does not run in a transaction")
+	// ErrFlush is returned by Commit when the transaction was committed, but a flusher of WithEventFlusher failed. The
+	// transaction is not retried by StartTransaction.
+	ErrFlush = errors.New("transaction is committed, but flushing its deferred events failed")
+)
+
+// UnitOfWork is implemented by the sessions of every instance, see Defer.
+type UnitOfWork interface {
+	// Defer collects event to be flushed once the transaction of the session has been committed.
+	Defer(event any) error
+}
+
+// Ensure sessions collect deferred events.
+var _ UnitOfWork = &session[any, any, any]{}
+
+// Defer collects event, such as a domain event or a message to publish, in the transaction of session. Once the
+// transaction has been committed, the collected events are handed to the flushers registered with WithEventFlusher,
+// when it is rolled back they are dropped. Events deferred in a transaction nested by StartTransaction are dropped when
+// its savepoint is rolled back. It fails with ErrNoTransaction for a session without a transaction, and with ErrTxDone
+// once the transaction has ended.
+func Defer[BUILDER any](session BuilderSession[BUILDER], event any) error {
+	if u, ok := session.(UnitOfWork); ok {
+		return u.Defer(event)
+	}
+	return ErrNoTransaction
+}
+
+// WithEventFlusher registers flush to receive the events of type EVENT deferred with Defer in a transaction, in the
+// order they were deferred, after the transaction has been committed. Several flushers can be registered for different
+// types of events, events without a flusher for their type are dropped. When a flusher fails, Commit returns its error
+// matching ErrFlush. Flushers run after the commit, a crash in between loses the events, so they should only be used for
+// events that may be lost or that can be recovered otherwise, like with an outbox table.
+func WithEventFlusher[EVENT any](flush func(ctx context.Context, events []EVENT) error) InstanceOption {
+	return func(cfg *instanceConfig) {
+		cfg.flushers = append(cfg.flushers, func(ctx context.Context, events []any) error {
+			typed := make([]EVENT, 0, len(events))
+			for _, event := range events {
+				if e, ok := event.(EVENT); ok {
+					typed = append(typed, e)
+				}
+			}
+			if len(typed) == 0 {
+				return nil
+			}
+			return flush(ctx, typed)
+		})
+	}
+}
+
+// Defer collects event to be flushed once the transaction of the session has been committed, see the function Defer.
+func (s *session[DRIVER, CONFIG, BUILDER]) Defer(event any) error {
+	if !s.InTransaction() {
+		return ErrNoTransaction
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.active {
+		return ErrTxDone
+	}
+	s.events = append(s.events, event)
+	return nil
+}
+
+// eventMark returns the number of events deferred so far, the events deferred afterwards can be dropped with
+// dropEvents.
+func (s *session[DRIVER, CONFIG, BUILDER]) eventMark() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.events)
+}
+
+// dropEvents drops the events deferred after mark.
+func (s *session[DRIVER, CONFIG, BUILDER]) dropEvents(mark int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if mark < len(s.events) {
+		clear(s.events[mark:])
+		s.events = s.events[:mark]
+	}
+}
+
+// flush hands the events deferred in the committed transaction of the session to the flushers of the instance.
+func (s *session[DRIVER, CONFIG, BUILDER]) flush(events []any) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	var errs []error
+	for _, flush := range s.ob.cfg.flushers {
+		if err := flush(s.parent, events); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("%w: %w", ErrFlush, err)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package octobe_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ponrove/octobe"
+	"github.com/stretchr/testify/require"
+)
+
+type orderPlaced struct{ id int }
+
+type orderShipped struct{ id int }
+
+func TestDefer(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("flushed after commit", func(t *testing.T) {
+		var placed []orderPlaced
+		var shipped []orderShipped
+		d := &fakeDriver{}
+		ob, err := octobe.New(d.open(),
+			octobe.WithEventFlusher(func(_ context.Context, events []orderPlaced) error {
+				placed = append(placed, events...)
+				return nil
+			}),
+			octobe.WithEventFlusher(func(_ context.Context, events []orderShipped) error {
+				shipped = append(shipped, events...)
+				return nil
+			}),
+		)
+		require.NoError(t, err)
+
+		err = ob.StartTransaction(ctx, func(session octobe.BuilderSession[string]) error {
+			require.NoError(t, octobe.Defer(session, orderPlaced{id: 1}))
+			require.NoError(t, octobe.Defer(session, orderShipped{id: 1}))
+			require.NoError(t, octobe.Defer(session, orderPlaced{id: 2}))
+			require.Empty(t, placed)
+			return nil
+		}, withTx())
+		require.NoError(t, err)
+		require.Equal(t, []orderPlaced{{id: 1}, {id: 2}}, placed)
+		require.Equal(t, []orderShipped{{id: 1}}, shipped)
+	})
+
+	t.Run("flushed after the session ended", func(t *testing.T) {
+		d := &fakeDriver{}
+		var ob *octobe.Octobe[fakeDriver, fakeConfig, string]
+		ob, err := octobe.New(d.open(),
+			octobe.WithMaxConcurrentSessions(1, time.Second),
+			octobe.WithEventFlusher(func(ctx context.Context, events []orderPlaced) error {
+				// The committed session released its slot, the flusher can write to an outbox in a session of its own.
+				require.Zero(t, ob.Stats().ActiveSessions)
+				return ob.StartTransaction(ctx, func(octobe.BuilderSession[string]) error { return nil }, withTx())
+			}),
+		)
+		require.NoError(t, err)
+
+		err = ob.StartTransaction(ctx, func(session octobe.BuilderSession[string]) error {
+			return octobe.Defer(session, orderPlaced{id: 1})
+		}, withTx())
+		require.NoError(t, err)
+		require.Len(t, d.sessions, 2)
+		require.True(t, d.sessions[1].committed)
+	})
+
+	t.Run("dropped on rollback", func(t *testing.T) {
+		flushed := false
+		d := &fakeDriver{}
+		ob, err := octobe.New(d.open(), octobe.WithEventFlusher(func(context.Context, []orderPlaced) error {
+			flushed = true
+			return nil
+		}))
+		require.NoError(t, err)
+
+		fnErr := errors.New("failed")
+		err = ob.StartTransaction(ctx, func(session octobe.BuilderSession[string]) error {
+			require.NoError(t, octobe.Defer(session, orderPlaced{id: 1}))
+			return fnErr
+		}, withTx())
+		require.ErrorIs(t, err, fnErr)
+		require.False(t, flushed)
+
+		d.commitErr = errors.New("commit failed")
+		err = ob.StartTransaction(ctx, func(session octobe.BuilderSession[string]) error {
+			return octobe.Defer(session, orderPlaced{id: 2})
+		}, withTx())
+		require.ErrorIs(t, err, d.commitErr)
+		require.False(t, flushed)
+	})
+
+	t.Run("flush error is not retried", func(t *testing.T) {
+		flushErr := errors.New("broker unavailable")
+		d := &fakeDriver{retryErr: flushErr}
+		ob, err := octobe.New(d.open(),
+			octobe.WithTxRetry(3, octobe.ConstantBackoff(0)),
+			octobe.WithEventFlusher(func(context.Context, []orderPlaced) error { return flushErr }),
+		)
+		require.NoError(t, err)
+
+		err = ob.StartTransaction(ctx, func(session octobe.BuilderSession[string]) error {
+			return octobe.Defer(session, orderPlaced{id: 1})
+		}, withTx())
+		require.ErrorIs(t, err, octobe.ErrFlush)
+		require.ErrorIs(t, err, flushErr)
+		require.Len(t, d.sessions, 1)
+		require.True(t, d.sessions[0].committed)
+	})
+
+	t.Run("requires an active transaction", func(t *testing.T) {
+		d := &fakeDriver{}
+		ob, err := octobe.New(d.open())
+		require.NoError(t, err)
+
+		session, err := ob.Begin(ctx, withoutTx())
+		require.NoError(t, err)
+		require.ErrorIs(t, octobe.Defer(session, orderPlaced{}), octobe.ErrNoTransaction)
+
+		session, err = ob.Begin(ctx, withTx())
+		require.NoError(t, err)
+		require.NoError(t, session.Commit())
+		require.ErrorIs(t, octobe.Defer(session, orderPlaced{}), octobe.ErrTxDone)
+	})
+}
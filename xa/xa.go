@@ -0,0 +1,289 @@
+// Package xa coordinates transactions that span several databases with a two-phase commit. A Coordinator begins a
+// transaction on every participating Octobe instance, runs a callback with all of them, prepares the transactions and
+// commits them once all are prepared, so either all databases commit or none of them does. The drivers must implement
+// octobe.Preparer and octobe.TwoPhaseCommitter, the postgres drivers do so with PREPARE TRANSACTION and COMMIT PREPARED,
+// which requires max_prepared_transactions to be set on the servers.
+//
+// Branches are prepared and committed in the order of the participants, so the transaction is decided as soon as the
+// branch of the last participant is prepared: a transaction whose last branch is prepared is committed, any other is
+// rolled back. Committing stops at the first branch that fails to commit, which keeps the last branch prepared until all
+// others are committed. Recover applies that rule to the prepared transactions left behind by a crash.
+package xa
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ponrove/octobe"
+)
+
+// gidPrefix starts the id of every transaction prepared by a Coordinator.
+const gidPrefix = "octobe_xa:"
+
+var (
+	// ErrInDoubt is returned by Run when the transaction was decided to commit, but committing some of its prepared
+	// branches failed. The branches stay prepared, holding their locks, until Recover commits them.
+	ErrInDoubt = errors.New("transaction is committed but some branches are still prepared")
+	// ErrNoParticipants is returned by New without participants.
+	ErrNoParticipants = errors.New("no participants given")
+)
+
+// Participant is an Octobe instance taking part in the transactions of a Coordinator, created with Join.
+type Participant interface {
+	begin(ctx context.Context) (session, error)
+	instance() twoPhase
+}
+
+// session is the part of an octobe session used by the Coordinator.
+type session interface {
+	octobe.Preparer
+	Rollback() error
+}
+
+// twoPhase is the part of an Octobe instance that finishes prepared transactions.
+type twoPhase interface {
+	CommitPrepared(ctx context.Context, id string) error
+	RollbackPrepared(ctx context.Context, id string) error
+	PreparedTransactions(ctx context.Context, prefix string) ([]string, error)
+}
+
+// Branch is the participation of an Octobe instance in the transactions of a Coordinator.
+type Branch[DRIVER any, CONFIG any, BUILDER any] struct {
+	ob   *octobe.Octobe[DRIVER, CONFIG, BUILDER]
+	opts []octobe.Option[CONFIG]
+}
+
+// Join creates the branch of ob for a Coordinator, its transactions are begun with opts, which must start a
+// transaction.
+func Join[DRIVER any, CONFIG any, BUILDER any](ob *octobe.Octobe[DRIVER, CONFIG, BUILDER], opts ...octobe.Option[CONFIG]) *Branch[DRIVER, CONFIG, BUILDER] {
+	return &Branch[DRIVER, CONFIG, BUILDER]{ob: ob, opts: opts}
+}
+
+// Session returns the session of the branch within tx. It panics if the branch is not a participant of the Coordinator
+// running tx.
+func (b *Branch[DRIVER, CONFIG, BUILDER]) Session(tx *Tx) octobe.BuilderSession[BUILDER] {
+	s, ok := tx.sessions[b].(octobe.BuilderSession[BUILDER])
+	if !ok {
+		panic("xa: branch is not a participant of the transaction")
+	}
+	return s
+}
+
+// begin begins the transaction of the branch.
+func (b *Branch[DRIVER, CONFIG, BUILDER]) begin(ctx context.Context) (session, error) {
+	s, err := b.ob.Begin(ctx, b.opts...)
+	if err != nil {
+		return nil, err
+	}
+	preparer, ok := s.(session)
+	if !ok {
+		return nil, errors.Join(octobe.ErrTwoPhaseUnsupported, s.Rollback())
+	}
+	return preparer, nil
+}
+
+// instance returns the instance of the branch.
+func (b *Branch[DRIVER, CONFIG, BUILDER]) instance() twoPhase {
+	return b.ob
+}
+
+// Tx is a transaction spanning all participants of a Coordinator.
+type Tx struct {
+	id       string
+	sessions map[Participant]any
+}
+
+// ID returns the id of the transaction, the prepared branches are named after it.
+func (tx *Tx) ID() string {
+	return tx.id
+}
+
+// Coordinator runs transactions across its participants with a two-phase commit.
+type Coordinator struct {
+	name         string
+	participants []Participant
+}
+
+// New creates a coordinator named name for the given participants. The name identifies the prepared transactions of the
+// coordinator for Recover, it must not contain a colon. It must be stable across restarts of a process, and unique among
+// the processes running coordinators at the same time, like a host name. The order of the participants is part of the
+// transaction ids, it must stay the same as well.
+func New(name string, participants ...Participant) (*Coordinator, error) {
+	if name == "" || strings.Contains(name, ":") {
+		return nil, fmt.Errorf("invalid coordinator name %q", name)
+	}
+	if len(participants) == 0 {
+		return nil, ErrNoParticipants
+	}
+	return &Coordinator{name: name, participants: participants}, nil
+}
+
+// Run begins a transaction on every participant and runs fn, which gets the sessions of the participants through
+// Branch.Session. When fn fails or panics, all transactions are rolled back. Otherwise they are prepared and committed.
+// If a branch fails to prepare, all branches are rolled back and the error is returned. If committing a prepared branch
+// fails, the returned error matches ErrInDoubt, the transaction is committed and Recover finishes that branch and the
+// ones after it, which are left prepared.
+func (c *Coordinator) Run(ctx context.Context, fn func(tx *Tx) error) (err error) {
+	tx := &Tx{id: newID(), sessions: make(map[Participant]any, len(c.participants))}
+	sessions := make([]session, 0, len(c.participants))
+	for i, p := range c.participants {
+		s, err := p.begin(ctx)
+		if err != nil {
+			return errors.Join(fmt.Errorf("begin branch %d: %w", i, err), rollback(sessions))
+		}
+		sessions = append(sessions, s)
+		tx.sessions[p] = s
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = rollback(sessions)
+			panic(p)
+		}
+	}()
+	if err = fn(tx); err != nil {
+		return errors.Join(err, rollback(sessions))
+	}
+
+	// Finishing the transaction must not be interrupted by ctx, that would leave branches prepared.
+	finishCtx := context.WithoutCancel(ctx)
+	for i, s := range sessions {
+		if err = s.Prepare(c.gid(tx.id, i)); err != nil {
+			errs := []error{fmt.Errorf("prepare branch %d: %w", i, err), rollback(sessions[i+1:])}
+			for j := range i {
+				if err := c.participants[j].instance().RollbackPrepared(finishCtx, c.gid(tx.id, j)); err != nil {
+					errs = append(errs, fmt.Errorf("rollback prepared branch %d: %w", j, err))
+				}
+			}
+			return errors.Join(errs...)
+		}
+	}
+
+	// The last branch is prepared, the transaction is committed. Committing a later branch after a failure could commit
+	// the last branch, and Recover would then roll back the failed one.
+	for i, p := range c.participants {
+		if err := p.instance().CommitPrepared(finishCtx, c.gid(tx.id, i)); err != nil {
+			return fmt.Errorf("%w: commit prepared branch %d: %w", ErrInDoubt, i, err)
+		}
+	}
+	return nil
+}
+
+// Recovery lists the prepared branches finished by Recover by their ids.
+type Recovery struct {
+	Committed  []string
+	RolledBack []string
+}
+
+// Recover finishes the prepared branches of the coordinator left behind by a crash or a failed commit: branches of
+// transactions whose last branch is prepared are committed, all others are rolled back. It must run before the
+// coordinator starts new transactions, e.g. at startup, a running transaction of the same coordinator would be rolled
+// back. Branches that cannot be finished are reported in the returned error and left prepared for the next Recover.
+func (c *Coordinator) Recover(ctx context.Context) (Recovery, error) {
+	prefix := gidPrefix + c.name + ":"
+	type branch struct {
+		gid         string
+		tx          string
+		index       int
+		participant int
+	}
+
+	var (
+		branches []branch
+		decided  = make(map[string]bool)
+		errs     []error
+	)
+	for i, p := range c.participants {
+		gids, err := p.instance().PreparedTransactions(ctx, prefix)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("list prepared branches of participant %d: %w", i, err))
+			continue
+		}
+		for _, gid := range gids {
+			tx, index, count, ok := parseGID(strings.TrimPrefix(gid, prefix))
+			// A participant sharing its database with others sees their branches as well, it only finishes its own.
+			if !ok || index != i {
+				continue
+			}
+			if count != len(c.participants) {
+				errs = append(errs, fmt.Errorf("prepared branch %q belongs to a transaction of %d participants", gid, count))
+				continue
+			}
+			branches = append(branches, branch{gid: gid, tx: tx, index: index, participant: i})
+			if index == count-1 {
+				decided[tx] = true
+			}
+		}
+	}
+
+	// Branches are committed in order, so the last branch, which decides the transaction, is committed last. Once a
+	// branch fails to commit, the later branches of its transaction are left prepared for the next Recover.
+	var (
+		recovery Recovery
+		failed   = make(map[string]bool)
+	)
+	for _, b := range branches {
+		instance := c.participants[b.participant].instance()
+		if decided[b.tx] {
+			if failed[b.tx] {
+				continue
+			}
+			if err := instance.CommitPrepared(ctx, b.gid); err != nil {
+				failed[b.tx] = true
+				errs = append(errs, fmt.Errorf("commit prepared branch %q: %w", b.gid, err))
+				continue
+			}
+			recovery.Committed = append(recovery.Committed, b.gid)
+			continue
+		}
+		if err := instance.RollbackPrepared(ctx, b.gid); err != nil {
+			errs = append(errs, fmt.Errorf("rollback prepared branch %q: %w", b.gid, err))
+			continue
+		}
+		recovery.RolledBack = append(recovery.RolledBack, b.gid)
+	}
+	return recovery, errors.Join(errs...)
+}
+
+// gid returns the id the branch of participant index is prepared with.
+func (c *Coordinator) gid(tx string, index int) string {
+	return fmt.Sprintf("%s%s:%s:%d:%d", gidPrefix, c.name, tx, index, len(c.participants))
+}
+
+// parseGID parses the part of a branch id after the prefix of the coordinator.
+func parseGID(s string) (tx string, index, count int, ok bool) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return "", 0, 0, false
+	}
+	index, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return "", 0, 0, false
+	}
+	count, err = strconv.Atoi(parts[2])
+	if err != nil || index < 0 || index >= count {
+		return "", 0, 0, false
+	}
+	return parts[0], index, count, true
+}
+
+// rollback rolls back the transactions of sessions that have not been prepared.
+func rollback(sessions []session) error {
+	var errs []error
+	for _, s := range sessions {
+		errs = append(errs, s.Rollback())
+	}
+	return errors.Join(errs...)
+}
+
+// newID returns a random transaction id.
+func newID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
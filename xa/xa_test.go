@@ -0,0 +1,208 @@
+package xa_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/ponrove/octobe"
+	"github.com/ponrove/octobe/driver/postgres"
+	"github.com/ponrove/octobe/xa"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	orders, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	billing, err := pgxmock.NewPool()
+	require.NoError(t, err)
+
+	orders.ExpectBeginTx(pgx.TxOptions{})
+	orders.ExpectExec("INSERT INTO orders").WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	orders.ExpectExec(`PREPARE TRANSACTION 'octobe_xa:app:\w+:0:2'`).WillReturnResult(pgxmock.NewResult("PREPARE TRANSACTION", 0))
+	orders.ExpectRollback()
+	billing.ExpectBeginTx(pgx.TxOptions{})
+	billing.ExpectExec("INSERT INTO invoices").WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	billing.ExpectExec(`PREPARE TRANSACTION 'octobe_xa:app:\w+:1:2'`).WillReturnResult(pgxmock.NewResult("PREPARE TRANSACTION", 0))
+	billing.ExpectRollback()
+	orders.ExpectExec(`COMMIT PREPARED 'octobe_xa:app:\w+:0:2'`).WillReturnResult(pgxmock.NewResult("COMMIT PREPARED", 0))
+	billing.ExpectExec(`COMMIT PREPARED 'octobe_xa:app:\w+:1:2'`).WillReturnResult(pgxmock.NewResult("COMMIT PREPARED", 0))
+
+	ordersOb, err := octobe.New(postgres.OpenPGXPoolWithPool(orders))
+	require.NoError(t, err)
+	billingOb, err := octobe.New(postgres.OpenPGXPoolWithPool(billing))
+	require.NoError(t, err)
+
+	ordersBranch := xa.Join(ordersOb, postgres.WithPGXTxOptions(postgres.PGXTxOptions{}))
+	billingBranch := xa.Join(billingOb, postgres.WithPGXTxOptions(postgres.PGXTxOptions{}))
+	coordinator, err := xa.New("app", ordersBranch, billingBranch)
+	require.NoError(t, err)
+
+	err = coordinator.Run(ctx, func(tx *xa.Tx) error {
+		require.NotEmpty(t, tx.ID())
+		if _, err := ordersBranch.Session(tx).Builder()("INSERT INTO orders (id) VALUES (1)").Exec(); err != nil {
+			return err
+		}
+		_, err := billingBranch.Session(tx).Builder()("INSERT INTO invoices (order_id) VALUES (1)").Exec()
+		return err
+	})
+	require.NoError(t, err)
+	require.NoError(t, orders.ExpectationsWereMet())
+	require.NoError(t, billing.ExpectationsWereMet())
+}
+
+func TestRunPrepareError(t *testing.T) {
+	ctx := context.Background()
+	orders, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	billing, err := pgxmock.NewPool()
+	require.NoError(t, err)
+
+	orders.ExpectBeginTx(pgx.TxOptions{})
+	orders.ExpectExec("PREPARE TRANSACTION").WillReturnResult(pgxmock.NewResult("PREPARE TRANSACTION", 0))
+	orders.ExpectRollback()
+	billing.ExpectBeginTx(pgx.TxOptions{})
+	billing.ExpectExec("PREPARE TRANSACTION").WillReturnError(errors.New("max_prepared_transactions is zero"))
+	billing.ExpectRollback()
+	orders.ExpectExec(`ROLLBACK PREPARED 'octobe_xa:app:\w+:0:2'`).WillReturnResult(pgxmock.NewResult("ROLLBACK PREPARED", 0))
+
+	ordersOb, err := octobe.New(postgres.OpenPGXPoolWithPool(orders))
+	require.NoError(t, err)
+	billingOb, err := octobe.New(postgres.OpenPGXPoolWithPool(billing))
+	require.NoError(t, err)
+
+	coordinator, err := xa.New("app",
+		xa.Join(ordersOb, postgres.WithPGXTxOptions(postgres.PGXTxOptions{})),
+		xa.Join(billingOb, postgres.WithPGXTxOptions(postgres.PGXTxOptions{})),
+	)
+	require.NoError(t, err)
+
+	err = coordinator.Run(ctx, func(*xa.Tx) error { return nil })
+	require.ErrorContains(t, err, "prepare branch 1: max_prepared_transactions is zero")
+	require.NoError(t, orders.ExpectationsWereMet())
+	require.NoError(t, billing.ExpectationsWereMet())
+}
+
+func TestRunCommitError(t *testing.T) {
+	ctx := context.Background()
+	orders, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	billing, err := pgxmock.NewPool()
+	require.NoError(t, err)
+
+	orders.ExpectBeginTx(pgx.TxOptions{})
+	orders.ExpectExec(`PREPARE TRANSACTION 'octobe_xa:app:\w+:0:2'`).WillReturnResult(pgxmock.NewResult("PREPARE TRANSACTION", 0))
+	orders.ExpectRollback()
+	billing.ExpectBeginTx(pgx.TxOptions{})
+	billing.ExpectExec(`PREPARE TRANSACTION 'octobe_xa:app:\w+:1:2'`).WillReturnResult(pgxmock.NewResult("PREPARE TRANSACTION", 0))
+	billing.ExpectRollback()
+	orders.ExpectExec(`COMMIT PREPARED 'octobe_xa:app:\w+:0:2'`).WillReturnError(errors.New("connection reset"))
+
+	ordersOb, err := octobe.New(postgres.OpenPGXPoolWithPool(orders))
+	require.NoError(t, err)
+	billingOb, err := octobe.New(postgres.OpenPGXPoolWithPool(billing))
+	require.NoError(t, err)
+
+	coordinator, err := xa.New("app",
+		xa.Join(ordersOb, postgres.WithPGXTxOptions(postgres.PGXTxOptions{})),
+		xa.Join(billingOb, postgres.WithPGXTxOptions(postgres.PGXTxOptions{})),
+	)
+	require.NoError(t, err)
+
+	var id string
+	err = coordinator.Run(ctx, func(tx *xa.Tx) error {
+		id = tx.ID()
+		return nil
+	})
+	require.ErrorIs(t, err, xa.ErrInDoubt)
+	// The last branch is not committed, it stays prepared so Recover commits the failed branch.
+	require.NoError(t, orders.ExpectationsWereMet())
+	require.NoError(t, billing.ExpectationsWereMet())
+
+	first, last := "octobe_xa:app:"+id+":0:2", "octobe_xa:app:"+id+":1:2"
+	orders.ExpectQuery("SELECT gid FROM pg_prepared_xacts").WithArgs("octobe_xa:app:").
+		WillReturnRows(pgxmock.NewRows([]string{"gid"}).AddRow(first))
+	billing.ExpectQuery("SELECT gid FROM pg_prepared_xacts").WithArgs("octobe_xa:app:").
+		WillReturnRows(pgxmock.NewRows([]string{"gid"}).AddRow(last))
+	orders.ExpectExec("COMMIT PREPARED '" + first + "'").WillReturnResult(pgxmock.NewResult("COMMIT PREPARED", 0))
+	billing.ExpectExec("COMMIT PREPARED '" + last + "'").WillReturnResult(pgxmock.NewResult("COMMIT PREPARED", 0))
+
+	recovery, err := coordinator.Recover(ctx)
+	require.NoError(t, err)
+	require.Equal(t, xa.Recovery{Committed: []string{first, last}}, recovery)
+	require.NoError(t, orders.ExpectationsWereMet())
+	require.NoError(t, billing.ExpectationsWereMet())
+}
+
+func TestRecoverCommitError(t *testing.T) {
+	ctx := context.Background()
+	orders, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	billing, err := pgxmock.NewPool()
+	require.NoError(t, err)
+
+	orders.ExpectQuery("SELECT gid FROM pg_prepared_xacts").WithArgs("octobe_xa:app:").
+		WillReturnRows(pgxmock.NewRows([]string{"gid"}).AddRow("octobe_xa:app:a:0:2"))
+	billing.ExpectQuery("SELECT gid FROM pg_prepared_xacts").WithArgs("octobe_xa:app:").
+		WillReturnRows(pgxmock.NewRows([]string{"gid"}).AddRow("octobe_xa:app:a:1:2"))
+	orders.ExpectExec("COMMIT PREPARED 'octobe_xa:app:a:0:2'").WillReturnError(errors.New("connection reset"))
+
+	ordersOb, err := octobe.New(postgres.OpenPGXPoolWithPool(orders))
+	require.NoError(t, err)
+	billingOb, err := octobe.New(postgres.OpenPGXPoolWithPool(billing))
+	require.NoError(t, err)
+
+	coordinator, err := xa.New("app", xa.Join(ordersOb), xa.Join(billingOb))
+	require.NoError(t, err)
+
+	// The last branch stays prepared, so the next Recover still commits the first one.
+	recovery, err := coordinator.Recover(ctx)
+	require.ErrorContains(t, err, `commit prepared branch "octobe_xa:app:a:0:2": connection reset`)
+	require.Empty(t, recovery.Committed)
+	require.Empty(t, recovery.RolledBack)
+	require.NoError(t, orders.ExpectationsWereMet())
+	require.NoError(t, billing.ExpectationsWereMet())
+}
+
+func TestRecover(t *testing.T) {
+	ctx := context.Background()
+	orders, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	billing, err := pgxmock.NewPool()
+	require.NoError(t, err)
+
+	// Transaction a crashed while committing, b while preparing.
+	orders.ExpectQuery("SELECT gid FROM pg_prepared_xacts").WithArgs("octobe_xa:app:").
+		WillReturnRows(pgxmock.NewRows([]string{"gid"}).AddRow("octobe_xa:app:b:0:2"))
+	billing.ExpectQuery("SELECT gid FROM pg_prepared_xacts").WithArgs("octobe_xa:app:").
+		WillReturnRows(pgxmock.NewRows([]string{"gid"}).AddRow("octobe_xa:app:a:1:2"))
+	orders.ExpectExec("ROLLBACK PREPARED 'octobe_xa:app:b:0:2'").WillReturnResult(pgxmock.NewResult("ROLLBACK PREPARED", 0))
+	billing.ExpectExec("COMMIT PREPARED 'octobe_xa:app:a:1:2'").WillReturnResult(pgxmock.NewResult("COMMIT PREPARED", 0))
+
+	ordersOb, err := octobe.New(postgres.OpenPGXPoolWithPool(orders))
+	require.NoError(t, err)
+	billingOb, err := octobe.New(postgres.OpenPGXPoolWithPool(billing))
+	require.NoError(t, err)
+
+	coordinator, err := xa.New("app", xa.Join(ordersOb), xa.Join(billingOb))
+	require.NoError(t, err)
+
+	recovery, err := coordinator.Recover(ctx)
+	require.NoError(t, err)
+	require.Equal(t, xa.Recovery{
+		Committed:  []string{"octobe_xa:app:a:1:2"},
+		RolledBack: []string{"octobe_xa:app:b:0:2"},
+	}, recovery)
+	require.NoError(t, orders.ExpectationsWereMet())
+	require.NoError(t, billing.ExpectationsWereMet())
+}
+
+func TestNew(t *testing.T) {
+	_, err := xa.New("app")
+	require.ErrorIs(t, err, xa.ErrNoParticipants)
+	_, err = xa.New("a:b")
+	require.Error(t, err)
+}